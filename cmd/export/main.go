@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/config"
+	"github.com/SkyClf/SkyClf/internal/export"
+	"github.com/SkyClf/SkyClf/internal/imghash"
+	"github.com/SkyClf/SkyClf/internal/infer"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+func main() {
+	out := flag.String("out", "", "output CSV file (default stdout); a configured mask/crop is written to <out>.preprocess.json")
+	day := flag.String("date", "", "only images fetched on this day (YYYY-MM-DD, see SKYCLF_DAY_GROUPING)")
+	resolution := flag.String("resolution", "", "only images of this resolution (WxH)")
+	exposureMin := flag.Float64("exposure-min", -1, "minimum sidecar exposure (-1 = no limit)")
+	exposureMax := flag.Float64("exposure-max", -1, "maximum sidecar exposure (-1 = no limit)")
+	dedup := flag.Bool("dedup", false, "keep one representative per cluster of near-identical frames")
+	threshold := flag.Int("dedup-threshold", imghash.DefaultThreshold, "max Hamming distance (bits) for frames to count as identical")
+	annotations := flag.String("annotations", "", "also write bounding-box annotations of the exported images to this CSV file")
+	hasAnnotations := flag.Bool("has-annotations", false, "only images with at least one annotation region")
+	split := flag.String("split", "", "only images of this split (train, val, test or unassigned)")
+	includeFITS := flag.Bool("include-fits", false, "also export FITS images (skipped by default)")
+	convertWebP := flag.String("convert-webp", "", "write JPEG copies of WebP images to this directory and list those instead")
+	excludeUnreviewed := flag.Bool("exclude-unreviewed", false, "skip images whose label awaits review")
+	snapshot := flag.String("snapshot", "", "take the labels from this dataset snapshot instead of the live ones")
+	weather := flag.Bool("weather", false, "add the nearest weather reading as cloud_cover,temperature,humidity,precipitation columns")
+	window := flag.Duration("dedup-window", imghash.DefaultWindow, "max time span of one dedup cluster (0 = unlimited)")
+	oversampleSpec := flag.String("oversample", "", `repeat rare-class train images: "balance" (up to the median class count) or class:multiplier,... e.g. precipitation:4`)
+	oversampleSeed := flag.Int64("oversample-seed", 0, "seed picking the images repeated once more for fractional factors")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("config: %v", err)
+	}
+
+	st, err := store.Open(cfg.LabelsDBPath)
+	if err != nil {
+		log.Fatalf("open store: %v", err)
+	}
+	defer st.Close()
+	displayLoc, _ := time.LoadLocation(cfg.DisplayTZ) // validated in config.Load
+	st.SetDayGrouping(store.DayGrouping{Loc: displayLoc, Night: cfg.DayGrouping == "night"})
+
+	opts := export.Options{
+		Filter:         store.ImageFilter{Day: *day, HasAnnotations: *hasAnnotations, Split: *split, Snapshot: *snapshot},
+		Dedup:          *dedup,
+		DedupThreshold: *threshold,
+		DedupWindow:    *window,
+		IncludeFITS:    *includeFITS,
+
+		ExcludeUnreviewed: *excludeUnreviewed,
+		Weather:           *weather,
+	}
+	if *resolution != "" {
+		w, h, err := store.ParseResolution(*resolution)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		opts.Filter.Width, opts.Filter.Height = w, h
+	}
+	if *exposureMin >= 0 {
+		opts.Filter.ExposureMin = exposureMin
+	}
+	if *exposureMax >= 0 {
+		opts.Filter.ExposureMax = exposureMax
+	}
+	var oversample *export.Oversample
+	if *oversampleSpec != "" {
+		o, err := export.ParseOversample(*oversampleSpec, *oversampleSeed)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		oversample = o
+	}
+
+	if *snapshot != "" {
+		snap, err := st.GetSnapshot(context.Background(), *snapshot)
+		if err != nil {
+			log.Fatalf("snapshot: %v", err)
+		}
+		if snap == nil {
+			log.Fatalf("snapshot %s not found", *snapshot)
+		}
+	}
+
+	items, err := export.Select(context.Background(), st, opts)
+	if err != nil {
+		log.Fatalf("select images: %v", err)
+	}
+
+	if *convertWebP != "" {
+		if err := export.ConvertWebP(items, *convertWebP); err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+	if oversample != nil {
+		var res export.OversampleResult
+		items, res = export.ApplyOversample(items, *oversample)
+		for _, c := range slices.Sorted(maps.Keys(res.Classes)) {
+			r := res.Classes[c]
+			log.Printf("oversample: %s %d -> %d rows (%.2fx)", c, r.Images, r.Rows, r.Factor)
+		}
+		for _, w := range res.Warnings {
+			log.Printf("oversample: WARNING %s", w)
+		}
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("create %s: %v", *out, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := export.WriteCSV(w, items, opts.Weather); err != nil {
+		log.Fatalf("write csv: %v", err)
+	}
+
+	// Record the mask/crop so training sees the same view as inference
+	var pre infer.PreprocessConfig
+	if _, err := st.GetSetting(context.Background(), store.SettingPreprocess, &pre); err != nil {
+		log.Fatalf("read preprocess setting: %v", err)
+	}
+	if !pre.IsZero() {
+		if *out == "" {
+			log.Printf("note: a mask/crop is configured for inference; use --out to also write it to <out>.preprocess.json")
+		} else {
+			data, _ := json.MarshalIndent(pre, "", "  ")
+			if err := os.WriteFile(*out+".preprocess.json", append(data, '\n'), 0o644); err != nil {
+				log.Fatalf("write preprocess: %v", err)
+			}
+		}
+	}
+
+	if *annotations != "" {
+		anns, err := st.ListAnnotations(context.Background(), "")
+		if err != nil {
+			log.Fatalf("list annotations: %v", err)
+		}
+		f, err := os.Create(*annotations)
+		if err != nil {
+			log.Fatalf("create %s: %v", *annotations, err)
+		}
+		defer f.Close()
+		if err := export.WriteAnnotationsCSV(f, items, anns); err != nil {
+			log.Fatalf("write annotations csv: %v", err)
+		}
+	}
+
+	log.Printf("exported %d images", len(items))
+}
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"flag"
+	"fmt"
 	"log"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/SkyClf/SkyClf/internal/config"
@@ -10,11 +15,33 @@ import (
 )
 
 func main() {
-	state := flag.String("state", "heavy_clouds", "skystate to set for all images")
+	state := flag.String("state", "heavy_clouds", "skystate to set for all selected images ("+strings.Join(store.SkyStates, ", ")+")")
 	meteor := flag.Bool("meteor", false, "set meteor flag for all images")
 	limit := flag.Int("limit", 0, "max images to process (0 = all)")
+	unlabeledOnly := flag.Bool("unlabeled-only", true, "only select images without a label")
+	day := flag.String("date", "", "only images fetched on this day (YYYY-MM-DD, see SKYCLF_DAY_GROUPING)")
+	skystateFilter := flag.String("skystate-filter", "", "only images currently labeled with this skystate (requires -unlabeled-only=false)")
+	force := flag.Bool("force", false, "overwrite existing labels (without it, labeled images are skipped)")
+	yes := flag.Bool("yes", false, "don't ask for confirmation")
 	flag.Parse()
 
+	if !store.ValidSkystate(*state) {
+		log.Fatalf("invalid -state %q; use one of: %s", *state, strings.Join(store.SkyStates, ", "))
+	}
+	if *skystateFilter != "" {
+		if !store.ValidSkystate(*skystateFilter) {
+			log.Fatalf("invalid -skystate-filter %q; use one of: %s", *skystateFilter, strings.Join(store.SkyStates, ", "))
+		}
+		if *unlabeledOnly {
+			log.Fatalf("-skystate-filter selects labeled images; combine it with -unlabeled-only=false")
+		}
+	}
+	if *day != "" {
+		if _, err := time.Parse("2006-01-02", *day); err != nil {
+			log.Fatalf("invalid -date %q; use YYYY-MM-DD", *day)
+		}
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("config: %v", err)
@@ -25,18 +52,78 @@ func main() {
 		log.Fatalf("open store: %v", err)
 	}
 	defer st.Close()
+	displayLoc, _ := time.LoadLocation(cfg.DisplayTZ) // validated in config.Load
+	st.SetDayGrouping(store.DayGrouping{Loc: displayLoc, Night: cfg.DayGrouping == "night"})
 
-	images, err := st.ListImages(*limit, false, "")
+	ctx := context.Background()
+	images, err := st.ListImagesFiltered(ctx, store.ImageFilter{
+		Limit:         *limit,
+		UnlabeledOnly: *unlabeledOnly,
+		Day:           *day,
+		Skystate:      *skystateFilter,
+	})
 	if err != nil {
 		log.Fatalf("list images: %v", err)
 	}
 
-	now := time.Now()
+	// Labeled images are only touched with -force
+	var targets []store.ImageWithLabel
+	skipped := 0
 	for _, img := range images {
-		if err := st.SetLabel(img.ID, *state, *meteor, now); err != nil {
+		if img.Skystate != nil && !*force {
+			skipped++
+			continue
+		}
+		targets = append(targets, img)
+	}
+	overwrite := 0
+	for _, img := range targets {
+		if img.Skystate != nil {
+			overwrite++
+		}
+	}
+
+	if skipped > 0 {
+		log.Printf("skipping %d already labeled images (use -force to overwrite)", skipped)
+	}
+	if len(targets) == 0 {
+		log.Printf("nothing to label")
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "About to label %d images as %s (meteor=%t); %d existing labels will be overwritten.\n",
+		len(targets), *state, *meteor, overwrite)
+	if !*yes && !confirm("Continue? [y/N] ") {
+		log.Printf("aborted, nothing written")
+		return
+	}
+
+	now := time.Now().UTC()
+	labeled := 0
+	for _, img := range targets {
+		if err := st.WriteLabel(ctx, store.LabelWrite{
+			ImageID:   img.ID,
+			Skystate:  *state,
+			Meteor:    *meteor,
+			LabeledAt: now,
+			Labeler:   "cmd/label",
+		}); err != nil {
 			log.Printf("set label %s for %s: %v", *state, img.ID, err)
+			continue
 		}
+		labeled++
 	}
 
-	log.Printf("labeled %d images as %s", len(images), *state)
+	log.Printf("labeled %d images as %s", labeled, *state)
+}
+
+// confirm asks a yes/no question on stderr and reads the answer from stdin.
+func confirm(prompt string) bool {
+	fmt.Fprint(os.Stderr, prompt)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
 }
@@ -0,0 +1,74 @@
+// Command parity checks that the server's preprocessing matches the trainer's
+// transform: it runs LoadAndPreprocessNCHW on fixture images and compares the
+// tensors with references the trainer exported (see infer.ParityDir).
+//
+//	go run ./cmd/parity -dir fixtures/parity [-threshold 0.1] [-json]
+//	go run ./cmd/parity -models data/models [-version v3]
+//
+// It prints the largest and mean absolute difference per image and exits 1
+// when any image is over the threshold.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/SkyClf/SkyClf/internal/infer"
+)
+
+func main() {
+	dir := flag.String("dir", "", "directory of fixture images and reference tensors")
+	models := flag.String("models", "", "models directory; checks <version>/"+infer.ParityDir+" of a skystate model (instead of -dir)")
+	version := flag.String("version", "", "model version with -models (default: the latest)")
+	threshold := flag.Float64("threshold", infer.DefaultParityThreshold, "largest allowed absolute difference per value")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+	log.SetFlags(0)
+
+	if (*dir == "") == (*models == "") {
+		log.Fatalf("set one of -dir or -models")
+	}
+	if *models != "" {
+		info, err := infer.FindSkyStateModel(*models, *version)
+		if err != nil {
+			log.Fatalf("find model: %v", err)
+		}
+		if info == nil {
+			log.Fatalf("no published skystate model in %s", *models)
+		}
+		*dir = filepath.Join(info.Dir, infer.ParityDir)
+	}
+
+	rep, err := infer.CheckParity(*dir, *threshold)
+	if err != nil {
+		log.Fatalf("parity: %v", err)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(rep)
+	} else {
+		for _, r := range rep.Results {
+			verdict := "ok"
+			if !r.Pass {
+				verdict = "FAIL"
+			}
+			if r.Error != "" {
+				fmt.Printf("%-4s  %s: %s\n", verdict, r.Image, r.Error)
+				continue
+			}
+			fmt.Printf("%-4s  %s  max %.4f  mean %.4f\n", verdict, r.Image, r.MaxAbs, r.MeanAbs)
+		}
+		fmt.Printf("%d images, threshold %g\n", len(rep.Results), rep.Threshold)
+	}
+	if len(rep.Results) == 0 {
+		log.Fatalf("no fixture image with a reference tensor in %s", *dir)
+	}
+	if !rep.Pass {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/config"
+	"github.com/SkyClf/SkyClf/internal/fetcher"
+	"github.com/SkyClf/SkyClf/internal/infer"
+	"github.com/SkyClf/SkyClf/internal/store"
+	"github.com/SkyClf/SkyClf/internal/trainer"
+)
+
+// Check outcomes. Only failures make -check-config exit non-zero; warnings
+// are for optional parts (training, sync) the server runs without.
+const (
+	checkOK   = "OK"
+	checkWarn = "WARN"
+	checkFail = "FAIL"
+	checkSkip = "SKIP"
+)
+
+const checkTimeout = 15 * time.Second
+
+// checker prints one line per check and remembers whether any failed.
+type checker struct {
+	w      io.Writer
+	failed bool
+}
+
+func (c *checker) report(status, name, format string, args ...any) {
+	if status == checkFail {
+		c.failed = true
+	}
+	fmt.Fprintf(c.w, "  %-4s  %-14s %s\n", status, name, fmt.Sprintf(format, args...))
+}
+
+// runCheckConfig validates the configuration and the environment it points
+// at (directories, database, camera, inference, Docker), prints a report and
+// returns the process exit code.
+func runCheckConfig(w io.Writer) int {
+	c := &checker{w: w}
+	fmt.Fprintln(w, "SkyClf configuration check")
+
+	cfg, err := config.Load()
+	if err != nil {
+		for _, msg := range strings.Split(err.Error(), "; ") {
+			c.report(checkFail, "config", "%s", msg)
+		}
+		return 1
+	}
+	settings := cfg.Settings()
+	fromEnv := 0
+	for _, s := range settings {
+		if s.Source == config.SourceEnv {
+			fromEnv++
+		}
+	}
+	c.report(checkOK, "config", "valid (%d of %d settings from the environment)", fromEnv, len(settings))
+
+	for _, d := range []struct{ name, path string }{
+		{"data dir", cfg.DataDir},
+		{"images dir", cfg.ImagesDir},
+		{"models dir", cfg.ModelsDir},
+		{"db dir", filepath.Dir(cfg.LabelsDBPath)},
+		{"backup dir", cfg.BackupDir},
+	} {
+		if err := checkWritable(d.path); err != nil {
+			c.report(checkFail, d.name, "%s: %v", d.path, err)
+		} else {
+			c.report(checkOK, d.name, "%s is writable", d.path)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	checkDB(ctx, c, cfg)
+	checkCamera(ctx, c, cfg)
+	checkInference(ctx, c, cfg)
+
+	dctx, dcancel := context.WithTimeout(ctx, checkTimeout)
+	defer dcancel()
+	if err := trainer.CheckDocker(dctx, cfg.TrainerContainer); err != nil {
+		c.report(checkWarn, "docker", "%v (training will be disabled)", err)
+	} else {
+		c.report(checkOK, "docker", "daemon reachable, container %s exists", cfg.TrainerContainer)
+	}
+
+	if cfg.SyncPeerURL == "" {
+		c.report(checkSkip, "sync peer", "SKYCLF_SYNC_PEER_URL not set")
+	} else if status, _, err := httpGet(ctx, cfg.SyncPeerURL+"/health"); err != nil || status != http.StatusOK {
+		c.report(checkWarn, "sync peer", "%s", describeGet(status, err))
+	} else {
+		c.report(checkOK, "sync peer", "%s is up", cfg.SyncPeerURL)
+	}
+
+	fmt.Fprintln(w, "\nEffective settings:")
+	for _, s := range settings {
+		v := s.Value
+		if v == nil {
+			v = ""
+		}
+		fmt.Fprintf(w, "  %-30s %v (%s)\n", s.Key, v, s.Source)
+	}
+
+	if c.failed {
+		fmt.Fprintln(w, "\nConfiguration check failed.")
+		return 1
+	}
+	fmt.Fprintln(w, "\nConfiguration check passed.")
+	return 0
+}
+
+// checkWritable creates dir if needed and writes and removes a probe file in it.
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".skyclf-check-*")
+	if err != nil {
+		return err
+	}
+	_, werr := f.WriteString("ok")
+	cerr := f.Close()
+	os.Remove(f.Name())
+	if werr != nil {
+		return werr
+	}
+	return cerr
+}
+
+func checkDB(ctx context.Context, c *checker, cfg config.Config) {
+	st, err := store.Open(cfg.LabelsDBPath)
+	if err != nil {
+		c.report(checkFail, "database", "%s: %v", cfg.LabelsDBPath, err)
+		return
+	}
+	defer st.Close()
+	problems, err := st.IntegrityCheck(ctx)
+	if err != nil {
+		c.report(checkFail, "database", "integrity check: %v", err)
+		return
+	}
+	if len(problems) > 0 {
+		c.report(checkFail, "database", "integrity check: %s", strings.Join(problems, "; "))
+		return
+	}
+	n, err := st.CountLabeled(ctx)
+	if err != nil {
+		c.report(checkFail, "database", "%v", err)
+		return
+	}
+	c.report(checkOK, "database", "%s opens, %d labeled images", cfg.LabelsDBPath, n)
+}
+
+// checkCamera fetches from the configured source once, the way the fetcher would.
+func checkCamera(ctx context.Context, c *checker, cfg config.Config) {
+	if !cfg.Fetching() {
+		if cfg.Mode == "watch" {
+			c.report(checkSkip, "camera", "fetching disabled (SKYCLF_MODE=watch); %s is watched for new files", cfg.ImagesDir)
+			return
+		}
+		c.report(checkSkip, "camera", "fetching disabled (SKYCLF_MODE=no-fetch); %s is scanned every %s", cfg.ImagesDir, cfg.ScanInterval)
+		return
+	}
+	u := cfg.AllSkyURL
+	switch cfg.FetchMode {
+	case "capture":
+		argv := strings.Fields(cfg.CaptureCmd)
+		if _, err := exec.LookPath(argv[0]); err != nil {
+			c.report(checkFail, "camera", "capture command: %v", err)
+		} else {
+			c.report(checkOK, "camera", "capture command %s found (not run)", argv[0])
+		}
+		return
+	case "template":
+		loc, _ := time.LoadLocation(cfg.FetchTZ) // validated in config.Load
+		u = fetcher.ExpandTemplate(u, time.Now().In(loc))
+	}
+
+	status, head, err := httpGet(ctx, u)
+	if err != nil || status != http.StatusOK {
+		c.report(checkFail, "camera", "%s", describeGet(status, err))
+		return
+	}
+	if cfg.FetchMode == "index" {
+		c.report(checkOK, "camera", "index %s answers 200", u)
+		return
+	}
+	format := fetcher.DetectFormat(head.body, head.contentType)
+	switch {
+	case format == "":
+		c.report(checkFail, "camera", "%s answers 200 but not with a recognizable image (Content-Type %q)", u, head.contentType)
+	case !slices.Contains(cfg.ImageFormats, format):
+		c.report(checkFail, "camera", "%s serves %s, which SKYCLF_IMAGE_FORMATS doesn't include", u, format)
+	default:
+		c.report(checkOK, "camera", "%s serves %s", u, format)
+	}
+}
+
+func checkInference(ctx context.Context, c *checker, cfg config.Config) {
+	if cfg.InferBackend == "remote" {
+		status, _, err := httpGet(ctx, cfg.InferRemoteURL+"/health")
+		if err != nil || status != http.StatusOK {
+			c.report(checkFail, "inference", "remote %s", describeGet(status, err))
+			return
+		}
+		c.report(checkOK, "inference", "remote %s is up", cfg.InferRemoteURL)
+		return
+	}
+	mi, err := infer.FindSkyStateModel(cfg.ModelsDir, "")
+	switch {
+	case err != nil:
+		c.report(checkFail, "inference", "scan %s: %v", cfg.ModelsDir, err)
+	case mi == nil:
+		c.report(checkWarn, "inference", "no published model in %s yet (predictions disabled until one is trained)", cfg.ModelsDir)
+	default:
+		c.report(checkOK, "inference", "model %s found", mi.Version)
+	}
+}
+
+type responseHead struct {
+	contentType string
+	body        []byte // first bytes only
+}
+
+// httpGet requests u and returns the status with the start of the body.
+func httpGet(ctx context.Context, u string) (int, responseHead, error) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return 0, responseHead{}, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, responseHead{}, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return resp.StatusCode, responseHead{contentType: resp.Header.Get("Content-Type"), body: body}, nil
+}
+
+func describeGet(status int, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("answered %d %s", status, http.StatusText(status))
+}
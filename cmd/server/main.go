@@ -2,35 +2,109 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"strings"
+	"path/filepath"
+	"slices"
 	"syscall"
+	"time"
 
 	"github.com/SkyClf/SkyClf/internal/api"
+	"github.com/SkyClf/SkyClf/internal/buildinfo"
 	"github.com/SkyClf/SkyClf/internal/config"
+	"github.com/SkyClf/SkyClf/internal/export"
 	"github.com/SkyClf/SkyClf/internal/fetcher"
 	"github.com/SkyClf/SkyClf/internal/infer"
+	"github.com/SkyClf/SkyClf/internal/ingest"
+	"github.com/SkyClf/SkyClf/internal/labelsidecar"
+	"github.com/SkyClf/SkyClf/internal/labelsync"
+	"github.com/SkyClf/SkyClf/internal/mqtt"
+	"github.com/SkyClf/SkyClf/internal/report"
+	"github.com/SkyClf/SkyClf/internal/retention"
+	"github.com/SkyClf/SkyClf/internal/rules"
+	"github.com/SkyClf/SkyClf/internal/storage"
 	"github.com/SkyClf/SkyClf/internal/store"
+	"github.com/SkyClf/SkyClf/internal/thermal"
 	"github.com/SkyClf/SkyClf/internal/trainer"
+	"github.com/SkyClf/SkyClf/internal/weather"
+	"github.com/SkyClf/SkyClf/internal/webhook"
+	"github.com/SkyClf/SkyClf/ui"
 )
 
 func main() {
+	checkConfig := flag.Bool("check-config", false, "validate the configuration, directories, database, camera, inference and Docker, print a report and exit")
+	flag.Parse()
+	if *checkConfig {
+		os.Exit(runCheckConfig(os.Stdout))
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("config error: %v", err)
 	}
 
-	pred, err := infer.NewORTPredictor(cfg.ModelsDir)
-	if err != nil {
-		log.Fatalf("infer init: %v", err)
+	// Create context that cancels on interrupt
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	// The listener comes up first so liveness probes pass during startup;
+	// /ready stays 503 until the DB, the initial model scan and the first
+	// fetch attempt are done. Routes are added to mux as they become available.
+	mux := http.NewServeMux()
+	api.SetStrictPaths(cfg.StrictPaths)
+
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+
+	ready := api.NewReadinessHandler(api.ReadyDB, api.ReadyModel, api.ReadyFetch)
+	ready.RegisterRoutes(mux)
+
+	// Storage health: when the data directory (e.g. an NFS share) disappears,
+	// ingestion pauses and file endpoints answer 503 until it is back.
+	var storageMon *storage.Monitor
+	if cfg.StorageProbeInterval > 0 {
+		storageMon = storage.NewMonitor(cfg.DataDir, cfg.StorageProbeInterval)
+		go storageMon.Start(ctx)
+		ready.AddCheck("storage", storageMon.Check)
+	}
+	// Thermal wind-down: a hot board pauses auto-predict and training
+	var thermalMon *thermal.Monitor
+	if cfg.ThermalPath != "" {
+		thermalMon = thermal.NewMonitor(thermal.FileSensor(cfg.ThermalPath), cfg.ThermalMaxC, cfg.ThermalResumeC, cfg.ThermalInterval)
+		thermalMon.SetNotifier(webhook.New(cfg.WebhookURL))
+		go thermalMon.Start(ctx)
+	}
+	healthHandler := api.NewHealthHandler(storageMon)
+	healthHandler.SetFetching(cfg.Fetching())
+	healthHandler.SetThermal(thermalMon)
+	healthHandler.RegisterRoutes(mux)
+	api.NewVersionHandler(cfg.InferBackend).RegisterRoutes(mux)
+
+	// Optional login in front of the API and image files; a login stored in
+	// settings is known once the DB is open, guarded routes answer 503 until then
+	auth := api.NewAuthenticator(cfg.SessionTTL)
+	auth.SetToken(cfg.AdminToken)
+	if cfg.AuthUser != "" {
+		auth.SetCredentials(cfg.AuthUser, cfg.AuthPasswordHash)
+	}
+
+	// Request contexts derive from ctx so in-flight queries stop on shutdown
+	server := &http.Server{
+		Addr:        cfg.Addr,
+		Handler:     api.ServerHeader(auth.Guard(api.Gzip(api.StorageGuard(storageMon, mux)))),
+		BaseContext: func(net.Listener) context.Context { return ctx },
 	}
-	defer func() {
-		if pred != nil {
-			_ = pred.Close()
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("server error: %v", err)
 		}
 	}()
 
@@ -40,146 +114,480 @@ func main() {
 		log.Fatalf("db error: %v", err)
 	}
 	defer st.Close()
+	displayLoc, _ := time.LoadLocation(cfg.DisplayTZ) // validated in config.Load
+	st.SetDayGrouping(store.DayGrouping{Loc: displayLoc, Night: cfg.DayGrouping == "night"})
+	ready.Done(api.ReadyDB, nil)
 
-	n, _ := st.CountLabeled()
-	log.Printf("SkyClf starting addr=%s poll=%s allsky=%s labeled=%d", cfg.Addr, cfg.PollInterval, cfg.AllSkyURL, n)
+	if err := auth.Restore(ctx, st); err != nil {
+		log.Fatalf("auth settings: %v", err)
+	}
+	auth.RegisterRoutes(mux)
+	if auth.Required() {
+		log.Printf("login required for the API and images")
+	}
 
-	// Create context that cancels on interrupt
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer cancel()
+	// The model loads in the background (see ModelsHandler.Startup); load
+	// errors show in /api/models and /ready.
+	var (
+		pred infer.Predictor
+		ort  *infer.ORTPredictor // nil with the remote backend; explain, eval and mask/crop need a local model
+	)
+	if cfg.InferBackend == "remote" {
+		pred = infer.NewRemotePredictor(cfg.InferRemoteURL, cfg.InferRemoteTimeout)
+	} else {
+		ort = infer.NewORTPredictor(cfg.ModelsDir)
+		ort.SetSessionConfig(infer.SessionConfig{
+			IntraOpThreads: cfg.ORTIntraThreads,
+			InterOpThreads: cfg.ORTInterThreads,
+			Provider:       cfg.ORTProvider,
+		})
+		pred = ort
+		// A model answering NaN/Inf again and again needs the operator
+		ready.AddCheck("model_output", ort.Health)
+	}
+	defer pred.Close()
+	// Models API (active model, reload/activate, versions, comparison); its
+	// routes are registered once the trainer is set up
+	modelsHandler := api.NewModelsHandler(pred, cfg.ModelsDir)
+	modelsHandler.SetStore(st)
+	modelsHandler.Startup(func(err error) { ready.Done(api.ReadyModel, err) })
 
-	// Start the image fetcher in background + upsert new images into DB
-	fetch := fetcher.New(cfg.AllSkyURL, cfg.ImagesDir, cfg.PollInterval, func(ev fetcher.NewImageEvent) {
-		// Use filename (without .jpg) as image_id; stable + human readable
-		imageID := ev.Filename
-		if len(imageID) > 4 && imageID[len(imageID)-4:] == ".jpg" {
-			imageID = imageID[:len(imageID)-4]
-		}
+	n, _ := st.CountLabeled(ctx)
+	log.Printf("SkyClf %s starting addr=%s mode=%s poll=%s allsky=%s fetch_mode=%s labeled=%d", buildinfo.String(), cfg.Addr, cfg.Mode, cfg.PollInterval, cfg.AllSkyURL, cfg.FetchMode, n)
 
-		if err := st.UpsertImage(imageID, ev.Path, ev.SHA256Hex, ev.FetchedAt, int64(ev.SizeBytes)); err != nil {
-			log.Printf("db: upsert image error: %v", err)
+	// Start the image fetcher (or, in no-fetch and watch mode, the directory scanner) in
+	// background + upsert new images into DB
+	// Auto-predictions queue behind interactive ones and are dropped (and
+	// caught up later) when a burst of frames outpaces the model
+	var predQueue *ingest.PredictQueue
+	if cfg.PredictOnIngest {
+		predQueue = ingest.NewPredictQueue(st, pred, cfg.PredictWorkers, cfg.PredictQueueLimit)
+		predQueue.SetThermal(thermalMon)
+		go predQueue.Start(ctx)
+	}
+	// Camera clock check: capture vs fetch time of every frame, warned about
+	// in the log and /api/health past SKYCLF_CLOCK_SKEW_WARN
+	clockSkew := ingest.NewClockSkew(st, cfg.ClockSkewWarn)
+	clockSkew.Refresh(ctx)
+	healthHandler.SetClockSkew(clockSkew)
+	ing := ingest.New(st, pred, ingest.Options{Predict: cfg.PredictOnIngest, Queue: predQueue, Thermal: thermalMon, ClockSkew: clockSkew})
+	var (
+		fetch   *fetcher.Fetcher // nil unless fetching
+		scanner *ingest.Scanner  // only when not fetching
+	)
+	if cfg.Fetching() {
+		// Saved frames go out on a bus so more observers can subscribe
+		// without slowing down the fetch loop; ingestion must see every
+		// frame (or it stays on disk without a row), so it is required,
+		// and drains its queue on shutdown (before the store closes)
+		events := fetcher.NewBus()
+		defer events.Close()
+		events.SubscribeRequired("ingest", fetcher.DefaultSubscriberBuffer, ing.HandleNewImage)
+		fetch = fetcher.NewWithBus(cfg.AllSkyURL, cfg.ImagesDir, cfg.PollInterval, events)
+
+		fetchMode, err := fetcher.ParseMode(cfg.FetchMode)
+		if err != nil {
+			log.Fatalf("config error: %v", err)
+		}
+		fetchLoc, _ := time.LoadLocation(cfg.FetchTZ) // validated in config.Load
+		fetch.SetMode(fetchMode, fetchLoc)
+		if fetchMode == fetcher.ModeCapture {
+			fetch.SetCapture(cfg.CaptureCmd, cfg.CaptureTimeout)
+		}
+		fetch.SetSidecarSuffix(cfg.SidecarSuffix)
+		fetch.SetFormats(cfg.ImageFormats)
+		fetch.SetStorageMonitor(storageMon)
+		fetch.SetPerceptualHash(cfg.PerceptualHash)
+		fetch.SetStoreTransform(fetcher.StoreTransform{MaxDim: cfg.StoreMaxDim, JPEGQuality: cfg.StoreJPEGQuality})
+		fetch.SetStaleSkew(cfg.StaleFrameSkew)
+		fetch.SetStateStore(st)
+		healthHandler.SetFetcher(fetch)
+		if cfg.PollAdaptive {
+			fetch.SetAdaptivePolling(cfg.PollMin, cfg.PollMax)
+		}
+		if cfg.PollDayInterval > 0 {
+			fetch.SetDaytimeInterval(cfg.PollDayInterval, cfg.SiteLat, cfg.SiteLon)
 		}
-	})
 
-	// Enable auto-cleanup: delete oldest unlabeled images when count exceeds 30,000
-	fetch.SetAutoCleanup(st, 30000, func(result store.CleanupResult) {
-		log.Printf("auto-cleanup completed: deleted %d images", result.DeletedCount)
-	})
+		// Enable auto-cleanup: delete oldest unlabeled images when count exceeds 30,000
+		fetch.SetAutoCleanup(st, 30000, func(result store.CleanupResult) {
+			log.Printf("auto-cleanup completed: deleted %d images", result.DeletedCount)
+		})
 
-	go func() {
-		if err := fetch.Start(ctx); err != nil && err != context.Canceled {
-			log.Printf("fetcher error: %v", err)
+		go func() {
+			if err := fetch.Start(ctx); err != nil && err != context.Canceled {
+				log.Printf("fetcher error: %v", err)
+			}
+		}()
+		go func() {
+			select {
+			case <-fetch.Attempted():
+				ready.Done(api.ReadyFetch, nil)
+			case <-ctx.Done():
+			}
+		}()
+	} else {
+		// No camera: pick up whatever appears in the images directory
+		scanner = ingest.NewScanner(ing, cfg.ImagesDir, cfg.ScanInterval)
+		scanner.SetPerceptualHash(cfg.PerceptualHash)
+		if cfg.Mode == "watch" {
+			go ingest.NewWatcher(scanner).Start(ctx)
+		} else {
+			go scanner.Start(ctx)
 		}
-	}()
+		go func() {
+			select {
+			case <-scanner.Scanned():
+				ready.Done(api.ReadyFetch, nil)
+			case <-ctx.Done():
+			}
+		}()
+	}
 
 	// Set up HTTP routes
-	mux := http.NewServeMux()
 
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("ok"))
-	})
+	// Fetcher status
+	fetcherHandler := api.NewFetcherHandler(fetch)
+	fetcherHandler.SetScanner(scanner)
+	fetcherHandler.RegisterRoutes(mux)
 
 	// Images API
 	imagesHandler := api.NewImagesHandler(cfg.ImagesDir)
+	imagesHandler.SetStore(st)
 	imagesHandler.RegisterRoutes(mux)
 
 	// Serve latest image directly at /latest.jpg
 	mux.HandleFunc("GET /latest.jpg", imagesHandler.ServeLatestImage)
 
-	// (Optional) config debug endpoint
-	mux.HandleFunc("/api/config", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		w.Write([]byte("ok\n"))
-	})
+	// Effective configuration, secrets redacted
+	api.NewConfigHandler(cfg).RegisterRoutes(mux)
 
 	// Dataset API (images list + labels)
 	datasetHandler := api.NewDatasetHandler(st)
+	datasetHandler.SetClaimTTL(cfg.ClaimTTL)
 	datasetHandler.RegisterRoutes(mux)
 
-	latestHandler := api.NewLatestHandler(st, cfg.ImagesDir, cfg.ModelsDir, pred)
+	// Destructive and maintenance operations (audited, optionally token-protected)
+	adminHandler := api.NewAdminHandler(st, datasetHandler, cfg.ImagesDir, cfg.BackupDir)
+	adminHandler.SetToken(cfg.AdminToken)
+	adminHandler.SetClockSkew(clockSkew)
+	adminHandler.SetStorageLayout(storage.Layout{
+		DataDir:   cfg.DataDir,
+		ImagesDir: cfg.ImagesDir,
+		ModelsDir: cfg.ModelsDir,
+		BackupDir: cfg.BackupDir,
+		DBPath:    cfg.LabelsDBPath,
+	})
+	adminHandler.RegisterRoutes(mux)
+
+	// Label sidecars next to the images for other tools (optional)
+	if cfg.LabelSidecars {
+		sidecars := labelsidecar.New(st)
+		st.SetLabelObserver(sidecars.Notify)
+		adminHandler.SetSidecarWriter(sidecars)
+		go sidecars.Start(ctx)
+		log.Printf("label sidecars enabled")
+	}
+
+	// Image quota: evicts the oldest frames; started once the trainer is known
+	quota := retention.NewQuota(st, store.Quota{
+		MaxImages:    cfg.QuotaMaxImages,
+		MaxBytes:     int64(cfg.QuotaMaxGB * 1e9),
+		EvictLabeled: cfg.QuotaEvictLabeled,
+	}, cfg.QuotaInterval)
+	quota.SetNotifier(webhook.New(cfg.WebhookURL))
+	quota.SetStorageMonitor(storageMon)
+	adminHandler.SetQuota(quota)
+
+	// After a storage outage: drop rows for frames lost meanwhile, fetch right
+	// away and retry a model load that failed because the share was gone.
+	if storageMon != nil {
+		storageMon.SetOnRecover(func() {
+			if res, err := adminHandler.Reconcile(ctx, false); err != nil {
+				log.Printf("storage: reconcile: %v", err)
+			} else {
+				log.Printf("storage: reconcile: %d missing files, %d orphan files", res.MissingFiles, res.OrphanFiles)
+			}
+			if fetch != nil {
+				fetch.PollNow()
+			} else {
+				scanner.ScanNow()
+			}
+			if ort != nil && ort.LoadError() != nil {
+				if err := pred.Reload(cfg.ModelsDir, ""); err != nil {
+					log.Printf("storage: model reload: %v", err)
+				}
+			}
+		})
+	}
+
+	// Bounding-box annotations (meteor regions etc.)
+	api.NewAnnotationsHandler(st).RegisterRoutes(mux)
+
+	// Near-duplicate report and perceptual hash backfill
+	dedupHandler := api.NewDedupHandler(st)
+	dedupHandler.RegisterRoutes(mux)
+	if cfg.PerceptualHash {
+		dedupHandler.StartBackfill()
+	}
+
+	// Label sync with a peer instance (optional)
+	if cfg.SyncPeerURL != "" {
+		syncer := labelsync.New(st, cfg.SyncPeerURL, cfg.InstanceName, cfg.SyncInterval, cfg.SyncDryRun)
+		api.NewLabelSyncHandler(syncer).RegisterRoutes(mux)
+		go func() {
+			if err := syncer.Start(ctx); err != nil && err != context.Canceled {
+				log.Printf("labelsync error: %v", err)
+			}
+		}()
+		log.Printf("label sync enabled: peer=%s interval=%s dry_run=%t", cfg.SyncPeerURL, cfg.SyncInterval, cfg.SyncDryRun)
+	}
+
+	// Eval API (calibration)
+	api.NewEvalHandler(st, ort).RegisterRoutes(mux)
+
+	// Inference latency percentiles from stored predictions
+	metricsHandler := api.NewMetricsHandler(st)
+	metricsHandler.SetPredictQueue(predQueue)
+	metricsHandler.RegisterRoutes(mux)
+
+	// Horizon mask/crop before inference (persisted in settings)
+	preprocessHandler := api.NewPreprocessHandler(st, ort)
+	if err := preprocessHandler.Restore(ctx); err != nil {
+		log.Printf("preprocess settings: %v", err)
+	}
+	preprocessHandler.RegisterRoutes(mux)
+
+	// Explainability (occlusion saliency)
+	api.NewExplainHandler(st, ort).RegisterRoutes(mux)
+
+	latestHandler := api.NewLatestHandler(st, cfg.ImagesDir, pred)
+	latestHandler.SetPredictionTTL(cfg.PollInterval) // no new frame to classify before the next poll
+	latestHandler.SetPredictionTimeout(cfg.LatestPredictTimeout)
+	latestHandler.SetPredictQueue(predQueue)
+	latestHandler.SetLocation(displayLoc)
 	latestHandler.RegisterRoutes(mux)
 
+	// gRPC API (optional) over the same store, predictor and queue
+	var grpcServer *api.GRPCServer
+	if cfg.GRPCAddr != "" {
+		grpcServer = api.NewGRPCServer(st, latestHandler, auth)
+		go func() {
+			if err := grpcServer.Serve(cfg.GRPCAddr); err != nil {
+				log.Fatalf("grpc server error: %v", err)
+			}
+		}()
+		log.Printf("grpc api listening on %s", cfg.GRPCAddr)
+	}
+
 	// Trainer API (start/stop/status)
 	tr, err := trainer.NewTrainer(cfg.TrainerContainer)
 	if err != nil {
 		log.Printf("trainer init warning (training disabled): %v", err)
+		tr = nil
 	} else {
 		defer tr.Close()
-
-		// Auto-reload model when training completess
-		tr.OnComplete = func() {
-			log.Printf("trainer: reloading models after training completion")
-			if pred != nil {
-				if err := pred.Reload(cfg.ModelsDir, ""); err != nil {
-					log.Printf("trainer: model reload error: %v", err)
+		tr.SetLogDir(filepath.Join(cfg.DataDir, "train-logs"))
+		tr.SetSharedDir(filepath.Join(cfg.DataDir, "train"))
+		tr.SetEnvPrefixes(cfg.TrainEnvPrefixes)
+		tr.SetVolumeCheck(trainer.VolumeCheck{
+			MinFreeBytes: int64(cfg.TrainMinFreeGB * 1e9),
+			Dirs:         []string{cfg.ModelsDir, cfg.ImagesDir},
+			PathMap:      cfg.TrainPathMap,
+		})
+		tr.SetStartLimit(trainer.StartLimit{
+			MinInterval: cfg.TrainMinInterval,
+			Load: func(ctx context.Context) (trainer.StartHistory, error) {
+				var h trainer.StartHistory
+				_, err := st.GetSetting(ctx, store.SettingTrainStarts, &h)
+				return h, err
+			},
+			Save: func(ctx context.Context, h trainer.StartHistory) error {
+				return st.SetSetting(ctx, store.SettingTrainStarts, h)
+			},
+		})
+		if thermalMon != nil {
+			tr.Guard = thermalMon.Check
+		}
+		tr.ClassCounts = func(ctx context.Context, snapshot string) (map[string]int, error) {
+			if snapshot != "" {
+				return snapshotClassCounts(ctx, st, snapshot)
+			}
+			stats, err := st.CountStats(ctx)
+			if err != nil {
+				return nil, err
+			}
+			// Weigh by what the model trains on once splits are assigned
+			if train := stats.BySplit[store.SplitTrain]; len(train) > 0 {
+				return train, nil
+			}
+			return stats.ByClass, nil
+		}
+		// The test split never reaches the trainer
+		tr.Filelist = func(ctx context.Context, w io.Writer, snapshot string, oversample *export.Oversample) (*export.OversampleResult, error) {
+			if err := checkSnapshot(ctx, st, snapshot); err != nil {
+				return nil, err
+			}
+			opts := export.Options{Filter: store.ImageFilter{Snapshot: snapshot}, Weather: cfg.WeatherURL != ""}
+			items, err := export.Select(ctx, st, opts)
+			if err != nil {
+				return nil, err
+			}
+			items = slices.DeleteFunc(items, func(it store.ImageWithLabel) bool { return it.Split == store.SplitTest })
+			if cfg.TrainConvertWebP {
+				if err := export.ConvertWebP(items, filepath.Join(cfg.DataDir, "train", "jpeg")); err != nil {
+					return nil, err
 				}
 			}
+			var res *export.OversampleResult
+			if oversample != nil {
+				var r export.OversampleResult
+				items, r = export.ApplyOversample(items, *oversample)
+				res = &r
+			}
+			return res, export.WriteCSV(w, items, cfg.WeatherURL != "")
+		}
+
+		tr.LatestVersion = func() (string, error) {
+			vers, _, err := infer.ListVersions(cfg.ModelsDir)
+			if err != nil || len(vers) == 0 {
+				return "", err
+			}
+			return vers[len(vers)-1], nil
 		}
 
+		// Publish and load the new model when training completes
+		tr.OnComplete = func(run trainer.Run) { modelsHandler.OnTrainComplete(ctx, run) }
+
 		trainerHandler := api.NewTrainerHandler(tr)
 		trainerHandler.RegisterRoutes(mux)
 		log.Printf("trainer ready: container=%s", cfg.TrainerContainer)
 	}
 
-	// Models API (reload endpoint)
-	mux.HandleFunc("POST /api/models/reload", func(w http.ResponseWriter, r *http.Request) {
-		if pred == nil {
-			http.Error(w, `{"error":"predictor not initialized"}`, http.StatusInternalServerError)
-			return
-		}
-		version := r.URL.Query().Get("version")
-		if err := pred.Reload(cfg.ModelsDir, version); err != nil {
-			http.Error(w, fmt.Sprintf(`{"error":"%s"}`, err.Error()), http.StatusInternalServerError)
-			return
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte(`{"status":"ok","message":"models reloaded"}`))
-	})
+	if tr != nil {
+		quota.InUse = tr.FilelistPaths // never pull images out from under a run
+		adminHandler.SetInUse(tr.FilelistPaths)
+	}
+	if cfg.QuotaMaxImages > 0 || cfg.QuotaMaxGB > 0 {
+		go quota.Start(ctx)
+		log.Printf("image quota: max_images=%d max_gb=%g evict_labeled=%t", cfg.QuotaMaxImages, cfg.QuotaMaxGB, cfg.QuotaEvictLabeled)
+	}
 
-	mux.HandleFunc("GET /api/models", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		if pred == nil {
-			w.Write([]byte(`{"active":null}`))
-			return
-		}
-		data, err := pred.ModelJSON()
+	// Completed training runs and their curves, with or without the trainer
+	trainRunsHandler := api.NewTrainRunsHandler(st)
+	trainRunsHandler.SetMinStartInterval(cfg.TrainMinInterval)
+	trainRunsHandler.RegisterRoutes(mux)
+
+	modelsHandler.SetCompare(api.NewCompareHandler(st, ort, tr, cfg.ModelsDir))
+	modelsHandler.SetAdmin(adminHandler)
+	modelsHandler.RegisterRoutes(mux)
+	replayHandler := api.NewReplayHandler(st, ort, tr, cfg.ModelsDir)
+	replayHandler.SetPredictQueue(predQueue)
+	replayHandler.RegisterRoutes(mux)
+
+	// Weather at the site; its failures only log
+	var weatherPoller *weather.Poller
+	if cfg.WeatherURL != "" {
+		weatherPoller, err = weather.NewPoller(st, cfg.WeatherURL, cfg.SiteLat, cfg.SiteLon, cfg.WeatherInterval)
 		if err != nil {
-			http.Error(w, `{"error":"json encode"}`, http.StatusInternalServerError)
-			return
+			log.Fatalf("weather: %v", err)
 		}
-		w.Write(data)
-	})
+		go weatherPoller.Start(ctx)
+	}
 
-	// Serve frontend from ui/dist (built Vue app)
+	// Dashboard summary (each section fails independently)
+	summaryHandler := api.NewSummaryHandler(st, fetch, ort, tr, cfg.ImagesDir)
+	summaryHandler.SetPredictQueue(predQueue)
+	summaryHandler.SetWeather(weatherPoller)
+	summaryHandler.SetThermal(thermalMon)
+	summaryHandler.RegisterRoutes(mux)
+
+	// Alerting rules on the debounced sky state and system events
+	mqttPub, err := mqtt.New(cfg.MQTTURL)
+	if err != nil {
+		log.Fatalf("config error: %v", err)
+	}
+	ruleEngine := rules.NewEngine(st, displayLoc, cfg.WebhookURL, mqttPub)
+	if fetch != nil {
+		ruleEngine.SetProbe(rules.EventFetchDegraded, func() bool { return fetch.Status().ConsecutiveFailures >= rules.DegradedFailures })
+	}
+	if storageMon != nil {
+		ruleEngine.SetProbe(rules.EventStorageUnavailable, func() bool { return storageMon.Check() != nil })
+	}
+	if tr != nil {
+		ruleEngine.SetProbe(rules.EventTrainingFailed, func() bool { return tr.State() == "failed" })
+	}
+	if thermalMon != nil {
+		ruleEngine.SetProbe(rules.EventThermalHot, func() bool { return thermalMon.Check() != nil })
+	}
+	ruleEngine.SetProbe(rules.EventClockSkew, func() bool { return clockSkew.Status().Warning })
+	go ruleEngine.Start(ctx)
+	api.NewRulesHandler(st, ruleEngine).RegisterRoutes(mux)
+
+	// Status badges for embedding elsewhere (public, from stored state only)
+	api.NewBadgeHandler(st, pred, tr).RegisterRoutes(mux)
+
+	// Night reports (cached per night, announced via webhook after dawn)
+	var site *report.Site
+	if cfg.HasSite {
+		site = &report.Site{Lat: cfg.SiteLat, Lon: cfg.SiteLon}
+	}
+	reports := report.NewGenerator(st, site)
+	api.NewReportsHandler(reports).RegisterRoutes(mux)
+	go reports.Schedule(ctx, webhook.New(cfg.WebhookURL))
+
+	// Serve frontend from ui/dist (built Vue app), else the copy embedded with -tags embedui
 	uiDir := "./ui/dist"
-	if _, err := os.Stat(uiDir); err == nil {
-		fs := http.FileServer(http.Dir(uiDir))
-		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-			// Serve index.html for SPA routes (non-API, non-file requests)
-			path := r.URL.Path
-			if path != "/" && !strings.HasPrefix(path, "/api/") && !strings.HasPrefix(path, "/latest") {
-				// Check if file exists
-				if _, err := os.Stat(uiDir + path); os.IsNotExist(err) {
-					// SPA fallback: serve index.html
-					http.ServeFile(w, r, uiDir+"/index.html")
-					return
-				}
-			}
-			fs.ServeHTTP(w, r)
-		})
-		log.Printf("serving frontend from %s", uiDir)
+	if uiHandler, src := api.UIHandler(uiDir, ui.Dist()); uiHandler != nil {
+		mux.Handle("/", uiHandler)
+		log.Printf("serving frontend from %s", src)
 	} else {
-		log.Printf("frontend not found at %s (run 'npm run build' in ui/)", uiDir)
+		log.Printf("frontend not found at %s (run 'npm run build' in ui/, or build with -tags embedui)", uiDir)
 	}
 
-	// Start server
-	server := &http.Server{Addr: cfg.Addr, Handler: mux}
-	go func() {
-		<-ctx.Done()
-		log.Println("shutting down server...")
-		_ = server.Close()
-	}()
+	<-ctx.Done()
+	log.Println("shutting down server...")
+	if grpcServer != nil {
+		grpcServer.Stop(5 * time.Second)
+	}
+	_ = server.Close()
+}
 
-	log.Fatal(server.ListenAndServe())
+// checkSnapshot returns an error if name is set but no such dataset snapshot
+// exists, so a mistyped name fails the run instead of training on nothing.
+func checkSnapshot(ctx context.Context, st *store.Store, name string) error {
+	if name == "" {
+		return nil
+	}
+	snap, err := st.GetSnapshot(ctx, name)
+	if err != nil {
+		return err
+	}
+	if snap == nil {
+		return fmt.Errorf("snapshot %s not found", name)
+	}
+	return nil
+}
+
+// snapshotClassCounts counts the labels of a snapshot per class like the
+// live ClassCounts: the train split once splits are assigned, else all.
+func snapshotClassCounts(ctx context.Context, st *store.Store, name string) (map[string]int, error) {
+	if err := checkSnapshot(ctx, st, name); err != nil {
+		return nil, err
+	}
+	items, err := export.Select(ctx, st, export.Options{Filter: store.ImageFilter{Snapshot: name}})
+	if err != nil {
+		return nil, err
+	}
+	all, train := map[string]int{}, map[string]int{}
+	for _, it := range items {
+		all[*it.Skystate]++
+		if it.Split == store.SplitTrain {
+			train[*it.Skystate]++
+		}
+	}
+	if len(train) > 0 {
+		return train, nil
+	}
+	return all, nil
 }
@@ -4,9 +4,13 @@ go 1.25.2
 
 require (
 	github.com/docker/docker v28.2.2+incompatible
+	github.com/fsnotify/fsnotify v1.10.1
 	github.com/joho/godotenv v1.5.1
 	github.com/yalue/onnxruntime_go v1.24.0
+	golang.org/x/crypto v0.46.0
 	golang.org/x/image v0.34.0
+	google.golang.org/grpc v1.77.0
+	google.golang.org/protobuf v1.36.10
 	modernc.org/sqlite v1.40.1
 )
 
@@ -42,8 +46,11 @@ require (
 	go.opentelemetry.io/otel/metric v1.39.0 // indirect
 	go.opentelemetry.io/otel/trace v1.39.0 // indirect
 	golang.org/x/exp v0.0.0-20250620022241-b7579e27df2b // indirect
+	golang.org/x/net v0.47.0 // indirect
 	golang.org/x/sys v0.39.0 // indirect
+	golang.org/x/text v0.32.0 // indirect
 	golang.org/x/time v0.14.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20251202230838-ff82c1b0f217 // indirect
 	gotest.tools/v3 v3.5.2 // indirect
 	modernc.org/libc v1.66.10 // indirect
 	modernc.org/mathutil v1.7.1 // indirect
@@ -0,0 +1,442 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/fetcher"
+	"github.com/SkyClf/SkyClf/internal/ingest"
+	"github.com/SkyClf/SkyClf/internal/labelsidecar"
+	"github.com/SkyClf/SkyClf/internal/retention"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// AdminHandler groups destructive and maintenance operations under /api/admin/
+// and records every invocation in the audit log. When a token is set, all
+// admin routes require "Authorization: Bearer <token>".
+type AdminHandler struct {
+	st        *store.Store
+	ds        *DatasetHandler
+	imagesDir string
+	backupDir string
+	token     string
+	quota     *retention.Quota  // nil = no image quota
+	clockSkew *ingest.ClockSkew // nil = not tracked
+	storage   storageAdmin
+
+	sidecars    *labelsidecar.Writer // nil = label sidecars disabled
+	sidecarBusy sync.Mutex           // one backfill at a time
+}
+
+func NewAdminHandler(st *store.Store, ds *DatasetHandler, imagesDir, backupDir string) *AdminHandler {
+	return &AdminHandler{st: st, ds: ds, imagesDir: imagesDir, backupDir: backupDir}
+}
+
+// SetToken requires token on every admin route ("" = no token required).
+func (h *AdminHandler) SetToken(token string) {
+	h.token = token
+}
+
+// SetQuota reports the image quota job in GET /api/admin/retention and lets
+// POST /api/admin/retention/quota run it.
+func (h *AdminHandler) SetQuota(q *retention.Quota) {
+	h.quota = q
+}
+
+func (h *AdminHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/admin/audit", h.authorized(h.handleAudit))
+	mux.HandleFunc("POST /api/admin/labels/reset", h.audited("labels.reset", h.ds.handleClearLabels))
+	mux.HandleFunc("POST /api/admin/images/cleanup", h.audited("images.cleanup", h.ds.handleCleanupImages))
+	mux.HandleFunc("DELETE /api/admin/days/{date}", h.audited("days.delete", h.ds.handleDeleteDay))
+	mux.HandleFunc("POST /api/admin/reconcile", h.audited("reconcile", h.handleReconcile))
+	mux.HandleFunc("POST /api/admin/maintenance", h.audited("maintenance", h.handleMaintenance))
+	mux.HandleFunc("GET /api/admin/storage/report", h.authorized(h.handleStorageReport))
+	mux.HandleFunc("POST /api/admin/storage/clean", h.audited("storage.clean", h.handleStorageClean))
+	mux.HandleFunc("POST /api/admin/sidecars/backfill", h.audited("sidecars.backfill", h.handleSidecarBackfill))
+	mux.HandleFunc("GET /api/admin/backups", h.authorized(h.handleListBackups))
+	mux.HandleFunc("POST /api/admin/backup", h.audited("backup", h.handleBackup))
+	mux.HandleFunc("POST /api/admin/restore", h.audited("restore", h.handleRestore))
+	mux.HandleFunc("POST /api/admin/retention", h.audited("retention", h.handleRetention))
+	mux.HandleFunc("GET /api/admin/retention", h.authorized(h.handleRetentionStatus))
+	mux.HandleFunc("POST /api/admin/retention/quota", h.audited("retention.quota", h.handleQuota))
+	mux.HandleFunc("POST /api/admin/purge", h.audited("purge", h.handlePurge))
+	mux.HandleFunc("GET /api/admin/clock-skew", h.authorized(h.handleClockSkew))
+	mux.HandleFunc("PUT /api/admin/clock-skew", h.audited("clock_skew.correction", h.handleSetClockCorrection))
+	mux.HandleFunc("DELETE /api/images/{id}", h.audited("images.archive", h.ds.handleArchiveImage))
+	mux.HandleFunc("POST /api/images/{id}/unarchive", h.audited("images.unarchive", h.ds.handleUnarchiveImage))
+
+	// Legacy paths used by the UI; same handlers, same audit and token.
+	mux.HandleFunc("POST /api/labels/reset", h.audited("labels.reset", h.ds.handleClearLabels))
+	mux.HandleFunc("POST /api/images/cleanup", h.audited("images.cleanup", h.ds.handleCleanupImages))
+	mux.HandleFunc("DELETE /api/dataset/days/{date}", h.audited("days.delete", h.ds.handleDeleteDay))
+}
+
+// identity checks the admin token and returns who the caller is. A UI login
+// or API token alone doesn't pass while an admin token is set; without one,
+// the logged-in user is only recorded.
+func (h *AdminHandler) identity(r *http.Request) (string, bool) {
+	if h.token == "" {
+		if who, ok := identityFrom(r.Context()); ok {
+			return who, true
+		}
+		return "anonymous", true
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(h.token)) != 1 {
+		return "anonymous", false
+	}
+	return "admin", true
+}
+
+// authorized wraps read-only admin routes with the token check.
+func (h *AdminHandler) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := h.identity(r); !ok {
+			writeError(w, http.StatusUnauthorized, "admin token required")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// audited wraps a destructive route with the token check and writes an audit
+// row once the handler has finished (or the caller was denied).
+func (h *AdminHandler) audited(action string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := h.identity(r)
+		entry := store.AuditEntry{
+			Action:     action,
+			Params:     auditParams(r),
+			RemoteAddr: r.RemoteAddr,
+			Identity:   identity,
+			CreatedAt:  time.Now(),
+		}
+		if !ok {
+			entry.Status, entry.Outcome = http.StatusUnauthorized, store.AuditDenied
+			h.record(r.Context(), entry)
+			writeError(w, http.StatusUnauthorized, "admin token required")
+			return
+		}
+
+		rec := &auditRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+		entry.Status, entry.Outcome = rec.status, store.AuditOK
+		if rec.status >= 400 {
+			entry.Outcome = store.AuditError
+			entry.Error = strings.TrimSpace(rec.body.String())
+		}
+		h.record(r.Context(), entry)
+	}
+}
+
+// record writes the audit row even if the client has gone away.
+func (h *AdminHandler) record(ctx context.Context, e store.AuditEntry) {
+	if err := h.st.RecordAudit(context.WithoutCancel(ctx), e); err != nil {
+		log.Printf("api: audit %s: %v", e.Action, err)
+	}
+}
+
+// auditParams collects the query and path parameters of r.
+func auditParams(r *http.Request) map[string]string {
+	params := map[string]string{}
+	for k, v := range r.URL.Query() {
+		params[k] = strings.Join(v, ",")
+	}
+	for _, k := range []string{"date", "id", "version"} {
+		if v := r.PathValue(k); v != "" {
+			params[k] = v
+		}
+	}
+	return params
+}
+
+// auditRecorder captures the status and the start of an error body.
+type auditRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+const maxAuditError = 512
+
+func (a *auditRecorder) WriteHeader(code int) {
+	a.status = code
+	a.ResponseWriter.WriteHeader(code)
+}
+
+func (a *auditRecorder) Write(b []byte) (int, error) {
+	if a.status >= 400 && a.body.Len() < maxAuditError {
+		a.body.Write(b[:min(len(b), maxAuditError-a.body.Len())])
+	}
+	return a.ResponseWriter.Write(b)
+}
+
+func (a *auditRecorder) Unwrap() http.ResponseWriter {
+	return a.ResponseWriter
+}
+
+// GET /api/admin/audit?limit=100&action= - audit log, newest first
+func (h *AdminHandler) handleAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := 100
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 10000 {
+			writeError(w, http.StatusBadRequest, "invalid limit (1-10000)")
+			return
+		}
+		limit = n
+	}
+	entries, err := h.st.ListAudit(r.Context(), limit, strings.TrimSpace(q.Get("action")))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"count": len(entries), "items": entries})
+}
+
+// maxReportedOrphans caps the orphan file list in reconcile responses.
+const maxReportedOrphans = 100
+
+// ReconcileResult describes the differences between the images table and ImagesDir.
+type ReconcileResult struct {
+	DryRun         bool     `json:"dry_run"`
+	Checked        int      `json:"checked"`
+	MissingFiles   int      `json:"missing_files"`   // rows whose file is gone
+	RemovedRows    int      `json:"removed_rows"`    // of those, rows deleted
+	OrphanFiles    int      `json:"orphan_files"`    // image files without a row
+	OrphanExamples []string `json:"orphan_examples"` // first few orphan names
+}
+
+// Reconcile deletes image rows whose file no longer exists (unless dryRun) and
+// reports image files on disk that have no row. Orphan files are never deleted.
+func (h *AdminHandler) Reconcile(ctx context.Context, dryRun bool) (ReconcileResult, error) {
+	res := ReconcileResult{DryRun: dryRun, OrphanExamples: []string{}}
+	images, err := h.st.ListImageRefs(ctx)
+	if err != nil {
+		return res, err
+	}
+	known := make(map[string]bool, len(images))
+	for _, img := range images {
+		res.Checked++
+		known[filepath.Clean(img.Path)] = true
+		if _, err := os.Stat(img.Path); !errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		res.MissingFiles++
+		if dryRun {
+			continue
+		}
+		if err := h.st.DeleteImage(ctx, img.ID); err != nil {
+			return res, err
+		}
+		res.RemovedRows++
+	}
+
+	err = filepath.WalkDir(h.imagesDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !fetcher.IsImageFile(p) || known[filepath.Clean(p)] {
+			return nil
+		}
+		res.OrphanFiles++
+		if len(res.OrphanExamples) < maxReportedOrphans {
+			res.OrphanExamples = append(res.OrphanExamples, filepath.Base(p))
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return res, err
+	}
+	return res, nil
+}
+
+// POST /api/admin/reconcile?dry_run=1 - drop rows for missing files, report orphan files
+func (h *AdminHandler) handleReconcile(w http.ResponseWriter, r *http.Request) {
+	res, err := h.Reconcile(r.Context(), isTrue(r.URL.Query().Get("dry_run")))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// POST /api/admin/maintenance?vacuum=1 - integrity check, WAL checkpoint, ANALYZE (and VACUUM)
+func (h *AdminHandler) handleMaintenance(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	problems, err := h.st.IntegrityCheck(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(problems) > 0 {
+		// optimizing a damaged file can make things worse; restore a backup instead
+		writeJSON(w, http.StatusConflict, map[string]any{"ok": false, "integrity": problems})
+		return
+	}
+	vacuum := isTrue(r.URL.Query().Get("vacuum"))
+	if err := h.st.Optimize(r.Context(), vacuum); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":          true,
+		"integrity":   "ok",
+		"vacuumed":    vacuum,
+		"duration_ms": time.Since(start).Milliseconds(),
+	})
+}
+
+type backupInfo struct {
+	Name      string    `json:"name"`
+	SizeBytes int64     `json:"size_bytes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GET /api/admin/backups - backups in the backup directory, newest first
+func (h *AdminHandler) handleListBackups(w http.ResponseWriter, r *http.Request) {
+	entries, err := os.ReadDir(h.backupDir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	out := []backupInfo{}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".db" {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		out = append(out, backupInfo{Name: e.Name(), SizeBytes: fi.Size(), CreatedAt: fi.ModTime().UTC()})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name > out[j].Name })
+	writeJSON(w, http.StatusOK, map[string]any{"count": len(out), "items": out})
+}
+
+// POST /api/admin/backup - write a consistent copy of the database to the backup directory
+func (h *AdminHandler) handleBackup(w http.ResponseWriter, r *http.Request) {
+	if err := os.MkdirAll(h.backupDir, 0o755); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	name := "labels-" + time.Now().UTC().Format("20060102-150405") + ".db"
+	dest := filepath.Join(h.backupDir, name)
+	if err := h.st.Backup(r.Context(), dest); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var size int64
+	if fi, err := os.Stat(dest); err == nil {
+		size = fi.Size()
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{"ok": true, "name": name, "size_bytes": size})
+}
+
+// POST /api/admin/restore?name=labels-....db - stage a backup to replace the
+// database on the next restart (the live database is never swapped underneath
+// running requests)
+func (h *AdminHandler) handleRestore(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" || name != filepath.Base(name) || filepath.Ext(name) != ".db" {
+		writeError(w, http.StatusBadRequest, "name must be a backup file name from /api/admin/backups")
+		return
+	}
+	path := filepath.Join(h.backupDir, name)
+	if _, err := os.Stat(path); err != nil {
+		writeError(w, http.StatusNotFound, "backup not found")
+		return
+	}
+	if err := h.st.StageRestore(r.Context(), path); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":               true,
+		"name":             name,
+		"restart_required": true,
+		"message":          "restore staged; it is applied when the server restarts",
+	})
+}
+
+// POST /api/admin/retention?days=N&dry_run=1 - delete unlabeled images older
+// than N days, rows and files; labeled images are always kept
+func (h *AdminHandler) handleRetention(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	days, err := strconv.Atoi(q.Get("days"))
+	if err != nil || days < 1 {
+		writeError(w, http.StatusBadRequest, "days must be a positive integer")
+		return
+	}
+	dryRun := isTrue(q.Get("dry_run"))
+	cutoff := time.Now().AddDate(0, 0, -days)
+
+	result, err := h.st.DeleteUnlabeledBefore(r.Context(), cutoff, dryRun)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	deletedFromDisk := 0
+	if !dryRun {
+		for _, path := range result.DeletedPaths {
+			if err := os.Remove(path); err == nil || errors.Is(err, fs.ErrNotExist) {
+				deletedFromDisk++
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":                true,
+		"dry_run":           dryRun,
+		"cutoff":            cutoff.UTC().Format(time.RFC3339),
+		"deleted_count":     result.DeletedCount,
+		"deleted_from_disk": deletedFromDisk,
+		"freed_bytes":       result.FreedBytes,
+	})
+}
+
+// GET /api/admin/retention - the image quota, current usage and evictions
+func (h *AdminHandler) handleRetentionStatus(w http.ResponseWriter, r *http.Request) {
+	if h.quota == nil {
+		writeJSON(w, http.StatusOK, retention.Status{})
+		return
+	}
+	status, err := h.quota.Status(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// POST /api/admin/retention/quota?dry_run=1 - enforce the image quota now
+func (h *AdminHandler) handleQuota(w http.ResponseWriter, r *http.Request) {
+	if h.quota == nil {
+		writeError(w, http.StatusConflict, "no image quota configured (SKYCLF_QUOTA_MAX_IMAGES, SKYCLF_QUOTA_MAX_GB)")
+		return
+	}
+	res, err := h.quota.Run(r.Context(), isTrue(r.URL.Query().Get("dry_run")))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func isTrue(v string) bool {
+	return v == "1" || strings.EqualFold(v, "true")
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/infer"
+)
+
+const (
+	// annotatedMaxDim bounds the longer side of /latest_annotated.jpg.
+	annotatedMaxDim = 1920
+	// annotatedQuality is its JPEG quality.
+	annotatedQuality = 85
+)
+
+// annotatedCache holds the last rendered /latest_annotated.jpg; the display
+// polling it asks for the same frame many times.
+type annotatedCache struct {
+	mu      sync.Mutex
+	key     string // image sha256 and what the banner says
+	body    []byte
+	modTime time.Time
+}
+
+// SetLocation sets the time zone of the timestamp in /latest_annotated.jpg
+// (default UTC).
+func (h *LatestHandler) SetLocation(loc *time.Location) {
+	h.loc = loc
+}
+
+// GET /latest_annotated.jpg - the newest frame with its prediction (sky state,
+// confidence, model version) and fetch time in a banner, for wall displays.
+// It answers an image in every state: "no model" without one, a dark frame
+// when there is no image or it can't be decoded (e.g. FITS). Caching headers
+// are those of /latest.jpg (Last-Modified, conditional GET).
+func (h *LatestHandler) handleLatestAnnotated(w http.ResponseWriter, r *http.Request) {
+	latest, err := h.st.GetLatest(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	loc := h.loc
+	if loc == nil {
+		loc = time.UTC
+	}
+	text, key, path := "no image", "none", ""
+	if latest != nil {
+		state := "no model"
+		version := h.modelVersion()
+		if version != "" {
+			state = "no prediction"
+			if pred, _, _ := h.getPrediction(r, latest.ID, latest.Path, latest.Format); pred != nil {
+				state = fmt.Sprintf("%s %.0f%%  ·  %s", pred.SkyState, pred.Confidence*100, pred.ModelVer)
+			}
+		}
+		text = fmt.Sprintf("%s  ·  %s", state, latest.FetchedAt.In(loc).Format("2006-01-02 15:04 MST"))
+		key = latest.SHA256 + "\x00" + text
+		path = latest.Path
+	}
+
+	h.annotated.mu.Lock()
+	defer h.annotated.mu.Unlock()
+	if h.annotated.key != key || h.annotated.body == nil {
+		body, err := renderAnnotated(path, text)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// The banner can change for the same frame (a prediction arriving),
+		// so the content is as new as the rendering
+		h.annotated.key, h.annotated.body, h.annotated.modTime = key, body, time.Now()
+	}
+	http.ServeContent(w, r, "latest_annotated.jpg", h.annotated.modTime, bytes.NewReader(h.annotated.body))
+}
+
+// renderAnnotated draws text over the frame at path, or over a dark
+// placeholder when path is empty or can't be decoded, as JPEG.
+func renderAnnotated(path, text string) ([]byte, error) {
+	var img *image.RGBA
+	if path != "" {
+		var err error
+		if img, err = infer.LoadScaled(path, annotatedMaxDim); err != nil {
+			log.Printf("latest_annotated: %v", err)
+		}
+	}
+	if img == nil {
+		img = image.NewRGBA(image.Rect(0, 0, 1280, 720))
+		draw.Draw(img, img.Bounds(), image.NewUniform(color.Gray{Y: 0x20}), image.Point{}, draw.Src)
+	}
+	if err := infer.DrawBanner(img, text); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: annotatedQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
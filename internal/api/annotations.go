@@ -0,0 +1,201 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/SkyClf/SkyClf/internal/store"
+	_ "golang.org/x/image/webp"
+)
+
+const maxAnnotationKindLen = 32
+
+// AnnotationsHandler manages bounding-box regions on images, e.g. meteor streaks.
+type AnnotationsHandler struct {
+	st *store.Store
+}
+
+// NewAnnotationsHandler creates a new annotations API handler
+func NewAnnotationsHandler(st *store.Store) *AnnotationsHandler {
+	return &AnnotationsHandler{st: st}
+}
+
+// RegisterRoutes registers the annotations API routes
+func (h *AnnotationsHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/images/{id}/annotations", h.list)
+	mux.HandleFunc("POST /api/images/{id}/annotations", h.create)
+	mux.HandleFunc("PUT /api/images/{id}/annotations/{aid}", h.update)
+	mux.HandleFunc("DELETE /api/images/{id}/annotations/{aid}", h.delete)
+}
+
+type annotationRequest struct {
+	Kind    string `json:"kind"`
+	X       int    `json:"x"`
+	Y       int    `json:"y"`
+	W       int    `json:"w"`
+	H       int    `json:"h"`
+	Labeler string `json:"labeler,omitempty"`
+}
+
+// GET /api/images/{id}/annotations - list annotation regions of an image
+func (h *AnnotationsHandler) list(w http.ResponseWriter, r *http.Request) {
+	imageID := r.PathValue("id")
+	img, err := h.st.GetImage(r.Context(), imageID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if img == nil {
+		writeError(w, http.StatusNotFound, "image not found")
+		return
+	}
+
+	anns, err := h.st.ListAnnotations(r.Context(), imageID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"image_id":    imageID,
+		"count":       len(anns),
+		"annotations": anns,
+	})
+}
+
+// POST /api/images/{id}/annotations - add a region {kind, x, y, w, h, labeler}
+func (h *AnnotationsHandler) create(w http.ResponseWriter, r *http.Request) {
+	a, status, err := h.decode(r)
+	if err != nil {
+		writeError(w, status, err.Error())
+		return
+	}
+
+	a, err = h.st.CreateAnnotation(r.Context(), a)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, a)
+}
+
+// PUT /api/images/{id}/annotations/{aid} - replace kind, box and labeler of a region
+func (h *AnnotationsHandler) update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("aid"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid annotation id")
+		return
+	}
+	a, status, err := h.decode(r)
+	if err != nil {
+		writeError(w, status, err.Error())
+		return
+	}
+	a.ID = id
+
+	found, err := h.st.UpdateAnnotation(r.Context(), a)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "annotation not found")
+		return
+	}
+	updated, err := h.st.GetAnnotation(r.Context(), a.ImageID, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// DELETE /api/images/{id}/annotations/{aid} - remove a region
+func (h *AnnotationsHandler) delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("aid"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid annotation id")
+		return
+	}
+
+	found, err := h.st.DeleteAnnotation(r.Context(), r.PathValue("id"), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "annotation not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// decode parses and validates an annotation body for the image in the path.
+// The box must lie within the image; on error the HTTP status to return is given.
+func (h *AnnotationsHandler) decode(r *http.Request) (store.Annotation, int, error) {
+	var req annotationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return store.Annotation{}, http.StatusBadRequest, fmt.Errorf("invalid json")
+	}
+	req.Kind = strings.TrimSpace(req.Kind)
+	if req.Kind == "" {
+		return store.Annotation{}, http.StatusBadRequest, fmt.Errorf("kind required")
+	}
+	if len(req.Kind) > maxAnnotationKindLen {
+		return store.Annotation{}, http.StatusBadRequest, fmt.Errorf("kind too long (max %d)", maxAnnotationKindLen)
+	}
+	if req.X < 0 || req.Y < 0 || req.W <= 0 || req.H <= 0 {
+		return store.Annotation{}, http.StatusBadRequest, fmt.Errorf("x and y must be >= 0, w and h > 0")
+	}
+
+	imageID := r.PathValue("id")
+	img, err := h.st.GetImage(r.Context(), imageID)
+	if err != nil {
+		return store.Annotation{}, http.StatusInternalServerError, err
+	}
+	if img == nil {
+		return store.Annotation{}, http.StatusNotFound, fmt.Errorf("image not found")
+	}
+
+	width, height := img.Width, img.Height
+	if width == 0 || height == 0 {
+		// Images fetched before dimensions were recorded: read the header
+		width, height, err = imageDimensions(img.Path)
+		if err != nil {
+			return store.Annotation{}, http.StatusInternalServerError, fmt.Errorf("image dimensions unknown: %w", err)
+		}
+	}
+	if req.X+req.W > width || req.Y+req.H > height {
+		return store.Annotation{}, http.StatusBadRequest,
+			fmt.Errorf("box exceeds image bounds (%s)", store.FormatResolution(width, height))
+	}
+
+	return store.Annotation{
+		ImageID: imageID,
+		Kind:    req.Kind,
+		X:       req.X,
+		Y:       req.Y,
+		W:       req.W,
+		H:       req.H,
+		Labeler: strings.TrimSpace(req.Labeler),
+	}, http.StatusOK, nil
+}
+
+func imageDimensions(path string) (int, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return 0, 0, err
+	}
+	return cfg.Width, cfg.Height, nil
+}
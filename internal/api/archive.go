@@ -0,0 +1,96 @@
+package api
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// defaultPurgeDays is how long archived images are kept by POST /api/admin/purge
+// unless ?days= says otherwise.
+const defaultPurgeDays = 30
+
+// handleArchiveImage archives (soft-deletes) one image: it disappears from
+// lists, latest, stats and training but keeps its label and file until purged.
+// DELETE /api/images/{id}
+func (h *DatasetHandler) handleArchiveImage(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	img, err := h.st.GetImage(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if img == nil {
+		http.Error(w, "image not found", http.StatusNotFound)
+		return
+	}
+	if _, err := h.st.ArchiveImages(r.Context(), []string{id}, time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "id": id, "archived": true})
+}
+
+// handleUnarchiveImage restores an archived image.
+// POST /api/images/{id}/unarchive
+func (h *DatasetHandler) handleUnarchiveImage(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	img, err := h.st.GetImage(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if img == nil {
+		http.Error(w, "image not found (purged images can't be restored)", http.StatusNotFound)
+		return
+	}
+	restored, err := h.st.UnarchiveImage(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "id": id, "restored": restored})
+}
+
+// POST /api/admin/purge?days=N&dry_run=1 - permanently delete images archived
+// more than N days ago (default 30), rows and files
+func (h *AdminHandler) handlePurge(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	days := defaultPurgeDays
+	if raw := q.Get("days"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "days must be a non-negative integer")
+			return
+		}
+		days = n
+	}
+	dryRun := isTrue(q.Get("dry_run"))
+	cutoff := time.Now().AddDate(0, 0, -days)
+
+	result, err := h.st.PurgeArchived(r.Context(), cutoff, dryRun)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	deletedFromDisk := 0
+	if !dryRun {
+		for _, path := range result.DeletedPaths {
+			if err := os.Remove(path); err == nil || errors.Is(err, fs.ErrNotExist) {
+				deletedFromDisk++
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":                true,
+		"dry_run":           dryRun,
+		"cutoff":            cutoff.UTC().Format(time.RFC3339),
+		"deleted_count":     result.DeletedCount,
+		"deleted_from_disk": deletedFromDisk,
+		"freed_bytes":       result.FreedBytes,
+	})
+}
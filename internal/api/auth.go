@@ -0,0 +1,440 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+const (
+	sessionCookie  = "skyclf_session"
+	minPasswordLen = 8
+	maxPasswordLen = 72 // bcrypt ignores anything longer
+	maxUsernameLen = 64
+)
+
+// Credentials is the UI login, stored under store.SettingAuth unless it comes
+// from the environment.
+type Credentials struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"password_hash"` // bcrypt
+}
+
+// Authenticator puts an optional login in front of the API and the image
+// files. Without credentials everything stays open. With them, a request needs
+// a session cookie from POST /api/auth/login, HTTP Basic credentials or the
+// admin bearer token. Sessions live in memory and end on restart.
+type Authenticator struct {
+	ttl   time.Duration
+	key   []byte // signs session cookies
+	token string // admin bearer token, also accepted ("" = not accepted)
+	st    *store.Store
+
+	mu       sync.Mutex
+	creds    *Credentials // nil = no login
+	fromEnv  bool         // creds are fixed by the environment
+	loaded   bool         // creds are known; until then guarded routes answer 503
+	sessions map[string]session
+}
+
+type session struct {
+	username string
+	expires  time.Time
+}
+
+// NewAuthenticator creates an authenticator whose sessions last ttl.
+func NewAuthenticator(ttl time.Duration) *Authenticator {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return &Authenticator{ttl: ttl, key: key, sessions: map[string]session{}}
+}
+
+// SetToken also accepts "Authorization: Bearer <token>" ("" = not accepted).
+func (a *Authenticator) SetToken(token string) {
+	a.token = token
+}
+
+// SetCredentials fixes the login to username and a bcrypt hash of the
+// password; the API can't change it.
+func (a *Authenticator) SetCredentials(username, passwordHash string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.creds = &Credentials{Username: username, PasswordHash: passwordHash}
+	a.fromEnv, a.loaded = true, true
+}
+
+// Restore loads the login stored in settings, unless SetCredentials was
+// called. Guarded routes are unavailable until it succeeds.
+func (a *Authenticator) Restore(ctx context.Context, st *store.Store) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.st = st
+	if a.fromEnv {
+		return nil
+	}
+	var c Credentials
+	ok, err := st.GetSetting(ctx, store.SettingAuth, &c)
+	if err != nil {
+		return err
+	}
+	if ok && c.Username != "" { // removed logins are stored as null
+		a.creds = &c
+	}
+	a.loaded = true
+	return nil
+}
+
+// Required reports whether a login is configured.
+func (a *Authenticator) Required() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.creds != nil
+}
+
+func (a *Authenticator) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/auth/session", a.handleSession)
+	mux.HandleFunc("POST /api/auth/login", a.handleLogin)
+	mux.HandleFunc("POST /api/auth/logout", a.handleLogout)
+	mux.HandleFunc("POST /api/auth/rotate", a.handleRotate)
+	mux.HandleFunc("PUT /api/auth/password", a.handleSetPassword)
+	mux.HandleFunc("DELETE /api/auth/password", a.handleDeletePassword)
+}
+
+// guarded reports whether path needs a login: the API and image files. The UI
+// itself stays reachable so it can show the login form.
+func guarded(path string) bool {
+	switch path {
+	case "/api/health", "/api/auth/session", "/api/auth/login", "/api/auth/logout":
+		return false
+	case "/latest.jpg", "/latest_annotated.jpg":
+		return true
+	}
+	return strings.HasPrefix(path, "/api/") || strings.HasPrefix(path, "/images/")
+}
+
+// Guard wraps next with the login check. Requests that pass carry the caller's
+// identity in their context.
+func (a *Authenticator) Guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !guarded(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		loaded, required := a.state()
+		if !loaded {
+			writeError(w, http.StatusServiceUnavailable, "starting up")
+			return
+		}
+		if !required {
+			next.ServeHTTP(w, r)
+			return
+		}
+		who, _, ok := a.identify(r)
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "login required")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, who)))
+	})
+}
+
+// state reports whether the login is known yet and whether one is required.
+func (a *Authenticator) state() (loaded, required bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.loaded, a.creds != nil
+}
+
+type identityKey struct{}
+
+// identityFrom returns who Guard let through, if a login is required.
+func identityFrom(ctx context.Context) (string, bool) {
+	who, ok := ctx.Value(identityKey{}).(string)
+	return who, ok
+}
+
+// identify checks the bearer token, Basic credentials and session cookie of r
+// and returns who the caller is and how they authenticated.
+func (a *Authenticator) identify(r *http.Request) (who, via string, ok bool) {
+	if got, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+		if a.token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(a.token)) == 1 {
+			return "admin", "token", true
+		}
+		return "", "", false
+	}
+	if user, pass, found := r.BasicAuth(); found {
+		if a.checkPassword(user, pass) {
+			return user, "basic", true
+		}
+		return "", "", false
+	}
+	if id, found := a.sessionID(r); found {
+		if s, ok := a.lookup(id); ok {
+			return s.username, "session", true
+		}
+	}
+	return "", "", false
+}
+
+// checkPassword compares against the configured login in constant time, also
+// for unknown usernames.
+func (a *Authenticator) checkPassword(username, password string) bool {
+	a.mu.Lock()
+	creds := a.creds
+	a.mu.Unlock()
+	if creds == nil {
+		return false
+	}
+	userOK := subtle.ConstantTimeCompare([]byte(username), []byte(creds.Username)) == 1
+	passOK := bcrypt.CompareHashAndPassword([]byte(creds.PasswordHash), []byte(password)) == nil
+	return userOK && passOK
+}
+
+// sessionID returns the session ID of r's cookie if its signature is valid.
+func (a *Authenticator) sessionID(r *http.Request) (string, bool) {
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return "", false
+	}
+	id, sig, ok := strings.Cut(c.Value, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(a.sign(id))) {
+		return "", false
+	}
+	return id, true
+}
+
+func (a *Authenticator) sign(id string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// lookup returns the unexpired session id.
+func (a *Authenticator) lookup(id string) (session, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s, ok := a.sessions[id]
+	if !ok {
+		return session{}, false
+	}
+	if time.Now().After(s.expires) {
+		delete(a.sessions, id)
+		return session{}, false
+	}
+	return s, true
+}
+
+// startSession creates a session for username, drops expired ones and sets
+// the cookie on w.
+func (a *Authenticator) startSession(w http.ResponseWriter, r *http.Request, username string) session {
+	b := make([]byte, 32)
+	rand.Read(b)
+	id := base64.RawURLEncoding.EncodeToString(b)
+	s := session{username: username, expires: time.Now().Add(a.ttl)}
+
+	a.mu.Lock()
+	now := time.Now()
+	for k, old := range a.sessions {
+		if now.After(old.expires) {
+			delete(a.sessions, k)
+		}
+	}
+	a.sessions[id] = s
+	a.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    id + "." + a.sign(id),
+		Path:     "/",
+		Expires:  s.expires,
+		HttpOnly: true,
+		Secure:   secureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	return s
+}
+
+// dropSession forgets the session of r, if any.
+func (a *Authenticator) dropSession(r *http.Request) {
+	if id, ok := a.sessionID(r); ok {
+		a.mu.Lock()
+		delete(a.sessions, id)
+		a.mu.Unlock()
+	}
+}
+
+// endSession drops the session of r and clears the cookie on w.
+func (a *Authenticator) endSession(w http.ResponseWriter, r *http.Request) {
+	a.dropSession(r)
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// secureRequest reports whether r reached us (or the proxy in front) over HTTPS.
+func secureRequest(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// GET /api/auth/session - whether a login is required and who the caller is,
+// so the UI knows whether to show the login form
+func (a *Authenticator) handleSession(w http.ResponseWriter, r *http.Request) {
+	resp := map[string]any{"login_required": a.Required(), "authenticated": false}
+	if !a.Required() {
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+	if who, via, ok := a.identify(r); ok {
+		resp["authenticated"], resp["username"], resp["via"] = true, who, via
+		if id, found := a.sessionID(r); found && via == "session" {
+			if s, ok := a.lookup(id); ok {
+				resp["expires_at"] = s.expires.UTC()
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// POST /api/auth/login {"username": "...", "password": "..."} - starts a
+// session and sets its HttpOnly cookie
+func (a *Authenticator) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if !a.Required() {
+		writeError(w, http.StatusConflict, "no login configured")
+		return
+	}
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if !a.checkPassword(req.Username, req.Password) {
+		log.Printf("api: failed login for %q from %s", req.Username, r.RemoteAddr)
+		writeError(w, http.StatusUnauthorized, "invalid username or password")
+		return
+	}
+	a.dropSession(r) // don't leave the previous session behind
+	s := a.startSession(w, r, req.Username)
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "username": s.username, "expires_at": s.expires.UTC()})
+}
+
+// POST /api/auth/logout - ends the caller's session
+func (a *Authenticator) handleLogout(w http.ResponseWriter, r *http.Request) {
+	a.endSession(w, r)
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// POST /api/auth/rotate - replaces the caller's session with a new one, e.g.
+// after the cookie may have leaked
+func (a *Authenticator) handleRotate(w http.ResponseWriter, r *http.Request) {
+	id, ok := a.sessionID(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "no session to rotate")
+		return
+	}
+	old, ok := a.lookup(id)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "session expired")
+		return
+	}
+	a.dropSession(r)
+	s := a.startSession(w, r, old.username)
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "username": s.username, "expires_at": s.expires.UTC()})
+}
+
+// PUT /api/auth/password {"username": "...", "password": "..."} - sets the
+// login (stored as a bcrypt hash in settings), ends every other session and
+// logs the caller in. Not available when the login comes from the environment.
+func (a *Authenticator) handleSetPassword(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" || len(req.Username) > maxUsernameLen || strings.ContainsAny(req.Username, ":\r\n") {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("username must be 1-%d characters without ':'", maxUsernameLen))
+		return
+	}
+	if len(req.Password) < minPasswordLen || len(req.Password) > maxPasswordLen {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("password must be %d-%d bytes", minPasswordLen, maxPasswordLen))
+		return
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	creds := Credentials{Username: req.Username, PasswordHash: string(hash)}
+	if err := a.saveCredentials(r.Context(), &creds); err != nil {
+		if errors.Is(err, errLoginFromEnv) {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	log.Printf("api: login set for %q from %s", creds.Username, r.RemoteAddr)
+	s := a.startSession(w, r, creds.Username)
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "username": s.username, "expires_at": s.expires.UTC()})
+}
+
+// DELETE /api/auth/password - removes the login set through the API; the API
+// is open again
+func (a *Authenticator) handleDeletePassword(w http.ResponseWriter, r *http.Request) {
+	if err := a.saveCredentials(r.Context(), nil); err != nil {
+		if errors.Is(err, errLoginFromEnv) {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	log.Printf("api: login removed from %s", r.RemoteAddr)
+	a.endSession(w, r)
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+var errLoginFromEnv = errors.New("login is set by SKYCLF_AUTH_USER and SKYCLF_AUTH_PASSWORD_HASH")
+
+// saveCredentials persists creds (nil = no login) and ends every session.
+func (a *Authenticator) saveCredentials(ctx context.Context, creds *Credentials) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.fromEnv {
+		return errLoginFromEnv
+	}
+	if err := a.st.SetSetting(ctx, store.SettingAuth, creds); err != nil {
+		return err
+	}
+	a.creds = creds
+	clear(a.sessions)
+	return nil
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"bytes"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SkyClf/SkyClf/internal/infer"
+	"github.com/SkyClf/SkyClf/internal/store"
+	"github.com/SkyClf/SkyClf/internal/trainer"
+)
+
+// badgeMaxAge is how long badges may be cached; wiki pages showing them
+// refresh about this often.
+const badgeMaxAge = "60"
+
+// badgeColors are the shields.io named colors.
+var badgeColors = map[string]string{
+	"brightgreen": "#4c1",
+	"green":       "#97ca00",
+	"yellowgreen": "#a4a61d",
+	"yellow":      "#dfb317",
+	"orange":      "#fe7d37",
+	"red":         "#e05d44",
+	"blue":        "#007ec6",
+	"grey":        "#555",
+	"lightgrey":   "#9f9f9f",
+}
+
+// Default colors of the sky states and training states
+var defaultBadgeColors = map[string]string{
+	"clear":         "brightgreen",
+	"light_clouds":  "yellowgreen",
+	"heavy_clouds":  "grey",
+	"precipitation": "blue",
+	"unknown":       "lightgrey",
+	"idle":          "blue",
+	"running":       "orange",
+	"failed":        "red",
+	"disabled":      "lightgrey",
+}
+
+var hexColorRe = regexp.MustCompile(`^#?([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+var badgeTmpl = template.Must(template.New("badge").Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="20" role="img" aria-label="{{.Label}}: {{.Value}}">
+<title>{{.Label}}: {{.Value}}</title>
+<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="{{.Width}}" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)"><rect width="{{.LabelWidth}}" height="20" fill="#555"/><rect x="{{.LabelWidth}}" width="{{.ValueWidth}}" height="20" fill="{{.Color}}"/><rect width="{{.Width}}" height="20" fill="url(#s)"/></g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="{{.LabelX}}" y="15" fill="#010101" fill-opacity=".3">{{.Label}}</text><text x="{{.LabelX}}" y="14">{{.Label}}</text>
+<text x="{{.ValueX}}" y="15" fill="#010101" fill-opacity=".3">{{.Value}}</text><text x="{{.ValueX}}" y="14">{{.Value}}</text>
+</g>
+</svg>
+`))
+
+type badge struct {
+	Label, Value, Color    string
+	LabelWidth, ValueWidth int
+	Width, LabelX, ValueX  int
+}
+
+// newBadge lays out a badge; text widths are estimated at Verdana 11px.
+func newBadge(label, value, color string) badge {
+	textWidth := func(s string) int { return 7*utf8.RuneCountInString(s) + 10 }
+	b := badge{Label: label, Value: value, Color: color, LabelWidth: textWidth(label), ValueWidth: textWidth(value)}
+	b.Width = b.LabelWidth + b.ValueWidth
+	b.LabelX = b.LabelWidth / 2
+	b.ValueX = b.LabelWidth + b.ValueWidth/2
+	return b
+}
+
+// BadgeHandler serves SVG status badges for embedding in other pages. They
+// are built from stored state only and never run inference; like the UI they
+// need no login.
+type BadgeHandler struct {
+	st   *store.Store
+	pred infer.Predictor
+	tr   *trainer.Trainer // nil when training is disabled
+}
+
+// NewBadgeHandler creates a badge handler. tr may be nil.
+func NewBadgeHandler(st *store.Store, pred infer.Predictor, tr *trainer.Trainer) *BadgeHandler {
+	return &BadgeHandler{st: st, pred: pred, tr: tr}
+}
+
+func (h *BadgeHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /badge/skystate.svg", h.skystate)
+	mux.HandleFunc("GET /badge/model.svg", h.model)
+	mux.HandleFunc("GET /badge/training.svg", h.training)
+}
+
+// GET /badge/skystate.svg - debounced sky state of the last stored predictions
+// ("unknown" without any). All badges take label=<text> for the left side,
+// color_<value>=<hex or shields.io color name> and text_<value>=<text> to
+// restyle a value, e.g. ?label=Sky&text_heavy_clouds=cloudy&color_clear=2ea44f.
+func (h *BadgeHandler) skystate(w http.ResponseWriter, r *http.Request) {
+	state := "unknown"
+	recent, err := h.st.RecentPredictions(r.Context(), store.DebounceWindow)
+	if err != nil {
+		log.Printf("api: badge: %v", err)
+	} else if len(recent) > 0 {
+		state = store.Debounce(recent)
+	}
+	writeBadge(w, r.URL.Query(), "sky", state, defaultBadgeColors[state])
+}
+
+// GET /badge/model.svg - active model version ("none" without a model)
+func (h *BadgeHandler) model(w http.ResponseWriter, r *http.Request) {
+	version := ""
+	if vr, ok := h.pred.(infer.VersionReporter); ok {
+		version = vr.ModelVersion()
+	}
+	if version == "" {
+		writeBadge(w, r.URL.Query(), "model", "none", "lightgrey")
+		return
+	}
+	writeBadge(w, r.URL.Query(), "model", version, "blue")
+}
+
+// GET /badge/training.svg - idle, running, failed (the last run) or disabled
+func (h *BadgeHandler) training(w http.ResponseWriter, r *http.Request) {
+	state := "disabled"
+	if h.tr != nil {
+		state = h.tr.State()
+	}
+	writeBadge(w, r.URL.Query(), "training", state, defaultBadgeColors[state])
+}
+
+// writeBadge renders the badge for value, colored color (a name) unless q
+// overrides it.
+func writeBadge(w http.ResponseWriter, q url.Values, label, value, color string) {
+	if v := q.Get("label"); v != "" {
+		label = v
+	}
+	color = badgeColor(q.Get("color_"+value), badgeColors[color])
+	if v := q.Get("text_" + value); v != "" {
+		value = v
+	}
+
+	var buf bytes.Buffer
+	if err := badgeTmpl.Execute(&buf, newBadge(label, value, color)); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "public, max-age="+badgeMaxAge)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// badgeColor resolves a color given as a shields.io name or hex (with or
+// without "#"); anything else gives def, or grey without one.
+func badgeColor(v, def string) string {
+	if c, ok := badgeColors[strings.ToLower(v)]; ok {
+		return c
+	}
+	if hexColorRe.MatchString(v) {
+		return "#" + strings.TrimPrefix(v, "#")
+	}
+	if def != "" {
+		return def
+	}
+	return badgeColors["grey"]
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// handleBiasReport compares how often manual labels agree with the model when
+// its suggestion was shown (shown_prediction on POST /api/labels) and when it
+// wasn't.
+// Query params:
+//   - labeler: only labels by this labeler
+//   - since: labels recorded on or after this day (YYYY-MM-DD, see SKYCLF_DAY_GROUPING)
+func (h *DatasetHandler) handleBiasReport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := store.BiasFilter{Labeler: strings.TrimSpace(q.Get("labeler"))}
+	if raw := q.Get("since"); raw != "" {
+		t, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			http.Error(w, "invalid since; use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		f.Since, _ = h.st.DayGrouping().Bounds(t)
+	}
+
+	report, err := h.st.BiasReport(r.Context(), f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// validShown checks the suggestion a label request says was on screen.
+func validShown(p *store.ShownPrediction) string {
+	p.Skystate = strings.TrimSpace(p.Skystate)
+	p.ModelVersion = strings.TrimSpace(p.ModelVersion)
+	switch {
+	case !validSkystate(p.Skystate):
+		return "invalid shown_prediction.skystate"
+	case p.Confidence < 0 || p.Confidence > 1:
+		return "shown_prediction.confidence must be in [0, 1]"
+	}
+	return ""
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/infer"
+)
+
+// predictionCache lets concurrent /api/clf and /api/latest requests for the
+// same image share one inference, and reuses the result for ttl afterwards,
+// so a burst of dashboards polling right after a new frame costs one
+// inference. Failed inferences and "no model" answers are handed to every
+// waiter but not kept: a reload must take effect with the next request.
+type predictionCache struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	calls map[string]*predictCall
+	stats clfStats
+}
+
+var errPredictionAborted = errors.New("prediction aborted")
+
+// How predictionCache.get served a request.
+const (
+	servedInference = "inference" // ran the inference itself
+	servedCoalesced = "coalesced" // waited for an inference already in flight
+	servedCache     = "cache"     // reused a result younger than the TTL
+)
+
+type predictCall struct {
+	done chan struct{} // closed when pred/err are set
+	pred *infer.Prediction
+	err  error
+	at   time.Time // when the inference finished
+}
+
+// clfStats counts how prediction requests were served.
+type clfStats struct {
+	Requests   int64  `json:"requests"`
+	Inferences int64  `json:"inferences"` // actually run
+	Coalesced  int64  `json:"coalesced"`  // joined an inference already in flight
+	CacheHits  int64  `json:"cache_hits"` // served a result younger than the TTL
+	Errors     int64  `json:"errors"`     // failed inferences (shared by their waiters)
+	TTL        string `json:"ttl"`
+}
+
+func newPredictionCache(ttl time.Duration) *predictionCache {
+	return &predictionCache{ttl: ttl, calls: map[string]*predictCall{}}
+}
+
+func (c *predictionCache) setTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = ttl
+}
+
+// get returns the prediction for key and how it was served, running fn only if
+// no inference for key is in flight or fresh. fn runs detached from ctx: a
+// waiter leaving early, the one that started it included, must not cancel the
+// inference the others are waiting for.
+func (c *predictionCache) get(ctx context.Context, key string, fn func(context.Context) (*infer.Prediction, error)) (*infer.Prediction, string, error) {
+	c.mu.Lock()
+	c.stats.Requests++
+	if call, ok := c.calls[key]; ok {
+		select {
+		case <-call.done:
+			if time.Since(call.at) < c.ttl {
+				c.stats.CacheHits++
+				c.mu.Unlock()
+				return call.pred, servedCache, call.err
+			}
+		default:
+			c.stats.Coalesced++
+			c.mu.Unlock()
+			select {
+			case <-call.done:
+				return call.pred, servedCoalesced, call.err
+			case <-ctx.Done():
+				return nil, servedCoalesced, ctx.Err()
+			}
+		}
+	}
+	call := &predictCall{done: make(chan struct{})}
+	c.calls[key] = call
+	c.stats.Inferences++
+	// Only the latest image is asked for; older entries are dead weight
+	for k, old := range c.calls {
+		if k != key && isClosed(old.done) {
+			delete(c.calls, k)
+		}
+	}
+	c.mu.Unlock()
+
+	// The caller may give up (ctx) while the inference finishes and warms the
+	// cache for the next request
+	go c.run(key, call, context.WithoutCancel(ctx), fn)
+	select {
+	case <-call.done:
+		return call.pred, servedInference, call.err
+	case <-ctx.Done():
+		return nil, servedInference, ctx.Err()
+	}
+}
+
+// run runs fn for call and publishes its result.
+func (c *predictionCache) run(key string, call *predictCall, ctx context.Context, fn func(context.Context) (*infer.Prediction, error)) {
+	// Deferred so waiters are released even if fn panics; off the request
+	// goroutine, net/http no longer recovers it for us
+	call.err = errPredictionAborted
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("api: prediction panicked: %v", p)
+		}
+		c.mu.Lock()
+		call.at = time.Now()
+		if call.err != nil {
+			c.stats.Errors++
+		}
+		if call.err != nil || call.pred == nil {
+			delete(c.calls, key)
+		}
+		close(call.done)
+		c.mu.Unlock()
+	}()
+	call.pred, call.err = fn(ctx)
+}
+
+func (c *predictionCache) snapshot() clfStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.TTL = c.ttl.String()
+	return s
+}
+
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
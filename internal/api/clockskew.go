@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/apitypes"
+	"github.com/SkyClf/SkyClf/internal/ingest"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// defaultClockSkewDays is how many days GET /api/admin/clock-skew covers
+// without ?days.
+const defaultClockSkewDays = 30
+
+// SetClockSkew reports the camera clock check in GET /api/admin/clock-skew.
+func (h *AdminHandler) SetClockSkew(c *ingest.ClockSkew) {
+	h.clockSkew = c
+}
+
+// GET /api/admin/clock-skew?days=30 - current camera clock skew, the
+// correction in effect and the median skew per day
+func (h *AdminHandler) handleClockSkew(w http.ResponseWriter, r *http.Request) {
+	days := defaultClockSkewDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "days must be a positive integer")
+			return
+		}
+		days = n
+	}
+
+	var correction string
+	if _, err := h.st.GetSetting(r.Context(), store.SettingClockCorrection, &correction); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	offset, _ := h.clockSkew.Offset(correction) // validated when set
+
+	g := h.st.DayGrouping()
+	today, _ := time.Parse("2006-01-02", g.DayOf(time.Now()))
+	since, _ := g.Bounds(today.AddDate(0, 0, 1-days))
+	byDay, err := h.st.ClockSkewByDay(r.Context(), since)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, apitypes.ClockSkew{
+		Current:           h.clockSkew.Status(),
+		Correction:        correction,
+		CorrectionSeconds: offset.Seconds(),
+		Days:              byDay,
+	})
+}
+
+// PUT /api/admin/clock-skew {"correction": "auto"} - set the offset subtracted
+// from the capture times of newly ingested images: "auto" (the current median
+// skew), a duration such as "-90s", or "" for none. Stored skews stay raw.
+func (h *AdminHandler) handleSetClockCorrection(w http.ResponseWriter, r *http.Request) {
+	var req apitypes.ClockCorrection
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if _, err := ingest.ParseClockCorrection(req.Correction); err != nil {
+		writeError(w, http.StatusBadRequest, `correction must be "", "auto" or a duration such as "-90s"`)
+		return
+	}
+	if err := h.st.SetSetting(r.Context(), store.SettingClockCorrection, req.Correction); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, req)
+}
@@ -0,0 +1,319 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/infer"
+	"github.com/SkyClf/SkyClf/internal/store"
+	"github.com/SkyClf/SkyClf/internal/trainer"
+)
+
+// maxDisagreements caps the image list returned by a comparison.
+const maxDisagreements = 200
+
+var errTrainingActive = errors.New("training is running; compare models after it has finished")
+
+// CompareHandler evaluates model versions side by side on the test split. Its
+// route is served by ModelsHandler (see SetCompare).
+type CompareHandler struct {
+	st        *store.Store
+	pred      *infer.ORTPredictor // source of session options and mask/crop
+	tr        *trainer.Trainer    // nil when training is disabled
+	modelsDir string
+
+	mu  sync.Mutex
+	job compareStatus
+}
+
+type compareStatus struct {
+	Running    bool      `json:"running"`
+	Versions   []string  `json:"versions,omitempty"`
+	Current    string    `json:"current,omitempty"` // version being evaluated
+	Processed  int       `json:"processed"`         // images of the current version
+	Total      int       `json:"total"`
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// modelEval is the cached result of evaluating one version on a split.
+type modelEval struct {
+	EvalID      string            `json:"eval_id"` // per-image results at /api/eval/{id}/pairs
+	Version     string            `json:"version"`
+	SplitHash   string            `json:"split_hash"`
+	EvaluatedAt time.Time         `json:"evaluated_at"`
+	Skipped     int               `json:"skipped"` // labels the model has no class for, unreadable images
+	Metrics     infer.Metrics     `json:"metrics"`
+	Predictions map[string]string `json:"predictions"` // image id -> predicted class
+}
+
+// NewCompareHandler creates a new model comparison handler. tr may be nil.
+func NewCompareHandler(st *store.Store, pred *infer.ORTPredictor, tr *trainer.Trainer, modelsDir string) *CompareHandler {
+	return &CompareHandler{st: st, pred: pred, tr: tr, modelsDir: modelsDir}
+}
+
+// GET /api/models/compare?versions=v4,v5 - accuracy, per-class F1 and the images
+// the versions disagree on, evaluated on the test split. Results are cached per
+// (version, split hash). Missing results are computed by a background job, one
+// version after another; until it finishes the endpoint answers 202 with the
+// job progress. Refused (409) while training runs.
+func (h *CompareHandler) compare(w http.ResponseWriter, r *http.Request) {
+	if h.pred == nil {
+		writeError(w, http.StatusServiceUnavailable, "model comparison needs the local ONNX Runtime backend")
+		return
+	}
+	var versions []string
+	for _, v := range strings.Split(r.URL.Query().Get("versions"), ",") {
+		if v = strings.TrimSpace(v); v != "" && !slices.Contains(versions, v) {
+			versions = append(versions, v)
+		}
+	}
+	if len(versions) < 2 {
+		writeError(w, http.StatusBadRequest, "versions must list at least two model versions, e.g. versions=v4,v5")
+		return
+	}
+	for _, v := range versions {
+		if !infer.ValidVersionName(v) {
+			writeError(w, http.StatusBadRequest, "invalid version "+v+"; expected a name like v3")
+			return
+		}
+		mi, err := infer.FindSkyStateModel(h.modelsDir, v)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if mi == nil {
+			writeError(w, http.StatusNotFound, "model "+v+" not found")
+			return
+		}
+	}
+
+	ctx := r.Context()
+	splitHash, n, err := h.st.SplitHash(ctx, store.SplitTest)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if n == 0 {
+		writeError(w, http.StatusConflict, "the test split is empty; assign splits with POST /api/dataset/split")
+		return
+	}
+
+	evals, missing, err := h.cached(ctx, versions, splitHash)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(missing) == 0 {
+		h.writeComparison(w, r, splitHash, evals)
+		return
+	}
+
+	if h.trainingActive(ctx) {
+		writeError(w, http.StatusConflict, errTrainingActive.Error())
+		return
+	}
+	h.mu.Lock()
+	if h.job.Running {
+		job := h.job
+		h.mu.Unlock()
+		if !slices.Equal(job.Versions, versions) {
+			writeError(w, http.StatusConflict, "another comparison is running")
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]any{"status": job})
+		return
+	}
+	h.job = compareStatus{Running: true, Versions: versions, StartedAt: time.Now().UTC()}
+	job := h.job
+	h.mu.Unlock()
+
+	// Job outlives the request
+	go h.run(context.Background(), missing)
+
+	writeJSON(w, http.StatusAccepted, map[string]any{"status": job})
+}
+
+// cached returns the cached evaluations of versions and the versions without one.
+func (h *CompareHandler) cached(ctx context.Context, versions []string, splitHash string) ([]modelEval, []string, error) {
+	var (
+		evals   []modelEval
+		missing []string
+	)
+	for _, v := range versions {
+		body, err := h.st.GetModelEval(ctx, v, splitHash)
+		if err != nil {
+			return nil, nil, err
+		}
+		var ev modelEval
+		// Evaluations cached before per-image results were kept have no ID
+		if body == nil || json.Unmarshal(body, &ev) != nil || ev.EvalID == "" {
+			missing = append(missing, v)
+			continue
+		}
+		evals = append(evals, ev)
+	}
+	return evals, missing, nil
+}
+
+func (h *CompareHandler) trainingActive(ctx context.Context) bool {
+	return h.tr != nil && h.tr.Status(ctx).Running
+}
+
+func (h *CompareHandler) run(ctx context.Context, versions []string) {
+	var err error
+	for _, v := range versions {
+		if h.trainingActive(ctx) {
+			err = errTrainingActive
+			break
+		}
+		if err = h.evaluate(ctx, v); err != nil {
+			break
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.job.Running = false
+	h.job.Current = ""
+	h.job.FinishedAt = time.Now().UTC()
+	if err != nil {
+		h.job.Error = err.Error()
+		log.Printf("api: model comparison failed: %v", err)
+	}
+}
+
+// evaluate runs version on the test split in a separate session and caches the result.
+func (h *CompareHandler) evaluate(ctx context.Context, version string) error {
+	// Hash and list together so the cached result matches what was evaluated
+	splitHash, _, err := h.st.SplitHash(ctx, store.SplitTest)
+	if err != nil {
+		return err
+	}
+	items, err := h.st.ListImagesFiltered(ctx, store.ImageFilter{LabeledOnly: true, Split: store.SplitTest, ExcludeUnpredictable: true})
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	h.job.Current, h.job.Processed, h.job.Total = version, 0, len(items)
+	h.mu.Unlock()
+
+	p, err := h.pred.OpenVersion(version)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	mi := p.ActiveModel()
+
+	ev := modelEval{EvalID: store.EvalID(version, splitHash), Version: version, SplitHash: splitHash, Predictions: map[string]string{}}
+	var (
+		labels, predicted []string
+		results           []store.EvalResult
+	)
+	for _, it := range items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		h.mu.Lock()
+		h.job.Processed++
+		h.mu.Unlock()
+
+		if _, ok := mi.Classes[*it.Skystate]; !ok {
+			ev.Skipped++
+			continue
+		}
+		pred, err := p.PredictImage(ctx, it.Path)
+		if err != nil || pred == nil {
+			ev.Skipped++
+			continue
+		}
+		labels = append(labels, *it.Skystate)
+		predicted = append(predicted, pred.SkyState)
+		ev.Predictions[it.ID] = pred.SkyState
+		results = append(results, store.EvalResult{ImageID: it.ID, Truth: *it.Skystate, Predicted: pred.SkyState, Confidence: float64(pred.Confidence)})
+	}
+	ev.Metrics = infer.Score(labels, predicted)
+	ev.EvaluatedAt = time.Now().UTC()
+
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	if err := h.st.SaveModelEval(ctx, version, splitHash, body, ev.EvaluatedAt, results); err != nil {
+		return err
+	}
+	log.Printf("api: evaluated %s on the test split: accuracy %.3f, macro F1 %.3f (n=%d)",
+		version, ev.Metrics.Accuracy, ev.Metrics.MacroF1, ev.Metrics.Samples)
+	return nil
+}
+
+func (h *CompareHandler) writeComparison(w http.ResponseWriter, r *http.Request, splitHash string, evals []modelEval) {
+	items, err := h.st.ListImagesFiltered(r.Context(), store.ImageFilter{LabeledOnly: true, Split: store.SplitTest, ExcludeUnpredictable: true})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	type versionResult struct {
+		EvalID      string    `json:"eval_id"`
+		Version     string    `json:"version"`
+		EvaluatedAt time.Time `json:"evaluated_at"`
+		Skipped     int       `json:"skipped"`
+		infer.Metrics
+	}
+	type disagreement struct {
+		ImageID     string            `json:"image_id"`
+		URL         string            `json:"url"`
+		Label       string            `json:"label"`
+		Predictions map[string]string `json:"predictions"` // version -> class
+	}
+
+	results := make([]versionResult, 0, len(evals))
+	for _, ev := range evals {
+		results = append(results, versionResult{EvalID: ev.EvalID, Version: ev.Version, EvaluatedAt: ev.EvaluatedAt, Skipped: ev.Skipped, Metrics: ev.Metrics})
+	}
+
+	disagreements := []disagreement{}
+	count := 0
+	for _, it := range items {
+		preds := make(map[string]string, len(evals))
+		differ := false
+		for _, ev := range evals {
+			preds[ev.Version] = ev.Predictions[it.ID]
+			if preds[ev.Version] != preds[evals[0].Version] {
+				differ = true
+			}
+		}
+		if !differ {
+			continue
+		}
+		count++
+		if len(disagreements) < maxDisagreements {
+			disagreements = append(disagreements, disagreement{
+				ImageID:     it.ID,
+				URL:         "/images/" + filepath.Base(it.Path),
+				Label:       *it.Skystate,
+				Predictions: preds,
+			})
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"split":              store.SplitTest,
+		"split_hash":         splitHash,
+		"images":             len(items),
+		"versions":           results,
+		"disagreement_count": count,
+		"disagreements":      disagreements,
+	})
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/SkyClf/SkyClf/internal/config"
+)
+
+// ConfigHandler serves the effective configuration.
+type ConfigHandler struct {
+	settings []config.Setting
+}
+
+// NewConfigHandler creates a handler for cfg; values are captured once, at startup.
+func NewConfigHandler(cfg config.Config) *ConfigHandler {
+	return &ConfigHandler{settings: cfg.Settings()}
+}
+
+// RegisterRoutes registers the config route
+func (h *ConfigHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/config", h.getConfig)
+}
+
+// GET /api/config - every setting with its effective value and whether it came
+// from the environment, a default or another setting; secrets are redacted
+func (h *ConfigHandler) getConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"settings": h.settings})
+}
@@ -2,32 +2,64 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/SkyClf/SkyClf/internal/apitypes"
 	"github.com/SkyClf/SkyClf/internal/store"
 )
 
+// DefaultClaimTTL is how long /api/dataset/next-unlabeled holds an image for a labeler.
+const DefaultClaimTTL = 2 * time.Minute
+
 type DatasetHandler struct {
-	st *store.Store
+	st       *store.Store
+	claimTTL time.Duration
 }
 
 func NewDatasetHandler(st *store.Store) *DatasetHandler {
-	return &DatasetHandler{st: st}
+	return &DatasetHandler{st: st, claimTTL: DefaultClaimTTL}
+}
+
+// SetClaimTTL sets how long a next-unlabeled claim is held.
+func (h *DatasetHandler) SetClaimTTL(ttl time.Duration) {
+	if ttl > 0 {
+		h.claimTTL = ttl
+	}
 }
 
 func (h *DatasetHandler) RegisterRoutes(mux *http.ServeMux) {
 	// NOTE: This conflicts with ImagesHandler which also registers GET /api/images
 	// You should either disable ImagesHandler.listImages or change this route.
 	mux.HandleFunc("GET /api/dataset/images", h.handleListImages)
+	mux.HandleFunc("PATCH /api/images/{id}", h.handleSetImageNotes)
 	mux.HandleFunc("GET /api/dataset/stats", h.handleStats)
 	mux.HandleFunc("GET /api/dataset/days", h.handleListDays)
+	mux.HandleFunc("GET /api/dataset/overview", h.handleOverview)
+	mux.HandleFunc("POST /api/dataset/split", h.handleAssignSplits)
+	mux.HandleFunc("GET /api/dataset/next-unlabeled", h.handleNextUnlabeled)
 	mux.HandleFunc("POST /api/labels", h.handleSetLabel)
-	mux.HandleFunc("POST /api/labels/reset", h.handleClearLabels)
-	mux.HandleFunc("POST /api/images/cleanup", h.handleCleanupImages)
+	mux.HandleFunc("GET /api/labels/changes", h.handleLabelChanges)
+	mux.HandleFunc("POST /api/labels/merge", h.handleMergeLabels)
+	mux.HandleFunc("GET /api/labels/auto-label", h.handleGetAutoLabel)
+	mux.HandleFunc("PUT /api/labels/auto-label", h.handleSetAutoLabel)
+	mux.HandleFunc("POST /api/labels/accept-suggestions", h.handleAcceptSuggestions)
+	mux.HandleFunc("GET /api/dataset/review-queue", h.handleReviewQueue)
+	mux.HandleFunc("GET /api/dataset/samples", h.handleSamples)
+	mux.HandleFunc("GET /api/dataset/bias-report", h.handleBiasReport)
+	mux.HandleFunc("POST /api/labels/confirm", h.handleConfirmLabels)
+	mux.HandleFunc("GET /api/dataset/export", h.handleExport)
+	mux.HandleFunc("GET /api/dataset/export/annotations", h.handleExportAnnotations)
+	mux.HandleFunc("POST /api/dataset/snapshots", h.handleCreateSnapshot)
+	mux.HandleFunc("GET /api/dataset/snapshots", h.handleListSnapshots)
+	mux.HandleFunc("DELETE /api/dataset/snapshots/{name}", h.handleDeleteSnapshot)
 }
 
 func (h *DatasetHandler) handleListImages(w http.ResponseWriter, r *http.Request) {
@@ -42,31 +74,88 @@ func (h *DatasetHandler) handleListImages(w http.ResponseWriter, r *http.Request
 		}
 	}
 
-	var day string
-	if raw := strings.TrimSpace(q.Get("date")); raw != "" {
-		if _, err := time.Parse("2006-01-02", raw); err != nil {
-			http.Error(w, "invalid date format; use YYYY-MM-DD", http.StatusBadRequest)
+	filter, err := parseImageFilter(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// ?q= searches image ID prefixes and notes; newest matches first, capped
+	if search := strings.TrimSpace(q.Get("q")); search != "" {
+		if len(search) > maxSearchLen {
+			http.Error(w, "q too long", http.StatusBadRequest)
 			return
 		}
-		day = raw
+		filter.Search = search
+		if limit <= 0 || limit > maxSearchResults {
+			limit = maxSearchResults
+		}
 	}
+	filter.Limit = limit
+	filter.UnlabeledOnly = unlabeled
+	filter.IncludeMeta = hasInclude(q.Get("include"), "meta")
+	filter.IncludeProvenance = hasInclude(q.Get("include"), "provenance")
+	filter.IncludeWeather = hasInclude(q.Get("include"), "weather")
+	filter.IncludeSkips = hasInclude(q.Get("include"), "skips")
 
-	items, err := h.st.ListImages(limit, unlabeled, day)
+	items, err := h.st.ListImagesFiltered(r.Context(), filter)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	resp := map[string]any{
-		"count": len(items),
-		"items": items,
-	}
+	writeJSON(w, http.StatusOK, apitypes.ImageList{Count: len(items), Items: items})
+}
 
-	writeJSON(w, http.StatusOK, resp)
+// parseImageFilter reads the filters shared by the image list and the export:
+// date, exposure_min, exposure_max, resolution, has_annotations and split.
+func parseImageFilter(q url.Values) (store.ImageFilter, error) {
+	var filter store.ImageFilter
+	if raw := strings.TrimSpace(q.Get("date")); raw != "" {
+		if _, err := time.Parse("2006-01-02", raw); err != nil {
+			return filter, errors.New("invalid date format; use YYYY-MM-DD")
+		}
+		filter.Day = raw
+	}
+	for _, p := range []struct {
+		key string
+		dst **float64
+	}{{"exposure_min", &filter.ExposureMin}, {"exposure_max", &filter.ExposureMax}} {
+		if raw := strings.TrimSpace(q.Get(p.key)); raw != "" {
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return filter, errors.New("invalid " + p.key)
+			}
+			*p.dst = &v
+		}
+	}
+	if raw := q.Get("resolution"); raw != "" {
+		width, height, err := store.ParseResolution(raw)
+		if err != nil {
+			return filter, err
+		}
+		filter.Width, filter.Height = width, height
+	}
+	ha := q.Get("has_annotations")
+	filter.HasAnnotations = ha == "1" || strings.EqualFold(ha, "true")
+	if sp := q.Get("split"); sp != "" {
+		if sp != store.SplitNone && !slices.Contains(store.Splits, sp) {
+			return filter, errors.New("invalid split; use train, val, test or unassigned")
+		}
+		filter.Split = sp
+	}
+	filter.IncludeArchived = isTrue(q.Get("include_archived"))
+	filter.ArchivedOnly = isTrue(q.Get("archived"))
+	return filter, nil
 }
 
+// handleStats returns dataset counters; archived images are left out unless
+// ?include_archived=1.
 func (h *DatasetHandler) handleStats(w http.ResponseWriter, r *http.Request) {
-	stats, err := h.st.CountStats()
+	count := h.st.CountStats
+	if isTrue(r.URL.Query().Get("include_archived")) {
+		count = h.st.CountStatsWithArchived
+	}
+	stats, err := count(r.Context())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -75,13 +164,79 @@ func (h *DatasetHandler) handleStats(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *DatasetHandler) handleListDays(w http.ResponseWriter, r *http.Request) {
-	days, err := h.st.ListDays()
+	days, err := h.st.ListDays(r.Context())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{
-		"days": days,
+	writeJSON(w, http.StatusOK, apitypes.Days{Days: days})
+}
+
+// handleOverview returns what the dataset page needs in one call: the
+// counters of /api/dataset/stats, the days of /api/dataset/days and the first
+// page of /api/dataset/images, read from one snapshot.
+// Query params: the image list filters, and limit (default 100, max 1000)
+func (h *DatasetHandler) handleOverview(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter, err := parseImageFilter(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.Limit = 100
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 1000 {
+			http.Error(w, "invalid limit (1-1000)", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = n
+	}
+	filter.UnlabeledOnly = isTrue(q.Get("unlabeled"))
+
+	ov, err := h.st.Overview(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, apitypes.Overview{
+		Stats: ov.Stats,
+		Days:  ov.Days,
+		Count: len(ov.Images),
+		Items: ov.Images,
+	})
+}
+
+// handleNextUnlabeled returns one unlabeled image and claims it for the caller,
+// so concurrent labelers are served different images. The claim is released when
+// the image is labeled or after the claim TTL.
+// Query params:
+//   - labeler: claimant name (default: client address)
+//   - skip: image ID to release and pass over
+func (h *DatasetHandler) handleNextUnlabeled(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	claimant := strings.TrimSpace(q.Get("labeler"))
+	if claimant == "" {
+		claimant = r.RemoteAddr
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			claimant = host
+		}
+	}
+
+	img, expiresAt, err := h.st.ClaimNextUnlabeled(r.Context(), claimant, h.claimTTL, strings.TrimSpace(q.Get("skip")))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if img == nil {
+		http.Error(w, "no unclaimed unlabeled images", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, apitypes.NextUnlabeled{
+		Image:          img,
+		Claimant:       claimant,
+		ClaimExpiresAt: expiresAt,
 	})
 }
 
@@ -89,6 +244,12 @@ type setLabelRequest struct {
 	ImageID  string `json:"image_id"`
 	Skystate string `json:"skystate"`
 	Meteor   bool   `json:"meteor"`
+	Labeler  string `json:"labeler,omitempty"`
+
+	NeedsReview bool `json:"needs_review,omitempty"` // unsure; list in the review queue
+
+	// Model suggestion visible while labeling; kept for GET /api/dataset/bias-report
+	Shown *store.ShownPrediction `json:"shown_prediction,omitempty"`
 }
 
 func (h *DatasetHandler) handleSetLabel(w http.ResponseWriter, r *http.Request) {
@@ -110,14 +271,27 @@ func (h *DatasetHandler) handleSetLabel(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	switch req.Skystate {
-	case "clear", "light_clouds", "heavy_clouds", "precipitation", "unknown":
-	default:
+	if !validSkystate(req.Skystate) {
 		http.Error(w, "invalid skystate value", http.StatusBadRequest)
 		return
 	}
+	if req.Shown != nil {
+		if msg := validShown(req.Shown); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := h.st.WriteLabel(r.Context(), store.LabelWrite{
+		ImageID:   req.ImageID,
+		Skystate:  req.Skystate,
+		Meteor:    req.Meteor,
+		LabeledAt: time.Now().UTC(),
+		Labeler:   strings.TrimSpace(req.Labeler),
 
-	if err := h.st.SetLabel(req.ImageID, req.Skystate, req.Meteor, time.Now().UTC()); err != nil {
+		NeedsReview: req.NeedsReview,
+		Shown:       req.Shown,
+	}); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -125,17 +299,65 @@ func (h *DatasetHandler) handleSetLabel(w http.ResponseWriter, r *http.Request)
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 }
 
+// hasInclude reports whether a comma-separated ?include= list contains name.
+func hasInclude(raw, name string) bool {
+	for _, part := range strings.Split(raw, ",") {
+		if strings.TrimSpace(part) == name {
+			return true
+		}
+	}
+	return false
+}
+
+func validSkystate(s string) bool {
+	return store.ValidSkystate(s)
+}
+
+// handleClearLabels deletes labels; without filters all labels are removed.
+// Query params (all optional, combined with AND):
+//   - date: labels set on this day (YYYY-MM-DD, see SKYCLF_DAY_GROUPING)
+//   - image_date: labels of images fetched on this day (YYYY-MM-DD, see SKYCLF_DAY_GROUPING)
+//   - skystate: only labels with this class
+//   - labeler: only labels set by this labeler
 func (h *DatasetHandler) handleClearLabels(w http.ResponseWriter, r *http.Request) {
-	confirm := r.URL.Query().Get("confirm")
+	q := r.URL.Query()
+	confirm := q.Get("confirm")
 	if confirm != "yes" {
 		http.Error(w, "confirmation required; pass ?confirm=yes", http.StatusBadRequest)
 		return
 	}
-	if err := h.st.ClearLabels(); err != nil {
+
+	f := store.LabelFilter{
+		Date:      strings.TrimSpace(q.Get("date")),
+		ImageDate: strings.TrimSpace(q.Get("image_date")),
+		Skystate:  strings.TrimSpace(q.Get("skystate")),
+		Labeler:   strings.TrimSpace(q.Get("labeler")),
+	}
+	for _, d := range []string{f.Date, f.ImageDate} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			http.Error(w, "invalid date format; use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+	if f.Skystate != "" && !validSkystate(f.Skystate) {
+		http.Error(w, "invalid skystate value", http.StatusBadRequest)
+		return
+	}
+
+	n, err := h.st.ClearLabelsWhere(r.Context(), f)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "message": "all labels removed"})
+
+	msg := "all labels removed"
+	if f != (store.LabelFilter{}) {
+		msg = "matching labels removed"
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "message": msg, "deleted": n})
 }
 
 // handleCleanupImages handles cleanup of unlabeled images
@@ -154,7 +376,7 @@ func (h *DatasetHandler) handleCleanupImages(w http.ResponseWriter, r *http.Requ
 
 	if day != "" {
 		// Delete all unlabeled from a specific day
-		result, err = h.st.DeleteUnlabeledByDay(day)
+		result, err = h.st.DeleteUnlabeledByDay(r.Context(), day)
 	} else if maxUnlabeledStr != "" {
 		// Delete oldest unlabeled to keep count under threshold
 		maxUnlabeled, parseErr := strconv.Atoi(maxUnlabeledStr)
@@ -162,7 +384,7 @@ func (h *DatasetHandler) handleCleanupImages(w http.ResponseWriter, r *http.Requ
 			http.Error(w, "invalid max_unlabeled value", http.StatusBadRequest)
 			return
 		}
-		result, err = h.st.DeleteOldestUnlabeled(maxUnlabeled)
+		result, err = h.st.DeleteOldestUnlabeled(r.Context(), maxUnlabeled)
 	} else {
 		http.Error(w, "must specify 'day' or 'max_unlabeled' parameter", http.StatusBadRequest)
 		return
@@ -188,3 +410,84 @@ func (h *DatasetHandler) handleCleanupImages(w http.ResponseWriter, r *http.Requ
 		"freed_bytes":      result.FreedBytes,
 	})
 }
+
+// handleDeleteDay archives every image fetched on the given UTC day; they can
+// be restored until POST /api/admin/purge removes them. With ?permanent=1 the
+// DB rows (labels, metadata and annotations included) and files on disk are
+// removed right away. With ?dry_run=1 only the counts and bytes are returned.
+func (h *DatasetHandler) handleDeleteDay(w http.ResponseWriter, r *http.Request) {
+	day := r.PathValue("date")
+	if _, err := time.Parse("2006-01-02", day); err != nil {
+		http.Error(w, "invalid date format; use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	permanent := isTrue(r.URL.Query().Get("permanent"))
+
+	dry := r.URL.Query().Get("dry_run")
+	if dry == "1" || strings.EqualFold(dry, "true") {
+		images, err := h.st.ListImagePathsByDay(r.Context(), day)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var count int
+		var bytes int64
+		for _, img := range images {
+			if img.ArchivedAt != nil && !permanent {
+				continue
+			}
+			count++
+			bytes += img.SizeBytes
+		}
+		writeJSON(w, http.StatusOK, apitypes.DayDryRun{
+			DryRun:     true,
+			Permanent:  permanent,
+			Date:       day,
+			Count:      count,
+			FreedBytes: bytes,
+		})
+		return
+	}
+
+	if !permanent {
+		result, err := h.st.ArchiveImagesByDay(r.Context(), day, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, apitypes.DayArchived{
+			OK:            true,
+			Date:          day,
+			ArchivedCount: result.ArchivedCount,
+			ArchivedBytes: result.ArchivedBytes,
+		})
+		return
+	}
+
+	result, err := h.st.DeleteImagesByDay(r.Context(), day)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// DB is already consistent; file failures are reported, not fatal.
+	deletedFromDisk := 0
+	fileErrors := []string{}
+	for _, path := range result.DeletedPaths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			fileErrors = append(fileErrors, err.Error())
+			continue
+		}
+		deletedFromDisk++
+	}
+
+	writeJSON(w, http.StatusOK, apitypes.DayDeleted{
+		OK:              len(fileErrors) == 0,
+		Date:            day,
+		Permanent:       true,
+		DeletedCount:    result.DeletedCount,
+		DeletedFromDisk: deletedFromDisk,
+		FreedBytes:      result.FreedBytes,
+		FileErrors:      fileErrors,
+	})
+}
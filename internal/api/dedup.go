@@ -0,0 +1,151 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/apitypes"
+	"github.com/SkyClf/SkyClf/internal/imghash"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// DedupHandler reports near-identical frames and backfills perceptual hashes.
+type DedupHandler struct {
+	st *store.Store
+
+	mu       sync.Mutex
+	backfill apitypes.PHashBackfill
+}
+
+// NewDedupHandler creates a new dedup API handler
+func NewDedupHandler(st *store.Store) *DedupHandler {
+	return &DedupHandler{st: st}
+}
+
+// RegisterRoutes registers the dedup API routes
+func (h *DedupHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/dataset/dedup", h.handleReport)
+	mux.HandleFunc("POST /api/dataset/phash/backfill", h.handleStartBackfill)
+	mux.HandleFunc("GET /api/dataset/phash/backfill", h.handleBackfillStatus)
+}
+
+// GET /api/dataset/dedup - clusters of near-identical frames
+// Query params:
+//   - threshold: max Hamming distance in bits (default 5)
+//   - window: max time span of one cluster, e.g. "30m" (default 1h, "0" = unlimited)
+//   - date: only images fetched on this day (YYYY-MM-DD, see SKYCLF_DAY_GROUPING)
+func (h *DedupHandler) handleReport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	threshold := imghash.DefaultThreshold
+	if raw := q.Get("threshold"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 || n > 64 {
+			writeError(w, http.StatusBadRequest, "invalid threshold (0-64)")
+			return
+		}
+		threshold = n
+	}
+	window := imghash.DefaultWindow
+	if raw := q.Get("window"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid window duration")
+			return
+		}
+		window = d
+	}
+	day := q.Get("date")
+	if day != "" {
+		if _, err := time.Parse("2006-01-02", day); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid date format; use YYYY-MM-DD")
+			return
+		}
+	}
+
+	images, err := h.st.ListImagesFiltered(r.Context(), store.ImageFilter{Day: day})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var items []imghash.Item
+	for _, img := range images {
+		if hash, err := imghash.Parse(img.PHash); err == nil {
+			items = append(items, imghash.Item{ID: img.ID, FetchedAt: img.FetchedAt, Hash: hash})
+		}
+	}
+
+	clusters := []imghash.Cluster{}
+	duplicates := 0
+	for _, c := range imghash.Group(items, threshold, window) {
+		if len(c.Members) > 1 {
+			clusters = append(clusters, c)
+			duplicates += len(c.Members) - 1
+		}
+	}
+
+	writeJSON(w, http.StatusOK, apitypes.Dedup{
+		Threshold:  threshold,
+		Window:     window.String(),
+		Images:     len(images),
+		Hashed:     len(items),
+		Duplicates: duplicates,
+		Clusters:   clusters,
+	})
+}
+
+// POST /api/dataset/phash/backfill - hash all images that don't have a perceptual hash yet
+func (h *DedupHandler) handleStartBackfill(w http.ResponseWriter, r *http.Request) {
+	if !h.StartBackfill() {
+		writeError(w, http.StatusConflict, "backfill already running")
+		return
+	}
+	writeJSON(w, http.StatusAccepted, apitypes.Message{Message: "backfill started"})
+}
+
+// GET /api/dataset/phash/backfill - progress of the last backfill
+func (h *DedupHandler) handleBackfillStatus(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	status := h.backfill
+	h.mu.Unlock()
+	writeJSON(w, http.StatusOK, status)
+}
+
+// StartBackfill runs a perceptual hash backfill in the background.
+// It returns false if one is already running.
+func (h *DedupHandler) StartBackfill() bool {
+	h.mu.Lock()
+	if h.backfill.Running {
+		h.mu.Unlock()
+		return false
+	}
+	h.backfill = apitypes.PHashBackfill{Running: true, StartedAt: time.Now().UTC()}
+	h.mu.Unlock()
+
+	go func() {
+		res, err := imghash.Backfill(context.Background(), h.st, func(p imghash.BackfillResult) {
+			h.mu.Lock()
+			h.backfill.Result = p
+			h.mu.Unlock()
+		})
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.backfill.Running = false
+		h.backfill.FinishedAt = time.Now().UTC()
+		h.backfill.Result = res
+		if err != nil {
+			h.backfill.Error = err.Error()
+			log.Printf("imghash: backfill failed: %v", err)
+			return
+		}
+		if res.Hashed > 0 || res.Failed > 0 {
+			log.Printf("imghash: backfill hashed %d images (%d failed)", res.Hashed, res.Failed)
+		}
+	}()
+	return true
+}
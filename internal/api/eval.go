@@ -0,0 +1,281 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/infer"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+const (
+	defaultCalibrateLimit = 2000
+	defaultPairsLimit     = 50
+	maxPairsLimit         = 500
+)
+
+var errNoCalibrationSamples = errors.New("no labeled images matching the model classes")
+
+// EvalHandler runs evaluation jobs against the labeled dataset.
+type EvalHandler struct {
+	st   *store.Store
+	pred *infer.ORTPredictor
+
+	mu    sync.Mutex
+	calib calibrateStatus
+}
+
+type calibrateStatus struct {
+	Running      bool               `json:"running"`
+	ModelVersion string             `json:"model_version,omitempty"`
+	Split        string             `json:"split"` // "all" = every labeled image
+	StartedAt    time.Time          `json:"started_at,omitempty"`
+	FinishedAt   time.Time          `json:"finished_at,omitempty"`
+	Processed    int                `json:"processed"`
+	Total        int                `json:"total"`
+	Skipped      int                `json:"skipped"`
+	Applied      bool               `json:"applied"`
+	Error        string             `json:"error,omitempty"`
+	Result       *infer.Calibration `json:"result,omitempty"`
+}
+
+// NewEvalHandler creates a new eval API handler
+func NewEvalHandler(st *store.Store, pred *infer.ORTPredictor) *EvalHandler {
+	return &EvalHandler{st: st, pred: pred}
+}
+
+// RegisterRoutes registers the eval API routes
+func (h *EvalHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/eval/calibrate", h.startCalibrate)
+	mux.HandleFunc("GET /api/eval/calibrate", h.getCalibrate)
+	mux.HandleFunc("GET /api/eval/{id}/pairs", h.getPairs)
+}
+
+// GET /api/eval/{id}/pairs?pred=clear&truth=heavy_clouds - the images of one
+// confusion cell of an evaluation, most confident first, for relabeling. {id}
+// is a version's eval_id from GET /api/models/compare. Each image carries its
+// current label; relabel with POST label_url {"image_id", "skystate"}.
+// Query params:
+//   - pred, truth: predicted and labeled class (required)
+//   - limit: page size (default 50, max 500)
+//   - offset: from next_offset of the previous page
+func (h *EvalHandler) getPairs(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	q := r.URL.Query()
+	pred, truth := strings.TrimSpace(q.Get("pred")), strings.TrimSpace(q.Get("truth"))
+	if pred == "" || truth == "" {
+		writeError(w, http.StatusBadRequest, "pred and truth are required, e.g. pred=clear&truth=heavy_clouds")
+		return
+	}
+	limit := defaultPairsLimit
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = min(n, maxPairsLimit)
+	}
+	offset := 0
+	if raw := q.Get("offset"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid offset")
+			return
+		}
+		offset = n
+	}
+
+	ctx := r.Context()
+	ok, err := h.st.HasEvalResults(ctx, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "no results for evaluation "+id+"; run GET /api/models/compare again")
+		return
+	}
+	pairs, total, err := h.st.EvalPairs(ctx, id, pred, truth, limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	type pairItem struct {
+		store.EvalPair
+		URL string `json:"url"`
+	}
+	items := make([]pairItem, 0, len(pairs))
+	for _, p := range pairs {
+		items = append(items, pairItem{EvalPair: p, URL: "/images/" + filepath.Base(p.Path)})
+	}
+	var nextOffset any = nil
+	if offset+len(items) < total {
+		nextOffset = offset + len(items)
+	}
+	version, splitHash, _ := store.ParseEvalID(id)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"eval_id":     id,
+		"version":     version,
+		"split_hash":  splitHash,
+		"pred":        pred,
+		"truth":       truth,
+		"total":       total,
+		"count":       len(items),
+		"items":       items,
+		"next_offset": nextOffset,
+		"label_url":   "/api/labels",
+	})
+}
+
+// POST /api/eval/calibrate - fit a softmax temperature on labeled images
+// Query params:
+//   - limit: max labeled images to use, newest first (default 2000)
+//   - split: dataset split to use (default "test"; "all" = every labeled image)
+//   - apply: "0" to only report the fit without writing meta.json
+func (h *EvalHandler) startCalibrate(w http.ResponseWriter, r *http.Request) {
+	mi := h.pred.ActiveModel()
+	if mi == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "no model loaded"})
+		return
+	}
+
+	limit := defaultCalibrateLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid limit"})
+			return
+		}
+		limit = n
+	}
+	apply := r.URL.Query().Get("apply") != "0"
+
+	split := r.URL.Query().Get("split")
+	switch {
+	case split == "":
+		split = store.SplitTest
+	case split == "all":
+		split = ""
+	case !slices.Contains(store.Splits, split):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid split; use train, val, test or all"})
+		return
+	}
+
+	h.mu.Lock()
+	if h.calib.Running {
+		h.mu.Unlock()
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "calibration already running"})
+		return
+	}
+	h.calib = calibrateStatus{Running: true, ModelVersion: mi.Version, Split: cmp.Or(split, "all"), StartedAt: time.Now().UTC()}
+	h.mu.Unlock()
+
+	// Job outlives the request
+	go h.runCalibrate(context.Background(), mi, limit, split, apply)
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"message": "calibration started"})
+}
+
+// GET /api/eval/calibrate - progress and result of the last calibration job
+func (h *EvalHandler) getCalibrate(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	status := h.calib
+	h.mu.Unlock()
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (h *EvalHandler) runCalibrate(ctx context.Context, mi *infer.ModelInfo, limit int, split string, apply bool) {
+	result, err := h.fitCalibration(ctx, mi, limit, split)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.calib.Running = false
+	h.calib.FinishedAt = time.Now().UTC()
+	if err != nil {
+		h.calib.Error = err.Error()
+		log.Printf("eval: calibration failed: %v", err)
+		return
+	}
+	h.calib.Result = result
+
+	if !apply {
+		return
+	}
+	if err := infer.SaveCalibration(mi.Dir, result); err != nil {
+		h.calib.Error = err.Error()
+		log.Printf("eval: save calibration: %v", err)
+		return
+	}
+	h.pred.SetCalibration(mi.Version, result)
+	h.calib.Applied = true
+	log.Printf("eval: calibration applied to %s: T=%.3f (nll %.4f -> %.4f, n=%d)",
+		mi.Version, result.Temperature, result.NLLBefore, result.NLLAfter, result.Samples)
+}
+
+func (h *EvalHandler) fitCalibration(ctx context.Context, mi *infer.ModelInfo, limit int, split string) (*infer.Calibration, error) {
+	items, err := h.st.ListImagesFiltered(ctx, store.ImageFilter{Limit: limit, LabeledOnly: true, Split: split, ExcludeUnpredictable: true})
+	if err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	h.calib.Total = len(items)
+	h.mu.Unlock()
+
+	var (
+		logits [][]float32
+		labels []int
+	)
+	for _, it := range items {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		idx, ok := mi.Classes[*it.Skystate]
+		if !ok {
+			h.bumpCalibrate(false)
+			continue
+		}
+		pred, err := h.pred.PredictImageOpts(ctx, it.Path, infer.PredictOptions{Logits: true})
+		if err != nil || pred == nil || pred.ModelVer != mi.Version {
+			// model changed underneath or unreadable image
+			h.bumpCalibrate(false)
+			continue
+		}
+		logits = append(logits, pred.Logits)
+		labels = append(labels, idx)
+		h.bumpCalibrate(true)
+	}
+
+	if len(logits) == 0 {
+		return nil, errNoCalibrationSamples
+	}
+
+	t, before, after := infer.FitTemperature(logits, labels)
+	return &infer.Calibration{
+		Temperature: t,
+		Samples:     len(logits),
+		NLLBefore:   before,
+		NLLAfter:    after,
+		FittedAt:    time.Now().UTC().Format(time.RFC3339),
+	}, nil
+}
+
+func (h *EvalHandler) bumpCalibrate(used bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.calib.Processed++
+	if !used {
+		h.calib.Skipped++
+	}
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/png"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/SkyClf/SkyClf/internal/infer"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+const (
+	defaultExplainGrid = 7
+	maxExplainCache    = 64
+)
+
+// ExplainHandler serves occlusion saliency maps for stored images.
+type ExplainHandler struct {
+	st   *store.Store
+	pred *infer.ORTPredictor
+
+	// one explain at a time: each costs grid*grid inferences
+	sem chan struct{}
+
+	mu    sync.Mutex
+	cache map[string]*infer.Saliency
+	order []string // insertion order for eviction
+}
+
+// NewExplainHandler creates a new explainability API handler
+func NewExplainHandler(st *store.Store, pred *infer.ORTPredictor) *ExplainHandler {
+	return &ExplainHandler{
+		st:    st,
+		pred:  pred,
+		sem:   make(chan struct{}, 1),
+		cache: make(map[string]*infer.Saliency),
+	}
+}
+
+// RegisterRoutes registers the explain API routes
+func (h *ExplainHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/images/{id}/explain", h.handleExplain)
+}
+
+// GET /api/images/{id}/explain?grid=7&format=json|png
+func (h *ExplainHandler) handleExplain(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	grid := defaultExplainGrid
+	if raw := q.Get("grid"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > infer.MaxOcclusionGrid {
+			http.Error(w, fmt.Sprintf("grid must be between 1 and %d", infer.MaxOcclusionGrid), http.StatusBadRequest)
+			return
+		}
+		grid = n
+	}
+	format := q.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "png" {
+		http.Error(w, "format must be json or png", http.StatusBadRequest)
+		return
+	}
+
+	img, err := h.st.GetImage(r.Context(), r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if img == nil {
+		http.Error(w, "image not found", http.StatusNotFound)
+		return
+	}
+	if !store.Predictable(img.Format) {
+		http.Error(w, img.Format+" images can't be classified", http.StatusUnprocessableEntity)
+		return
+	}
+
+	mi := h.pred.ActiveModel()
+	if mi == nil {
+		http.Error(w, "no model loaded", http.StatusServiceUnavailable)
+		return
+	}
+
+	view, _ := json.Marshal(h.pred.Preprocess()) // saliency depends on the mask/crop too
+	key := fmt.Sprintf("%s|%s|%d|%s", img.SHA256, mi.Version, grid, view)
+	sal := h.cached(key)
+	if sal == nil {
+		select {
+		case h.sem <- struct{}{}:
+		case <-r.Context().Done():
+			return
+		}
+		sal, err = h.pred.Occlusion(r.Context(), img.Path, grid)
+		<-h.sem
+		if err != nil {
+			if errors.Is(err, infer.ErrNoModel) {
+				http.Error(w, "no model loaded", http.StatusServiceUnavailable)
+				return
+			}
+			if errors.Is(err, infer.ErrBadModelOutput) {
+				writePredictError(w, err)
+				return
+			}
+			http.Error(w, "explain failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		h.store(key, sal)
+	}
+
+	if format == "json" {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"image_id": img.ID,
+			"saliency": sal,
+		})
+		return
+	}
+
+	overlay, err := infer.RenderSaliency(img.Path, sal)
+	if err != nil {
+		http.Error(w, "render failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	_ = png.Encode(w, overlay)
+}
+
+func (h *ExplainHandler) cached(key string) *infer.Saliency {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.cache[key]
+}
+
+func (h *ExplainHandler) store(key string, s *infer.Saliency) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.cache[key]; ok {
+		return
+	}
+	h.cache[key] = s
+	h.order = append(h.order, key)
+	if len(h.order) > maxExplainCache {
+		delete(h.cache, h.order[0])
+		h.order = h.order[1:]
+	}
+}
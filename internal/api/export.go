@@ -0,0 +1,180 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/export"
+	"github.com/SkyClf/SkyClf/internal/imghash"
+	"github.com/SkyClf/SkyClf/internal/infer"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// GET /api/dataset/export - labeled images as a CSV training file list
+// Query params: date, resolution, exposure_min, exposure_max (as for the image list),
+// split (train, val, test or unassigned), dedup=1 with optional dedup_threshold
+// (bits) and dedup_window (duration), include_fits=1 to also list FITS images,
+// exclude_unreviewed=1 to leave out labels awaiting review, include=weather to
+// add the nearest weather reading as extra columns, snapshot=<name> to take the
+// labels from a dataset snapshot instead of the live ones, oversample=balance or
+// oversample=precipitation:4,... with optional oversample_seed to repeat
+// rare-class train images (what was done is returned as JSON in the
+// X-SkyClf-Oversample header).
+// Each row carries the image's split.
+// A configured mask/crop is returned as JSON in the X-SkyClf-Preprocess header.
+// The query runs on the request context, so a disconnecting client aborts it.
+func (h *DatasetHandler) handleExport(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseExportOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	oversample, err := parseOversample(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !h.snapshotExists(w, r, opts.Filter.Snapshot) {
+		return
+	}
+
+	items, err := export.Select(r.Context(), h.st, opts)
+	if err != nil {
+		if r.Context().Err() != nil {
+			log.Printf("api: export aborted: %v", r.Context().Err())
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Record the mask/crop so training sees the same view as inference
+	var pre infer.PreprocessConfig
+	if _, err := h.st.GetSetting(r.Context(), store.SettingPreprocess, &pre); err != nil {
+		log.Printf("api: export preprocess setting: %v", err)
+	} else if !pre.IsZero() {
+		view, _ := json.Marshal(pre)
+		w.Header().Set("X-SkyClf-Preprocess", string(view))
+	}
+	if oversample != nil {
+		var res export.OversampleResult
+		items, res = export.ApplyOversample(items, *oversample)
+		done, _ := json.Marshal(res)
+		w.Header().Set("X-SkyClf-Oversample", string(done))
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="skyclf-export.csv"`)
+	if err := export.WriteCSV(w, items, opts.Weather); err != nil {
+		log.Printf("api: export write: %v", err)
+	}
+}
+
+// GET /api/dataset/export/annotations - bounding boxes of the exported images as CSV
+// Accepts the same query params as /api/dataset/export.
+func (h *DatasetHandler) handleExportAnnotations(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseExportOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !h.snapshotExists(w, r, opts.Filter.Snapshot) {
+		return
+	}
+
+	items, err := export.Select(r.Context(), h.st, opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	anns, err := h.st.ListAnnotations(r.Context(), "")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="skyclf-annotations.csv"`)
+	if err := export.WriteAnnotationsCSV(w, items, anns); err != nil {
+		log.Printf("api: annotations export write: %v", err)
+	}
+}
+
+func parseExportOptions(q url.Values) (export.Options, error) {
+	filter, err := parseImageFilter(q)
+	if err != nil {
+		return export.Options{}, err
+	}
+	opts := export.Options{
+		Filter:         filter,
+		Dedup:          q.Get("dedup") == "1" || q.Get("dedup") == "true",
+		DedupThreshold: imghash.DefaultThreshold,
+		DedupWindow:    imghash.DefaultWindow,
+		IncludeFITS:    isTrue(q.Get("include_fits")),
+
+		ExcludeUnreviewed: isTrue(q.Get("exclude_unreviewed")),
+		Weather:           hasInclude(q.Get("include"), "weather"),
+	}
+	if name := q.Get("snapshot"); name != "" {
+		if !store.ValidSnapshotName(name) {
+			return opts, errors.New("invalid snapshot name")
+		}
+		opts.Filter.Snapshot = name
+	}
+	if raw := q.Get("dedup_threshold"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 || n > 64 {
+			return opts, errors.New("invalid dedup_threshold (0-64)")
+		}
+		opts.DedupThreshold = n
+	}
+	if raw := q.Get("dedup_window"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return opts, errors.New("invalid dedup_window")
+		}
+		opts.DedupWindow = d
+	}
+	return opts, nil
+}
+
+// parseOversample reads the oversample and oversample_seed params; nil
+// without oversample.
+func parseOversample(q url.Values) (*export.Oversample, error) {
+	spec := q.Get("oversample")
+	if spec == "" {
+		return nil, nil
+	}
+	var seed int64
+	if raw := q.Get("oversample_seed"); raw != "" {
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid oversample_seed")
+		}
+		seed = n
+	}
+	return export.ParseOversample(spec, seed)
+}
+
+// snapshotExists answers 404 and returns false if name is set but no such
+// snapshot exists.
+func (h *DatasetHandler) snapshotExists(w http.ResponseWriter, r *http.Request, name string) bool {
+	if name == "" {
+		return true
+	}
+	snap, err := h.st.GetSnapshot(r.Context(), name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if snap == nil {
+		http.Error(w, "snapshot "+name+" not found", http.StatusNotFound)
+		return false
+	}
+	return true
+}
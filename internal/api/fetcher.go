@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/SkyClf/SkyClf/internal/fetcher"
+	"github.com/SkyClf/SkyClf/internal/ingest"
+)
+
+// FetcherHandler exposes the image fetcher state.
+type FetcherHandler struct {
+	fetch *fetcher.Fetcher // nil when fetching is disabled (SKYCLF_MODE=no-fetch)
+	scan  *ingest.Scanner  // directory scanner used instead; may be nil
+}
+
+// NewFetcherHandler creates a new fetcher API handler. f is nil when fetching
+// is disabled.
+func NewFetcherHandler(f *fetcher.Fetcher) *FetcherHandler {
+	return &FetcherHandler{fetch: f}
+}
+
+// SetScanner reports the directory scanner that replaces the fetcher in
+// no-fetch mode; a poll request triggers a scan instead.
+func (h *FetcherHandler) SetScanner(s *ingest.Scanner) {
+	h.scan = s
+}
+
+// RegisterRoutes registers the fetcher API routes
+func (h *FetcherHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/fetcher/status", h.getStatus)
+	mux.HandleFunc("POST /api/fetcher/poll", h.pollNow)
+}
+
+// GET /api/fetcher/status - last attempt/success and the kind of the last failure;
+// with fetching disabled, {"enabled": false} and the directory scan state
+func (h *FetcherHandler) getStatus(w http.ResponseWriter, r *http.Request) {
+	if h.fetch == nil {
+		resp := map[string]any{"enabled": false, "reason": "fetching is disabled (SKYCLF_MODE=no-fetch or watch)"}
+		if h.scan != nil {
+			resp["scan"] = h.scan.Status()
+		}
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.fetch.Status())
+}
+
+// POST /api/fetcher/poll - fetch immediately regardless of the current interval
+// (scan the images directory when fetching is disabled)
+func (h *FetcherHandler) pollNow(w http.ResponseWriter, r *http.Request) {
+	if h.fetch == nil {
+		if h.scan == nil {
+			writeError(w, http.StatusConflict, "fetching is disabled")
+			return
+		}
+		h.scan.ScanNow()
+		writeJSON(w, http.StatusAccepted, map[string]string{"message": "scan triggered"})
+		return
+	}
+	h.fetch.PollNow()
+	writeJSON(w, http.StatusAccepted, map[string]string{"message": "poll triggered"})
+}
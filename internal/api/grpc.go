@@ -0,0 +1,252 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	skyclfv1 "github.com/SkyClf/SkyClf/api/proto/skyclf/v1"
+	"github.com/SkyClf/SkyClf/internal/infer"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// maxGRPCImage bounds the image of a Predict call, as for POST /api/predict.
+const maxGRPCImage = 12 << 20
+
+// GRPCServer serves the gRPC API (api/proto/skyclf/v1) over the services of
+// the HTTP handlers: predictions go through the latest handler's cache and
+// queue, labels and stats through the store. Calls pass the same login check
+// as the HTTP API, with the credentials in "authorization" metadata.
+type GRPCServer struct {
+	skyclfv1.UnimplementedSkyClfServer
+
+	st     *store.Store
+	latest *LatestHandler
+	auth   *Authenticator
+	srv    *grpc.Server
+}
+
+// NewGRPCServer creates the gRPC API; auth may be nil (open).
+func NewGRPCServer(st *store.Store, latest *LatestHandler, auth *Authenticator) *GRPCServer {
+	g := &GRPCServer{st: st, latest: latest, auth: auth}
+	g.srv = grpc.NewServer(
+		grpc.UnaryInterceptor(g.authorize),
+		grpc.MaxRecvMsgSize(maxGRPCImage+1<<20),
+	)
+	skyclfv1.RegisterSkyClfServer(g.srv, g)
+	return g
+}
+
+// Serve accepts calls on addr until Stop; it returns nil after Stop.
+func (g *GRPCServer) Serve(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return g.srv.Serve(l)
+}
+
+// Stop refuses new calls and waits up to timeout for running ones before
+// closing their connections.
+func (g *GRPCServer) Stop(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		g.srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		g.srv.Stop()
+	}
+}
+
+// authorize applies the Authenticator's rules to a call: open without a
+// login, else a bearer token or Basic credentials in its metadata. Session
+// cookies don't apply.
+func (g *GRPCServer) authorize(ctx context.Context, req any, _ *grpc.UnaryServerInfo, next grpc.UnaryHandler) (any, error) {
+	if g.auth == nil {
+		return next(ctx, req)
+	}
+	loaded, required := g.auth.state()
+	if !loaded {
+		return nil, status.Error(codes.Unavailable, "starting up")
+	}
+	if !required {
+		return next(ctx, req)
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	who, _, ok := g.auth.identify(&http.Request{Header: http.Header{"Authorization": md.Get("authorization")}})
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "login required")
+	}
+	return next(context.WithValue(ctx, identityKey{}, who), req)
+}
+
+// Predict classifies req.Image or, without one, the newest frame like /api/clf.
+func (g *GRPCServer) Predict(ctx context.Context, req *skyclfv1.PredictRequest) (*skyclfv1.PredictResponse, error) {
+	if len(req.Image) > 0 {
+		return g.predictUpload(ctx, req.Image)
+	}
+	latest, err := g.st.GetLatest(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if latest == nil {
+		return nil, status.Error(codes.NotFound, "no image")
+	}
+	if !store.Predictable(latest.Format) {
+		return nil, status.Error(codes.FailedPrecondition, "latest image is "+latest.Format+"; it can't be classified")
+	}
+	pred, _, err := g.latest.predictLatest(ctx, latest.ID, latest.Path)
+	if err != nil {
+		return nil, predictStatus(err)
+	}
+	if pred == nil {
+		return nil, status.Error(codes.Unavailable, "no model loaded")
+	}
+	return &skyclfv1.PredictResponse{Prediction: grpcPrediction(pred), Image: grpcImage(latest)}, nil
+}
+
+// predictUpload classifies an image sent with the call, like POST /api/predict.
+func (g *GRPCServer) predictUpload(ctx context.Context, image []byte) (*skyclfv1.PredictResponse, error) {
+	if len(image) > maxGRPCImage {
+		return nil, status.Errorf(codes.InvalidArgument, "image larger than %d bytes", maxGRPCImage)
+	}
+	tmpPath, _, err := bufferUpload(bytes.NewReader(image))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	defer os.Remove(tmpPath)
+	pred, err := g.latest.queue.Do(ctx, func(ctx context.Context) (*infer.Prediction, error) {
+		return g.latest.pred.PredictImage(ctx, tmpPath)
+	})
+	if err != nil {
+		return nil, predictStatus(err)
+	}
+	if pred == nil {
+		return nil, status.Error(codes.Unavailable, "no model loaded")
+	}
+	return &skyclfv1.PredictResponse{Prediction: grpcPrediction(pred)}, nil
+}
+
+// GetLatest returns the newest frame and its label.
+func (g *GRPCServer) GetLatest(ctx context.Context, _ *skyclfv1.GetLatestRequest) (*skyclfv1.GetLatestResponse, error) {
+	latest, err := g.st.GetLatest(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if latest == nil {
+		return nil, status.Error(codes.NotFound, "no image")
+	}
+	resp := &skyclfv1.GetLatestResponse{Image: grpcImage(latest)}
+	if latest.SkyState != nil {
+		resp.Label = &skyclfv1.Label{Skystate: *latest.SkyState}
+		if latest.Meteor != nil {
+			resp.Label.Meteor = *latest.Meteor
+		}
+		if latest.LabeledAt != nil {
+			resp.Label.LabeledAt = timestamppb.New(*latest.LabeledAt)
+		}
+	}
+	return resp, nil
+}
+
+// SetLabel labels an image with the checks of POST /api/labels.
+func (g *GRPCServer) SetLabel(ctx context.Context, req *skyclfv1.SetLabelRequest) (*skyclfv1.SetLabelResponse, error) {
+	imageID := strings.TrimSpace(req.ImageId)
+	skystate := strings.TrimSpace(req.Skystate)
+	switch {
+	case imageID == "":
+		return nil, status.Error(codes.InvalidArgument, "image_id required")
+	case skystate == "":
+		return nil, status.Error(codes.InvalidArgument, "skystate required")
+	case !validSkystate(skystate):
+		return nil, status.Error(codes.InvalidArgument, "invalid skystate value")
+	}
+	if err := g.st.WriteLabel(ctx, store.LabelWrite{
+		ImageID:     imageID,
+		Skystate:    skystate,
+		Meteor:      req.Meteor,
+		LabeledAt:   time.Now().UTC(),
+		Labeler:     strings.TrimSpace(req.Labeler),
+		NeedsReview: req.NeedsReview,
+	}); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &skyclfv1.SetLabelResponse{}, nil
+}
+
+// GetStats returns the dataset counters.
+func (g *GRPCServer) GetStats(ctx context.Context, req *skyclfv1.GetStatsRequest) (*skyclfv1.GetStatsResponse, error) {
+	count := g.st.CountStats
+	if req.IncludeArchived {
+		count = g.st.CountStatsWithArchived
+	}
+	stats, err := count(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &skyclfv1.GetStatsResponse{
+		Total:          int64(stats.Total),
+		Labeled:        int64(stats.Labeled),
+		Unlabeled:      int64(stats.Unlabeled),
+		ByClass:        counts64(stats.ByClass),
+		ByResolution:   counts64(stats.ByResolution),
+		BySplit:        make(map[string]*skyclfv1.ClassCounts, len(stats.BySplit)),
+		NeedsReview:    int64(stats.NeedsReview),
+		Archived:       int64(stats.Archived),
+		TotalSizeBytes: stats.TotalSizeBytes,
+	}
+	for split, byClass := range stats.BySplit {
+		resp.BySplit[split] = &skyclfv1.ClassCounts{Counts: counts64(byClass)}
+	}
+	return resp, nil
+}
+
+// predictStatus maps a failed prediction like writePredictError does.
+func predictStatus(err error) error {
+	if errors.Is(err, infer.ErrBadModelOutput) {
+		return status.Error(codes.Internal, "the model returned invalid output ("+err.Error()+"); it may be corrupted, see /api/models")
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	return status.Error(codes.Internal, "prediction failed")
+}
+
+func grpcPrediction(p *infer.Prediction) *skyclfv1.Prediction {
+	return &skyclfv1.Prediction{
+		Skystate:     p.SkyState,
+		Confidence:   p.Confidence,
+		Probs:        p.Probs,
+		Task:         p.ModelTask,
+		ModelVersion: p.ModelVer,
+		Calibrated:   p.Calibrated,
+		PreprocessMs: p.PreprocessMS,
+		InferenceMs:  p.InferenceMS,
+	}
+}
+
+func grpcImage(l *store.LatestRow) *skyclfv1.Image {
+	return &skyclfv1.Image{Id: l.ID, Sha256: l.SHA256, FetchedAt: timestamppb.New(l.FetchedAt), Format: l.Format}
+}
+
+func counts64(m map[string]int) map[string]int64 {
+	out := make(map[string]int64, len(m))
+	for k, v := range m {
+		out[k] = int64(v)
+	}
+	return out
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/SkyClf/SkyClf/internal/apitypes"
+)
+
+// GET /api/images/{id} - everything stored about one image: the image row,
+// label and label history, the last prediction per model version,
+// annotations, the nearest weather reading, prediction skips and links to
+// the endpoints serving it. Parts the image doesn't have are null.
+func (h *ImagesHandler) imageDetail(w http.ResponseWriter, r *http.Request) {
+	if h.st == nil {
+		http.NotFound(w, r)
+		return
+	}
+	id := r.PathValue("id")
+	d, err := h.st.GetImageDetail(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if d == nil {
+		http.Error(w, "image not found", http.StatusNotFound)
+		return
+	}
+
+	_, statErr := os.Stat(d.Path)
+	base := "/api/images/" + url.PathEscape(id)
+	writeJSON(w, http.StatusOK, apitypes.ImageDetail{
+		ImageDetail: *d,
+		FileExists:  statErr == nil,
+		Links: apitypes.ImageLinks{
+			File:         imageURL(h.imagesDir, d.Path),
+			Preprocessed: base + "/preprocessed.png",
+			Explain:      base + "/explain",
+			Annotations:  base + "/annotations",
+		},
+	})
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+func TestImageDetail(t *testing.T) {
+	ctx := context.Background()
+	st := openStore(t)
+	imagesDir := t.TempDir()
+	at := time.Date(2024, 10, 3, 21, 30, 0, 0, time.UTC)
+
+	full := filepath.Join(imagesDir, "2024", "20241003_213000.jpg")
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte("jpeg"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.UpsertImage(ctx, "20241003_213000", full, "ab12", at, 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SetImageProvenance(ctx, "ab12", store.Provenance{HTTPStatus: 200, ContentLength: 4, BytesWritten: 4}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.WriteLabel(ctx, store.LabelWrite{ImageID: "20241003_213000", Skystate: "clear", LabeledAt: at}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.RecordPrediction(ctx, store.PredictionRecord{ImageID: "20241003_213000", ModelVersion: "v3", Skystate: "clear", Confidence: 0.9, PredictedAt: at}); err != nil {
+		t.Fatal(err)
+	}
+	// No file, label, prediction or provenance
+	if err := st.UpsertImage(ctx, "bare one", filepath.Join(imagesDir, "gone.jpg"), "cd34", at, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewImagesHandler(imagesDir)
+	h.SetStore(st)
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	get := func(t *testing.T, target string, want int) []byte {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != want {
+			t.Fatalf("%s: status %d, want %d: %s", target, rec.Code, want, rec.Body)
+		}
+		return rec.Body.Bytes()
+	}
+
+	t.Run("full", func(t *testing.T) {
+		var d struct {
+			ID          string
+			FileExists  bool `json:"file_exists"`
+			Label       *store.ImageLabel
+			Predictions []store.ImagePrediction
+			Provenance  *store.Provenance
+			Links       struct{ File, Annotations string }
+		}
+		if err := json.Unmarshal(get(t, "/api/images/20241003_213000", http.StatusOK), &d); err != nil {
+			t.Fatal(err)
+		}
+		if d.ID != "20241003_213000" || !d.FileExists {
+			t.Fatalf("id %s, file exists %t", d.ID, d.FileExists)
+		}
+		if d.Label == nil || d.Label.Skystate != "clear" || len(d.Predictions) != 1 || d.Predictions[0].ModelVersion != "v3" {
+			t.Fatalf("label %+v, predictions %+v", d.Label, d.Predictions)
+		}
+		if d.Provenance == nil || d.Provenance.BytesWritten != 4 {
+			t.Fatalf("provenance %+v", d.Provenance)
+		}
+		if d.Links.File != "/images/2024/20241003_213000.jpg" || d.Links.Annotations != "/api/images/20241003_213000/annotations" {
+			t.Fatalf("links %+v", d.Links)
+		}
+	})
+
+	t.Run("null parts", func(t *testing.T) {
+		var body map[string]json.RawMessage
+		if err := json.Unmarshal(get(t, "/api/images/bare%20one", http.StatusOK), &body); err != nil {
+			t.Fatal(err)
+		}
+		for _, k := range []string{"label", "suggestion", "provenance", "weather", "meta", "captured_at"} {
+			if string(body[k]) != "null" {
+				t.Errorf("%s = %s, want null", k, body[k])
+			}
+		}
+		for _, k := range []string{"label_history", "predictions", "annotations", "skips"} {
+			if string(body[k]) != "[]" {
+				t.Errorf("%s = %s, want []", k, body[k])
+			}
+		}
+		if string(body["file_exists"]) != "false" {
+			t.Errorf("file_exists = %s", body["file_exists"])
+		}
+		var links struct{ Explain string }
+		if err := json.Unmarshal(body["links"], &links); err != nil || links.Explain != "/api/images/bare%20one/explain" {
+			t.Errorf("links %s", body["links"])
+		}
+	})
+
+	t.Run("unknown id", func(t *testing.T) {
+		get(t, "/api/images/20240101_000000", http.StatusNotFound)
+	})
+
+	t.Run("no store", func(t *testing.T) {
+		mux := http.NewServeMux()
+		NewImagesHandler(imagesDir).RegisterRoutes(mux)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/images/20241003_213000", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("status %d, want 404", rec.Code)
+		}
+	})
+}
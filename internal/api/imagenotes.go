@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maxSearchLen     = 200  // ?q= on the image list
+	maxSearchResults = 200  // matches returned by a search unless ?limit= is lower
+	maxNotesLen      = 2000 // characters of free-text notes per image
+)
+
+// handleSetImageNotes sets the free-text notes of an image, which ?q= on the
+// image list searches. An empty string clears them.
+// PATCH /api/images/{id} {"notes": "..."}
+func (h *DatasetHandler) handleSetImageNotes(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Notes *string `json:"notes"`
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if req.Notes == nil {
+		http.Error(w, "notes required", http.StatusBadRequest)
+		return
+	}
+	notes := strings.TrimSpace(*req.Notes)
+	if n := utf8.RuneCountInString(notes); n > maxNotesLen {
+		http.Error(w, fmt.Sprintf("notes too long (%d characters, max %d)", n, maxNotesLen), http.StatusBadRequest)
+		return
+	}
+	if strings.ContainsFunc(notes, func(c rune) bool { return unicode.IsControl(c) && c != '\n' && c != '\t' }) {
+		http.Error(w, "notes must not contain control characters", http.StatusBadRequest)
+		return
+	}
+
+	id := r.PathValue("id")
+	found, err := h.st.SetImageNotes(r.Context(), id, notes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "image not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "id": id, "notes": notes})
+}
@@ -6,11 +6,15 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+
+	"github.com/SkyClf/SkyClf/internal/fetcher"
+	"github.com/SkyClf/SkyClf/internal/store"
 )
 
 // ImagesHandler handles requests to list and serve images.
 type ImagesHandler struct {
 	imagesDir string
+	st        *store.Store // nil = latest is the newest file name
 }
 
 // NewImagesHandler creates a new ImagesHandler.
@@ -18,6 +22,25 @@ func NewImagesHandler(imagesDir string) *ImagesHandler {
 	return &ImagesHandler{imagesDir: imagesDir}
 }
 
+// SetStore makes the latest image the store's latest, like /api/latest, so
+// stale frames never become latest. Files not in the store are ignored then.
+func (h *ImagesHandler) SetStore(st *store.Store) {
+	h.st = st
+}
+
+// latestStored returns the path of the store's latest image. ok is false
+// without a store or when it has no image.
+func (h *ImagesHandler) latestStored(r *http.Request) (path string, ok bool, err error) {
+	if h.st == nil {
+		return "", false, nil
+	}
+	latest, err := h.st.GetLatest(r.Context())
+	if err != nil || latest == nil {
+		return "", false, err
+	}
+	return latest.Path, true, nil
+}
+
 // ImageInfo represents metadata about an image.
 type ImageInfo struct {
 	Name string `json:"name"`
@@ -33,8 +56,36 @@ func (h *ImagesHandler) RegisterRoutes(mux *http.ServeMux) {
 	// Get latest image info
 	mux.HandleFunc("GET /api/images/latest", h.latestImage)
 
+	// Everything stored about one image
+	mux.HandleFunc("GET /api/images/{id}", h.imageDetail)
+
 	// Serve image files
-	mux.Handle("GET /images/", http.StripPrefix("/images/", http.FileServer(http.Dir(h.imagesDir))))
+	mux.HandleFunc("GET /images/", h.serveImage)
+}
+
+// serveImage serves one image file by name, or by path relative to the images
+// directory for date-partitioned layouts. In strict mode traversal attempts
+// get 400.
+func (h *ImagesHandler) serveImage(w http.ResponseWriter, r *http.Request) {
+	if !strictPaths.Load() {
+		http.StripPrefix("/images/", http.FileServer(http.Dir(h.imagesDir))).ServeHTTP(w, r)
+		return
+	}
+	if badRequestPath(r) {
+		http.Error(w, errUnsafePath.Error(), http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/images/")
+	p, err := safeJoin(h.imagesDir, name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if info, err := os.Stat(p); err != nil || !info.Mode().IsRegular() {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFile(w, r, p)
 }
 
 // listImages returns a JSON list of all images.
@@ -51,7 +102,7 @@ func (h *ImagesHandler) listImages(w http.ResponseWriter, r *http.Request) {
 
 	var images []ImageInfo
 	for _, e := range entries {
-		if e.IsDir() || !strings.HasSuffix(strings.ToLower(e.Name()), ".jpg") {
+		if e.IsDir() || !fetcher.IsImageFile(e.Name()) {
 			continue
 		}
 		info, err := e.Info()
@@ -75,6 +126,20 @@ func (h *ImagesHandler) listImages(w http.ResponseWriter, r *http.Request) {
 
 // latestImage returns info about the most recent image.
 func (h *ImagesHandler) latestImage(w http.ResponseWriter, r *http.Request) {
+	if p, ok, err := h.latestStored(r); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	} else if ok {
+		info, err := os.Stat(p)
+		if err != nil {
+			http.Error(w, "latest image file missing", http.StatusNotFound)
+			return
+		}
+		url := imageURL(h.imagesDir, p)
+		writeJSON(w, http.StatusOK, ImageInfo{Name: strings.TrimPrefix(url, "/images/"), URL: url, Size: info.Size()})
+		return
+	}
+
 	entries, err := os.ReadDir(h.imagesDir)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -88,7 +153,7 @@ func (h *ImagesHandler) latestImage(w http.ResponseWriter, r *http.Request) {
 	var latest string
 	var latestSize int64
 	for _, e := range entries {
-		if e.IsDir() || !strings.HasSuffix(strings.ToLower(e.Name()), ".jpg") {
+		if e.IsDir() || !fetcher.IsImageFile(e.Name()) {
 			continue
 		}
 		if e.Name() > latest {
@@ -113,6 +178,14 @@ func (h *ImagesHandler) latestImage(w http.ResponseWriter, r *http.Request) {
 
 // ServeLatestImage serves the actual latest image file (for direct embedding).
 func (h *ImagesHandler) ServeLatestImage(w http.ResponseWriter, r *http.Request) {
+	if p, ok, err := h.latestStored(r); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	} else if ok {
+		http.ServeFile(w, r, p)
+		return
+	}
+
 	entries, err := os.ReadDir(h.imagesDir)
 	if err != nil || len(entries) == 0 {
 		http.Error(w, "no images found", http.StatusNotFound)
@@ -121,7 +194,7 @@ func (h *ImagesHandler) ServeLatestImage(w http.ResponseWriter, r *http.Request)
 
 	var latest string
 	for _, e := range entries {
-		if e.IsDir() || !strings.HasSuffix(strings.ToLower(e.Name()), ".jpg") {
+		if e.IsDir() || !fetcher.IsImageFile(e.Name()) {
 			continue
 		}
 		if e.Name() > latest {
@@ -136,3 +209,13 @@ func (h *ImagesHandler) ServeLatestImage(w http.ResponseWriter, r *http.Request)
 
 	http.ServeFile(w, r, filepath.Join(h.imagesDir, latest))
 }
+
+// imageURL returns the /images/ URL of the image file at p, which may be in a
+// subdirectory of imagesDir.
+func imageURL(imagesDir, p string) string {
+	rel, err := filepath.Rel(imagesDir, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		rel = filepath.Base(p)
+	}
+	return "/images/" + filepath.ToSlash(rel)
+}
@@ -3,6 +3,8 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/SkyClf/SkyClf/internal/apitypes"
 )
 
 func writeJSON(w http.ResponseWriter, status int, v any) {
@@ -12,3 +14,8 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 	enc.SetIndent("", "  ")
 	_ = enc.Encode(v)
 }
+
+// writeError writes {"error": msg} with proper JSON escaping.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, apitypes.Error{Error: msg})
+}
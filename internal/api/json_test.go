@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SkyClf/SkyClf/internal/apitypes"
+)
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		want string // round-tripped message, "" = msg
+	}{
+		{"plain", "no model loaded", ""},
+		{"quotes", `open "model.onnx": "v3" not found`, ""},
+		{"backslashes", `C:\data\models\v3`, ""},
+		{"newlines", "line one\nline two\r\n", ""},
+		{"tab and control characters", "a\tb\x00c\x1bd\x7f", ""},
+		{"html", "<script>alert('x')</script> & more", ""},
+		{"unicode", "Wolken über München ☁️ 雲 — 81.0°C", ""},
+		{"line separators", "a\u2028b\u2029c", ""},
+		{"invalid utf-8", "bad \xff byte", "bad \ufffd byte"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeError(rec, http.StatusConflict, tt.msg)
+			if rec.Code != http.StatusConflict {
+				t.Fatalf("status %d", rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+				t.Fatalf("Content-Type %q", ct)
+			}
+			if !json.Valid(rec.Body.Bytes()) {
+				t.Fatalf("invalid JSON: %q", rec.Body)
+			}
+			var got apitypes.Error
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			want := tt.want
+			if want == "" {
+				want = tt.msg
+			}
+			if got.Error != want {
+				t.Fatalf("error %q, want %q", got.Error, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/labelsync"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+const (
+	defaultChangesLimit = 500
+	maxChangesLimit     = 5000
+	maxMergeBatch       = 5000
+)
+
+// handleLabelChanges lists labels changed at or after a timestamp, keyed by image sha256.
+// Query params:
+//   - since: RFC3339 timestamp, inclusive (default: beginning of time)
+//   - limit: page size (default 500, max 5000)
+//   - offset: page offset
+func (h *DatasetHandler) handleLabelChanges(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var since time.Time
+	if raw := strings.TrimSpace(q.Get("since")); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			http.Error(w, "invalid since; use RFC3339", http.StatusBadRequest)
+			return
+		}
+		since = t
+	}
+
+	limit := defaultChangesLimit
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxChangesLimit)
+	}
+
+	offset := 0
+	if raw := q.Get("offset"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid offset", http.StatusBadRequest)
+			return
+		}
+		offset = n
+	}
+
+	// Fetch one extra row to know whether another page exists.
+	items, err := h.st.ListLabelChanges(r.Context(), since, limit+1, offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var nextOffset any = nil
+	if len(items) > limit {
+		items = items[:limit]
+		nextOffset = offset + limit
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"count":       len(items),
+		"items":       items,
+		"next_offset": nextOffset,
+	})
+}
+
+type mergeLabelsRequest struct {
+	Source string              `json:"source"`
+	DryRun bool                `json:"dry_run"`
+	Labels []store.LabelChange `json:"labels"`
+}
+
+// handleMergeLabels applies labels from a peer instance (latest labeled_at wins).
+// Dry-run via body "dry_run": true or ?dry_run=1 reports the outcome without writing.
+func (h *DatasetHandler) handleMergeLabels(w http.ResponseWriter, r *http.Request) {
+	var req mergeLabelsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if len(req.Labels) > maxMergeBatch {
+		http.Error(w, "too many labels in one request", http.StatusBadRequest)
+		return
+	}
+	if v := r.URL.Query().Get("dry_run"); v == "1" || strings.EqualFold(v, "true") {
+		req.DryRun = true
+	}
+
+	for _, l := range req.Labels {
+		if strings.TrimSpace(l.SHA256) == "" || l.LabeledAt.IsZero() {
+			http.Error(w, "each label needs sha256 and labeled_at", http.StatusBadRequest)
+			return
+		}
+		if !validSkystate(l.Skystate) {
+			http.Error(w, "invalid skystate value", http.StatusBadRequest)
+			return
+		}
+	}
+
+	source := strings.TrimSpace(req.Source)
+	if source == "" {
+		source = r.RemoteAddr
+	}
+
+	result, err := h.st.MergeLabels(r.Context(), req.Labels, store.LabelSourceSyncPrefix+source, req.DryRun)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// LabelSyncHandler exposes manual sync rounds against the configured peer.
+type LabelSyncHandler struct {
+	syncer *labelsync.Syncer
+}
+
+func NewLabelSyncHandler(s *labelsync.Syncer) *LabelSyncHandler {
+	return &LabelSyncHandler{syncer: s}
+}
+
+func (h *LabelSyncHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/labels/sync", h.handleSync)
+}
+
+// handleSync runs one sync round now; ?dry_run=1 reports what would change without writing.
+func (h *LabelSyncHandler) handleSync(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query().Get("dry_run")
+	dryRun := v == "1" || strings.EqualFold(v, "true")
+
+	rep, err := h.syncer.SyncOnce(r.Context(), dryRun)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, rep)
+}
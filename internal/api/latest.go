@@ -1,190 +1,303 @@
 package api
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
-	"path/filepath"
-	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/SkyClf/SkyClf/internal/apitypes"
 	"github.com/SkyClf/SkyClf/internal/infer"
+	"github.com/SkyClf/SkyClf/internal/ingest"
 	"github.com/SkyClf/SkyClf/internal/store"
 )
 
 type LatestHandler struct {
 	st        *store.Store
 	imagesDir string
-	modelsDir string
 	pred      infer.Predictor
+	clf       *predictionCache
+	queue     *ingest.PredictQueue // nil: predict directly
+
+	predictTimeout time.Duration // how long /api/latest waits for a prediction (0 = no limit)
+
+	loc       *time.Location // of the /latest_annotated.jpg timestamp
+	annotated annotatedCache
 }
 
-func NewLatestHandler(st *store.Store, imagesDir string, modelsDir string, pred infer.Predictor) *LatestHandler {
+func NewLatestHandler(st *store.Store, imagesDir string, pred infer.Predictor) *LatestHandler {
 	return &LatestHandler{
 		st:        st,
 		imagesDir: imagesDir,
-		modelsDir: modelsDir,
 		pred:      pred,
+		clf:       newPredictionCache(0),
 	}
 }
 
+// SetPredictionTTL sets how long the prediction for the latest image is reused
+// by /api/clf and /api/latest; the poll interval is a good fit. Concurrent
+// requests share one inference regardless.
+func (h *LatestHandler) SetPredictionTTL(ttl time.Duration) {
+	h.clf.setTTL(ttl)
+}
+
+// SetPredictionTimeout bounds how long /api/latest waits for the prediction;
+// past it the response has none and the inference finishes in the background
+// for the next poll. /api/clf always waits. 0 disables the limit.
+func (h *LatestHandler) SetPredictionTimeout(d time.Duration) {
+	h.predictTimeout = d
+}
+
+// SetPredictQueue runs the handler's predictions on q, ahead of queued
+// auto-predictions.
+func (h *LatestHandler) SetPredictQueue(q *ingest.PredictQueue) {
+	h.queue = q
+}
+
 func (h *LatestHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/latest", h.handleLatest)
 	mux.HandleFunc("GET /api/clf", h.handleClf)
+	mux.HandleFunc("GET /api/clf/stats", h.handleClfStats)
+	mux.HandleFunc("GET /api/clf/cached", h.handleClfCached)
 	mux.HandleFunc("POST /api/classify", h.handleClassifyUpload)
-	mux.HandleFunc("GET /api/models/download", h.handleDownloadModel)
-	mux.HandleFunc("GET /api/models/list", h.handleListModels)
+	mux.HandleFunc("POST /api/predict", h.handlePredict)
+	mux.HandleFunc("GET /latest_annotated.jpg", h.handleLatestAnnotated)
 }
 
-// handleDownloadModel serves a model file for download, optionally by version
-func (h *LatestHandler) handleDownloadModel(w http.ResponseWriter, r *http.Request) {
-	version := r.URL.Query().Get("version")
-	file := r.URL.Query().Get("file") // model.onnx or model.pt
-	modelDir := filepath.Join(h.modelsDir, "skystate")
-	var modelPath string
-
-	// ensure deterministic ordering
-	entries, err := os.ReadDir(modelDir)
-	if err == nil && version == "" {
-		var vers []string
-		for _, e := range entries {
-			if e.IsDir() && len(e.Name()) > 0 && e.Name()[0] == 'v' {
-				vers = append(vers, e.Name())
-			}
+// handleLatest returns the newest image with its label and prediction.
+// Responses carry a weak ETag over the image, its label and the model version;
+// a poll with a matching If-None-Match gets 304 without running inference.
+// A prediction slower than the timeout is reported as "prediction_status":
+// "timeout". With ?debug=1 the response adds "timings" and is never a 304.
+func (h *LatestHandler) handleLatest(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().UTC()
+	debug := isTrue(r.URL.Query().Get("debug"))
+
+	latest, err := h.st.GetLatest(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	timings := apitypes.LatestTimings{DBGetLatestMS: msSince(now)}
+	w.Header().Set("Cache-Control", "no-cache") // always revalidate
+	if latest == nil {
+		resp := apitypes.Latest{
+			SchemaVersion: apitypes.SchemaVersion,
+			Status:        "no_image",
+			Timestamp:     now.Format(time.RFC3339),
 		}
-		if len(vers) > 0 {
-			sort.Strings(vers)
-			version = vers[len(vers)-1]
+		if debug {
+			timings.TotalMS = msSince(now)
+			resp.Timings = &timings
 		}
+		writeJSON(w, http.StatusOK, resp)
+		return
 	}
 
-	// Download specific version
-	if version != "" {
-		targetDir := filepath.Join(modelDir, version)
-		candidates := []string{"model.onnx", "model.pt"}
-		if file != "" {
-			candidates = []string{file}
-		}
-		for _, fname := range candidates {
-			tryPath := filepath.Join(targetDir, fname)
-			if _, err := os.Stat(tryPath); err == nil {
-				modelPath = tryPath
-				break
-			}
-		}
+	// label: if not labeled yet => unknown
+	label := &apitypes.LatestLabel{SkyState: "unknown", Meteor: latest.Meteor}
+	if latest.SkyState != nil {
+		label.SkyState = *latest.SkyState
 	}
-	if modelPath == "" {
-		http.Error(w, "No model found", http.StatusNotFound)
-		return
+	if latest.LabeledAt != nil {
+		labeledAt := latest.LabeledAt.Format(time.RFC3339)
+		label.LabeledAt = &labeledAt
 	}
-	w.Header().Set("Content-Type", "application/octet-stream")
-	w.Header().Set("Content-Disposition", "attachment; filename=\""+filepath.Base(modelPath)+"\"")
-	http.ServeFile(w, r, modelPath)
-}
 
-// handleListModels lists all available model versions
-func (h *LatestHandler) handleListModels(w http.ResponseWriter, r *http.Request) {
-	modelDir := filepath.Join(h.modelsDir, "skystate")
-	entries, err := os.ReadDir(modelDir)
-	if err != nil {
-		http.Error(w, "No models found", http.StatusNotFound)
+	version := h.modelVersion()
+	etag := latestETag(latest, version)
+	if !debug && etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
-	var models []map[string]any
-	for _, e := range entries {
-		if e.IsDir() && len(e.Name()) > 0 && e.Name()[0] == 'v' {
-			version := e.Name()
-			m := map[string]any{"version": version}
-			// Optional metadata
-			if meta, err := os.ReadFile(filepath.Join(modelDir, version, "meta.json")); err == nil {
-				var metaMap map[string]any
-				if json.Unmarshal(meta, &metaMap) == nil {
-					if created, ok := metaMap["created_at"]; ok {
-						m["created_at"] = created
-					}
-				}
-			}
-			for _, fname := range []string{"model.onnx", "model.pt"} {
-				tryPath := filepath.Join(modelDir, version, fname)
-				if _, err := os.Stat(tryPath); err == nil {
-					m[fname] = "/api/models/download?version=" + version + "&file=" + fname
-				}
-			}
-			models = append(models, m)
+	pred, served, timedOut := h.getPrediction(r, latest.ID, latest.Path, latest.Format)
+	if !debug && (pred != nil || version == "" || !store.Predictable(latest.Format)) {
+		// A failed prediction is retried by the next poll instead
+		w.Header().Set("ETag", etag)
+	}
+
+	resp := apitypes.Latest{
+		SchemaVersion: apitypes.SchemaVersion,
+		Status:        "ok",
+		Timestamp:     now.Format(time.RFC3339),
+		Image: &apitypes.LatestImage{
+			ID:        latest.ID,
+			SHA256:    latest.SHA256,
+			FetchedAt: latest.FetchedAt.Format(time.RFC3339),
+			URL:       imageURL(h.imagesDir, latest.Path),
+			LatestURL: "/latest.jpg",
+		},
+		Label:      label,
+		Prediction: apitypes.NewPrediction(pred),
+	}
+	if timedOut {
+		resp.PredictionStatus = "timeout"
+	}
+	if debug {
+		timings.Source = served
+		timings.Cached = served == servedCache || served == servedCoalesced
+		if pred != nil && served != servedCache {
+			// a cache hit cost this request neither; those stay 0
+			timings.PreprocessMS = pred.PreprocessMS
+			timings.InferenceMS = pred.InferenceMS
 		}
+		timings.TotalMS = msSince(now)
+		resp.Timings = &timings
 	}
-	sort.Slice(models, func(i, j int) bool { return models[i]["version"].(string) > models[j]["version"].(string) })
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(models)
+	writeJSON(w, http.StatusOK, resp)
 }
 
-func (h *LatestHandler) handleLatest(w http.ResponseWriter, r *http.Request) {
-	now := time.Now().UTC()
+// msSince returns the time elapsed since t in fractional milliseconds.
+func msSince(t time.Time) float64 {
+	return float64(time.Since(t).Microseconds()) / 1000
+}
 
-	latest, err := h.st.GetLatest()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	if latest == nil {
-		writeJSON(w, http.StatusOK, map[string]any{
-			"status":    "no_image",
-			"timestamp": now.Format(time.RFC3339),
-			"image":     nil,
-			"label":     nil,
-		})
-		return
+// latestETag identifies what /api/latest reports about latest: the image, its
+// label and the model that classifies it. The timestamp field is not covered.
+func latestETag(latest *store.LatestRow, modelVersion string) string {
+	var labeledAt int64
+	if latest.LabeledAt != nil {
+		labeledAt = latest.LabeledAt.UnixNano()
 	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d\x00%s", latest.SHA256, labeledAt, modelVersion)))
+	return `W/"` + hex.EncodeToString(sum[:12]) + `"`
+}
 
-	filename := filepath.Base(latest.Path)
+// etagMatches reports whether an If-None-Match header lists etag, comparing weakly.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
 
-	// label: if not labeled yet => unknown
-	skystate := "unknown"
-	var meteor any = nil
-	var labeledAt any = nil
-	if latest.SkyState != nil {
-		skystate = *latest.SkyState
+// modelVersion returns the version of the model answering predictions, if known.
+func (h *LatestHandler) modelVersion() string {
+	if vr, ok := h.pred.(infer.VersionReporter); ok {
+		return vr.ModelVersion()
 	}
-	if latest.Meteor != nil {
-		meteor = *latest.Meteor
+	return ""
+}
+
+// getPrediction runs inference if a model is loaded and the image format is
+// classifiable, otherwise returns nil; served says how the cache answered.
+// After the prediction timeout it returns nil with timedOut set, and the
+// inference goes on in the background.
+func (h *LatestHandler) getPrediction(r *http.Request, imageID, imagePath, format string) (pred *infer.Prediction, served string, timedOut bool) {
+	if h.pred == nil || !store.Predictable(format) {
+		return nil, "", false
 	}
-	if latest.LabeledAt != nil {
-		labeledAt = latest.LabeledAt.Format(time.RFC3339)
+	ctx := r.Context()
+	if h.predictTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.predictTimeout)
+		defer cancel()
 	}
+	pred, served, _ = h.predictLatest(ctx, imageID, imagePath) // ignore error for stability
+	return pred, served, pred == nil && errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
 
-	writeJSON(w, http.StatusOK, map[string]any{
-		"status":    "ok",
-		"timestamp": now.Format(time.RFC3339),
-		"image": map[string]any{
-			"id":         latest.ID,
-			"sha256":     latest.SHA256,
-			"fetched_at": latest.FetchedAt.Format(time.RFC3339),
-			"url":        "/images/" + filename, // specific file
-			"latest_url": "/latest.jpg",         // always points to newest file
-		},
-		"label": map[string]any{
-			"skystate":   skystate,
-			"meteor":     meteor,
-			"labeled_at": labeledAt,
-		},
-		"prediction": h.getPrediction(r, latest.Path),
+// predictLatest predicts imagePath through the shared cache and records the
+// prediction once per inference rather than once per request.
+func (h *LatestHandler) predictLatest(ctx context.Context, imageID, imagePath string) (*infer.Prediction, string, error) {
+	key := imagePath + "\x00" + h.modelVersion() // a reload must not serve the old model's answer
+	return h.clf.get(ctx, key, func(ctx context.Context) (*infer.Prediction, error) {
+		pred, err := h.queue.Do(ctx, func(ctx context.Context) (*infer.Prediction, error) {
+			return h.pred.PredictImage(ctx, imagePath)
+		})
+		ingest.RecordPrediction(ctx, h.st, imageID, pred)
+		return pred, err
 	})
 }
 
-// getPrediction runs inference if a model is loaded, otherwise returns nil
-func (h *LatestHandler) getPrediction(r *http.Request, imagePath string) *infer.Prediction {
-	if h.pred == nil {
-		return nil
+// GET /api/clf/stats - how prediction requests were served: inferences run,
+// requests that joined one in flight (coalesced) or reused a fresh result
+func (h *LatestHandler) handleClfStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.clf.snapshot())
+}
+
+// handleClfCached returns the last stored prediction of the newest image that
+// has one, without running inference, so it answers fast even while a model is
+// busy or missing. age_seconds is the age of that image: the prediction
+// describes the sky when it was fetched.
+// GET /api/clf/cached -> {"skystate": ..., "confidence": ..., "probs": {...}, "age_seconds": 42, ...}
+// With ?max_age=10m (or seconds) an older prediction is a 409, so the caller
+// can fall back to GET /api/clf.
+func (h *LatestHandler) handleClfCached(w http.ResponseWriter, r *http.Request) {
+	var maxAge time.Duration
+	if raw := r.URL.Query().Get("max_age"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n >= 0 {
+			maxAge = time.Duration(n) * time.Second
+		} else if d, err := time.ParseDuration(raw); err == nil && d >= 0 {
+			maxAge = d
+		} else {
+			http.Error(w, "invalid max_age; use seconds or a duration like 10m", http.StatusBadRequest)
+			return
+		}
+	}
+
+	p, fetchedAt, err := h.st.LatestPrediction(r.Context())
+	if err != nil {
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	if p == nil {
+		http.Error(w, "no stored prediction", http.StatusNotFound)
+		return
+	}
+	age := max(time.Since(fetchedAt), 0)
+	resp := apitypes.ClfCached{
+		SchemaVersion: apitypes.SchemaVersion,
+		SkyState:      p.Skystate,
+		Confidence:    p.Confidence,
+		Probs:         p.Probs,
+		ImageID:       p.ImageID,
+		ModelVersion:  p.ModelVersion,
+		FetchedAt:     fetchedAt,
+		PredictedAt:   p.PredictedAt,
+		AgeSeconds:    int64(age.Seconds()),
+	}
+	if maxAge > 0 && age > maxAge {
+		resp.Error = fmt.Sprintf("stored prediction is %s old (max_age %s); use /api/clf", age.Truncate(time.Second), maxAge)
+		writeJSON(w, http.StatusConflict, resp)
+		return
 	}
-	pred, _ := h.pred.PredictImage(r.Context(), imagePath) // ignore error for stability
-	return pred
+	writeJSON(w, http.StatusOK, resp)
 }
 
 // handleClf returns only the prediction for the latest image - simple and easy to use
-// GET /api/clf -> {"skystate": "heavy_clouds", "confidence": 0.998, "probs": {...}}
+// GET /api/clf -> {"schema_version": 1, "skystate": "heavy_clouds", "confidence": 0.998, "probs": {...}}
+// GET /api/clf?detail=1[&k=3] additionally returns "logits", "class_names" and "top_k".
+// crop=x,y,w,h / mask=cx,cy,r / preprocess=none override the stored mask/crop for this call.
 func (h *LatestHandler) handleClf(w http.ResponseWriter, r *http.Request) {
-	latest, err := h.st.GetLatest()
+	q := r.URL.Query()
+	override, err := parsePreprocessOverride(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	detail, k, err := parseDetail(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	latest, err := h.st.GetLatest(r.Context())
 	if err != nil {
 		http.Error(w, "db error", http.StatusInternalServerError)
 		return
@@ -198,26 +311,40 @@ func (h *LatestHandler) handleClf(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "no model loaded", http.StatusServiceUnavailable)
 		return
 	}
+	if !store.Predictable(latest.Format) {
+		http.Error(w, "latest image is "+latest.Format+"; it can't be classified", http.StatusUnprocessableEntity)
+		return
+	}
 
-	pred, err := h.pred.PredictImage(r.Context(), latest.Path)
+	var pred *infer.Prediction
+	shared := false // recorded by predictLatest
+	if dp, ok := h.pred.(infer.DetailedPredictor); ok && (detail || override != nil) {
+		pred, err = h.queue.Do(r.Context(), func(ctx context.Context) (*infer.Prediction, error) {
+			return dp.PredictImageOpts(ctx, latest.Path, infer.PredictOptions{Logits: detail, TopK: k, Preprocess: override})
+		})
+	} else {
+		pred, _, err = h.predictLatest(r.Context(), latest.ID, latest.Path)
+		shared = true
+	}
 	if err != nil {
-		http.Error(w, "prediction failed", http.StatusInternalServerError)
+		writePredictError(w, err)
 		return
 	}
 	if pred == nil {
 		http.Error(w, "no prediction", http.StatusServiceUnavailable)
 		return
 	}
+	if override == nil && !shared {
+		// experimental views would skew the stored prediction history
+		ingest.RecordPrediction(r.Context(), h.st, latest.ID, pred)
+	}
 
 	// Simple response: just skystate, confidence, probs
-	writeJSON(w, http.StatusOK, map[string]any{
-		"skystate":   pred.SkyState,
-		"confidence": pred.Confidence,
-		"probs":      pred.Probs,
-	})
+	writeJSON(w, http.StatusOK, apitypes.NewClf(pred, detail))
 }
 
 // handleClassifyUpload runs inference against an uploaded image (test hook for the UI)
+// Accepts the same crop/mask/preprocess overrides as /api/clf (query or form fields).
 func (h *LatestHandler) handleClassifyUpload(w http.ResponseWriter, r *http.Request) {
 	if h.pred == nil {
 		http.Error(w, "no model loaded", http.StatusServiceUnavailable)
@@ -229,34 +356,34 @@ func (h *LatestHandler) handleClassifyUpload(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	file, hdr, err := r.FormFile("file")
+	override, err := parsePreprocessOverride(r.Form)
 	if err != nil {
-		http.Error(w, "file field 'file' required", http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer file.Close()
 
-	tmp, err := os.CreateTemp("", "skyclf-upload-*")
+	file, hdr, err := r.FormFile("file")
 	if err != nil {
-		http.Error(w, "failed to buffer upload", http.StatusInternalServerError)
+		http.Error(w, "file field 'file' required", http.StatusBadRequest)
 		return
 	}
-	defer os.Remove(tmp.Name())
+	defer file.Close()
 
-	n, err := io.Copy(tmp, file)
+	tmpPath, n, err := bufferUpload(file)
 	if err != nil {
-		http.Error(w, "failed to read upload", http.StatusBadRequest)
-		tmp.Close()
-		return
-	}
-	if err := tmp.Close(); err != nil {
-		http.Error(w, "failed to buffer upload", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	defer os.Remove(tmpPath)
 
-	pred, err := h.pred.PredictImage(r.Context(), tmp.Name())
+	pred, err := h.queue.Do(r.Context(), func(ctx context.Context) (*infer.Prediction, error) {
+		if dp, ok := h.pred.(infer.DetailedPredictor); ok && override != nil {
+			return dp.PredictImageOpts(ctx, tmpPath, infer.PredictOptions{Preprocess: override})
+		}
+		return h.pred.PredictImage(ctx, tmpPath)
+	})
 	if err != nil {
-		http.Error(w, "prediction failed", http.StatusInternalServerError)
+		writePredictError(w, err)
 		return
 	}
 	if pred == nil {
@@ -270,3 +397,97 @@ func (h *LatestHandler) handleClassifyUpload(w http.ResponseWriter, r *http.Requ
 		"prediction": pred,
 	})
 }
+
+// handlePredict runs inference on the raw image in the request body and returns
+// the full Prediction; other instances use it as their remote inference backend.
+// POST /api/predict[?detail=1&k=3] with crop/mask/preprocess overrides as for /api/clf.
+// 503 means no model is loaded.
+func (h *LatestHandler) handlePredict(w http.ResponseWriter, r *http.Request) {
+	if h.pred == nil {
+		http.Error(w, "no model loaded", http.StatusServiceUnavailable)
+		return
+	}
+	q := r.URL.Query()
+	override, err := parsePreprocessOverride(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	detail, k, err := parseDetail(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tmpPath, n, err := bufferUpload(http.MaxBytesReader(w, r.Body, 12<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer os.Remove(tmpPath)
+	if n == 0 {
+		http.Error(w, "image body required", http.StatusBadRequest)
+		return
+	}
+
+	pred, err := h.queue.Do(r.Context(), func(ctx context.Context) (*infer.Prediction, error) {
+		if dp, ok := h.pred.(infer.DetailedPredictor); ok && (detail || override != nil) {
+			return dp.PredictImageOpts(ctx, tmpPath, infer.PredictOptions{Logits: detail, TopK: k, Preprocess: override})
+		}
+		return h.pred.PredictImage(ctx, tmpPath)
+	})
+	if err != nil {
+		writePredictError(w, err)
+		return
+	}
+	if pred == nil {
+		http.Error(w, "no model loaded", http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, http.StatusOK, pred)
+}
+
+// writePredictError answers a failed prediction: 502 if the model's output
+// was invalid (the model, not the request, is broken), else 500.
+func writePredictError(w http.ResponseWriter, err error) {
+	if errors.Is(err, infer.ErrBadModelOutput) {
+		http.Error(w, "the model returned invalid output ("+err.Error()+"); it may be corrupted, see /api/models", http.StatusBadGateway)
+		return
+	}
+	http.Error(w, "prediction failed", http.StatusInternalServerError)
+}
+
+// parseDetail reads ?detail=1 and ?k=; with detail, k defaults to all classes.
+func parseDetail(q url.Values) (detail bool, k int, err error) {
+	detail = q.Get("detail") == "1" || strings.EqualFold(q.Get("detail"), "true")
+	if !detail {
+		return false, 0, nil
+	}
+	k = 1 << 10 // all classes unless ?k= narrows it
+	if raw := q.Get("k"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			return false, 0, errors.New("invalid k")
+		}
+		k = n
+	}
+	return detail, k, nil
+}
+
+// bufferUpload copies an uploaded image to a temp file (the predictors read
+// from paths) and returns its path and size; the caller removes the file.
+func bufferUpload(src io.Reader) (string, int64, error) {
+	tmp, err := os.CreateTemp("", "skyclf-upload-*")
+	if err != nil {
+		return "", 0, errors.New("failed to buffer upload")
+	}
+	n, err := io.Copy(tmp, src)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", 0, errors.New("failed to read upload")
+	}
+	return tmp.Name(), n, nil
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/apitypes"
+	"github.com/SkyClf/SkyClf/internal/infer"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// fakePredictor answers every prediction with pred and err.
+type fakePredictor struct {
+	pred *infer.Prediction
+	err  error
+}
+
+func (f *fakePredictor) PredictImage(context.Context, string) (*infer.Prediction, error) {
+	return f.pred, f.err
+}
+func (f *fakePredictor) Reload(string, string) error { return nil }
+func (f *fakePredictor) Close() error                { return nil }
+
+func openStore(t *testing.T) *store.Store {
+	t.Helper()
+	st, err := store.Open(filepath.Join(t.TempDir(), "labels.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+// TestPredictNonFiniteOutput checks a model answering NaN or Inf is reported
+// as a broken model (502), not as a failed request or a prediction.
+func TestPredictNonFiniteOutput(t *testing.T) {
+	badOutput := fmt.Errorf("logit 1 is NaN: %w", infer.ErrBadModelOutput)
+	clear := &infer.Prediction{SkyState: "clear", Confidence: 1, ModelVer: "v3", Probs: map[string]float32{"clear": 1, "heavy_clouds": 0}}
+	tests := []struct {
+		name string
+		pred *fakePredictor
+		want int
+	}{
+		{"nan logits", &fakePredictor{err: badOutput}, http.StatusBadGateway},
+		{"inf logits", &fakePredictor{err: fmt.Errorf("predict: %w", fmt.Errorf("logit 0 is +Inf: %w", infer.ErrBadModelOutput))}, http.StatusBadGateway},
+		{"other failure", &fakePredictor{err: errors.New("session closed")}, http.StatusInternalServerError},
+		{"no model", &fakePredictor{}, http.StatusServiceUnavailable},
+		{"finite", &fakePredictor{pred: clear}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		for _, route := range []struct{ method, path string }{
+			{http.MethodGet, "/api/clf"},
+			{http.MethodPost, "/api/predict"},
+		} {
+			t.Run(tt.name+route.path, func(t *testing.T) {
+				ctx := context.Background()
+				st := openStore(t)
+				if err := st.UpsertImage(ctx, "20241003_213000", "/data/images/20241003_213000.jpg", "ab12", time.Now(), 100); err != nil {
+					t.Fatal(err)
+				}
+				mux := http.NewServeMux()
+				NewLatestHandler(st, t.TempDir(), tt.pred).RegisterRoutes(mux)
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(route.method, route.path, bytes.NewReader([]byte("jpeg"))))
+				if rec.Code != tt.want {
+					t.Fatalf("status %d, want %d: %s", rec.Code, tt.want, rec.Body)
+				}
+				if tt.want == http.StatusBadGateway && !bytes.Contains(rec.Body.Bytes(), []byte("invalid output")) {
+					t.Fatalf("body %q does not name the model output", rec.Body)
+				}
+			})
+		}
+	}
+}
+
+// TestClfCached covers the stored-answer endpoint: nothing stored, an image
+// without a prediction, a hit, and a hit older than max_age.
+func TestClfCached(t *testing.T) {
+	now := time.Now().UTC()
+	tests := []struct {
+		name    string
+		fetched time.Duration // ago; 0 = no image
+		predict bool
+		query   string
+		want    int
+	}{
+		{name: "empty db", want: http.StatusNotFound},
+		{name: "miss", fetched: time.Minute, want: http.StatusNotFound},
+		{name: "hit", fetched: time.Minute, predict: true, want: http.StatusOK},
+		{name: "hit within max_age", fetched: time.Minute, predict: true, query: "?max_age=10m", want: http.StatusOK},
+		{name: "hit within max_age seconds", fetched: time.Minute, predict: true, query: "?max_age=600", want: http.StatusOK},
+		{name: "stale", fetched: time.Hour, predict: true, query: "?max_age=10m", want: http.StatusConflict},
+		{name: "stale without max_age", fetched: time.Hour, predict: true, want: http.StatusOK},
+		{name: "bad max_age", fetched: time.Minute, predict: true, query: "?max_age=soon", want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			st := openStore(t)
+			fetchedAt := now.Add(-tt.fetched).Truncate(time.Second)
+			if tt.fetched > 0 {
+				if err := st.UpsertImage(ctx, "20241003_213000", "/data/images/20241003_213000.jpg", "ab12", fetchedAt, 100); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.predict {
+				if err := st.RecordPrediction(ctx, store.PredictionRecord{ImageID: "20241003_213000", ModelVersion: "v3", Skystate: "clear",
+					Confidence: 0.9, Probs: map[string]float32{"clear": 0.9, "heavy_clouds": 0.1}, PredictedAt: now}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// No model: the cached answer never runs inference
+			mux := http.NewServeMux()
+			NewLatestHandler(st, t.TempDir(), nil).RegisterRoutes(mux)
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/clf/cached"+tt.query, nil))
+			if rec.Code != tt.want {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.want != http.StatusOK && tt.want != http.StatusConflict {
+				return
+			}
+			var got apitypes.ClfCached
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.SkyState != "clear" || got.ImageID != "20241003_213000" || got.ModelVersion != "v3" ||
+				got.Probs["heavy_clouds"] != 0.1 || !got.FetchedAt.Equal(fetchedAt) {
+				t.Fatalf("body %+v", got)
+			}
+			if age := time.Duration(got.AgeSeconds) * time.Second; age < tt.fetched-2*time.Second || age > tt.fetched+2*time.Second {
+				t.Fatalf("age %v, want about %v", age, tt.fetched)
+			}
+			if (got.Error != "") != (tt.want == http.StatusConflict) {
+				t.Fatalf("error %q with status %d", got.Error, rec.Code)
+			}
+		})
+	}
+}
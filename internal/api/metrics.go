@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/SkyClf/SkyClf/internal/ingest"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+const (
+	defaultLatencyWindow = 1000
+	maxLatencyWindow     = 100000
+)
+
+// MetricsHandler serves lightweight metrics computed from the store.
+type MetricsHandler struct {
+	st    *store.Store
+	queue *ingest.PredictQueue // nil unless predicting on ingest
+}
+
+// NewMetricsHandler creates a new metrics API handler
+func NewMetricsHandler(st *store.Store) *MetricsHandler {
+	return &MetricsHandler{st: st}
+}
+
+// SetPredictQueue serves q's stats on /api/metrics/queue.
+func (h *MetricsHandler) SetPredictQueue(q *ingest.PredictQueue) {
+	h.queue = q
+}
+
+// RegisterRoutes registers the metrics API routes
+func (h *MetricsHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/metrics/inference", h.getInference)
+	mux.HandleFunc("GET /api/metrics/queue", h.getQueue)
+}
+
+// GET /api/metrics/inference?last=N - p50/p95/p99 preprocess and inference latency
+// over the last N stored predictions (default 1000), overall and per model version.
+func (h *MetricsHandler) getInference(w http.ResponseWriter, r *http.Request) {
+	last := defaultLatencyWindow
+	if raw := r.URL.Query().Get("last"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "invalid last")
+			return
+		}
+		last = min(n, maxLatencyWindow)
+	}
+
+	overall, byModel, err := h.st.InferenceLatency(r.Context(), last)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"last":     last,
+		"overall":  overall,
+		"by_model": byModel,
+	})
+}
+
+// GET /api/metrics/queue - prediction queue depth (interactive and background),
+// running workers, images marked prediction pending and the processed, dropped
+// and swept totals
+func (h *MetricsHandler) getQueue(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.queue.Stats(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
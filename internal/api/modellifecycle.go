@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/SkyClf/SkyClf/internal/infer"
+	"github.com/SkyClf/SkyClf/internal/store"
+	"github.com/SkyClf/SkyClf/internal/trainer"
+)
+
+// Startup prepares the models directory and loads the newest model in the
+// background: a slow or corrupt model must not keep labeling and the rest of
+// the API from starting. Until it's loaded the predictor answers "no model
+// loaded". done, if set, gets the result of the load.
+func (h *ModelsHandler) Startup(done func(error)) {
+	// Models from before the DoneMarker stay loadable
+	if marked, err := infer.MigrateUnmarked(h.modelsDir); err != nil {
+		log.Printf("model marker migration: %v", err)
+	} else if len(marked) > 0 {
+		log.Printf("marked existing models as published: %v", marked)
+	}
+	// Move models finished while the server was down into place before scanning
+	if published, err := infer.PublishPending(h.modelsDir); err != nil {
+		log.Printf("model publish: %v", err)
+	} else if len(published) > 0 {
+		log.Printf("published models: %v", published)
+	}
+
+	loader, ok := h.pred.(interface{ Load() error })
+	if !ok {
+		return
+	}
+	go func() {
+		err := loader.Load()
+		if err != nil {
+			log.Printf("infer init: %v", err)
+		}
+		if done != nil {
+			done(err)
+		}
+		if p, ok := h.pred.(*infer.ORTPredictor); ok && err == nil {
+			checkParity(p.ActiveModel())
+		}
+	}()
+}
+
+// OnTrainComplete publishes the versions a finished run wrote, records their
+// lineage and the run with its metrics, and reloads the newest model.
+func (h *ModelsHandler) OnTrainComplete(ctx context.Context, run trainer.Run) {
+	log.Printf("trainer: reloading models after training completion")
+	// The run has exited, so versions it wrote in place are complete
+	if marked, err := infer.MarkCompleted(h.modelsDir, run.StartedAt); err != nil {
+		log.Printf("trainer: model publish error: %v", err)
+	} else if len(marked) > 0 {
+		log.Printf("trainer: published models: %v", marked)
+	}
+	if published, err := infer.PublishPending(h.modelsDir); err != nil {
+		log.Printf("trainer: model publish error: %v", err)
+	} else if len(published) > 0 {
+		log.Printf("trainer: published models: %v", published)
+	}
+	// Versions the trainer published itself are covered too
+	body, _ := json.Marshal(run)
+	lineage := infer.Lineage{Parent: run.Parent, RunID: run.ID, TrainedAt: run.FinishedAt.UTC(), Run: body}
+	recorded, err := infer.RecordLineage(h.modelsDir, lineage, run.StartedAt)
+	if err != nil {
+		log.Printf("trainer: record model lineage: %v", err)
+	} else if len(recorded) > 0 {
+		log.Printf("trainer: run %s produced %v (parent %q)", run.ID, recorded, run.Parent)
+	}
+	h.recordTrainRun(ctx, run, body, recorded)
+	if err := h.pred.Reload(h.modelsDir, ""); err != nil {
+		log.Printf("trainer: model reload error: %v", err)
+	}
+}
+
+// recordTrainRun adds a completed run to the run history with the metrics.json
+// of the version it produced. Missing or malformed metrics are recorded as a
+// warning; the run itself succeeded.
+func (h *ModelsHandler) recordTrainRun(ctx context.Context, run trainer.Run, body []byte, versions []string) {
+	if run.ID == "" || h.st == nil {
+		return
+	}
+	rec := store.TrainRun{ID: run.ID, Versions: versions, Run: body, StartedAt: run.StartedAt, FinishedAt: run.FinishedAt}
+	var curves []byte
+	if len(versions) == 0 {
+		rec.Warning = "no new model version"
+	} else {
+		v := versions[len(versions)-1]
+		m, err := infer.ReadTrainingMetrics(filepath.Join(h.modelsDir, "skystate", v))
+		if err != nil {
+			rec.Warning = fmt.Sprintf("%s: %v", v, err)
+		} else {
+			curves, _ = json.Marshal(m)
+			rec.Summary, _ = json.Marshal(m.Summary())
+		}
+	}
+	if rec.Warning != "" {
+		log.Printf("trainer: run %s: no metrics: %s", run.ID, rec.Warning)
+	}
+	if err := h.st.SaveTrainRun(ctx, rec, curves); err != nil {
+		log.Printf("trainer: record run %s: %v", run.ID, err)
+	}
+}
+
+// checkParity compares the preprocessing with the reference tensors shipped
+// in the model's parity directory, if any, and only warns on a mismatch.
+func checkParity(info *infer.ModelInfo) {
+	if info == nil {
+		return
+	}
+	dir := filepath.Join(info.Dir, infer.ParityDir)
+	if _, err := os.Stat(dir); err != nil {
+		return
+	}
+	rep, err := infer.CheckParity(dir, infer.DefaultParityThreshold)
+	if err != nil {
+		log.Printf("infer: WARNING preprocess parity: %v", err)
+		return
+	}
+	for _, r := range rep.Results {
+		switch {
+		case r.Error != "":
+			log.Printf("infer: WARNING preprocess parity %s/%s: %s", info.Version, r.Image, r.Error)
+		case !r.Pass:
+			log.Printf("infer: WARNING preprocess parity %s/%s: max diff %.4f > %g (mean %.4f); serving preprocessing differs from training",
+				info.Version, r.Image, r.MaxAbs, rep.Threshold, r.MeanAbs)
+		}
+	}
+	if rep.Pass {
+		log.Printf("infer: preprocess parity %s: %d images within %g", info.Version, len(rep.Results), rep.Threshold)
+	}
+}
@@ -0,0 +1,347 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/infer"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// ModelsHandler owns the /api/models routes: the active model, reloads,
+// activation and publishing, the published versions with their files, and
+// their comparison. It also publishes and loads models at startup and after
+// each training run (see Startup and OnTrainComplete).
+type ModelsHandler struct {
+	pred      infer.Predictor
+	modelsDir string
+	compare   *CompareHandler // nil = comparisons unavailable
+	admin     *AdminHandler   // nil = model deletion unavailable
+	st        *store.Store    // nil = training runs aren't recorded
+
+	sumMu sync.Mutex
+	sums  map[string]fileSum // by path
+}
+
+// fileSum is the SHA-256 of a file as of its size and modification time.
+type fileSum struct {
+	size    int64
+	modTime time.Time
+	sum     string
+}
+
+// NewModelsHandler creates a new models API handler
+func NewModelsHandler(pred infer.Predictor, modelsDir string) *ModelsHandler {
+	return &ModelsHandler{pred: pred, modelsDir: modelsDir, sums: map[string]fileSum{}}
+}
+
+// SetCompare serves GET /api/models/compare from c.
+func (h *ModelsHandler) SetCompare(c *CompareHandler) {
+	h.compare = c
+}
+
+// SetAdmin lets DELETE /api/models/{version} remove model versions, audited
+// and behind the admin token like the other destructive routes.
+func (h *ModelsHandler) SetAdmin(a *AdminHandler) {
+	h.admin = a
+}
+
+// SetStore records completed training runs in st.
+func (h *ModelsHandler) SetStore(st *store.Store) {
+	h.st = st
+}
+
+// RegisterRoutes registers the models API routes
+func (h *ModelsHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/models", h.getActive)
+	mux.HandleFunc("POST /api/models/reload", h.reload)
+	mux.HandleFunc("POST /api/models/{version}/activate", h.activate)
+	mux.HandleFunc("POST /api/models/publish", h.publish)
+	mux.HandleFunc("GET /api/models/lineage", h.lineage)
+	mux.HandleFunc("GET /api/models/list", h.list)
+	mux.HandleFunc("GET /api/models/download", h.download)
+	if h.compare != nil {
+		mux.HandleFunc("GET /api/models/compare", h.compare.compare)
+	}
+	if h.admin != nil {
+		mux.HandleFunc("DELETE /api/models/{version}", h.admin.audited("models.delete", h.handleDelete))
+	}
+}
+
+// activeVersion returns the version the local predictor has loaded, "" if
+// none (or inference is remote).
+func (h *ModelsHandler) activeVersion() string {
+	if p, ok := h.pred.(*infer.ORTPredictor); ok {
+		if mi := p.ActiveModel(); mi != nil {
+			return mi.Version
+		}
+	}
+	return ""
+}
+
+// GET /api/models - currently active model
+func (h *ModelsHandler) getActive(w http.ResponseWriter, r *http.Request) {
+	mr, ok := h.pred.(infer.ModelReporter)
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]any{"active": nil})
+		return
+	}
+	data, err := mr.ModelJSON()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "json encode: "+err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// GET /api/models/lineage - every published version with the run that produced
+// it and the version it was fine-tuned from (parent_version), forming a DAG
+func (h *ModelsHandler) lineage(w http.ResponseWriter, r *http.Request) {
+	nodes, err := infer.ModelLineage(h.modelsDir)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var active any = nil
+	if v := h.activeVersion(); v != "" {
+		active = v
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"active":   active,
+		"versions": nodes,
+	})
+}
+
+// POST /api/models/publish - move finished pending model directories into place
+// and load the newest model; the trainer container may call this when it is done.
+func (h *ModelsHandler) publish(w http.ResponseWriter, r *http.Request) {
+	published, err := infer.PublishPending(h.modelsDir)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(published) > 0 && h.pred != nil {
+		if err := h.pred.Reload(h.modelsDir, ""); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if published == nil {
+		published = []string{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"published": published})
+}
+
+// POST /api/models/reload?version=v3 - rescan models and load the latest (or given) version;
+// 404 if the given version isn't published
+func (h *ModelsHandler) reload(w http.ResponseWriter, r *http.Request) {
+	if h.pred == nil {
+		writeError(w, http.StatusInternalServerError, "predictor not initialized")
+		return
+	}
+	version := r.URL.Query().Get("version")
+	if version != "" && !infer.ValidVersionName(version) {
+		writeError(w, http.StatusBadRequest, "invalid version; expected a name like v3")
+		return
+	}
+	if err := h.pred.Reload(h.modelsDir, version); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, infer.ErrModelNotFound) {
+			status = http.StatusNotFound
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "message": "models reloaded"})
+}
+
+// POST /api/models/{version}/activate - load a published version and keep it
+// (pinned) until another is activated or reloaded; 404 if it isn't published
+func (h *ModelsHandler) activate(w http.ResponseWriter, r *http.Request) {
+	if h.pred == nil {
+		writeError(w, http.StatusInternalServerError, "predictor not initialized")
+		return
+	}
+	version := r.PathValue("version")
+	if !infer.ValidVersionName(version) {
+		writeError(w, http.StatusBadRequest, "invalid version; expected a name like v3")
+		return
+	}
+	if err := h.pred.Reload(h.modelsDir, version); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, infer.ErrModelNotFound) {
+			status = http.StatusNotFound
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "active": version})
+}
+
+// DELETE /api/models/{version} - remove a version directory, published or
+// not; 409 for the active version, 404 if there is no such version
+func (h *ModelsHandler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	version := r.PathValue("version")
+	if !infer.ValidVersionName(version) {
+		writeError(w, http.StatusBadRequest, "invalid version; expected a name like v3")
+		return
+	}
+	published, incomplete, err := infer.ListVersions(h.modelsDir)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !slices.Contains(published, version) && !slices.Contains(incomplete, version) {
+		writeError(w, http.StatusNotFound, "model "+version+" not found")
+		return
+	}
+	if version == h.activeVersion() {
+		writeError(w, http.StatusConflict, "model "+version+" is active; activate another version first")
+		return
+	}
+	dir := filepath.Join(h.modelsDir, "skystate", version)
+	// Unpublish first, so a removal cut short never leaves a loadable half
+	if err := os.Remove(filepath.Join(dir, infer.DoneMarker)); err != nil && !os.IsNotExist(err) {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "deleted": version})
+}
+
+// modelFiles are the files of a model version that may be downloaded.
+var modelFiles = []string{"model.onnx", "model.pt", "classes.json", "meta.json"}
+
+// GET /api/models/download?version=v3&file=model.onnx - a published model file;
+// the newest version and model.onnx (or model.pt) by default. HEAD and Range
+// requests are supported, so an interrupted download can resume:
+// X-Checksum-SHA256 (also the ETag, for If-Range) is the hash of the whole
+// file, to verify the reassembled download.
+func (h *ModelsHandler) download(w http.ResponseWriter, r *http.Request) {
+	version := r.URL.Query().Get("version")
+	file := r.URL.Query().Get("file") // model.onnx or model.pt
+	if strictPaths.Load() && file != "" && !slices.Contains(modelFiles, file) {
+		writeError(w, http.StatusBadRequest, "file must be one of: "+strings.Join(modelFiles, ", "))
+		return
+	}
+	modelDir := filepath.Join(h.modelsDir, "skystate")
+	var (
+		modelPath string
+		modelInfo os.FileInfo
+	)
+
+	// Only published versions are served; a model may still be being written
+	vers, _, err := infer.ListVersions(h.modelsDir)
+	if err == nil && version == "" && len(vers) > 0 {
+		version = vers[len(vers)-1]
+	}
+
+	// Download specific version
+	if version != "" && slices.Contains(vers, version) {
+		targetDir := filepath.Join(modelDir, version)
+		candidates := []string{"model.onnx", "model.pt"}
+		if file != "" {
+			candidates = []string{file}
+		}
+		for _, fname := range candidates {
+			tryPath, err := resolvePath(targetDir, fname)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			if info, err := os.Stat(tryPath); err == nil && info.Mode().IsRegular() {
+				modelPath, modelInfo = tryPath, info
+				break
+			}
+		}
+	}
+	if modelPath == "" {
+		writeError(w, http.StatusNotFound, "no model found")
+		return
+	}
+	sum, err := h.fileSHA256(modelPath, modelInfo)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+filepath.Base(modelPath)+"\"")
+	w.Header().Set("X-Checksum-SHA256", sum)
+	w.Header().Set("ETag", `"`+sum+`"`)
+	http.ServeFile(w, r, modelPath)
+}
+
+// fileSHA256 returns the hex SHA-256 of the file at path, hashing it only when
+// its size or modification time differ from the last call. Published versions
+// don't change, so each file is normally hashed once per process.
+func (h *ModelsHandler) fileSHA256(path string, info os.FileInfo) (string, error) {
+	h.sumMu.Lock()
+	cached, ok := h.sums[path]
+	h.sumMu.Unlock()
+	if ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+		return cached.sum, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", fmt.Errorf("hash %s: %w", filepath.Base(path), err)
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+
+	h.sumMu.Lock()
+	h.sums[path] = fileSum{size: info.Size(), modTime: info.ModTime(), sum: sum}
+	h.sumMu.Unlock()
+	return sum, nil
+}
+
+// GET /api/models/list - published versions, newest first, with download links
+func (h *ModelsHandler) list(w http.ResponseWriter, r *http.Request) {
+	modelDir := filepath.Join(h.modelsDir, "skystate")
+	vers, _, err := infer.ListVersions(h.modelsDir)
+	if err != nil || vers == nil {
+		writeError(w, http.StatusNotFound, "no models found")
+		return
+	}
+	var models []map[string]any
+	for _, version := range vers {
+		m := map[string]any{"version": version}
+		// Optional metadata
+		if meta, err := os.ReadFile(filepath.Join(modelDir, version, "meta.json")); err == nil {
+			var metaMap map[string]any
+			if json.Unmarshal(meta, &metaMap) == nil {
+				if created, ok := metaMap["created_at"]; ok {
+					m["created_at"] = created
+				}
+			}
+		}
+		for _, fname := range []string{"model.onnx", "model.pt"} {
+			tryPath := filepath.Join(modelDir, version, fname)
+			if _, err := os.Stat(tryPath); err == nil {
+				m[fname] = "/api/models/download?version=" + version + "&file=" + fname
+			}
+		}
+		models = append(models, m)
+	}
+	slices.Reverse(models) // ListVersions sorts oldest first
+	writeJSON(w, http.StatusOK, models)
+}
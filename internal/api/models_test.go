@@ -0,0 +1,275 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/infer"
+	"github.com/SkyClf/SkyClf/internal/trainer"
+)
+
+// publishModel creates a published model version in modelsDir.
+func publishModel(t *testing.T, modelsDir, version string) {
+	t.Helper()
+	dir := filepath.Join(modelsDir, "skystate", version)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, body := range map[string]string{
+		"model.onnx":     "onnx",
+		"classes.json":   `{"clear": 0, "cloudy": 1}`,
+		infer.DoneMarker: "",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReloadUnknownVersionIsNotFound(t *testing.T) {
+	modelsDir := t.TempDir()
+	publishModel(t, modelsDir, "v1")
+	mux := http.NewServeMux()
+	NewModelsHandler(infer.NewORTPredictor(modelsDir), modelsDir).RegisterRoutes(mux)
+
+	tests := []struct {
+		version string
+		want    int
+	}{
+		{"v99", http.StatusNotFound},
+		{"../v1", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/models/reload?version="+tt.version, nil))
+			if rec.Code != tt.want {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestModelsListNewestFirst(t *testing.T) {
+	modelsDir := t.TempDir()
+	for _, v := range []string{"v2", "v10", "v9", "v1"} {
+		publishModel(t, modelsDir, v)
+	}
+	mux := http.NewServeMux()
+	NewModelsHandler(infer.NewORTPredictor(modelsDir), modelsDir).RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/models/list", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var models []struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &models); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range models {
+		got = append(got, m.Version)
+	}
+	if want := []string{"v10", "v9", "v2", "v1"}; !slices.Equal(got, want) {
+		t.Fatalf("listed %v, want %v", got, want)
+	}
+
+	// The default download is the newest version too
+	newest := []byte("v10 onnx")
+	if err := os.WriteFile(filepath.Join(modelsDir, "skystate", "v10", "model.onnx"), newest, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/models/download", nil))
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), newest) {
+		t.Fatalf("default download: status %d, body %q; want v10's model", rec.Code, rec.Body)
+	}
+}
+
+func TestActivateModel(t *testing.T) {
+	modelsDir := t.TempDir()
+	publishModel(t, modelsDir, "v1")
+	mux := http.NewServeMux()
+	NewModelsHandler(infer.NewORTPredictor(modelsDir), modelsDir).RegisterRoutes(mux)
+
+	tests := []struct {
+		version string
+		want    int
+	}{
+		{"v2", http.StatusNotFound},
+		{"v..1", http.StatusBadRequest},
+		{"model", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/models/"+tt.version+"/activate", nil))
+			if rec.Code != tt.want {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestDeleteModel(t *testing.T) {
+	modelsDir := t.TempDir()
+	publishModel(t, modelsDir, "v1")
+	publishModel(t, modelsDir, "v2")
+	// Left by a trainer that never finished
+	if err := os.MkdirAll(filepath.Join(modelsDir, "skystate", "v3"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	h := NewModelsHandler(infer.NewORTPredictor(modelsDir), modelsDir)
+	mux := http.NewServeMux()
+	mux.HandleFunc("DELETE /api/models/{version}", h.handleDelete)
+
+	tests := []struct {
+		version string
+		want    int
+	}{
+		{"v1", http.StatusOK},
+		{"v1", http.StatusNotFound}, // already gone
+		{"v3", http.StatusOK},
+		{"v7", http.StatusNotFound},
+		{"skystate", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/models/"+tt.version, nil))
+		if rec.Code != tt.want {
+			t.Fatalf("DELETE %s: status %d, want %d: %s", tt.version, rec.Code, tt.want, rec.Body)
+		}
+	}
+	published, incomplete, err := infer.ListVersions(modelsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(published, []string{"v2"}) || len(incomplete) != 0 {
+		t.Fatalf("left published %v, incomplete %v", published, incomplete)
+	}
+}
+
+// TestDeleteModelRoute checks DELETE /api/models/{version} is only served
+// with an admin handler, behind its token and audited.
+func TestDeleteModelRoute(t *testing.T) {
+	ctx := context.Background()
+	modelsDir := t.TempDir()
+	publishModel(t, modelsDir, "v1")
+	st := openStore(t)
+	del := func(mux *http.ServeMux, token string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodDelete, "/api/models/v1", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	mux := http.NewServeMux()
+	NewModelsHandler(infer.NewORTPredictor(modelsDir), modelsDir).RegisterRoutes(mux)
+	if code := del(mux, ""); code != http.StatusNotFound && code != http.StatusMethodNotAllowed {
+		t.Fatalf("without an admin handler: status %d", code)
+	}
+
+	admin := NewAdminHandler(st, nil, "", "")
+	admin.SetToken("s3cret")
+	h := NewModelsHandler(infer.NewORTPredictor(modelsDir), modelsDir)
+	h.SetAdmin(admin)
+	mux = http.NewServeMux()
+	h.RegisterRoutes(mux)
+	if code := del(mux, ""); code != http.StatusUnauthorized {
+		t.Fatalf("without the token: status %d, want 401", code)
+	}
+	if code := del(mux, "s3cret"); code != http.StatusOK {
+		t.Fatalf("with the token: status %d, want 200", code)
+	}
+
+	entries, err := st.ListAudit(ctx, 10, "models.delete")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, fmt.Sprintf("%s/%d/%s", e.Identity, e.Status, e.Params["version"]))
+	}
+	if want := []string{"admin/200/v1", "anonymous/401/v1"}; !slices.Equal(got, want) {
+		t.Fatalf("audit %v, want %v", got, want)
+	}
+}
+
+// reloadCounter is a predictor that only counts reloads.
+type reloadCounter struct {
+	fakePredictor
+	reloads int
+}
+
+func (p *reloadCounter) Reload(string, string) error {
+	p.reloads++
+	return nil
+}
+
+func TestOnTrainComplete(t *testing.T) {
+	ctx := context.Background()
+	modelsDir := t.TempDir()
+	started := time.Now().Add(-time.Minute)
+	// v1 predates the run; v2 was written in place by it, without a marker
+	publishModel(t, modelsDir, "v1")
+	old := started.Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(modelsDir, "skystate", "v1", infer.DoneMarker), old, old); err != nil {
+		t.Fatal(err)
+	}
+	v2 := filepath.Join(modelsDir, "skystate", "v2")
+	if err := os.MkdirAll(v2, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"model.onnx", "classes.json"} {
+		if err := os.WriteFile(filepath.Join(v2, name), []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	st := openStore(t)
+	pred := &reloadCounter{}
+	h := NewModelsHandler(pred, modelsDir)
+	h.SetStore(st)
+	h.OnTrainComplete(ctx, trainer.Run{ID: "20250101_120000", Parent: "v1", StartedAt: started, FinishedAt: time.Now()})
+
+	published, incomplete, err := infer.ListVersions(modelsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(published, []string{"v1", "v2"}) || len(incomplete) != 0 {
+		t.Fatalf("published %v, incomplete %v", published, incomplete)
+	}
+	if l, err := infer.ReadLineage(v2); err != nil || l == nil || l.RunID != "20250101_120000" || l.Parent != "v1" {
+		t.Fatalf("v2 lineage %+v, %v", l, err)
+	}
+	if l, _ := infer.ReadLineage(filepath.Join(modelsDir, "skystate", "v1")); l != nil {
+		t.Fatalf("v1 got lineage %+v", l)
+	}
+	runs, err := st.ListTrainRuns(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// No metrics.json: recorded with a warning
+	if len(runs) != 1 || !slices.Equal(runs[0].Versions, []string{"v2"}) || runs[0].Warning == "" {
+		t.Fatalf("train runs %+v", runs)
+	}
+	if pred.reloads != 1 {
+		t.Fatalf("%d reloads, want 1", pred.reloads)
+	}
+}
@@ -0,0 +1,245 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+
+	"github.com/SkyClf/SkyClf/internal/infer"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// PreprocessHandler manages the horizon mask/crop applied before inference.
+// The configuration is persisted in the settings table.
+type PreprocessHandler struct {
+	st   *store.Store
+	pred *infer.ORTPredictor
+}
+
+// NewPreprocessHandler creates a new preprocess API handler
+func NewPreprocessHandler(st *store.Store, pred *infer.ORTPredictor) *PreprocessHandler {
+	return &PreprocessHandler{st: st, pred: pred}
+}
+
+// RegisterRoutes registers the preprocess API routes
+func (h *PreprocessHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/preprocess", h.get)
+	mux.HandleFunc("PUT /api/preprocess", h.put)
+	mux.HandleFunc("GET /api/images/{id}/preprocessed.png", h.preview)
+	mux.HandleFunc("GET /api/debug/preprocessed", h.debugLatest)
+}
+
+// Restore applies the stored configuration to the predictor (call once at startup).
+func (h *PreprocessHandler) Restore(ctx context.Context) error {
+	var cfg infer.PreprocessConfig
+	ok, err := h.st.GetSetting(ctx, store.SettingPreprocess, &cfg)
+	if err != nil || !ok {
+		return err
+	}
+	h.pred.SetPreprocess(cfg)
+	return nil
+}
+
+// GET /api/preprocess - active mask/crop configuration
+func (h *PreprocessHandler) get(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.pred.Preprocess())
+}
+
+// PUT /api/preprocess - replace the configuration, e.g.
+// {"mask": {"cx": 640, "cy": 480, "r": 450}, "crop": {"x": 190, "y": 30, "w": 900, "h": 900}}
+// An empty object disables both.
+func (h *PreprocessHandler) put(w http.ResponseWriter, r *http.Request) {
+	var cfg infer.PreprocessConfig
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.st.SetSetting(r.Context(), store.SettingPreprocess, cfg); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.pred.SetPreprocess(cfg)
+	writeJSON(w, http.StatusOK, cfg)
+}
+
+// GET /api/images/{id}/preprocessed.png - the image as the model sees it, for checking
+// the geometry. Accepts crop/mask overrides like /api/clf; ?input=1 returns the
+// resized model input instead of the source-resolution view.
+func (h *PreprocessHandler) preview(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	cfg := h.pred.Preprocess()
+	override, err := parsePreprocessOverride(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if override != nil {
+		cfg = *override
+	}
+
+	img, err := h.st.GetImage(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if img == nil {
+		writeError(w, http.StatusNotFound, "image not found")
+		return
+	}
+
+	view, err := infer.LoadView(img.Path, cfg)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if q.Get("input") == "1" || strings.EqualFold(q.Get("input"), "true") {
+		view = infer.ResizeToInput(view)
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = png.Encode(w, view)
+}
+
+// Bounds of the size of GET /api/debug/preprocessed.
+const (
+	minDebugPreviewSize = 32
+	maxDebugPreviewSize = 1024
+)
+
+// GET /api/debug/preprocessed?size=224 - the newest frame through the whole
+// inference pipeline (mask, crop, resize to the model input, normalization)
+// with the normalization undone, as PNG: exactly what the model sees. Takes
+// the crop/mask overrides of /api/clf to try a mask without saving it; size
+// scales the model input for viewing (nearest neighbour, so its pixels stay
+// visible). The ETag covers the frame, the mask/crop and the size.
+func (h *PreprocessHandler) debugLatest(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	size := infer.InputSize
+	if raw := q.Get("size"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < minDebugPreviewSize || n > maxDebugPreviewSize {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("size must be between %d and %d", minDebugPreviewSize, maxDebugPreviewSize))
+			return
+		}
+		size = n
+	}
+	cfg := h.pred.Preprocess()
+	override, err := parsePreprocessOverride(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if override != nil {
+		cfg = *override
+	}
+
+	latest, err := h.st.GetLatest(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if latest == nil {
+		writeError(w, http.StatusNotFound, "no image")
+		return
+	}
+	if !store.Predictable(latest.Format) {
+		writeError(w, http.StatusUnprocessableEntity, latest.Format+" images can't be classified")
+		return
+	}
+
+	view, _ := json.Marshal(cfg)
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%d", latest.SHA256, view, size)))
+	etag := `"` + hex.EncodeToString(sum[:12]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	x, err := infer.LoadAndPreprocessNCHWConfig(latest.Path, cfg)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	img, err := infer.DenormalizeNCHW(x)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var out image.Image = img
+	if size != infer.InputSize {
+		scaled := image.NewRGBA(image.Rect(0, 0, size, size))
+		xdraw.NearestNeighbor.Scale(scaled, scaled.Bounds(), img, img.Bounds(), xdraw.Src, nil)
+		out = scaled
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("X-Image-ID", latest.ID)
+	_ = png.Encode(w, out)
+}
+
+// parsePreprocessOverride reads a per-request mask/crop override:
+// crop=x,y,w,h and mask=cx,cy,r (either or both), or preprocess=none for the
+// full frame. It returns nil if the request doesn't override anything.
+func parsePreprocessOverride(q url.Values) (*infer.PreprocessConfig, error) {
+	if q.Get("preprocess") == "none" {
+		return &infer.PreprocessConfig{}, nil
+	}
+	rawCrop, rawMask := q.Get("crop"), q.Get("mask")
+	if rawCrop == "" && rawMask == "" {
+		return nil, nil
+	}
+
+	var cfg infer.PreprocessConfig
+	if rawCrop != "" {
+		v, err := parseInts(rawCrop, 4)
+		if err != nil {
+			return nil, fmt.Errorf("invalid crop; use x,y,w,h")
+		}
+		cfg.Crop = &infer.Rect{X: v[0], Y: v[1], W: v[2], H: v[3]}
+	}
+	if rawMask != "" {
+		v, err := parseInts(rawMask, 3)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mask; use cx,cy,r")
+		}
+		cfg.Mask = &infer.Circle{CX: v[0], CY: v[1], R: v[2]}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// parseInts parses exactly n comma-separated integers.
+func parseInts(raw string, n int) ([]int, error) {
+	parts := strings.Split(raw, ",")
+	if len(parts) != n {
+		return nil, errors.New("wrong number of values")
+	}
+	out := make([]int, n)
+	for i, p := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
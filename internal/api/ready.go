@@ -0,0 +1,99 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+)
+
+// Startup steps tracked by the readiness handler.
+const (
+	ReadyDB    = "db"
+	ReadyModel = "model" // initial model scan finished, with or without a model
+	ReadyFetch = "fetch" // first fetch attempt (or no-fetch directory scan) completed, successful or not
+)
+
+// ReadinessHandler serves GET /ready: 200 once every startup step is done,
+// 503 before. Unlike /health it tells a proxy when to route traffic.
+type ReadinessHandler struct {
+	mu     sync.Mutex
+	order  []string
+	steps  map[string]*readyStep
+	checks map[string]func() error // evaluated on every request after startup
+}
+
+type readyStep struct {
+	Done  bool   `json:"done"`
+	Error string `json:"error,omitempty"` // the step finished, but with this problem
+}
+
+// NewReadinessHandler creates a readiness handler waiting for the given steps.
+func NewReadinessHandler(steps ...string) *ReadinessHandler {
+	h := &ReadinessHandler{order: steps, steps: make(map[string]*readyStep, len(steps))}
+	for _, s := range steps {
+		h.steps[s] = &readyStep{}
+	}
+	return h
+}
+
+// RegisterRoutes registers the readiness route
+func (h *ReadinessHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /ready", h.getReady)
+}
+
+// AddCheck adds a condition that keeps the service unready whenever fn
+// returns an error, e.g. a data directory that has gone away.
+func (h *ReadinessHandler) AddCheck(name string, fn func() error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.checks == nil {
+		h.checks = make(map[string]func() error)
+	}
+	h.checks[name] = fn
+}
+
+// Done marks a step as finished. A non-nil err is reported but doesn't keep
+// the service unready: e.g. a broken model must not block labeling.
+func (h *ReadinessHandler) Done(step string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.steps[step]
+	if !ok {
+		return
+	}
+	s.Done = true
+	s.Error = ""
+	if err != nil {
+		s.Error = err.Error()
+	}
+}
+
+// GET /ready - 200 {"ready": true, "steps": {...}} once startup finished, else 503
+func (h *ReadinessHandler) getReady(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	ready := true
+	steps := make(map[string]readyStep, len(h.steps))
+	for _, name := range h.order {
+		s := *h.steps[name]
+		steps[name] = s
+		ready = ready && s.Done
+	}
+	checks := make(map[string]string, len(h.checks))
+	for name, fn := range h.checks {
+		checks[name] = "ok"
+		if err := fn(); err != nil {
+			checks[name] = err.Error()
+			ready = false
+		}
+	}
+	h.mu.Unlock()
+
+	status := http.StatusOK
+	if !ready {
+		status = http.StatusServiceUnavailable
+	}
+	resp := map[string]any{"ready": ready, "steps": steps}
+	if len(checks) > 0 {
+		resp["checks"] = checks
+	}
+	writeJSON(w, status, resp)
+}
@@ -0,0 +1,277 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/apitypes"
+	"github.com/SkyClf/SkyClf/internal/infer"
+	"github.com/SkyClf/SkyClf/internal/ingest"
+	"github.com/SkyClf/SkyClf/internal/store"
+	"github.com/SkyClf/SkyClf/internal/trainer"
+)
+
+const (
+	defaultReplayRate = 2.0  // images per second
+	maxReplayRate     = 20.0 // images per second
+	// replayYield is how long a replay waits while interactive predictions
+	// have the workers.
+	replayYield = 250 * time.Millisecond
+)
+
+// ReplayHandler re-runs predictions over a date range, e.g. after a model
+// change, in a background job.
+type ReplayHandler struct {
+	st        *store.Store
+	pred      *infer.ORTPredictor // source of session options and mask/crop
+	tr        *trainer.Trainer    // nil when training is disabled
+	queue     *ingest.PredictQueue
+	modelsDir string
+
+	mu     sync.Mutex
+	job    apitypes.PredictReplay
+	cancel context.CancelFunc
+}
+
+// NewReplayHandler creates a new prediction replay handler. tr may be nil.
+func NewReplayHandler(st *store.Store, pred *infer.ORTPredictor, tr *trainer.Trainer, modelsDir string) *ReplayHandler {
+	return &ReplayHandler{st: st, pred: pred, tr: tr, modelsDir: modelsDir}
+}
+
+// SetPredictQueue makes replays give way to the interactive predictions
+// queued on q.
+func (h *ReplayHandler) SetPredictQueue(q *ingest.PredictQueue) {
+	h.queue = q
+}
+
+// RegisterRoutes registers the replay API routes
+func (h *ReplayHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/predict/replay", h.handleStart)
+	mux.HandleFunc("GET /api/predict/replay", h.handleStatus)
+	mux.HandleFunc("DELETE /api/predict/replay", h.handleCancel)
+}
+
+// POST /api/predict/replay - predict the images fetched on the days from
+// through to again and store the results, replacing earlier predictions of
+// the same model version. Body:
+//
+//	{"from": "2024-09-01", "to": "2024-09-30", "model_version": "v7", "rate": 2}
+//
+// model_version defaults to the active model, rate (max images per second,
+// default 2) keeps the job from hogging the board; it also pauses while
+// interactive predictions wait. Answers 202 with the job status; 409 while
+// another replay or training runs.
+func (h *ReplayHandler) handleStart(w http.ResponseWriter, r *http.Request) {
+	if h.pred == nil {
+		writeError(w, http.StatusServiceUnavailable, "prediction replay needs the local ONNX Runtime backend")
+		return
+	}
+	var req struct {
+		From         string  `json:"from"`
+		To           string  `json:"to"`
+		ModelVersion string  `json:"model_version"`
+		Rate         float64 `json:"rate"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	from, err := time.Parse("2006-01-02", req.From)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid from; use YYYY-MM-DD")
+		return
+	}
+	to, err := time.Parse("2006-01-02", req.To)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid to; use YYYY-MM-DD")
+		return
+	}
+	if to.Before(from) {
+		writeError(w, http.StatusBadRequest, "to is before from")
+		return
+	}
+	rate := req.Rate
+	if rate == 0 {
+		rate = defaultReplayRate
+	}
+	if rate < 0 || rate > maxReplayRate {
+		writeError(w, http.StatusBadRequest, "rate must be between 0 and 20 images per second")
+		return
+	}
+
+	version := req.ModelVersion
+	if version == "" {
+		mi := h.pred.ActiveModel()
+		if mi == nil {
+			writeError(w, http.StatusConflict, "no model loaded; pass model_version")
+			return
+		}
+		version = mi.Version
+	}
+	if !infer.ValidVersionName(version) {
+		writeError(w, http.StatusBadRequest, "invalid model_version "+version+"; expected a name like v3")
+		return
+	}
+	mi, err := infer.FindSkyStateModel(h.modelsDir, version)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if mi == nil {
+		writeError(w, http.StatusNotFound, "model "+version+" not found")
+		return
+	}
+
+	if h.tr != nil && h.tr.Status(r.Context()).Running {
+		writeError(w, http.StatusConflict, "training is running; replay predictions after it has finished")
+		return
+	}
+	images, err := h.st.ListPredictableImages(r.Context(), from, to)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.mu.Lock()
+	if h.job.Running {
+		h.mu.Unlock()
+		writeError(w, http.StatusConflict, "a replay is already running")
+		return
+	}
+	// Job outlives the request
+	ctx, cancel := context.WithCancel(context.Background())
+	h.job = apitypes.PredictReplay{
+		Running:      true,
+		From:         req.From,
+		To:           req.To,
+		ModelVersion: version,
+		Rate:         rate,
+		Total:        len(images),
+		StartedAt:    time.Now().UTC(),
+	}
+	h.cancel = cancel
+	job := h.job
+	h.mu.Unlock()
+
+	go h.run(ctx, version, rate, images)
+
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// GET /api/predict/replay - progress of the last replay
+func (h *ReplayHandler) handleStatus(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	job := h.job
+	h.mu.Unlock()
+	if job.Running && job.Processed > 0 {
+		perImage := time.Since(job.StartedAt) / time.Duration(job.Processed)
+		job.ETASeconds = int((perImage * time.Duration(job.Total-job.Processed)).Seconds())
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// DELETE /api/predict/replay - cancel the running replay; predictions stored
+// so far are kept
+func (h *ReplayHandler) handleCancel(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.job.Running {
+		writeError(w, http.StatusConflict, "no replay is running")
+		return
+	}
+	h.cancel()
+	writeJSON(w, http.StatusAccepted, h.job)
+}
+
+func (h *ReplayHandler) run(ctx context.Context, version string, rate float64, images []store.Image) {
+	err := h.replay(ctx, version, rate, images)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cancel()
+	h.job.Running = false
+	h.job.FinishedAt = time.Now().UTC()
+	switch {
+	case errors.Is(err, context.Canceled):
+		h.job.Canceled = true
+		log.Printf("api: prediction replay canceled after %d of %d images", h.job.Processed, h.job.Total)
+	case err != nil:
+		h.job.Error = err.Error()
+		log.Printf("api: prediction replay failed: %v", err)
+	default:
+		log.Printf("api: replayed %s on %s..%s: %d images, %d failed",
+			version, h.job.From, h.job.To, h.job.Processed, h.job.Failed)
+	}
+}
+
+// replay predicts images with version in a separate session, at most rate
+// per second, and stores the results.
+func (h *ReplayHandler) replay(ctx context.Context, version string, rate float64, images []store.Image) error {
+	p, err := h.pred.OpenVersion(version)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	interval := time.Duration(float64(time.Second) / rate)
+	next := time.Now()
+	for _, img := range images {
+		if err := h.wait(ctx, next); err != nil {
+			return err
+		}
+		next = time.Now().Add(interval)
+
+		pred, err := p.PredictImage(ctx, img.Path)
+		if err == nil && pred != nil {
+			err = h.st.RecordPrediction(ctx, store.PredictionRecord{
+				ImageID:      img.ID,
+				ModelVersion: pred.ModelVer,
+				Skystate:     pred.SkyState,
+				Confidence:   float64(pred.Confidence),
+				Probs:        pred.Probs,
+				PreprocessMS: pred.PreprocessMS,
+				InferenceMS:  pred.InferenceMS,
+				PredictedAt:  time.Now(),
+			})
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		failed := err != nil || pred == nil
+		if err != nil {
+			log.Printf("api: replay %s: %v", img.ID, err)
+		}
+
+		h.mu.Lock()
+		h.job.Processed++
+		if failed {
+			h.job.Failed++
+		}
+		h.mu.Unlock()
+	}
+	return nil
+}
+
+// wait sleeps until next, then for as long as interactive predictions keep
+// the queue busy.
+func (h *ReplayHandler) wait(ctx context.Context, next time.Time) error {
+	for d := time.Until(next); ; d = replayYield {
+		if d > 0 {
+			t := time.NewTimer(d)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return ctx.Err()
+			case <-t.C:
+			}
+		}
+		if !h.queue.Busy() {
+			return ctx.Err()
+		}
+	}
+}
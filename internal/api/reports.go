@@ -0,0 +1,70 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/report"
+)
+
+// ReportsHandler serves generated reports.
+type ReportsHandler struct {
+	gen *report.Generator
+}
+
+// NewReportsHandler creates a new reports API handler
+func NewReportsHandler(gen *report.Generator) *ReportsHandler {
+	return &ReportsHandler{gen: gen}
+}
+
+// RegisterRoutes registers the reports API routes
+func (h *ReportsHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/reports", h.list)
+	mux.HandleFunc("GET /api/reports/night", h.night)
+}
+
+// GET /api/reports - cached night reports, newest first
+func (h *ReportsHandler) list(w http.ResponseWriter, r *http.Request) {
+	items, err := h.gen.List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+// GET /api/reports/night?date=YYYY-MM-DD&format=html&refresh=1 - report for the
+// night starting on date (default: the last finished night). Finished nights
+// are cached; refresh=1 regenerates.
+func (h *ReportsHandler) night(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	date := q.Get("date")
+	if date == "" {
+		date = h.gen.LastFinishedNight(time.Now())
+	} else if _, err := time.Parse("2006-01-02", date); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid date; use YYYY-MM-DD")
+		return
+	}
+
+	rep, err := h.gen.Night(r.Context(), date, isTrue(q.Get("refresh")))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	switch q.Get("format") {
+	case "", "json":
+		writeJSON(w, http.StatusOK, rep)
+	case "html":
+		var buf bytes.Buffer
+		if err := report.RenderHTML(&buf, rep); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(buf.Bytes())
+	default:
+		writeError(w, http.StatusBadRequest, "format must be json or html")
+	}
+}
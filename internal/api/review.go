@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/SkyClf/SkyClf/internal/apitypes"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// handleReviewQueue lists images whose label was marked needs_review, newest first.
+// Query params:
+//   - limit: max images (default: all)
+//   - date: images fetched on this day (YYYY-MM-DD, see SKYCLF_DAY_GROUPING)
+func (h *DatasetHandler) handleReviewQueue(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter, err := parseImageFilter(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = n
+	}
+	filter.NeedsReview = true
+
+	items, err := h.st.ListImagesFiltered(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if items == nil {
+		items = []store.ImageWithLabel{}
+	}
+	writeJSON(w, http.StatusOK, apitypes.ImageList{Count: len(items), Items: items})
+}
+
+type confirmLabelsRequest struct {
+	ImageID  string   `json:"image_id,omitempty"`
+	ImageIDs []string `json:"image_ids,omitempty"`
+	Labeler  string   `json:"labeler,omitempty"`
+}
+
+// handleConfirmLabels clears the review flag, keeping the label as it is; to
+// correct a label, POST /api/labels instead.
+// POST /api/labels/confirm {"image_ids": ["...", ...], "labeler": "..."}
+func (h *DatasetHandler) handleConfirmLabels(w http.ResponseWriter, r *http.Request) {
+	var req confirmLabelsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	var ids []string
+	for _, id := range append(req.ImageIDs, req.ImageID) {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		http.Error(w, "image_id or image_ids required", http.StatusBadRequest)
+		return
+	}
+
+	n, err := h.st.ConfirmLabels(r.Context(), ids, strings.TrimSpace(req.Labeler))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "confirmed": n})
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/SkyClf/SkyClf/internal/apitypes"
+	"github.com/SkyClf/SkyClf/internal/rules"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// RulesHandler manages alerting rules (see package rules).
+type RulesHandler struct {
+	st  *store.Store
+	eng *rules.Engine
+}
+
+// NewRulesHandler creates a new rules API handler
+func NewRulesHandler(st *store.Store, eng *rules.Engine) *RulesHandler {
+	return &RulesHandler{st: st, eng: eng}
+}
+
+// RegisterRoutes registers the rules API routes
+func (h *RulesHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/rules", h.list)
+	mux.HandleFunc("POST /api/rules", h.create)
+	mux.HandleFunc("POST /api/rules/simulate", h.simulate)
+	mux.HandleFunc("GET /api/rules/{id}", h.get)
+	mux.HandleFunc("PUT /api/rules/{id}", h.update)
+	mux.HandleFunc("DELETE /api/rules/{id}", h.delete)
+}
+
+// GET /api/rules - all rules, oldest first
+func (h *RulesHandler) list(w http.ResponseWriter, r *http.Request) {
+	stored, err := h.st.ListRules(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	out := apitypes.Rules{Rules: make([]apitypes.Rule, 0, len(stored))}
+	for _, sr := range stored {
+		out.Rules = append(out.Rules, ruleResponse(sr))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// GET /api/rules/{id} - one rule
+func (h *RulesHandler) get(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid rule id")
+		return
+	}
+	h.writeRule(w, r, id, http.StatusOK)
+}
+
+// POST /api/rules - add a rule {name, condition, action, cooldown, disabled}
+func (h *RulesHandler) create(w http.ResponseWriter, r *http.Request) {
+	spec, ok := h.decode(w, r)
+	if !ok {
+		return
+	}
+	id, err := h.st.CreateRule(r.Context(), spec)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.reload(w, r, id, http.StatusCreated)
+}
+
+// PUT /api/rules/{id} - replace a rule's definition
+func (h *RulesHandler) update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid rule id")
+		return
+	}
+	spec, ok := h.decode(w, r)
+	if !ok {
+		return
+	}
+	found, err := h.st.UpdateRule(r.Context(), id, spec)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "rule not found")
+		return
+	}
+	h.reload(w, r, id, http.StatusOK)
+}
+
+// DELETE /api/rules/{id} - remove a rule
+func (h *RulesHandler) delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid rule id")
+		return
+	}
+	found, err := h.st.DeleteRule(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "rule not found")
+		return
+	}
+	if err := h.eng.Reload(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// POST /api/rules/simulate {"rules": [...], "observations": [{"at", "state",
+// "confidence", "events"}]} - the firings the rules (default: the stored
+// ones) would produce over the observations, starting from no state and
+// without running any action. Use it to try a rule before saving it.
+func (h *RulesHandler) simulate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Rules        []rules.Spec        `json:"rules"`
+		Observations []rules.Observation `json:"observations"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	specs := req.Rules
+	if specs == nil {
+		stored, err := h.st.ListRules(r.Context())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		for _, sr := range stored {
+			specs = append(specs, ruleResponse(sr).Spec)
+		}
+	}
+	firings, err := h.eng.Simulate(specs, req.Observations)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, apitypes.RuleSimulation{Firings: firings})
+}
+
+// decode parses and validates a rule body and returns it as stored. On
+// failure it has answered the request.
+func (h *RulesHandler) decode(w http.ResponseWriter, r *http.Request) (json.RawMessage, bool) {
+	var spec rules.Spec
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&spec); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json: "+err.Error())
+		return nil, false
+	}
+	spec.Name = strings.TrimSpace(spec.Name)
+	if err := h.eng.Validate(spec); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	body, err := json.Marshal(spec)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	return body, true
+}
+
+// reload makes the engine pick up a changed rule and answers with the rule.
+func (h *RulesHandler) reload(w http.ResponseWriter, r *http.Request, id int64, status int) {
+	if err := h.eng.Reload(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.writeRule(w, r, id, status)
+}
+
+func (h *RulesHandler) writeRule(w http.ResponseWriter, r *http.Request, id int64, status int) {
+	sr, err := h.st.GetRule(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if sr == nil {
+		writeError(w, http.StatusNotFound, "rule not found")
+		return
+	}
+	writeJSON(w, status, ruleResponse(*sr))
+}
+
+func ruleResponse(sr store.Rule) apitypes.Rule {
+	out := apitypes.Rule{ID: sr.ID, CreatedAt: sr.CreatedAt, UpdatedAt: sr.UpdatedAt, LastFiredAt: sr.LastFiredAt}
+	_ = json.Unmarshal(sr.Spec, &out.Spec)
+	return out
+}
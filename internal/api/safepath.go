@@ -0,0 +1,67 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+var errUnsafePath = errors.New("invalid path")
+
+// strictPaths rejects suspicious request paths (".." segments, backslashes,
+// absolute names) with 400 instead of cleaning them. On by default.
+var strictPaths atomic.Bool
+
+func init() { strictPaths.Store(true) }
+
+// SetStrictPaths switches strict path checking for the file-serving handlers
+// (UI, images, model download). With it off, such paths are cleaned and
+// resolved inside the base directory as before.
+func SetStrictPaths(on bool) {
+	strictPaths.Store(on)
+}
+
+// safeJoin joins the slash-separated name to base and verifies the result
+// stays within base. Absolute names, ".." segments, backslashes and NUL bytes
+// are rejected outright rather than cleaned away.
+func safeJoin(base, name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, "\\\x00") || strings.HasPrefix(name, "/") ||
+		filepath.IsAbs(name) || filepath.VolumeName(name) != "" || hasDotDot(name) {
+		return "", errUnsafePath
+	}
+	p := filepath.Join(base, filepath.FromSlash(name))
+	rel, err := filepath.Rel(base, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errUnsafePath
+	}
+	return p, nil
+}
+
+// resolvePath joins a request-supplied name to base: via safeJoin in strict
+// mode, otherwise by cleaning the name as if rooted at base (http.Dir style),
+// which also can't leave base but accepts odd spellings.
+func resolvePath(base, name string) (string, error) {
+	if strictPaths.Load() {
+		return safeJoin(base, name)
+	}
+	return filepath.Join(base, filepath.FromSlash(path.Clean("/"+name))), nil
+}
+
+// hasDotDot reports whether any slash-separated element of name is "..".
+func hasDotDot(name string) bool {
+	for _, el := range strings.Split(name, "/") {
+		if el == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// badRequestPath reports whether strict mode should reject the decoded
+// request path (encoded payloads such as %2e%2e or %5c arrive decoded).
+func badRequestPath(r *http.Request) bool {
+	return strictPaths.Load() && (hasDotDot(r.URL.Path) || strings.ContainsAny(r.URL.Path, "\\\x00"))
+}
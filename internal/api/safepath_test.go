@@ -0,0 +1,192 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const secret = "top secret"
+
+// setStrictPaths switches strict mode for one test.
+func setStrictPaths(t *testing.T, on bool) {
+	t.Helper()
+	SetStrictPaths(on)
+	t.Cleanup(func() { SetStrictPaths(true) })
+}
+
+// traversalTree returns a temp directory holding secret.txt and the base
+// directory dir inside it, with the given files.
+func traversalTree(t *testing.T, dir string, files map[string]string) (root, base string) {
+	t.Helper()
+	root = t.TempDir()
+	base = filepath.Join(root, dir)
+	if err := os.MkdirAll(base, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files["../secret.txt"] = secret
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(base, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root, base
+}
+
+func TestSafeJoin(t *testing.T) {
+	base := t.TempDir()
+	tests := []struct {
+		name string
+		want string // "" = rejected
+	}{
+		{"a.jpg", "a.jpg"},
+		{"2024/10/03/a.jpg", "2024/10/03/a.jpg"},
+		{"a..b.jpg", "a..b.jpg"},
+		{"", ""},
+		{"..", ""},
+		{"../secret.txt", ""},
+		{"2024/../../secret.txt", ""},
+		{"..\\secret.txt", ""},
+		{"2024\\..\\..\\secret.txt", ""},
+		{"/etc/passwd", ""},
+		{filepath.Join(base, "a.jpg"), ""},
+		{"a.jpg\x00.png", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := safeJoin(base, tt.name)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("safeJoin(%q) = %s, want an error", tt.name, got)
+				}
+				return
+			}
+			if err != nil || got != filepath.Join(base, filepath.FromSlash(tt.want)) {
+				t.Fatalf("safeJoin(%q) = %q, %v", tt.name, got, err)
+			}
+		})
+	}
+}
+
+// TestResolvePathStaysInBase checks the lax mode cleans every payload into
+// the base directory instead of rejecting it.
+func TestResolvePathStaysInBase(t *testing.T) {
+	setStrictPaths(t, false)
+	base := t.TempDir()
+	for _, name := range []string{"../secret.txt", "../../../../etc/passwd", "/etc/passwd", "a/../../b", "..\\secret.txt"} {
+		p, err := resolvePath(base, name)
+		if err != nil {
+			t.Fatalf("resolvePath(%q): %v", name, err)
+		}
+		if rel, err := filepath.Rel(base, p); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			t.Fatalf("resolvePath(%q) = %s, outside %s", name, p, base)
+		}
+	}
+}
+
+// traversalCase is one request against a file-serving handler, with the
+// status expected in strict and in lax mode. No response may carry the
+// secret, whatever its status.
+type traversalCase struct {
+	name         string
+	target       string // request URI
+	strict, lax  int
+	wantBodyPart string // on success
+}
+
+func runTraversal(t *testing.T, h http.Handler, tests []traversalCase) {
+	t.Helper()
+	for _, strict := range []bool{true, false} {
+		for _, tt := range tests {
+			t.Run(tt.name+map[bool]string{true: "/strict", false: "/lax"}[strict], func(t *testing.T) {
+				setStrictPaths(t, strict)
+				want := tt.lax
+				if strict {
+					want = tt.strict
+				}
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+				if strings.Contains(rec.Body.String(), secret) {
+					t.Fatalf("%s disclosed the secret (status %d)", tt.target, rec.Code)
+				}
+				if rec.Code != want {
+					t.Fatalf("%s: status %d, want %d: %s", tt.target, rec.Code, want, rec.Body)
+				}
+				if want == http.StatusOK && !strings.Contains(rec.Body.String(), tt.wantBodyPart) {
+					t.Fatalf("%s: body %q, want %q", tt.target, rec.Body, tt.wantBodyPart)
+				}
+			})
+		}
+	}
+}
+
+func TestServeImageTraversal(t *testing.T) {
+	root, imagesDir := traversalTree(t, "images", map[string]string{"a.jpg": "frame"})
+	h := http.HandlerFunc(NewImagesHandler(imagesDir).serveImage)
+
+	runTraversal(t, h, []traversalCase{
+		{"plain", "/images/a.jpg", 200, 200, "frame"},
+		{"missing", "/images/b.jpg", 404, 404, ""},
+		{"dot dot", "/images/../secret.txt", 400, 404, ""},
+		{"encoded dot dot", "/images/%2e%2e/secret.txt", 400, 404, ""},
+		{"encoded slash", "/images/%2e%2e%2fsecret.txt", 400, 404, ""},
+		{"backslash", "/images/..%5csecret.txt", 400, 404, ""},
+		{"nested backslash", "/images/x%5c..%5c..%5csecret.txt", 400, 404, ""},
+		{"absolute", "/images/" + url.PathEscape(filepath.Join(root, "secret.txt")), 400, 404, ""},
+		{"double slash absolute", "/images//" + filepath.ToSlash(root) + "/secret.txt", 400, 404, ""},
+	})
+}
+
+func TestUITraversal(t *testing.T) {
+	root, uiDir := traversalTree(t, "ui", map[string]string{"index.html": "<html>index", "app.js": "app()"})
+	h, _ := UIHandler(uiDir, nil)
+	if h == nil {
+		t.Fatal("no UI handler")
+	}
+
+	// In lax mode the file server itself refuses ".."; unknown names get the
+	// SPA's index.html, never a file outside the UI directory.
+	runTraversal(t, h, []traversalCase{
+		{"asset", "/app.js", 200, 200, "app()"},
+		{"spa route", "/labels/today", 200, 200, "index"},
+		{"dot dot", "/../secret.txt", 400, 400, ""},
+		{"encoded dot dot", "/%2e%2e/secret.txt", 400, 400, ""},
+		{"encoded slash", "/assets%2f..%2f..%2fsecret.txt", 400, 400, ""},
+		{"backslash", "/..%5csecret.txt", 400, 400, ""},
+		{"absolute", "/" + filepath.ToSlash(root) + "/secret.txt", 200, 200, "index"},
+	})
+}
+
+func TestModelDownloadTraversal(t *testing.T) {
+	root := t.TempDir()
+	modelsDir := filepath.Join(root, "models")
+	publishModel(t, modelsDir, "v1")
+	for _, p := range []string{filepath.Join(root, "secret.txt"), filepath.Join(modelsDir, "skystate", "secret.txt")} {
+		if err := os.WriteFile(p, []byte(secret), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mux := http.NewServeMux()
+	NewModelsHandler(nil, modelsDir).RegisterRoutes(mux)
+
+	download := func(version, file string) string {
+		return "/api/models/download?" + url.Values{"version": {version}, "file": {file}}.Encode()
+	}
+	runTraversal(t, mux, []traversalCase{
+		{"default", download("", ""), 200, 200, "onnx"},
+		{"allowed file", download("v1", "classes.json"), 200, 200, "clear"},
+		{"allowed but missing", download("v1", "meta.json"), 404, 404, ""},
+		{"not allowed", download("v1", "DONE"), 400, 200, ""},
+		{"dot dot file", download("v1", "../secret.txt"), 400, 404, ""},
+		{"deep dot dot file", download("v1", "../../../secret.txt"), 400, 404, ""},
+		{"encoded dot dot file", "/api/models/download?version=v1&file=%2e%2e%2fsecret.txt", 400, 404, ""},
+		{"backslash file", download("v1", "..\\secret.txt"), 400, 404, ""},
+		{"absolute file", download("v1", filepath.Join(root, "secret.txt")), 400, 404, ""},
+		{"dot dot version", download("..", "secret.txt"), 400, 404, ""},
+		{"unpublished version", download("v1/..", "model.onnx"), 404, 404, ""},
+	})
+}
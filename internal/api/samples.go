@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/SkyClf/SkyClf/internal/apitypes"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+const (
+	defaultSamples = 20
+	maxSamples     = 200
+)
+
+// handleSamples returns a reproducible random sample of labeled images per
+// class, for eyeballing label mistakes before training.
+// GET /api/dataset/samples?skystate=precipitation&n=20&seed=7
+// Query params:
+//   - skystate: one class; omitted or "all" returns every class
+//   - n: images per class (default 20, max 200)
+//   - seed: the same seed returns the same images (default 0)
+func (h *DatasetHandler) handleSamples(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	n := defaultSamples
+	if raw := q.Get("n"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 {
+			http.Error(w, "invalid n", http.StatusBadRequest)
+			return
+		}
+		n = min(v, maxSamples)
+	}
+	var seed int64
+	if raw := q.Get("seed"); raw != "" {
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid seed", http.StatusBadRequest)
+			return
+		}
+		seed = v
+	}
+	skystate := strings.TrimSpace(q.Get("skystate"))
+	classes := store.SkyStates
+	if skystate != "" && skystate != "all" {
+		if !validSkystate(skystate) {
+			http.Error(w, "invalid skystate", http.StatusBadRequest)
+			return
+		}
+		classes = []string{skystate}
+	}
+
+	samples, err := h.st.SampleLabeled(r.Context(), classes, n, seed)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	byClass := make(map[string][]apitypes.SampleImage, len(samples))
+	for c, ims := range samples {
+		items := make([]apitypes.SampleImage, 0, len(ims))
+		for _, im := range ims {
+			items = append(items, apitypes.SampleImage{SampleImage: im, URL: "/images/" + filepath.Base(im.Path)})
+		}
+		byClass[c] = items
+	}
+
+	if len(classes) == 1 {
+		writeJSON(w, http.StatusOK, apitypes.ClassSample{
+			Skystate: skystate,
+			N:        n,
+			Seed:     seed,
+			Count:    len(byClass[skystate]),
+			Items:    byClass[skystate],
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, apitypes.Samples{N: n, Seed: seed, Classes: byClass})
+}
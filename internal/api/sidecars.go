@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/SkyClf/SkyClf/internal/labelsidecar"
+)
+
+// SetSidecarWriter enables POST /api/admin/sidecars/backfill.
+func (h *AdminHandler) SetSidecarWriter(w *labelsidecar.Writer) {
+	h.sidecars = w
+}
+
+// POST /api/admin/sidecars/backfill - write the label sidecar
+// (image.jpg.skyclf.json) of every labeled image, e.g. after enabling
+// SKYCLF_LABEL_SIDECARS on an existing dataset. Images whose sidecar can't be
+// written are counted as failed and logged.
+func (h *AdminHandler) handleSidecarBackfill(w http.ResponseWriter, r *http.Request) {
+	if h.sidecars == nil {
+		writeError(w, http.StatusNotFound, "label sidecars not enabled (SKYCLF_LABEL_SIDECARS)")
+		return
+	}
+	if !h.sidecarBusy.TryLock() {
+		writeError(w, http.StatusConflict, "a backfill is already running")
+		return
+	}
+	defer h.sidecarBusy.Unlock()
+	res, err := h.sidecars.Backfill(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
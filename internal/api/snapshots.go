@@ -0,0 +1,63 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/apitypes"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// POST /api/dataset/snapshots {"name": "dataset-2024-10"} - freeze the current
+// labels under a name. Export with /api/dataset/export?snapshot=<name> or
+// train with {"snapshot": "<name>"} to use them instead of the live labels.
+// 409 if the name is taken.
+func (h *DatasetHandler) handleCreateSnapshot(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if !store.ValidSnapshotName(req.Name) {
+		http.Error(w, "invalid name; use letters, digits, '.', '_' and '-' (at most 64)", http.StatusBadRequest)
+		return
+	}
+	snap, err := h.st.CreateSnapshot(r.Context(), req.Name, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if snap == nil {
+		http.Error(w, "snapshot "+req.Name+" already exists", http.StatusConflict)
+		return
+	}
+	writeJSON(w, http.StatusCreated, snap)
+}
+
+// GET /api/dataset/snapshots - snapshots with their label counts, newest first
+func (h *DatasetHandler) handleListSnapshots(w http.ResponseWriter, r *http.Request) {
+	snaps, err := h.st.ListSnapshots(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, apitypes.Snapshots{Snapshots: snaps})
+}
+
+// DELETE /api/dataset/snapshots/{name} - drop a snapshot; live labels stay
+func (h *DatasetHandler) handleDeleteSnapshot(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	ok, err := h.st.DeleteSnapshot(r.Context(), name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "snapshot "+name+" not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, apitypes.SnapshotDeleted{Deleted: name})
+}
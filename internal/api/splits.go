@@ -0,0 +1,42 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/SkyClf/SkyClf/internal/apitypes"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// POST /api/dataset/split - assign a train/val/test split to every labeled image
+// that has none yet, deterministically from its sha256. Existing assignments are
+// kept. Optional body: {"train": 0.8, "val": 0.1, "test": 0.1} (the default).
+func (h *DatasetHandler) handleAssignSplits(w http.ResponseWriter, r *http.Request) {
+	ratios := store.DefaultSplitRatios
+	if err := json.NewDecoder(r.Body).Decode(&ratios); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if err := ratios.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	assigned, err := h.st.AssignSplits(r.Context(), ratios)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	stats, err := h.st.CountStats(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, apitypes.Splits{
+		Ratios:   ratios,
+		Assigned: assigned,
+		BySplit:  stats.BySplit,
+	})
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/SkyClf/SkyClf/internal/fetcher"
+	"github.com/SkyClf/SkyClf/internal/ingest"
+	"github.com/SkyClf/SkyClf/internal/storage"
+	"github.com/SkyClf/SkyClf/internal/thermal"
+)
+
+// storagePaths lists the endpoints (path prefixes) that read or write image or
+// model files. While the data directory is unavailable they answer 503
+// "storage unavailable" instead of failing halfway with I/O errors. A prefix
+// ending in "/" matches only paths below it.
+var storagePaths = []string{
+	"/images",
+	"/latest.jpg",
+	"/latest_annotated.jpg",
+	"/api/images",
+	"/api/latest",
+	"/api/clf",
+	"/api/classify",
+	"/api/eval/calibrate",
+	"/api/dataset/export",
+	"/api/dataset/phash/backfill",
+	"/api/dataset/days/", // DELETE of a whole day; the day list is DB only
+	"/api/models/download",
+	"/api/models/reload",
+	"/api/models/publish",
+	"/api/models/compare",
+	"/api/train/start",
+	"/api/admin", // reconcile would mistake a missing mount for deleted files
+}
+
+// StorageGuard wraps next so storagePaths fail fast while mon reports the
+// storage as unavailable.
+func StorageGuard(mon *storage.Monitor, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if needsStorage(r.URL.Path) && mon.Check() != nil {
+			writeError(w, http.StatusServiceUnavailable, storage.ErrUnavailable.Error())
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func needsStorage(path string) bool {
+	for _, p := range storagePaths {
+		if strings.HasSuffix(p, "/") {
+			if strings.HasPrefix(path, p) {
+				return true
+			}
+			continue
+		}
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// HealthHandler serves GET /api/health: 200 while storage is available,
+// 503 {"status": "degraded"} while it is not.
+type HealthHandler struct {
+	mon       *storage.Monitor
+	thermal   *thermal.Monitor
+	clockSkew *ingest.ClockSkew
+	fetcher   *fetcher.Fetcher // nil when not fetching
+	fetching  bool
+}
+
+func NewHealthHandler(mon *storage.Monitor) *HealthHandler {
+	return &HealthHandler{mon: mon, fetching: true}
+}
+
+// SetFetching reports whether the fetcher runs; with SKYCLF_MODE=no-fetch the
+// health response says fetching is disabled instead of leaving it implied.
+func (h *HealthHandler) SetFetching(enabled bool) {
+	h.fetching = enabled
+}
+
+// SetThermal adds the SoC temperature to the response. Being too hot pauses
+// auto-predict and training but isn't degraded: ingestion goes on.
+func (h *HealthHandler) SetThermal(m *thermal.Monitor) {
+	h.thermal = m
+}
+
+// SetClockSkew adds the camera clock skew to the response. A skew over the
+// threshold is a warning, not degraded.
+func (h *HealthHandler) SetClockSkew(c *ingest.ClockSkew) {
+	h.clockSkew = c
+}
+
+// SetFetcher adds the age of the last successful fetch to the response, known
+// from before a restart too. A stale fetch is a warning, not degraded.
+func (h *HealthHandler) SetFetcher(f *fetcher.Fetcher) {
+	h.fetcher = f
+}
+
+func (h *HealthHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/health", h.getHealth)
+}
+
+// GET /api/health - overall status with the storage probe result
+func (h *HealthHandler) getHealth(w http.ResponseWriter, r *http.Request) {
+	st := h.mon.Status()
+	status, code := "ok", http.StatusOK
+	if !st.Available {
+		status, code = "degraded", http.StatusServiceUnavailable
+	}
+	fetching := "enabled"
+	if !h.fetching {
+		fetching = "disabled"
+	}
+	resp := map[string]any{"status": status, "storage": st, "fetching": fetching, "thermal": h.thermal.Status(), "clock_skew": h.clockSkew.Status()}
+	if h.fetcher != nil {
+		resp["fetch"] = h.fetcher.Freshness()
+	}
+	writeJSON(w, code, resp)
+}
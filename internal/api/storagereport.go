@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/SkyClf/SkyClf/internal/fetcher"
+	"github.com/SkyClf/SkyClf/internal/storage"
+)
+
+// storageAdmin is the state of the storage report and clean routes.
+type storageAdmin struct {
+	layout storage.Layout
+	inUse  func(ctx context.Context) ([]string, error) // nil = nothing in use
+	busy   sync.Mutex                                  // one walk at a time
+}
+
+// SetStorageLayout enables GET /api/admin/storage/report and POST
+// /api/admin/storage/clean over the server's directories.
+func (h *AdminHandler) SetStorageLayout(l storage.Layout) {
+	h.storage.layout = l
+}
+
+// SetInUse sets what storage cleaning must leave alone, e.g. the file list of
+// a running training (trainer.FilelistPaths).
+func (h *AdminHandler) SetInUse(fn func(ctx context.Context) ([]string, error)) {
+	h.storage.inUse = fn
+}
+
+// GET /api/admin/storage/report?rate=5000 - disk usage under the data
+// directory by category (images with a row, untracked image files, cache,
+// models per version, backups, database, training, tmp leftovers, other).
+// The walk visits at most rate entries per second (0 = unthrottled) and
+// stops when the request is canceled.
+func (h *AdminHandler) handleStorageReport(w http.ResponseWriter, r *http.Request) {
+	walker, ok := h.storageWalker(w, r)
+	if !ok {
+		return
+	}
+	defer h.storage.busy.Unlock()
+	rep, err := walker.Report(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, rep)
+}
+
+// POST /api/admin/storage/clean?targets=cache,tmp,untracked&dry_run=1 - remove
+// the files of the given categories that are older than an hour. "thumbs" is
+// accepted for cache, the only derived image copies kept. Images with a row
+// and files of a running training are never removed.
+func (h *AdminHandler) handleStorageClean(w http.ResponseWriter, r *http.Request) {
+	var targets []string
+	for _, t := range strings.Split(r.URL.Query().Get("targets"), ",") {
+		switch t = strings.TrimSpace(t); {
+		case t == "":
+		case t == "thumbs":
+			targets = append(targets, storage.CategoryCache)
+		case slices.Contains(storage.Cleanable, t):
+			targets = append(targets, t)
+		default:
+			writeError(w, http.StatusBadRequest, "unknown target "+t+"; use "+strings.Join(storage.Cleanable, ", "))
+			return
+		}
+	}
+	if len(targets) == 0 {
+		writeError(w, http.StatusBadRequest, "targets required: "+strings.Join(storage.Cleanable, ", "))
+		return
+	}
+
+	walker, ok := h.storageWalker(w, r)
+	if !ok {
+		return
+	}
+	defer h.storage.busy.Unlock()
+	opts := storage.CleanOptions{Targets: targets, DryRun: isTrue(r.URL.Query().Get("dry_run")), InUse: map[string]bool{}}
+	if h.storage.inUse != nil {
+		paths, err := h.storage.inUse(r.Context())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		for _, p := range paths {
+			opts.InUse[filepath.Clean(p)] = true
+		}
+	}
+	res, err := walker.Clean(r.Context(), opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// storageWalker takes the walk lock and returns a walker over the layout with
+// the tracked images loaded; on failure it has answered the request. The
+// caller unlocks h.storage.busy.
+func (h *AdminHandler) storageWalker(w http.ResponseWriter, r *http.Request) (*storage.Walker, bool) {
+	if h.storage.layout.DataDir == "" {
+		writeError(w, http.StatusNotFound, "storage report not configured")
+		return nil, false
+	}
+	rate := storage.DefaultWalkRate
+	if v := r.URL.Query().Get("rate"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "rate must be entries per second (0 = unthrottled)")
+			return nil, false
+		}
+		rate = n
+	}
+	if !h.storage.busy.TryLock() {
+		writeError(w, http.StatusConflict, "a storage walk is already running")
+		return nil, false
+	}
+	images, err := h.st.ListImageRefs(r.Context())
+	if err != nil {
+		h.storage.busy.Unlock()
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	tracked := make(map[string]bool, len(images))
+	for _, img := range images {
+		tracked[filepath.Clean(img.Path)] = true
+	}
+	return &storage.Walker{Layout: h.storage.layout, Tracked: tracked, Rate: rate, IsImage: fetcher.IsImageFile}, true
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+type autoLabelSettings struct {
+	MinConfidence float64 `json:"min_confidence"` // 0 = off
+	// ClassMinConfidence overrides MinConfidence for some classes
+	ClassMinConfidence map[string]float64 `json:"class_min_confidence"`
+}
+
+// handleGetAutoLabel returns the auto-label thresholds.
+// GET /api/labels/auto-label -> {"min_confidence": 0.95, "class_min_confidence": {"clear": 0.98}}
+func (h *DatasetHandler) handleGetAutoLabel(w http.ResponseWriter, r *http.Request) {
+	t, err := h.st.AutoLabelThresholds(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, autoLabelResponse(t))
+}
+
+// handleSetAutoLabel sets the confidence above which stored predictions become
+// label suggestions for unlabeled images; 0 turns suggestions off.
+// class_min_confidence overrides it per predicted class (values in (0,1]);
+// leaving it out keeps the stored overrides, {} removes them.
+// PUT /api/labels/auto-label {"min_confidence": 0.95, "class_min_confidence": {"clear": 0.98}}
+func (h *DatasetHandler) handleSetAutoLabel(w http.ResponseWriter, r *http.Request) {
+	var req autoLabelSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if req.MinConfidence < 0 || req.MinConfidence > 1 {
+		http.Error(w, "min_confidence must be between 0 and 1", http.StatusBadRequest)
+		return
+	}
+	for class, v := range req.ClassMinConfidence {
+		if !validSkystate(class) {
+			http.Error(w, "class_min_confidence: unknown class "+strconv.Quote(class), http.StatusBadRequest)
+			return
+		}
+		if !(v > 0 && v <= 1) {
+			http.Error(w, "class_min_confidence: "+class+" must be above 0 and at most 1", http.StatusBadRequest)
+			return
+		}
+	}
+	if err := h.st.SetSetting(r.Context(), store.SettingAutoLabelMinConfidence, req.MinConfidence); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if req.ClassMinConfidence != nil {
+		if err := h.st.SetSetting(r.Context(), store.SettingAutoLabelClassMinConf, req.ClassMinConfidence); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	h.handleGetAutoLabel(w, r)
+}
+
+func autoLabelResponse(t store.AutoLabelThresholds) autoLabelSettings {
+	if t.ByClass == nil {
+		t.ByClass = map[string]float64{}
+	}
+	return autoLabelSettings{MinConfidence: t.Global, ClassMinConfidence: t.ByClass}
+}
+
+// handleAcceptSuggestions promotes model suggestions to real labels (source "auto").
+// Query params (all optional, combined with AND):
+//   - date: images fetched on this day (YYYY-MM-DD, see SKYCLF_DAY_GROUPING)
+//   - skystate: only suggestions of this class
+//   - min_confidence: only suggestions at least this confident
+//
+// Suggestions must also reach the current auto-label threshold of their class.
+func (h *DatasetHandler) handleAcceptSuggestions(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := store.SuggestionFilter{
+		Day:      strings.TrimSpace(q.Get("date")),
+		Skystate: strings.TrimSpace(q.Get("skystate")),
+	}
+	if f.Day != "" {
+		if _, err := time.Parse("2006-01-02", f.Day); err != nil {
+			http.Error(w, "invalid date format; use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+	if f.Skystate != "" && !validSkystate(f.Skystate) {
+		http.Error(w, "invalid skystate value", http.StatusBadRequest)
+		return
+	}
+	if raw := q.Get("min_confidence"); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v < 0 || v > 1 {
+			http.Error(w, "invalid min_confidence", http.StatusBadRequest)
+			return
+		}
+		f.MinConfidence = v
+	}
+
+	thresholds, err := h.st.AutoLabelThresholds(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	f.Thresholds = &thresholds
+
+	n, err := h.st.AcceptSuggestions(r.Context(), f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "accepted": n})
+}
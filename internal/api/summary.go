@@ -0,0 +1,292 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/fetcher"
+	"github.com/SkyClf/SkyClf/internal/infer"
+	"github.com/SkyClf/SkyClf/internal/ingest"
+	"github.com/SkyClf/SkyClf/internal/store"
+	"github.com/SkyClf/SkyClf/internal/thermal"
+	"github.com/SkyClf/SkyClf/internal/trainer"
+	"github.com/SkyClf/SkyClf/internal/weather"
+)
+
+const (
+	// sectionTimeout bounds each summary section so one slow component can't stall the rest.
+	sectionTimeout = 3 * time.Second
+	// diskUsageTTL caches the images directory walk.
+	diskUsageTTL = time.Minute
+	// skipsWindow is how far back prediction skips are counted.
+	skipsWindow = 24 * time.Hour
+)
+
+// SummaryHandler aggregates the state a wall dashboard needs into one call.
+// Every section fails independently: a broken component yields null plus an
+// entry in "errors" instead of failing the whole response.
+type SummaryHandler struct {
+	st        *store.Store
+	fetch     *fetcher.Fetcher
+	pred      *infer.ORTPredictor
+	tr        *trainer.Trainer     // nil when training is disabled
+	queue     *ingest.PredictQueue // nil unless predicting on ingest
+	weather   *weather.Poller      // nil unless a weather source is configured
+	thermal   *thermal.Monitor     // nil unless a temperature sensor is configured
+	imagesDir string
+
+	diskMu    sync.Mutex
+	diskBytes int64
+	diskFiles int
+	diskAt    time.Time
+}
+
+// NewSummaryHandler creates a new summary API handler. tr may be nil.
+func NewSummaryHandler(st *store.Store, fetch *fetcher.Fetcher, pred *infer.ORTPredictor, tr *trainer.Trainer, imagesDir string) *SummaryHandler {
+	return &SummaryHandler{st: st, fetch: fetch, pred: pred, tr: tr, imagesDir: imagesDir}
+}
+
+// SetPredictQueue reports q's depth and drop counts in the
+// "prediction_queue" section.
+func (h *SummaryHandler) SetPredictQueue(q *ingest.PredictQueue) {
+	h.queue = q
+}
+
+// SetWeather reports the weather poller's state in the "weather" section.
+func (h *SummaryHandler) SetWeather(p *weather.Poller) {
+	h.weather = p
+}
+
+// SetThermal reports the SoC temperature in the "thermal" section.
+func (h *SummaryHandler) SetThermal(m *thermal.Monitor) {
+	h.thermal = m
+}
+
+// RegisterRoutes registers the summary API routes
+func (h *SummaryHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/summary", h.getSummary)
+}
+
+type summaryPrediction struct {
+	ImageID      string    `json:"image_id"`
+	Skystate     string    `json:"skystate"`
+	Confidence   float64   `json:"confidence"`
+	ModelVersion string    `json:"model_version"`
+	PredictedAt  time.Time `json:"predicted_at"`
+}
+
+// GET /api/summary - debounced sky state, latest prediction, dataset counts,
+// fetch age, active model, training state, prediction queue, prediction skips,
+// weather, thermal state and images disk usage
+func (h *SummaryHandler) getSummary(w http.ResponseWriter, r *http.Request) {
+	sections := map[string]func(ctx context.Context) (any, error){
+		"sky_state":        h.skyState,
+		"dataset":          h.dataset,
+		"fetcher":          h.fetcherSection,
+		"model":            h.model,
+		"training":         h.training,
+		"disk_usage":       h.diskUsage,
+		"prediction_queue": h.predictionQueue,
+		"prediction_skips": h.predictionSkips,
+		"weather":          h.weatherSection,
+		"thermal":          h.thermalSection,
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		resp = make(map[string]any, len(sections)+1)
+		errs = map[string]string{}
+	)
+	for name, fn := range sections {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(r.Context(), sectionTimeout)
+			defer cancel()
+			v, err := fn(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				resp[name] = nil
+				errs[name] = err.Error()
+				return
+			}
+			resp[name] = v
+		}()
+	}
+	wg.Wait()
+
+	resp["errors"] = errs
+	resp["generated_at"] = time.Now().UTC()
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// skyState reports the latest stored prediction and the majority state of the
+// last few predictions, which smooths out single-frame flips.
+func (h *SummaryHandler) skyState(ctx context.Context) (any, error) {
+	recent, err := h.st.RecentPredictions(ctx, store.DebounceWindow)
+	if err != nil {
+		return nil, err
+	}
+	if len(recent) == 0 {
+		return nil, errors.New("no predictions recorded yet")
+	}
+
+	latest := recent[0]
+	return map[string]any{
+		"debounced": store.Debounce(recent),
+		"window":    len(recent),
+		"latest": summaryPrediction{
+			ImageID:      latest.ImageID,
+			Skystate:     latest.Skystate,
+			Confidence:   latest.Confidence,
+			ModelVersion: latest.ModelVersion,
+			PredictedAt:  latest.PredictedAt,
+		},
+	}, nil
+}
+
+func (h *SummaryHandler) dataset(ctx context.Context) (any, error) {
+	stats, err := h.st.CountStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"total":     stats.Total,
+		"labeled":   stats.Labeled,
+		"unlabeled": stats.Unlabeled,
+	}, nil
+}
+
+func (h *SummaryHandler) fetcherSection(ctx context.Context) (any, error) {
+	if h.fetch == nil {
+		return map[string]any{"enabled": false}, nil // SKYCLF_MODE=no-fetch
+	}
+	st := h.fetch.Status()
+	out := map[string]any{
+		"last_saved":           st.LastSaved,
+		"last_success":         st.LastSuccess,
+		"last_error":           st.LastError,
+		"consecutive_failures": st.ConsecutiveFailures,
+	}
+	if !st.LastSaved.IsZero() {
+		out["last_fetch_age_s"] = int(time.Since(st.LastSaved).Seconds())
+	}
+	return out, nil
+}
+
+func (h *SummaryHandler) model(ctx context.Context) (any, error) {
+	mi := h.pred.ActiveModel()
+	if mi == nil {
+		if err := h.pred.LoadError(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("no model loaded")
+	}
+	return map[string]any{
+		"version":    mi.Version,
+		"classes":    mi.ClassNames,
+		"calibrated": mi.Calibration != nil,
+	}, nil
+}
+
+func (h *SummaryHandler) training(ctx context.Context) (any, error) {
+	if h.tr == nil {
+		return nil, errors.New("training disabled")
+	}
+	st := h.tr.Status(ctx)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"running":    st.Running,
+		"started_at": st.StartedAt,
+		"exit_code":  st.ExitCode,
+		"error":      st.Error,
+	}, nil
+}
+
+// diskUsage sums file sizes below the images directory; the walk is cached
+// for diskUsageTTL since the directory can hold tens of thousands of files.
+func (h *SummaryHandler) diskUsage(ctx context.Context) (any, error) {
+	h.diskMu.Lock()
+	defer h.diskMu.Unlock()
+
+	if time.Since(h.diskAt) > diskUsageTTL {
+		var (
+			bytes int64
+			files int
+		)
+		err := filepath.WalkDir(h.imagesDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if d.Type().IsRegular() {
+				if info, err := d.Info(); err == nil {
+					bytes += info.Size()
+					files++
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		h.diskBytes, h.diskFiles, h.diskAt = bytes, files, time.Now()
+	}
+
+	return map[string]any{
+		"images_dir":  h.imagesDir,
+		"bytes":       h.diskBytes,
+		"files":       h.diskFiles,
+		"measured_at": h.diskAt.UTC(),
+	}, nil
+}
+
+// predictionQueue reports the prediction queue; "enabled" is false unless
+// predicting on ingest.
+func (h *SummaryHandler) predictionQueue(ctx context.Context) (any, error) {
+	return h.queue.Stats(ctx)
+}
+
+// predictionSkips counts the images ingestion did not predict in the last
+// day by reason.
+func (h *SummaryHandler) predictionSkips(ctx context.Context) (any, error) {
+	byReason, err := h.st.PredictionSkipsSince(ctx, time.Now().Add(-skipsWindow))
+	if err != nil {
+		return nil, err
+	}
+	total := 0
+	for _, n := range byReason {
+		total += n
+	}
+	return map[string]any{
+		"window_hours": int(skipsWindow.Hours()),
+		"total":        total,
+		"by_reason":    byReason,
+	}, nil
+}
+
+// thermalSection reports the SoC temperature; "enabled" is false without a
+// sensor.
+func (h *SummaryHandler) thermalSection(ctx context.Context) (any, error) {
+	return h.thermal.Status(), nil
+}
+
+// weatherSection reports the latest reading and how the weather source is doing.
+func (h *SummaryHandler) weatherSection(ctx context.Context) (any, error) {
+	if h.weather == nil {
+		return nil, errors.New("weather source not configured")
+	}
+	return h.weather.Status(), nil
+}
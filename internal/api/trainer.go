@@ -2,8 +2,16 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/SkyClf/SkyClf/internal/apitypes"
+	"github.com/SkyClf/SkyClf/internal/thermal"
 	"github.com/SkyClf/SkyClf/internal/trainer"
 )
 
@@ -22,6 +30,58 @@ func (h *TrainerHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/train/status", h.getStatus)
 	mux.HandleFunc("POST /api/train/start", h.startTraining)
 	mux.HandleFunc("POST /api/train/stop", h.stopTraining)
+	mux.HandleFunc("DELETE /api/train/queue", h.cancelQueued)
+	mux.HandleFunc("GET /api/train/logs", h.getLogs)
+}
+
+// GET /api/train/logs?tail=N - last N log lines (default 200)
+// GET /api/train/logs?full=1 - complete log of the current/last run, streamed
+func (h *TrainerHandler) getLogs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	if q.Get("full") == "1" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fw := flushWriter{w: w}
+		if f, ok := w.(http.Flusher); ok {
+			fw.f = f
+		}
+		if err := h.trainer.WriteFullLogs(r.Context(), fw); err != nil {
+			log.Printf("api: stream train logs: %v", err)
+		}
+		return
+	}
+
+	tail := 200
+	if raw := q.Get("tail"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "invalid tail")
+			return
+		}
+		tail = min(n, trainer.MaxLogTail)
+	}
+
+	logs, err := h.trainer.Logs(r.Context(), tail)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(logs))
+}
+
+// flushWriter flushes after every write so long responses stream in chunks.
+type flushWriter struct {
+	w io.Writer
+	f http.Flusher
+}
+
+func (fw flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if fw.f != nil {
+		fw.f.Flush()
+	}
+	return n, err
 }
 
 // GET /api/train/status - Get current training status
@@ -30,57 +90,138 @@ func (h *TrainerHandler) getStatus(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, status)
 }
 
+// startRequest is a TrainConfig plus how to handle a busy trainer.
+type startRequest struct {
+	trainer.TrainConfig
+	QueueIfBusy bool `json:"queue_if_busy"` // run after the current job instead of failing
+}
+
 // POST /api/train/start - Start a training job
 // Request body: { "epochs": 10, "batch_size": 16, "lr": "0.001", ... }
+// "extra_env": {"TRAIN_AUGMENT_LEVEL": "2"} is set in the job container's
+// environment; keys must start with an allowed prefix (SKYCLF_TRAIN_ENV_PREFIXES).
+// "oversample": {"multipliers": {"precipitation": 4}, "seed": 1} or
+// {"balance": true} repeats rare-class train images in the file list; what
+// was done is recorded in the run.
+// With "queue_if_busy": true a request made while a job runs is queued and
+// started when that job ends (one at most; a second one gets 409).
+// While the board is too hot it answers 409 with "reason": "thermal"; when a
+// trainer volume is below SKYCLF_TRAIN_MIN_FREE_GB, 409 with "reason":
+// "disk_space" and the measured "free_bytes". Less than
+// SKYCLF_TRAIN_MIN_INTERVAL after the previous start it answers 429 with
+// "reason": "rate_limit", "next_start_at" and Retry-After, unless the request
+// has "ignore_min_interval": true. 503 means Docker or the trainer container
+// can't be reached.
 func (h *TrainerHandler) startTraining(w http.ResponseWriter, r *http.Request) {
-	cfg := trainer.DefaultTrainConfig()
+	req := startRequest{TrainConfig: trainer.DefaultTrainConfig()}
 
 	// Parse optional overrides from request body
 	if r.ContentLength > 0 {
-		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{
-				"error": "invalid request body: " + err.Error(),
-			})
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
 			return
 		}
 	}
 
+	cfg := req.TrainConfig
+
 	// Validate
 	if cfg.Epochs < 1 || cfg.Epochs > 1000 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error": "epochs must be between 1 and 1000",
-		})
+		writeError(w, http.StatusBadRequest, "epochs must be between 1 and 1000")
 		return
 	}
 	if cfg.BatchSize < 1 || cfg.BatchSize > 256 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error": "batch_size must be between 1 and 256",
+		writeError(w, http.StatusBadRequest, "batch_size must be between 1 and 256")
+		return
+	}
+	if cfg.Oversample != nil {
+		if err := cfg.Oversample.Validate(); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if bad := h.trainer.DisallowedEnv(cfg.ExtraEnv); len(bad) > 0 {
+		writeJSON(w, http.StatusBadRequest, apitypes.Error{
+			Error: "extra_env keys not allowed: " + strings.Join(bad, ", "),
+			Keys:  bad,
 		})
 		return
 	}
 
-	if err := h.trainer.Start(r.Context(), cfg); err != nil {
-		writeJSON(w, http.StatusConflict, map[string]string{
-			"error": err.Error(),
-		})
+	if req.QueueIfBusy {
+		queued, err := h.trainer.StartOrQueue(r.Context(), cfg)
+		if err != nil {
+			writeStartError(w, err)
+			return
+		}
+		if queued {
+			writeJSON(w, http.StatusAccepted, apitypes.Message{Message: "training queued"})
+			return
+		}
+	} else if err := h.trainer.Start(r.Context(), cfg); err != nil {
+		writeStartError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, apitypes.Message{Message: "training started"})
+}
+
+// writeStartError answers 409 for a run refused by the trainer's state, with
+// "reason": "thermal" while the board is too hot, "disk_space" when a trainer
+// volume is short of space (with the free bytes measured) and "volume" when
+// the trainer's images volume lacks the file list's images; a start too soon
+// after the previous one gets 429 with "reason": "rate_limit". Docker or the
+// trainer container being unreachable is 503, any other failure 500.
+func writeStartError(w http.ResponseWriter, err error) {
+	resp := apitypes.Error{Error: err.Error()}
+	var disk *trainer.DiskSpaceError
+	var tooSoon *trainer.TooSoonError
+	var guard *trainer.GuardError
+	switch {
+	case errors.As(err, &tooSoon):
+		resp.Reason, resp.NextStartAt = "rate_limit", &tooSoon.NextStart
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(tooSoon.NextStart).Seconds())+1))
+		writeJSON(w, http.StatusTooManyRequests, resp)
+		return
+	case errors.Is(err, thermal.ErrHot):
+		resp.Reason = "thermal"
+	case errors.As(err, &disk):
+		resp.Reason = "disk_space"
+		resp.Path, resp.FreeBytes, resp.MinFreeBytes = disk.Path, &disk.FreeBytes, disk.MinFreeBytes
+	case errors.Is(err, trainer.ErrVolume):
+		resp.Reason = "volume"
+	case errors.As(err, &guard), errors.Is(err, trainer.ErrBusy), errors.Is(err, trainer.ErrQueueFull),
+		errors.Is(err, trainer.ErrNoFilelist):
+	case errors.Is(err, trainer.ErrDockerUnavailable):
+		writeJSON(w, http.StatusServiceUnavailable, resp)
+		return
+	default:
+		log.Printf("api: start training: %v", err)
+		writeJSON(w, http.StatusInternalServerError, resp)
 		return
 	}
+	writeJSON(w, http.StatusConflict, resp)
+}
 
-	writeJSON(w, http.StatusAccepted, map[string]string{
-		"message": "training started",
+// DELETE /api/train/queue - Cancel the queued training job
+func (h *TrainerHandler) cancelQueued(w http.ResponseWriter, r *http.Request) {
+	cfg := h.trainer.CancelQueued()
+	if cfg == nil {
+		writeError(w, http.StatusNotFound, "no training queued")
+		return
+	}
+	writeJSON(w, http.StatusOK, apitypes.TrainCanceled{
+		Message:  "queued training canceled",
+		Canceled: cfg,
 	})
 }
 
 // POST /api/train/stop - Stop the running training job
 func (h *TrainerHandler) stopTraining(w http.ResponseWriter, r *http.Request) {
 	if err := h.trainer.Stop(r.Context()); err != nil {
-		writeJSON(w, http.StatusConflict, map[string]string{
-			"error": err.Error(),
-		})
+		writeError(w, http.StatusConflict, err.Error())
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]string{
-		"message": "training stopped",
-	})
+	writeJSON(w, http.StatusOK, apitypes.Message{Message: "training stopped"})
 }
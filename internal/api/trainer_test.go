@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/apitypes"
+	"github.com/SkyClf/SkyClf/internal/thermal"
+	"github.com/SkyClf/SkyClf/internal/trainer"
+)
+
+func TestWriteStartError(t *testing.T) {
+	hot := fmt.Errorf("%w: 81.0°C, resuming below 70.0°C", thermal.ErrHot)
+	tests := []struct {
+		name       string
+		err        error
+		want       int
+		wantReason string
+	}{
+		{"busy", trainer.ErrBusy, http.StatusConflict, ""},
+		{"deferred run", fmt.Errorf("%w: a queued run waits to start", trainer.ErrBusy), http.StatusConflict, ""},
+		{"queue full", trainer.ErrQueueFull, http.StatusConflict, ""},
+		{"too hot", &trainer.GuardError{Err: hot}, http.StatusConflict, "thermal"},
+		{"other guard", &trainer.GuardError{Err: errors.New("maintenance")}, http.StatusConflict, ""},
+		{"disk space", &trainer.DiskSpaceError{Path: "/data", FreeBytes: 1, MinFreeBytes: 2}, http.StatusConflict, "disk_space"},
+		{"volume", fmt.Errorf("%w: 3 images missing", trainer.ErrVolume), http.StatusConflict, "volume"},
+		{"no file list", fmt.Errorf("oversampling %w", trainer.ErrNoFilelist), http.StatusConflict, ""},
+		{"too soon", &trainer.TooSoonError{NextStart: time.Now().Add(time.Minute)}, http.StatusTooManyRequests, "rate_limit"},
+		{"docker down", fmt.Errorf("start container: %w", fmt.Errorf("connection refused (%w)", trainer.ErrDockerUnavailable)), http.StatusServiceUnavailable, ""},
+		{"class counts", fmt.Errorf("class counts: %w", errors.New("database is locked")), http.StatusInternalServerError, ""},
+		{"unknown", errors.New("boom"), http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeStartError(rec, tt.err)
+			if rec.Code != tt.want {
+				t.Fatalf("status %d, want %d", rec.Code, tt.want)
+			}
+			var resp apitypes.Error
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Reason != tt.wantReason || resp.Error != tt.err.Error() {
+				t.Fatalf("body %+v, want reason %q", resp, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/apitypes"
+	"github.com/SkyClf/SkyClf/internal/store"
+	"github.com/SkyClf/SkyClf/internal/trainer"
+)
+
+// TrainRunsHandler serves the history of completed training runs. It works
+// without Docker: the history outlives the trainer.
+type TrainRunsHandler struct {
+	st          *store.Store
+	minInterval time.Duration // between training starts (0 = no limit)
+}
+
+// NewTrainRunsHandler creates a new training run history handler
+func NewTrainRunsHandler(st *store.Store) *TrainRunsHandler {
+	return &TrainRunsHandler{st: st}
+}
+
+// SetMinStartInterval reports when the next training start is allowed.
+func (h *TrainRunsHandler) SetMinStartInterval(d time.Duration) {
+	h.minInterval = d
+}
+
+// RegisterRoutes registers the run history API routes
+func (h *TrainRunsHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/train/runs", h.list)
+	mux.HandleFunc("GET /api/train/runs/{id}/metrics", h.metrics)
+}
+
+// GET /api/train/runs?limit=50 - completed runs with their final validation
+// metrics, newest first, and the recent start attempts (refused and
+// overridden ones marked, with the time since the previous start) so a loop
+// of starts stands out
+func (h *TrainRunsHandler) list(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 1000 {
+			writeError(w, http.StatusBadRequest, "invalid limit (1-1000)")
+			return
+		}
+		limit = n
+	}
+	runs, err := h.st.ListTrainRuns(r.Context(), limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var starts trainer.StartHistory
+	if _, err := h.st.GetSetting(r.Context(), store.SettingTrainStarts, &starts); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp := apitypes.TrainRuns{Count: len(runs), Items: runs, Starts: []trainer.StartAttempt{}}
+	for i := len(starts.Attempts) - 1; i >= 0; i-- {
+		resp.Starts = append(resp.Starts, starts.Attempts[i])
+	}
+	if next := starts.NextStart(h.minInterval); !next.IsZero() {
+		resp.NextStartAt = &next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GET /api/train/runs/{id}/metrics - per-epoch loss/accuracy curves and final
+// validation metrics of a run, as written by the trainer (infer.TrainingMetrics)
+func (h *TrainRunsHandler) metrics(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	body, found, err := h.st.GetTrainRunMetrics(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "run not found")
+		return
+	}
+	if body == nil {
+		writeError(w, http.StatusNotFound, "no metrics recorded for this run")
+		return
+	}
+	writeJSON(w, http.StatusOK, apitypes.TrainRunMetrics{RunID: id, Metrics: body})
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// UIHandler serves the built frontend, preferring dir on disk when it exists and
+// falling back to embedded (may be nil). Unknown non-API paths get index.html so
+// client-side routes survive a reload. It returns nil if neither is available,
+// and a description of the source in use.
+func UIHandler(dir string, embedded fs.FS) (http.Handler, string) {
+	var fsys fs.FS
+	var src string
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		fsys, src = os.DirFS(dir), dir
+	} else if embedded != nil {
+		if _, err := fs.Stat(embedded, "index.html"); err == nil {
+			fsys, src = embedded, "embedded assets"
+		}
+	}
+	if fsys == nil {
+		return nil, ""
+	}
+
+	files := http.FileServerFS(fsys)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Serve index.html for SPA routes (non-API, non-file requests). fs.FS
+		// names are slash-separated, rooted and never contain "..", so cleaned
+		// paths can't escape the UI directory.
+		if badRequestPath(r) {
+			http.Error(w, errUnsafePath.Error(), http.StatusBadRequest)
+			return
+		}
+		p := path.Clean("/" + r.URL.Path)
+		if p != "/" && !strings.HasPrefix(p, "/api/") && !strings.HasPrefix(p, "/latest") {
+			if _, err := fs.Stat(fsys, strings.TrimPrefix(p, "/")); err != nil {
+				http.ServeFileFS(w, r, fsys, "index.html")
+				return
+			}
+		}
+		files.ServeHTTP(w, r)
+	}), src
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"runtime"
+
+	"github.com/SkyClf/SkyClf/internal/buildinfo"
+	"github.com/SkyClf/SkyClf/internal/infer"
+)
+
+// VersionHandler reports which build is running, for bug reports.
+type VersionHandler struct {
+	backend string // inference backend, "ort" or "remote"
+}
+
+// NewVersionHandler creates a new version handler
+func NewVersionHandler(backend string) *VersionHandler {
+	return &VersionHandler{backend: backend}
+}
+
+// RegisterRoutes registers the version API route
+func (h *VersionHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/version", h.get)
+}
+
+// GET /api/version - build version, commit and date, the Go runtime and the
+// ONNX Runtime library actually loaded
+func (h *VersionHandler) get(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, struct {
+		buildinfo.Info
+		GoVersion    string            `json:"go_version"`
+		InferBackend string            `json:"infer_backend"`
+		ONNXRuntime  infer.RuntimeInfo `json:"onnxruntime"`
+	}{buildinfo.Get(), runtime.Version(), h.backend, infer.Runtime()})
+}
+
+// ServerHeader wraps next so every response names the build in its Server header.
+func ServerHeader(next http.Handler) http.Handler {
+	server := "SkyClf/" + buildinfo.Get().Version
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", server)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,20 @@
+package apitypes
+
+import (
+	"github.com/SkyClf/SkyClf/internal/ingest"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// ClockSkew is the body of GET /api/admin/clock-skew: the camera clock's
+// current skew, the correction applied to capture times and the daily trend.
+type ClockSkew struct {
+	Current           ingest.ClockSkewStatus `json:"current"`
+	Correction        string                 `json:"correction"`         // "", "auto" or a duration
+	CorrectionSeconds float64                `json:"correction_seconds"` // subtracted from capture times now
+	Days              []store.ClockSkewDay   `json:"days"`               // oldest first
+}
+
+// ClockCorrection is the body of PUT /api/admin/clock-skew and its response.
+type ClockCorrection struct {
+	Correction string `json:"correction"`
+}
@@ -0,0 +1,35 @@
+// Package apitypes holds the response bodies of the HTTP API that scripts
+// consume: /api/clf, /api/latest, /api/dataset/* and /api/train/*. Handlers
+// marshal these types rather than building maps, so the JSON contract is
+// written down in one place.
+//
+// Field names are part of the contract. Renaming or removing one, or changing
+// its type, is a breaking change and bumps SchemaVersion; adding a field is
+// not.
+package apitypes
+
+import "time"
+
+// SchemaVersion is sent as schema_version in the prediction payloads
+// (/api/clf, /api/clf/cached, /api/latest).
+const SchemaVersion = 1
+
+// Error is the body of an error answered as JSON.
+type Error struct {
+	Error  string   `json:"error"`
+	Reason string   `json:"reason,omitempty"` // machine-readable cause where there is one, e.g. "thermal"
+	Keys   []string `json:"keys,omitempty"`   // offending request keys
+
+	// Reason "disk_space": the volume short of space and what it has
+	Path         string `json:"path,omitempty"`
+	FreeBytes    *int64 `json:"free_bytes,omitempty"`
+	MinFreeBytes int64  `json:"min_free_bytes,omitempty"`
+
+	// Reason "rate_limit": when the next start is allowed
+	NextStartAt *time.Time `json:"next_start_at,omitempty"`
+}
+
+// Message is the body of a request that only needs an acknowledgement.
+type Message struct {
+	Message string `json:"message"`
+}
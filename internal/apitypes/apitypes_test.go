@@ -0,0 +1,111 @@
+package apitypes
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/infer"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func ptr[T any](v T) *T { return &v }
+
+// TestGolden pins the JSON of the prediction payloads. A diff here is a
+// change to the API contract: if it renames, removes or retypes a field, bump
+// SchemaVersion; then rerun with -update.
+func TestGolden(t *testing.T) {
+	pred := &infer.Prediction{
+		SkyState:     "clear",
+		Confidence:   0.875,
+		Probs:        map[string]float32{"clear": 0.875, "heavy_clouds": 0.125},
+		ModelTask:    "skystate",
+		ModelVer:     "v3",
+		ModelPath:    "/data/models/skystate/v3/model.onnx",
+		Calibrated:   true,
+		Temperature:  1.5,
+		Logits:       []float32{2, 0},
+		ClassNames:   []string{"clear", "heavy_clouds"},
+		TopK:         []infer.ClassProb{{Class: "clear", Prob: 0.875}, {Class: "heavy_clouds", Prob: 0.125}},
+		PreprocessMS: 12.5,
+		InferenceMS:  40.25,
+	}
+	fetched := time.Date(2024, 10, 3, 21, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		body any
+	}{
+		{"clf", NewClf(pred, false)},
+		{"clf_detail", NewClf(pred, true)},
+		{"clf_cached", ClfCached{
+			SchemaVersion: SchemaVersion,
+			SkyState:      "clear",
+			Confidence:    0.875,
+			Probs:         pred.Probs,
+			ImageID:       "20241003_213000",
+			ModelVersion:  "v3",
+			FetchedAt:     fetched,
+			PredictedAt:   fetched.Add(2 * time.Second),
+			AgeSeconds:    90,
+		}},
+		{"latest", Latest{
+			SchemaVersion: SchemaVersion,
+			Status:        "ok",
+			Timestamp:     fetched.Add(90 * time.Second).Format(time.RFC3339),
+			Image: &LatestImage{
+				ID:        "20241003_213000",
+				SHA256:    "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+				FetchedAt: fetched.Format(time.RFC3339),
+				URL:       "/images/20241003_213000.jpg",
+				LatestURL: "/latest.jpg",
+			},
+			Label: &LatestLabel{
+				SkyState:  "clear",
+				Meteor:    ptr(false),
+				LabeledAt: ptr(fetched.Add(time.Hour).Format(time.RFC3339)),
+			},
+			Prediction: NewPrediction(pred),
+			Timings: &LatestTimings{
+				DBGetLatestMS: 0.5,
+				PreprocessMS:  12.5,
+				InferenceMS:   40.25,
+				TotalMS:       55,
+				Source:        "inference",
+			},
+		}},
+		{"latest_no_image", Latest{
+			SchemaVersion: SchemaVersion,
+			Status:        "no_image",
+			Timestamp:     fetched.Format(time.RFC3339),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.MarshalIndent(tt.body, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+			path := filepath.Join("testdata", tt.name+".golden.json")
+			if *update {
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("%s changed:\n--- got\n%s\n--- want\n%s", path, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package apitypes
+
+import (
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/imghash"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// Bodies that are a store type as is.
+type (
+	DatasetStats    = store.DatasetStats    // GET /api/dataset/stats
+	BiasReport      = store.BiasReport      // GET /api/dataset/bias-report
+	DatasetSnapshot = store.DatasetSnapshot // POST /api/dataset/snapshots
+)
+
+// ImageList is the body of GET /api/dataset/images and
+// GET /api/dataset/review-queue.
+type ImageList struct {
+	Count int                    `json:"count"`
+	Items []store.ImageWithLabel `json:"items"`
+}
+
+// Days is the body of GET /api/dataset/days.
+type Days struct {
+	Days []store.DaySummary `json:"days"`
+}
+
+// Overview is the body of GET /api/dataset/overview.
+type Overview struct {
+	Stats store.DatasetStats     `json:"stats"`
+	Days  []store.DaySummary     `json:"days"`
+	Count int                    `json:"count"`
+	Items []store.ImageWithLabel `json:"items"`
+}
+
+// NextUnlabeled is the body of GET /api/dataset/next-unlabeled.
+type NextUnlabeled struct {
+	Image          *store.ImageWithLabel `json:"image"`
+	Claimant       string                `json:"claimant"`
+	ClaimExpiresAt time.Time             `json:"claim_expires_at"`
+}
+
+// Splits is the body of POST /api/dataset/split.
+type Splits struct {
+	Ratios   store.SplitRatios         `json:"ratios"`
+	Assigned map[string]int            `json:"assigned"` // newly assigned per split
+	BySplit  map[string]map[string]int `json:"by_split"`
+}
+
+// SampleImage is an image of GET /api/dataset/samples.
+type SampleImage struct {
+	store.SampleImage
+	URL string `json:"url"`
+}
+
+// ClassSample is the body of GET /api/dataset/samples for one skystate.
+type ClassSample struct {
+	Skystate string        `json:"skystate"`
+	N        int           `json:"n"`
+	Seed     int64         `json:"seed"`
+	Count    int           `json:"count"`
+	Items    []SampleImage `json:"items"`
+}
+
+// Samples is the body of GET /api/dataset/samples for all classes.
+type Samples struct {
+	N       int                      `json:"n"`
+	Seed    int64                    `json:"seed"`
+	Classes map[string][]SampleImage `json:"classes"`
+}
+
+// Snapshots is the body of GET /api/dataset/snapshots.
+type Snapshots struct {
+	Snapshots []store.DatasetSnapshot `json:"snapshots"`
+}
+
+// SnapshotDeleted is the body of DELETE /api/dataset/snapshots/{name}.
+type SnapshotDeleted struct {
+	Deleted string `json:"deleted"`
+}
+
+// Dedup is the body of GET /api/dataset/dedup.
+type Dedup struct {
+	Threshold  int               `json:"threshold"`
+	Window     string            `json:"window"` // Go duration
+	Images     int               `json:"images"`
+	Hashed     int               `json:"hashed"`
+	Duplicates int               `json:"duplicates"`
+	Clusters   []imghash.Cluster `json:"clusters"`
+}
+
+// PHashBackfill is the body of GET /api/dataset/phash/backfill.
+type PHashBackfill struct {
+	Running    bool                   `json:"running"`
+	StartedAt  time.Time              `json:"started_at,omitempty"`
+	FinishedAt time.Time              `json:"finished_at,omitempty"`
+	Result     imghash.BackfillResult `json:"result"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// DayDryRun is the body of DELETE /api/dataset/days/{date}?dry_run=1.
+type DayDryRun struct {
+	DryRun     bool   `json:"dry_run"`
+	Permanent  bool   `json:"permanent"`
+	Date       string `json:"date"`
+	Count      int    `json:"count"`
+	FreedBytes int64  `json:"freed_bytes"`
+}
+
+// DayArchived is the body of DELETE /api/dataset/days/{date}.
+type DayArchived struct {
+	OK            bool   `json:"ok"`
+	Date          string `json:"date"`
+	Permanent     bool   `json:"permanent"` // false
+	ArchivedCount int    `json:"archived_count"`
+	ArchivedBytes int64  `json:"archived_bytes"`
+}
+
+// DayDeleted is the body of DELETE /api/dataset/days/{date}?permanent=1. OK
+// is false if some files couldn't be removed; the rows are gone regardless.
+type DayDeleted struct {
+	OK              bool     `json:"ok"`
+	Date            string   `json:"date"`
+	Permanent       bool     `json:"permanent"` // true
+	DeletedCount    int      `json:"deleted_count"`
+	DeletedFromDisk int      `json:"deleted_from_disk"`
+	FreedBytes      int64    `json:"freed_bytes"`
+	FileErrors      []string `json:"file_errors"`
+}
+
+// ImageDetail is the body of GET /api/images/{id}.
+type ImageDetail struct {
+	store.ImageDetail
+	FileExists bool       `json:"file_exists"`
+	Links      ImageLinks `json:"links"`
+}
+
+// ImageLinks are the endpoints serving one image.
+type ImageLinks struct {
+	File         string `json:"file"`
+	Preprocessed string `json:"preprocessed"` // the model input
+	Explain      string `json:"explain"`
+	Annotations  string `json:"annotations"`
+}
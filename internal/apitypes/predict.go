@@ -0,0 +1,170 @@
+package apitypes
+
+import (
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/infer"
+)
+
+// ClassProb is one entry of a top-k list.
+type ClassProb struct {
+	Class string  `json:"class"`
+	Prob  float32 `json:"prob"`
+}
+
+// Prediction is a model prediction as /api/latest reports it.
+type Prediction struct {
+	SkyState   string             `json:"skystate"`
+	Confidence float32            `json:"confidence"`
+	Probs      map[string]float32 `json:"probs,omitempty"`
+	ModelTask  string             `json:"task"`
+	ModelVer   string             `json:"model_version"`
+	ModelPath  string             `json:"model_path"`
+
+	// Set only when the model has a calibration in meta.json
+	Calibrated  bool    `json:"calibrated,omitempty"`
+	Temperature float64 `json:"temperature,omitempty"`
+
+	// Raw (uncalibrated) logits in ClassNames order, only when requested
+	Logits     []float32   `json:"logits,omitempty"`
+	ClassNames []string    `json:"class_names,omitempty"`
+	TopK       []ClassProb `json:"top_k,omitempty"`
+
+	PreprocessMS float64 `json:"preprocess_ms,omitempty"`
+	InferenceMS  float64 `json:"inference_ms,omitempty"`
+}
+
+// PredictReplay is the body of GET /api/predict/replay.
+type PredictReplay struct {
+	Running      bool      `json:"running"`
+	From         string    `json:"from,omitempty"`
+	To           string    `json:"to,omitempty"`
+	ModelVersion string    `json:"model_version,omitempty"`
+	Rate         float64   `json:"rate,omitempty"` // max images per second
+	Processed    int       `json:"processed"`      // including failed
+	Failed       int       `json:"failed"`
+	Total        int       `json:"total"`
+	StartedAt    time.Time `json:"started_at,omitempty"`
+	FinishedAt   time.Time `json:"finished_at,omitempty"`
+	ETASeconds   int       `json:"eta_seconds,omitempty"` // while running, from the rate so far
+	Canceled     bool      `json:"canceled,omitempty"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// NewPrediction converts p; nil stays nil.
+func NewPrediction(p *infer.Prediction) *Prediction {
+	if p == nil {
+		return nil
+	}
+	return &Prediction{
+		SkyState:     p.SkyState,
+		Confidence:   p.Confidence,
+		Probs:        p.Probs,
+		ModelTask:    p.ModelTask,
+		ModelVer:     p.ModelVer,
+		ModelPath:    p.ModelPath,
+		Calibrated:   p.Calibrated,
+		Temperature:  p.Temperature,
+		Logits:       p.Logits,
+		ClassNames:   p.ClassNames,
+		TopK:         newTopK(p.TopK),
+		PreprocessMS: p.PreprocessMS,
+		InferenceMS:  p.InferenceMS,
+	}
+}
+
+func newTopK(in []infer.ClassProb) []ClassProb {
+	if in == nil {
+		return nil
+	}
+	out := make([]ClassProb, len(in))
+	for i, c := range in {
+		out[i] = ClassProb{Class: c.Class, Prob: c.Prob}
+	}
+	return out
+}
+
+// Clf is the body of GET /api/clf. The detail fields are set with ?detail=1.
+type Clf struct {
+	SchemaVersion int                `json:"schema_version"`
+	SkyState      string             `json:"skystate"`
+	Confidence    float32            `json:"confidence"`
+	Probs         map[string]float32 `json:"probs"`
+
+	Logits     []float32   `json:"logits,omitempty"`
+	ClassNames []string    `json:"class_names,omitempty"`
+	TopK       []ClassProb `json:"top_k,omitempty"`
+}
+
+// NewClf converts p to a /api/clf body, with the detail fields if detail.
+func NewClf(p *infer.Prediction, detail bool) Clf {
+	c := Clf{
+		SchemaVersion: SchemaVersion,
+		SkyState:      p.SkyState,
+		Confidence:    p.Confidence,
+		Probs:         p.Probs,
+	}
+	if detail {
+		c.Logits = p.Logits
+		c.ClassNames = p.ClassNames
+		c.TopK = newTopK(p.TopK)
+	}
+	return c
+}
+
+// ClfCached is the body of GET /api/clf/cached, also sent with the 409 for a
+// prediction older than max_age (then with Error set).
+type ClfCached struct {
+	SchemaVersion int                `json:"schema_version"`
+	SkyState      string             `json:"skystate"`
+	Confidence    float64            `json:"confidence"`
+	Probs         map[string]float32 `json:"probs"`
+	ImageID       string             `json:"image_id"`
+	ModelVersion  string             `json:"model_version"`
+	FetchedAt     time.Time          `json:"fetched_at"`
+	PredictedAt   time.Time          `json:"predicted_at"`
+	AgeSeconds    int64              `json:"age_seconds"` // since FetchedAt
+	Error         string             `json:"error,omitempty"`
+}
+
+// Latest is the body of GET /api/latest. Status is "ok", or "no_image" with
+// Image, Label and Prediction null.
+type Latest struct {
+	SchemaVersion    int            `json:"schema_version"`
+	Status           string         `json:"status"`
+	Timestamp        string         `json:"timestamp"` // RFC 3339
+	Image            *LatestImage   `json:"image"`
+	Label            *LatestLabel   `json:"label"`
+	Prediction       *Prediction    `json:"prediction"`                  // null without a model or after a timeout
+	PredictionStatus string         `json:"prediction_status,omitempty"` // "timeout"
+	Timings          *LatestTimings `json:"timings,omitempty"`           // only with ?debug=1
+}
+
+// LatestImage is the newest image in a Latest.
+type LatestImage struct {
+	ID        string `json:"id"`
+	SHA256    string `json:"sha256"`
+	FetchedAt string `json:"fetched_at"` // RFC 3339
+	URL       string `json:"url"`        // this image's file
+	LatestURL string `json:"latest_url"` // always the newest file
+}
+
+// LatestLabel is the label of the newest image; Skystate is "unknown" and the
+// rest null while it is unlabeled.
+type LatestLabel struct {
+	SkyState  string  `json:"skystate"`
+	Meteor    *bool   `json:"meteor"`
+	LabeledAt *string `json:"labeled_at"` // RFC 3339
+}
+
+// LatestTimings is the ?debug=1 breakdown of a /api/latest request, in
+// milliseconds. Preprocess and inference are the predictor's own measurements
+// of the inference this request ran or waited for.
+type LatestTimings struct {
+	DBGetLatestMS float64 `json:"db_get_latest_ms"`
+	PreprocessMS  float64 `json:"preprocess_ms"`
+	InferenceMS   float64 `json:"inference_ms"`
+	TotalMS       float64 `json:"total_ms"`
+	Cached        bool    `json:"cached"`           // answered by another request's inference
+	Source        string  `json:"source,omitempty"` // inference, coalesced or cache; empty without a model
+}
@@ -0,0 +1,28 @@
+package apitypes
+
+import (
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/rules"
+)
+
+// Rule is an alerting rule as answered by /api/rules: its definition and
+// bookkeeping.
+type Rule struct {
+	ID int64 `json:"id"`
+	rules.Spec
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	LastFiredAt *time.Time `json:"last_fired_at"`
+}
+
+// Rules is the body of GET /api/rules.
+type Rules struct {
+	Rules []Rule `json:"rules"`
+}
+
+// RuleSimulation is the body of POST /api/rules/simulate: the firings the
+// rules would have produced over the given observations.
+type RuleSimulation struct {
+	Firings []rules.Firing `json:"firings"`
+}
@@ -0,0 +1,36 @@
+package apitypes
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/store"
+	"github.com/SkyClf/SkyClf/internal/trainer"
+)
+
+// TrainStatus is the body of GET /api/train/status.
+type TrainStatus = trainer.TrainStatus
+
+// TrainCanceled is the body of DELETE /api/train/queue.
+type TrainCanceled struct {
+	Message  string               `json:"message"`
+	Canceled *trainer.TrainConfig `json:"canceled"`
+}
+
+// TrainRuns is the body of GET /api/train/runs.
+type TrainRuns struct {
+	Count int              `json:"count"`
+	Items []store.TrainRun `json:"items"`
+
+	// Recent training starts, refused ones included, newest first, and when
+	// the next start is allowed by SKYCLF_TRAIN_MIN_INTERVAL (omitted = now)
+	Starts      []trainer.StartAttempt `json:"starts"`
+	NextStartAt *time.Time             `json:"next_start_at,omitempty"`
+}
+
+// TrainRunMetrics is the body of GET /api/train/runs/{id}/metrics. Metrics is
+// the trainer's metrics.json as written (infer.TrainingMetrics).
+type TrainRunMetrics struct {
+	RunID   string          `json:"run_id"`
+	Metrics json.RawMessage `json:"metrics"`
+}
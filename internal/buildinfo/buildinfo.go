@@ -0,0 +1,67 @@
+// Package buildinfo identifies the running build. Release builds set the
+// variables with -ldflags, e.g.
+//
+//	go build -ldflags "-X github.com/SkyClf/SkyClf/internal/buildinfo.Version=v1.4.0
+//	  -X github.com/SkyClf/SkyClf/internal/buildinfo.Commit=$(git rev-parse HEAD)
+//	  -X github.com/SkyClf/SkyClf/internal/buildinfo.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/server
+//
+// Without them, Commit and Date fall back to the VCS stamp go build embeds.
+package buildinfo
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+var (
+	Version = "dev"
+	Commit  = "" // full git commit
+	Date    = "" // build time, RFC3339
+)
+
+var fromVCS = sync.OnceFunc(func() {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	var modified bool
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if Commit == "" {
+				Commit = s.Value
+			}
+		case "vcs.time":
+			if Date == "" {
+				Date = s.Value
+			}
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	if modified && Version == "dev" {
+		Version = "dev-dirty"
+	}
+})
+
+// Info is the build as reported by GET /api/version.
+type Info struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit,omitempty"`
+	Date    string `json:"build_date,omitempty"`
+}
+
+// Get returns the build info.
+func Get() Info {
+	fromVCS()
+	return Info{Version: Version, Commit: Commit, Date: Date}
+}
+
+// String returns the version with a short commit, e.g. "v1.4.0 (3f2a9c1)".
+func String() string {
+	i := Get()
+	if i.Commit == "" {
+		return i.Version
+	}
+	return i.Version + " (" + i.Commit[:min(7, len(i.Commit))] + ")"
+}
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Sources of a Setting's value.
+const (
+	SourceEnv     = "env"     // set in the environment or .env
+	SourceDefault = "default" // built-in default
+	SourceDerived = "derived" // computed from another setting, e.g. paths under DataDir
+)
+
+// redacted replaces secret values in Settings.
+const redacted = "[redacted]"
+
+// Setting is one effective configuration value.
+type Setting struct {
+	Key      string `json:"key"` // environment variable
+	Value    any    `json:"value"`
+	Source   string `json:"source"`
+	Redacted bool   `json:"redacted,omitempty"`
+}
+
+// settingKind says how a value is shown.
+type settingKind int
+
+const (
+	plain   settingKind = iota
+	derived             // default is computed from another setting
+	secret              // never shown
+	urlish              // passwords and token-like query params hidden
+	command             // URLs among the arguments treated as urlish
+	hook                // only scheme and host shown (tokens often live in the path)
+)
+
+var settings = []struct {
+	key   string
+	kind  settingKind
+	value func(c Config) any
+}{
+	{"SKYCLF_ADDR", plain, func(c Config) any { return c.Addr }},
+	{"SKYCLF_GRPC_ADDR", plain, func(c Config) any { return c.GRPCAddr }},
+	{"SKYCLF_MODE", plain, func(c Config) any { return c.Mode }},
+	{"SKYCLF_SCAN_INTERVAL", plain, func(c Config) any { return c.ScanInterval }},
+	{"SKYCLF_ALLSKY_URL", urlish, func(c Config) any { return c.AllSkyURL }},
+	{"SKYCLF_FETCH_MODE", plain, func(c Config) any { return c.FetchMode }},
+	{"SKYCLF_FETCH_TZ", plain, func(c Config) any { return c.FetchTZ }},
+	{"SKYCLF_POLL_INTERVAL", plain, func(c Config) any { return c.PollInterval }},
+	{"SKYCLF_DATA_DIR", plain, func(c Config) any { return c.DataDir }},
+	{"SKYCLF_MODELS_DIR", derived, func(c Config) any { return c.ModelsDir }},
+	{"SKYCLF_IMAGES_DIR", derived, func(c Config) any { return c.ImagesDir }},
+	{"SKYCLF_LABELS_DB", derived, func(c Config) any { return c.LabelsDBPath }},
+	{"SKYCLF_LOG_LEVEL", plain, func(c Config) any { return c.LogLevel }},
+	{"SKYCLF_CAPTURE_CMD", command, func(c Config) any { return c.CaptureCmd }},
+	{"SKYCLF_CAPTURE_TIMEOUT", plain, func(c Config) any { return c.CaptureTimeout }},
+	{"SKYCLF_SIDECAR_SUFFIX", plain, func(c Config) any { return c.SidecarSuffix }},
+	{"SKYCLF_IMAGE_FORMATS", plain, func(c Config) any { return c.ImageFormats }},
+	{"SKYCLF_PHASH", plain, func(c Config) any { return c.PerceptualHash }},
+	{"SKYCLF_STORE_MAX_DIM", plain, func(c Config) any { return c.StoreMaxDim }},
+	{"SKYCLF_STORE_JPEG_QUALITY", plain, func(c Config) any { return c.StoreJPEGQuality }},
+	{"SKYCLF_PREDICT_ON_INGEST", plain, func(c Config) any { return c.PredictOnIngest }},
+	{"SKYCLF_PREDICT_WORKERS", plain, func(c Config) any { return c.PredictWorkers }},
+	{"SKYCLF_PREDICT_QUEUE_LIMIT", plain, func(c Config) any { return c.PredictQueueLimit }},
+	{"SKYCLF_POLL_ADAPTIVE", plain, func(c Config) any { return c.PollAdaptive }},
+	{"SKYCLF_POLL_MIN", derived, func(c Config) any { return c.PollMin }},
+	{"SKYCLF_POLL_MAX", plain, func(c Config) any { return c.PollMax }},
+	{"SKYCLF_POLL_DAY_INTERVAL", plain, func(c Config) any { return c.PollDayInterval }},
+	{"SKYCLF_SITE_LAT", plain, func(c Config) any { return siteCoord(c, c.SiteLat) }},
+	{"SKYCLF_SITE_LON", plain, func(c Config) any { return siteCoord(c, c.SiteLon) }},
+	{"SKYCLF_CLAIM_TTL", plain, func(c Config) any { return c.ClaimTTL }},
+	{"SKYCLF_DISPLAY_TZ", plain, func(c Config) any { return c.DisplayTZ }},
+	{"SKYCLF_DAY_GROUPING", plain, func(c Config) any { return c.DayGrouping }},
+	{"SKYCLF_STALE_FRAME_SKEW", plain, func(c Config) any { return c.StaleFrameSkew }},
+	{"SKYCLF_CLOCK_SKEW_WARN", plain, func(c Config) any { return c.ClockSkewWarn }},
+	{"SKYCLF_STORAGE_PROBE_INTERVAL", plain, func(c Config) any { return c.StorageProbeInterval }},
+	{"SKYCLF_ADMIN_TOKEN", secret, func(c Config) any { return c.AdminToken }},
+	{"SKYCLF_BACKUP_DIR", derived, func(c Config) any { return c.BackupDir }},
+	{"SKYCLF_QUOTA_MAX_IMAGES", plain, func(c Config) any { return c.QuotaMaxImages }},
+	{"SKYCLF_QUOTA_MAX_GB", plain, func(c Config) any { return c.QuotaMaxGB }},
+	{"SKYCLF_QUOTA_EVICT_LABELED", plain, func(c Config) any { return c.QuotaEvictLabeled }},
+	{"SKYCLF_QUOTA_INTERVAL", plain, func(c Config) any { return c.QuotaInterval }},
+	{"SKYCLF_WEATHER_URL", urlish, func(c Config) any { return c.WeatherURL }},
+	{"SKYCLF_WEATHER_INTERVAL", plain, func(c Config) any { return c.WeatherInterval }},
+	{"SKYCLF_THERMAL_PATH", plain, func(c Config) any { return c.ThermalPath }},
+	{"SKYCLF_THERMAL_MAX_C", plain, func(c Config) any { return c.ThermalMaxC }},
+	{"SKYCLF_THERMAL_RESUME_C", plain, func(c Config) any { return c.ThermalResumeC }},
+	{"SKYCLF_THERMAL_INTERVAL", plain, func(c Config) any { return c.ThermalInterval }},
+	{"SKYCLF_AUTH_USER", plain, func(c Config) any { return c.AuthUser }},
+	{"SKYCLF_AUTH_PASSWORD_HASH", secret, func(c Config) any { return c.AuthPasswordHash }},
+	{"SKYCLF_SESSION_TTL", plain, func(c Config) any { return c.SessionTTL }},
+	{"SKYCLF_WEBHOOK_URL", hook, func(c Config) any { return c.WebhookURL }},
+	{"SKYCLF_MQTT_URL", urlish, func(c Config) any { return c.MQTTURL }},
+	{"SKYCLF_STRICT_PATHS", plain, func(c Config) any { return c.StrictPaths }},
+	{"SKYCLF_INFER_BACKEND", plain, func(c Config) any { return c.InferBackend }},
+	{"SKYCLF_INFER_REMOTE_URL", urlish, func(c Config) any { return c.InferRemoteURL }},
+	{"SKYCLF_INFER_REMOTE_TIMEOUT", plain, func(c Config) any { return c.InferRemoteTimeout }},
+	{"SKYCLF_LATEST_PREDICT_TIMEOUT", plain, func(c Config) any { return c.LatestPredictTimeout }},
+	{"SKYCLF_ORT_INTRA_THREADS", plain, func(c Config) any { return c.ORTIntraThreads }},
+	{"SKYCLF_ORT_INTER_THREADS", plain, func(c Config) any { return c.ORTInterThreads }},
+	{"SKYCLF_ORT_EP", plain, func(c Config) any { return c.ORTProvider }},
+	{"SKYCLF_TRAINER_CONTAINER", plain, func(c Config) any { return c.TrainerContainer }},
+	{"SKYCLF_TRAIN_CONVERT_WEBP", plain, func(c Config) any { return c.TrainConvertWebP }},
+	{"SKYCLF_TRAIN_ENV_PREFIXES", plain, func(c Config) any { return c.TrainEnvPrefixes }},
+	{"SKYCLF_TRAIN_MIN_FREE_GB", plain, func(c Config) any { return c.TrainMinFreeGB }},
+	{"SKYCLF_TRAIN_MIN_INTERVAL", plain, func(c Config) any { return c.TrainMinInterval }},
+	{"SKYCLF_TRAIN_PATH_MAP", plain, func(c Config) any { return c.TrainPathMap }},
+	{"SKYCLF_SYNC_PEER_URL", urlish, func(c Config) any { return c.SyncPeerURL }},
+	{"SKYCLF_SYNC_INTERVAL", plain, func(c Config) any { return c.SyncInterval }},
+	{"SKYCLF_SYNC_DRY_RUN", plain, func(c Config) any { return c.SyncDryRun }},
+	{"SKYCLF_INSTANCE_NAME", derived, func(c Config) any { return c.InstanceName }},
+	{"SKYCLF_LABEL_SIDECARS", plain, func(c Config) any { return c.LabelSidecars }},
+}
+
+func siteCoord(c Config, v float64) any {
+	if !c.HasSite {
+		return nil
+	}
+	return v
+}
+
+// Settings returns every setting with its effective value and source, in the
+// order of .env.example. Secrets are redacted, as are passwords and tokens in
+// URLs, so the result is safe to show over the API.
+func (c Config) Settings() []Setting {
+	out := make([]Setting, 0, len(settings))
+	for _, s := range settings {
+		st := Setting{Key: s.key, Value: s.value(c), Source: SourceDefault}
+		if strings.TrimSpace(os.Getenv(s.key)) != "" {
+			st.Source = SourceEnv
+		} else if s.kind == derived {
+			st.Source = SourceDerived
+		}
+		if d, ok := st.Value.(time.Duration); ok {
+			st.Value = d.String()
+		}
+		if v, ok := st.Value.(string); ok && v != "" {
+			var r string
+			switch s.kind {
+			case secret:
+				r = redacted
+			case urlish:
+				r = redactURL(v)
+			case command:
+				fields := strings.Fields(v)
+				for i, f := range fields {
+					fields[i] = redactURL(f)
+				}
+				r = strings.Join(fields, " ")
+			case hook:
+				r = redactPath(v)
+			default:
+				r = v
+			}
+			st.Value, st.Redacted = r, r != v
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+// sensitiveParams are query parameters hidden by redactURL.
+var sensitiveParams = []string{"token", "key", "apikey", "api_key", "password", "pass", "secret", "auth"}
+
+// redactURL hides the password of a URL's user info and the values of
+// token-like query parameters. Anything that doesn't parse as an absolute URL
+// is returned unchanged.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return raw
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	}
+	if u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			for _, p := range sensitiveParams {
+				if strings.EqualFold(k, p) {
+					q.Set(k, "xxxxx")
+				}
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
+
+// redactPath keeps only the scheme and host of a URL.
+func redactPath(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return redacted
+	}
+	return u.Scheme + "://" + u.Host + "/" + redacted
+}
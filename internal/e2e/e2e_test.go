@@ -0,0 +1,484 @@
+//go:build integration
+
+// Package e2e runs the server binary end to end against a temp data dir and
+// walks the happy path through its HTTP API: frames fetched from a simulated
+// camera, listed, labeled, counted and exported, a training run started on a
+// fake Docker daemon and completed, the new model published and reloaded, and
+// /api/clf answering from it, also through a gRPC client. It catches wiring
+// mistakes in cmd/server that the packages can't see on their own. Inference
+// runs on a stub remote backend, so ONNX Runtime isn't needed.
+//
+//	go test -tags=integration ./internal/e2e
+//
+// Takes about 10 seconds; -args -server=PATH tests a prebuilt binary, -keep
+// keeps the data dir.
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcstatus "google.golang.org/grpc/status"
+
+	skyclfv1 "github.com/SkyClf/SkyClf/api/proto/skyclf/v1"
+	"github.com/SkyClf/SkyClf/internal/fetcher/testutil"
+)
+
+const (
+	pollInterval = 2 * time.Second // the minimum config.Load accepts
+	timeout      = time.Minute     // for the whole run
+	frames       = 3
+	trainerName  = "skyclf-trainer"
+)
+
+var (
+	serverBin = flag.String("server", "", "server binary to run (default: build ./cmd/server)")
+	keep      = flag.Bool("keep", false, "keep the temp data dir and log its path")
+)
+
+func TestEndToEnd(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	tmp := t.TempDir()
+	if *keep {
+		var err error
+		if tmp, err = os.MkdirTemp("", "skyclf-e2e-"); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("data dir: %s", tmp)
+	}
+	dataDir := filepath.Join(tmp, "data")
+
+	bin := *serverBin
+	if bin == "" {
+		bin = filepath.Join(tmp, "skyclf-server")
+		t.Log("--- build server")
+		build := exec.CommandContext(ctx, "go", "build", "-o", bin, "github.com/SkyClf/SkyClf/cmd/server")
+		if out, err := build.CombinedOutput(); err != nil {
+			t.Fatalf("go build: %v\n%s", err, out)
+		}
+	}
+
+	cam := testutil.NewCamera()
+	defer cam.Close()
+	backend := newInferBackend()
+	defer backend.Close()
+	docker := newDockerDaemon(trainerName, filepath.Join(dataDir, "models"))
+	defer docker.Close()
+	// The remote instance serves the new model once training has produced it
+	docker.onDone = backend.setActive
+
+	addr := freeAddr(t)
+	grpcAddr := freeAddr(t)
+	h := &harness{t: t, base: "http://" + addr, client: &http.Client{Timeout: 10 * time.Second}}
+
+	t.Log("--- start server")
+	h.startServer(bin, tmp,
+		"SKYCLF_ADDR="+addr,
+		"SKYCLF_GRPC_ADDR="+grpcAddr,
+		"SKYCLF_DATA_DIR="+dataDir,
+		"SKYCLF_ALLSKY_URL="+cam.URL(),
+		"SKYCLF_POLL_INTERVAL="+pollInterval.String(),
+		"SKYCLF_INFER_BACKEND=remote",
+		"SKYCLF_INFER_REMOTE_URL="+backend.URL(),
+		"SKYCLF_TRAINER_CONTAINER="+trainerName,
+		"DOCKER_HOST="+docker.Host(),
+	)
+
+	h.waitFor(ctx, "server ready", func() error {
+		return expectStatus(h.get("/ready"), http.StatusOK)
+	})
+
+	var version struct {
+		Version string `json:"version"`
+	}
+	h.check(h.getJSON("/api/version", &version), "GET /api/version")
+	if version.Version == "" {
+		t.Fatalf("/api/version reports no version")
+	}
+
+	t.Log("--- ingest frames")
+	var list struct {
+		Count int `json:"count"`
+		Items []struct {
+			ID       string  `json:"id"`
+			Path     string  `json:"path"`
+			Skystate *string `json:"skystate"`
+		} `json:"items"`
+	}
+	for i := 1; i < frames; i++ {
+		h.waitFor(ctx, fmt.Sprintf("frame %d listed", i), func() error {
+			if err := h.getJSON("/api/dataset/images", &list); err != nil {
+				return err
+			}
+			if list.Count < i {
+				return fmt.Errorf("%d images listed, want %d", list.Count, i)
+			}
+			return nil
+		})
+		cam.Advance()
+	}
+	h.waitFor(ctx, "all frames listed", func() error {
+		if err := h.getJSON("/api/dataset/images", &list); err != nil {
+			return err
+		}
+		if list.Count != frames {
+			return fmt.Errorf("%d images listed, want %d", list.Count, frames)
+		}
+		return nil
+	})
+	for _, it := range list.Items {
+		if it.Skystate != nil {
+			t.Fatalf("image %s is labeled before labeling", it.ID)
+		}
+		if _, err := os.Stat(it.Path); err != nil {
+			t.Fatalf("image %s: %v", it.ID, err)
+		}
+	}
+	h.check(expectStatus(h.get("/api/latest"), http.StatusOK), "GET /api/latest")
+
+	t.Log("--- label via the API")
+	labels := []string{"clear", "heavy_clouds", "clear"}
+	for i, it := range list.Items {
+		body := fmt.Sprintf(`{"image_id":%q,"skystate":%q,"labeler":"e2e"}`, it.ID, labels[i])
+		h.check(expectStatus(h.post("/api/labels", body), http.StatusOK), "POST /api/labels")
+	}
+	h.check(expectStatus(h.post("/api/labels", `{"image_id":"`+list.Items[0].ID+`","skystate":"sunny"}`), http.StatusBadRequest), "label with an invalid skystate")
+
+	t.Log("--- stats")
+	var stats struct {
+		Total     int            `json:"total"`
+		Labeled   int            `json:"labeled"`
+		Unlabeled int            `json:"unlabeled"`
+		ByClass   map[string]int `json:"by_class"`
+	}
+	h.check(h.getJSON("/api/dataset/stats", &stats), "GET /api/dataset/stats")
+	if stats.Total != frames || stats.Labeled != frames || stats.Unlabeled != 0 {
+		t.Fatalf("stats: total=%d labeled=%d unlabeled=%d, want %d/%d/0", stats.Total, stats.Labeled, stats.Unlabeled, frames, frames)
+	}
+	if stats.ByClass["clear"] != 2 || stats.ByClass["heavy_clouds"] != 1 {
+		t.Fatalf("stats by class: %v", stats.ByClass)
+	}
+
+	t.Log("--- export")
+	resp := h.get("/api/dataset/export")
+	h.check(expectStatus(resp, http.StatusOK), "GET /api/dataset/export")
+	rows, err := csv.NewReader(bytes.NewReader(resp.body)).ReadAll()
+	h.check(err, "parse export")
+	if len(rows) != frames+1 {
+		t.Fatalf("export has %d rows, want a header and %d images", len(rows), frames)
+	}
+
+	t.Log("--- no model yet")
+	h.check(expectStatus(h.get("/api/clf"), http.StatusServiceUnavailable), "GET /api/clf before training")
+
+	t.Log("--- train")
+	h.check(expectStatus(h.post("/api/train/start", `{"epochs":1,"batch_size":4}`), http.StatusAccepted), "POST /api/train/start")
+	var status struct {
+		Running  bool   `json:"running"`
+		RunID    string `json:"run_id"`
+		ExitCode int    `json:"exit_code"`
+		Error    string `json:"error"`
+	}
+	h.check(h.getJSON("/api/train/status", &status), "GET /api/train/status")
+	if !status.Running || status.RunID == "" {
+		t.Fatalf("status right after start: running=%t run_id=%q", status.Running, status.RunID)
+	}
+	h.check(expectStatus(h.post("/api/train/start", `{"epochs":1}`), http.StatusConflict), "second start while training")
+	h.waitFor(ctx, "training finished", func() error {
+		if err := h.getJSON("/api/train/status", &status); err != nil {
+			return err
+		}
+		if status.Running {
+			return fmt.Errorf("still running")
+		}
+		return nil
+	})
+	if status.ExitCode != 0 || status.Error != "" {
+		t.Fatalf("training: exit code %d, error %q", status.ExitCode, status.Error)
+	}
+	cmds := docker.Commands()
+	if len(cmds) != 1 || !slices.Contains(cmds[0], "--filelist") {
+		t.Fatalf("job containers created: %q, want one with --filelist", cmds)
+	}
+
+	t.Log("--- model reloaded")
+	h.waitFor(ctx, "model v1 active", func() error {
+		var m struct {
+			Active *string `json:"active"`
+		}
+		if err := h.getJSON("/api/models", &m); err != nil {
+			return err
+		}
+		if m.Active == nil || *m.Active != "v1" {
+			return fmt.Errorf("active model %v", m.Active)
+		}
+		return nil
+	})
+	if _, err := os.Stat(filepath.Join(dataDir, "models", "skystate", "v1", "DONE")); err != nil {
+		t.Fatalf("v1 not published: %v", err)
+	}
+	var lineage struct {
+		Versions []struct {
+			Version string `json:"version"`
+			Lineage *struct {
+				RunID string `json:"run_id"`
+			} `json:"lineage"`
+		} `json:"versions"`
+	}
+	// Training reports finished before OnComplete has recorded the run
+	h.waitFor(ctx, "lineage recorded", func() error {
+		if err := h.getJSON("/api/models/lineage", &lineage); err != nil {
+			return err
+		}
+		if len(lineage.Versions) != 1 || lineage.Versions[0].Lineage == nil || lineage.Versions[0].Lineage.RunID != status.RunID {
+			return fmt.Errorf("lineage %+v, want v1 from run %s", lineage.Versions, status.RunID)
+		}
+		return nil
+	})
+
+	t.Log("--- run history")
+	var runs struct {
+		Items []struct {
+			RunID    string   `json:"run_id"`
+			Versions []string `json:"versions"`
+			Summary  *struct {
+				Accuracy float64 `json:"accuracy"`
+			} `json:"summary"`
+			Warning string `json:"warning"`
+		} `json:"items"`
+	}
+	h.waitFor(ctx, "run recorded", func() error {
+		if err := h.getJSON("/api/train/runs", &runs); err != nil {
+			return err
+		}
+		if len(runs.Items) != 1 {
+			return fmt.Errorf("%d runs recorded", len(runs.Items))
+		}
+		return nil
+	})
+	if run := runs.Items[0]; run.RunID != status.RunID || run.Warning != "" || run.Summary == nil || run.Summary.Accuracy != 0.9 {
+		t.Fatalf("run history: %+v, want run %s with accuracy 0.9", run, status.RunID)
+	}
+	var curves struct {
+		Metrics struct {
+			ValAccuracy []float64 `json:"val_accuracy"`
+		} `json:"metrics"`
+	}
+	h.check(h.getJSON("/api/train/runs/"+status.RunID+"/metrics", &curves), "GET /api/train/runs/{id}/metrics")
+	if len(curves.Metrics.ValAccuracy) != 2 {
+		t.Fatalf("run metrics: val_accuracy %v, want 2 epochs", curves.Metrics.ValAccuracy)
+	}
+	h.check(expectStatus(h.get("/api/train/runs/nope/metrics"), http.StatusNotFound), "metrics of an unknown run")
+
+	t.Log("--- classify")
+	var clf struct {
+		Skystate   string  `json:"skystate"`
+		Confidence float64 `json:"confidence"`
+	}
+	h.check(h.getJSON("/api/clf", &clf), "GET /api/clf")
+	if clf.Skystate != "clear" || clf.Confidence <= 0 {
+		t.Fatalf("/api/clf: %+v", clf)
+	}
+
+	t.Log("--- grpc")
+	conn, err := grpc.NewClient(grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	h.check(err, "grpc dial")
+	defer conn.Close()
+	rpc := skyclfv1.NewSkyClfClient(conn)
+	predicted, err := rpc.Predict(ctx, &skyclfv1.PredictRequest{})
+	h.check(err, "grpc Predict latest")
+	if p := predicted.GetPrediction(); p.GetSkystate() != clf.Skystate || p.GetModelVersion() != "v1" || predicted.GetImage().GetId() == "" {
+		t.Fatalf("grpc Predict latest: %v, want %s from v1", predicted, clf.Skystate)
+	}
+	frame, err := os.ReadFile(list.Items[0].Path)
+	h.check(err, "read a frame")
+	uploaded, err := rpc.Predict(ctx, &skyclfv1.PredictRequest{Image: frame})
+	h.check(err, "grpc Predict upload")
+	if uploaded.GetPrediction().GetSkystate() == "" || uploaded.GetImage() != nil {
+		t.Fatalf("grpc Predict upload: %v", uploaded)
+	}
+	grpcLatest, err := rpc.GetLatest(ctx, &skyclfv1.GetLatestRequest{})
+	h.check(err, "grpc GetLatest")
+	if grpcLatest.GetImage().GetId() != predicted.GetImage().GetId() || grpcLatest.GetLabel().GetSkystate() == "" {
+		t.Fatalf("grpc GetLatest: %v", grpcLatest)
+	}
+	_, err = rpc.SetLabel(ctx, &skyclfv1.SetLabelRequest{ImageId: list.Items[1].ID, Skystate: "clear", Labeler: "e2e-grpc"})
+	h.check(err, "grpc SetLabel")
+	if _, err := rpc.SetLabel(ctx, &skyclfv1.SetLabelRequest{ImageId: list.Items[1].ID, Skystate: "sunny"}); grpcstatus.Code(err) != codes.InvalidArgument {
+		t.Fatalf("grpc SetLabel with an invalid skystate: %v, want InvalidArgument", err)
+	}
+	grpcStats, err := rpc.GetStats(ctx, &skyclfv1.GetStatsRequest{})
+	h.check(err, "grpc GetStats")
+	if grpcStats.GetTotal() != frames || grpcStats.GetByClass()["clear"] != 3 {
+		t.Fatalf("grpc GetStats: %v, want %d images, 3 clear", grpcStats, frames)
+	}
+
+	t.Log("--- latest timings")
+	var plain map[string]any
+	h.check(h.getJSON("/api/latest", &plain), "GET /api/latest")
+	if _, ok := plain["timings"]; ok {
+		t.Fatalf("/api/latest has timings without ?debug=1")
+	}
+	var dbg struct {
+		Timings *struct {
+			DBGetLatestMS float64 `json:"db_get_latest_ms"`
+			TotalMS       float64 `json:"total_ms"`
+			Cached        bool    `json:"cached"`
+			Source        string  `json:"source"`
+		} `json:"timings"`
+	}
+	h.check(h.getJSON("/api/latest?debug=1", &dbg), "GET /api/latest?debug=1")
+	if tm := dbg.Timings; tm == nil || tm.Source == "" || tm.TotalMS < tm.DBGetLatestMS || tm.Cached != (tm.Source != "inference") {
+		t.Fatalf("/api/latest?debug=1 timings: %+v", dbg.Timings)
+	}
+}
+
+// harness talks to the server under test and fails t on errors.
+type harness struct {
+	t      *testing.T
+	base   string // server URL
+	client *http.Client
+}
+
+// startServer runs bin in dir with env added to the environment. The server
+// is stopped when the test ends, and its log printed if the test failed.
+func (h *harness) startServer(bin, dir string, env ...string) {
+	t := h.t
+	logPath := filepath.Join(dir, "server.log")
+	logFile, err := os.Create(logPath)
+	h.check(err, "server log")
+
+	server := exec.Command(bin)
+	server.Dir = dir // no ui/dist here; the API is all that's needed
+	server.Env = append(os.Environ(), env...)
+	server.Stdout, server.Stderr = logFile, logFile
+	if err := server.Start(); err != nil {
+		logFile.Close()
+		t.Fatalf("start server: %v", err)
+	}
+	t.Cleanup(func() {
+		stopServer(server)
+		logFile.Close()
+		if t.Failed() {
+			if data, err := os.ReadFile(logPath); err == nil {
+				t.Logf("server log:\n%s", data)
+			}
+		}
+	})
+}
+
+// response is a completed request with its body read.
+type response struct {
+	path   string
+	status int
+	body   []byte
+	err    error
+}
+
+func (h *harness) do(method, path, body string) response {
+	req, err := http.NewRequest(method, h.base+path, strings.NewReader(body))
+	if err != nil {
+		return response{path: path, err: err}
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return response{path: path, err: err}
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return response{path: path, status: resp.StatusCode, body: data, err: err}
+}
+
+func (h *harness) get(path string) response        { return h.do(http.MethodGet, path, "") }
+func (h *harness) post(path, body string) response { return h.do(http.MethodPost, path, body) }
+
+func expectStatus(r response, want ...int) error {
+	if r.err != nil {
+		return r.err
+	}
+	if !slices.Contains(want, r.status) {
+		return fmt.Errorf("%s: status %d, want %v: %s", r.path, r.status, want, bytes.TrimSpace(r.body))
+	}
+	return nil
+}
+
+func (h *harness) getJSON(path string, v any) error {
+	r := h.get(path)
+	if err := expectStatus(r, http.StatusOK); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(r.body, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// waitFor retries cond until it succeeds, failing with its last error once ctx is done.
+func (h *harness) waitFor(ctx context.Context, what string, cond func() error) {
+	h.t.Helper()
+	for {
+		err := cond()
+		if err == nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			h.t.Fatalf("%s: %v", what, err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func (h *harness) check(err error, what string) {
+	h.t.Helper()
+	if err != nil {
+		h.t.Fatalf("%s: %v", what, err)
+	}
+}
+
+func stopServer(server *exec.Cmd) {
+	server.Process.Signal(os.Interrupt)
+	done := make(chan struct{})
+	go func() {
+		server.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		server.Process.Kill()
+		<-done
+	}
+}
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("find a free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
@@ -0,0 +1,322 @@
+//go:build integration
+
+package e2e
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/infer"
+)
+
+// inferBackend stands in for a remote SkyClf instance (SKYCLF_INFER_BACKEND=remote):
+// it serves no model until setActive and then classifies every image as "clear".
+type inferBackend struct {
+	srv *httptest.Server
+
+	mu          sync.Mutex
+	active      string
+	predictions int
+}
+
+func newInferBackend() *inferBackend {
+	b := &inferBackend{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })
+	mux.HandleFunc("GET /api/models", func(w http.ResponseWriter, r *http.Request) {
+		var active any
+		if v := b.version(); v != "" {
+			active = v
+		}
+		writeJSON(w, map[string]any{"active": active})
+	})
+	mux.HandleFunc("POST /api/predict", func(w http.ResponseWriter, r *http.Request) {
+		v := b.version()
+		if v == "" {
+			http.Error(w, "no model loaded", http.StatusServiceUnavailable)
+			return
+		}
+		b.mu.Lock()
+		b.predictions++
+		b.mu.Unlock()
+		writeJSON(w, infer.Prediction{
+			SkyState:   "clear",
+			Confidence: 0.9,
+			Probs:      map[string]float32{"clear": 0.9, "light_clouds": 0.1},
+			ModelTask:  "skystate",
+			ModelVer:   v,
+		})
+	})
+	b.srv = httptest.NewServer(mux)
+	return b
+}
+
+func (b *inferBackend) URL() string { return b.srv.URL }
+func (b *inferBackend) Close()      { b.srv.Close() }
+
+func (b *inferBackend) version() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.active
+}
+
+func (b *inferBackend) setActive(v string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.active = v
+}
+
+// dockerDaemon implements the part of the Docker Engine API the trainer uses.
+// A started job container "trains" for jobTime, writes a pending model
+// version into modelsDir the way the trainer image does, and exits 0.
+type dockerDaemon struct {
+	srv       *httptest.Server
+	trainer   string // wrapper container name
+	modelsDir string
+	version   string        // version the next job produces
+	jobTime   time.Duration // how long a job runs
+	onDone    func(version string)
+
+	mu   sync.Mutex
+	jobs map[string]*fakeJob // by id and by name
+	seq  int
+	cmds [][]string // Cmd of every created job container
+}
+
+type fakeJob struct {
+	id, name string
+	cmd      []string
+	running  bool
+	done     chan struct{}
+}
+
+// Paths are versioned (/v1.47/containers/...) once the client has negotiated.
+var apiVersionPrefix = regexp.MustCompile(`^/v[0-9.]+`)
+
+func newDockerDaemon(trainer, modelsDir string) *dockerDaemon {
+	d := &dockerDaemon{
+		trainer:   trainer,
+		modelsDir: modelsDir,
+		version:   "v1",
+		jobTime:   time.Second,
+		jobs:      map[string]*fakeJob{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/_ping", d.ping)
+	mux.HandleFunc("GET /containers/{ref}/json", d.inspect)
+	mux.HandleFunc("DELETE /containers/{ref}", d.remove)
+	mux.HandleFunc("POST /containers/create", d.create)
+	mux.HandleFunc("POST /containers/{ref}/start", d.start)
+	mux.HandleFunc("POST /containers/{ref}/stop", d.stop)
+	mux.HandleFunc("POST /containers/{ref}/wait", d.wait)
+	mux.HandleFunc("GET /containers/{ref}/logs", d.logs)
+	d.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.URL.Path = apiVersionPrefix.ReplaceAllString(r.URL.Path, "")
+		mux.ServeHTTP(w, r)
+	}))
+	return d
+}
+
+// Host returns the value for DOCKER_HOST.
+func (d *dockerDaemon) Host() string { return "tcp://" + strings.TrimPrefix(d.srv.URL, "http://") }
+func (d *dockerDaemon) Close()       { d.srv.Close() }
+
+// Commands returns the Cmd of every job container created so far.
+func (d *dockerDaemon) Commands() [][]string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([][]string(nil), d.cmds...)
+}
+
+func (d *dockerDaemon) job(ref string) *fakeJob {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.jobs[ref]
+}
+
+func (d *dockerDaemon) ping(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("API-Version", "1.43")
+	w.Write([]byte("OK"))
+}
+
+func (d *dockerDaemon) inspect(w http.ResponseWriter, r *http.Request) {
+	ref := r.PathValue("ref")
+	if ref == d.trainer {
+		writeJSON(w, map[string]any{
+			"Id":         "wrapper",
+			"Name":       "/" + d.trainer,
+			"State":      map[string]any{"Running": true, "Status": "running"},
+			"Config":     map[string]any{"Image": "skyclf-trainer", "Cmd": []string{"sleep", "infinity"}},
+			"HostConfig": map[string]any{},
+		})
+		return
+	}
+	j := d.job(ref)
+	if j == nil {
+		notFound(w, ref)
+		return
+	}
+	d.mu.Lock()
+	running := j.running
+	d.mu.Unlock()
+	status := "exited"
+	if running {
+		status = "running"
+	}
+	writeJSON(w, map[string]any{
+		"Id":         j.id,
+		"Name":       "/" + j.name,
+		"State":      map[string]any{"Running": running, "Status": status},
+		"Config":     map[string]any{"Image": "skyclf-trainer", "Cmd": j.cmd},
+		"HostConfig": map[string]any{},
+	})
+}
+
+func (d *dockerDaemon) remove(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	j := d.jobs[r.PathValue("ref")]
+	if j == nil {
+		notFound(w, r.PathValue("ref"))
+		return
+	}
+	delete(d.jobs, j.id)
+	delete(d.jobs, j.name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (d *dockerDaemon) create(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Cmd []string
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.seq++
+	j := &fakeJob{
+		id:   fmt.Sprintf("job%d", d.seq),
+		name: r.URL.Query().Get("name"),
+		cmd:  body.Cmd,
+		done: make(chan struct{}),
+	}
+	d.jobs[j.id] = j
+	d.jobs[j.name] = j
+	d.cmds = append(d.cmds, body.Cmd)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{"Id": j.id, "Warnings": []string{}})
+}
+
+func (d *dockerDaemon) start(w http.ResponseWriter, r *http.Request) {
+	j := d.job(r.PathValue("ref"))
+	if j == nil {
+		notFound(w, r.PathValue("ref"))
+		return
+	}
+	d.mu.Lock()
+	j.running = true
+	d.mu.Unlock()
+	go func() {
+		time.Sleep(d.jobTime)
+		err := d.writeModel()
+		d.mu.Lock()
+		j.running = false
+		d.mu.Unlock()
+		if err == nil && d.onDone != nil {
+			d.onDone(d.version)
+		}
+		close(j.done)
+	}()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (d *dockerDaemon) stop(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (d *dockerDaemon) wait(w http.ResponseWriter, r *http.Request) {
+	j := d.job(r.PathValue("ref"))
+	if j == nil {
+		notFound(w, r.PathValue("ref"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+	select {
+	case <-j.done:
+	case <-r.Context().Done():
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{"StatusCode": 0})
+}
+
+// logs answers in the multiplexed stdout/stderr format; with follow=1 the
+// stream stays open until the job has exited.
+func (d *dockerDaemon) logs(w http.ResponseWriter, r *http.Request) {
+	j := d.job(r.PathValue("ref"))
+	if j == nil {
+		notFound(w, r.PathValue("ref"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.docker.multiplexed-stream")
+	writeFrame(w, "epoch 1/1 loss=0.42 acc=0.91\n")
+	if r.URL.Query().Get("follow") == "1" {
+		w.(http.Flusher).Flush()
+		select {
+		case <-j.done:
+		case <-r.Context().Done():
+			return
+		}
+	}
+	writeFrame(w, "saved model "+d.version+"\n")
+}
+
+// writeModel publishes like the trainer image: files and metrics into
+// .pending-vN, then the DoneMarker.
+func (d *dockerDaemon) writeModel() error {
+	dir := filepath.Join(d.modelsDir, "skystate", infer.PendingPrefix+d.version)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "model.onnx"), []byte("not a real model"), 0o644); err != nil {
+		return err
+	}
+	metrics := `{"train_loss":[0.9,0.5],"train_accuracy":[0.6,0.85],"val_loss":[0.8,0.4],"val_accuracy":[0.65,0.9],` +
+		`"final":{"samples":10,"correct":9,"accuracy":0.9,"loss":0.4}}`
+	if err := os.WriteFile(filepath.Join(dir, infer.MetricsFile), []byte(metrics), 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, infer.DoneMarker), nil, 0o644)
+}
+
+func writeFrame(w http.ResponseWriter, line string) {
+	hdr := make([]byte, 8)
+	hdr[0] = 1 // stdout
+	binary.BigEndian.PutUint32(hdr[4:], uint32(len(line)))
+	w.Write(hdr)
+	w.Write([]byte(line))
+}
+
+func notFound(w http.ResponseWriter, ref string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(map[string]string{"message": "No such container: " + ref})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,183 @@
+// Package export builds training file lists from the labeled dataset.
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/imghash"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// Options selects which labeled images go into an export.
+type Options struct {
+	Filter store.ImageFilter
+
+	// Dedup keeps one representative per cluster of near-identical frames.
+	Dedup          bool
+	DedupThreshold int           // Hamming distance in bits
+	DedupWindow    time.Duration // max span of one cluster (<= 0 = unlimited)
+
+	// IncludeFITS also exports FITS images, which the trainer can't decode
+	// without extra tooling, and the other formats the model can't read
+	// (HEIC, unrecognized frames); they are left out by default.
+	IncludeFITS bool
+
+	// ExcludeUnreviewed leaves out images whose label awaits review.
+	ExcludeUnreviewed bool
+
+	// Weather joins the nearest weather reading to each image; pass it on
+	// to WriteCSV for the extra columns.
+	Weather bool
+}
+
+// Select returns the labeled images matching opts, oldest first.
+func Select(ctx context.Context, st *store.Store, opts Options) ([]store.ImageWithLabel, error) {
+	f := opts.Filter
+	f.LabeledOnly = true
+	f.UnlabeledOnly = false
+	f.ExcludeTruncated = true
+	f.ExcludeUnpredictable = !opts.IncludeFITS
+	f.ExcludeUnreviewed = opts.ExcludeUnreviewed
+	f.IncludeWeather = opts.Weather
+	if f.Snapshot != "" {
+		// Archived since the snapshot was taken, but still part of it
+		f.IncludeArchived = true
+	}
+
+	items, err := st.ListImagesFiltered(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	// ListImagesFiltered is newest first; exports read more naturally in time order
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+
+	if opts.Dedup {
+		items = Dedup(items, opts.DedupThreshold, opts.DedupWindow)
+	}
+	return items, nil
+}
+
+// Dedup drops all but the representative of each perceptual-hash cluster.
+// Images without a hash are always kept.
+func Dedup(items []store.ImageWithLabel, threshold int, window time.Duration) []store.ImageWithLabel {
+	var hashed []imghash.Item
+	for _, it := range items {
+		if h, err := imghash.Parse(it.PHash); err == nil {
+			hashed = append(hashed, imghash.Item{ID: it.ID, FetchedAt: it.FetchedAt, Hash: h})
+		}
+	}
+
+	drop := make(map[string]bool)
+	for _, c := range imghash.Group(hashed, threshold, window) {
+		for _, id := range c.Members[1:] {
+			drop[id] = true
+		}
+	}
+
+	out := items[:0]
+	for _, it := range items {
+		if !drop[it.ID] {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// WriteAnnotationsCSV writes the bounding boxes of the exported images, one row per
+// annotation: path,image_id,image_width,image_height,kind,x,y,w,h.
+// Annotations of images not in items are skipped.
+func WriteAnnotationsCSV(w io.Writer, items []store.ImageWithLabel, anns []store.Annotation) error {
+	byID := make(map[string]store.ImageWithLabel, len(items))
+	for _, it := range items {
+		byID[it.ID] = it
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"path", "image_id", "image_width", "image_height", "kind", "x", "y", "w", "h"}); err != nil {
+		return err
+	}
+	for _, a := range anns {
+		it, ok := byID[a.ImageID]
+		if !ok {
+			continue
+		}
+		if err := cw.Write([]string{
+			it.Path,
+			a.ImageID,
+			strconv.Itoa(it.Width),
+			strconv.Itoa(it.Height),
+			a.Kind,
+			strconv.Itoa(a.X),
+			strconv.Itoa(a.Y),
+			strconv.Itoa(a.W),
+			strconv.Itoa(a.H),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// weatherColumns are appended to the file list with weather.
+var weatherColumns = []string{"cloud_cover", "temperature", "humidity", "precipitation"}
+
+// WriteCSV writes items as a file list: path,sha256,skystate,meteor,fetched_at,split.
+// split is empty for images without an assigned split. With weather the
+// nearest reading follows as cloud_cover,temperature,humidity,precipitation,
+// empty for images without one.
+func WriteCSV(w io.Writer, items []store.ImageWithLabel, weather bool) error {
+	cw := csv.NewWriter(w)
+	header := []string{"path", "sha256", "skystate", "meteor", "fetched_at", "split"}
+	if weather {
+		header = append(header, weatherColumns...)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, it := range items {
+		var skystate string
+		var meteor bool
+		if it.Skystate != nil {
+			skystate = *it.Skystate
+		}
+		if it.Meteor != nil {
+			meteor = *it.Meteor
+		}
+		row := []string{
+			it.Path,
+			it.SHA256,
+			skystate,
+			strconv.FormatBool(meteor),
+			it.FetchedAt.UTC().Format(time.RFC3339),
+			it.Split,
+		}
+		if weather {
+			row = append(row, weatherRow(it.Weather)...)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// weatherRow formats r as the weatherColumns; all empty if r is nil.
+func weatherRow(r *store.WeatherReading) []string {
+	if r == nil {
+		return make([]string, len(weatherColumns))
+	}
+	return []string{
+		strconv.FormatFloat(r.CloudCover, 'f', -1, 64),
+		strconv.FormatFloat(r.Temperature, 'f', -1, 64),
+		strconv.FormatFloat(r.Humidity, 'f', -1, 64),
+		strconv.FormatFloat(r.Precipitation, 'f', -1, 64),
+	}
+}
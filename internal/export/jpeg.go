@@ -0,0 +1,70 @@
+package export
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+
+	"github.com/SkyClf/SkyClf/internal/store"
+	_ "golang.org/x/image/webp"
+)
+
+// jpegQuality is high enough that the copies don't lose detail the model
+// would have seen in the original.
+const jpegQuality = 95
+
+// ConvertWebP writes a JPEG copy of every WebP image in items to dir, named
+// after the image ID, and points the item's Path at the copy. Copies from an
+// earlier export are reused. It is for trainers that can't read WebP.
+func ConvertWebP(items []store.ImageWithLabel, dir string) error {
+	for i := range items {
+		if items[i].Format != store.FormatWebP {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("convert webp: %w", err)
+		}
+		dst := filepath.Join(dir, items[i].ID+".jpg")
+		if _, err := os.Stat(dst); err != nil {
+			if err := toJPEG(items[i].Path, dst); err != nil {
+				return fmt.Errorf("convert %s: %w", items[i].ID, err)
+			}
+		}
+		items[i].Path = dst
+	}
+	return nil
+}
+
+// toJPEG decodes src and writes it to dst as JPEG via a temp file, so an
+// interrupted conversion never leaves a partial copy to be reused.
+func toJPEG(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	img, _, err := image.Decode(in)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".convert-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := jpeg.Encode(tmp, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// CreateTemp makes it owner-only; the trainer may run as another user
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
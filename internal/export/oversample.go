@@ -0,0 +1,181 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// MaxRepeat is the repeat factor above which oversampling warns: a class that
+// rare needs more frames, not more copies of the few it has.
+const MaxRepeat = 10
+
+// maxMultiplier caps per-class multipliers.
+const maxMultiplier = 100
+
+// Oversample repeats train-split images of rare classes in a file list, so the
+// trainer sees them more often without changes on its side. Validation and
+// test images are never repeated.
+type Oversample struct {
+	// Multipliers is how often the images of a class appear (2 = twice).
+	// A fraction repeats that share of them once more, e.g. 2.5 lists half
+	// the images three times.
+	Multipliers map[string]float64 `json:"multipliers,omitempty"`
+
+	// Balance repeats every class below the median class count up to it;
+	// Multipliers must be empty.
+	Balance bool `json:"balance,omitempty"`
+
+	// Seed picks the images repeated once more for fractional factors.
+	Seed int64 `json:"seed"`
+}
+
+// Validate checks that o asks for exactly one mode with sane factors.
+func (o Oversample) Validate() error {
+	if o.Balance && len(o.Multipliers) > 0 {
+		return errors.New("oversample: use either multipliers or balance")
+	}
+	if !o.Balance && len(o.Multipliers) == 0 {
+		return errors.New("oversample: set multipliers or balance")
+	}
+	for c, m := range o.Multipliers {
+		if !store.ValidSkystate(c) {
+			return fmt.Errorf("oversample: unknown class %q", c)
+		}
+		if math.IsNaN(m) || m < 1 || m > maxMultiplier {
+			return fmt.Errorf("oversample: multiplier of %s must be between 1 and %d", c, maxMultiplier)
+		}
+	}
+	return nil
+}
+
+// ParseOversample reads an oversample option as given on the command line or
+// in a query: "balance", or per-class multipliers such as
+// "precipitation:4,light_clouds:1.5".
+func ParseOversample(spec string, seed int64) (*Oversample, error) {
+	o := &Oversample{Seed: seed}
+	if spec == "balance" {
+		o.Balance = true
+		return o, nil
+	}
+	o.Multipliers = map[string]float64{}
+	for _, part := range strings.Split(spec, ",") {
+		class, factor, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return nil, fmt.Errorf("oversample: %q is not class:multiplier", part)
+		}
+		m, err := strconv.ParseFloat(factor, 64)
+		if err != nil {
+			return nil, fmt.Errorf("oversample: multiplier of %s: %w", class, err)
+		}
+		o.Multipliers[class] = m
+	}
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// OversampleResult records what oversampling did, for the run history.
+type OversampleResult struct {
+	Oversample
+	Classes  map[string]OversampledClass `json:"classes"`
+	Warnings []string                    `json:"warnings,omitempty"`
+}
+
+// OversampledClass is the effect on the train-split images of one class.
+type OversampledClass struct {
+	Images int     `json:"images"` // before
+	Factor float64 `json:"factor"`
+	Rows   int     `json:"rows"` // file list rows after
+}
+
+// ApplyOversample returns items with the train-split images repeated as o
+// asks, each copy right after its original, and what was done. The same items
+// and seed always give the same list.
+func ApplyOversample(items []store.ImageWithLabel, o Oversample) ([]store.ImageWithLabel, OversampleResult) {
+	res := OversampleResult{Oversample: o, Classes: map[string]OversampledClass{}}
+
+	byClass := map[string][]int{} // indexes into items
+	for i, it := range items {
+		if it.Split == store.SplitTrain && it.Skystate != nil {
+			byClass[*it.Skystate] = append(byClass[*it.Skystate], i)
+		}
+	}
+	if len(byClass) == 0 {
+		res.Warnings = append(res.Warnings, "no images in the train split; nothing oversampled")
+		return items, res
+	}
+
+	factors := o.Multipliers
+	if o.Balance {
+		factors = balanceFactors(byClass)
+	}
+
+	copies := make([]int, len(items)) // extra rows per item
+	classes := make([]string, 0, len(byClass))
+	for c := range byClass {
+		classes = append(classes, c)
+	}
+	sort.Strings(classes)
+	for _, c := range classes {
+		idx := byClass[c]
+		factor := factors[c]
+		if factor < 1 {
+			factor = 1
+		}
+		rows := int(math.Round(float64(len(idx)) * factor))
+		extra := rows - len(idx)
+		for _, i := range idx {
+			copies[i] += extra / len(idx)
+		}
+		for _, j := range classRand(o.Seed, c).Perm(len(idx))[:extra%len(idx)] {
+			copies[idx[j]]++
+		}
+		res.Classes[c] = OversampledClass{Images: len(idx), Factor: factor, Rows: rows}
+		if factor > MaxRepeat {
+			res.Warnings = append(res.Warnings, fmt.Sprintf(
+				"%s needs a %.1fx repeat (%d train images); collect more frames of it", c, factor, len(idx)))
+		}
+	}
+
+	out := make([]store.ImageWithLabel, 0, len(items))
+	for i, it := range items {
+		for range 1 + copies[i] {
+			out = append(out, it)
+		}
+	}
+	return out, res
+}
+
+// balanceFactors returns the factor lifting each class to the median class
+// count (nearest rank); classes at or above it stay at 1.
+func balanceFactors(byClass map[string][]int) map[string]float64 {
+	counts := make([]int, 0, len(byClass))
+	for _, idx := range byClass {
+		counts = append(counts, len(idx))
+	}
+	sort.Ints(counts)
+	median := counts[(len(counts)+1)/2-1]
+
+	factors := make(map[string]float64, len(byClass))
+	for c, idx := range byClass {
+		factors[c] = max(1, float64(median)/float64(len(idx)))
+	}
+	return factors
+}
+
+// classRand is the random source picking a class's extra copies: fixed by
+// the seed, and independent of the other classes.
+func classRand(seed int64, class string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(class))
+	return rand.New(rand.NewPCG(uint64(seed), h.Sum64()))
+}
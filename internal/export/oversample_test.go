@@ -0,0 +1,179 @@
+package export
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// labeled returns n images of class in split, with IDs unique per call.
+func labeled(split, class string, n int) []store.ImageWithLabel {
+	out := make([]store.ImageWithLabel, n)
+	for i := range out {
+		c := class
+		out[i] = store.ImageWithLabel{ID: fmt.Sprintf("%s_%s_%03d", split, class, i), Split: split, Skystate: &c}
+	}
+	return out
+}
+
+// rowsByClass counts file list rows per split and class.
+func rowsByClass(items []store.ImageWithLabel) map[string]int {
+	out := map[string]int{}
+	for _, it := range items {
+		out[it.Split+"/"+*it.Skystate]++
+	}
+	return out
+}
+
+func TestApplyOversample(t *testing.T) {
+	items := slices.Concat(
+		labeled(store.SplitTrain, "clear", 40),
+		labeled(store.SplitTrain, "heavy_clouds", 20),
+		labeled(store.SplitTrain, "precipitation", 10),
+		labeled(store.SplitVal, "precipitation", 5),
+		labeled(store.SplitTest, "precipitation", 5),
+	)
+	tests := []struct {
+		name string
+		o    Oversample
+		want map[string]int
+	}{
+		{"whole factor", Oversample{Multipliers: map[string]float64{"precipitation": 4}}, map[string]int{
+			"train/clear": 40, "train/heavy_clouds": 20, "train/precipitation": 40, "val/precipitation": 5, "test/precipitation": 5}},
+		{"fractional factor", Oversample{Multipliers: map[string]float64{"precipitation": 2.5, "heavy_clouds": 1.25}}, map[string]int{
+			"train/clear": 40, "train/heavy_clouds": 25, "train/precipitation": 25, "val/precipitation": 5, "test/precipitation": 5}},
+		{"balance to the median", Oversample{Balance: true}, map[string]int{
+			"train/clear": 40, "train/heavy_clouds": 20, "train/precipitation": 20, "val/precipitation": 5, "test/precipitation": 5}},
+		{"class not in the list", Oversample{Multipliers: map[string]float64{"light_clouds": 3}}, map[string]int{
+			"train/clear": 40, "train/heavy_clouds": 20, "train/precipitation": 10, "val/precipitation": 5, "test/precipitation": 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, res := ApplyOversample(items, tt.o)
+			got := rowsByClass(out)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("rows %v, want %v", got, tt.want)
+			}
+			for class, c := range res.Classes {
+				if c.Rows != got[store.SplitTrain+"/"+class] {
+					t.Fatalf("result says %d %s rows, list has %d", c.Rows, class, got[store.SplitTrain+"/"+class])
+				}
+			}
+			// Copies follow their original; the order is otherwise kept
+			var ids []string
+			for i, it := range out {
+				if i == 0 || it.ID != out[i-1].ID {
+					ids = append(ids, it.ID)
+				}
+			}
+			if len(ids) != len(items) {
+				t.Fatalf("%d runs of copies for %d images", len(ids), len(items))
+			}
+			for i := range ids {
+				if ids[i] != items[i].ID {
+					t.Fatalf("image %d is %s, want %s", i, ids[i], items[i].ID)
+				}
+			}
+			if len(res.Warnings) != 0 {
+				t.Fatalf("warnings %v", res.Warnings)
+			}
+		})
+	}
+}
+
+func TestApplyOversampleWarnsAboveMaxRepeat(t *testing.T) {
+	items := slices.Concat(
+		labeled(store.SplitTrain, "clear", 30),
+		labeled(store.SplitTrain, "heavy_clouds", 30),
+		labeled(store.SplitTrain, "precipitation", 2),
+	)
+	tests := []struct {
+		name string
+		o    Oversample
+		warn bool
+	}{
+		{"at the limit", Oversample{Multipliers: map[string]float64{"precipitation": MaxRepeat}}, false},
+		{"above it", Oversample{Multipliers: map[string]float64{"precipitation": MaxRepeat + 2}}, true},
+		{"balance", Oversample{Balance: true}, true}, // 15x up to the median
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, res := ApplyOversample(items, tt.o)
+			if tt.warn != (len(res.Warnings) == 1) || (tt.warn && !strings.HasPrefix(res.Warnings[0], "precipitation needs")) {
+				t.Fatalf("warnings %q, want one: %t", res.Warnings, tt.warn)
+			}
+		})
+	}
+
+	_, res := ApplyOversample(labeled(store.SplitVal, "clear", 3), Oversample{Balance: true})
+	if len(res.Warnings) != 1 || len(res.Classes) != 0 {
+		t.Fatalf("no train split: warnings %q, classes %v", res.Warnings, res.Classes)
+	}
+}
+
+func TestApplyOversampleSeed(t *testing.T) {
+	items := slices.Concat(labeled(store.SplitTrain, "clear", 100), labeled(store.SplitTrain, "precipitation", 100))
+	o := Oversample{Multipliers: map[string]float64{"precipitation": 1.5, "clear": 1.1}, Seed: 7}
+	ids := func(o Oversample) string {
+		out, _ := ApplyOversample(items, o)
+		var b strings.Builder
+		for _, it := range out {
+			b.WriteString(it.ID + ",")
+		}
+		return b.String()
+	}
+
+	first := ids(o)
+	if again := ids(o); again != first {
+		t.Fatal("the same seed gave a different list")
+	}
+	other := o
+	other.Seed = 8
+	if ids(other) == first {
+		t.Fatal("another seed gave the same list")
+	}
+	// A class's picks don't depend on the others
+	alone := Oversample{Multipliers: map[string]float64{"precipitation": 1.5}, Seed: 7}
+	if !strings.HasSuffix(ids(alone), first[strings.Index(first, "train_precipitation"):]) {
+		t.Fatal("precipitation copies changed with the clear multiplier")
+	}
+}
+
+func TestParseOversample(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    map[string]float64
+		balance bool
+		wantErr bool
+	}{
+		{spec: "balance", balance: true},
+		{spec: "precipitation:4", want: map[string]float64{"precipitation": 4}},
+		{spec: "precipitation:4, light_clouds:1.5", want: map[string]float64{"precipitation": 4, "light_clouds": 1.5}},
+		{spec: "precipitation", wantErr: true},
+		{spec: "precipitation:x", wantErr: true},
+		{spec: "fog:2", wantErr: true},
+		{spec: "clear:0.5", wantErr: true},
+		{spec: "clear:101", wantErr: true},
+		{spec: "clear:NaN", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			o, err := ParseOversample(tt.spec, 1)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseOversample(%q) = %+v, want an error", tt.spec, o)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if o.Balance != tt.balance || fmt.Sprint(o.Multipliers) != fmt.Sprint(tt.want) || o.Seed != 1 {
+				t.Fatalf("ParseOversample(%q) = %+v", tt.spec, o)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package fetcher
+
+import (
+	"time"
+)
+
+// changeSmoothing weights a new observation in the moving average of the
+// interval between content changes.
+const changeSmoothing = 0.3
+
+// adaptive holds the state for stretching the poll interval toward the camera's
+// actual update rate. All fields are owned by the polling goroutine except
+// where noted.
+type adaptive struct {
+	enabled  bool
+	min, max time.Duration
+
+	dayInterval time.Duration // slower interval while the sun is up (0 = disabled)
+	lat, lon    float64
+
+	lastChange     time.Time
+	changeInterval time.Duration // smoothed interval between content changes
+}
+
+// SetAdaptivePolling stretches the poll interval toward half the observed interval
+// between content changes, bounded by min and max. Polling at half the update
+// period notices a faster camera again instead of locking onto a slow rate.
+func (f *Fetcher) SetAdaptivePolling(min, max time.Duration) {
+	f.adaptive.enabled = true
+	f.adaptive.min = min
+	f.adaptive.max = max
+}
+
+// SetDaytimeInterval polls no faster than interval while the sun is above the
+// horizon at the given site.
+func (f *Fetcher) SetDaytimeInterval(interval time.Duration, lat, lon float64) {
+	f.adaptive.dayInterval = interval
+	f.adaptive.lat = lat
+	f.adaptive.lon = lon
+}
+
+// observeChange records that a poll at now produced a new frame.
+func (f *Fetcher) observeChange(now time.Time) {
+	a := &f.adaptive
+	if !a.lastChange.IsZero() {
+		observed := now.Sub(a.lastChange)
+		if a.changeInterval == 0 {
+			a.changeInterval = observed
+		} else {
+			a.changeInterval = time.Duration(changeSmoothing*float64(observed) + (1-changeSmoothing)*float64(a.changeInterval))
+		}
+	}
+	a.lastChange = now
+}
+
+// nextInterval returns how long to wait before the next poll.
+func (f *Fetcher) nextInterval(now time.Time) time.Duration {
+	a := &f.adaptive
+	interval := f.pollInterval
+
+	if a.enabled && a.changeInterval > 0 {
+		interval = min(max(a.changeInterval/2, a.min), a.max)
+	}
+	if a.dayInterval > 0 && SunAltitude(now, a.lat, a.lon) > 0 {
+		interval = max(interval, a.dayInterval)
+	}
+
+	f.statusMu.Lock()
+	f.status.EffectiveInterval = interval.String()
+	if a.changeInterval > 0 {
+		f.status.ObservedChangeInterval = a.changeInterval.Round(time.Second).String()
+	}
+	f.statusMu.Unlock()
+	return interval
+}
+
+// PollNow triggers an immediate fetch regardless of the current interval.
+// Requests made while a poll is already pending are coalesced.
+func (f *Fetcher) PollNow() {
+	select {
+	case f.pollNow <- struct{}{}:
+	default:
+	}
+}
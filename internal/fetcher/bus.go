@@ -0,0 +1,143 @@
+package fetcher
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultSubscriberBuffer is the queue length of a subscriber added through
+// New: hours of frames at the usual poll intervals, so only a wedged
+// subscriber ever loses one.
+const DefaultSubscriberBuffer = 256
+
+// Bus fans new-image events out to any number of subscribers. Every
+// subscriber has its own queue and goroutine and receives events in publish
+// order. For an optional subscriber (Subscribe) Publish never blocks: when its
+// queue is full the event is dropped for it and counted, so a slow observer
+// cannot stall the fetch loop or the others. A required subscriber
+// (SubscribeRequired), such as ingestion, which must see every saved frame,
+// gets them all: Publish waits for room in its queue instead.
+type Bus struct {
+	mu     sync.RWMutex
+	subs   []*subscriber
+	closed bool
+	wg     sync.WaitGroup
+}
+
+type subscriber struct {
+	name      string
+	fn        OnNewImageFunc
+	required  bool
+	events    chan NewImageEvent
+	delivered atomic.Int64
+	dropped   atomic.Int64
+	waited    atomic.Int64
+}
+
+// SubscriberStats is the delivery record of one subscriber.
+type SubscriberStats struct {
+	Name      string `json:"name"`
+	Buffer    int    `json:"buffer"`
+	Queued    int    `json:"queued"`
+	Delivered int64  `json:"delivered"`
+	Dropped   int64  `json:"dropped"`
+	Required  bool   `json:"required,omitempty"`
+	Waited    int64  `json:"waited,omitempty"` // events Publish had to wait to queue (required only)
+}
+
+// NewBus creates a bus without subscribers.
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe calls fn for every event published from now on, one at a time,
+// keeping up to buffer (at least 1) events queued while fn runs; events
+// arriving while the queue is full are dropped for it. fn gets a context that
+// stays valid until Close has drained its queue.
+func (b *Bus) Subscribe(name string, buffer int, fn OnNewImageFunc) {
+	b.subscribe(&subscriber{name: name, fn: fn, events: make(chan NewImageEvent, max(buffer, 1))})
+}
+
+// SubscribeRequired is Subscribe for a subscriber that must not miss an
+// event: while its queue is full, Publish waits.
+func (b *Bus) SubscribeRequired(name string, buffer int, fn OnNewImageFunc) {
+	b.subscribe(&subscriber{name: name, fn: fn, required: true, events: make(chan NewImageEvent, max(buffer, 1))})
+}
+
+func (b *Bus) subscribe(s *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.subs = append(b.subs, s)
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for ev := range s.events {
+			s.fn(context.Background(), ev)
+			s.delivered.Add(1)
+		}
+	}()
+}
+
+// Publish queues ev for every subscriber, waiting only for required ones
+// whose queue is full. Events published after Close are discarded.
+func (b *Bus) Publish(ev NewImageEvent) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return
+	}
+	for _, s := range b.subs {
+		select {
+		case s.events <- ev:
+		default:
+			if s.required {
+				n := s.waited.Add(1)
+				log.Printf("fetcher: WARNING subscriber %s is %d events behind; waiting to queue %s (%d waits so far)",
+					s.name, cap(s.events), ev.Filename, n)
+				s.events <- ev
+				continue
+			}
+			n := s.dropped.Add(1)
+			log.Printf("fetcher: WARNING subscriber %s is %d events behind; dropped %s (%d dropped so far)",
+				s.name, cap(s.events), ev.Filename, n)
+		}
+	}
+}
+
+// Stats returns the delivery record of every subscriber, in subscription order.
+func (b *Bus) Stats() []SubscriberStats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	out := make([]SubscriberStats, 0, len(b.subs))
+	for _, s := range b.subs {
+		out = append(out, SubscriberStats{
+			Name:      s.name,
+			Buffer:    cap(s.events),
+			Queued:    len(s.events),
+			Delivered: s.delivered.Load(),
+			Dropped:   s.dropped.Load(),
+			Required:  s.required,
+			Waited:    s.waited.Load(),
+		})
+	}
+	return out
+}
+
+// Close stops accepting events and waits until every subscriber has handled
+// the ones already queued.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		for _, s := range b.subs {
+			close(s.events)
+		}
+	}
+	b.mu.Unlock()
+	b.wg.Wait()
+}
@@ -0,0 +1,139 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder collects the filenames of the events it handles.
+type recorder struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (r *recorder) handle(_ context.Context, ev NewImageEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names = append(r.names, ev.Filename)
+}
+
+func (r *recorder) got() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.names)
+}
+
+func events(n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("frame%03d.jpg", i)
+	}
+	return names
+}
+
+func TestBusFanoutOrder(t *testing.T) {
+	b := NewBus()
+	var a, c, req recorder
+	b.Subscribe("a", 8, a.handle)
+	b.Subscribe("c", 8, c.handle)
+	b.SubscribeRequired("req", 8, req.handle)
+
+	want := events(5)
+	for _, name := range want {
+		b.Publish(NewImageEvent{Filename: name})
+	}
+	b.Close()
+
+	for name, r := range map[string]*recorder{"a": &a, "c": &c, "req": &req} {
+		if got := r.got(); !slices.Equal(got, want) {
+			t.Errorf("subscriber %s got %v, want %v", name, got, want)
+		}
+	}
+	for _, st := range b.Stats() {
+		if st.Delivered != int64(len(want)) || st.Dropped != 0 {
+			t.Errorf("stats %+v", st)
+		}
+	}
+
+	// Closed: later events are discarded
+	b.Publish(NewImageEvent{Filename: "late.jpg"})
+	if got := a.got(); len(got) != len(want) {
+		t.Errorf("event published after Close delivered: %v", got)
+	}
+}
+
+func TestBusSlowSubscriber(t *testing.T) {
+	tests := []struct {
+		name        string
+		required    bool
+		wantDropped bool
+	}{
+		{"optional drops", false, true},
+		{"required waits", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBus()
+			release := make(chan struct{})
+			var slow, fast recorder
+			slowFn := func(ctx context.Context, ev NewImageEvent) {
+				<-release
+				slow.handle(ctx, ev)
+			}
+			if tt.required {
+				b.SubscribeRequired("slow", 2, slowFn)
+			} else {
+				b.Subscribe("slow", 2, slowFn)
+			}
+			b.Subscribe("fast", 16, fast.handle)
+
+			want := events(6)
+			published := make(chan struct{})
+			go func() {
+				defer close(published)
+				for _, name := range want {
+					b.Publish(NewImageEvent{Filename: name})
+				}
+			}()
+
+			select {
+			case <-published:
+				if tt.required {
+					t.Fatal("Publish didn't wait for the required subscriber")
+				}
+			case <-time.After(100 * time.Millisecond):
+				if !tt.required {
+					t.Fatal("Publish blocked on an optional subscriber")
+				}
+			}
+			close(release)
+			<-published
+			b.Close()
+
+			got := slow.got()
+			if tt.wantDropped {
+				// One in the handler, two queued; the rest are dropped
+				if len(got) >= len(want) || !slices.Equal(got, want[:len(got)]) {
+					t.Fatalf("slow subscriber got %v", got)
+				}
+			} else if !slices.Equal(got, want) {
+				t.Fatalf("required subscriber got %v, want %v", got, want)
+			}
+			if got := fast.got(); !slices.Equal(got, want) {
+				t.Fatalf("fast subscriber got %v, want all of %v", got, want)
+			}
+
+			st := b.Stats()[0]
+			if (st.Dropped > 0) != tt.wantDropped || st.Delivered+st.Dropped != int64(len(want)) {
+				t.Fatalf("slow stats %+v", st)
+			}
+			if tt.required && st.Waited == 0 {
+				t.Fatalf("slow stats %+v: no waits counted", st)
+			}
+		})
+	}
+}
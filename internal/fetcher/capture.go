@@ -0,0 +1,91 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const maxStderrTail = 2048
+
+// SetCapture configures ModeCapture: cmdTemplate is split on whitespace and
+// "{output}" / "{url}" are substituted per argument, e.g.
+//
+//	ffmpeg -y -rtsp_transport tcp -i {url} -frames:v 1 -q:v 2 {output}
+//
+// The process is killed after timeout.
+func (f *Fetcher) SetCapture(cmdTemplate string, timeout time.Duration) {
+	f.captureCmd = strings.Fields(cmdTemplate)
+	f.captureTimeout = timeout
+}
+
+// fetchCapture runs the capture command and feeds the produced frame into saveImage.
+func (f *Fetcher) fetchCapture(ctx context.Context) error {
+	if len(f.captureCmd) == 0 {
+		return &fetchError{kind: ErrKindCapture, err: errors.New("capture command not configured")}
+	}
+
+	tmpDir, err := os.MkdirTemp("", "skyclf-capture-*")
+	if err != nil {
+		return &fetchError{kind: ErrKindStorage, err: fmt.Errorf("create temp dir: %w", err)}
+	}
+	defer os.RemoveAll(tmpDir)
+	out := filepath.Join(tmpDir, "frame.jpg")
+
+	args := make([]string, len(f.captureCmd))
+	for i, a := range f.captureCmd {
+		a = strings.ReplaceAll(a, "{output}", out)
+		a = strings.ReplaceAll(a, "{url}", f.url)
+		args[i] = a
+	}
+
+	timeout := f.captureTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	cctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(cctx, args[0], args[1:]...)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if cctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %s", timeout)
+		}
+		return &fetchError{kind: ErrKindCapture, err: fmt.Errorf("capture %s: %w: %s", args[0], err, stderrTail(stderr.Bytes()))}
+	}
+
+	frame, err := os.Open(out)
+	if err != nil {
+		return &fetchError{kind: ErrKindCapture, err: fmt.Errorf("capture produced no frame: %w: %s", err, stderrTail(stderr.Bytes()))}
+	}
+	defer frame.Close()
+	if info, err := frame.Stat(); err == nil && info.Size() == 0 {
+		return &fetchError{kind: ErrKindCapture, err: fmt.Errorf("capture produced an empty frame: %s", stderrTail(stderr.Bytes()))}
+	}
+	fr, err := f.stage(frame)
+	if err != nil {
+		return err
+	}
+	return f.saveImage(ctx, fr, f.readSidecarFile(out), nil)
+}
+
+// stderrTail returns the last part of the process stderr, trimmed for log/error messages.
+func stderrTail(b []byte) string {
+	b = bytes.TrimSpace(b)
+	if len(b) > maxStderrTail {
+		b = b[len(b)-maxStderrTail:]
+	}
+	if len(b) == 0 {
+		return "(no stderr)"
+	}
+	return string(b)
+}
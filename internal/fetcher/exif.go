@@ -0,0 +1,146 @@
+package fetcher
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// exifScanLimit is how much of a JPEG is searched for the EXIF segment; it
+// comes right after SOI, so the first few kilobytes are enough.
+const exifScanLimit = 128 << 10
+
+// EXIF tags read for the capture time.
+const (
+	tagExifIFD          = 0x8769
+	tagDateTime         = 0x0132
+	tagDateTimeOriginal = 0x9003
+)
+
+// ExifTime returns the capture time recorded in the EXIF data of the JPEG at
+// path (DateTimeOriginal, else DateTime), or zero if there is none. EXIF times
+// carry no zone and are read as UTC; a camera clock set to local time shows
+// up as a whole-hour clock skew.
+func ExifTime(path string) time.Time {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, exifScanLimit))
+	if err != nil {
+		return time.Time{}
+	}
+	return exifTime(data)
+}
+
+func exifTime(jpeg []byte) time.Time {
+	// Walk the JPEG markers up to the first APP1 "Exif" segment
+	if len(jpeg) < 4 || jpeg[0] != 0xFF || jpeg[1] != 0xD8 {
+		return time.Time{}
+	}
+	for p := 2; p+4 <= len(jpeg); {
+		if jpeg[p] != 0xFF {
+			return time.Time{}
+		}
+		marker := jpeg[p+1]
+		if marker == 0xDA || marker == 0xD9 { // image data or end: no EXIF
+			return time.Time{}
+		}
+		n := int(binary.BigEndian.Uint16(jpeg[p+2:]))
+		if n < 2 || p+2+n > len(jpeg) {
+			return time.Time{}
+		}
+		seg := jpeg[p+4 : p+2+n]
+		if marker == 0xE1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			return tiffTime(seg[6:])
+		}
+		p += 2 + n
+	}
+	return time.Time{}
+}
+
+// tiffTime reads the capture time from the TIFF structure of an EXIF segment.
+func tiffTime(t []byte) time.Time {
+	if len(t) < 8 {
+		return time.Time{}
+	}
+	var bo binary.ByteOrder
+	switch string(t[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return time.Time{}
+	}
+	ifd0 := ifdEntries(t, bo, bo.Uint32(t[4:]))
+	if off, ok := ifd0[tagExifIFD]; ok {
+		if v := asciiAt(t, bo, ifdEntries(t, bo, bo.Uint32(off[8:]))[tagDateTimeOriginal]); v != "" {
+			return parseExifTime(v)
+		}
+	}
+	return parseExifTime(asciiAt(t, bo, ifd0[tagDateTime]))
+}
+
+// ifdEntries returns the 12-byte entries of the IFD at off by tag.
+func ifdEntries(t []byte, bo binary.ByteOrder, off uint32) map[uint16][]byte {
+	out := map[uint16][]byte{}
+	if int64(off)+2 > int64(len(t)) {
+		return out
+	}
+	n := int(bo.Uint16(t[off:]))
+	for i := 0; i < n; i++ {
+		at := int(off) + 2 + 12*i
+		if at+12 > len(t) {
+			break
+		}
+		out[bo.Uint16(t[at:])] = t[at : at+12]
+	}
+	return out
+}
+
+// asciiAt returns the ASCII value of an IFD entry stored at an offset, as the
+// 20-byte EXIF dates are.
+func asciiAt(t []byte, bo binary.ByteOrder, entry []byte) string {
+	if len(entry) != 12 || bo.Uint16(entry[2:]) != 2 { // type ASCII
+		return ""
+	}
+	count := bo.Uint32(entry[4:])
+	off := bo.Uint32(entry[8:])
+	if count <= 4 || int64(off)+int64(count) > int64(len(t)) {
+		return ""
+	}
+	return strings.TrimRight(string(t[off:off+count]), "\x00 ")
+}
+
+func parseExifTime(v string) time.Time {
+	t, err := time.Parse("2006:01:02 15:04:05", v)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// filenameTimeRe matches a timestamp in a frame's file name, e.g.
+// image-20250101221500.jpg or 2025-01-01_22-15-00.jpg.
+var filenameTimeRe = regexp.MustCompile(`(\d{4})-?(\d{2})-?(\d{2})[T_ -]?(\d{2})[-:]?(\d{2})[-:]?(\d{2})`)
+
+// FilenameTime returns the timestamp in name, read as UTC, or zero if it
+// has none.
+func FilenameTime(name string) time.Time {
+	m := filenameTimeRe.FindStringSubmatch(filepath.Base(name))
+	if m == nil {
+		return time.Time{}
+	}
+	t, err := time.Parse("20060102150405", strings.Join(m[1:], ""))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
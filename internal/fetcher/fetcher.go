@@ -2,19 +2,26 @@ package fetcher
 
 import (
 	"context"
-	"crypto/sha256"
+	"errors"
 	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
 	"io"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
+	"github.com/SkyClf/SkyClf/internal/imghash"
+	"github.com/SkyClf/SkyClf/internal/storage"
 	"github.com/SkyClf/SkyClf/internal/store"
+	_ "golang.org/x/image/webp"
 )
 
-type OnNewImageFunc func(ev NewImageEvent)
+type OnNewImageFunc func(ctx context.Context, ev NewImageEvent)
 
 // OnCleanupFunc is called after auto-cleanup
 type OnCleanupFunc func(result store.CleanupResult)
@@ -23,8 +30,18 @@ type NewImageEvent struct {
 	Filename  string
 	Path      string
 	SHA256Hex string
+	Format    string // store.FormatJPEG, FormatPNG, ...
 	FetchedAt time.Time
 	SizeBytes int
+	Width     int               // 0 if the header couldn't be decoded
+	Height    int               // 0 if the header couldn't be decoded
+	PHash     string            // perceptual hash, empty if disabled or undecodable
+	Meta      map[string]string // parsed sidecar metadata, nil if none
+
+	CapturedAt time.Time // when the camera took the frame (Last-Modified), zero if unknown
+	Stale      bool      // older than the latest frame; stored, but never the latest image
+
+	Provenance *store.Provenance // HTTP fetch details, nil for capture mode
 }
 
 // Fetcher periodically downloads images from an AllSky camera URL.
@@ -34,20 +51,71 @@ type Fetcher struct {
 	pollInterval   time.Duration
 	client         *http.Client
 	lastHash       [32]byte // Hash of last saved image to avoid duplicates
-	onNewImage     OnNewImageFunc
+	events         *Bus
 	store          *store.Store
 	maxUnlabeled   int // Auto-cleanup threshold (0 = disabled)
 	onCleanup      OnCleanupFunc
+
+	mode         Mode
+	location     *time.Location // timezone for template placeholders
+	lastIndexRef string         // newest file name seen in index mode
+
+	captureCmd     []string // argv template for ModeCapture
+	captureTimeout time.Duration
+	sidecarSuffix  string   // e.g. ".json"; empty = no sidecar ingestion
+	formats        []string // accepted image formats; empty = all
+
+	lastWidth, lastHeight int  // resolution of the last saved frame
+	perceptualHash        bool // compute a dHash for every saved frame
+
+	adaptive adaptive
+	pollNow  chan struct{}
+
+	staleSkew     time.Duration // 0 = frames are never stale
+	newestCapture time.Time     // newest capture time of a non-stale frame
+	staleRun      int           // different stale frames in a row
+
+	storage *storage.Monitor // nil = always write
+
+	transform StoreTransform // re-encoding of JPEG frames; zero = store as fetched
+
+	stateStore   *store.Store // nil = State is not persisted
+	savedState   State        // as last written
+	savedStateAt time.Time
+
+	attempted     chan struct{} // closed after the first fetch attempt
+	attemptedOnce sync.Once
+
+	statusMu sync.Mutex
+	status   Status
 }
 
-// New creates a new Fetcher.
+// New creates a new Fetcher calling onNewImage (if not nil) for every saved
+// frame, as the only subscriber of its own bus.
 func New(url, imagesDir string, pollInterval time.Duration, onNewImage OnNewImageFunc) *Fetcher {
+	bus := NewBus()
+	if onNewImage != nil {
+		bus.SubscribeRequired("default", DefaultSubscriberBuffer, onNewImage)
+	}
+	return NewWithBus(url, imagesDir, pollInterval, bus)
+}
+
+// NewWithBus creates a new Fetcher publishing every saved frame to bus; nil
+// gives it a bus of its own (see Events).
+func NewWithBus(url, imagesDir string, pollInterval time.Duration, bus *Bus) *Fetcher {
+	if bus == nil {
+		bus = NewBus()
+	}
 	return &Fetcher{
 		url:          url,
 		imagesDir:    imagesDir,
 		pollInterval: pollInterval,
-		onNewImage:   onNewImage,
+		events:       bus,
+		mode:         ModeStatic,
+		location:     time.UTC,
 		maxUnlabeled: 0, // disabled by default
+		pollNow:      make(chan struct{}, 1),
+		attempted:    make(chan struct{}),
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
@@ -61,96 +129,310 @@ func (f *Fetcher) SetAutoCleanup(st *store.Store, maxUnlabeled int, onCleanup On
 	f.onCleanup = onCleanup
 }
 
+// SetMode selects how the URL is interpreted (static, template or index).
+// loc is used to evaluate template placeholders; nil means UTC.
+func (f *Fetcher) SetMode(mode Mode, loc *time.Location) {
+	f.mode = mode
+	if loc != nil {
+		f.location = loc
+	}
+}
+
+// SetPerceptualHash enables computing a perceptual hash (dHash) for every saved frame.
+// This decodes the full image, so it is off by default.
+func (f *Fetcher) SetPerceptualHash(enabled bool) {
+	f.perceptualHash = enabled
+}
+
+// SetStorageMonitor pauses ingestion (and auto-cleanup) while mon reports the
+// images directory as unavailable.
+func (f *Fetcher) SetStorageMonitor(mon *storage.Monitor) {
+	f.storage = mon
+}
+
 // Start begins the polling loop. It blocks until the context is canceled.
 func (f *Fetcher) Start(ctx context.Context) error {
-	// Ensure images directory exists
+	// Ensure images directory exists; with a storage monitor a missing mount
+	// only pauses ingestion until it comes back.
 	if err := os.MkdirAll(f.imagesDir, 0755); err != nil {
-		return fmt.Errorf("create images dir: %w", err)
+		if f.storage == nil {
+			return fmt.Errorf("create images dir: %w", err)
+		}
+		log.Printf("fetcher: create images dir: %v", err)
 	}
+	f.removeStaleTemp()
+	f.loadNewestCapture(ctx)
+	f.restoreState(ctx)
 
 	// Fetch immediately on start
-	if err := f.fetchAndSave(); err != nil {
+	if err := f.poll(ctx); err != nil {
 		log.Printf("fetcher: initial fetch failed: %v", err)
 	}
+	f.saveState(ctx, false)
 
-	ticker := time.NewTicker(f.pollInterval)
-	defer ticker.Stop()
+	timer := time.NewTimer(f.nextInterval(time.Now()))
+	defer timer.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			log.Println("fetcher: stopping")
+			saveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			f.saveState(saveCtx, true)
+			cancel()
 			return ctx.Err()
-		case <-ticker.C:
-			if err := f.fetchAndSave(); err != nil {
-				log.Printf("fetcher: %v", err)
-			}
+		case <-f.pollNow:
+			timer.Stop() // Go 1.23+ timers deliver no stale value after Stop
+		case <-timer.C:
+		}
+		if err := f.poll(ctx); err != nil {
+			log.Printf("fetcher: %v", err)
+		}
+		f.saveState(ctx, false)
+		timer.Reset(f.nextInterval(time.Now()))
+	}
+}
+
+// poll runs one fetch and records the outcome in the status.
+func (f *Fetcher) poll(ctx context.Context) error {
+	// Paused while the images directory is unavailable; the monitor logs the outage
+	if err := f.storage.Check(); err != nil {
+		f.recordResult(&fetchError{kind: ErrKindStorage, err: err})
+		return nil
+	}
+
+	savedBefore := f.Status().Saved
+	err := f.fetchAndSave(ctx)
+	f.recordResult(err)
+	if f.Status().Saved > savedBefore {
+		f.observeChange(time.Now())
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", errorKind(err), err)
+	}
+	return nil
+}
+
+// fetchAndSave polls the source according to the configured mode.
+func (f *Fetcher) fetchAndSave(ctx context.Context) error {
+	switch f.mode {
+	case ModeCapture:
+		return f.fetchCapture(ctx)
+	case ModeTemplate:
+		return f.fetchTemplate(ctx)
+	case ModeIndex:
+		return f.fetchIndex(ctx)
+	default:
+		fr, prov, err := f.downloadFrame(ctx, f.url)
+		if err != nil {
+			return err
 		}
+		return f.saveImage(ctx, fr, f.fetchSidecar(ctx, f.url), prov)
 	}
 }
 
-// fetchAndSave downloads the image and saves it to disk only if it changed.
-func (f *Fetcher) fetchAndSave() error {
-	resp, err := f.client.Get(f.url)
+// download GETs url and returns the body with its fetch provenance; frames
+// go through downloadFrame instead. A body cut short of its Content-Length is
+// returned rather than dropped.
+func (f *Fetcher) download(ctx context.Context, url string) ([]byte, *store.Provenance, error) {
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetch %s: %w", url, err)
+	}
+	resp, err := f.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("fetch %s: %w", f.url, err)
+		return nil, nil, fmt.Errorf("fetch %s: %w", url, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("fetch %s: status %d", f.url, resp.StatusCode)
+		return nil, nil, &statusError{url: url, code: resp.StatusCode}
 	}
 
 	// Read entire image into memory to compute hash
 	data, err := io.ReadAll(resp.Body)
+	if err != nil && !(errors.Is(err, io.ErrUnexpectedEOF) && len(data) > 0) {
+		return nil, nil, fmt.Errorf("read response: %w", err)
+	}
 	if err != nil {
-		return fmt.Errorf("read response: %w", err)
+		log.Printf("fetcher: %s: body truncated after %d of %d bytes", url, len(data), resp.ContentLength)
+	}
+
+	prov := &store.Provenance{
+		HTTPStatus:    resp.StatusCode,
+		ContentLength: resp.ContentLength,
+		FetchMS:       float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if t, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		t = t.UTC()
+		prov.ServerDate = &t
+	}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		t = t.UTC()
+		prov.LastModified = &t
 	}
+	return data, prov, nil
+}
 
+// saveImage renames the staged frame into place if it differs from the last
+// saved image, and drops it otherwise. The NewImageEvent only fires once the
+// complete file is visible under its final name. meta and prov (nil when not
+// fetched over HTTP) are passed through to the NewImageEvent.
+func (f *Fetcher) saveImage(ctx context.Context, fr *stagedFrame, meta map[string]string, prov *store.Provenance) error {
 	// Check if image changed
-	hash := sha256.Sum256(data)
+	hash := fr.hash
 	if hash == f.lastHash {
 		// keep quiet-ish if you want, but leaving log is fine
 		log.Printf("fetcher: image unchanged, skipping")
+		fr.discard()
+		f.recordSave(false)
 		return nil
 	}
-	f.lastHash = hash
+
+	if !f.acceptFormat(fr.format) {
+		fr.discard()
+		if fr.format == "" {
+			return &fetchError{kind: ErrKindFormat, err: fmt.Errorf("not an image (starts with % x)", fr.head)}
+		}
+		return &fetchError{kind: ErrKindFormat, err: fmt.Errorf("%s frames are disabled (SKYCLF_IMAGE_FORMATS)", fr.format)}
+	}
 
 	fetchedAt := time.Now().UTC()
+	var capturedAt time.Time
+	if prov != nil && prov.LastModified != nil {
+		capturedAt = *prov.LastModified
+	}
+
+	// Re-encode for storage. The original's hash stays lastHash, so
+	// duplicates are still recognized by what the camera sends
+	if f.transform.enabled() && fr.format == store.FormatJPEG {
+		if capturedAt.IsZero() {
+			capturedAt = ExifTime(fr.tmp) // EXIF doesn't survive re-encoding
+		}
+		orig, err := f.transformFrame(fr)
+		if err != nil {
+			log.Printf("fetcher: re-encode frame, storing it as fetched: %v", err)
+		} else if orig != nil {
+			if prov == nil {
+				prov = &store.Provenance{ContentLength: -1, BytesWritten: orig.Bytes}
+			}
+			prov.Original = orig
+		}
+	}
 
-	// Generate filename with timestamp
+	// Generate filename with timestamp; index mode can save several frames
+	// within the same second, so add a counter suffix on collision. The name
+	// without extension is the image ID, so it must be unique across formats.
 	ts := fetchedAt.Format("20060102_150405")
-	filename := fmt.Sprintf("%s.jpg", ts)
+	ext := formatExt[fr.format]
+	stem := ts
+	for i := 1; f.stemTaken(stem); i++ {
+		stem = fmt.Sprintf("%s_%d", ts, i)
+	}
+	filename := stem + ext
 	fpath := filepath.Join(f.imagesDir, filename)
 
-	// Write file
-	if err := os.WriteFile(fpath, data, 0644); err != nil {
-		return fmt.Errorf("write file %s: %w", fpath, err)
+	// Move into place; a failed rename is retried with the next identical frame
+	if err := fr.commit(fpath); err != nil {
+		fr.discard()
+		return &fetchError{kind: ErrKindStorage, err: fmt.Errorf("write file %s: %w", fpath, err)}
 	}
+	f.lastHash = hash
+	f.recordSave(true)
 
-	log.Printf("fetcher: saved %s (%d bytes)", filename, len(data))
+	// Only the image header is parsed, not the whole image; FITS, HEIC and
+	// unrecognized frames are stored as-is and never decoded.
+	width, height := 0, 0
+	decodable := store.Predictable(fr.format)
+	if decodable {
+		if cfg, err := decodeConfigFile(fpath); err != nil {
+			log.Printf("fetcher: decode header of %s: %v", filename, err)
+		} else {
+			width, height = cfg.Width, cfg.Height
+			f.checkResolution(width, height)
+		}
+	}
 
-	if f.onNewImage != nil {
-		f.onNewImage(NewImageEvent{
-			Filename:  filename,
-			Path:      fpath,
-			SHA256Hex: fmt.Sprintf("%x", hash[:]),
-			FetchedAt: fetchedAt,
-			SizeBytes: len(data),
-		})
+	var phash string
+	if f.perceptualHash && decodable {
+		if data, err := os.ReadFile(fpath); err != nil {
+			log.Printf("fetcher: perceptual hash of %s: %v", filename, err)
+		} else if h, err := imghash.DHashBytes(data); err != nil {
+			log.Printf("fetcher: perceptual hash of %s: %v", filename, err)
+		} else {
+			phash = imghash.Format(h)
+		}
 	}
 
+	log.Printf("fetcher: saved %s (%d bytes, %dx%d)", filename, fr.size, width, height)
+	stale := f.checkStale(filename, capturedAt)
+
+	f.events.Publish(NewImageEvent{
+		Filename:  filename,
+		Path:      fpath,
+		SHA256Hex: fmt.Sprintf("%x", fr.hash[:]), // of the stored bytes
+		Format:    fr.format,
+		FetchedAt: fetchedAt,
+		SizeBytes: int(fr.size),
+		Width:     width,
+		Height:    height,
+		PHash:     phash,
+		Meta:      meta,
+
+		CapturedAt: capturedAt,
+		Stale:      stale,
+		Provenance: prov,
+	})
+
 	// Auto-cleanup if enabled and store is set
 	if f.store != nil && f.maxUnlabeled > 0 {
-		f.runAutoCleanup()
+		f.runAutoCleanup(ctx)
 	}
 
 	return nil
 }
 
+// checkResolution warns when the camera resolution differs from the previous frame,
+// e.g. after a sensor swap, since mixed resolutions silently skew training.
+func (f *Fetcher) checkResolution(width, height int) {
+	if f.lastWidth != 0 && (width != f.lastWidth || height != f.lastHeight) {
+		log.Printf("fetcher: WARNING camera resolution changed from %dx%d to %dx%d",
+			f.lastWidth, f.lastHeight, width, height)
+		f.statusMu.Lock()
+		f.status.ResolutionChangedAt = time.Now().UTC()
+		f.statusMu.Unlock()
+	}
+	f.lastWidth, f.lastHeight = width, height
+	f.statusMu.Lock()
+	f.status.Resolution = store.FormatResolution(width, height)
+	f.statusMu.Unlock()
+}
+
+// decodeConfigFile parses only the image header of the file at path.
+func decodeConfigFile(path string) (image.Config, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return image.Config{}, err
+	}
+	defer fh.Close()
+	cfg, _, err := image.DecodeConfig(fh)
+	return cfg, err
+}
+
+// stemTaken reports whether a frame named stem exists in any image format.
+func (f *Fetcher) stemTaken(stem string) bool {
+	for _, ext := range formatExt {
+		if _, err := os.Stat(filepath.Join(f.imagesDir, stem+ext)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
 // runAutoCleanup removes oldest unlabeled images to keep count under threshold
-func (f *Fetcher) runAutoCleanup() {
-	result, err := f.store.DeleteOldestUnlabeled(f.maxUnlabeled)
+func (f *Fetcher) runAutoCleanup(ctx context.Context) {
+	result, err := f.store.DeleteOldestUnlabeled(ctx, f.maxUnlabeled)
 	if err != nil {
 		log.Printf("fetcher: auto-cleanup error: %v", err)
 		return
@@ -182,7 +464,7 @@ func (f *Fetcher) LatestImage() (string, error) {
 
 	var latest string
 	for _, e := range entries {
-		if !e.IsDir() && filepath.Ext(e.Name()) == ".jpg" {
+		if !e.IsDir() && IsImageFile(e.Name()) {
 			// Files are named with timestamps, so lexicographic sort works
 			if e.Name() > latest {
 				latest = e.Name()
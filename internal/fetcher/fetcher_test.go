@@ -0,0 +1,250 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/fetcher/testutil"
+)
+
+// newTestFetcher returns a fetcher for cam storing into a temp directory,
+// and the channel its new-image events arrive on.
+func newTestFetcher(t *testing.T, cam *testutil.Camera) (*Fetcher, <-chan NewImageEvent) {
+	t.Helper()
+	evs := make(chan NewImageEvent, 16)
+	f := New(cam.URL(), t.TempDir(), time.Hour, func(_ context.Context, ev NewImageEvent) {
+		evs <- ev
+	})
+	t.Cleanup(f.events.Close)
+	return f, evs
+}
+
+func nextEvent(t *testing.T, evs <-chan NewImageEvent) NewImageEvent {
+	t.Helper()
+	select {
+	case ev := <-evs:
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("no new-image event")
+		return NewImageEvent{}
+	}
+}
+
+func noEvent(t *testing.T, evs <-chan NewImageEvent) {
+	t.Helper()
+	select {
+	case ev := <-evs:
+		t.Fatalf("unexpected event for %s", ev.Filename)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// dirFiles lists the names in dir, failing the test on a temp file left
+// behind.
+func dirFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		if filepath.Ext(e.Name()) == tempSuffix {
+			t.Errorf("temp file %s left behind", e.Name())
+		}
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestFetcherSavesNewFrame(t *testing.T) {
+	cam := testutil.NewCamera()
+	defer cam.Close()
+	f, evs := newTestFetcher(t, cam)
+
+	if err := f.poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ev := nextEvent(t, evs)
+	frame := cam.Frame()
+	if want := fmt.Sprintf("%x", sha256.Sum256(frame)); ev.SHA256Hex != want {
+		t.Fatalf("SHA256Hex = %s, want %s", ev.SHA256Hex, want)
+	}
+	data, err := os.ReadFile(ev.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, frame) {
+		t.Fatalf("stored %d bytes, camera served %d", len(data), len(frame))
+	}
+	if ev.Width != 64 || ev.Height != 48 || ev.Provenance == nil || ev.Provenance.Truncated() {
+		t.Fatalf("event %+v", ev)
+	}
+	if names := dirFiles(t, f.imagesDir); len(names) != 1 || names[0] != ev.Filename {
+		t.Fatalf("images dir holds %v, want [%s]", names, ev.Filename)
+	}
+}
+
+func TestFetcherSkipsDuplicate(t *testing.T) {
+	cam := testutil.NewCamera()
+	defer cam.Close()
+	f, evs := newTestFetcher(t, cam)
+	ctx := context.Background()
+
+	if err := f.poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	nextEvent(t, evs)
+	if err := f.poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	noEvent(t, evs)
+	if names := dirFiles(t, f.imagesDir); len(names) != 1 {
+		t.Fatalf("duplicate stored: %v", names)
+	}
+	if st := f.Status(); st.Saved != 1 || st.Unchanged != 1 {
+		t.Fatalf("saved %d, unchanged %d; want 1, 1", st.Saved, st.Unchanged)
+	}
+
+	cam.Advance()
+	if err := f.poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ev := nextEvent(t, evs); ev.SHA256Hex != fmt.Sprintf("%x", sha256.Sum256(cam.Frame())) {
+		t.Fatalf("new frame stored as %s", ev.SHA256Hex)
+	}
+}
+
+func TestFetcherRecoversAfterFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int // 0 drops the connection
+		kind   string
+	}{
+		{"server error", http.StatusInternalServerError, ErrKindHTTP},
+		{"unavailable", http.StatusServiceUnavailable, ErrKindHTTP},
+		{"connection dropped", 0, ErrKindNetwork},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cam := testutil.NewCamera()
+			defer cam.Close()
+			f, evs := newTestFetcher(t, cam)
+			ctx := context.Background()
+
+			cam.FailNext(2, tt.status)
+			for i := 1; i <= 2; i++ {
+				if err := f.poll(ctx); err == nil {
+					t.Fatalf("poll %d succeeded against a failing camera", i)
+				}
+				if st := f.Status(); st.ConsecutiveFailures != i || st.LastErrorKind != tt.kind {
+					t.Fatalf("after poll %d: failures %d, kind %q; want %d, %q",
+						i, st.ConsecutiveFailures, st.LastErrorKind, i, tt.kind)
+				}
+			}
+			noEvent(t, evs)
+			if names := dirFiles(t, f.imagesDir); len(names) != 0 {
+				t.Fatalf("failed fetch stored %v", names)
+			}
+
+			// The next poll retries and resets the failure count
+			if err := f.poll(ctx); err != nil {
+				t.Fatal(err)
+			}
+			nextEvent(t, evs)
+			if st := f.Status(); st.ConsecutiveFailures != 0 || st.Saved != 1 {
+				t.Fatalf("after recovery: failures %d, saved %d", st.ConsecutiveFailures, st.Saved)
+			}
+			if got := cam.Requests(); got != 3 {
+				t.Fatalf("camera saw %d requests, want 3", got)
+			}
+		})
+	}
+}
+
+func TestFetcherCanceledMidRequest(t *testing.T) {
+	cam := testutil.NewCamera()
+	defer cam.Close()
+	cam.SetDelay(time.Second)
+	f, evs := newTestFetcher(t, cam)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := f.poll(ctx); err == nil {
+		t.Fatal("canceled poll succeeded")
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Fatalf("poll took %v after its context ended", d)
+	}
+	noEvent(t, evs)
+	if names := dirFiles(t, f.imagesDir); len(names) != 0 {
+		t.Fatalf("canceled fetch left %v", names)
+	}
+}
+
+func TestFetcherTruncatedFrame(t *testing.T) {
+	cam := testutil.NewCamera()
+	defer cam.Close()
+	f, evs := newTestFetcher(t, cam)
+	ctx := context.Background()
+	frame := cam.Frame()
+
+	// A body cut short of its Content-Length is dropped like a failed fetch:
+	// no file, no event, so nothing reaches the DB
+	cam.TruncateNext(1)
+	if err := f.poll(ctx); err == nil {
+		t.Fatal("truncated fetch succeeded")
+	}
+	noEvent(t, evs)
+	if names := dirFiles(t, f.imagesDir); len(names) != 0 {
+		t.Fatalf("truncated fetch stored %v", names)
+	}
+	if st := f.Status(); st.ConsecutiveFailures != 1 || st.Saved != 0 {
+		t.Fatalf("failures %d, saved %d; want 1, 0", st.ConsecutiveFailures, st.Saved)
+	}
+
+	// The complete frame that follows is saved
+	if err := f.poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	ev := nextEvent(t, evs)
+	if ev.Provenance.Truncated() || ev.SHA256Hex != fmt.Sprintf("%x", sha256.Sum256(frame)) {
+		t.Fatalf("complete frame after truncation: %+v", ev)
+	}
+	if names := dirFiles(t, f.imagesDir); len(names) != 1 {
+		t.Fatalf("images dir holds %v, want the complete frame", names)
+	}
+}
+
+func TestStartRemovesStaleTemp(t *testing.T) {
+	cam := testutil.NewCamera()
+	defer cam.Close()
+	f, evs := newTestFetcher(t, cam)
+	// Left by a crash mid-write
+	stale := filepath.Join(f.imagesDir, "incoming-123"+tempSuffix)
+	if err := os.WriteFile(stale, cam.Frame()[:10], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- f.Start(ctx) }()
+	nextEvent(t, evs)
+	cancel()
+	<-done
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Fatalf("stale temp file still there: %v", err)
+	}
+	if names := dirFiles(t, f.imagesDir); len(names) == 0 {
+		t.Fatal("initial fetch stored nothing")
+	}
+}
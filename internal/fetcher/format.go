@@ -0,0 +1,131 @@
+package fetcher
+
+import (
+	"bytes"
+	"mime"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// sniffLen is how many leading bytes of a frame are kept for format detection.
+const sniffLen = 16
+
+var (
+	magicJPEG = []byte{0xFF, 0xD8, 0xFF}
+	magicPNG  = []byte("\x89PNG\r\n\x1a\n")
+	magicFITS = []byte("SIMPLE  =") // first header card of every FITS file
+)
+
+// heicBrands are the ISOBMFF major brands of HEIC/HEIF stills.
+var heicBrands = []string{"heic", "heix", "hevc", "hevx", "heim", "heis", "mif1", "msf1"}
+
+// formatExt is the extension frames of each format are saved with.
+var formatExt = map[string]string{
+	store.FormatJPEG:    ".jpg",
+	store.FormatPNG:     ".png",
+	store.FormatWebP:    ".webp",
+	store.FormatFITS:    ".fits",
+	store.FormatHEIC:    ".heic",
+	store.FormatUnknown: ".bin",
+}
+
+// extFormat maps file extensions (lower case) to formats, including the
+// spellings cameras and index listings use besides ours.
+var extFormat = map[string]string{
+	".jpg":  store.FormatJPEG,
+	".jpeg": store.FormatJPEG,
+	".png":  store.FormatPNG,
+	".webp": store.FormatWebP,
+	".fits": store.FormatFITS,
+	".fit":  store.FormatFITS,
+	".fts":  store.FormatFITS,
+	".heic": store.FormatHEIC,
+	".heif": store.FormatHEIC,
+	".bin":  store.FormatUnknown,
+}
+
+func init() {
+	// Not all in Go's builtin table (and a system mime.types may disagree);
+	// lets http.ServeFile send the right Content-Type
+	for _, ext := range []string{".fits", ".fit", ".fts"} {
+		_ = mime.AddExtensionType(ext, "image/fits")
+	}
+	_ = mime.AddExtensionType(".webp", "image/webp")
+	_ = mime.AddExtensionType(".heic", "image/heic")
+	_ = mime.AddExtensionType(".heif", "image/heif")
+}
+
+// DetectFormat returns the image format of a frame from its leading bytes,
+// falling back to the Content-Type header (may be empty) when they match no
+// known signature. Other frames are store.FormatUnknown, except ones that are
+// plainly not images (empty, text, markup or JSON, e.g. a camera's error
+// page), for which it returns "".
+func DetectFormat(head []byte, contentType string) string {
+	switch {
+	case bytes.HasPrefix(head, magicJPEG):
+		return store.FormatJPEG
+	case bytes.HasPrefix(head, magicPNG):
+		return store.FormatPNG
+	case len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "WEBP":
+		return store.FormatWebP
+	case bytes.HasPrefix(head, magicFITS):
+		return store.FormatFITS
+	case len(head) >= 12 && string(head[4:8]) == "ftyp" && slices.Contains(heicBrands, string(head[8:12])):
+		return store.FormatHEIC
+	}
+	mt, _, _ := mime.ParseMediaType(contentType)
+	switch mt {
+	case "image/jpeg", "image/jpg", "image/pjpeg":
+		return store.FormatJPEG
+	case "image/png":
+		return store.FormatPNG
+	case "image/webp":
+		return store.FormatWebP
+	case "image/fits", "application/fits":
+		return store.FormatFITS
+	case "image/heic", "image/heif":
+		return store.FormatHEIC
+	}
+	if notImage(head, mt) {
+		return ""
+	}
+	return store.FormatUnknown
+}
+
+// notImage reports whether a frame is evidently something other than an image.
+func notImage(head []byte, mediaType string) bool {
+	if strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "json") || strings.HasSuffix(mediaType, "xml") {
+		return true
+	}
+	head = bytes.TrimLeft(head, " \t\r\n")
+	return len(head) == 0 || head[0] == '<' || head[0] == '{'
+}
+
+// FormatOf returns the image format implied by the file name's extension, or
+// "" if it isn't an image file.
+func FormatOf(name string) string {
+	return extFormat[strings.ToLower(filepath.Ext(name))]
+}
+
+// IsImageFile reports whether name has the extension of a supported image format.
+func IsImageFile(name string) bool {
+	return FormatOf(name) != ""
+}
+
+// SetFormats restricts which image formats are saved; frames in other formats
+// are dropped with an ErrKindFormat error. All supported formats are accepted
+// by default.
+func (f *Fetcher) SetFormats(formats []string) {
+	f.formats = formats
+}
+
+// acceptFormat reports whether frames of format should be saved.
+func (f *Fetcher) acceptFormat(format string) bool {
+	if format == "" {
+		return false
+	}
+	return len(f.formats) == 0 || slices.Contains(f.formats, format)
+}
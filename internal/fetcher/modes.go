@@ -0,0 +1,228 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// Mode selects how the fetcher interprets its URL.
+type Mode string
+
+const (
+	// ModeStatic fetches a fixed URL that always serves the newest frame (e.g. latest.jpg).
+	ModeStatic Mode = "static"
+	// ModeTemplate expands strftime-style placeholders (%Y%m%d_%H%M%S) at poll time.
+	ModeTemplate Mode = "template"
+	// ModeIndex fetches a directory listing (HTML or JSON array) and downloads new files.
+	ModeIndex Mode = "index"
+	// ModeCapture runs an external command (e.g. ffmpeg grabbing an RTSP frame) per poll.
+	ModeCapture Mode = "capture"
+)
+
+// ParseMode validates a mode string; empty means ModeStatic.
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(strings.ToLower(strings.TrimSpace(s))); m {
+	case "":
+		return ModeStatic, nil
+	case ModeStatic, ModeTemplate, ModeIndex, ModeCapture:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown fetch mode %q (use static, template, index or capture)", s)
+	}
+}
+
+type statusError struct {
+	url  string
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("fetch %s: status %d", e.url, e.code)
+}
+
+// ExpandTemplate replaces strftime-style placeholders in tmpl with values from t.
+// Supported: %Y %y %m %d %H %M %S %j %s (unix seconds) and %% for a literal percent.
+func ExpandTemplate(tmpl string, t time.Time) string {
+	var b strings.Builder
+	for i := 0; i < len(tmpl); i++ {
+		c := tmpl[i]
+		if c != '%' || i+1 >= len(tmpl) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch tmpl[i] {
+		case 'Y':
+			fmt.Fprintf(&b, "%04d", t.Year())
+		case 'y':
+			fmt.Fprintf(&b, "%02d", t.Year()%100)
+		case 'm':
+			fmt.Fprintf(&b, "%02d", int(t.Month()))
+		case 'd':
+			fmt.Fprintf(&b, "%02d", t.Day())
+		case 'H':
+			fmt.Fprintf(&b, "%02d", t.Hour())
+		case 'M':
+			fmt.Fprintf(&b, "%02d", t.Minute())
+		case 'S':
+			fmt.Fprintf(&b, "%02d", t.Second())
+		case 'j':
+			fmt.Fprintf(&b, "%03d", t.YearDay())
+		case 's':
+			fmt.Fprintf(&b, "%d", t.Unix())
+		case '%':
+			b.WriteByte('%')
+		default:
+			// unknown placeholder: keep verbatim
+			b.WriteByte('%')
+			b.WriteByte(tmpl[i])
+		}
+	}
+	return b.String()
+}
+
+// fetchTemplate expands the URL template for the current time and downloads it.
+// A 404 means the camera has not produced that frame (yet) and is not an error.
+func (f *Fetcher) fetchTemplate(ctx context.Context) error {
+	u := ExpandTemplate(f.url, time.Now().In(f.location))
+	fr, prov, err := f.downloadFrame(ctx, u)
+	if err != nil {
+		var se *statusError
+		if errors.As(err, &se) && se.code == http.StatusNotFound {
+			log.Printf("fetcher: no frame at %s", u)
+			return nil
+		}
+		return err
+	}
+	return f.saveImage(ctx, fr, f.fetchSidecar(ctx, u), prov)
+}
+
+var hrefRe = regexp.MustCompile(`(?i)href\s*=\s*["']([^"'#?]+)["']`)
+
+// fetchIndex downloads every listed image newer than the last one seen.
+// On the first poll only the newest file is taken so an old archive isn't replayed.
+func (f *Fetcher) fetchIndex(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return fmt.Errorf("fetch index %s: %w", f.url, err)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch index %s: %w", f.url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &statusError{url: f.url, code: resp.StatusCode}
+	}
+
+	body, err := readLimited(resp, 4<<20)
+	if err != nil {
+		return fmt.Errorf("read index: %w", err)
+	}
+
+	names := parseIndex(body)
+	if len(names) == 0 {
+		log.Printf("fetcher: index %s lists no images", f.url)
+		return nil
+	}
+	sort.Slice(names, func(i, j int) bool { return path.Base(names[i]) < path.Base(names[j]) })
+
+	var pending []string
+	if f.lastIndexRef == "" {
+		pending = names[len(names)-1:]
+	} else {
+		for _, n := range names {
+			if path.Base(n) > f.lastIndexRef {
+				pending = append(pending, n)
+			}
+		}
+	}
+
+	base, err := url.Parse(f.url)
+	if err != nil {
+		return fmt.Errorf("parse index url: %w", err)
+	}
+	for _, n := range pending {
+		ref, err := url.Parse(n)
+		if err != nil {
+			log.Printf("fetcher: skip index entry %q: %v", n, err)
+			continue
+		}
+		u := base.ResolveReference(ref).String()
+		fr, prov, err := f.downloadFrame(ctx, u)
+		if err != nil {
+			return err
+		}
+		if err := f.saveImage(ctx, fr, f.fetchSidecar(ctx, u), prov); err != nil {
+			return err
+		}
+		f.lastIndexRef = path.Base(n)
+	}
+	return nil
+}
+
+// parseIndex extracts image file references from a JSON array (of strings or
+// objects with a "name"/"url" field) or from the hrefs of an HTML listing.
+func parseIndex(body []byte) []string {
+	var out []string
+	keep := func(s string) {
+		// Only links to known formats; a listing's .bin files are anyone's guess
+		if f := FormatOf(path.Base(s)); f != "" && f != store.FormatUnknown {
+			out = append(out, s)
+		}
+	}
+
+	trimmed := strings.TrimSpace(string(body))
+	if strings.HasPrefix(trimmed, "[") {
+		var strs []string
+		if json.Unmarshal(body, &strs) == nil {
+			for _, s := range strs {
+				keep(s)
+			}
+			return out
+		}
+		var objs []struct {
+			Name string `json:"name"`
+			URL  string `json:"url"`
+		}
+		if json.Unmarshal(body, &objs) == nil {
+			for _, o := range objs {
+				if o.URL != "" {
+					keep(o.URL)
+				} else {
+					keep(o.Name)
+				}
+			}
+			return out
+		}
+	}
+
+	for _, m := range hrefRe.FindAllStringSubmatch(trimmed, -1) {
+		keep(m[1])
+	}
+	return out
+}
+
+func readLimited(resp *http.Response, max int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(resp.Body, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > max {
+		return nil, fmt.Errorf("index larger than %d bytes", max)
+	}
+	return data, nil
+}
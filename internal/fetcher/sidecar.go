@@ -0,0 +1,86 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+)
+
+const maxSidecarBytes = 256 << 10
+
+// SetSidecarSuffix enables sidecar ingestion: after each frame, URL+suffix
+// (or, in capture mode, the output file + suffix) is read as a flat JSON object
+// and attached to the NewImageEvent as Meta. Empty disables it.
+func (f *Fetcher) SetSidecarSuffix(suffix string) {
+	f.sidecarSuffix = suffix
+}
+
+// fetchSidecar downloads and parses the sidecar for imageURL.
+// Failures are logged and yield nil; they never block the image itself.
+func (f *Fetcher) fetchSidecar(ctx context.Context, imageURL string) map[string]string {
+	if f.sidecarSuffix == "" {
+		return nil
+	}
+	data, _, err := f.download(ctx, imageURL+f.sidecarSuffix)
+	if err != nil {
+		log.Printf("fetcher: sidecar: %v", err)
+		return nil
+	}
+	return parseSidecar(data, imageURL+f.sidecarSuffix)
+}
+
+// readSidecarFile reads the sidecar next to a locally produced frame.
+func (f *Fetcher) readSidecarFile(imagePath string) map[string]string {
+	if f.sidecarSuffix == "" {
+		return nil
+	}
+	data, err := os.ReadFile(imagePath + f.sidecarSuffix)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("fetcher: sidecar: %v", err)
+		}
+		return nil
+	}
+	return parseSidecar(data, imagePath+f.sidecarSuffix)
+}
+
+// parseSidecar flattens the top-level scalar fields of a JSON object into strings.
+// Nested objects/arrays are kept as their JSON text.
+func parseSidecar(data []byte, src string) map[string]string {
+	if len(data) > maxSidecarBytes {
+		log.Printf("fetcher: sidecar %s too large (%d bytes), ignored", src, len(data))
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var raw map[string]any
+	if err := dec.Decode(&raw); err != nil {
+		log.Printf("fetcher: sidecar %s malformed: %v", src, err)
+		return nil
+	}
+
+	out := make(map[string]string, len(raw))
+	for k, v := range raw {
+		switch t := v.(type) {
+		case nil:
+			continue
+		case string:
+			out[k] = t
+		case json.Number:
+			out[k] = t.String()
+		case bool:
+			out[k] = fmt.Sprintf("%t", t)
+		default:
+			b, err := json.Marshal(t)
+			if err != nil {
+				continue
+			}
+			out[k] = string(b)
+		}
+	}
+	return out
+}
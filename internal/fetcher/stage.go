@@ -0,0 +1,169 @@
+package fetcher
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// tempSuffix marks files still being written; listings only pick up image
+// extensions, and Start removes leftovers of a crash or power loss.
+const tempSuffix = ".tmp"
+
+// stagedFrame is a frame fully written and synced to a temp file in the
+// images directory, waiting to be renamed into place (or dropped).
+type stagedFrame struct {
+	tmp    string
+	hash   [32]byte
+	size   int64
+	head   []byte // first sniffLen bytes
+	format string // detected image format, "" if unrecognized
+}
+
+// discard removes the temp file.
+func (fr *stagedFrame) discard() {
+	if err := os.Remove(fr.tmp); err != nil && !os.IsNotExist(err) {
+		log.Printf("fetcher: remove %s: %v", fr.tmp, err)
+	}
+}
+
+// stage streams r into a temp file in the images directory, hashing and
+// sniffing the format on the way, and fsyncs it. Any error, a stream that
+// ends early included, removes the temp file.
+func (f *Fetcher) stage(r io.Reader) (*stagedFrame, error) {
+	tmp, err := os.CreateTemp(f.imagesDir, "incoming-*"+tempSuffix)
+	if err != nil {
+		return nil, &fetchError{kind: ErrKindStorage, err: fmt.Errorf("create temp file: %w", err)}
+	}
+	h := sha256.New()
+	sn := &sniffer{}
+	n, copyErr := io.Copy(io.MultiWriter(fileWriter{tmp}, h, sn), r)
+	if copyErr != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		var we *writeError
+		if errors.As(copyErr, &we) {
+			return nil, &fetchError{kind: ErrKindStorage, err: fmt.Errorf("write %s: %w", tmp.Name(), we.err)}
+		}
+		return nil, fmt.Errorf("read response: %w", copyErr)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, &fetchError{kind: ErrKindStorage, err: fmt.Errorf("sync %s: %w", tmp.Name(), err)}
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return nil, &fetchError{kind: ErrKindStorage, err: fmt.Errorf("close %s: %w", tmp.Name(), err)}
+	}
+	return &stagedFrame{tmp: tmp.Name(), hash: sum(h), size: n, head: sn.head, format: DetectFormat(sn.head, "")}, nil
+}
+
+// sniffer keeps the first sniffLen bytes written to it.
+type sniffer struct{ head []byte }
+
+func (s *sniffer) Write(p []byte) (int, error) {
+	if rest := sniffLen - len(s.head); rest > 0 {
+		s.head = append(s.head, p[:min(rest, len(p))]...)
+	}
+	return len(p), nil
+}
+
+func sum(h hash.Hash) (out [32]byte) {
+	copy(out[:], h.Sum(nil))
+	return out
+}
+
+// writeError tags errors of the temp file (as opposed to the response body)
+// inside io.Copy.
+type writeError struct{ err error }
+
+func (e *writeError) Error() string { return e.err.Error() }
+
+type fileWriter struct{ f *os.File }
+
+func (w fileWriter) Write(p []byte) (int, error) {
+	n, err := w.f.Write(p)
+	if err != nil {
+		err = &writeError{err}
+	}
+	return n, err
+}
+
+// downloadFrame GETs url and stages the body, checking the written byte count
+// against Content-Length. A body cut short is dropped like a failed request,
+// so a partial frame is never stored or referenced.
+func (f *Fetcher) downloadFrame(ctx context.Context, url string) (*stagedFrame, *store.Provenance, error) {
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetch %s: %w", url, err)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, &statusError{url: url, code: resp.StatusCode}
+	}
+
+	fr, err := f.stage(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	fr.format = DetectFormat(fr.head, resp.Header.Get("Content-Type"))
+	prov := &store.Provenance{
+		HTTPStatus:    resp.StatusCode,
+		ContentLength: resp.ContentLength,
+		BytesWritten:  fr.size,
+		FetchMS:       float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if prov.Truncated() {
+		fr.discard()
+		return nil, nil, fmt.Errorf("fetch %s: body truncated after %d of %d bytes", url, fr.size, resp.ContentLength)
+	}
+	if t, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		t = t.UTC()
+		prov.ServerDate = &t
+	}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		t = t.UTC()
+		prov.LastModified = &t
+	}
+	return fr, prov, nil
+}
+
+// commit renames the staged frame to path and syncs the directory so the
+// rename survives a power loss.
+func (fr *stagedFrame) commit(path string) error {
+	if err := os.Rename(fr.tmp, path); err != nil {
+		return err
+	}
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		_ = dir.Sync() // not supported everywhere; the rename itself is atomic
+		dir.Close()
+	}
+	return nil
+}
+
+// removeStaleTemp deletes temp files left behind by a crash mid-write.
+func (f *Fetcher) removeStaleTemp() {
+	matches, _ := filepath.Glob(filepath.Join(f.imagesDir, "incoming-*"+tempSuffix))
+	for _, m := range matches {
+		if err := os.Remove(m); err == nil {
+			log.Printf("fetcher: removed incomplete %s", filepath.Base(m))
+		}
+	}
+}
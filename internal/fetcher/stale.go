@@ -0,0 +1,70 @@
+package fetcher
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// staleClockReset is how many different frames in a row may be stale before
+// that is taken as the camera clock going back (e.g. no NTP after a reboot)
+// rather than cached frames; the newest capture time then restarts from them.
+const staleClockReset = 3
+
+// SetStaleSkew keeps frames captured more than skew before the newest frame so
+// far from becoming the latest image: some cameras serve a cached frame after
+// a reboot. Such frames are still stored. The capture time is the response's
+// Last-Modified header; frames without one are never stale. 0 disables.
+func (f *Fetcher) SetStaleSkew(skew time.Duration) {
+	f.staleSkew = skew
+}
+
+// loadNewestCapture continues from the newest frame stored before a restart.
+func (f *Fetcher) loadNewestCapture(ctx context.Context) {
+	if f.staleSkew <= 0 || f.store == nil {
+		return
+	}
+	t, err := f.store.NewestCapture(ctx)
+	if err != nil {
+		log.Printf("fetcher: %v", err)
+		return
+	}
+	f.newestCapture = t
+}
+
+// checkStale reports whether a frame captured at capturedAt is older than the
+// newest frame by more than the skew, and tracks the newest capture time.
+func (f *Fetcher) checkStale(filename string, capturedAt time.Time) bool {
+	if f.staleSkew <= 0 || capturedAt.IsZero() {
+		return false
+	}
+	if f.newestCapture.IsZero() || !capturedAt.Before(f.newestCapture.Add(-f.staleSkew)) {
+		f.newestCapture = later(f.newestCapture, capturedAt)
+		f.staleRun = 0
+		return false
+	}
+
+	f.staleRun++
+	if f.staleRun >= staleClockReset {
+		log.Printf("fetcher: %d frames in a row captured before %s; assuming the camera clock went back",
+			f.staleRun, f.newestCapture.Format(time.RFC3339))
+		f.newestCapture = capturedAt
+		f.staleRun = 0
+		return false
+	}
+
+	log.Printf("fetcher: %s was captured at %s, %s before the latest frame; stored, but not used as latest",
+		filename, capturedAt.Format(time.RFC3339), f.newestCapture.Sub(capturedAt).Round(time.Second))
+	f.statusMu.Lock()
+	f.status.StaleFrames++
+	f.status.LastStaleAt = time.Now().UTC()
+	f.statusMu.Unlock()
+	return true
+}
+
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,173 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/hex"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// stateSaveInterval is how often a changed State is written at most; the
+// last change is written when the fetcher stops.
+const stateSaveInterval = 30 * time.Second
+
+// staleAfterPolls is how many poll intervals without a successful fetch
+// make the fetcher stale in Freshness.
+const staleAfterPolls = 3
+
+// State is what the fetcher remembers across restarts, saved under
+// store.SettingFetcherState by source: without it the first poll after a
+// restart stores the frame it saw last again, index mode skips the frames
+// published meanwhile and adaptive polling starts over.
+type State struct {
+	LastHash       string        `json:"last_hash,omitempty"` // hex SHA-256 of the last saved frame
+	LastIndexRef   string        `json:"last_index_ref,omitempty"`
+	LastChange     time.Time     `json:"last_change,omitzero"`
+	ChangeInterval time.Duration `json:"change_interval,omitempty"`
+	LastWidth      int           `json:"last_width,omitempty"`
+	LastHeight     int           `json:"last_height,omitempty"`
+	LastSuccess    time.Time     `json:"last_success,omitzero"`
+	LastSaved      time.Time     `json:"last_saved,omitzero"`
+}
+
+// SetStateStore persists the fetcher's State in st's settings.
+func (f *Fetcher) SetStateStore(st *store.Store) {
+	f.stateStore = st
+}
+
+// source identifies what the fetcher polls; a saved State only applies to
+// the same source.
+func (f *Fetcher) source() string {
+	if f.mode == ModeCapture {
+		return string(f.mode) + " " + strings.Join(f.captureCmd, " ")
+	}
+	return string(f.mode) + " " + f.url
+}
+
+// state returns the current State.
+func (f *Fetcher) state() State {
+	s := State{
+		LastIndexRef:   f.lastIndexRef,
+		LastChange:     f.adaptive.lastChange.UTC(),
+		ChangeInterval: f.adaptive.changeInterval,
+		LastWidth:      f.lastWidth,
+		LastHeight:     f.lastHeight,
+	}
+	if f.lastHash != [32]byte{} {
+		s.LastHash = hex.EncodeToString(f.lastHash[:])
+	}
+	f.statusMu.Lock()
+	s.LastSuccess, s.LastSaved = f.status.LastSuccess, f.status.LastSaved
+	f.statusMu.Unlock()
+	return s
+}
+
+// restoreState continues from the State saved for this source. A State that
+// can't be read is discarded with a warning; the fetcher then starts fresh.
+func (f *Fetcher) restoreState(ctx context.Context) {
+	if f.stateStore == nil {
+		return
+	}
+	var saved map[string]State
+	if _, err := f.stateStore.GetSetting(ctx, store.SettingFetcherState, &saved); err != nil {
+		log.Printf("fetcher: WARNING discarding saved state: %v", err)
+		return
+	}
+	s, ok := saved[f.source()]
+	if !ok {
+		return
+	}
+	var hash [32]byte
+	if s.LastHash != "" {
+		b, err := hex.DecodeString(s.LastHash)
+		if err != nil || len(b) != len(hash) {
+			log.Printf("fetcher: WARNING discarding saved state: bad last_hash %q", s.LastHash)
+			return
+		}
+		copy(hash[:], b)
+	}
+	if s.ChangeInterval < 0 || s.LastWidth < 0 || s.LastHeight < 0 {
+		log.Printf("fetcher: WARNING discarding saved state: negative values")
+		return
+	}
+
+	f.lastHash = hash
+	f.lastIndexRef = s.LastIndexRef
+	f.adaptive.lastChange, f.adaptive.changeInterval = s.LastChange, s.ChangeInterval
+	f.lastWidth, f.lastHeight = s.LastWidth, s.LastHeight
+	f.statusMu.Lock()
+	f.status.LastSuccess, f.status.LastSaved = s.LastSuccess, s.LastSaved
+	if s.LastWidth > 0 {
+		f.status.Resolution = store.FormatResolution(s.LastWidth, s.LastHeight)
+	}
+	f.statusMu.Unlock()
+	f.savedState, f.savedStateAt = s, time.Now()
+	log.Printf("fetcher: restored state (last success %s)", formatTime(s.LastSuccess))
+}
+
+// saveState writes the State if it changed, at most every stateSaveInterval
+// unless final. Failures only log.
+func (f *Fetcher) saveState(ctx context.Context, final bool) {
+	if f.stateStore == nil {
+		return
+	}
+	s := f.state()
+	if s == f.savedState || (!final && time.Since(f.savedStateAt) < stateSaveInterval) {
+		return
+	}
+	if err := f.writeState(ctx, s); err != nil {
+		log.Printf("fetcher: save state: %v", err)
+		return
+	}
+	f.savedState, f.savedStateAt = s, time.Now()
+}
+
+// writeState stores s for this source, keeping the states of other sources
+// (e.g. before a camera URL change that may be reverted).
+func (f *Fetcher) writeState(ctx context.Context, s State) error {
+	var saved map[string]State
+	if _, err := f.stateStore.GetSetting(ctx, store.SettingFetcherState, &saved); err != nil || saved == nil {
+		saved = map[string]State{} // a corrupt entry is replaced
+	}
+	saved[f.source()] = s
+	return f.stateStore.SetSetting(ctx, store.SettingFetcherState, saved)
+}
+
+// Freshness is how recent the last successful fetch is.
+type Freshness struct {
+	LastSuccess time.Time `json:"last_success,omitzero"`
+	LastSaved   time.Time `json:"last_saved,omitzero"`
+	AgeSeconds  *int64    `json:"age_seconds"` // since LastSuccess; null before the first one
+	Stale       bool      `json:"stale"`       // no success for staleAfterPolls poll intervals
+}
+
+// Freshness reports the age of the last successful fetch, including one
+// from before a restart, so staleness shows before the first poll finishes.
+func (f *Fetcher) Freshness() Freshness {
+	st := f.Status()
+	fr := Freshness{LastSuccess: st.LastSuccess, LastSaved: st.LastSaved}
+	if st.LastSuccess.IsZero() {
+		// Never fetched: stale once a first poll should have succeeded
+		fr.Stale = st.ConsecutiveFailures >= staleAfterPolls
+		return fr
+	}
+	interval := f.pollInterval
+	if d, err := time.ParseDuration(st.EffectiveInterval); err == nil {
+		interval = max(interval, d)
+	}
+	age := time.Since(st.LastSuccess)
+	secs := int64(age.Seconds())
+	fr.AgeSeconds = &secs
+	fr.Stale = age > staleAfterPolls*interval
+	return fr
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format(time.RFC3339)
+}
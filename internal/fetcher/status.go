@@ -0,0 +1,112 @@
+package fetcher
+
+import (
+	"errors"
+	"time"
+)
+
+// Error kinds reported in Status.LastErrorKind.
+const (
+	ErrKindNetwork = "network" // connection/timeout errors talking to the camera
+	ErrKindHTTP    = "http"    // camera answered with a non-200 status
+	ErrKindCapture = "capture" // external capture command failed
+	ErrKindStorage = "storage" // writing the frame to disk failed
+	ErrKindFormat  = "format"  // response that isn't an image, or a disabled image format
+)
+
+// fetchError tags an error with its kind so failures can be told apart in Status.
+type fetchError struct {
+	kind string
+	err  error
+}
+
+func (e *fetchError) Error() string { return e.err.Error() }
+func (e *fetchError) Unwrap() error { return e.err }
+
+// errorKind classifies err; untagged errors count as network failures.
+func errorKind(err error) string {
+	var fe *fetchError
+	if errors.As(err, &fe) {
+		return fe.kind
+	}
+	var se *statusError
+	if errors.As(err, &se) {
+		return ErrKindHTTP
+	}
+	return ErrKindNetwork
+}
+
+// Status is a snapshot of the fetcher's recent activity.
+type Status struct {
+	Mode                   Mode      `json:"mode"`
+	URL                    string    `json:"url"`
+	PollInterval           string    `json:"poll_interval"`
+	EffectiveInterval      string    `json:"effective_interval,omitempty"`       // current wait between polls (adaptive/daytime)
+	ObservedChangeInterval string    `json:"observed_change_interval,omitempty"` // smoothed interval between new frames
+	LastAttempt            time.Time `json:"last_attempt,omitempty"`
+	LastSuccess            time.Time `json:"last_success,omitempty"`
+	LastSaved              time.Time `json:"last_saved,omitempty"`
+	LastError              string    `json:"last_error,omitempty"`
+	LastErrorKind          string    `json:"last_error_kind,omitempty"`
+	LastErrorAt            time.Time `json:"last_error_at,omitempty"`
+	ConsecutiveFailures    int       `json:"consecutive_failures"`
+	Saved                  int64     `json:"saved"`
+	Unchanged              int64     `json:"unchanged"`
+	Resolution             string    `json:"resolution,omitempty"`            // of the last saved frame
+	ResolutionChangedAt    time.Time `json:"resolution_changed_at,omitempty"` // last time it differed from the previous frame
+	StaleFrames            int64     `json:"stale_frames"`                    // frames kept from becoming the latest (see SetStaleSkew)
+	LastStaleAt            time.Time `json:"last_stale_at,omitempty"`
+
+	Subscribers []SubscriberStats `json:"subscribers"` // of the new-image events
+}
+
+// Status returns a copy of the current fetcher status.
+func (f *Fetcher) Status() Status {
+	f.statusMu.Lock()
+	defer f.statusMu.Unlock()
+	st := f.status
+	st.Mode = f.mode
+	st.URL = f.url
+	st.PollInterval = f.pollInterval.String()
+	st.Subscribers = f.events.Stats()
+	return st
+}
+
+// Events returns the bus new frames are published to, to subscribe to.
+func (f *Fetcher) Events() *Bus {
+	return f.events
+}
+
+// Attempted returns a channel that is closed once the first fetch attempt
+// has completed, successful or not.
+func (f *Fetcher) Attempted() <-chan struct{} {
+	return f.attempted
+}
+
+func (f *Fetcher) recordResult(err error) {
+	defer f.attemptedOnce.Do(func() { close(f.attempted) })
+	now := time.Now().UTC()
+	f.statusMu.Lock()
+	defer f.statusMu.Unlock()
+	f.status.LastAttempt = now
+	if err != nil {
+		f.status.LastError = err.Error()
+		f.status.LastErrorKind = errorKind(err)
+		f.status.LastErrorAt = now
+		f.status.ConsecutiveFailures++
+		return
+	}
+	f.status.LastSuccess = now
+	f.status.ConsecutiveFailures = 0
+}
+
+func (f *Fetcher) recordSave(saved bool) {
+	f.statusMu.Lock()
+	defer f.statusMu.Unlock()
+	if saved {
+		f.status.Saved++
+		f.status.LastSaved = time.Now().UTC()
+	} else {
+		f.status.Unchanged++
+	}
+}
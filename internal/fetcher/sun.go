@@ -0,0 +1,29 @@
+package fetcher
+
+import (
+	"math"
+	"time"
+)
+
+// SunAltitude returns the approximate altitude of the sun in degrees above the
+// horizon at t for the given site (low-precision solar position, good to ~1°).
+func SunAltitude(t time.Time, lat, lon float64) float64 {
+	const rad = math.Pi / 180
+
+	// days since J2000.0
+	d := float64(t.UTC().Unix())/86400.0 + 2440587.5 - 2451545.0
+
+	g := (357.529 + 0.98560028*d) * rad // mean anomaly
+	q := 280.459 + 0.98564736*d         // mean longitude
+	l := (q + 1.915*math.Sin(g) + 0.020*math.Sin(2*g)) * rad
+	e := (23.439 - 0.00000036*d) * rad // obliquity of the ecliptic
+
+	ra := math.Atan2(math.Cos(e)*math.Sin(l), math.Cos(l))
+	dec := math.Asin(math.Sin(e) * math.Sin(l))
+
+	gmst := math.Mod(18.697374558+24.06570982441908*d, 24)
+	ha := (gmst*15+lon)*rad - ra
+
+	alt := math.Asin(math.Sin(lat*rad)*math.Sin(dec) + math.Cos(lat*rad)*math.Cos(dec)*math.Cos(ha))
+	return alt / rad
+}
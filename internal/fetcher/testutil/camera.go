@@ -0,0 +1,194 @@
+// Package testutil simulates an allsky camera over HTTP, with failure
+// injection, for exercising the fetcher without real hardware.
+package testutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Camera serves its current frame at every path. Zero configuration serves
+// a fixed JPEG with status 200.
+type Camera struct {
+	srv *httptest.Server
+
+	mu         sync.Mutex
+	frame      []byte
+	seq        uint8
+	delay      time.Duration // before headers are written
+	failNext   int           // requests still to fail
+	failStatus int           // 0 = drop the connection (network error)
+	truncate   int           // requests still to cut off halfway
+	etag       bool          // send ETag and answer If-None-Match with 304
+	requests   int
+}
+
+// NewCamera starts a simulator; Close it when done.
+func NewCamera() *Camera {
+	c := &Camera{}
+	c.frame = c.NextFrame()
+	c.srv = httptest.NewServer(http.HandlerFunc(c.serve))
+	return c
+}
+
+// URL returns the frame URL.
+func (c *Camera) URL() string { return c.srv.URL + "/latest.jpg" }
+
+// Close shuts the server down.
+func (c *Camera) Close() { c.srv.Close() }
+
+// SetFrame sets the bytes served from now on.
+func (c *Camera) SetFrame(data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.frame = data
+}
+
+// Frame returns the bytes currently served.
+func (c *Camera) Frame() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.frame
+}
+
+// NextFrame returns a new JPEG that differs from every earlier one (and does
+// not install it; see Advance).
+func (c *Camera) NextFrame() []byte {
+	c.mu.Lock()
+	c.seq++
+	seq := c.seq
+	c.mu.Unlock()
+	return JPEG(64, 48, seq)
+}
+
+// Advance installs a new distinct frame and returns it.
+func (c *Camera) Advance() []byte {
+	data := c.NextFrame()
+	c.SetFrame(data)
+	return data
+}
+
+// SetDelay delays every response by d (before headers), e.g. to cancel mid-request.
+func (c *Camera) SetDelay(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.delay = d
+}
+
+// FailNext makes the next n requests fail with status, or with a dropped
+// connection if status is 0.
+func (c *Camera) FailNext(n, status int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failNext, c.failStatus = n, status
+}
+
+// TruncateNext makes the next n responses announce the full Content-Length
+// but close the connection after half the body.
+func (c *Camera) TruncateNext(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.truncate = n
+}
+
+// SetETag enables ETag headers and 304 answers to a matching If-None-Match.
+func (c *Camera) SetETag(on bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.etag = on
+}
+
+// Requests returns how many requests were received.
+func (c *Camera) Requests() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.requests
+}
+
+func (c *Camera) serve(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	c.requests++
+	frame, delay, etag := c.frame, c.delay, c.etag
+	fail, failStatus := c.failNext > 0, c.failStatus
+	if fail {
+		c.failNext--
+	}
+	truncate := !fail && c.truncate > 0
+	if truncate {
+		c.truncate--
+	}
+	c.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if fail {
+		if failStatus != 0 {
+			http.Error(w, http.StatusText(failStatus), failStatus)
+			return
+		}
+		hijackClose(w)
+		return
+	}
+
+	if etag {
+		sum := sha256.Sum256(frame)
+		tag := fmt.Sprintf(`"%x"`, sum[:8])
+		w.Header().Set("ETag", tag)
+		if r.Header.Get("If-None-Match") == tag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Content-Length", strconv.Itoa(len(frame)))
+	if truncate {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(frame[:len(frame)/2])
+		if fl, ok := w.(http.Flusher); ok {
+			fl.Flush()
+		}
+		hijackClose(w)
+		return
+	}
+	_, _ = w.Write(frame)
+}
+
+// hijackClose drops the connection without a (further) response.
+func hijackClose(w http.ResponseWriter) {
+	if hj, ok := w.(http.Hijacker); ok {
+		if conn, _, err := hj.Hijack(); err == nil {
+			conn.Close()
+		}
+	}
+}
+
+// JPEG encodes a w×h test image whose content is determined by seed.
+func JPEG(w, h int, seed uint8) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: seed, G: uint8(x * 4), B: uint8(y * 5), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		panic(err) // encoding to memory can't fail
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,112 @@
+package fetcher
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"os"
+
+	"github.com/SkyClf/SkyClf/internal/store"
+	xdraw "golang.org/x/image/draw"
+)
+
+// defaultStoreQuality is the JPEG quality of frames scaled down without an
+// explicit quality.
+const defaultStoreQuality = 90
+
+// StoreTransform scales down and re-encodes JPEG frames before they are
+// stored, trading detail for card space. The zero value stores frames as
+// fetched.
+type StoreTransform struct {
+	MaxDim      int // longest side in pixels (0 = keep)
+	JPEGQuality int // 1-100 (0 = keep, or defaultStoreQuality when scaling)
+}
+
+func (t StoreTransform) enabled() bool {
+	return t.MaxDim > 0 || t.JPEGQuality > 0
+}
+
+// SetStoreTransform re-encodes fetched JPEG frames as t describes. Frames
+// keep their original hash for duplicate detection (see store.OriginalFrame).
+func (f *Fetcher) SetStoreTransform(t StoreTransform) {
+	f.transform = t
+}
+
+// transformFrame replaces the staged JPEG with its re-encoded version and
+// returns what the original was, or nil if it was already small enough and
+// no quality is set.
+func (f *Fetcher) transformFrame(fr *stagedFrame) (*store.OriginalFrame, error) {
+	in, err := os.Open(fr.tmp)
+	if err != nil {
+		return nil, err
+	}
+	img, err := jpeg.Decode(in)
+	in.Close()
+	if err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+
+	b := img.Bounds()
+	orig := &store.OriginalFrame{
+		SHA256: fmt.Sprintf("%x", fr.hash[:]),
+		Bytes:  fr.size,
+		Width:  b.Dx(),
+		Height: b.Dy(),
+	}
+	if w, h := fitWithin(b.Dx(), b.Dy(), f.transform.MaxDim); w != b.Dx() || h != b.Dy() {
+		dst := image.NewRGBA(image.Rect(0, 0, w, h))
+		xdraw.BiLinear.Scale(dst, dst.Bounds(), img, b, xdraw.Src, nil)
+		img = dst
+	} else if f.transform.JPEGQuality == 0 {
+		return nil, nil
+	}
+	quality := f.transform.JPEGQuality
+	if quality == 0 {
+		quality = defaultStoreQuality
+	}
+
+	out, err := os.CreateTemp(f.imagesDir, "incoming-*"+tempSuffix)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	sn := &sniffer{}
+	cw := &countWriter{}
+	err = jpeg.Encode(io.MultiWriter(out, h, sn, cw), img, &jpeg.Options{Quality: quality})
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return nil, fmt.Errorf("encode: %w", err)
+	}
+
+	fr.discard()
+	fr.tmp, fr.hash, fr.size, fr.head = out.Name(), sum(h), cw.n, sn.head
+	return orig, nil
+}
+
+// fitWithin scales w x h down to at most maxDim on the longest side, keeping
+// the aspect ratio; maxDim 0 keeps the size.
+func fitWithin(w, h, maxDim int) (int, int) {
+	if maxDim <= 0 || max(w, h) <= maxDim {
+		return w, h
+	}
+	if w >= h {
+		return maxDim, max(1, h*maxDim/w)
+	}
+	return max(1, w*maxDim/h), maxDim
+}
+
+// countWriter counts the bytes written to it.
+type countWriter struct{ n int64 }
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
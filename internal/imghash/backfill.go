@@ -0,0 +1,54 @@
+package imghash
+
+import (
+	"context"
+	"log"
+
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+const backfillBatch = 200
+
+// BackfillResult summarizes a backfill run.
+type BackfillResult struct {
+	Hashed int `json:"hashed"`
+	Failed int `json:"failed"` // missing or undecodable files; left unhashed
+}
+
+// Backfill computes perceptual hashes for all stored images that don't have one.
+// progress, if non-nil, is called after every batch.
+func Backfill(ctx context.Context, st *store.Store, progress func(BackfillResult)) (BackfillResult, error) {
+	var (
+		res   BackfillResult
+		after string
+	)
+	for {
+		batch, err := st.ListImagesWithoutPHash(ctx, after, backfillBatch)
+		if err != nil {
+			return res, err
+		}
+		if len(batch) == 0 {
+			return res, nil
+		}
+		for _, img := range batch {
+			if err := ctx.Err(); err != nil {
+				return res, err
+			}
+			after = img.ID
+
+			h, err := DHashFile(img.Path)
+			if err != nil {
+				log.Printf("imghash: %s: %v", img.ID, err)
+				res.Failed++
+				continue
+			}
+			if err := st.SetImagePHash(ctx, img.SHA256, Format(h)); err != nil {
+				return res, err
+			}
+			res.Hashed++
+		}
+		if progress != nil {
+			progress(res)
+		}
+	}
+}
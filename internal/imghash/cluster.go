@@ -0,0 +1,82 @@
+package imghash
+
+import (
+	"sort"
+	"time"
+)
+
+// Defaults for dedup reports and exports.
+const (
+	DefaultThreshold = 5         // bits out of 64
+	DefaultWindow    = time.Hour // max span of one cluster
+)
+
+// Item is one hashed image to be clustered.
+type Item struct {
+	ID        string
+	FetchedAt time.Time
+	Hash      uint64
+}
+
+// Cluster groups near-identical images. The first (oldest) member is the representative.
+type Cluster struct {
+	Representative string    `json:"representative"`
+	Members        []string  `json:"members"`
+	First          time.Time `json:"first"`
+	Last           time.Time `json:"last"`
+}
+
+// Group clusters items whose hash is within threshold bits of a cluster's
+// representative. A cluster only accepts images fetched within window of its
+// representative (window <= 0 means unlimited), so the same sky hours apart
+// still yields separate representatives.
+// Every item ends up in exactly one cluster; clusters are ordered by first fetch.
+func Group(items []Item, threshold int, window time.Duration) []Cluster {
+	sorted := make([]Item, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].FetchedAt.Before(sorted[j].FetchedAt) })
+
+	type open struct {
+		idx  int
+		hash uint64
+		at   time.Time
+	}
+	var (
+		clusters []Cluster
+		active   []open
+	)
+	for _, it := range sorted {
+		if window > 0 {
+			// drop clusters whose representative is too old to accept this item
+			keep := active[:0]
+			for _, o := range active {
+				if it.FetchedAt.Sub(o.at) <= window {
+					keep = append(keep, o)
+				}
+			}
+			active = keep
+		}
+
+		best, bestDist := -1, threshold+1
+		for i, o := range active {
+			if d := Distance(o.hash, it.Hash); d < bestDist {
+				best, bestDist = i, d
+			}
+		}
+		if best >= 0 {
+			c := &clusters[active[best].idx]
+			c.Members = append(c.Members, it.ID)
+			c.Last = it.FetchedAt
+			continue
+		}
+
+		clusters = append(clusters, Cluster{
+			Representative: it.ID,
+			Members:        []string{it.ID},
+			First:          it.FetchedAt,
+			Last:           it.FetchedAt,
+		})
+		active = append(active, open{idx: len(clusters) - 1, hash: it.Hash, at: it.FetchedAt})
+	}
+	return clusters
+}
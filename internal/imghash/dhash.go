@@ -0,0 +1,110 @@
+// Package imghash computes perceptual hashes used to find near-identical frames.
+package imghash
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"math/bits"
+	"os"
+	"strconv"
+
+	_ "golang.org/x/image/webp"
+)
+
+// dHash samples a 9x8 grayscale grid and compares horizontal neighbours,
+// giving 64 bits that are stable under small noise and exposure changes.
+const (
+	gridW = 9
+	gridH = 8
+
+	maxSamplesPerCell = 32 // per axis; keeps large frames cheap
+)
+
+// DHash returns the 64-bit difference hash of img.
+func DHash(img image.Image) uint64 {
+	var grid [gridH][gridW]float64
+	b := img.Bounds()
+	for gy := 0; gy < gridH; gy++ {
+		y0 := b.Min.Y + gy*b.Dy()/gridH
+		y1 := b.Min.Y + (gy+1)*b.Dy()/gridH
+		for gx := 0; gx < gridW; gx++ {
+			x0 := b.Min.X + gx*b.Dx()/gridW
+			x1 := b.Min.X + (gx+1)*b.Dx()/gridW
+			grid[gy][gx] = cellMean(img, x0, y0, x1, y1)
+		}
+	}
+
+	var h uint64
+	for y := 0; y < gridH; y++ {
+		for x := 0; x < gridW-1; x++ {
+			h <<= 1
+			if grid[y][x] < grid[y][x+1] {
+				h |= 1
+			}
+		}
+	}
+	return h
+}
+
+// cellMean averages the luminance of a block, sampling at most
+// maxSamplesPerCell pixels along each axis.
+func cellMean(img image.Image, x0, y0, x1, y1 int) float64 {
+	stepX := max(1, (x1-x0)/maxSamplesPerCell)
+	stepY := max(1, (y1-y0)/maxSamplesPerCell)
+
+	ycc, isYCbCr := img.(*image.YCbCr)
+	var sum float64
+	n := 0
+	for y := y0; y < y1; y += stepY {
+		for x := x0; x < x1; x += stepX {
+			if isYCbCr {
+				// JPEG frames decode to YCbCr; Y already is luminance
+				sum += float64(ycc.Y[ycc.YOffset(x, y)])
+			} else {
+				sum += float64(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+			}
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// DHashBytes decodes an encoded image and returns its hash.
+func DHashBytes(data []byte) (uint64, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("decode image: %w", err)
+	}
+	return DHash(img), nil
+}
+
+// DHashFile decodes the image at path and returns its hash.
+func DHashFile(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return DHashBytes(data)
+}
+
+// Distance is the Hamming distance between two hashes (0 = identical).
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Format renders a hash as 16 hex digits, the form stored in the database.
+func Format(h uint64) string {
+	return fmt.Sprintf("%016x", h)
+}
+
+// Parse is the inverse of Format.
+func Parse(s string) (uint64, error) {
+	return strconv.ParseUint(s, 16, 64)
+}
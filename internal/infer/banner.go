@@ -0,0 +1,82 @@
+package infer
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"sync"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// bannerFont is the bundled Go Regular font, parsed once.
+var bannerFont = sync.OnceValues(func() (*opentype.Font, error) {
+	return opentype.Parse(goregular.TTF)
+})
+
+// LoadScaled decodes the image at path scaled so the longer side is at most
+// maxDim (0 = original size).
+func LoadScaled(path string, maxDim int) (*image.RGBA, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if maxDim > 0 && (w > maxDim || h > maxDim) {
+		if w >= h {
+			w, h = maxDim, max(1, h*maxDim/w)
+		} else {
+			w, h = max(1, w*maxDim/h), maxDim
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	xdraw.BiLinear.Scale(dst, dst.Bounds(), src, b, xdraw.Src, nil)
+	return dst, nil
+}
+
+// DrawBanner writes text in white on a semi-transparent black band along the
+// bottom of img, sized to the image and shrunk until the text fits.
+func DrawBanner(img *image.RGBA, text string) error {
+	fnt, err := bannerFont()
+	if err != nil {
+		return err
+	}
+	b := img.Bounds()
+	size := max(12, float64(b.Dy())/24)
+	var face font.Face
+	for {
+		if face, err = opentype.NewFace(fnt, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull}); err != nil {
+			return err
+		}
+		if size <= 8 || font.MeasureString(face, text).Ceil() <= b.Dx()-int(size) {
+			break
+		}
+		face.Close()
+		size *= 0.9
+	}
+	defer face.Close()
+
+	band := image.Rect(b.Min.X, b.Max.Y-int(size*1.8), b.Max.X, b.Max.Y)
+	xdraw.Draw(img, band, image.NewUniform(color.NRGBA{A: 150}), image.Point{}, xdraw.Over)
+	m := face.Metrics()
+	baseline := band.Min.Y + (band.Dy()+(m.Ascent-m.Descent).Ceil())/2
+	d := font.Drawer{
+		Dst:  img,
+		Src:  image.White,
+		Face: face,
+		Dot:  fixed.P(b.Min.X+int(size/2), baseline),
+	}
+	d.DrawString(text)
+	return nil
+}
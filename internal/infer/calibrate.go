@@ -0,0 +1,134 @@
+package infer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// Calibration holds temperature scaling parameters stored under "calibration" in meta.json.
+// Logits are divided by Temperature and shifted by the optional per-class Bias before softmax.
+type Calibration struct {
+	Temperature float64            `json:"temperature"`
+	Bias        map[string]float64 `json:"bias,omitempty"`
+
+	// Fit diagnostics (informational)
+	Samples   int     `json:"samples,omitempty"`
+	NLLBefore float64 `json:"nll_before,omitempty"`
+	NLLAfter  float64 `json:"nll_after,omitempty"`
+	FittedAt  string  `json:"fitted_at,omitempty"`
+}
+
+// Apply returns calibrated logits; the input slice is not modified.
+func (c *Calibration) Apply(logits []float32, classNames []string) []float32 {
+	out := make([]float32, len(logits))
+	t := c.Temperature
+	if t <= 0 {
+		t = 1
+	}
+	for i, v := range logits {
+		x := float64(v) / t
+		if c.Bias != nil && i < len(classNames) {
+			x += c.Bias[classNames[i]]
+		}
+		out[i] = float32(x)
+	}
+	return out
+}
+
+// FitTemperature finds the temperature T minimizing the negative log-likelihood of
+// labels under softmax(logits/T). It returns T and the mean NLL before (T=1) and after.
+func FitTemperature(logits [][]float32, labels []int) (t, nllBefore, nllAfter float64) {
+	if len(logits) == 0 {
+		return 1, 0, 0
+	}
+
+	// Golden-section search over log(T) in [log 0.05, log 20]; NLL is unimodal in T.
+	lo, hi := math.Log(0.05), math.Log(20)
+	phi := (math.Sqrt(5) - 1) / 2
+	a := hi - phi*(hi-lo)
+	b := lo + phi*(hi-lo)
+	fa, fb := meanNLL(logits, labels, math.Exp(a)), meanNLL(logits, labels, math.Exp(b))
+	for i := 0; i < 60; i++ {
+		if fa < fb {
+			hi, b, fb = b, a, fa
+			a = hi - phi*(hi-lo)
+			fa = meanNLL(logits, labels, math.Exp(a))
+		} else {
+			lo, a, fa = a, b, fb
+			b = lo + phi*(hi-lo)
+			fb = meanNLL(logits, labels, math.Exp(b))
+		}
+	}
+
+	t = math.Exp((lo + hi) / 2)
+	return t, meanNLL(logits, labels, 1), meanNLL(logits, labels, t)
+}
+
+func meanNLL(logits [][]float32, labels []int, t float64) float64 {
+	var total float64
+	for n, row := range logits {
+		maxV := math.Inf(-1)
+		for _, v := range row {
+			maxV = math.Max(maxV, float64(v)/t)
+		}
+		var sum float64
+		for _, v := range row {
+			sum += math.Exp(float64(v)/t - maxV)
+		}
+		total += -(float64(row[labels[n]])/t - maxV - math.Log(sum))
+	}
+	return total / float64(len(logits))
+}
+
+// readCalibration loads the "calibration" block from dir/meta.json, if present.
+func readCalibration(dir string) (*Calibration, error) {
+	b, err := os.ReadFile(filepath.Join(dir, "meta.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read meta.json: %w", err)
+	}
+	var meta struct {
+		Calibration *Calibration `json:"calibration"`
+	}
+	if err := json.Unmarshal(b, &meta); err != nil {
+		return nil, fmt.Errorf("parse meta.json: %w", err)
+	}
+	if meta.Calibration != nil && meta.Calibration.Temperature <= 0 {
+		return nil, fmt.Errorf("meta.json: calibration temperature must be > 0")
+	}
+	return meta.Calibration, nil
+}
+
+// SaveCalibration writes c into dir/meta.json, keeping all other keys intact.
+func SaveCalibration(dir string, c *Calibration) error {
+	return updateMeta(dir, "calibration", c)
+}
+
+// updateMeta sets key in dir/meta.json to v, keeping all other keys intact.
+func updateMeta(dir, key string, v any) error {
+	path := filepath.Join(dir, "meta.json")
+	meta := map[string]any{}
+	if b, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(b, &meta); err != nil {
+			return fmt.Errorf("parse meta.json: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("read meta.json: %w", err)
+	}
+	meta[key] = v
+
+	b, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("write meta.json: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
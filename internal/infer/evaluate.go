@@ -0,0 +1,99 @@
+package infer
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ClassMetrics are one class's precision, recall and F1 in an evaluation.
+type ClassMetrics struct {
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1        float64 `json:"f1"`
+	Support   int     `json:"support"` // samples labeled with this class
+}
+
+// Metrics summarize predictions against labels, from an evaluation here or
+// from the trainer's final validation (see TrainingMetrics).
+type Metrics struct {
+	Samples  int                     `json:"samples"`
+	Correct  int                     `json:"correct"`
+	Accuracy float64                 `json:"accuracy"`
+	MacroF1  float64                 `json:"macro_f1"` // mean F1 over classes with support
+	PerClass map[string]ClassMetrics `json:"per_class"`
+	Loss     float64                 `json:"loss,omitempty"` // mean validation loss; reported by the trainer only
+}
+
+// Score computes accuracy and per-class metrics; labels[i] is the true class
+// of the sample predicted as predicted[i].
+func Score(labels, predicted []string) Metrics {
+	m := Metrics{Samples: len(labels), PerClass: map[string]ClassMetrics{}}
+	tp, fp, fn := map[string]int{}, map[string]int{}, map[string]int{}
+	classes := map[string]bool{}
+	for i, want := range labels {
+		got := predicted[i]
+		classes[want], classes[got] = true, true
+		if got == want {
+			m.Correct++
+			tp[want]++
+		} else {
+			fp[got]++
+			fn[want]++
+		}
+	}
+	if m.Samples > 0 {
+		m.Accuracy = float64(m.Correct) / float64(m.Samples)
+	}
+
+	names := make([]string, 0, len(classes))
+	for c := range classes {
+		names = append(names, c)
+	}
+	sort.Strings(names)
+
+	var f1Sum float64
+	var supported int
+	for _, c := range names {
+		cm := ClassMetrics{Support: tp[c] + fn[c]}
+		if d := tp[c] + fp[c]; d > 0 {
+			cm.Precision = float64(tp[c]) / float64(d)
+		}
+		if cm.Support > 0 {
+			cm.Recall = float64(tp[c]) / float64(cm.Support)
+			supported++
+		}
+		if cm.Precision+cm.Recall > 0 {
+			cm.F1 = 2 * cm.Precision * cm.Recall / (cm.Precision + cm.Recall)
+		}
+		if cm.Support > 0 {
+			f1Sum += cm.F1
+		}
+		m.PerClass[c] = cm
+	}
+	if supported > 0 {
+		m.MacroF1 = f1Sum / float64(supported)
+	}
+	return m
+}
+
+// OpenVersion loads the given model version into a new, separate predictor
+// with p's session options and mask/crop, leaving p's active session alone.
+// The caller must Close it.
+func (p *ORTPredictor) OpenVersion(version string) (*ORTPredictor, error) {
+	p.mu.Lock()
+	modelsDir, cfg, pre := p.modelsDir, p.sessionCfg, p.preprocess
+	p.mu.Unlock()
+
+	q := NewORTPredictor(modelsDir)
+	q.loading = false
+	q.sessionCfg = cfg
+	q.preprocess = pre
+	if err := q.Reload(modelsDir, version); err != nil {
+		q.Close()
+		return nil, err
+	}
+	if q.ActiveModel() == nil {
+		return nil, fmt.Errorf("model %s not found", version)
+	}
+	return q, nil
+}
@@ -0,0 +1,146 @@
+package infer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// MaxOcclusionGrid caps the saliency resolution (grid*grid inferences per request).
+const MaxOcclusionGrid = 14
+
+// ErrNoModel is returned by operations that need a loaded model.
+var ErrNoModel = errors.New("no model loaded")
+
+// ErrBadModelOutput is returned when the model's output contains NaN or Inf,
+// e.g. from corrupted weights. The image is not at fault.
+var ErrBadModelOutput = errors.New("model produced non-finite output")
+
+// ImageError is returned when the model input can't be made from the image,
+// e.g. a corrupt file. The model is not at fault.
+type ImageError struct {
+	Err error
+}
+
+func (e *ImageError) Error() string { return e.Err.Error() }
+func (e *ImageError) Unwrap() error { return e.Err }
+
+// Saliency is an occlusion sensitivity map: Scores[row][col] is the drop in
+// confidence of Class when that cell of the 224x224 input is greyed out.
+type Saliency struct {
+	ModelVersion   string      `json:"model_version"`
+	Class          string      `json:"class"`
+	BaseConfidence float32     `json:"base_confidence"`
+	Grid           int         `json:"grid"`
+	Scores         [][]float32 `json:"scores"`
+	// Preprocess is the mask/crop of the input the scores refer to
+	Preprocess PreprocessConfig `json:"preprocess"`
+}
+
+// Occlusion slides a grey patch over the preprocessed image and re-runs inference
+// per position. Each run takes the predictor lock separately, so interactive
+// predictions can interleave with a long explain request.
+func (p *ORTPredictor) Occlusion(ctx context.Context, imagePath string, grid int) (*Saliency, error) {
+	if grid < 1 || grid > MaxOcclusionGrid {
+		return nil, fmt.Errorf("grid must be between 1 and %d", MaxOcclusionGrid)
+	}
+
+	pre := p.Preprocess()
+	x, err := LoadAndPreprocessNCHWConfig(imagePath, pre)
+	if err != nil {
+		return nil, err
+	}
+
+	baseProbs, mi, err := p.runTensor(x)
+	if err != nil {
+		return nil, err
+	}
+	cls := argmax(baseProbs)
+
+	out := &Saliency{
+		ModelVersion:   mi.Version,
+		Class:          mi.ClassNames[cls],
+		BaseConfidence: baseProbs[cls],
+		Grid:           grid,
+		Scores:         make([][]float32, grid),
+		Preprocess:     pre,
+	}
+
+	hw := imgSize * imgSize
+	buf := make([]float32, len(x))
+	for row := 0; row < grid; row++ {
+		out.Scores[row] = make([]float32, grid)
+		y0, y1 := row*imgSize/grid, (row+1)*imgSize/grid
+		for col := 0; col < grid; col++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			x0, x1 := col*imgSize/grid, (col+1)*imgSize/grid
+
+			copy(buf, x)
+			// 0 after normalization == the dataset mean colour (neutral grey)
+			for c := 0; c < 3; c++ {
+				for y := y0; y < y1; y++ {
+					for xx := x0; xx < x1; xx++ {
+						buf[c*hw+y*imgSize+xx] = 0
+					}
+				}
+			}
+
+			probs, cur, err := p.runTensor(buf)
+			if err != nil {
+				return nil, err
+			}
+			if cur.Version != mi.Version {
+				return nil, errors.New("model changed during explain")
+			}
+			out.Scores[row][col] = baseProbs[cls] - probs[cls]
+		}
+	}
+	return out, nil
+}
+
+// runTensor runs one forward pass on an already preprocessed NCHW tensor and
+// returns (calibrated) probabilities plus the model that produced them.
+func (p *ORTPredictor) runTensor(x []float32) ([]float32, *ModelInfo, error) {
+	if p == nil {
+		return nil, nil, ErrNoModel
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.session == nil || p.model == nil {
+		return nil, nil, ErrNoModel
+	}
+
+	copy(p.inTensor.GetData(), x)
+	if err := p.session.Run(); err != nil {
+		return nil, nil, fmt.Errorf("onnx run: %w", err)
+	}
+	logits := p.outTensor.GetData()
+	if err := checkFinite(logits); err != nil {
+		return nil, nil, err
+	}
+	var probs []float32
+	if c := p.model.Calibration; c != nil {
+		probs = softmax(c.Apply(logits, p.model.ClassNames))
+	} else {
+		probs = softmax(logits)
+	}
+	if err := checkFinite(probs); err != nil {
+		return nil, nil, err
+	}
+	return probs, p.model, nil
+}
+
+// argmax returns the index of the largest value, skipping NaN; the first
+// wins ties. It returns 0 if all are NaN, so check with checkFinite first.
+func argmax(v []float32) int {
+	best := -1
+	for i, x := range v {
+		if !math.IsNaN(float64(x)) && (best < 0 || x > v[best]) {
+			best = i
+		}
+	}
+	return max(best, 0)
+}
@@ -0,0 +1,95 @@
+package infer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Lineage records which training run produced a model version and which
+// version it was fine-tuned from. It is stored under "lineage" in meta.json.
+type Lineage struct {
+	Parent    string          `json:"parent_version,omitempty"` // empty when trained from scratch
+	RunID     string          `json:"run_id"`
+	TrainedAt time.Time       `json:"trained_at"`
+	Run       json.RawMessage `json:"run,omitempty"` // settings and data of the run, as recorded by the trainer
+}
+
+// LineageNode is one version in the model DAG returned by ModelLineage.
+type LineageNode struct {
+	Version string   `json:"version"`
+	Lineage *Lineage `json:"lineage"` // nil for versions trained before lineage was recorded
+}
+
+// ReadLineage loads the "lineage" block from dir/meta.json, if present.
+func ReadLineage(dir string) (*Lineage, error) {
+	b, err := os.ReadFile(filepath.Join(dir, "meta.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read meta.json: %w", err)
+	}
+	var meta struct {
+		Lineage *Lineage `json:"lineage"`
+	}
+	if err := json.Unmarshal(b, &meta); err != nil {
+		return nil, fmt.Errorf("parse meta.json: %w", err)
+	}
+	return meta.Lineage, nil
+}
+
+// SaveLineage writes l into dir/meta.json, keeping all other keys intact.
+func SaveLineage(dir string, l Lineage) error {
+	return updateMeta(dir, "lineage", l)
+}
+
+// RecordLineage writes l into every published version that has no lineage yet
+// and was completed (DoneMarker written) at or after since, i.e. the versions
+// the run produced, and returns them.
+func RecordLineage(modelsDir string, l Lineage, since time.Time) ([]string, error) {
+	vers, _, err := ListVersions(modelsDir)
+	if err != nil {
+		return nil, err
+	}
+	var recorded []string
+	for _, v := range vers {
+		dir := filepath.Join(modelsDir, "skystate", v)
+		fi, err := os.Stat(filepath.Join(dir, DoneMarker))
+		if err != nil || fi.ModTime().Before(since) {
+			continue
+		}
+		existing, err := ReadLineage(dir)
+		if err != nil {
+			return recorded, fmt.Errorf("%s: %w", v, err)
+		}
+		if existing != nil || v == l.Parent {
+			continue
+		}
+		if err := SaveLineage(dir, l); err != nil {
+			return recorded, fmt.Errorf("%s: %w", v, err)
+		}
+		recorded = append(recorded, v)
+	}
+	return recorded, nil
+}
+
+// ModelLineage returns every published version with its lineage, in version
+// order. Parents refer to other nodes unless they have been deleted since.
+func ModelLineage(modelsDir string) ([]LineageNode, error) {
+	vers, _, err := ListVersions(modelsDir)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]LineageNode, 0, len(vers))
+	for _, v := range vers {
+		l, err := ReadLineage(filepath.Join(modelsDir, "skystate", v))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", v, err)
+		}
+		nodes = append(nodes, LineageNode{Version: v, Lineage: l})
+	}
+	return nodes, nil
+}
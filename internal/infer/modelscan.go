@@ -4,9 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
-	"sort"
+	"regexp"
 	"strings"
 )
 
@@ -16,25 +17,33 @@ type ModelInfo struct {
 	OnnxPath   string
 	Classes    map[string]int
 	ClassNames []string // index->name
+
+	Calibration *Calibration // optional temperature scaling from meta.json
+}
+
+// ErrModelNotFound is wrapped by the error of a reload asking for a version
+// that isn't published.
+var ErrModelNotFound = errors.New("model not found")
+
+var versionNameRe = regexp.MustCompile(`^v[A-Za-z0-9_.-]+$`)
+
+// ValidVersionName reports whether v looks like a model version directory name
+// (e.g. "v3"); it never contains path separators or "..".
+func ValidVersionName(v string) bool {
+	return versionNameRe.MatchString(v) && !strings.Contains(v, "..")
 }
 
 // FindSkyStateModel returns the specified version (e.g. "v3") of the skystate model.
-// If version is empty, the latest version is returned.
+// If version is empty, the latest version is returned. Only published versions
+// (see DoneMarker) are considered.
 func FindSkyStateModel(modelsDir, version string) (*ModelInfo, error) {
 	root := filepath.Join(modelsDir, "skystate")
-	ents, err := os.ReadDir(root)
+	vers, incomplete, err := ListVersions(modelsDir)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("read models root: %w", err)
+		return nil, err
 	}
-
-	var vers []string
-	for _, e := range ents {
-		if e.IsDir() && strings.HasPrefix(e.Name(), "v") {
-			vers = append(vers, e.Name())
-		}
+	for _, v := range incomplete {
+		log.Printf("[infer] ignoring %s: no %s marker (create it if the model is complete)", filepath.Join(root, v), DoneMarker)
 	}
 	if len(vers) == 0 {
 		return nil, nil
@@ -42,7 +51,6 @@ func FindSkyStateModel(modelsDir, version string) (*ModelInfo, error) {
 
 	// pick latest if no explicit version requested
 	if version == "" {
-		sort.Strings(vers)
 		version = vers[len(vers)-1]
 	} else {
 		found := false
@@ -95,12 +103,18 @@ func FindSkyStateModel(modelsDir, version string) (*ModelInfo, error) {
 		}
 	}
 
+	calib, err := readCalibration(dir)
+	if err != nil {
+		return nil, err
+	}
+
 	return &ModelInfo{
-		Version:    version,
-		Dir:        dir,
-		OnnxPath:   onnxPath,
-		Classes:    classes,
-		ClassNames: names,
+		Version:     version,
+		Dir:         dir,
+		OnnxPath:    onnxPath,
+		Classes:     classes,
+		ClassNames:  names,
+		Calibration: calib,
 	}, nil
 }
 
@@ -3,12 +3,15 @@ package infer
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	ort "github.com/yalue/onnxruntime_go"
@@ -19,91 +22,90 @@ type ORTPredictor struct {
 
 	modelsDir string
 	model     *ModelInfo
-	session   *ort.Session[float32]
+	session   *ort.AdvancedSession
+
+	// active mirrors model for readers that must not wait for mu, which a
+	// prediction holds for its whole preprocess and inference
+	active atomic.Pointer[ModelInfo]
 
 	inTensor  *ort.Tensor[float32]
 	outTensor *ort.Tensor[float32]
-}
 
-func NewORTPredictor(modelsDir string) (*ORTPredictor, error) {
-	// Optional: allow user to point to a specific shared library path
-	// e.g. SKYCLF_ORT_LIB=/usr/local/lib/onnxruntime.so
-	if p := os.Getenv("SKYCLF_ORT_LIB"); p != "" {
-		ort.SetSharedLibraryPath(p)
-	}
+	loadedAt      time.Time
+	warmup        time.Duration // first inference after load
+	pinnedVersion string        // explicit version requested via Reload ("" = follow latest)
+	reloadCount   uint64        // incremented on every model swap
 
-	// Global ORT env (should be called once per process)
-	if !ort.IsInitialized() {
-		if err := ort.InitializeEnvironment(); err != nil {
-			return nil, fmt.Errorf("onnxruntime init: %w", err)
-		}
-	}
+	preprocess PreprocessConfig // horizon mask/crop applied before resizing
 
-	log.Printf("[infer] scanning models in %s", modelsDir)
-	mi, err := FindSkyStateModel(modelsDir, "")
-	if err != nil {
-		return nil, err
-	}
-	if mi == nil {
-		log.Printf("[infer] no model found")
-		return nil, nil // no model yet
-	}
-	log.Printf("[infer] found model: %s (version=%s, classes=%v)", mi.OnnxPath, mi.Version, mi.ClassNames)
+	loading bool  // initial Load still running
+	loadErr error // last failed (re)load; cleared on success
 
-	// Create fixed-shape tensors (batch=1)
-	inShape := ort.NewShape(1, 3, 224, 224)
-	outShape := ort.NewShape(1, int64(len(mi.ClassNames)))
+	reloadCall   *reloadCall   // reload in progress, shared by concurrent callers
+	reloadQueue  []*reloadCall // reloads waiting for it, in order
+	lastReloadAt time.Time     // when the last reload finished, successful or not
 
-	inData := make([]float32, inShape.FlattenedSize())
-	inTensor, err := ort.NewTensor(inShape, inData)
-	if err != nil {
-		return nil, fmt.Errorf("create input tensor: %w", err)
-	}
+	reloadFn func(modelsDir, version string) error // runs a reload; nil = p.reload (tests replace it)
 
-	outTensor, err := ort.NewEmptyTensor[float32](outShape)
-	if err != nil {
-		_ = inTensor.Destroy()
-		return nil, fmt.Errorf("create output tensor: %w", err)
+	sessionCfg SessionConfig // threads/provider for new sessions
+	provider   string        // provider of the active session after fallback
+
+	// Predictions rejected with ErrBadModelOutput: consecutive ones (reset by
+	// a good prediction or a reload), all of them, and the last error
+	badOutputs      int
+	badOutputsTotal uint64
+	lastBadOutput   error
+}
+
+// MaxBadOutputs is how many consecutive predictions may have non-finite
+// output before Health reports the model as unhealthy.
+const MaxBadOutputs = 3
+
+// NewORTPredictor returns an empty predictor; it answers "no model loaded"
+// until Load (typically run in the background) has finished.
+func NewORTPredictor(modelsDir string) *ORTPredictor {
+	return &ORTPredictor{modelsDir: modelsDir, loading: true}
+}
+
+// SetSessionConfig sets the ORT options used for sessions created by later
+// loads; call it before Load.
+func (p *ORTPredictor) SetSessionConfig(cfg SessionConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sessionCfg = cfg
+}
+
+// initEnvironment initializes the global ORT environment once per process.
+func initEnvironment() error {
+	if ort.IsInitialized() {
+		return nil
 	}
+	// SKYCLF_ORT_LIB (e.g. /usr/local/lib/libonnxruntime.so) or the first
+	// library found in the usual places
+	return initORTLibrary()
+}
 
-	// Session: must provide names + tensors up front (per library design)
-	// ONNX Runtime looks for external data files (model.onnx.data) in the current
-	// working directory, so we need to temporarily change to the model's directory.
-	modelDir := filepath.Dir(mi.OnnxPath)
-	origDir, err := os.Getwd()
-	if err != nil {
-		_ = inTensor.Destroy()
-		_ = outTensor.Destroy()
-		return nil, fmt.Errorf("get working dir: %w", err)
+// Load performs the initial model scan and session creation. Errors (missing
+// runtime, corrupt model) are also kept for LoadError, so callers can keep
+// serving without a model instead of exiting.
+func (p *ORTPredictor) Load() error {
+	log.Printf("[infer] scanning models in %s", p.modelsDir)
+	err := p.Reload(p.modelsDir, "")
+
+	p.mu.Lock()
+	p.loading = false
+	p.mu.Unlock()
+	return err
+}
+
+// LoadError returns the error of the last failed load or reload, or nil.
+func (p *ORTPredictor) LoadError() error {
+	if p == nil {
+		return nil
 	}
-	if err := os.Chdir(modelDir); err != nil {
-		_ = inTensor.Destroy()
-		_ = outTensor.Destroy()
-		return nil, fmt.Errorf("chdir to model dir: %w", err)
-	}
-	defer os.Chdir(origDir)
-
-	sess, err := ort.NewSession[float32](
-		filepath.Base(mi.OnnxPath), // use just the filename since we're in the model dir
-		[]string{"input"},
-		[]string{"logits"},
-		[]*ort.Tensor[float32]{inTensor},
-		[]*ort.Tensor[float32]{outTensor},
-	)
-	if err != nil {
-		_ = inTensor.Destroy()
-		_ = outTensor.Destroy()
-		return nil, fmt.Errorf("create session: %w", err)
-	}
-
-	log.Printf("[infer] ONNX session loaded successfully")
-	return &ORTPredictor{
-		modelsDir: modelsDir,
-		model:     mi,
-		session:   sess,
-		inTensor:  inTensor,
-		outTensor: outTensor,
-	}, nil
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.loadErr
 }
 
 func (p *ORTPredictor) Close() error {
@@ -126,11 +128,68 @@ func (p *ORTPredictor) Close() error {
 	return nil
 }
 
+// reloadCall is one run of reload; callers asking for the same directory and
+// version while it runs or waits to run wait for it and get its result.
+type reloadCall struct {
+	modelsDir, version string
+	done               chan struct{}
+	err                error
+}
+
 // Reload scans for new models and loads the latest one, or a specific version if provided.
+// Reloads never overlap: a call made while another runs or waits to run with
+// the same arguments shares its result, one with other arguments queues
+// behind them and then reloads.
 func (p *ORTPredictor) Reload(modelsDir string, version string) error {
 	if p == nil {
 		return fmt.Errorf("predictor is nil")
 	}
+	p.mu.Lock()
+	if modelsDir == "" {
+		modelsDir = p.modelsDir
+	}
+	for _, c := range append([]*reloadCall{p.reloadCall}, p.reloadQueue...) {
+		if c != nil && c.modelsDir == modelsDir && c.version == version {
+			p.mu.Unlock()
+			<-c.done
+			return c.err
+		}
+	}
+	c := &reloadCall{modelsDir: modelsDir, version: version, done: make(chan struct{})}
+	prev := p.reloadCall
+	if n := len(p.reloadQueue); n > 0 {
+		prev = p.reloadQueue[n-1]
+	}
+	if prev != nil {
+		// Each queued call starts once the one before it has finished
+		p.reloadQueue = append(p.reloadQueue, c)
+		p.mu.Unlock()
+		<-prev.done
+		p.mu.Lock()
+		p.reloadQueue = p.reloadQueue[1:]
+	}
+	p.reloadCall = c
+	p.mu.Unlock()
+
+	run := p.reload
+	if p.reloadFn != nil {
+		run = p.reloadFn
+	}
+	c.err = run(modelsDir, version)
+
+	p.mu.Lock()
+	// Asking for a version that doesn't exist leaves the loaded model alone
+	if !errors.Is(c.err, ErrModelNotFound) {
+		p.loadErr = c.err
+	}
+	p.lastReloadAt = time.Now().UTC()
+	p.reloadCall = nil
+	p.mu.Unlock()
+	close(c.done)
+	return c.err
+}
+
+func (p *ORTPredictor) reload(modelsDir string, version string) error {
 	
 	if modelsDir == "" {
 		modelsDir = p.modelsDir
@@ -143,12 +202,16 @@ func (p *ORTPredictor) Reload(modelsDir string, version string) error {
 		return fmt.Errorf("scan models: %w", err)
 	}
 	if mi == nil {
+		if version != "" {
+			return fmt.Errorf("%w: %s", ErrModelNotFound, version)
+		}
 		log.Printf("[infer] no model found during reload")
 		return nil
 	}
 	
 	// Check if it's the same model we already have
 	p.mu.Lock()
+	p.pinnedVersion = version
 	if p.model != nil && p.model.OnnxPath == mi.OnnxPath {
 		p.mu.Unlock()
 		log.Printf("[infer] model unchanged: %s", mi.Version)
@@ -157,6 +220,10 @@ func (p *ORTPredictor) Reload(modelsDir string, version string) error {
 	p.mu.Unlock()
 	
 	log.Printf("[infer] loading new model: %s (version=%s, classes=%v)", mi.OnnxPath, mi.Version, mi.ClassNames)
+
+	if err := initEnvironment(); err != nil {
+		return err
+	}
 	
 	// Create new tensors
 	inShape := ort.NewShape(1, 3, 224, 224)
@@ -188,13 +255,10 @@ func (p *ORTPredictor) Reload(modelsDir string, version string) error {
 		return fmt.Errorf("chdir to model dir: %w", err)
 	}
 	
-	newSession, err := ort.NewSession[float32](
-		filepath.Base(mi.OnnxPath),
-		[]string{"input"},
-		[]string{"logits"},
-		[]*ort.Tensor[float32]{newInTensor},
-		[]*ort.Tensor[float32]{newOutTensor},
-	)
+	p.mu.Lock()
+	sessionCfg := p.sessionCfg
+	p.mu.Unlock()
+	newSession, provider, err := newSession(filepath.Base(mi.OnnxPath), newInTensor, newOutTensor, sessionCfg)
 	os.Chdir(origDir) // restore working dir
 	
 	if err != nil {
@@ -203,6 +267,8 @@ func (p *ORTPredictor) Reload(modelsDir string, version string) error {
 		return fmt.Errorf("create session: %w", err)
 	}
 
+	warmup := warmUp(newSession)
+
 	// Swap out old session/tensors
 	p.mu.Lock()
 	oldSession := p.session
@@ -210,10 +276,16 @@ func (p *ORTPredictor) Reload(modelsDir string, version string) error {
 	oldOut := p.outTensor
 	
 	p.model = mi
+	p.active.Store(mi)
 	p.session = newSession
 	p.inTensor = newInTensor
 	p.outTensor = newOutTensor
 	p.modelsDir = modelsDir
+	p.loadedAt = time.Now().UTC()
+	p.warmup = warmup
+	p.provider = provider
+	p.reloadCount++
+	p.badOutputs = 0
 	p.mu.Unlock()
 	
 	// Cleanup old resources
@@ -232,7 +304,12 @@ func (p *ORTPredictor) Reload(modelsDir string, version string) error {
 }
 
 func (p *ORTPredictor) PredictImage(ctx context.Context, imagePath string) (*Prediction, error) {
-	if p == nil || p.session == nil || p.model == nil {
+	return p.PredictImageOpts(ctx, imagePath, PredictOptions{})
+}
+
+// PredictImageOpts runs inference like PredictImage and adds the outputs requested in opts.
+func (p *ORTPredictor) PredictImageOpts(ctx context.Context, imagePath string, opts PredictOptions) (*Prediction, error) {
+	if p == nil {
 		return nil, nil // no model loaded
 	}
 
@@ -241,12 +318,22 @@ func (p *ORTPredictor) PredictImage(ctx context.Context, imagePath string) (*Pre
 	// single-thread safety: tensors are reused
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	locked := time.Now() // timings exclude waiting for the lock
 
-	x, err := LoadAndPreprocessNCHW(imagePath) // []float32 len=3*224*224
+	if p.session == nil || p.model == nil {
+		return nil, nil // no model loaded (yet)
+	}
+
+	pre := p.preprocess
+	if opts.Preprocess != nil {
+		pre = *opts.Preprocess
+	}
+	x, err := LoadAndPreprocessNCHWConfig(imagePath, pre) // []float32 len=3*224*224
 	if err != nil {
 		log.Printf("[infer] preprocess error: %v", err)
-		return nil, err
+		return nil, &ImageError{Err: err}
 	}
+	preprocessed := time.Now()
 
 	// Copy into the preallocated input tensor buffer
 	copy(p.inTensor.GetData(), x)
@@ -255,19 +342,26 @@ func (p *ORTPredictor) PredictImage(ctx context.Context, imagePath string) (*Pre
 	if err := p.session.Run(); err != nil {
 		return nil, fmt.Errorf("onnx run: %w", err)
 	}
+	inferred := time.Now()
 
 	logits := p.outTensor.GetData() // length = num_classes
-	probs := softmax(logits)
-
-	// argmax
-	bestIdx := 0
-	best := probs[0]
-	for i := 1; i < len(probs); i++ {
-		if probs[i] > best {
-			best = probs[i]
-			bestIdx = i
-		}
+	if err := checkFinite(logits); err != nil {
+		return nil, p.badOutput(err)
+	}
+	calib := p.model.Calibration
+	var probs []float32
+	if calib != nil {
+		probs = softmax(calib.Apply(logits, p.model.ClassNames))
+	} else {
+		probs = softmax(logits)
 	}
+	if err := checkFinite(probs); err != nil {
+		return nil, p.badOutput(fmt.Errorf("after calibration: %w", err))
+	}
+	p.badOutputs = 0
+
+	bestIdx := argmax(probs)
+	best := probs[bestIdx]
 
 	// Build probs map name->prob
 	probMap := make(map[string]float32, len(probs))
@@ -282,12 +376,47 @@ func (p *ORTPredictor) PredictImage(ctx context.Context, imagePath string) (*Pre
 		ModelTask:  "skystate",
 		ModelVer:   p.model.Version,
 		ModelPath:  filepath.ToSlash(p.model.OnnxPath),
+
+		PreprocessMS: msSince(locked, preprocessed),
+		InferenceMS:  msSince(preprocessed, inferred),
+	}
+	if calib != nil {
+		result.Calibrated = true
+		result.Temperature = calib.Temperature
+	}
+	if opts.Logits {
+		result.Logits = append([]float32(nil), logits...)
+		result.ClassNames = append([]string(nil), p.model.ClassNames...)
+	}
+	if opts.TopK > 0 {
+		result.TopK = topK(probs, p.model.ClassNames, opts.TopK)
 	}
 
 	log.Printf("[infer] prediction: %s (%.1f%%) took %v", result.SkyState, result.Confidence*100, time.Since(start))
 	return result, nil
 }
 
+// msSince returns the duration between from and to in fractional milliseconds.
+func msSince(from, to time.Time) float64 {
+	return float64(to.Sub(from).Microseconds()) / 1000
+}
+
+// topK returns the k most probable classes, highest first (ties keep class order).
+func topK(probs []float32, names []string, k int) []ClassProb {
+	out := make([]ClassProb, len(probs))
+	for i, p := range probs {
+		out[i] = ClassProb{Class: names[i], Prob: p}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Prob > out[j].Prob })
+	if k < len(out) {
+		out = out[:k]
+	}
+	return out
+}
+
+// softmax turns logits into probabilities. NaN logits get probability 0 and
+// +Inf ones share all of it, so the result never contains NaN; callers reject
+// such output with checkFinite first anyway.
 func softmax(logits []float32) []float32 {
 	out := make([]float32, len(logits))
 	if len(logits) == 0 {
@@ -295,16 +424,27 @@ func softmax(logits []float32) []float32 {
 	}
 
 	// numerical stability: subtract max
-	maxV := logits[0]
-	for _, v := range logits[1:] {
+	maxV := float32(math.Inf(-1))
+	for _, v := range logits {
 		if v > maxV {
 			maxV = v
 		}
 	}
+	if math.IsInf(float64(maxV), -1) {
+		return out // all NaN or -Inf
+	}
 
 	var sum float64
 	for i, v := range logits {
-		ev := math.Exp(float64(v - maxV))
+		var ev float64
+		switch {
+		case math.IsInf(float64(maxV), 1):
+			if math.IsInf(float64(v), 1) {
+				ev = 1
+			}
+		case !math.IsNaN(float64(v)):
+			ev = math.Exp(float64(v - maxV))
+		}
 		out[i] = float32(ev)
 		sum += ev
 	}
@@ -318,13 +458,215 @@ func softmax(logits []float32) []float32 {
 	return out
 }
 
-// Optional helper if you want /api/models later
+// checkFinite returns an ErrBadModelOutput if v contains NaN or Inf.
+func checkFinite(v []float32) error {
+	for i, x := range v {
+		if math.IsNaN(float64(x)) || math.IsInf(float64(x), 0) {
+			return fmt.Errorf("%w: value %d of %d is %v", ErrBadModelOutput, i, len(v), x)
+		}
+	}
+	return nil
+}
+
+// badOutput counts a prediction rejected for non-finite output and returns
+// err. Callers hold p.mu.
+func (p *ORTPredictor) badOutput(err error) error {
+	p.badOutputs++
+	p.badOutputsTotal++
+	p.lastBadOutput = err
+	log.Printf("[infer] %s: %v (%d in a row)", p.model.Version, err, p.badOutputs)
+	return err
+}
+
+// Health returns an error once MaxBadOutputs consecutive predictions had
+// non-finite output, until a good prediction or a reload. /ready reports it.
+func (p *ORTPredictor) Health() error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.badOutputs < MaxBadOutputs {
+		return nil
+	}
+	return fmt.Errorf("model %s: %d predictions in a row failed: %v", p.model.Version, p.badOutputs, p.lastBadOutput)
+}
+
+// ActiveModel returns a copy of the loaded model info, or nil if no model is loaded.
+func (p *ORTPredictor) ActiveModel() *ModelInfo {
+	if p == nil {
+		return nil
+	}
+	// Without mu: polls must not queue behind a running prediction
+	active := p.active.Load()
+	if active == nil {
+		return nil
+	}
+	mi := *active
+	return &mi
+}
+
+// ModelVersion returns the version of the loaded model, or "" if none is loaded.
+func (p *ORTPredictor) ModelVersion() string {
+	if mi := p.ActiveModel(); mi != nil {
+		return mi.Version
+	}
+	return ""
+}
+
+// SetPreprocess sets the mask/crop applied to every image before inference.
+func (p *ORTPredictor) SetPreprocess(cfg PreprocessConfig) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.preprocess = cfg
+}
+
+// Preprocess returns the active mask/crop configuration.
+func (p *ORTPredictor) Preprocess() PreprocessConfig {
+	if p == nil {
+		return PreprocessConfig{}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.preprocess
+}
+
+// SetCalibration replaces the calibration of the active model if it is still version.
+func (p *ORTPredictor) SetCalibration(version string, c *Calibration) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.model == nil || p.model.Version != version {
+		return
+	}
+	mi := *p.model
+	mi.Calibration = c
+	p.model = &mi
+	p.active.Store(&mi)
+}
+
+// newSession creates a session for onnxFile with the configured options. If the
+// session can't be created with a non-CPU provider (e.g. CUDA libraries
+// missing at runtime) it retries on the CPU.
+func newSession(onnxFile string, in, out *ort.Tensor[float32], cfg SessionConfig) (*ort.AdvancedSession, string, error) {
+	opts, provider, err := newSessionOptions(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	defer opts.Destroy()
+
+	sess, err := ort.NewAdvancedSession(onnxFile, []string{"input"}, []string{"logits"},
+		[]ort.Value{in}, []ort.Value{out}, opts)
+	if err != nil && provider != ProviderCPU {
+		log.Printf("[infer] WARN: session with %s provider failed, falling back to cpu: %v", provider, err)
+		cfg.Provider = ProviderCPU
+		return newSession(onnxFile, in, out, cfg)
+	}
+	return sess, provider, err
+}
+
+// warmUp runs one inference on the (zeroed) input tensor so the first real
+// request doesn't pay for lazy allocations, and returns how long it took.
+func warmUp(sess *ort.AdvancedSession) time.Duration {
+	start := time.Now()
+	if err := sess.Run(); err != nil {
+		log.Printf("[infer] warm-up run failed: %v", err)
+	}
+	return time.Since(start)
+}
+
+// modelSize returns the size of model.onnx plus its external data file, if any.
+func modelSize(onnxPath string) int64 {
+	var total int64
+	for _, p := range []string{onnxPath, onnxPath + ".data"} {
+		if fi, err := os.Stat(p); err == nil {
+			total += fi.Size()
+		}
+	}
+	return total
+}
+
+// ModelJSON describes the active model for /api/models. All fields are read under
+// the predictor lock so they are consistent during a concurrent reload.
+// reload_count increases on every model swap so clients can detect changes;
+// reloading is true while a reload runs, and load_error is the error of the
+// last one to finish at last_reload_at.
 func (p *ORTPredictor) ModelJSON() ([]byte, error) {
-	if p == nil || p.model == nil {
+	if p == nil {
 		return json.Marshal(map[string]any{"active": nil})
 	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var loadErr any = nil
+	if p.loadErr != nil {
+		loadErr = p.loadErr.Error()
+	}
+	var lastReload any = nil
+	if !p.lastReloadAt.IsZero() {
+		lastReload = p.lastReloadAt.Format(time.RFC3339)
+	}
+	if p.model == nil {
+		return json.Marshal(map[string]any{
+			"backend":      "onnxruntime",
+			"active":       nil,
+			"reload_count":   p.reloadCount,
+			"reloading":      p.reloadCall != nil || len(p.reloadQueue) > 0,
+			"last_reload_at": lastReload,
+			"loading":        p.loading,
+			"load_error":     loadErr,
+			"session":        p.sessionJSON(),
+		})
+	}
+
+	var pinned any = nil
+	if p.pinnedVersion != "" {
+		pinned = p.pinnedVersion
+	}
 	return json.Marshal(map[string]any{
-		"active": p.model.Version,
-		"path":   p.model.OnnxPath,
+		"backend":        "onnxruntime",
+		"active":         p.model.Version,
+		"path":           p.model.OnnxPath,
+		"classes":        p.model.ClassNames,
+		"size_bytes":     modelSize(p.model.OnnxPath),
+		"loaded_at":      p.loadedAt.Format(time.RFC3339),
+		"warmup_ms":      float64(p.warmup.Microseconds()) / 1000,
+		"pinned":         p.pinnedVersion != "",
+		"pinned_version": pinned,
+		"calibrated":     p.model.Calibration != nil,
+		"reload_count":   p.reloadCount,
+		"reloading":      p.reloadCall != nil || len(p.reloadQueue) > 0,
+		"last_reload_at": lastReload,
+		"loading":        p.loading,
+		"load_error":     loadErr,
+		"session":        p.sessionJSON(),
+		"health": map[string]any{
+			"bad_outputs":       p.badOutputs,
+			"bad_outputs_total": p.badOutputsTotal,
+			"healthy":           p.badOutputs < MaxBadOutputs,
+		},
 	})
 }
+
+// sessionJSON reports the configured ORT options and the provider in use
+// (which differs from the requested one after a fallback). Callers hold p.mu.
+func (p *ORTPredictor) sessionJSON() map[string]any {
+	requested := p.sessionCfg.Provider
+	if requested == "" {
+		requested = ProviderCPU
+	}
+	var active any = nil
+	if p.provider != "" {
+		active = p.provider
+	}
+	return map[string]any{
+		"intra_op_threads":   p.sessionCfg.IntraOpThreads,
+		"inter_op_threads":   p.sessionCfg.InterOpThreads,
+		"requested_provider": requested,
+		"provider":           active,
+	}
+}
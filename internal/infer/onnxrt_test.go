@@ -0,0 +1,189 @@
+package infer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestModelVersionDoesNotWaitForPrediction(t *testing.T) {
+	p := NewORTPredictor(t.TempDir())
+	if v := p.ModelVersion(); v != "" {
+		t.Fatalf("ModelVersion = %q before a load", v)
+	}
+	p.active.Store(&ModelInfo{Version: "v3"})
+
+	// A prediction holds mu for its whole preprocess and inference
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	got := make(chan string, 1)
+	go func() { got <- p.ModelVersion() }()
+	select {
+	case v := <-got:
+		if v != "v3" {
+			t.Fatalf("ModelVersion = %q, want v3", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ModelVersion blocked on the prediction lock")
+	}
+}
+
+func TestReloadUnknownVersion(t *testing.T) {
+	modelsDir := t.TempDir()
+	writeModel(t, modelsDir, "v1", true)
+	p := NewORTPredictor(modelsDir)
+
+	err := p.Reload(modelsDir, "v99")
+	if !errors.Is(err, ErrModelNotFound) {
+		t.Fatalf("Reload(v99) = %v, want ErrModelNotFound", err)
+	}
+	if err := p.LoadError(); err != nil {
+		t.Fatalf("LoadError = %v; a bad request must not mark the predictor broken", err)
+	}
+}
+
+func TestConcurrentReloadsShareOneRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []string // of the callers arriving during the first run
+		wantRuns int
+	}{
+		{"same version", []string{"", "", "", "", "", "", "", "", ""}, 1},
+		{"pinned version", []string{"v2", "v2", "v2"}, 2},
+		{"mixed", []string{"", "v2", "", "v2", "v3", ""}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewORTPredictor(t.TempDir())
+			started := make(chan string, 16)
+			release := make(chan struct{})
+			var mu sync.Mutex
+			runs, inside := 0, 0
+			p.reloadFn = func(_, version string) error {
+				mu.Lock()
+				runs++
+				inside++
+				n, overlap := runs, inside > 1
+				mu.Unlock()
+				if overlap {
+					t.Error("reloads overlap")
+				}
+				started <- version
+				<-release
+				mu.Lock()
+				inside--
+				mu.Unlock()
+				return fmt.Errorf("run %d (%s)", n, version)
+			}
+
+			errs := make(chan error, len(tt.versions)+1)
+			reload := func(version string) {
+				err := p.Reload("", version)
+				if !strings.Contains(fmt.Sprint(err), "("+version+")") {
+					t.Errorf("Reload(%q) got the result of another version: %v", version, err)
+				}
+				errs <- err
+			}
+			go reload("")
+			<-started
+			if !modelJSON(t, p)["reloading"].(bool) {
+				t.Error("reloading = false during a reload")
+			}
+			for _, v := range tt.versions {
+				go reload(v)
+			}
+			time.Sleep(50 * time.Millisecond) // all callers waiting
+			close(release)
+			for range len(tt.versions) + 1 {
+				<-errs
+			}
+
+			if runs != tt.wantRuns {
+				t.Fatalf("%d reloads ran, want %d", runs, tt.wantRuns)
+			}
+			m := modelJSON(t, p)
+			if m["reloading"].(bool) || m["last_reload_at"] == nil || m["load_error"] == nil {
+				t.Fatalf("after the reloads: %v", m)
+			}
+		})
+	}
+}
+
+func modelJSON(t *testing.T, p *ORTPredictor) map[string]any {
+	t.Helper()
+	data, err := p.ModelJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestSoftmaxNonFinite(t *testing.T) {
+	nan, inf := float32(math.NaN()), float32(math.Inf(1))
+	tests := []struct {
+		name     string
+		logits   []float32
+		want     []float32
+		finite   bool // logits pass checkFinite
+		wantBest int
+	}{
+		{"plain", []float32{0, 0}, []float32{0.5, 0.5}, true, 0},
+		{"large", []float32{1000, 0}, []float32{1, 0}, true, 0},
+		{"nan", []float32{nan, 1}, []float32{0, 1}, false, 1},
+		{"all nan", []float32{nan, nan}, []float32{0, 0}, false, 0},
+		{"+inf", []float32{1, inf, inf}, []float32{0, 0.5, 0.5}, false, 1},
+		{"-inf", []float32{float32(math.Inf(-1)), 0}, []float32{0, 1}, false, 1},
+		{"empty", nil, []float32{}, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := softmax(tt.logits)
+			if len(got) != len(tt.want) {
+				t.Fatalf("softmax(%v) = %v", tt.logits, got)
+			}
+			for i := range got {
+				if math.Abs(float64(got[i]-tt.want[i])) > 1e-6 {
+					t.Fatalf("softmax(%v) = %v, want %v", tt.logits, got, tt.want)
+				}
+			}
+			if err := checkFinite(got); err != nil {
+				t.Fatalf("softmax output not finite: %v", err)
+			}
+			err := checkFinite(tt.logits)
+			if (err == nil) != tt.finite || (err != nil && !errors.Is(err, ErrBadModelOutput)) {
+				t.Fatalf("checkFinite(%v) = %v", tt.logits, err)
+			}
+			if len(got) > 0 {
+				if best := argmax(tt.logits); best != tt.wantBest {
+					t.Fatalf("argmax(%v) = %d, want %d", tt.logits, best, tt.wantBest)
+				}
+			}
+		})
+	}
+}
+
+func TestHealthAfterBadOutputs(t *testing.T) {
+	p := NewORTPredictor(t.TempDir())
+	p.model = &ModelInfo{Version: "v3"}
+	for i := 1; i <= MaxBadOutputs; i++ {
+		if err := p.Health(); err != nil {
+			t.Fatalf("unhealthy after %d bad outputs: %v", i-1, err)
+		}
+		p.badOutput(checkFinite([]float32{float32(math.NaN())}))
+	}
+	if err := p.Health(); err == nil || !strings.Contains(err.Error(), "v3") {
+		t.Fatalf("Health = %v after %d bad outputs", err, MaxBadOutputs)
+	}
+	if m := modelJSON(t, p)["health"].(map[string]any); m["healthy"] != false || m["bad_outputs_total"] != float64(MaxBadOutputs) {
+		t.Fatalf("health %v", m)
+	}
+}
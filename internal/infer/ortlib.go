@@ -0,0 +1,128 @@
+package infer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// ortLibraryName is the file name of the ONNX Runtime library on this OS.
+func ortLibraryName() string {
+	switch runtime.GOOS {
+	case "windows":
+		return "onnxruntime.dll"
+	case "darwin":
+		return "libonnxruntime.dylib"
+	default:
+		return "libonnxruntime.so"
+	}
+}
+
+// ortLibraryDirs are the directories searched for the library, most specific
+// first: lib/ next to the binary (bundled releases), the binary's directory,
+// then the usual install locations for this OS and architecture.
+func ortLibraryDirs() []string {
+	var dirs []string
+	if exe, err := os.Executable(); err == nil {
+		if exe, err := filepath.EvalSymlinks(exe); err == nil {
+			dirs = append(dirs, filepath.Join(filepath.Dir(exe), "lib"), filepath.Dir(exe))
+		}
+	}
+	switch runtime.GOOS {
+	case "windows":
+		dirs = append(dirs, "lib", ".")
+	case "darwin":
+		dirs = append(dirs, "/opt/homebrew/lib", "/usr/local/lib", "/opt/onnxruntime/lib")
+	default:
+		// Debian-style multiarch directory first
+		switch runtime.GOARCH {
+		case "amd64":
+			dirs = append(dirs, "/usr/lib/x86_64-linux-gnu")
+		case "arm64":
+			dirs = append(dirs, "/usr/lib/aarch64-linux-gnu")
+		case "arm":
+			dirs = append(dirs, "/usr/lib/arm-linux-gnueabihf")
+		}
+		dirs = append(dirs, "/usr/local/lib", "/usr/lib", "/usr/lib64", "/opt/onnxruntime/lib")
+	}
+	return dirs
+}
+
+// ortLibraryCandidates returns the library files to look for: the plain name
+// in each directory, followed by versioned ones (libonnxruntime.so.1.20.1)
+// for installs without the unversioned symlink.
+func ortLibraryCandidates() []string {
+	name := ortLibraryName()
+	var out []string
+	for _, dir := range ortLibraryDirs() {
+		out = append(out, filepath.Join(dir, name))
+		if runtime.GOOS != "windows" {
+			versioned, _ := filepath.Glob(filepath.Join(dir, strings.TrimSuffix(name, filepath.Ext(name))+".*"))
+			for _, v := range versioned {
+				if v != filepath.Join(dir, name) {
+					out = append(out, v)
+				}
+			}
+		}
+	}
+	return out
+}
+
+var (
+	ortLibMu  sync.Mutex
+	ortLibErr error // last failed discovery, for RuntimeInfo
+)
+
+// initORTLibrary loads the ONNX Runtime library and initializes the
+// environment. SKYCLF_ORT_LIB is tried first, then the files from
+// ortLibraryCandidates that exist, then the bare name for the dynamic
+// loader's own search path (LD_LIBRARY_PATH and friends). A file that fails
+// to load (wrong architecture, missing dependency) is skipped. The error
+// lists every attempt.
+func initORTLibrary() error {
+	ortLibMu.Lock()
+	defer ortLibMu.Unlock()
+	if ort.IsInitialized() {
+		return nil
+	}
+
+	var paths []string
+	if p := os.Getenv("SKYCLF_ORT_LIB"); p != "" {
+		paths = append(paths, p)
+	}
+	for _, p := range ortLibraryCandidates() {
+		if _, err := os.Stat(p); err == nil && !slices.Contains(paths, p) {
+			paths = append(paths, p)
+		}
+	}
+	paths = append(paths, ortLibraryName())
+
+	var tried []string
+	for _, p := range paths {
+		ort.SetSharedLibraryPath(p)
+		err := ort.InitializeEnvironment()
+		if err == nil {
+			ortLibrary = p
+			ortLibErr = nil
+			return nil
+		}
+		tried = append(tried, fmt.Sprintf("  %s: %v", p, err))
+	}
+
+	ortLibErr = fmt.Errorf("onnxruntime init: no usable %s for %s/%s; set SKYCLF_ORT_LIB to its path or put it in lib/ next to the binary. Searched %s. Tried:\n%s",
+		ortLibraryName(), runtime.GOOS, runtime.GOARCH, strings.Join(ortLibraryDirs(), ", "), strings.Join(tried, "\n"))
+	return ortLibErr
+}
+
+// ortLibraryError returns the error of the last failed library discovery.
+func ortLibraryError() error {
+	ortLibMu.Lock()
+	defer ortLibMu.Unlock()
+	return ortLibErr
+}
@@ -0,0 +1,63 @@
+package infer
+
+import (
+	"image"
+	"image/color"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+const overlayMaxDim = 512
+
+// RenderSaliency draws s as a red heat overlay on top of the image at imagePath,
+// masked and cropped like the input the scores were computed on and scaled so
+// the longer side is at most 512px. Only positive drops are shown.
+func RenderSaliency(imagePath string, s *Saliency) (image.Image, error) {
+	src, err := LoadView(imagePath, s.Preprocess)
+	if err != nil {
+		return nil, err
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > overlayMaxDim || h > overlayMaxDim {
+		if w >= h {
+			w, h = overlayMaxDim, h*overlayMaxDim/w
+		} else {
+			w, h = w*overlayMaxDim/h, overlayMaxDim
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	xdraw.BiLinear.Scale(dst, dst.Bounds(), src, b, xdraw.Src, nil)
+
+	var maxScore float32
+	for _, row := range s.Scores {
+		for _, v := range row {
+			if v > maxScore {
+				maxScore = v
+			}
+		}
+	}
+	if maxScore <= 0 {
+		return dst, nil
+	}
+
+	for y := 0; y < h; y++ {
+		row := y * s.Grid / h
+		for x := 0; x < w; x++ {
+			v := s.Scores[row][x*s.Grid/w]
+			if v <= 0 {
+				continue
+			}
+			a := float32(0.6) * v / maxScore
+			c := dst.RGBAAt(x, y)
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(float32(c.R)*(1-a) + 255*a),
+				G: uint8(float32(c.G) * (1 - a)),
+				B: uint8(float32(c.B) * (1 - a)),
+				A: 255,
+			})
+		}
+	}
+	return dst, nil
+}
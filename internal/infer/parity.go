@@ -0,0 +1,161 @@
+package infer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParityDir is the directory inside a model version that holds fixture
+// images with the tensors the trainer's transform made of them: for each
+// image (name.jpg, .png or .webp) a name.npy (float32) or name.f32 (raw
+// little-endian float32), shaped [1,3,224,224] or [3,224,224].
+const ParityDir = "parity"
+
+// DefaultParityThreshold is the largest absolute difference, in normalized
+// units, a parity check accepts per tensor element. Bilinear resizing in Go
+// and in PIL round differently, so exact equality is not expected.
+const DefaultParityThreshold = 0.1
+
+// ParityResult compares one fixture image.
+type ParityResult struct {
+	Image   string  `json:"image"`
+	MaxAbs  float64 `json:"max_abs"`
+	MeanAbs float64 `json:"mean_abs"`
+	Pass    bool    `json:"pass"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// ParityReport is the outcome of CheckParity.
+type ParityReport struct {
+	Dir       string         `json:"dir"`
+	Threshold float64        `json:"threshold"`
+	Results   []ParityResult `json:"results"`
+	Pass      bool           `json:"pass"` // every image passed
+}
+
+// CheckParity runs LoadAndPreprocessNCHW on each fixture image in dir and
+// compares the result with its reference tensor. An image without a
+// reference is ignored; one that can't be read or compared fails.
+func CheckParity(dir string, threshold float64) (*ParityReport, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	rep := &ParityReport{Dir: dir, Threshold: threshold, Results: []ParityResult{}, Pass: true}
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.IsDir() || (ext != ".jpg" && ext != ".jpeg" && ext != ".png" && ext != ".webp") {
+			continue
+		}
+		base := filepath.Join(dir, strings.TrimSuffix(e.Name(), filepath.Ext(e.Name())))
+		ref, err := readReference(base)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		res := ParityResult{Image: e.Name()}
+		if err == nil {
+			var got []float32
+			if got, err = LoadAndPreprocessNCHW(filepath.Join(dir, e.Name())); err == nil {
+				res.MaxAbs, res.MeanAbs, err = tensorDiff(got, ref)
+			}
+		}
+		if err != nil {
+			res.Error = err.Error()
+		} else {
+			res.Pass = res.MaxAbs <= threshold
+		}
+		rep.Pass = rep.Pass && res.Pass
+		rep.Results = append(rep.Results, res)
+	}
+	sort.Slice(rep.Results, func(i, j int) bool { return rep.Results[i].Image < rep.Results[j].Image })
+	return rep, nil
+}
+
+// tensorDiff returns the largest and the mean absolute difference.
+func tensorDiff(got, want []float32) (maxAbs, meanAbs float64, err error) {
+	if len(got) != len(want) {
+		return 0, 0, fmt.Errorf("reference has %d values, want %d", len(want), len(got))
+	}
+	var sum float64
+	for i := range got {
+		d := math.Abs(float64(got[i]) - float64(want[i]))
+		sum += d
+		maxAbs = max(maxAbs, d)
+	}
+	return maxAbs, sum / float64(len(got)), nil
+}
+
+// readReference reads base.npy or, failing that, base.f32.
+func readReference(base string) ([]float32, error) {
+	if data, err := os.ReadFile(base + ".npy"); err == nil {
+		return parseNPY(data)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	data, err := os.ReadFile(base + ".f32")
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("%s.f32: size %d is not a multiple of 4", filepath.Base(base), len(data))
+	}
+	return decodeFloat32LE(data), nil
+}
+
+var (
+	npyMagic = []byte("\x93NUMPY")
+	npyDescr = regexp.MustCompile(`'descr':\s*'([^']*)'`)
+	npyOrder = regexp.MustCompile(`'fortran_order':\s*(True|False)`)
+)
+
+// parseNPY decodes a NumPy .npy file holding little-endian float32 in C order.
+func parseNPY(data []byte) ([]float32, error) {
+	if len(data) < 10 || !bytes.HasPrefix(data, npyMagic) {
+		return nil, errors.New("npy: bad magic")
+	}
+	var hlen, off int
+	switch data[6] {
+	case 1:
+		hlen, off = int(binary.LittleEndian.Uint16(data[8:10])), 10
+	case 2, 3:
+		if len(data) < 12 {
+			return nil, errors.New("npy: truncated header")
+		}
+		hlen, off = int(binary.LittleEndian.Uint32(data[8:12])), 12
+	default:
+		return nil, fmt.Errorf("npy: unsupported version %d", data[6])
+	}
+	if len(data) < off+hlen {
+		return nil, errors.New("npy: truncated header")
+	}
+	header := string(data[off : off+hlen])
+	if m := npyDescr.FindStringSubmatch(header); m == nil || (m[1] != "<f4" && m[1] != "float32") {
+		return nil, fmt.Errorf("npy: want little-endian float32 (<f4), header %s", strconv.Quote(strings.TrimSpace(header)))
+	}
+	if m := npyOrder.FindStringSubmatch(header); m != nil && m[1] == "True" {
+		return nil, errors.New("npy: fortran order not supported")
+	}
+	body := data[off+hlen:]
+	if len(body)%4 != 0 {
+		return nil, errors.New("npy: truncated data")
+	}
+	return decodeFloat32LE(body), nil
+}
+
+func decodeFloat32LE(b []byte) []float32 {
+	out := make([]float32, len(b)/4)
+	for i := range out {
+		out[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[i*4:]))
+	}
+	return out
+}
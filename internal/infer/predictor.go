@@ -9,6 +9,51 @@ type Prediction struct {
 	ModelTask   string             `json:"task"`
 	ModelVer    string             `json:"model_version"`
 	ModelPath   string             `json:"model_path"`
+
+	// Set only when the model has a calibration in meta.json
+	Calibrated  bool               `json:"calibrated,omitempty"`
+	Temperature float64            `json:"temperature,omitempty"`
+
+	// Raw (uncalibrated) logits in ClassNames order, only when requested via PredictOptions
+	Logits      []float32          `json:"logits,omitempty"`
+	ClassNames  []string           `json:"class_names,omitempty"`
+	// Classes sorted by probability (descending), only when requested via PredictOptions
+	TopK        []ClassProb        `json:"top_k,omitempty"`
+
+	// Time spent decoding/resizing the image and running the model
+	PreprocessMS float64 `json:"preprocess_ms,omitempty"`
+	InferenceMS  float64 `json:"inference_ms,omitempty"`
+}
+
+// ClassProb is one entry of a top-k list.
+type ClassProb struct {
+	Class string  `json:"class"`
+	Prob  float32 `json:"prob"`
+}
+
+// PredictOptions requests extra output from PredictImageOpts.
+type PredictOptions struct {
+	Logits bool // include raw logits (and their class order) in the Prediction
+	TopK   int  // include the k most probable classes; <= 0 disables, > classes means all
+
+	Preprocess *PreprocessConfig // overrides the predictor's mask/crop for this call
+}
+
+// DetailedPredictor is implemented by predictors that can return logits and top-k lists.
+type DetailedPredictor interface {
+	PredictImageOpts(ctx context.Context, imagePath string, opts PredictOptions) (*Prediction, error)
+}
+
+// ModelReporter is implemented by predictors that can describe the model
+// answering their predictions for /api/models.
+type ModelReporter interface {
+	ModelJSON() ([]byte, error)
+}
+
+// VersionReporter is implemented by predictors that know which model version
+// answers their predictions ("" while none is loaded).
+type VersionReporter interface {
+	ModelVersion() string
 }
 
 type Predictor interface {
@@ -5,20 +5,98 @@ import (
 	"image"
 	"image/color"
 	_ "image/jpeg"
+	_ "image/png"
 	"os"
 
 	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
 )
 
 const (
 	imgSize = 224
 )
 
+// InputSize is the width and height of the model input.
+const InputSize = imgSize
+
 // ImageNet normalization (matches your training)
 var mean = [3]float32{0.485, 0.456, 0.406}
 var std = [3]float32{0.229, 0.224, 0.225}
 
-func LoadAndPreprocessNCHW(path string) ([]float32, error) {
+// Rect is a crop rectangle in source image pixels.
+type Rect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// Circle is a circular mask in source image pixels; everything outside it is blacked out.
+type Circle struct {
+	CX int `json:"cx"`
+	CY int `json:"cy"`
+	R  int `json:"r"`
+}
+
+// PreprocessConfig restricts the part of the frame the model sees, e.g. to hide
+// trees and buildings at the horizon. Both are given in source image coordinates;
+// the crop is applied after masking. The zero value uses the full frame.
+type PreprocessConfig struct {
+	Crop *Rect   `json:"crop,omitempty"`
+	Mask *Circle `json:"mask,omitempty"`
+}
+
+// IsZero reports whether cfg leaves the image unchanged.
+func (cfg PreprocessConfig) IsZero() bool {
+	return cfg.Crop == nil && cfg.Mask == nil
+}
+
+// Validate checks that the geometry is well-formed (bounds are checked per image).
+func (cfg PreprocessConfig) Validate() error {
+	if c := cfg.Crop; c != nil && (c.X < 0 || c.Y < 0 || c.W <= 0 || c.H <= 0) {
+		return fmt.Errorf("crop: x and y must be >= 0, w and h > 0")
+	}
+	if m := cfg.Mask; m != nil && (m.CX < 0 || m.CY < 0 || m.R <= 0) {
+		return fmt.Errorf("mask: cx and cy must be >= 0, r > 0")
+	}
+	return nil
+}
+
+// ApplyView masks and crops src according to cfg, at source resolution.
+func ApplyView(src image.Image, cfg PreprocessConfig) (image.Image, error) {
+	if cfg.IsZero() {
+		return src, nil
+	}
+	sb := src.Bounds()
+	view := sb
+	if c := cfg.Crop; c != nil {
+		view = image.Rect(sb.Min.X+c.X, sb.Min.Y+c.Y, sb.Min.X+c.X+c.W, sb.Min.Y+c.Y+c.H).Intersect(sb)
+		if view.Empty() {
+			return nil, fmt.Errorf("crop lies outside the %dx%d image", sb.Dx(), sb.Dy())
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, view.Dx(), view.Dy()))
+	xdraw.Draw(dst, dst.Bounds(), src, view.Min, xdraw.Src)
+
+	if m := cfg.Mask; m != nil {
+		r2 := m.R * m.R
+		black := color.RGBA{A: 255}
+		for y := 0; y < view.Dy(); y++ {
+			dy := view.Min.Y - sb.Min.Y + y - m.CY
+			for x := 0; x < view.Dx(); x++ {
+				dx := view.Min.X - sb.Min.X + x - m.CX
+				if dx*dx+dy*dy > r2 {
+					dst.SetRGBA(x, y, black)
+				}
+			}
+		}
+	}
+	return dst, nil
+}
+
+// LoadView decodes the image at path and applies cfg (see ApplyView).
+func LoadView(path string, cfg PreprocessConfig) (image.Image, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -29,19 +107,49 @@ func LoadAndPreprocessNCHW(path string) ([]float32, error) {
 	if err != nil {
 		return nil, err
 	}
+	return ApplyView(src, cfg)
+}
 
-	// Resize to 224x224
+// ResizeToInput scales img to the model input size.
+func ResizeToInput(img image.Image) *image.RGBA {
 	dst := image.NewRGBA(image.Rect(0, 0, imgSize, imgSize))
-	xdraw.BiLinear.Scale(dst, dst.Bounds(), src, src.Bounds(), xdraw.Over, nil)
+	xdraw.BiLinear.Scale(dst, dst.Bounds(), img, img.Bounds(), xdraw.Over, nil)
+	return dst
+}
 
-	// NCHW: [1,3,224,224]
+func LoadAndPreprocessNCHW(path string) ([]float32, error) {
+	return LoadAndPreprocessNCHWConfig(path, PreprocessConfig{})
+}
+
+// LoadAndPreprocessNCHWConfig is LoadAndPreprocessNCHW with a mask/crop applied first.
+func LoadAndPreprocessNCHWConfig(path string, cfg PreprocessConfig) ([]float32, error) {
+	input, err := LoadInput(path, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NormalizeNCHW(input), nil
+}
+
+// LoadInput decodes the image at path, applies cfg and scales it to the model
+// input size: what the model sees, before normalization.
+func LoadInput(path string, cfg PreprocessConfig) (*image.RGBA, error) {
+	src, err := LoadView(path, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return ResizeToInput(src), nil
+}
+
+// NormalizeNCHW turns a model input image (see LoadInput) into the normalized
+// [1,3,224,224] tensor the model takes.
+func NormalizeNCHW(img *image.RGBA) []float32 {
 	out := make([]float32, 1*3*imgSize*imgSize)
 	hw := imgSize * imgSize
 
 	// channel-first
 	for y := 0; y < imgSize; y++ {
 		for x := 0; x < imgSize; x++ {
-			c := dst.At(x, y)
+			c := img.At(x, y)
 			r8, g8, b8, _ := color.RGBAModel.Convert(c).RGBA()
 			// r8 is 0..65535
 			r := float32(r8) / 65535.0
@@ -59,9 +167,24 @@ func LoadAndPreprocessNCHW(path string) ([]float32, error) {
 			out[2*hw+i] = b
 		}
 	}
+	return out
+}
 
-	if len(out) != 3*imgSize*imgSize {
-		return nil, fmt.Errorf("unexpected tensor size: %d", len(out))
+// DenormalizeNCHW undoes NormalizeNCHW for display, clamping values the
+// normalization can't have produced.
+func DenormalizeNCHW(x []float32) (*image.RGBA, error) {
+	hw := imgSize * imgSize
+	if len(x) != 3*hw {
+		return nil, fmt.Errorf("unexpected tensor size: %d", len(x))
+	}
+	img := image.NewRGBA(image.Rect(0, 0, imgSize, imgSize))
+	var px [3]uint8
+	for i := 0; i < hw; i++ {
+		for c := range px {
+			v := (x[c*hw+i]*std[c] + mean[c]) * 255
+			px[c] = uint8(min(max(v+0.5, 0), 255))
+		}
+		img.SetRGBA(i%imgSize, i/imgSize, color.RGBA{R: px[0], G: px[1], B: px[2], A: 255})
 	}
-	return out, nil
+	return img, nil
 }
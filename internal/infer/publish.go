@@ -0,0 +1,190 @@
+package infer
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Model directories are published atomically: the trainer writes a new version
+// into skystate/.pending-vN, creates the DoneMarker file in it once every file is
+// complete, and the server renames it to skystate/vN (PublishPending). Version
+// directories without the marker are never loaded or served, so a half-written
+// model.onnx can't be picked up by a concurrent scan.
+const (
+	DoneMarker    = "DONE"
+	PendingPrefix = ".pending-"
+
+	// migratedMarker in skystate/ records that MigrateUnmarked has run.
+	migratedMarker = ".done-markers"
+)
+
+// IsPublished reports whether dir carries the DoneMarker.
+func IsPublished(dir string) bool {
+	fi, err := os.Stat(filepath.Join(dir, DoneMarker))
+	return err == nil && !fi.IsDir()
+}
+
+// CompareVersions orders version names by their number, so v10 comes after
+// v9; names with the same (or no) number compare as strings.
+func CompareVersions(a, b string) int {
+	na, oka := versionNumber(a)
+	nb, okb := versionNumber(b)
+	switch {
+	case oka && okb && na != nb:
+		return cmp.Compare(na, nb)
+	case oka != okb:
+		if oka {
+			return -1 // numbered versions first
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// versionNumber parses the digits following the "v" of a version name.
+func versionNumber(v string) (int, bool) {
+	digits := strings.TrimPrefix(v, "v")
+	if i := strings.IndexFunc(digits, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+		digits = digits[:i]
+	}
+	n, err := strconv.Atoi(digits)
+	return n, err == nil
+}
+
+// ListVersions returns the published skystate model versions, oldest first
+// (see CompareVersions), and separately the version directories that are
+// skipped for lack of a DoneMarker.
+func ListVersions(modelsDir string) (published, incomplete []string, err error) {
+	root := filepath.Join(modelsDir, "skystate")
+	ents, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("read models root: %w", err)
+	}
+
+	for _, e := range ents {
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), "v") {
+			continue
+		}
+		if IsPublished(filepath.Join(root, e.Name())) {
+			published = append(published, e.Name())
+		} else {
+			incomplete = append(incomplete, e.Name())
+		}
+	}
+	slices.SortFunc(published, CompareVersions)
+	slices.SortFunc(incomplete, CompareVersions)
+	return published, incomplete, nil
+}
+
+// PublishPending renames every finished pending directory (one containing the
+// DoneMarker) into place and returns the versions it published. Pending
+// directories without the marker are still being written and are left alone;
+// an existing version is never overwritten.
+func PublishPending(modelsDir string) ([]string, error) {
+	root := filepath.Join(modelsDir, "skystate")
+	ents, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read models root: %w", err)
+	}
+
+	var published []string
+	for _, e := range ents {
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), PendingPrefix) {
+			continue
+		}
+		version := strings.TrimPrefix(e.Name(), PendingPrefix)
+		if !ValidVersionName(version) {
+			return published, fmt.Errorf("pending model %q: invalid version name", e.Name())
+		}
+		src := filepath.Join(root, e.Name())
+		if !IsPublished(src) {
+			continue
+		}
+		dst := filepath.Join(root, version)
+		if _, err := os.Stat(dst); err == nil {
+			return published, fmt.Errorf("publish %s: version already exists", version)
+		}
+		if err := os.Rename(src, dst); err != nil {
+			return published, fmt.Errorf("publish %s: %w", version, err)
+		}
+		published = append(published, version)
+	}
+	return published, nil
+}
+
+// MigrateUnmarked marks the version directories that predate the DoneMarker
+// as published, once per models directory: the versions of an upgraded
+// install stay loadable, while markerless directories appearing later are
+// still treated as incomplete. It returns the versions it marked.
+func MigrateUnmarked(modelsDir string) ([]string, error) {
+	root := filepath.Join(modelsDir, "skystate")
+	if _, err := os.Stat(filepath.Join(root, migratedMarker)); err == nil {
+		return nil, nil
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("create models root: %w", err)
+	}
+	_, incomplete, err := ListVersions(modelsDir)
+	if err != nil {
+		return nil, err
+	}
+	var marked []string
+	for _, v := range incomplete {
+		dir := filepath.Join(root, v)
+		if _, err := os.Stat(filepath.Join(dir, "model.onnx")); err != nil {
+			continue
+		}
+		if err := writeDoneMarker(dir); err != nil {
+			return marked, fmt.Errorf("mark %s: %w", v, err)
+		}
+		marked = append(marked, v)
+	}
+	if err := os.WriteFile(filepath.Join(root, migratedMarker), nil, 0o644); err != nil {
+		return marked, fmt.Errorf("record marker migration: %w", err)
+	}
+	return marked, nil
+}
+
+// MarkCompleted marks the version directories with a model.onnx written at
+// or after since that lack the DoneMarker as published, and returns them.
+// Call it once a training run has exited successfully, for trainers that
+// write skystate/vN directly instead of a pending directory.
+func MarkCompleted(modelsDir string, since time.Time) ([]string, error) {
+	root := filepath.Join(modelsDir, "skystate")
+	_, incomplete, err := ListVersions(modelsDir)
+	if err != nil {
+		return nil, err
+	}
+	var marked []string
+	for _, v := range incomplete {
+		dir := filepath.Join(root, v)
+		if !ValidVersionName(v) {
+			continue
+		}
+		fi, err := os.Stat(filepath.Join(dir, "model.onnx"))
+		if err != nil || fi.ModTime().Before(since) {
+			continue
+		}
+		if err := writeDoneMarker(dir); err != nil {
+			return marked, fmt.Errorf("mark %s: %w", v, err)
+		}
+		marked = append(marked, v)
+	}
+	return marked, nil
+}
+
+func writeDoneMarker(dir string) error {
+	return os.WriteFile(filepath.Join(dir, DoneMarker), nil, 0o644)
+}
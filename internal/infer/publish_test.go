@@ -0,0 +1,158 @@
+package infer
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// writeModel creates skystate/<name> with a model and classes, and the
+// DoneMarker if done.
+func writeModel(t *testing.T, modelsDir, name string, done bool) string {
+	t.Helper()
+	dir := filepath.Join(modelsDir, "skystate", name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"model.onnx":   "onnx",
+		"classes.json": `{"clear": 0, "cloudy": 1}`,
+	}
+	if done {
+		files[DoneMarker] = ""
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestMigrateUnmarkedKeepsPreSeriesModels(t *testing.T) {
+	modelsDir := t.TempDir()
+	writeModel(t, modelsDir, "v1", false)
+	writeModel(t, modelsDir, "v2", false)
+	// A version directory without a model is not a model
+	if err := os.MkdirAll(filepath.Join(modelsDir, "skystate", "v3"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	marked, err := MigrateUnmarked(modelsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"v1", "v2"}; !slices.Equal(marked, want) {
+		t.Fatalf("marked %v, want %v", marked, want)
+	}
+	mi, err := FindSkyStateModel(modelsDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mi == nil || mi.Version != "v2" {
+		t.Fatalf("latest model = %+v, want v2", mi)
+	}
+
+	// After the migration, markerless directories are incomplete again
+	writeModel(t, modelsDir, "v4", false)
+	if marked, err := MigrateUnmarked(modelsDir); err != nil || len(marked) != 0 {
+		t.Fatalf("second migration marked %v, %v", marked, err)
+	}
+	published, incomplete, err := ListVersions(modelsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(published, []string{"v1", "v2"}) || !slices.Equal(incomplete, []string{"v3", "v4"}) {
+		t.Fatalf("published %v, incomplete %v", published, incomplete)
+	}
+}
+
+func TestMigrateUnmarkedWithoutModels(t *testing.T) {
+	modelsDir := t.TempDir()
+	if marked, err := MigrateUnmarked(modelsDir); err != nil || len(marked) != 0 {
+		t.Fatalf("marked %v, %v", marked, err)
+	}
+	// A trainer writing in place later must not be picked up half-written
+	writeModel(t, modelsDir, "v1", false)
+	if mi, err := FindSkyStateModel(modelsDir, "v1"); err != nil || mi != nil {
+		t.Fatalf("loaded unmarked v1: %+v, %v", mi, err)
+	}
+}
+
+func TestPartialVersionIsNeverLoaded(t *testing.T) {
+	modelsDir := t.TempDir()
+	if _, err := MigrateUnmarked(modelsDir); err != nil {
+		t.Fatal(err)
+	}
+	writeModel(t, modelsDir, "v1", true)
+	writeModel(t, modelsDir, PendingPrefix+"v2", false) // still being written
+
+	published, err := PublishPending(modelsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(published) != 0 {
+		t.Fatalf("published unfinished %v", published)
+	}
+	if mi, err := FindSkyStateModel(modelsDir, ""); err != nil || mi == nil || mi.Version != "v1" {
+		t.Fatalf("latest model = %+v, %v; want v1", mi, err)
+	}
+
+	// The marker makes it publishable
+	pending := filepath.Join(modelsDir, "skystate", PendingPrefix+"v2")
+	if err := os.WriteFile(filepath.Join(pending, DoneMarker), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if published, err = PublishPending(modelsDir); err != nil || !slices.Equal(published, []string{"v2"}) {
+		t.Fatalf("published %v, %v; want [v2]", published, err)
+	}
+	if mi, err := FindSkyStateModel(modelsDir, ""); err != nil || mi == nil || mi.Version != "v2" {
+		t.Fatalf("latest model = %+v, %v; want v2", mi, err)
+	}
+}
+
+func TestMarkCompleted(t *testing.T) {
+	modelsDir := t.TempDir()
+	if _, err := MigrateUnmarked(modelsDir); err != nil {
+		t.Fatal(err)
+	}
+	old := writeModel(t, modelsDir, "v1", false)
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(old, "model.onnx"), past, past); err != nil {
+		t.Fatal(err)
+	}
+	runStart := time.Now().Add(-time.Minute)
+	writeModel(t, modelsDir, "v2", false)
+
+	marked, err := MarkCompleted(modelsDir, runStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(marked, []string{"v2"}) {
+		t.Fatalf("marked %v, want [v2]", marked)
+	}
+	if IsPublished(old) {
+		t.Fatal("marked a version the run didn't write")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	vers := []string{"v10", "v2", "vx", "v9", "v1", "v10b", "v9_ft"}
+	slices.SortFunc(vers, CompareVersions)
+	if want := []string{"v1", "v2", "v9", "v9_ft", "v10", "v10b", "vx"}; !slices.Equal(vers, want) {
+		t.Fatalf("sorted %v, want %v", vers, want)
+	}
+
+	modelsDir := t.TempDir()
+	if _, err := MigrateUnmarked(modelsDir); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"v9", "v10", "v11"} {
+		writeModel(t, modelsDir, v, true)
+	}
+	if mi, err := FindSkyStateModel(modelsDir, ""); err != nil || mi == nil || mi.Version != "v11" {
+		t.Fatalf("latest model = %+v, %v; want v11", mi, err)
+	}
+}
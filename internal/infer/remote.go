@@ -0,0 +1,244 @@
+package infer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// remoteRetries is how often a failed request to the remote instance is
+// retried (network errors and 5xx only).
+const remoteRetries = 2
+
+// RemotePredictor forwards inference to another SkyClf instance's
+// POST /api/predict, for devices that can't run ONNX Runtime themselves.
+// The mask/crop configured on the remote instance applies.
+type RemotePredictor struct {
+	baseURL string
+	client  *http.Client
+
+	mu          sync.Mutex
+	version     string          // model version that answered last
+	remoteModel json.RawMessage // remote /api/models at the last check
+	checkedAt   time.Time
+	loadErr     error
+}
+
+// NewRemotePredictor creates a predictor for the instance at baseURL
+// (e.g. "http://skyclf:8080"); each request is limited to timeout.
+func NewRemotePredictor(baseURL string, timeout time.Duration) *RemotePredictor {
+	return &RemotePredictor{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Load checks that the remote instance answers; see Reload.
+func (p *RemotePredictor) Load() error {
+	log.Printf("[infer] using remote inference at %s", p.baseURL)
+	return p.Reload("", "")
+}
+
+// Reload doesn't load anything: the remote instance owns its models. It
+// re-checks that the remote answers and records which model it serves.
+func (p *RemotePredictor) Reload(modelsDir string, version string) error {
+	if version != "" {
+		return errors.New("model versions are selected on the remote instance")
+	}
+	raw, err := p.fetchModel(context.Background())
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.checkedAt = time.Now().UTC()
+	p.loadErr = err
+	if err != nil {
+		return err
+	}
+	p.remoteModel = raw
+	var m struct {
+		Active *string `json:"active"`
+	}
+	if json.Unmarshal(raw, &m) == nil && m.Active != nil {
+		p.version = *m.Active
+	}
+	return nil
+}
+
+func (p *RemotePredictor) fetchModel(ctx context.Context) (json.RawMessage, error) {
+	resp, err := p.do(ctx, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/api/models", nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("remote models: %s", resp.Status)
+	}
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("remote models: %w", err)
+	}
+	return raw, nil
+}
+
+// LoadError returns the error of the last failed availability check, or nil.
+func (p *RemotePredictor) LoadError() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.loadErr
+}
+
+func (p *RemotePredictor) Close() error {
+	p.client.CloseIdleConnections()
+	return nil
+}
+
+func (p *RemotePredictor) PredictImage(ctx context.Context, imagePath string) (*Prediction, error) {
+	return p.PredictImageOpts(ctx, imagePath, PredictOptions{})
+}
+
+// PredictImageOpts posts the image to the remote instance; logits, top-k and
+// mask/crop overrides are passed along as query parameters. Like a local
+// predictor without a model, it returns nil when the remote has none loaded.
+func (p *RemotePredictor) PredictImageOpts(ctx context.Context, imagePath string, opts PredictOptions) (*Prediction, error) {
+	data, err := os.ReadFile(imagePath)
+	if err != nil {
+		return nil, fmt.Errorf("read image: %w", err)
+	}
+
+	q := url.Values{}
+	if opts.Logits {
+		q.Set("detail", "1")
+	}
+	if opts.TopK > 0 {
+		q.Set("k", strconv.Itoa(opts.TopK))
+	}
+	if pre := opts.Preprocess; pre != nil {
+		if pre.IsZero() {
+			q.Set("preprocess", "none")
+		}
+		if c := pre.Crop; c != nil {
+			q.Set("crop", fmt.Sprintf("%d,%d,%d,%d", c.X, c.Y, c.W, c.H))
+		}
+		if m := pre.Mask; m != nil {
+			q.Set("mask", fmt.Sprintf("%d,%d,%d", m.CX, m.CY, m.R))
+		}
+	}
+	u := p.baseURL + "/api/predict"
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+
+	resp, err := p.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(data))
+		if err == nil {
+			req.Header.Set("Content-Type", "image/jpeg")
+		}
+		return req, err
+	})
+	if err != nil {
+		p.setErr(err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusServiceUnavailable:
+		return nil, nil // remote has no model (yet)
+	case resp.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("remote predict: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	var pred Prediction
+	if err := json.NewDecoder(resp.Body).Decode(&pred); err != nil {
+		return nil, fmt.Errorf("remote predict: %w", err)
+	}
+	p.mu.Lock()
+	p.version = pred.ModelVer
+	p.loadErr = nil
+	p.mu.Unlock()
+	return &pred, nil
+}
+
+// do sends the request built by newReq, retrying network errors and 5xx
+// responses other than 503 (which means "no model" and won't change soon).
+func (p *RemotePredictor) do(ctx context.Context, newReq func() (*http.Request, error)) (*http.Response, error) {
+	var lastErr error
+	for attempt := 0; attempt <= remoteRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Duration(attempt) * 250 * time.Millisecond):
+			}
+		}
+		req, err := newReq()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := p.client.Do(req)
+		if err != nil {
+			lastErr = fmt.Errorf("remote %s: %w", req.URL.Path, err)
+			continue
+		}
+		if resp.StatusCode >= 500 && resp.StatusCode != http.StatusServiceUnavailable {
+			resp.Body.Close()
+			lastErr = fmt.Errorf("remote %s: %s", req.URL.Path, resp.Status)
+			continue
+		}
+		return resp, nil
+	}
+	return nil, lastErr
+}
+
+func (p *RemotePredictor) setErr(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.loadErr = err
+}
+
+// ModelVersion returns the version the remote served at the last check or
+// prediction, or "" if it had none.
+func (p *RemotePredictor) ModelVersion() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.version
+}
+
+// ModelJSON describes the remote backend for /api/models: the version that
+// answered last and the remote's own /api/models response at the last check.
+func (p *RemotePredictor) ModelJSON() ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var active, loadErr, checkedAt any
+	if p.version != "" {
+		active = p.version
+	}
+	if p.loadErr != nil {
+		loadErr = p.loadErr.Error()
+	}
+	if !p.checkedAt.IsZero() {
+		checkedAt = p.checkedAt.Format(time.RFC3339)
+	}
+	return json.Marshal(map[string]any{
+		"backend":    "remote",
+		"remote_url": p.baseURL,
+		"active":     active,
+		"available":  p.loadErr == nil && !p.checkedAt.IsZero(),
+		"checked_at": checkedAt,
+		"load_error": loadErr,
+		"remote":     p.remoteModel,
+	})
+}
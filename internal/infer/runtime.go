@@ -0,0 +1,60 @@
+package infer
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// ortLibrary is the library initORTLibrary loaded.
+var ortLibrary string
+
+// RuntimeInfo describes the ONNX Runtime library the process has loaded.
+type RuntimeInfo struct {
+	Loaded  bool   `json:"loaded"` // false until a model load initialized it, and with the remote backend
+	Library string `json:"library,omitempty"`
+	Version string `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"` // why no library could be loaded
+}
+
+// Runtime reports the loaded ONNX Runtime library. Library is the file the
+// dynamic loader actually mapped where the OS tells (Linux), else the name
+// it was asked for.
+func Runtime() RuntimeInfo {
+	if !ort.IsInitialized() {
+		info := RuntimeInfo{}
+		if err := ortLibraryError(); err != nil {
+			info.Error = err.Error()
+		}
+		return info
+	}
+	lib := mappedLibrary("onnxruntime")
+	if lib == "" {
+		lib = ortLibrary
+	}
+	return RuntimeInfo{Loaded: true, Library: lib, Version: ort.GetVersion()}
+}
+
+// mappedLibrary returns the path of the first shared object mapped into the
+// process whose file name contains name, or "" if /proc/self/maps isn't available.
+func mappedLibrary(name string) string {
+	f, err := os.Open("/proc/self/maps")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 6 {
+			continue
+		}
+		path := strings.Join(fields[5:], " ")
+		if base := path[strings.LastIndexByte(path, '/')+1:]; strings.Contains(base, name) && strings.Contains(base, ".so") {
+			return path
+		}
+	}
+	return ""
+}
@@ -0,0 +1,71 @@
+package infer
+
+import (
+	"fmt"
+	"log"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// Execution providers accepted in SessionConfig.Provider.
+const (
+	ProviderCPU    = "cpu"
+	ProviderCUDA   = "cuda"
+	ProviderCoreML = "coreml"
+)
+
+// SessionConfig holds the ONNX Runtime options used for every session the
+// predictor creates. Zero thread counts leave the ORT default (all cores).
+type SessionConfig struct {
+	IntraOpThreads int    `json:"intra_op_threads"`
+	InterOpThreads int    `json:"inter_op_threads"`
+	Provider       string `json:"provider"` // "" or "cpu" = default CPU provider
+}
+
+// newSessionOptions builds ORT session options for cfg. When the requested
+// provider can't be enabled it logs a warning and returns CPU-only options;
+// the provider actually used is returned alongside.
+func newSessionOptions(cfg SessionConfig) (*ort.SessionOptions, string, error) {
+	opts, err := ort.NewSessionOptions()
+	if err != nil {
+		return nil, "", fmt.Errorf("create session options: %w", err)
+	}
+	if cfg.IntraOpThreads > 0 {
+		if err := opts.SetIntraOpNumThreads(cfg.IntraOpThreads); err != nil {
+			opts.Destroy()
+			return nil, "", fmt.Errorf("set intra-op threads: %w", err)
+		}
+	}
+	if cfg.InterOpThreads > 0 {
+		if err := opts.SetInterOpNumThreads(cfg.InterOpThreads); err != nil {
+			opts.Destroy()
+			return nil, "", fmt.Errorf("set inter-op threads: %w", err)
+		}
+	}
+
+	switch cfg.Provider {
+	case "", ProviderCPU:
+		return opts, ProviderCPU, nil
+	case ProviderCUDA:
+		err = appendCUDA(opts)
+	case ProviderCoreML:
+		err = opts.AppendExecutionProviderCoreMLV2(nil)
+	default:
+		err = fmt.Errorf("unknown execution provider %q", cfg.Provider)
+	}
+	if err != nil {
+		log.Printf("[infer] WARN: execution provider %s unavailable, falling back to cpu: %v", cfg.Provider, err)
+		opts.Destroy()
+		return newSessionOptions(SessionConfig{IntraOpThreads: cfg.IntraOpThreads, InterOpThreads: cfg.InterOpThreads})
+	}
+	return opts, cfg.Provider, nil
+}
+
+func appendCUDA(opts *ort.SessionOptions) error {
+	cuda, err := ort.NewCUDAProviderOptions()
+	if err != nil {
+		return err
+	}
+	defer cuda.Destroy()
+	return opts.AppendExecutionProviderCUDA(cuda)
+}
@@ -0,0 +1,73 @@
+package infer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// MetricsFile is written by the trainer image into each version it produces.
+const MetricsFile = "metrics.json"
+
+// TrainingMetrics are the learning curves of the run that produced a version,
+// one entry per epoch, and the validation metrics of the saved model:
+//
+//	{"train_loss": [...], "train_accuracy": [...], "val_loss": [...],
+//	 "val_accuracy": [...], "final": {"accuracy": 0.91, "loss": 0.27, ...}}
+//
+// Curves the trainer didn't record are left out.
+type TrainingMetrics struct {
+	TrainLoss     []float64 `json:"train_loss,omitempty"`
+	TrainAccuracy []float64 `json:"train_accuracy,omitempty"`
+	ValLoss       []float64 `json:"val_loss,omitempty"`
+	ValAccuracy   []float64 `json:"val_accuracy,omitempty"`
+	Final         *Metrics  `json:"final,omitempty"`
+}
+
+// Epochs returns the number of epochs recorded.
+func (m *TrainingMetrics) Epochs() int {
+	return max(len(m.TrainLoss), len(m.TrainAccuracy), len(m.ValLoss), len(m.ValAccuracy))
+}
+
+// Summary returns the final validation metrics, or those of the last epoch if
+// the trainer wrote none.
+func (m *TrainingMetrics) Summary() Metrics {
+	if m.Final != nil {
+		return *m.Final
+	}
+	var s Metrics
+	if n := len(m.ValAccuracy); n > 0 {
+		s.Accuracy = m.ValAccuracy[n-1]
+	}
+	if n := len(m.ValLoss); n > 0 {
+		s.Loss = m.ValLoss[n-1]
+	}
+	return s
+}
+
+// ReadTrainingMetrics loads dir/metrics.json. The error wraps fs.ErrNotExist
+// if the trainer didn't write one.
+func ReadTrainingMetrics(dir string) (*TrainingMetrics, error) {
+	b, err := os.ReadFile(filepath.Join(dir, MetricsFile))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", MetricsFile, err)
+	}
+	var m TrainingMetrics
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", MetricsFile, err)
+	}
+	n := m.Epochs()
+	if n == 0 && m.Final == nil {
+		return nil, fmt.Errorf("%s has neither curves nor final metrics", MetricsFile)
+	}
+	for name, curve := range map[string][]float64{
+		"train_loss": m.TrainLoss, "train_accuracy": m.TrainAccuracy,
+		"val_loss": m.ValLoss, "val_accuracy": m.ValAccuracy,
+	} {
+		if len(curve) != 0 && len(curve) != n {
+			return nil, fmt.Errorf("%s: %s has %d epochs, want %d", MetricsFile, name, len(curve), n)
+		}
+	}
+	return &m, nil
+}
@@ -0,0 +1,123 @@
+package ingest
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// clockSkewWindow is how many of the newest images the current skew is the
+// median of: enough to ride out a single odd timestamp.
+const clockSkewWindow = 20
+
+// CorrectionAuto as the clock correction setting subtracts the current median
+// skew from capture times.
+const CorrectionAuto = "auto"
+
+// ClockSkewStatus is a snapshot of the camera clock check.
+type ClockSkewStatus struct {
+	store.ClockSkewStats
+	WarnSeconds float64 `json:"warn_seconds,omitempty"` // 0 = never warns
+	Warning     bool    `json:"warning"`                // |median| at or above the threshold
+}
+
+// ClockSkew tracks how far the camera's capture times are from the fetch
+// times, as the median over the newest images, and logs when it crosses the
+// warning threshold. A nil *ClockSkew does nothing.
+type ClockSkew struct {
+	st   *store.Store
+	warn time.Duration
+
+	mu      sync.Mutex
+	current store.ClockSkewStats
+	warning bool
+}
+
+// NewClockSkew creates a tracker warning at warn (0 = never).
+func NewClockSkew(st *store.Store, warn time.Duration) *ClockSkew {
+	return &ClockSkew{st: st, warn: warn}
+}
+
+// Refresh recomputes the current skew from the store; ingestion calls it after
+// recording each image's skew, and the server once at startup.
+func (c *ClockSkew) Refresh(ctx context.Context) {
+	if c == nil {
+		return
+	}
+	cur, err := c.st.RecentClockSkew(ctx, clockSkewWindow)
+	if err != nil {
+		log.Printf("ingest: %v", err)
+		return
+	}
+	median := time.Duration(cur.MedianSeconds * float64(time.Second))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.current = cur
+	over := c.warn > 0 && cur.Images > 0 && median.Abs() >= c.warn
+	switch {
+	case over && !c.warning:
+		log.Printf("ingest: WARNING camera clock is %s off the server clock (median of the last %d images, positive = ahead); capture times and day grouping may be wrong",
+			median.Round(time.Second), cur.Images)
+	case !over && c.warning:
+		log.Printf("ingest: camera clock back within %s of the server clock", c.warn)
+	}
+	c.warning = over
+}
+
+// Median returns the current median skew.
+func (c *ClockSkew) Median() time.Duration {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Duration(c.current.MedianSeconds * float64(time.Second))
+}
+
+// Status returns the current skew and whether it is over the threshold.
+func (c *ClockSkew) Status() ClockSkewStatus {
+	if c == nil {
+		return ClockSkewStatus{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ClockSkewStatus{ClockSkewStats: c.current, WarnSeconds: c.warn.Seconds(), Warning: c.warning}
+}
+
+// ParseClockCorrection validates a clock correction setting: "" (none),
+// CorrectionAuto or a duration such as "-90s".
+func ParseClockCorrection(v string) (time.Duration, error) {
+	if v == "" || v == CorrectionAuto {
+		return 0, nil
+	}
+	return time.ParseDuration(v)
+}
+
+// Offset returns what the clock correction setting v subtracts from capture
+// times: the current median skew for CorrectionAuto, else the duration.
+func (c *ClockSkew) Offset(v string) (time.Duration, error) {
+	if v == CorrectionAuto {
+		return c.Median().Round(time.Second), nil
+	}
+	return ParseClockCorrection(v)
+}
+
+// clockCorrection returns the offset to subtract from capture times under the
+// clock correction setting.
+func (in *Ingestor) clockCorrection(ctx context.Context) time.Duration {
+	var v string
+	if _, err := in.st.GetSetting(ctx, store.SettingClockCorrection, &v); err != nil {
+		log.Printf("ingest: clock correction setting: %v", err)
+		return 0
+	}
+	d, err := in.opts.ClockSkew.Offset(v)
+	if err != nil {
+		log.Printf("ingest: clock correction setting %q: %v", v, err)
+		return 0
+	}
+	return d
+}
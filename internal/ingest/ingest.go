@@ -0,0 +1,207 @@
+// Package ingest records newly fetched frames in the store: the image row and
+// everything the fetcher learned about it, and optionally a prediction.
+package ingest
+
+import (
+	"context"
+	"errors"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/fetcher"
+	"github.com/SkyClf/SkyClf/internal/infer"
+	"github.com/SkyClf/SkyClf/internal/store"
+	"github.com/SkyClf/SkyClf/internal/thermal"
+)
+
+// Options configures an Ingestor.
+type Options struct {
+	// Predict classifies every new frame as it arrives and records the
+	// prediction (and, in auto-label mode, a suggestion). Truncated frames
+	// and formats the model can't read are skipped; every skip is recorded
+	// with its reason (see store.RecordPredictionSkip).
+	Predict bool
+	// Queue runs those predictions in the background and drops them under
+	// load; without one they run before HandleNewImage returns.
+	Queue *PredictQueue
+	// Thermal pauses those predictions while the board is too hot; the
+	// images are marked prediction pending for the queue's sweep instead.
+	Thermal *thermal.Monitor
+	// ClockSkew is told the skew of every image with a capture time, and
+	// gives the median for the "auto" clock correction.
+	ClockSkew *ClockSkew
+}
+
+// Ingestor handles the fetcher's new-image events.
+type Ingestor struct {
+	st   *store.Store
+	pred infer.Predictor // may be nil; only used with Options.Predict
+	opts Options
+}
+
+// New creates an Ingestor. pred may be nil.
+func New(st *store.Store, pred infer.Predictor, opts Options) *Ingestor {
+	return &Ingestor{st: st, pred: pred, opts: opts}
+}
+
+// ImageID derives the image ID from a frame's file name: the name without its
+// extension, which is stable and human readable (e.g. 20250101_221500).
+func ImageID(filename string) string {
+	return strings.TrimSuffix(filename, filepath.Ext(filename))
+}
+
+// HandleNewImage stores a new frame; it has the signature of
+// fetcher.OnNewImageFunc. Failing to insert the image row aborts, while
+// errors storing its details only log.
+func (in *Ingestor) HandleNewImage(ctx context.Context, ev fetcher.NewImageEvent) {
+	imageID := ImageID(ev.Filename)
+
+	if err := in.st.UpsertImage(ctx, imageID, ev.Path, ev.SHA256Hex, ev.FetchedAt, int64(ev.SizeBytes)); err != nil {
+		log.Printf("db: upsert image error: %v", err)
+		return
+	}
+	if ev.Format != "" && ev.Format != store.FormatJPEG {
+		if err := in.st.SetImageFormat(ctx, ev.SHA256Hex, ev.Format); err != nil {
+			log.Printf("db: image format error: %v", err)
+		}
+	}
+	if err := in.st.SetImageMeta(ctx, ev.SHA256Hex, ev.Meta); err != nil {
+		log.Printf("db: image meta error: %v", err)
+	}
+	if ev.PHash != "" {
+		if err := in.st.SetImagePHash(ctx, ev.SHA256Hex, ev.PHash); err != nil {
+			log.Printf("db: image phash error: %v", err)
+		}
+	}
+	if ev.Width > 0 {
+		if err := in.st.SetImageDimensions(ctx, ev.SHA256Hex, ev.Width, ev.Height); err != nil {
+			log.Printf("db: image dimensions error: %v", err)
+		}
+	}
+	captured := ev.CapturedAt
+	if captured.IsZero() && (ev.Format == "" || ev.Format == store.FormatJPEG) {
+		captured = fetcher.ExifTime(ev.Path)
+	}
+	if !captured.IsZero() {
+		// The skew is recorded as the camera reported it; the stored
+		// capture time is corrected
+		if err := in.st.SetCaptureSkew(ctx, ev.SHA256Hex, captured.Sub(ev.FetchedAt)); err != nil {
+			log.Printf("db: capture skew error: %v", err)
+		}
+		in.opts.ClockSkew.Refresh(ctx)
+		captured = captured.Add(-in.clockCorrection(ctx))
+	}
+	if !captured.IsZero() || ev.Stale {
+		if err := in.st.SetImageCapture(ctx, ev.SHA256Hex, captured, ev.Stale); err != nil {
+			log.Printf("db: image capture error: %v", err)
+		}
+	}
+	if ev.Provenance != nil {
+		if err := in.st.SetImageProvenance(ctx, ev.SHA256Hex, *ev.Provenance); err != nil {
+			log.Printf("db: image provenance error: %v", err)
+		}
+	}
+
+	predict, skip := in.shouldPredict(ev)
+	if skip != "" {
+		recordSkip(ctx, in.st, imageID, skip, "")
+	}
+	if predict {
+		if err := in.opts.Thermal.Check(); err != nil {
+			recordSkip(ctx, in.st, imageID, store.SkipThermal, err.Error())
+			if err := in.st.SetPredictionPending(ctx, imageID, true); err != nil {
+				log.Printf("ingest: %v", err)
+			}
+			return
+		}
+		if in.opts.Queue != nil {
+			in.opts.Queue.Enqueue(ctx, imageID, ev.Path)
+			return
+		}
+		pred, err := in.pred.PredictImage(ctx, ev.Path)
+		if err != nil {
+			log.Printf("ingest: predict %s: %v", imageID, err)
+			recordSkip(ctx, in.st, imageID, predictErrorSkip(err), err.Error())
+			return
+		}
+		if pred == nil {
+			recordSkip(ctx, in.st, imageID, store.SkipNoModel, "")
+		}
+		RecordPrediction(ctx, in.st, imageID, pred)
+	}
+}
+
+// shouldPredict reports whether to predict the frame, and otherwise the skip
+// reason to record, if any: with prediction off nothing is recorded.
+func (in *Ingestor) shouldPredict(ev fetcher.NewImageEvent) (predict bool, skip string) {
+	if !in.opts.Predict || in.pred == nil {
+		return false, ""
+	}
+	if ev.Format != "" && !store.Predictable(ev.Format) {
+		return false, store.SkipFormat
+	}
+	if ev.Provenance != nil && ev.Provenance.Truncated() {
+		return false, store.SkipTruncated
+	}
+	return true, ""
+}
+
+// predictErrorSkip returns the skip reason for a failed prediction.
+func predictErrorSkip(err error) string {
+	var ie *infer.ImageError
+	if errors.As(err, &ie) {
+		return store.SkipBadImage
+	}
+	return store.SkipPredictError
+}
+
+// recordSkip records why an image got no prediction; failures only log.
+func recordSkip(ctx context.Context, st *store.Store, imageID, reason, detail string) {
+	if err := st.RecordPredictionSkip(ctx, imageID, reason, detail); err != nil {
+		log.Printf("ingest: %v", err)
+	}
+}
+
+// RecordPrediction stores a prediction for a stored image with its timings
+// and, in auto-label mode, a label suggestion. pred may be nil (no model
+// loaded). Failures only log so callers never fail because of them.
+func RecordPrediction(ctx context.Context, st *store.Store, imageID string, pred *infer.Prediction) {
+	if pred == nil {
+		return
+	}
+	err := st.RecordPrediction(ctx, store.PredictionRecord{
+		ImageID:      imageID,
+		ModelVersion: pred.ModelVer,
+		Skystate:     pred.SkyState,
+		Confidence:   float64(pred.Confidence),
+		Probs:        pred.Probs,
+		PreprocessMS: pred.PreprocessMS,
+		InferenceMS:  pred.InferenceMS,
+		PredictedAt:  time.Now(),
+	})
+	if err != nil {
+		log.Printf("ingest: record prediction: %v", err)
+	}
+
+	// Auto-label mode: confident predictions become suggestions, never labels
+	thresholds, err := st.AutoLabelThresholds(ctx)
+	if err != nil {
+		log.Printf("ingest: auto-label setting: %v", err)
+		return
+	}
+	min := thresholds.For(pred.SkyState)
+	if min <= 0 || float64(pred.Confidence) < min {
+		return
+	}
+	if _, err := st.SuggestLabel(ctx, imageID, store.Suggestion{
+		Skystate:     pred.SkyState,
+		Confidence:   float64(pred.Confidence),
+		ModelVersion: pred.ModelVer,
+		SuggestedAt:  time.Now(),
+		Threshold:    &min,
+	}); err != nil {
+		log.Printf("ingest: suggest label: %v", err)
+	}
+}
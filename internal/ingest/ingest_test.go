@@ -0,0 +1,207 @@
+package ingest
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/fetcher"
+	"github.com/SkyClf/SkyClf/internal/fetcher/testutil"
+	"github.com/SkyClf/SkyClf/internal/infer"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// fakePredictor answers every prediction with pred and err.
+type fakePredictor struct {
+	pred  *infer.Prediction
+	err   error
+	calls int
+}
+
+func (f *fakePredictor) PredictImage(context.Context, string) (*infer.Prediction, error) {
+	f.calls++
+	return f.pred, f.err
+}
+func (f *fakePredictor) Reload(string, string) error { return nil }
+func (f *fakePredictor) Close() error                { return nil }
+
+func openStore(t *testing.T) *store.Store {
+	t.Helper()
+	st, err := store.Open(filepath.Join(t.TempDir(), "labels.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+// frameEvent writes a JPEG frame to dir and returns its new-image event.
+func frameEvent(t *testing.T, dir string) fetcher.NewImageEvent {
+	t.Helper()
+	data := testutil.JPEG(64, 48, 1)
+	path := filepath.Join(dir, "20241003_213000.jpg")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return fetcher.NewImageEvent{
+		Filename:  filepath.Base(path),
+		Path:      path,
+		SHA256Hex: fmt.Sprintf("%x", sha256.Sum256(data)),
+		Format:    store.FormatJPEG,
+		FetchedAt: time.Date(2024, 10, 3, 21, 30, 0, 0, time.UTC),
+		SizeBytes: len(data),
+		Width:     64,
+		Height:    48,
+	}
+}
+
+func TestImageID(t *testing.T) {
+	tests := []struct{ filename, want string }{
+		{"20241003_213000.jpg", "20241003_213000"},
+		{"20241003_213000_1.png", "20241003_213000_1"},
+		{"frame.fits.fz", "frame.fits"},
+		{"noext", "noext"},
+	}
+	for _, tt := range tests {
+		if got := ImageID(tt.filename); got != tt.want {
+			t.Errorf("ImageID(%q) = %q, want %q", tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestHandleNewImage(t *testing.T) {
+	clear := &infer.Prediction{SkyState: "clear", Confidence: 0.9, ModelVer: "v3", Probs: map[string]float32{"clear": 0.9}}
+	tests := []struct {
+		name      string
+		predict   bool
+		pred      *fakePredictor
+		threshold float64 // auto-label minimum confidence, 0 = off
+		edit      func(ev *fetcher.NewImageEvent)
+
+		wantCalls      int
+		wantPrediction string // skystate stored, "" = none
+		wantSkip       string
+		wantSuggestion bool
+	}{
+		{name: "prediction off", pred: &fakePredictor{pred: clear}},
+		{name: "predicted", predict: true, pred: &fakePredictor{pred: clear},
+			wantCalls: 1, wantPrediction: "clear"},
+		{name: "suggested", predict: true, pred: &fakePredictor{pred: clear}, threshold: 0.8,
+			wantCalls: 1, wantPrediction: "clear", wantSuggestion: true},
+		{name: "below the threshold", predict: true, pred: &fakePredictor{pred: clear}, threshold: 0.95,
+			wantCalls: 1, wantPrediction: "clear"},
+		{name: "no model", predict: true, pred: &fakePredictor{},
+			wantCalls: 1, wantSkip: store.SkipNoModel},
+		{name: "bad image", predict: true, pred: &fakePredictor{err: &infer.ImageError{Err: errors.New("decode")}},
+			wantCalls: 1, wantSkip: store.SkipBadImage},
+		{name: "model failure", predict: true, pred: &fakePredictor{err: errors.New("session")},
+			wantCalls: 1, wantSkip: store.SkipPredictError},
+		{name: "unpredictable format", predict: true, pred: &fakePredictor{pred: clear},
+			edit:     func(ev *fetcher.NewImageEvent) { ev.Format = store.FormatFITS },
+			wantSkip: store.SkipFormat},
+		{name: "truncated", predict: true, pred: &fakePredictor{pred: clear},
+			edit: func(ev *fetcher.NewImageEvent) {
+				ev.Provenance = &store.Provenance{HTTPStatus: 200, ContentLength: int64(ev.SizeBytes) * 2, BytesWritten: int64(ev.SizeBytes)}
+			},
+			wantSkip: store.SkipTruncated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			st := openStore(t)
+			if tt.threshold > 0 {
+				if err := st.SetSetting(ctx, store.SettingAutoLabelMinConfidence, tt.threshold); err != nil {
+					t.Fatal(err)
+				}
+			}
+			ev := frameEvent(t, t.TempDir())
+			if tt.edit != nil {
+				tt.edit(&ev)
+			}
+
+			New(st, tt.pred, Options{Predict: tt.predict}).HandleNewImage(ctx, ev)
+
+			d, err := st.GetImageDetail(ctx, ImageID(ev.Filename))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d == nil || d.SHA256 != ev.SHA256Hex || d.Path != ev.Path || d.Format != ev.Format {
+				t.Fatalf("stored image %+v", d)
+			}
+			if tt.pred.calls != tt.wantCalls {
+				t.Fatalf("predictor called %d times, want %d", tt.pred.calls, tt.wantCalls)
+			}
+
+			var got string
+			if len(d.Predictions) > 0 {
+				got = d.Predictions[0].Skystate
+			}
+			if got != tt.wantPrediction || len(d.Predictions) > 1 {
+				t.Fatalf("predictions %+v, want %q", d.Predictions, tt.wantPrediction)
+			}
+			var skip string
+			if len(d.Skips) > 0 {
+				skip = d.Skips[len(d.Skips)-1].Reason
+			}
+			if skip != tt.wantSkip {
+				t.Fatalf("skip %q, want %q", skip, tt.wantSkip)
+			}
+			if (d.Suggestion != nil) != tt.wantSuggestion {
+				t.Fatalf("suggestion %+v, want one: %t", d.Suggestion, tt.wantSuggestion)
+			}
+			if d.Label != nil {
+				t.Fatalf("ingest labeled the image: %+v", d.Label)
+			}
+		})
+	}
+}
+
+func TestHandleNewImageDetails(t *testing.T) {
+	ctx := context.Background()
+	st := openStore(t)
+	ev := frameEvent(t, t.TempDir())
+	ev.Meta = map[string]string{"exposure": "30"}
+	ev.PHash = "00ff00ff00ff00ff"
+	ev.CapturedAt = ev.FetchedAt.Add(-90 * time.Second)
+	ev.Provenance = &store.Provenance{HTTPStatus: 200, ContentLength: int64(ev.SizeBytes), BytesWritten: int64(ev.SizeBytes)}
+
+	in := New(st, nil, Options{Predict: true}) // no predictor: nothing to skip
+	in.HandleNewImage(ctx, ev)
+	// The same frame again changes nothing
+	in.HandleNewImage(ctx, ev)
+
+	d, err := st.GetImageDetail(ctx, ImageID(ev.Filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Width != 64 || d.Height != 48 || d.PHash != ev.PHash || d.Meta["exposure"] != "30" {
+		t.Fatalf("details not stored: %+v", d)
+	}
+	if d.CapturedAt == nil || !d.CapturedAt.Equal(ev.CapturedAt) {
+		t.Fatalf("captured_at %v, want %v", d.CapturedAt, ev.CapturedAt)
+	}
+	if d.CaptureSkewSeconds == nil || *d.CaptureSkewSeconds != -90 {
+		t.Fatalf("capture skew %v, want -90", d.CaptureSkewSeconds)
+	}
+	if d.Provenance == nil || d.Provenance.HTTPStatus != 200 {
+		t.Fatalf("provenance %+v", d.Provenance)
+	}
+	if len(d.Skips) != 0 || len(d.Predictions) != 0 {
+		t.Fatalf("skips %v, predictions %v", d.Skips, d.Predictions)
+	}
+	stats, err := st.CountStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total != 1 {
+		t.Fatalf("%d images after a duplicate event, want 1", stats.Total)
+	}
+}
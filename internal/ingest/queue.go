@@ -0,0 +1,302 @@
+package ingest
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/infer"
+	"github.com/SkyClf/SkyClf/internal/store"
+	"github.com/SkyClf/SkyClf/internal/thermal"
+)
+
+// sweepInterval is how often images marked prediction pending are requeued
+// once the queue has drained.
+const sweepInterval = time.Minute
+
+// QueueStats is what /api/summary and /api/metrics/queue report.
+type QueueStats struct {
+	Enabled     bool `json:"enabled"`
+	Workers     int  `json:"workers"`
+	Limit       int  `json:"limit"`       // queued auto-predictions past which new ones are dropped
+	Interactive int  `json:"interactive"` // API requests waiting for a worker
+	Background  int  `json:"background"`  // auto-predictions waiting for a worker
+	Running     int  `json:"running"`
+	Pending     int  `json:"pending"` // images marked prediction pending
+
+	// Totals since the server started
+	Processed int64 `json:"processed_total"` // auto-predictions run
+	Dropped   int64 `json:"dropped_total"`   // auto-predictions dropped and marked pending
+	Swept     int64 `json:"swept_total"`     // pending images requeued by the sweep
+}
+
+// interactiveJob is an API request's prediction; the caller waits on done.
+type interactiveJob struct {
+	ctx  context.Context
+	fn   func(ctx context.Context) (*infer.Prediction, error)
+	pred *infer.Prediction
+	err  error
+	done chan struct{}
+}
+
+// backgroundJob is an auto-prediction for a stored image.
+type backgroundJob struct {
+	imageID, path string
+	pending       bool // marked pending in the store; cleared once predicted
+}
+
+// PredictQueue runs predictions on a fixed pool of workers. Interactive
+// requests go ahead of queued auto-predictions, and auto-predictions beyond
+// the limit are dropped and the image marked prediction pending; a sweep
+// requeues those once the queue is empty. A nil *PredictQueue runs
+// interactive predictions directly.
+type PredictQueue struct {
+	st      *store.Store
+	pred    infer.Predictor
+	workers int
+	limit   int
+	thermal *thermal.Monitor
+
+	mu          sync.Mutex
+	cond        *sync.Cond
+	interactive []*interactiveJob
+	background  []backgroundJob
+	queued      map[string]bool // image IDs in background or being predicted
+	running     int
+	closed      bool
+
+	processed, dropped, swept int64
+}
+
+// NewPredictQueue creates a queue running pred on workers goroutines and
+// holding at most limit auto-predictions. Start runs it.
+func NewPredictQueue(st *store.Store, pred infer.Predictor, workers, limit int) *PredictQueue {
+	q := &PredictQueue{st: st, pred: pred, workers: max(workers, 1), limit: max(limit, 1), queued: map[string]bool{}}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// SetThermal pauses auto-predictions while m is hot: queued ones are marked
+// prediction pending instead of run, and the sweep waits for it to cool
+// down. Interactive predictions still run.
+func (q *PredictQueue) SetThermal(m *thermal.Monitor) {
+	q.thermal = m
+}
+
+// Start runs the workers and the catch-up sweep until ctx is canceled.
+// Queued auto-predictions are abandoned then; their images are not marked.
+func (q *PredictQueue) Start(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range q.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+
+	t := time.NewTicker(sweepInterval)
+	defer t.Stop()
+	for {
+		q.Sweep(ctx)
+		select {
+		case <-ctx.Done():
+			q.mu.Lock()
+			q.closed = true
+			q.cond.Broadcast()
+			q.mu.Unlock()
+			wg.Wait()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Do runs fn on the next free worker, ahead of any queued auto-predictions,
+// and returns its result. It gives up when ctx is done; fn is then skipped if
+// it hasn't started.
+func (q *PredictQueue) Do(ctx context.Context, fn func(ctx context.Context) (*infer.Prediction, error)) (*infer.Prediction, error) {
+	if q == nil {
+		return fn(ctx)
+	}
+	job := &interactiveJob{ctx: ctx, fn: fn, done: make(chan struct{})}
+	q.mu.Lock()
+	q.interactive = append(q.interactive, job)
+	q.cond.Signal()
+	q.mu.Unlock()
+
+	select {
+	case <-job.done:
+		return job.pred, job.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Enqueue queues an auto-prediction for a stored image unless one is queued
+// already. When the queue is full the prediction is dropped, the image marked
+// prediction pending and false returned.
+func (q *PredictQueue) Enqueue(ctx context.Context, imageID, path string) bool {
+	_, dropped := q.enqueue(ctx, backgroundJob{imageID: imageID, path: path})
+	return !dropped
+}
+
+func (q *PredictQueue) enqueue(ctx context.Context, job backgroundJob) (added, dropped bool) {
+	q.mu.Lock()
+	if q.queued[job.imageID] {
+		q.mu.Unlock()
+		return false, false
+	}
+	if len(q.background) >= q.limit {
+		q.dropped++
+		q.mu.Unlock()
+		if !job.pending {
+			recordSkip(ctx, q.st, job.imageID, store.SkipQueueDropped, "")
+			if err := q.st.SetPredictionPending(ctx, job.imageID, true); err != nil {
+				log.Printf("ingest: %v", err)
+			}
+		}
+		return false, true
+	}
+	q.background = append(q.background, job)
+	q.queued[job.imageID] = true
+	q.cond.Signal()
+	q.mu.Unlock()
+	return true, false
+}
+
+// Busy reports whether interactive predictions are waiting or every worker
+// is running, for background work that should give way.
+func (q *PredictQueue) Busy() bool {
+	if q == nil {
+		return false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.interactive) > 0 || q.running >= q.workers
+}
+
+// Stats reports the queue depths and counters.
+func (q *PredictQueue) Stats(ctx context.Context) (QueueStats, error) {
+	if q == nil {
+		return QueueStats{}, nil
+	}
+	pending, err := q.st.CountPendingPredictions(ctx)
+	if err != nil {
+		return QueueStats{}, err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return QueueStats{
+		Enabled:     true,
+		Workers:     q.workers,
+		Limit:       q.limit,
+		Interactive: len(q.interactive),
+		Background:  len(q.background),
+		Running:     q.running,
+		Pending:     pending,
+		Processed:   q.processed,
+		Dropped:     q.dropped,
+		Swept:       q.swept,
+	}, nil
+}
+
+// work runs jobs until the queue is closed, interactive ones first.
+func (q *PredictQueue) work(ctx context.Context) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for {
+		for !q.closed && len(q.interactive) == 0 && len(q.background) == 0 {
+			q.cond.Wait()
+		}
+		if q.closed {
+			return
+		}
+		q.running++
+		if len(q.interactive) > 0 {
+			job := q.interactive[0]
+			q.interactive = q.interactive[1:]
+			q.mu.Unlock()
+			if job.ctx.Err() == nil {
+				job.pred, job.err = job.fn(job.ctx)
+			}
+			close(job.done)
+			q.mu.Lock()
+		} else {
+			job := q.background[0]
+			q.background = q.background[1:]
+			q.mu.Unlock()
+			q.predict(ctx, job)
+			q.mu.Lock()
+			delete(q.queued, job.imageID)
+			q.processed++
+		}
+		q.running--
+	}
+}
+
+// predict runs and records an auto-prediction. A pending image stays marked
+// while no model is loaded or the board is too hot, so a later sweep retries
+// it. Skips are recorded once, not on every retry.
+func (q *PredictQueue) predict(ctx context.Context, job backgroundJob) {
+	if err := q.thermal.Check(); err != nil {
+		if !job.pending {
+			recordSkip(ctx, q.st, job.imageID, store.SkipThermal, err.Error())
+			if err := q.st.SetPredictionPending(ctx, job.imageID, true); err != nil {
+				log.Printf("ingest: %v", err)
+			}
+		}
+		return
+	}
+	pred, err := q.pred.PredictImage(ctx, job.path)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("ingest: predict %s: %v", job.imageID, err)
+		recordSkip(ctx, q.st, job.imageID, predictErrorSkip(err), err.Error())
+	} else if pred == nil && !job.pending {
+		recordSkip(ctx, q.st, job.imageID, store.SkipNoModel, "")
+	}
+	RecordPrediction(ctx, q.st, job.imageID, pred)
+	if job.pending && (pred != nil || err != nil) {
+		if err := q.st.SetPredictionPending(ctx, job.imageID, false); err != nil {
+			log.Printf("ingest: %v", err)
+		}
+	}
+}
+
+// Sweep requeues images marked prediction pending, but only into an empty
+// queue: fresh frames come first. Start calls it every minute.
+func (q *PredictQueue) Sweep(ctx context.Context) {
+	q.mu.Lock()
+	busy := len(q.background) > 0
+	q.mu.Unlock()
+	if busy || q.thermal.Check() != nil {
+		return
+	}
+	pending, err := q.st.PendingPredictions(ctx, q.limit)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("ingest: sweep: %v", err)
+		}
+		return
+	}
+	n := 0
+	for _, p := range pending {
+		added, dropped := q.enqueue(ctx, backgroundJob{imageID: p.ID, path: p.Path, pending: true})
+		if dropped {
+			break
+		}
+		if added {
+			n++
+		}
+	}
+	if n > 0 {
+		q.mu.Lock()
+		q.swept += int64(n)
+		q.mu.Unlock()
+		log.Printf("ingest: requeued %d pending predictions", n)
+	}
+}
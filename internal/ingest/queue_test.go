@@ -0,0 +1,112 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/fetcher"
+	"github.com/SkyClf/SkyClf/internal/infer"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// gatedPredictor answers every image once gate is closed.
+type gatedPredictor struct {
+	gate chan struct{}
+}
+
+func (p *gatedPredictor) PredictImage(ctx context.Context, _ string) (*infer.Prediction, error) {
+	select {
+	case <-p.gate:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &infer.Prediction{SkyState: "clear", Confidence: 0.9, ModelVer: "stub"}, nil
+}
+func (p *gatedPredictor) Reload(string, string) error { return nil }
+func (p *gatedPredictor) Close() error                { return nil }
+
+func waitStats(t *testing.T, q *PredictQueue, ok func(QueueStats) bool) QueueStats {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		stats, err := q.Stats(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok(stats) {
+			return stats
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("queue stuck at %+v", stats)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestQueueBurst pushes a backfill of 1000 new-image events through the
+// queue while the model is stuck on the first one: the queue never holds
+// more than its limit, every overflowing image is marked pending rather than
+// lost, and the sweep predicts each image exactly once.
+func TestQueueBurst(t *testing.T) {
+	const events, limit = 1000, 100
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	st := openStore(t)
+	pred := &gatedPredictor{gate: make(chan struct{})}
+	q := NewPredictQueue(st, pred, 1, limit)
+	go q.Start(ctx)
+	in := New(st, pred, Options{Predict: true, Queue: q})
+
+	dir := t.TempDir()
+	base := time.Date(2025, 1, 1, 20, 0, 0, 0, time.UTC)
+	maxQueued := 0
+	for i := range events {
+		name := fmt.Sprintf("burst_%05d.jpg", i)
+		in.HandleNewImage(ctx, fetcher.NewImageEvent{
+			Filename:  name,
+			Path:      filepath.Join(dir, name),
+			SHA256Hex: fmt.Sprintf("%064x", i),
+			Format:    store.FormatJPEG,
+			FetchedAt: base.Add(time.Duration(i) * time.Minute),
+			SizeBytes: 1000,
+		})
+		if i == 0 {
+			// The worker holds the first image from here on
+			waitStats(t, q, func(s QueueStats) bool { return s.Running == 1 })
+		}
+		stats, err := q.Stats(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		maxQueued = max(maxQueued, stats.Background)
+	}
+
+	stats, err := q.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maxQueued > limit || stats.Background != limit {
+		t.Fatalf("queued up to %d, %d at the end; want the limit, %d", maxQueued, stats.Background, limit)
+	}
+	if want := int64(events - limit - 1); stats.Dropped != want || int64(stats.Pending) != want {
+		t.Fatalf("dropped %d, pending %d; want %d each", stats.Dropped, stats.Pending, want)
+	}
+
+	// Catch up: sweep whenever the queue has drained
+	close(pred.gate)
+	waitStats(t, q, func(s QueueStats) bool {
+		q.Sweep(ctx)
+		return s.Pending == 0 && s.Background == 0 && s.Running == 0
+	})
+
+	var predicted, total int
+	if err := st.DB.QueryRowContext(ctx, `SELECT COUNT(DISTINCT image_id), COUNT(*) FROM predictions WHERE model_version = 'stub'`).Scan(&predicted, &total); err != nil {
+		t.Fatal(err)
+	}
+	if predicted != events || total != events {
+		t.Fatalf("%d images predicted with %d predictions; want %d each", predicted, total, events)
+	}
+}
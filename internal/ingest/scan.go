@@ -0,0 +1,312 @@
+package ingest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/fetcher"
+	"github.com/SkyClf/SkyClf/internal/imghash"
+	"github.com/SkyClf/SkyClf/internal/store"
+	_ "golang.org/x/image/webp"
+)
+
+// settleTime is how long a file must go unmodified before a scan picks it up,
+// so files still being copied in (other than by rsync, which uses hidden
+// temp names) aren't ingested half-written.
+const settleTime = 10 * time.Second
+
+// ScanStatus describes the directory scanner for the fetcher status endpoint.
+type ScanStatus struct {
+	Dir      string    `json:"dir"`
+	Interval string    `json:"interval"`
+	Watching bool      `json:"watching"` // new files are picked up via filesystem notifications
+	LastScan time.Time `json:"last_scan,omitempty"`
+	LastErr  string    `json:"last_error,omitempty"`
+	Last     ScanStats `json:"last"`     // of the most recent scan
+	Ingested int64     `json:"ingested"` // since startup
+}
+
+// ScanStats counts the outcome of one scan.
+type ScanStats struct {
+	Files      int `json:"files"`      // image files in the directory
+	Ingested   int `json:"ingested"`   // new files stored
+	Duplicates int `json:"duplicates"` // content already stored under another name
+	Failed     int `json:"failed"`     // unreadable files
+}
+
+// Scanner ingests image files that appear in a directory by other means than
+// the fetcher, e.g. rsync from the camera host (SKYCLF_MODE=no-fetch). Files
+// are matched by path; new ones go through the Ingestor like fetched frames.
+type Scanner struct {
+	in       *Ingestor
+	dir      string
+	interval time.Duration
+	phash    bool
+	scanNow  chan struct{}
+
+	mu     sync.Mutex
+	skip   map[string]bool // duplicates and failures, not retried until restart
+	status ScanStatus
+	done   chan struct{} // closed after the first scan
+	once   sync.Once
+}
+
+// NewScanner creates a scanner of dir that runs every interval once started.
+func NewScanner(in *Ingestor, dir string, interval time.Duration) *Scanner {
+	return &Scanner{
+		in:       in,
+		dir:      dir,
+		interval: interval,
+		scanNow:  make(chan struct{}, 1),
+		skip:     map[string]bool{},
+		status:   ScanStatus{Dir: dir, Interval: interval.String()},
+		done:     make(chan struct{}),
+	}
+}
+
+// SetPerceptualHash enables computing a perceptual hash for every new file.
+func (s *Scanner) SetPerceptualHash(enabled bool) {
+	s.phash = enabled
+}
+
+// Start scans right away and then every interval (or when ScanNow is called)
+// until ctx is canceled.
+func (s *Scanner) Start(ctx context.Context) {
+	log.Printf("ingest: fetching disabled; scanning %s every %s", s.dir, s.interval)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.scanOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.scanNow:
+		}
+	}
+}
+
+// scanOnce runs a scan, logging a failure, and marks the first one done.
+func (s *Scanner) scanOnce(ctx context.Context) {
+	if _, err := s.Scan(ctx); err != nil && ctx.Err() == nil {
+		log.Printf("ingest: scan %s: %v", s.dir, err)
+	}
+	s.once.Do(func() { close(s.done) })
+}
+
+// ScanNow triggers a scan without waiting for the interval.
+func (s *Scanner) ScanNow() {
+	select {
+	case s.scanNow <- struct{}{}:
+	default:
+	}
+}
+
+// Scanned is closed once the first scan has finished.
+func (s *Scanner) Scanned() <-chan struct{} {
+	return s.done
+}
+
+// Status returns a snapshot of the scanner state.
+func (s *Scanner) Status() ScanStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// Scan ingests every image file under the directory, including date
+// subdirectories, that isn't stored yet. Hidden files and directories, such
+// as rsync's partial transfers, and files modified within settleTime are left
+// for a later scan.
+func (s *Scanner) Scan(ctx context.Context) (ScanStats, error) {
+	stats, err := s.scan(ctx)
+	s.mu.Lock()
+	s.status.LastScan = time.Now().UTC()
+	s.status.Last = stats
+	s.status.Ingested += int64(stats.Ingested)
+	s.status.LastErr = ""
+	if err != nil {
+		s.status.LastErr = err.Error()
+	}
+	s.mu.Unlock()
+	if stats.Ingested > 0 {
+		log.Printf("ingest: scan found %d new images (%d duplicates, %d failed)", stats.Ingested, stats.Duplicates, stats.Failed)
+	}
+	return stats, err
+}
+
+func (s *Scanner) scan(ctx context.Context) (ScanStats, error) {
+	var stats ScanStats
+	refs, err := s.in.st.ListImageRefs(ctx)
+	if err != nil {
+		return stats, err
+	}
+	known := make(map[string]bool, len(refs))
+	for _, r := range refs {
+		known[filepath.Clean(r.Path)] = true
+	}
+
+	err = filepath.WalkDir(s.dir, func(p string, e fs.DirEntry, err error) error {
+		if err != nil {
+			if p == s.dir {
+				return err
+			}
+			log.Printf("ingest: %s: %v", p, err)
+			return nil
+		}
+		name := e.Name()
+		if e.IsDir() {
+			if p != s.dir && hidden(name) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if hidden(name) || !fetcher.IsImageFile(name) {
+			return nil
+		}
+		stats.Files++
+		if known[filepath.Clean(p)] || s.skipped(p) {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		info, err := e.Info()
+		if err != nil || time.Since(info.ModTime()) < settleTime {
+			return nil
+		}
+		return s.ingestFile(ctx, p, info, &stats)
+	})
+	return stats, err
+}
+
+// ingestFile stores the file at p unless its content is stored already,
+// counting the outcome in stats. Only store errors are returned.
+func (s *Scanner) ingestFile(ctx context.Context, p string, info os.FileInfo, stats *ScanStats) error {
+	ev, err := s.describe(p, info)
+	if err != nil {
+		log.Printf("ingest: %s: %v", p, err)
+		stats.Failed++
+		s.skipPath(p)
+		return nil
+	}
+	dup, err := s.in.st.HasImageSHA(ctx, ev.SHA256Hex)
+	if err != nil {
+		return err
+	}
+	if dup {
+		stats.Duplicates++
+		s.skipPath(p)
+		return nil
+	}
+	s.in.HandleNewImage(ctx, ev)
+	stats.Ingested++
+	return nil
+}
+
+// ingestChanged ingests the file at p after the Watcher saw it change, even
+// if an earlier version was skipped.
+func (s *Scanner) ingestChanged(ctx context.Context, p string, info os.FileInfo) error {
+	var stats ScanStats
+	s.mu.Lock()
+	delete(s.skip, p)
+	s.mu.Unlock()
+	if err := s.ingestFile(ctx, p, info, &stats); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.status.Ingested += int64(stats.Ingested)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Scanner) setWatching(on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.Watching = on
+}
+
+// hidden reports whether name is a dotfile, e.g. ".latest.jpg.Xa3f9" as
+// written by rsync before renaming it into place.
+func hidden(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+func (s *Scanner) skipped(p string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.skip[p]
+}
+
+func (s *Scanner) skipPath(p string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.skip[p] = true
+}
+
+// describe builds the new-image event the fetcher would have sent for the
+// file: content hash, format, dimensions and (optionally) perceptual hash.
+// The modification time stands in for the fetch time; the capture time is
+// the EXIF time or, failing that, a timestamp in the file name.
+func (s *Scanner) describe(p string, info os.FileInfo) (fetcher.NewImageEvent, error) {
+	ev := fetcher.NewImageEvent{
+		Filename:  info.Name(),
+		Path:      p,
+		FetchedAt: info.ModTime().UTC(),
+		SizeBytes: int(info.Size()),
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return ev, err
+	}
+	defer f.Close()
+
+	head := make([]byte, 16)
+	n, _ := io.ReadFull(f, head)
+	ev.Format = fetcher.DetectFormat(head[:n], "")
+	if ev.Format == "" || ev.Format == store.FormatUnknown {
+		ev.Format = fetcher.FormatOf(p) // trust the extension
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return ev, err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ev, err
+	}
+	ev.SHA256Hex = hex.EncodeToString(h.Sum(nil))
+	if ev.Format == store.FormatJPEG {
+		ev.CapturedAt = fetcher.ExifTime(p)
+	}
+	if ev.CapturedAt.IsZero() {
+		ev.CapturedAt = fetcher.FilenameTime(info.Name())
+	}
+
+	if !store.Predictable(ev.Format) {
+		return ev, nil
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return ev, err
+	}
+	if cfg, _, err := image.DecodeConfig(f); err == nil {
+		ev.Width, ev.Height = cfg.Width, cfg.Height
+	}
+	if s.phash {
+		if ph, err := imghash.DHashFile(p); err == nil {
+			ev.PHash = imghash.Format(ph)
+		}
+	}
+	return ev, nil
+}
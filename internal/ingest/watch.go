@@ -0,0 +1,185 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/SkyClf/SkyClf/internal/fetcher"
+)
+
+// quietPeriod is how long a watched file's size and modification time must
+// stay the same before it is ingested; write events alone don't say when a
+// copy has finished.
+const quietPeriod = 2 * time.Second
+
+// Watcher ingests image files as soon as they are written anywhere under the
+// scanner's directory (SKYCLF_MODE=watch), using filesystem notifications
+// instead of periodic scans. Hashing, duplicate detection and status are
+// shared with the Scanner, which also catches up on files added while the
+// server was down and takes over when notifications are unavailable.
+type Watcher struct {
+	sc      *Scanner
+	pending map[string]pendingFile // files written to but not yet settled
+}
+
+type pendingFile struct {
+	size    int64
+	modTime time.Time
+	seen    time.Time // when size or modTime last changed
+}
+
+// NewWatcher creates a watcher that ingests through sc.
+func NewWatcher(sc *Scanner) *Watcher {
+	return &Watcher{sc: sc, pending: map[string]pendingFile{}}
+}
+
+// Start scans the directory once, then ingests new files as they settle until
+// ctx is canceled. If the directory tree can't be watched it falls back to
+// sc.Start; if a directory created later can't be, periodic scans are added.
+func (w *Watcher) Start(ctx context.Context) {
+	fw, err := fsnotify.NewWatcher()
+	if err == nil {
+		if err = w.addTree(fw, w.sc.dir, false); err != nil {
+			fw.Close()
+		}
+	}
+	if err != nil {
+		log.Printf("ingest: can't watch %s (%v); falling back to periodic scans", w.sc.dir, err)
+		w.sc.Start(ctx)
+		return
+	}
+	defer fw.Close()
+	log.Printf("ingest: fetching disabled; watching %s", w.sc.dir)
+	w.sc.setWatching(true)
+
+	// Files added while the server was down
+	w.sc.scanOnce(ctx)
+
+	settle := time.NewTicker(quietPeriod / 2)
+	defer settle.Stop()
+	var fallback <-chan time.Time // periodic scans once a directory couldn't be watched
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-fw.Events:
+			if !ok {
+				return
+			}
+			if err := w.handle(fw, ev); err != nil && fallback == nil {
+				log.Printf("ingest: can't watch %s (%v); scanning every %s as well", ev.Name, err, w.sc.interval)
+				t := time.NewTicker(w.sc.interval)
+				defer t.Stop()
+				fallback = t.C
+				w.sc.setWatching(false)
+			}
+		case err, ok := <-fw.Errors:
+			if !ok {
+				return
+			}
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				log.Printf("ingest: missed file events; rescanning %s", w.sc.dir)
+			} else {
+				log.Printf("ingest: watch %s: %v", w.sc.dir, err)
+			}
+			// Files written just now are left by the scan; look again once they settle
+			w.sc.scanOnce(ctx)
+			time.AfterFunc(settleTime, w.sc.ScanNow)
+		case <-w.sc.scanNow:
+			w.sc.scanOnce(ctx)
+		case <-fallback:
+			w.sc.scanOnce(ctx)
+		case <-settle.C:
+			w.settle(ctx)
+		}
+	}
+}
+
+// addTree watches dir and its subdirectories, skipping hidden ones. With
+// queue set, image files already in them are queued, as they may have been
+// written before the watch was in place.
+func (w *Watcher) addTree(fw *fsnotify.Watcher, dir string, queue bool) error {
+	return filepath.WalkDir(dir, func(p string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !e.IsDir() {
+			if queue && !hidden(e.Name()) && fetcher.IsImageFile(e.Name()) {
+				if info, err := e.Info(); err == nil {
+					w.touch(p, info)
+				}
+			}
+			return nil
+		}
+		if p != dir && hidden(e.Name()) {
+			return filepath.SkipDir
+		}
+		return fw.Add(p)
+	})
+}
+
+// handle tracks one event. rsync writes to a hidden temp file and renames it
+// into place, which arrives as a create of the final name; renames and
+// removals drop the old name.
+func (w *Watcher) handle(fw *fsnotify.Watcher, ev fsnotify.Event) error {
+	name := filepath.Base(ev.Name)
+	if hidden(name) {
+		return nil
+	}
+	if ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename) {
+		delete(w.pending, ev.Name)
+		return nil
+	}
+	if !ev.Has(fsnotify.Create) && !ev.Has(fsnotify.Write) {
+		return nil
+	}
+	info, err := os.Lstat(ev.Name)
+	if err != nil {
+		return nil // gone again
+	}
+	if info.IsDir() {
+		if ev.Has(fsnotify.Create) {
+			return w.addTree(fw, ev.Name, true)
+		}
+		return nil
+	}
+	if info.Mode().IsRegular() && fetcher.IsImageFile(name) {
+		w.touch(ev.Name, info)
+	}
+	return nil
+}
+
+// touch records the current size and modification time of p.
+func (w *Watcher) touch(p string, info os.FileInfo) {
+	pf, ok := w.pending[p]
+	if ok && pf.size == info.Size() && pf.modTime.Equal(info.ModTime()) {
+		return
+	}
+	w.pending[p] = pendingFile{size: info.Size(), modTime: info.ModTime(), seen: time.Now()}
+}
+
+// settle ingests the pending files that haven't changed for quietPeriod.
+func (w *Watcher) settle(ctx context.Context) {
+	for p := range w.pending {
+		info, err := os.Stat(p)
+		if err != nil {
+			delete(w.pending, p)
+			continue
+		}
+		w.touch(p, info)
+		if time.Since(w.pending[p].seen) < quietPeriod {
+			continue
+		}
+		delete(w.pending, p)
+		if err := w.sc.ingestChanged(ctx, p, info); err != nil && ctx.Err() == nil {
+			log.Printf("ingest: %s: %v", p, err)
+		}
+	}
+}
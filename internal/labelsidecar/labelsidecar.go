@@ -0,0 +1,178 @@
+// Package labelsidecar writes each image's label to a small JSON file next
+// to the image (image.jpg.skyclf.json) for tools that read per-image
+// sidecars rather than the API.
+package labelsidecar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// Suffix is appended to the image path to name its sidecar.
+const Suffix = ".skyclf.json"
+
+// backfillPage is how many labels Backfill reads at a time.
+const backfillPage = 500
+
+// Sidecar is the content of a sidecar file.
+type Sidecar struct {
+	ImageID    string      `json:"image_id"`
+	SHA256     string      `json:"sha256"`
+	Skystate   string      `json:"skystate"`
+	Meteor     bool        `json:"meteor"`
+	LabeledAt  time.Time   `json:"labeled_at"`
+	Prediction *Prediction `json:"prediction,omitempty"` // the newest stored one
+}
+
+// Prediction is the model's opinion of the image.
+type Prediction struct {
+	ModelVersion string    `json:"model_version"`
+	Skystate     string    `json:"skystate"`
+	Confidence   float64   `json:"confidence"`
+	PredictedAt  time.Time `json:"predicted_at"`
+}
+
+// BackfillResult is what Backfill did.
+type BackfillResult struct {
+	Written int `json:"written"`
+	Failed  int `json:"failed"`
+}
+
+// Writer keeps the sidecars in line with the labels. Label changes reported
+// through Notify are applied by Start in the background; failures only log.
+type Writer struct {
+	st *store.Store
+
+	mu      sync.Mutex
+	pending map[string]bool // image IDs to sync
+	wake    chan struct{}
+}
+
+// New creates a Writer; register its Notify with st.SetLabelObserver and
+// run Start.
+func New(st *store.Store) *Writer {
+	return &Writer{st: st, pending: map[string]bool{}, wake: make(chan struct{}, 1)}
+}
+
+// Notify queues the images for a sidecar update without blocking.
+func (w *Writer) Notify(imageIDs []string) {
+	w.mu.Lock()
+	for _, id := range imageIDs {
+		w.pending[id] = true
+	}
+	w.mu.Unlock()
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start applies queued updates until ctx is canceled.
+func (w *Writer) Start(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.wake:
+		}
+		w.mu.Lock()
+		ids := w.pending
+		w.pending = map[string]bool{}
+		w.mu.Unlock()
+		for id := range ids {
+			if err := w.Sync(ctx, id); err != nil && ctx.Err() == nil {
+				log.Printf("labelsidecar: %s: %v", id, err)
+			}
+		}
+	}
+}
+
+// Sync writes the sidecar of a labeled image and removes that of an
+// unlabeled one. Images that no longer exist are ignored.
+func (w *Writer) Sync(ctx context.Context, imageID string) error {
+	d, err := w.st.GetImageDetail(ctx, imageID)
+	if err != nil || d == nil {
+		return err
+	}
+	path := d.Path + Suffix
+	if d.Label == nil {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	sc := Sidecar{
+		ImageID:   d.ID,
+		SHA256:    d.SHA256,
+		Skystate:  d.Label.Skystate,
+		Meteor:    d.Label.Meteor,
+		LabeledAt: d.Label.LabeledAt,
+	}
+	if len(d.Predictions) > 0 {
+		p := d.Predictions[0]
+		sc.Prediction = &Prediction{ModelVersion: p.ModelVersion, Skystate: p.Skystate, Confidence: p.Confidence, PredictedAt: p.PredictedAt}
+	}
+	data, err := json.MarshalIndent(sc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeAtomic(path, append(data, '\n'))
+}
+
+// Backfill writes the sidecars of all labeled images, e.g. after enabling
+// sidecars on an existing dataset.
+func (w *Writer) Backfill(ctx context.Context) (BackfillResult, error) {
+	var res BackfillResult
+	for offset := 0; ; offset += backfillPage {
+		page, err := w.st.ListLabelChanges(ctx, time.Time{}, backfillPage, offset)
+		if err != nil {
+			return res, err
+		}
+		for _, c := range page {
+			if err := w.Sync(ctx, c.ImageID); err != nil {
+				if ctx.Err() != nil {
+					return res, ctx.Err()
+				}
+				log.Printf("labelsidecar: %s: %v", c.ImageID, err)
+				res.Failed++
+				continue
+			}
+			res.Written++
+		}
+		if len(page) < backfillPage {
+			return res, nil
+		}
+	}
+}
+
+// writeAtomic replaces path with data via a temp file in the same
+// directory, so readers never see a partial sidecar.
+func writeAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// CreateTemp makes it owner-only; the tools reading sidecars may run as another user
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,282 @@
+package labelsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+const pageSize = 500
+
+// Syncer periodically exchanges labels with a peer SkyClf instance.
+// Labels are matched by image sha256; the newer labeled_at wins on both sides.
+type Syncer struct {
+	st       *store.Store
+	peerURL  string
+	selfName string
+	interval time.Duration
+	dryRun   bool
+	client   *http.Client
+
+	mu       sync.Mutex
+	lastPull watermark // newest labels seen from the peer
+	lastPush watermark // newest local labels sent to the peer
+}
+
+// watermark is how far one direction of the sync got: the newest labeled_at
+// exchanged and the labels exchanged at exactly that second. labeled_at has
+// second precision, so a label written later in the same second sorts level
+// with them; the next round asks for labels at or after the second and skips
+// the ones listed here.
+type watermark struct {
+	at   time.Time
+	seen map[string]bool // by sha256; their labeled_at is at
+}
+
+// fresh reports whether c was not exchanged yet.
+func (w watermark) fresh(c store.LabelChange) bool {
+	return c.LabeledAt.After(w.at) || (c.LabeledAt.Equal(w.at) && !w.seen[c.SHA256])
+}
+
+// advance returns the watermark after exchanging c.
+func (w watermark) advance(c store.LabelChange) watermark {
+	switch {
+	case c.LabeledAt.After(w.at):
+		return watermark{at: c.LabeledAt, seen: map[string]bool{c.SHA256: true}}
+	case c.LabeledAt.Equal(w.at):
+		seen := make(map[string]bool, len(w.seen)+1)
+		for k := range w.seen {
+			seen[k] = true
+		}
+		seen[c.SHA256] = true
+		return watermark{at: w.at, seen: seen}
+	}
+	return w
+}
+
+// freshChanges returns the changes of page not exchanged as of w, the
+// watermark the round started from, and advances next past them.
+func freshChanges(w, next watermark, page []store.LabelChange) ([]store.LabelChange, watermark) {
+	var out []store.LabelChange
+	for _, c := range page {
+		if w.fresh(c) {
+			out = append(out, c)
+			next = next.advance(c)
+		}
+	}
+	return out, next
+}
+
+// Report summarizes one sync round.
+type Report struct {
+	Pulled store.MergeResult `json:"pulled"`
+	Pushed store.MergeResult `json:"pushed"`
+}
+
+// New creates a Syncer against peerURL (e.g. "http://other:8080").
+// selfName is recorded as the source of pushed labels on the peer.
+func New(st *store.Store, peerURL, selfName string, interval time.Duration, dryRun bool) *Syncer {
+	return &Syncer{
+		st:       st,
+		peerURL:  strings.TrimRight(peerURL, "/"),
+		selfName: selfName,
+		interval: interval,
+		dryRun:   dryRun,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Start runs a sync round immediately and then on every interval until ctx is canceled.
+func (s *Syncer) Start(ctx context.Context) error {
+	s.logRound(s.SyncOnce(ctx, s.dryRun))
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("labelsync: stopping")
+			return ctx.Err()
+		case <-ticker.C:
+			s.logRound(s.SyncOnce(ctx, s.dryRun))
+		}
+	}
+}
+
+func (s *Syncer) logRound(rep Report, err error) {
+	if err != nil {
+		log.Printf("labelsync: %v", err)
+		return
+	}
+	prefix := "labelsync:"
+	if rep.Pulled.DryRun {
+		prefix = "labelsync (dry-run):"
+	}
+	log.Printf("%s pulled created=%d updated=%d older=%d unknown=%d; pushed created=%d updated=%d older=%d unknown=%d",
+		prefix,
+		rep.Pulled.Created, rep.Pulled.Updated, rep.Pulled.Older, rep.Pulled.UnknownImage,
+		rep.Pushed.Created, rep.Pushed.Updated, rep.Pushed.Older, rep.Pushed.UnknownImage)
+}
+
+// SyncOnce pulls peer changes into the local store, then pushes local changes to the peer.
+// A dry run (requested here or configured) writes nothing on either side and
+// leaves the watermarks untouched.
+func (s *Syncer) SyncOnce(ctx context.Context, dryRun bool) (Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dryRun = dryRun || s.dryRun
+	var rep Report
+
+	pulled, pullMark, err := s.pull(ctx, dryRun)
+	if err != nil {
+		return rep, fmt.Errorf("pull: %w", err)
+	}
+	rep.Pulled = pulled
+
+	pushed, pushMark, err := s.push(ctx, dryRun)
+	if err != nil {
+		return rep, fmt.Errorf("push: %w", err)
+	}
+	rep.Pushed = pushed
+
+	if !dryRun {
+		s.lastPull = pullMark
+		s.lastPush = pushMark
+	}
+	return rep, nil
+}
+
+func (s *Syncer) pull(ctx context.Context, dryRun bool) (store.MergeResult, watermark, error) {
+	total := store.MergeResult{DryRun: dryRun}
+	mark := s.lastPull
+
+	offset := 0
+	for {
+		page, next, err := s.fetchChanges(ctx, s.lastPull.at, offset)
+		if err != nil {
+			return total, mark, err
+		}
+		var changes []store.LabelChange
+		changes, mark = freshChanges(s.lastPull, mark, page)
+		if len(changes) > 0 {
+			res, err := s.st.MergeLabels(ctx, changes, store.LabelSourceSyncPrefix+s.peerURL, dryRun)
+			if err != nil {
+				return total, mark, err
+			}
+			addResult(&total, res)
+		}
+		if next == nil {
+			return total, mark, nil
+		}
+		offset = *next
+	}
+}
+
+func (s *Syncer) push(ctx context.Context, dryRun bool) (store.MergeResult, watermark, error) {
+	total := store.MergeResult{DryRun: dryRun}
+	mark := s.lastPush
+
+	for offset := 0; ; offset += pageSize {
+		page, err := s.st.ListLabelChanges(ctx, s.lastPush.at, pageSize, offset)
+		if err != nil {
+			return total, mark, err
+		}
+		var changes []store.LabelChange
+		changes, mark = freshChanges(s.lastPush, mark, page)
+		if len(changes) > 0 {
+			res, err := s.postMerge(ctx, changes, dryRun)
+			if err != nil {
+				return total, mark, err
+			}
+			addResult(&total, res)
+		}
+		if len(page) < pageSize {
+			return total, mark, nil
+		}
+	}
+}
+
+func (s *Syncer) fetchChanges(ctx context.Context, since time.Time, offset int) ([]store.LabelChange, *int, error) {
+	q := url.Values{}
+	q.Set("limit", strconv.Itoa(pageSize))
+	q.Set("offset", strconv.Itoa(offset))
+	if !since.IsZero() {
+		q.Set("since", since.UTC().Format(time.RFC3339))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.peerURL+"/api/labels/changes?"+q.Encode(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("GET changes: status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Items      []store.LabelChange `json:"items"`
+		NextOffset *int                `json:"next_offset"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, nil, fmt.Errorf("decode changes: %w", err)
+	}
+	return body.Items, body.NextOffset, nil
+}
+
+func (s *Syncer) postMerge(ctx context.Context, labels []store.LabelChange, dryRun bool) (store.MergeResult, error) {
+	payload, err := json.Marshal(map[string]any{
+		"source":  s.selfName,
+		"dry_run": dryRun,
+		"labels":  labels,
+	})
+	if err != nil {
+		return store.MergeResult{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.peerURL+"/api/labels/merge", bytes.NewReader(payload))
+	if err != nil {
+		return store.MergeResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return store.MergeResult{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return store.MergeResult{}, fmt.Errorf("POST merge: status %d", resp.StatusCode)
+	}
+
+	var res store.MergeResult
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return store.MergeResult{}, fmt.Errorf("decode merge result: %w", err)
+	}
+	return res, nil
+}
+
+func addResult(total *store.MergeResult, r store.MergeResult) {
+	total.Created += r.Created
+	total.Updated += r.Updated
+	total.Unchanged += r.Unchanged
+	total.Older += r.Older
+	total.UnknownImage += r.UnknownImage
+	total.Items = append(total.Items, r.Items...)
+}
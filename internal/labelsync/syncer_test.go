@@ -0,0 +1,221 @@
+package labelsync
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+var t0 = time.Date(2024, 10, 3, 21, 30, 0, 0, time.UTC)
+
+func openStore(t *testing.T, name string, shas ...string) *store.Store {
+	t.Helper()
+	st, err := store.Open(filepath.Join(t.TempDir(), name+".db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	// Image IDs are local filenames; only the hash is shared
+	for _, sha := range shas {
+		if err := st.UpsertImage(context.Background(), name+"_"+sha, "/data/"+sha+".jpg", sha, t0, 100); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return st
+}
+
+func label(t *testing.T, st *store.Store, sha, skystate string, at time.Time) {
+	t.Helper()
+	id, _, err := st.GetLabelBySHA256(context.Background(), sha)
+	if err != nil || id == "" {
+		t.Fatalf("no image %s: %v", sha, err)
+	}
+	if err := st.WriteLabel(context.Background(), store.LabelWrite{ImageID: id, Skystate: skystate, LabeledAt: at}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// labelOf returns the skystate of the image with sha in st, "" if unlabeled.
+func labelOf(t *testing.T, st *store.Store, sha string) string {
+	t.Helper()
+	_, l, err := st.GetLabelBySHA256(context.Background(), sha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l == nil {
+		return ""
+	}
+	return l.Skystate
+}
+
+// peer serves the label sync endpoints of another instance from st, like
+// the API does, and counts the merges pushed to it.
+type peer struct {
+	st     *store.Store
+	merges atomic.Int32
+}
+
+func newPeer(t *testing.T, st *store.Store) (*peer, string) {
+	t.Helper()
+	p := &peer{st: st}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/labels/changes", func(w http.ResponseWriter, r *http.Request) {
+		var since time.Time
+		if raw := r.URL.Query().Get("since"); raw != "" {
+			since, _ = time.Parse(time.RFC3339, raw)
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		items, err := st.ListLabelChanges(r.Context(), since, limit+1, offset)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var next any
+		if len(items) > limit {
+			items, next = items[:limit], offset+limit
+		}
+		json.NewEncoder(w).Encode(map[string]any{"items": items, "next_offset": next})
+	})
+	mux.HandleFunc("POST /api/labels/merge", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Source string              `json:"source"`
+			DryRun bool                `json:"dry_run"`
+			Labels []store.LabelChange `json:"labels"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		p.merges.Add(1)
+		res, err := st.MergeLabels(r.Context(), req.Labels, store.LabelSourceSyncPrefix+req.Source, req.DryRun)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(res)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return p, srv.URL
+}
+
+func syncOnce(t *testing.T, s *Syncer, dryRun bool) Report {
+	t.Helper()
+	rep, err := s.SyncOnce(context.Background(), dryRun)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rep
+}
+
+// TestSyncSameSecond checks a label written in the same second as the last
+// one pulled (or pushed) is still exchanged, and nothing is exchanged twice.
+func TestSyncSameSecond(t *testing.T) {
+	local := openStore(t, "local", "a", "b", "c", "d")
+	remote := openStore(t, "remote", "a", "b", "c", "d")
+	p, url := newPeer(t, remote)
+	s := New(local, url, "local", time.Hour, false)
+
+	label(t, remote, "a", "clear", t0)
+	label(t, local, "c", "clear", t0.Add(time.Minute))
+	if rep := syncOnce(t, s, false); rep.Pulled.Created != 1 || rep.Pushed.Created != 1 {
+		t.Fatalf("first round %+v", rep)
+	}
+
+	// Same seconds as the watermarks
+	label(t, remote, "b", "heavy_clouds", t0)
+	label(t, local, "d", "precipitation", t0.Add(time.Minute))
+	rep := syncOnce(t, s, false)
+	actions := map[string]string{}
+	for _, it := range rep.Pulled.Items {
+		actions[it.SHA256] = it.Action
+	}
+	// c and d come back from the peer unchanged; a isn't pulled again
+	if actions["b"] != store.MergeCreated || actions["a"] != "" || rep.Pulled.Created != 1 {
+		t.Fatalf("pulled %+v, want b created and not a", rep.Pulled)
+	}
+	if labelOf(t, local, "b") != "heavy_clouds" || labelOf(t, remote, "d") != "precipitation" {
+		t.Fatalf("same-second labels not exchanged: local b %q, remote d %q", labelOf(t, local, "b"), labelOf(t, remote, "d"))
+	}
+
+	// Once d has come back from the peer, nothing is merged on either side
+	syncOnce(t, s, false)
+	merges := p.merges.Load()
+	rep = syncOnce(t, s, false)
+	if len(rep.Pulled.Items) != 0 || len(rep.Pushed.Items) != 0 || p.merges.Load() != merges {
+		t.Fatalf("idle round %+v, %d merges", rep, p.merges.Load()-merges)
+	}
+}
+
+func TestSyncConflicts(t *testing.T) {
+	local := openStore(t, "local", "a", "b", "c", "d")
+	remote := openStore(t, "remote", "a", "b", "c", "e")
+	_, url := newPeer(t, remote)
+	s := New(local, url, "local", time.Hour, false)
+
+	label(t, local, "a", "clear", t0.Add(time.Hour)) // newer here
+	label(t, remote, "a", "heavy_clouds", t0)
+	label(t, local, "b", "clear", t0) // newer there
+	label(t, remote, "b", "light_clouds", t0.Add(time.Hour))
+	label(t, local, "c", "clear", t0) // the same on both
+	label(t, remote, "c", "clear", t0)
+	label(t, remote, "e", "clear", t0) // no such image here
+	label(t, local, "d", "clear", t0)  // nor there
+
+	rep := syncOnce(t, s, false)
+	if p := rep.Pulled; p.Older != 1 || p.Updated != 1 || p.Unchanged != 1 || p.UnknownImage != 1 || p.Created != 0 {
+		t.Fatalf("pulled %+v", p)
+	}
+	// b came back from the peer; a and d go to it
+	if p := rep.Pushed; p.Updated != 1 || p.UnknownImage != 1 || p.Created != 0 || p.Older != 0 {
+		t.Fatalf("pushed %+v", p)
+	}
+	for _, sha := range []string{"a", "b", "c"} {
+		if l, r := labelOf(t, local, sha), labelOf(t, remote, sha); l != r {
+			t.Fatalf("%s: local %q, remote %q", sha, l, r)
+		}
+	}
+	if labelOf(t, local, "a") != "clear" || labelOf(t, local, "b") != "light_clouds" {
+		t.Fatalf("the newer label didn't win: a %q, b %q", labelOf(t, local, "a"), labelOf(t, local, "b"))
+	}
+}
+
+func TestSyncDryRun(t *testing.T) {
+	local := openStore(t, "local", "a", "b")
+	remote := openStore(t, "remote", "a", "b")
+	_, url := newPeer(t, remote)
+	label(t, remote, "a", "clear", t0)
+	label(t, local, "b", "precipitation", t0)
+
+	for _, s := range map[string]*Syncer{
+		"requested":  New(local, url, "local", time.Hour, false),
+		"configured": New(local, url, "local", time.Hour, true),
+	} {
+		rep := syncOnce(t, s, true)
+		if !rep.Pulled.DryRun || !rep.Pushed.DryRun || rep.Pulled.Created != 1 || rep.Pushed.Created != 1 {
+			t.Fatalf("dry run %+v", rep)
+		}
+		if labelOf(t, local, "a") != "" || labelOf(t, remote, "b") != "" {
+			t.Fatal("a dry run wrote labels")
+		}
+	}
+
+	// The dry run left the watermarks alone
+	s := New(local, url, "local", time.Hour, false)
+	syncOnce(t, s, true)
+	if rep := syncOnce(t, s, false); rep.Pulled.Created != 1 || rep.Pushed.Created != 1 {
+		t.Fatalf("after a dry run %+v", rep)
+	}
+	if labelOf(t, local, "a") != "clear" || labelOf(t, remote, "b") != "precipitation" {
+		t.Fatal("labels not exchanged after the dry run")
+	}
+}
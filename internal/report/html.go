@@ -0,0 +1,43 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+)
+
+var nightTmpl = template.Must(template.New("night").Funcs(template.FuncMap{
+	"pct": func(v *float64) string {
+		if v == nil {
+			return "–"
+		}
+		return fmt.Sprintf("%.0f%%", *v*100)
+	},
+}).Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><title>SkyClf night {{.Date}}</title>
+<style>
+body{font-family:sans-serif;margin:2em;background:#0b1020;color:#dde}
+table{border-collapse:collapse}td,th{padding:.2em .6em;border-bottom:1px solid #334}
+.hl{display:inline-block;margin-right:1em;vertical-align:top}.hl img{max-width:320px;display:block}
+</style></head><body>
+<h1>Night of {{.Date}}</h1>
+<p>{{.Window.Start.Format "2006-01-02 15:04"}} – {{.Window.End.Format "2006-01-02 15:04"}} UTC
+{{if .Window.Astronomical}}(astronomical night){{else}}(noon to noon){{end}}</p>
+<p>{{.TotalFrames}} frames, {{.ClassifiedFrames}} classified, {{printf "%.1f" .ClearHours}} clear hours,
+{{.Meteors.Frames}} meteor frames ({{.Meteors.Regions}} regions)</p>
+<h2>Highlights</h2>
+{{with .Highlights.Meteor}}<div class="hl"><a href="{{.URL}}"><img src="{{.URL}}" alt=""></a>Meteor · {{.FetchedAt.Format "15:04"}}</div>{{end}}
+{{with .Highlights.FirstClear}}<div class="hl"><a href="{{.URL}}"><img src="{{.URL}}" alt=""></a>First clear · {{.FetchedAt.Format "15:04"}}</div>{{end}}
+{{with .Highlights.LastFrame}}<div class="hl"><a href="{{.URL}}"><img src="{{.URL}}" alt=""></a>Last frame · {{.FetchedAt.Format "15:04"}}</div>{{end}}
+<h2>Timeline</h2>
+<table><tr><th>Hour (UTC)</th><th>Frames</th><th>Sky</th><th>Cloud cover</th></tr>
+{{range .Timeline}}<tr><td>{{.Start.Format "15:04"}}</td><td>{{.Frames}}</td><td>{{.Dominant}}</td><td>{{pct .CloudCover}}</td></tr>
+{{end}}</table>
+<p><small>generated {{.GeneratedAt.Format "2006-01-02 15:04:05"}} UTC</small></p>
+</body></html>
+`))
+
+// RenderHTML writes r as a standalone HTML page.
+func RenderHTML(w io.Writer, r *NightReport) error {
+	return nightTmpl.Execute(w, r)
+}
@@ -0,0 +1,309 @@
+// Package report builds per-night summaries from stored images, labels and
+// predictions.
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/fetcher"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// KindNight is the store report kind of night reports.
+const KindNight = "night"
+
+const (
+	astroNightAltitude = -18.0            // sun altitude of astronomical dusk/dawn
+	debounceWindow     = 5                // frames voting on the sky state
+	maxFrameGap        = 10 * time.Minute // longer gaps (outages) don't count as clear time
+	maxMeteorIDs       = 50
+)
+
+// cloudCover maps sky states to a 0 (clear) .. 1 (overcast) cover value;
+// states missing here (unknown) don't count.
+var cloudCover = map[string]float64{
+	"clear":         0,
+	"light_clouds":  0.5,
+	"heavy_clouds":  1,
+	"precipitation": 1,
+}
+
+// Site is the observing location used to find astronomical night.
+type Site struct {
+	Lat, Lon float64
+}
+
+// Window is the time span a night report covers.
+type Window struct {
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	Astronomical bool      `json:"astronomical"` // dusk to dawn; false = noon to noon
+}
+
+// NightWindow returns the night starting on the evening of date. With a site
+// it runs from astronomical dusk to dawn; without one, from noon to noon in
+// loc, and when the sun never gets 18° below the horizon, from solar noon to
+// noon.
+func NightWindow(date time.Time, site *Site, loc *time.Location) Window {
+	if site == nil {
+		return Window{
+			Start: time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, loc).UTC(),
+			End:   time.Date(date.Year(), date.Month(), date.Day()+1, 12, 0, 0, 0, loc).UTC(),
+		}
+	}
+	noon := time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, time.UTC)
+	noon = noon.Add(-time.Duration(site.Lon / 15 * float64(time.Hour)))
+	w := Window{Start: noon, End: noon.Add(24 * time.Hour)}
+
+	var dusk time.Time
+	for t := noon; t.Before(noon.Add(24 * time.Hour)); t = t.Add(time.Minute) {
+		dark := fetcher.SunAltitude(t, site.Lat, site.Lon) < astroNightAltitude
+		if dusk.IsZero() {
+			if dark {
+				dusk = t
+			}
+			continue
+		}
+		if !dark {
+			return Window{Start: dusk, End: t, Astronomical: true}
+		}
+	}
+	return w
+}
+
+// NightReport summarizes one night.
+type NightReport struct {
+	Date             string        `json:"date"`
+	Window           Window        `json:"window"`
+	TotalFrames      int           `json:"total_frames"`
+	ClassifiedFrames int           `json:"classified_frames"` // labeled or predicted
+	ClearHours       float64       `json:"clear_hours"`
+	Timeline         []Bucket      `json:"timeline"`
+	Meteors          MeteorSummary `json:"meteors"`
+	Highlights       Highlights    `json:"highlights"`
+	GeneratedAt      time.Time     `json:"generated_at"`
+}
+
+// Bucket is one hour of the cloud-cover timeline.
+type Bucket struct {
+	Start      time.Time      `json:"start"`
+	Frames     int            `json:"frames"`
+	States     map[string]int `json:"states"`
+	Dominant   string         `json:"dominant,omitempty"`
+	CloudCover *float64       `json:"cloud_cover"` // mean 0..1; null without classified frames
+}
+
+// MeteorSummary counts frames labeled or annotated as containing a meteor.
+type MeteorSummary struct {
+	Frames   int      `json:"frames"`
+	Regions  int      `json:"regions"`
+	ImageIDs []string `json:"image_ids"`
+}
+
+// Highlights are the frames worth looking at first.
+type Highlights struct {
+	Meteor     *Frame `json:"meteor"` // most meteor regions, else first meteor label
+	FirstClear *Frame `json:"first_clear"`
+	LastFrame  *Frame `json:"last_frame"`
+}
+
+// Frame references one image of the night.
+type Frame struct {
+	ImageID   string    `json:"image_id"`
+	FetchedAt time.Time `json:"fetched_at"`
+	URL       string    `json:"url"`
+	State     string    `json:"state,omitempty"`
+}
+
+// Build computes the report for date (the evening the night starts) from frames.
+func Build(date time.Time, w Window, frames []store.NightFrame) *NightReport {
+	r := &NightReport{
+		Date:        date.Format("2006-01-02"),
+		Window:      w,
+		TotalFrames: len(frames),
+		Timeline:    []Bucket{},
+		Meteors:     MeteorSummary{ImageIDs: []string{}},
+		GeneratedAt: time.Now().UTC(),
+	}
+
+	// Labels win over predictions; the debounced state ignores single outliers.
+	states := make([]string, len(frames))
+	var recent []string
+	debounced := make([]string, len(frames))
+	for i, f := range frames {
+		states[i] = f.Label
+		if states[i] == "" {
+			states[i] = f.Predicted
+		}
+		if states[i] != "" {
+			r.ClassifiedFrames++
+			recent = append(recent, states[i])
+			if len(recent) > debounceWindow {
+				recent = recent[1:]
+			}
+		}
+		debounced[i] = majority(recent)
+	}
+
+	var clear time.Duration
+	for i := 0; i+1 < len(frames); i++ {
+		if debounced[i] == "clear" {
+			clear += min(frames[i+1].FetchedAt.Sub(frames[i].FetchedAt), maxFrameGap)
+		}
+	}
+	r.ClearHours = float64(clear.Round(time.Minute)) / float64(time.Hour)
+
+	r.Timeline = timeline(w, frames, states)
+
+	bestRegions := 0
+	for i, f := range frames {
+		if f.Meteor || f.MeteorRegions > 0 {
+			r.Meteors.Frames++
+			r.Meteors.Regions += f.MeteorRegions
+			if len(r.Meteors.ImageIDs) < maxMeteorIDs {
+				r.Meteors.ImageIDs = append(r.Meteors.ImageIDs, f.ImageID)
+			}
+			if r.Highlights.Meteor == nil || f.MeteorRegions > bestRegions {
+				r.Highlights.Meteor = frameRef(f, states[i])
+				bestRegions = f.MeteorRegions
+			}
+		}
+		if r.Highlights.FirstClear == nil && debounced[i] == "clear" && states[i] == "clear" {
+			r.Highlights.FirstClear = frameRef(f, states[i])
+		}
+	}
+	if n := len(frames); n > 0 {
+		r.Highlights.LastFrame = frameRef(frames[n-1], states[n-1])
+	}
+	return r
+}
+
+func timeline(w Window, frames []store.NightFrame, states []string) []Bucket {
+	var out []Bucket
+	i := 0
+	for start := w.Start.Truncate(time.Hour); start.Before(w.End); start = start.Add(time.Hour) {
+		b := Bucket{Start: start, States: map[string]int{}}
+		var cover float64
+		var covered int
+		for ; i < len(frames) && frames[i].FetchedAt.Before(start.Add(time.Hour)); i++ {
+			if frames[i].FetchedAt.Before(start) {
+				continue
+			}
+			b.Frames++
+			if s := states[i]; s != "" {
+				b.States[s]++
+				if c, ok := cloudCover[s]; ok {
+					cover += c
+					covered++
+				}
+			}
+		}
+		for s, n := range b.States {
+			if b.Dominant == "" || n > b.States[b.Dominant] || (n == b.States[b.Dominant] && s < b.Dominant) {
+				b.Dominant = s
+			}
+		}
+		if covered > 0 {
+			c := cover / float64(covered)
+			b.CloudCover = &c
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+// majority returns the most common state in votes (earliest wins ties).
+func majority(votes []string) string {
+	best, bestN := "", 0
+	counts := map[string]int{}
+	for _, v := range votes {
+		counts[v]++
+	}
+	for _, v := range votes {
+		if counts[v] > bestN {
+			best, bestN = v, counts[v]
+		}
+	}
+	return best
+}
+
+func frameRef(f store.NightFrame, state string) *Frame {
+	return &Frame{
+		ImageID:   f.ImageID,
+		FetchedAt: f.FetchedAt,
+		URL:       "/images/" + filepath.Base(f.Path),
+		State:     state,
+	}
+}
+
+// Generator builds night reports and caches those of finished nights.
+type Generator struct {
+	st   *store.Store
+	site *Site
+}
+
+// NewGenerator creates a generator; site may be nil (noon-to-noon nights).
+func NewGenerator(st *store.Store, site *Site) *Generator {
+	return &Generator{st: st, site: site}
+}
+
+// Night returns the report for the night starting on date (YYYY-MM-DD).
+// Reports of finished nights come from the cache unless refresh is set;
+// a night still in progress is built fresh and not cached.
+func (g *Generator) Night(ctx context.Context, date string, refresh bool) (*NightReport, error) {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q; use YYYY-MM-DD", date)
+	}
+	if !refresh {
+		body, err := g.st.GetReport(ctx, KindNight, date)
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			var r NightReport
+			if err := json.Unmarshal(body, &r); err == nil {
+				return &r, nil
+			}
+		}
+	}
+
+	w := NightWindow(day, g.site, g.st.DayGrouping().Location())
+	frames, err := g.st.ListFramesBetween(ctx, w.Start, w.End)
+	if err != nil {
+		return nil, err
+	}
+	r := Build(day, w, frames)
+	if time.Now().After(w.End) {
+		body, err := json.Marshal(r)
+		if err != nil {
+			return nil, err
+		}
+		if err := g.st.SaveReport(ctx, KindNight, date, body, r.GeneratedAt); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// LastFinishedNight returns the date of the most recent night whose window has ended.
+func (g *Generator) LastFinishedNight(now time.Time) string {
+	loc := g.st.DayGrouping().Location()
+	local := now.In(loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	for {
+		day = day.AddDate(0, 0, -1)
+		if now.After(NightWindow(day, g.site, loc).End) {
+			return day.Format("2006-01-02")
+		}
+	}
+}
+
+// List returns the cached night reports, newest first.
+func (g *Generator) List(ctx context.Context) ([]store.ReportInfo, error) {
+	return g.st.ListReports(ctx, KindNight)
+}
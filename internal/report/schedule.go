@@ -0,0 +1,53 @@
+package report
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/webhook"
+)
+
+// scheduleInterval is how often the scheduler checks whether a night has ended.
+const scheduleInterval = 5 * time.Minute
+
+// Schedule generates each night's report once its window has ended (after
+// astronomical dawn with a site) and sends a night_report_ready event. It
+// blocks until ctx is canceled. A night whose report is already cached at
+// startup is not announced again.
+func (g *Generator) Schedule(ctx context.Context, notify *webhook.Notifier) {
+	announced := ""
+	if last := g.LastFinishedNight(time.Now()); last != "" {
+		if body, err := g.st.GetReport(ctx, KindNight, last); err == nil && body != nil {
+			announced = last
+		}
+	}
+
+	t := time.NewTicker(scheduleInterval)
+	defer t.Stop()
+	for {
+		if date := g.LastFinishedNight(time.Now()); date != announced {
+			r, err := g.Night(ctx, date, false)
+			if err != nil {
+				log.Printf("report: night %s: %v", date, err)
+			} else {
+				log.Printf("report: night %s ready (%d frames, %.1f clear hours)", date, r.TotalFrames, r.ClearHours)
+				if err := notify.Send(ctx, webhook.EventNightReportReady, map[string]any{
+					"date":        r.Date,
+					"url":         "/api/reports/night?date=" + r.Date,
+					"frames":      r.TotalFrames,
+					"clear_hours": r.ClearHours,
+					"meteors":     r.Meteors.Frames,
+				}); err != nil {
+					log.Printf("report: %v", err)
+				}
+				announced = date
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
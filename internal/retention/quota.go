@@ -0,0 +1,233 @@
+// Package retention keeps the image store within a quota by evicting the
+// oldest frames, unlabeled ones first.
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/storage"
+	"github.com/SkyClf/SkyClf/internal/store"
+	"github.com/SkyClf/SkyClf/internal/webhook"
+)
+
+// maxReportedIDs bounds the labeled image IDs kept in a Result.
+const maxReportedIDs = 50
+
+// Result describes one quota run.
+type Result struct {
+	DryRun         bool     `json:"dry_run,omitempty"`
+	Evicted        int      `json:"evicted"`
+	EvictedLabeled int      `json:"evicted_labeled"`
+	FreedBytes     int64    `json:"freed_bytes"`
+	LabeledIDs     []string `json:"labeled_ids,omitempty"` // first few evicted labeled images
+	// OverQuota is set when the quota couldn't be met without evicting
+	// labeled (if not allowed) or protected images.
+	OverQuota bool `json:"over_quota"`
+}
+
+// Status is what GET /api/admin/retention reports.
+type Status struct {
+	Enabled      bool             `json:"enabled"`
+	MaxImages    int              `json:"max_images,omitempty"`
+	MaxBytes     int64            `json:"max_bytes,omitempty"`
+	EvictLabeled bool             `json:"evict_labeled"`
+	Interval     string           `json:"interval,omitempty"`
+	Usage        store.QuotaUsage `json:"usage"`
+	LastRun      *time.Time       `json:"last_run,omitempty"`
+	LastError    string           `json:"last_error,omitempty"`
+	Last         *Result          `json:"last,omitempty"`
+
+	// Totals since the server started
+	Evicted        int   `json:"evicted_total"`
+	EvictedLabeled int   `json:"evicted_labeled_total"`
+	FreedBytes     int64 `json:"freed_bytes_total"`
+}
+
+// Quota enforces a store.Quota periodically.
+type Quota struct {
+	st       *store.Store
+	quota    store.Quota
+	interval time.Duration
+	notifier *webhook.Notifier
+	storage  *storage.Monitor
+
+	// InUse returns paths that must not be evicted, e.g. the file list of a
+	// running training job. Optional.
+	InUse func(ctx context.Context) ([]string, error)
+
+	runMu sync.Mutex // one run at a time
+	mu    sync.Mutex
+	last  *Result
+	// lastRun and lastErr describe the last run, dry or not
+	lastRun                 time.Time
+	lastErr                 string
+	evicted, evictedLabeled int
+	freedBytes              int64
+}
+
+// NewQuota creates a job enforcing q every interval.
+func NewQuota(st *store.Store, q store.Quota, interval time.Duration) *Quota {
+	return &Quota{st: st, quota: q, interval: interval}
+}
+
+// SetNotifier sends a webhook event whenever labeled images are evicted.
+func (j *Quota) SetNotifier(n *webhook.Notifier) {
+	j.notifier = n
+}
+
+// SetStorageMonitor skips runs while the data directory is unavailable.
+func (j *Quota) SetStorageMonitor(m *storage.Monitor) {
+	j.storage = m
+}
+
+// Start runs immediately and then every interval until ctx is canceled.
+func (j *Quota) Start(ctx context.Context) {
+	t := time.NewTicker(j.interval)
+	defer t.Stop()
+	for {
+		if _, err := j.Run(ctx, false); err != nil && ctx.Err() == nil {
+			log.Printf("retention: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Run evicts images until the store is within the quota. A dry run only
+// reports what would go.
+func (j *Quota) Run(ctx context.Context, dryRun bool) (Result, error) {
+	j.runMu.Lock()
+	defer j.runMu.Unlock()
+
+	res, err := j.run(ctx, dryRun)
+	j.mu.Lock()
+	j.lastRun = time.Now().UTC()
+	j.lastErr = ""
+	if err != nil {
+		j.lastErr = err.Error()
+	}
+	if !dryRun {
+		j.last = &res
+		j.evicted += res.Evicted
+		j.evictedLabeled += res.EvictedLabeled
+		j.freedBytes += res.FreedBytes
+	}
+	j.mu.Unlock()
+	return res, err
+}
+
+func (j *Quota) run(ctx context.Context, dryRun bool) (Result, error) {
+	res := Result{DryRun: dryRun}
+	if !j.quota.Enabled() {
+		return res, nil
+	}
+	if err := j.storage.Check(); err != nil {
+		return res, err
+	}
+
+	keep, err := j.protected(ctx)
+	if err != nil {
+		return res, err
+	}
+	evs, over, err := j.st.QuotaEvictions(ctx, j.quota, keep)
+	if err != nil {
+		return res, err
+	}
+	res.OverQuota = over
+
+	for _, e := range evs {
+		if !dryRun {
+			if err := j.st.DeleteImage(ctx, e.ID); err != nil {
+				return res, fmt.Errorf("delete image %s: %w", e.ID, err)
+			}
+			if err := os.Remove(e.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				log.Printf("retention: remove %s: %v", e.Path, err)
+			}
+		}
+		res.Evicted++
+		res.FreedBytes += e.SizeBytes
+		if e.Labeled {
+			res.EvictedLabeled++
+			if len(res.LabeledIDs) < maxReportedIDs {
+				res.LabeledIDs = append(res.LabeledIDs, e.ID)
+			}
+		}
+	}
+
+	if dryRun || res.Evicted == 0 {
+		return res, nil
+	}
+	log.Printf("retention: evicted %d images (%d labeled), freed %d bytes", res.Evicted, res.EvictedLabeled, res.FreedBytes)
+	if over {
+		log.Printf("retention: still over quota; only protected images are left to evict")
+	}
+	if res.EvictedLabeled > 0 {
+		if err := j.notifier.Send(ctx, webhook.EventLabeledEvicted, res); err != nil {
+			log.Printf("retention: %v", err)
+		}
+	}
+	return res, nil
+}
+
+// protected returns the paths never to evict: the image served as the latest
+// and whatever InUse reports.
+func (j *Quota) protected(ctx context.Context) (map[string]bool, error) {
+	keep := map[string]bool{}
+	latest, err := j.st.GetLatest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil {
+		keep[latest.Path] = true
+	}
+	if j.InUse != nil {
+		paths, err := j.InUse(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("paths in use: %w", err)
+		}
+		for _, p := range paths {
+			keep[p] = true
+		}
+	}
+	return keep, nil
+}
+
+// Status reports the quota, the current usage and what the job has done.
+func (j *Quota) Status(ctx context.Context) (Status, error) {
+	u, err := j.st.QuotaUsage(ctx)
+	if err != nil {
+		return Status{}, err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	s := Status{
+		Enabled:        j.quota.Enabled(),
+		MaxImages:      j.quota.MaxImages,
+		MaxBytes:       j.quota.MaxBytes,
+		EvictLabeled:   j.quota.EvictLabeled,
+		Usage:          u,
+		LastError:      j.lastErr,
+		Last:           j.last,
+		Evicted:        j.evicted,
+		EvictedLabeled: j.evictedLabeled,
+		FreedBytes:     j.freedBytes,
+	}
+	if s.Enabled {
+		s.Interval = j.interval.String()
+	}
+	if !j.lastRun.IsZero() {
+		lastRun := j.lastRun
+		s.LastRun = &lastRun
+	}
+	return s, nil
+}
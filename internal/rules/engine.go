@@ -0,0 +1,291 @@
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/mqtt"
+	"github.com/SkyClf/SkyClf/internal/store"
+	"github.com/SkyClf/SkyClf/internal/webhook"
+)
+
+// TickInterval is how often the engine looks at the sky state and probes.
+const TickInterval = 15 * time.Second
+
+// EventRuleFired is the webhook event of a webhook action.
+const EventRuleFired = "rule_fired"
+
+// Observation is what the engine sees at one tick.
+type Observation struct {
+	At         time.Time `json:"at"`
+	State      string    `json:"state"`      // debounced sky state; "" keeps the previous one
+	Confidence float64   `json:"confidence"` // of the newest prediction
+	Events     []string  `json:"events"`     // system events that began since the last tick
+}
+
+// Firing is one rule firing, as passed to its action.
+type Firing struct {
+	RuleID int64     `json:"rule_id"`
+	Name   string    `json:"name"`
+	At     time.Time `json:"at"`
+	State  string    `json:"state,omitempty"` // state rules: the state and since when
+	Since  time.Time `json:"since,omitzero"`
+	Event  string    `json:"event,omitempty"` // event rules
+}
+
+// evaluator decides which rules fire, from observations alone, so the engine
+// and Simulate share it.
+type evaluator struct {
+	loc   *time.Location
+	rules []*rule
+
+	state   string    // debounced state
+	since   time.Time // since when
+	changed bool      // state was reached by a change, not seen first
+
+	firedFor  map[int64]time.Time // rule ID -> since of the stretch it fired for
+	lastFired map[int64]time.Time
+}
+
+func newEvaluator(loc *time.Location) *evaluator {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &evaluator{loc: loc, firedFor: map[int64]time.Time{}, lastFired: map[int64]time.Time{}}
+}
+
+func (ev *evaluator) step(obs Observation) []Firing {
+	if obs.State != "" && obs.State != ev.state {
+		ev.changed = ev.state != ""
+		ev.state, ev.since = obs.State, obs.At
+	}
+
+	var out []Firing
+	for _, r := range ev.rules {
+		if r.spec.Disabled || !r.inWindow(obs.At.In(ev.loc)) {
+			continue
+		}
+		if last, ok := ev.lastFired[r.id]; ok && obs.At.Sub(last) < r.cooldown {
+			continue
+		}
+		f := Firing{RuleID: r.id, Name: r.spec.Name, At: obs.At}
+		c := r.spec.Condition
+		switch c.Kind {
+		case KindState:
+			if ev.state != c.State || (c.ChangedTo && !ev.changed) ||
+				obs.At.Sub(ev.since) < r.forDur || obs.Confidence < c.MinConfidence {
+				continue
+			}
+			if fired, ok := ev.firedFor[r.id]; ok && fired.Equal(ev.since) {
+				continue
+			}
+			ev.firedFor[r.id] = ev.since
+			f.State, f.Since = ev.state, ev.since
+		case KindEvent:
+			if !slices.Contains(obs.Events, c.Event) {
+				continue
+			}
+			f.Event = c.Event
+		}
+		ev.lastFired[r.id] = obs.At
+		out = append(out, f)
+	}
+	return out
+}
+
+// Simulate runs specs over observations (in time order) from a fresh start
+// and returns the firings, without running any action. Rules are numbered
+// from 1 in the order given.
+func Simulate(specs []Spec, obs []Observation, loc *time.Location) ([]Firing, error) {
+	ev := newEvaluator(loc)
+	for i, s := range specs {
+		r, err := compile(int64(i+1), s)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		ev.rules = append(ev.rules, r)
+	}
+	sorted := slices.Clone(obs)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].At.Before(sorted[j].At) })
+	out := []Firing{}
+	for _, o := range sorted {
+		out = append(out, ev.step(o)...)
+	}
+	return out, nil
+}
+
+// Engine evaluates the stored rules every TickInterval and runs the actions
+// of those that fire.
+type Engine struct {
+	st         *store.Store
+	webhookURL string
+	mqtt       *mqtt.Publisher
+
+	mu     sync.Mutex
+	ev     *evaluator
+	probes map[string]func() bool
+	active map[string]bool // probe results at the last tick
+}
+
+// NewEngine returns an engine over the rules in st. webhookURL is the default
+// of webhook actions; pub may be nil when no broker is configured.
+func NewEngine(st *store.Store, loc *time.Location, webhookURL string, pub *mqtt.Publisher) *Engine {
+	return &Engine{
+		st:         st,
+		webhookURL: webhookURL,
+		mqtt:       pub,
+		ev:         newEvaluator(loc),
+		probes:     map[string]func() bool{},
+		active:     map[string]bool{},
+	}
+}
+
+// SetProbe makes event fire whenever active turns true.
+func (e *Engine) SetProbe(event string, active func() bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.probes[event] = active
+}
+
+// Validate checks a spec against the server's configuration too.
+func (e *Engine) Validate(s Spec) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	switch s.Action.Kind {
+	case ActionWebhook:
+		if s.Action.URL == "" && e.webhookURL == "" {
+			return fmt.Errorf("action: webhook needs a url (or SKYCLF_WEBHOOK_URL)")
+		}
+	case ActionMQTT:
+		if e.mqtt == nil {
+			return fmt.Errorf("action: mqtt needs SKYCLF_MQTT_URL")
+		}
+	}
+	return nil
+}
+
+// Reload reads the rules from the store. A rule that no longer validates is
+// logged and left out; cooldowns carry over from the stored last firing.
+func (e *Engine) Reload(ctx context.Context) error {
+	stored, err := e.st.ListRules(ctx)
+	if err != nil {
+		return err
+	}
+	var compiled []*rule
+	for _, sr := range stored {
+		var s Spec
+		if err := json.Unmarshal(sr.Spec, &s); err != nil {
+			log.Printf("rules: rule %d: %v", sr.ID, err)
+			continue
+		}
+		r, err := compile(sr.ID, s)
+		if err != nil {
+			log.Printf("rules: rule %d: %v", sr.ID, err)
+			continue
+		}
+		compiled = append(compiled, r)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ev.rules = compiled
+	for _, sr := range stored {
+		if sr.LastFiredAt != nil && sr.LastFiredAt.After(e.ev.lastFired[sr.ID]) {
+			e.ev.lastFired[sr.ID] = *sr.LastFiredAt
+		}
+	}
+	return nil
+}
+
+// Start loads the rules and evaluates them until ctx is done.
+func (e *Engine) Start(ctx context.Context) {
+	if err := e.Reload(ctx); err != nil {
+		log.Printf("rules: %v", err)
+	}
+	t := time.NewTicker(TickInterval)
+	defer t.Stop()
+	for {
+		e.tick(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (e *Engine) tick(ctx context.Context, now time.Time) {
+	obs := Observation{At: now}
+	recent, err := e.st.RecentPredictions(ctx, store.DebounceWindow)
+	if err != nil {
+		log.Printf("rules: %v", err)
+	} else if len(recent) > 0 {
+		obs.State = store.Debounce(recent)
+		obs.Confidence = recent[0].Confidence
+	}
+
+	e.mu.Lock()
+	for event, probe := range e.probes {
+		on := probe()
+		if on && !e.active[event] {
+			obs.Events = append(obs.Events, event)
+		}
+		e.active[event] = on
+	}
+	firings := e.ev.step(obs)
+	actions := make([]Action, len(firings))
+	for i, f := range firings {
+		for _, r := range e.ev.rules {
+			if r.id == f.RuleID {
+				actions[i] = r.spec.Action
+			}
+		}
+	}
+	e.mu.Unlock()
+
+	for i, f := range firings {
+		if err := e.st.SetRuleFired(ctx, f.RuleID, f.At); err != nil {
+			log.Printf("rules: %v", err)
+		}
+		if err := e.run(ctx, actions[i], f); err != nil {
+			log.Printf("rules: rule %d (%s): %v", f.RuleID, f.Name, err)
+		}
+	}
+}
+
+// run carries out a firing's action.
+func (e *Engine) run(ctx context.Context, a Action, f Firing) error {
+	switch a.Kind {
+	case ActionWebhook:
+		url := a.URL
+		if url == "" {
+			url = e.webhookURL
+		}
+		return webhook.New(url).Send(ctx, EventRuleFired, f)
+	case ActionMQTT:
+		payload, err := json.Marshal(f)
+		if err != nil {
+			return err
+		}
+		return e.mqtt.Publish(ctx, a.Topic, payload)
+	}
+	if f.Event != "" {
+		log.Printf("rules: %q fired on %s", f.Name, f.Event)
+	} else {
+		log.Printf("rules: %q fired: %s since %s", f.Name, f.State, f.Since.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Simulate runs specs over observations like the package Simulate, in the
+// engine's time zone.
+func (e *Engine) Simulate(specs []Spec, obs []Observation) ([]Firing, error) {
+	return Simulate(specs, obs, e.ev.loc)
+}
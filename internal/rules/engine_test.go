@@ -0,0 +1,266 @@
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+	_ "time/tzdata" // zones don't depend on the host
+
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// step is one observation, minutes after the start of a sequence.
+type step struct {
+	min    int
+	state  string
+	conf   float64
+	events []string
+}
+
+func stateRule(name, state, dur, cooldown string) Spec {
+	return Spec{
+		Name:      name,
+		Condition: Condition{Kind: KindState, State: state, For: dur},
+		Action:    Action{Kind: ActionLog},
+		Cooldown:  cooldown,
+	}
+}
+
+func eventRule(name, event, cooldown string) Spec {
+	return Spec{
+		Name:      name,
+		Condition: Condition{Kind: KindEvent, Event: event},
+		Action:    Action{Kind: ActionLog},
+		Cooldown:  cooldown,
+	}
+}
+
+// every returns steps of state from minute from to minute to, every 5 minutes.
+func every(from, to int, state string) []step {
+	var out []step
+	for m := from; m <= to; m += 5 {
+		out = append(out, step{min: m, state: state, conf: 0.9})
+	}
+	return out
+}
+
+func TestSimulate(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 21:00 local in Berlin, winter time
+	start := time.Date(2024, 1, 10, 20, 0, 0, 0, time.UTC)
+
+	changedTo := stateRule("turned clear", "clear", "", "0s")
+	changedTo.Condition.ChangedTo = true
+	confident := stateRule("surely clear", "clear", "", "0s")
+	confident.Condition.MinConfidence = 0.8
+	night := eventRule("hot at night", EventThermalHot, "0s")
+	night.Condition.Window = &Window{From: "22:00", To: "02:00"}
+	disabled := eventRule("off", EventTrainingFailed, "0s")
+	disabled.Disabled = true
+
+	tests := []struct {
+		name  string
+		specs []Spec
+		steps []step
+		want  []string // "rule@minute"
+	}{
+		{
+			name:  "held for the duration",
+			specs: []Spec{stateRule("clear 15m", "clear", "15m", "")},
+			steps: every(0, 40, "clear"),
+			want:  []string{"1@15"},
+		},
+		{
+			name:  "a flip restarts the duration",
+			specs: []Spec{stateRule("clear 15m", "clear", "15m", "0s")},
+			steps: slices.Concat(every(0, 10, "clear"), every(12, 12, "light_clouds"), every(14, 40, "clear")),
+			want:  []string{"1@29"},
+		},
+		{
+			name:  "once per stretch, again after a change",
+			specs: []Spec{stateRule("clear", "clear", "", "0s")},
+			steps: slices.Concat(every(0, 20, "clear"), every(25, 30, "heavy_clouds"), every(35, 45, "clear")),
+			want:  []string{"1@0", "1@35"},
+		},
+		{
+			name:  "cooldown holds back the next stretch",
+			specs: []Spec{stateRule("clear", "clear", "", "30m")},
+			steps: slices.Concat(every(0, 5, "clear"), every(10, 10, "heavy_clouds"), every(15, 60, "clear")),
+			want:  []string{"1@0", "1@30"},
+		},
+		{
+			name:  "empty state keeps the previous one",
+			specs: []Spec{stateRule("clear 10m", "clear", "10m", "")},
+			steps: []step{{min: 0, state: "clear"}, {min: 5}, {min: 10}, {min: 15}},
+			want:  []string{"1@10"},
+		},
+		{
+			name:  "changed_to ignores the state at startup",
+			specs: []Spec{changedTo},
+			steps: slices.Concat(every(0, 10, "clear"), every(15, 15, "precipitation"), every(20, 25, "clear")),
+			want:  []string{"1@20"},
+		},
+		{
+			name:  "min_confidence waits for a confident prediction",
+			specs: []Spec{confident},
+			steps: []step{{0, "clear", 0.5, nil}, {5, "clear", 0.7, nil}, {10, "clear", 0.85, nil}, {15, "clear", 0.95, nil}},
+			want:  []string{"1@10"},
+		},
+		{
+			name:  "event cooldown",
+			specs: []Spec{eventRule("failed", EventTrainingFailed, "1h")},
+			steps: []step{
+				{min: 0, events: []string{EventTrainingFailed}},
+				{min: 10, events: []string{EventTrainingFailed}},
+				{min: 59, events: []string{EventTrainingFailed}},
+				{min: 60, events: []string{EventTrainingFailed}},
+			},
+			want: []string{"1@0", "1@60"},
+		},
+		{
+			name:  "window over midnight, local time",
+			specs: []Spec{night},
+			steps: []step{
+				{min: 30, events: []string{EventThermalHot}},  // 21:30
+				{min: 60, events: []string{EventThermalHot}},  // 22:00
+				{min: 290, events: []string{EventThermalHot}}, // 01:50
+				{min: 300, events: []string{EventThermalHot}}, // 02:00
+			},
+			want: []string{"1@60", "1@290"},
+		},
+		{
+			name:  "disabled rule and other events",
+			specs: []Spec{disabled, eventRule("storage", EventStorageUnavailable, "")},
+			steps: []step{{min: 0, events: []string{EventTrainingFailed, EventStorageUnavailable}}},
+			want:  []string{"2@0"},
+		},
+		{
+			name:  "rules fire independently",
+			specs: []Spec{stateRule("clear", "clear", "", ""), stateRule("clear 10m", "clear", "10m", ""), stateRule("rain", "precipitation", "", "")},
+			steps: every(0, 20, "clear"),
+			want:  []string{"1@0", "2@10"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var obs []Observation
+			for _, s := range tt.steps {
+				obs = append(obs, Observation{At: start.Add(time.Duration(s.min) * time.Minute), State: s.state, Confidence: s.conf, Events: s.events})
+			}
+			firings, err := Simulate(tt.specs, obs, berlin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, f := range firings {
+				got = append(got, fmt.Sprintf("%d@%d", f.RuleID, int(f.At.Sub(start).Minutes())))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("fired %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestEngineTick drives the engine with stored predictions, a probe and an
+// injected clock, and checks firings are stored and their cooldown survives
+// a reload.
+func TestEngineTick(t *testing.T) {
+	ctx := context.Background()
+	st, err := store.Open(filepath.Join(t.TempDir(), "labels.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	for _, s := range []Spec{stateRule("clear 10m", "clear", "10m", ""), eventRule("hot", EventThermalHot, "1h")} {
+		b, err := json.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := st.CreateRule(ctx, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	e := NewEngine(st, time.UTC, "", nil)
+	if err := e.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	hot := false
+	e.SetProbe(EventThermalHot, func() bool { return hot })
+
+	start := time.Date(2024, 1, 10, 22, 0, 0, 0, time.UTC)
+	predict := func(i int, state string) {
+		t.Helper()
+		id := fmt.Sprintf("img%d", i)
+		if err := st.UpsertImage(ctx, id, "/data/"+id+".jpg", id, start, 100); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.RecordPrediction(ctx, store.PredictionRecord{ImageID: id, ModelVersion: "v1", Skystate: state, Confidence: 0.9, PredictedAt: start}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lastFired := func() map[int64]time.Time {
+		t.Helper()
+		rs, err := st.ListRules(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := map[int64]time.Time{}
+		for _, r := range rs {
+			if r.LastFiredAt != nil {
+				out[r.ID] = r.LastFiredAt.UTC()
+			}
+		}
+		return out
+	}
+
+	for i := range store.DebounceWindow {
+		predict(i, "clear")
+	}
+	e.tick(ctx, start)
+	e.tick(ctx, start.Add(5*time.Minute))
+	if got := lastFired(); len(got) != 0 {
+		t.Fatalf("fired before the duration: %v", got)
+	}
+	e.tick(ctx, start.Add(10*time.Minute))
+	if got := lastFired(); !got[1].Equal(start.Add(10*time.Minute)) || len(got) != 1 {
+		t.Fatalf("last fired %v, want rule 1 at +10m", got)
+	}
+
+	// The probe fires when it turns on, not while it stays on
+	hot = true
+	e.tick(ctx, start.Add(15*time.Minute))
+	e.tick(ctx, start.Add(20*time.Minute))
+	if got := lastFired(); !got[2].Equal(start.Add(15 * time.Minute)) {
+		t.Fatalf("last fired %v, want rule 2 at +15m", got)
+	}
+
+	// A restarted engine keeps the stored cooldown
+	e = NewEngine(st, time.UTC, "", nil)
+	if err := e.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	e.SetProbe(EventThermalHot, func() bool { return hot })
+	hot = false
+	e.tick(ctx, start.Add(30*time.Minute))
+	hot = true
+	e.tick(ctx, start.Add(35*time.Minute))
+	if got := lastFired(); !got[2].Equal(start.Add(15 * time.Minute)) {
+		t.Fatalf("fired within the cooldown after a reload: %v", got)
+	}
+	hot = false
+	e.tick(ctx, start.Add(80*time.Minute))
+	hot = true
+	e.tick(ctx, start.Add(85*time.Minute))
+	if got := lastFired(); !got[2].Equal(start.Add(85 * time.Minute)) {
+		t.Fatalf("last fired %v, want rule 2 at +85m", got)
+	}
+}
@@ -0,0 +1,195 @@
+// Package rules evaluates user-defined alerting rules, such as "the sky has
+// been clear for 15 minutes after midnight" or "training failed", against the
+// debounced sky state and system events, and runs their actions: a log line,
+// a webhook or an MQTT message.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// Condition kinds.
+const (
+	KindState = "state" // the debounced sky state
+	KindEvent = "event" // a system event
+)
+
+// System events a rule can react to. Each fires when its condition begins.
+const (
+	EventFetchDegraded      = "fetch_degraded"      // fetching failed DegradedFailures times in a row
+	EventStorageUnavailable = "storage_unavailable" // the images directory can't be written
+	EventTrainingFailed     = "training_failed"     // a training run ended with an error
+	EventThermalHot         = "thermal_hot"         // auto-predict and training paused for heat
+	EventClockSkew          = "clock_skew"          // the camera clock is off past SKYCLF_CLOCK_SKEW_WARN
+)
+
+// Events lists the system events.
+var Events = []string{EventFetchDegraded, EventStorageUnavailable, EventTrainingFailed, EventThermalHot, EventClockSkew}
+
+// DegradedFailures is how many failed fetches in a row make fetch_degraded.
+const DegradedFailures = 3
+
+// Action kinds.
+const (
+	ActionLog     = "log"
+	ActionWebhook = "webhook"
+	ActionMQTT    = "mqtt"
+)
+
+// DefaultCooldown is the cooldown of a rule that doesn't set one.
+const DefaultCooldown = 30 * time.Minute
+
+// Spec is a rule as stored and sent over the API.
+type Spec struct {
+	Name      string    `json:"name"`
+	Disabled  bool      `json:"disabled,omitempty"`
+	Condition Condition `json:"condition"`
+	Action    Action    `json:"action"`
+	// Cooldown is the least time between two firings, as a Go duration
+	// (default DefaultCooldown)
+	Cooldown string `json:"cooldown,omitempty"`
+}
+
+// Condition is what makes a rule fire.
+type Condition struct {
+	Kind string `json:"kind"` // KindState or KindEvent
+
+	// KindState: the debounced state is State. With ChangedTo it must have
+	// changed to it while the server watched, rather than being it at
+	// startup. For (a Go duration) is how long it must have held first.
+	// A rule fires once per stretch of the state, however long it lasts.
+	State         string  `json:"state,omitempty"`
+	ChangedTo     bool    `json:"changed_to,omitempty"`
+	For           string  `json:"for,omitempty"`
+	MinConfidence float64 `json:"min_confidence,omitempty"` // of the newest prediction
+
+	// KindEvent: one of Events
+	Event string `json:"event,omitempty"`
+
+	// Window limits firing to a time of day, for either kind
+	Window *Window `json:"window,omitempty"`
+}
+
+// Window is a time of day as "HH:MM" in SKYCLF_DISPLAY_TZ, From inclusive and
+// To exclusive; a window with To before From runs over midnight.
+type Window struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Action is what a rule does when it fires.
+type Action struct {
+	Kind  string `json:"kind"`            // ActionLog, ActionWebhook or ActionMQTT
+	URL   string `json:"url,omitempty"`   // ActionWebhook; default SKYCLF_WEBHOOK_URL
+	Topic string `json:"topic,omitempty"` // ActionMQTT
+}
+
+// rule is a Spec with its durations parsed.
+type rule struct {
+	id       int64
+	spec     Spec
+	forDur   time.Duration
+	cooldown time.Duration
+	from, to int // window in minutes after midnight; from == to means all day
+}
+
+// Validate checks a spec without regard to the server's configuration.
+func (s Spec) Validate() error {
+	_, err := compile(0, s)
+	return err
+}
+
+func compile(id int64, s Spec) (*rule, error) {
+	r := &rule{id: id, spec: s, cooldown: DefaultCooldown}
+	if strings.TrimSpace(s.Name) == "" {
+		return nil, errors.New("name is required")
+	}
+	c := s.Condition
+	switch c.Kind {
+	case KindState:
+		if !store.ValidSkystate(c.State) {
+			return nil, fmt.Errorf("condition: unknown state %q", c.State)
+		}
+		if c.For != "" {
+			d, err := time.ParseDuration(c.For)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("condition: for must be a duration such as 15m")
+			}
+			r.forDur = d
+		}
+		if c.MinConfidence < 0 || c.MinConfidence > 1 {
+			return nil, errors.New("condition: min_confidence must be between 0 and 1")
+		}
+	case KindEvent:
+		if !validEvent(c.Event) {
+			return nil, fmt.Errorf("condition: unknown event %q (one of %s)", c.Event, strings.Join(Events, ", "))
+		}
+	default:
+		return nil, fmt.Errorf("condition: kind must be %s or %s", KindState, KindEvent)
+	}
+	if c.Window != nil {
+		var err error
+		if r.from, err = parseClock(c.Window.From); err != nil {
+			return nil, fmt.Errorf("condition: window from: %w", err)
+		}
+		if r.to, err = parseClock(c.Window.To); err != nil {
+			return nil, fmt.Errorf("condition: window to: %w", err)
+		}
+	}
+
+	switch s.Action.Kind {
+	case ActionLog, ActionWebhook:
+	case ActionMQTT:
+		if s.Action.Topic == "" {
+			return nil, errors.New("action: mqtt needs a topic")
+		}
+	default:
+		return nil, fmt.Errorf("action: kind must be %s, %s or %s", ActionLog, ActionWebhook, ActionMQTT)
+	}
+	if s.Cooldown != "" {
+		d, err := time.ParseDuration(s.Cooldown)
+		if err != nil || d < 0 {
+			return nil, errors.New("cooldown must be a duration such as 1h")
+		}
+		r.cooldown = d
+	}
+	return r, nil
+}
+
+func validEvent(e string) bool {
+	for _, v := range Events {
+		if v == e {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock returns minutes after midnight of "HH:MM".
+func parseClock(v string) (int, error) {
+	h, m, ok := strings.Cut(v, ":")
+	hh, err1 := strconv.Atoi(h)
+	mm, err2 := strconv.Atoi(m)
+	if !ok || err1 != nil || err2 != nil || hh < 0 || hh > 23 || mm < 0 || mm > 59 {
+		return 0, fmt.Errorf("%q is not HH:MM", v)
+	}
+	return hh*60 + mm, nil
+}
+
+// inWindow reports whether the local time t is in the rule's window.
+func (r *rule) inWindow(t time.Time) bool {
+	if r.from == r.to {
+		return true
+	}
+	m := t.Hour()*60 + t.Minute()
+	if r.from < r.to {
+		return m >= r.from && m < r.to
+	}
+	return m >= r.from || m < r.to
+}
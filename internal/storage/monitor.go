@@ -0,0 +1,167 @@
+// Package storage watches the data directory so file-touching components can
+// pause together when a network share disappears, instead of each failing
+// with its own symptoms.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrUnavailable is returned by Check while the data directory is unusable.
+var ErrUnavailable = errors.New("storage unavailable")
+
+// probeFile is written and removed in the watched directory on every probe.
+const probeFile = ".skyclf-probe"
+
+// Monitor periodically probes a directory by writing and removing a sentinel
+// file. A nil *Monitor always reports storage as available.
+type Monitor struct {
+	dir      string
+	interval time.Duration
+	timeout  time.Duration // a hung NFS mount blocks syscalls; give up after this
+
+	mu        sync.Mutex
+	onRecover func()
+	healthy   bool
+	lastErr   error
+	since     time.Time // start of the current state
+	lastProbe time.Time
+	probing   bool // a probe is still running (possibly hung)
+}
+
+// Status is a snapshot of the monitor state.
+type Status struct {
+	Available bool      `json:"available"`
+	Dir       string    `json:"dir"`
+	Error     string    `json:"error,omitempty"`
+	Since     time.Time `json:"since"`
+	LastProbe time.Time `json:"last_probe,omitempty"`
+}
+
+// NewMonitor creates a monitor for dir; it reports available until the first
+// probe says otherwise.
+func NewMonitor(dir string, interval time.Duration) *Monitor {
+	return &Monitor{
+		dir:      dir,
+		interval: interval,
+		timeout:  10 * time.Second,
+		healthy:  true,
+		since:    time.Now().UTC(),
+	}
+}
+
+// SetOnRecover sets fn to run (in its own goroutine) whenever the storage
+// becomes available again after an outage.
+func (m *Monitor) SetOnRecover(fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onRecover = fn
+}
+
+// Start probes immediately and then every interval until ctx is canceled.
+func (m *Monitor) Start(ctx context.Context) {
+	t := time.NewTicker(m.interval)
+	defer t.Stop()
+	for {
+		m.probe()
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Check returns nil while storage is available, else an error wrapping ErrUnavailable.
+func (m *Monitor) Check() error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.healthy {
+		return nil
+	}
+	return fmt.Errorf("%w: %v", ErrUnavailable, m.lastErr)
+}
+
+// Status returns the current state.
+func (m *Monitor) Status() Status {
+	if m == nil {
+		return Status{Available: true}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := Status{Available: m.healthy, Dir: m.dir, Since: m.since, LastProbe: m.lastProbe}
+	if m.lastErr != nil {
+		st.Error = m.lastErr.Error()
+	}
+	return st
+}
+
+// probe runs one write/remove cycle with a timeout and updates the state.
+func (m *Monitor) probe() {
+	m.mu.Lock()
+	if m.probing {
+		// the previous probe is stuck in a syscall; that alone means "down"
+		m.mu.Unlock()
+		m.set(errors.New("probe still blocked"))
+		return
+	}
+	m.probing = true
+	m.mu.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- writeProbe(m.dir)
+		m.mu.Lock()
+		m.probing = false
+		m.mu.Unlock()
+	}()
+
+	select {
+	case err := <-done:
+		m.set(err)
+	case <-time.After(m.timeout):
+		m.set(fmt.Errorf("probe timed out after %s", m.timeout))
+	}
+}
+
+func writeProbe(dir string) error {
+	p := filepath.Join(dir, probeFile)
+	if err := os.WriteFile(p, []byte(time.Now().UTC().Format(time.RFC3339)), 0o644); err != nil {
+		return err
+	}
+	return os.Remove(p)
+}
+
+// set records a probe result and logs/handles state transitions.
+func (m *Monitor) set(err error) {
+	m.mu.Lock()
+	was := m.healthy
+	m.healthy = err == nil
+	m.lastErr = err
+	m.lastProbe = time.Now().UTC()
+	if was != m.healthy {
+		m.since = m.lastProbe
+	}
+	onRecover := m.onRecover
+	m.mu.Unlock()
+
+	switch {
+	case was && err != nil:
+		log.Printf("storage: %s unavailable, pausing ingestion: %v", m.dir, err)
+	case !was && err == nil:
+		log.Printf("storage: %s available again, resuming", m.dir)
+		if onRecover != nil {
+			go onRecover()
+		}
+	}
+}
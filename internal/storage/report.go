@@ -0,0 +1,333 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Categories of the files under the data directory.
+const (
+	CategoryImages    = "images"    // image files with a database row
+	CategoryUntracked = "untracked" // image files in the images directory without a row
+	CategoryCache     = "cache"     // derived copies, e.g. JPEG versions of WebP images for the trainer
+	CategoryModels    = "models"
+	CategoryBackups   = "backups"
+	CategoryDatabase  = "database"
+	CategoryTraining  = "training" // run logs and files handed to the trainer
+	CategoryTmp       = "tmp"      // leftovers of interrupted writes
+	CategoryOther     = "other"
+)
+
+// Cleanable lists the categories Clean may delete. Images with a row never are.
+var Cleanable = []string{CategoryCache, CategoryTmp, CategoryUntracked}
+
+// DefaultWalkRate is how many directory entries per second a walk visits, so
+// a data directory with a million files doesn't starve fetching and training
+// of I/O.
+const DefaultWalkRate = 5000
+
+// CleanMinAge is how old a file must be for Clean to delete it, so files
+// being written, or images just fetched and not yet in the database, stay.
+const CleanMinAge = time.Hour
+
+// maxExamples caps the example paths per category.
+const maxExamples = 20
+
+// Layout is where the server keeps its files.
+type Layout struct {
+	DataDir   string
+	ImagesDir string
+	ModelsDir string
+	BackupDir string
+	DBPath    string
+}
+
+// Usage is a file count and their total size.
+type Usage struct {
+	Files int64 `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
+
+func (u *Usage) add(size int64) {
+	u.Files++
+	u.Bytes += size
+}
+
+// ModelUsage is the disk usage of one model version directory.
+type ModelUsage struct {
+	Version string `json:"version"` // task/version, e.g. skystate/v3
+	Usage
+}
+
+// Report is the disk usage under a Layout by category.
+type Report struct {
+	DataDir    string              `json:"data_dir"`
+	Entries    int64               `json:"entries"` // directory entries visited
+	DurationMS int64               `json:"duration_ms"`
+	Categories map[string]*Usage   `json:"categories"`
+	Models     []ModelUsage        `json:"models"`
+	Examples   map[string][]string `json:"examples"` // untracked and tmp paths, a few each
+}
+
+// CleanOptions says what Clean removes.
+type CleanOptions struct {
+	Targets []string        // categories, a subset of Cleanable
+	DryRun  bool            // only report
+	InUse   map[string]bool // cleaned paths never removed, e.g. a running training's file list
+}
+
+// CleanResult is what Clean removed, or would have.
+type CleanResult struct {
+	DryRun   bool              `json:"dry_run"`
+	Targets  []string          `json:"targets"`
+	Removed  map[string]*Usage `json:"removed"`
+	Kept     int64             `json:"kept"`   // younger than CleanMinAge or in use
+	Errors   int64             `json:"errors"` // files that couldn't be removed
+	Examples []string          `json:"examples"`
+}
+
+// Walker classifies the files under a Layout, cross-referencing the images
+// with the database. It visits at most Rate entries per second (0 = no limit)
+// and stops when its context is done.
+type Walker struct {
+	Layout  Layout
+	Tracked map[string]bool // cleaned paths of the images with a row
+	Rate    int
+	// IsImage tells image files from others in the images directory, e.g.
+	// fetcher.IsImageFile (nil = all files are images)
+	IsImage func(name string) bool
+}
+
+// Report walks the layout and sums up the usage per category.
+func (w *Walker) Report(ctx context.Context) (*Report, error) {
+	start := time.Now()
+	rep := &Report{
+		DataDir:    w.Layout.DataDir,
+		Categories: map[string]*Usage{},
+		Models:     []ModelUsage{},
+		Examples:   map[string][]string{CategoryUntracked: {}, CategoryTmp: {}},
+	}
+	models := map[string]*Usage{}
+	entries, err := w.walk(ctx, func(p, category string, info fs.FileInfo) {
+		u := rep.Categories[category]
+		if u == nil {
+			u = &Usage{}
+			rep.Categories[category] = u
+		}
+		u.add(info.Size())
+		if ex, ok := rep.Examples[category]; ok && len(ex) < maxExamples {
+			rep.Examples[category] = append(ex, p)
+		}
+		if category == CategoryModels {
+			if v := w.modelVersion(p); v != "" {
+				if models[v] == nil {
+					models[v] = &Usage{}
+				}
+				models[v].add(info.Size())
+			}
+		}
+	})
+	rep.Entries = entries
+	rep.DurationMS = time.Since(start).Milliseconds()
+	for v, u := range models {
+		rep.Models = append(rep.Models, ModelUsage{Version: v, Usage: *u})
+	}
+	sort.Slice(rep.Models, func(i, j int) bool { return rep.Models[i].Version < rep.Models[j].Version })
+	return rep, err
+}
+
+// Clean removes the files of the target categories older than CleanMinAge.
+func (w *Walker) Clean(ctx context.Context, opts CleanOptions) (*CleanResult, error) {
+	res := &CleanResult{DryRun: opts.DryRun, Targets: opts.Targets, Removed: map[string]*Usage{}, Examples: []string{}}
+	targets := map[string]bool{}
+	for _, t := range opts.Targets {
+		targets[t] = true
+	}
+	cutoff := time.Now().Add(-CleanMinAge)
+	_, err := w.walk(ctx, func(p, category string, info fs.FileInfo) {
+		if !targets[category] || category == CategoryImages {
+			return
+		}
+		if info.ModTime().After(cutoff) || opts.InUse[p] {
+			res.Kept++
+			return
+		}
+		if !opts.DryRun {
+			if err := os.Remove(p); err != nil {
+				res.Errors++
+				return
+			}
+		}
+		u := res.Removed[category]
+		if u == nil {
+			u = &Usage{}
+			res.Removed[category] = u
+		}
+		u.add(info.Size())
+		if len(res.Examples) < maxExamples {
+			res.Examples = append(res.Examples, p)
+		}
+	})
+	return res, err
+}
+
+// walk visits every file under the layout's directories once and calls fn
+// with its cleaned path and category. It returns the entries visited.
+func (w *Walker) walk(ctx context.Context, fn func(p, category string, info fs.FileInfo)) (int64, error) {
+	lim := newLimiter(w.Rate)
+	var entries int64
+	for _, root := range w.roots() {
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			entries++
+			if err := lim.wait(ctx); err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil // removed meanwhile
+			}
+			p = filepath.Clean(p)
+			fn(p, w.classify(p), info)
+			return nil
+		})
+		if err != nil {
+			return entries, err
+		}
+	}
+	return entries, nil
+}
+
+// roots returns the layout's directories without those inside another.
+func (w *Walker) roots() []string {
+	var dirs []string
+	for _, d := range []string{w.Layout.DataDir, w.Layout.ImagesDir, w.Layout.ModelsDir, w.Layout.BackupDir, filepath.Dir(w.Layout.DBPath)} {
+		if d != "" && d != "." {
+			dirs = append(dirs, filepath.Clean(d))
+		}
+	}
+	sort.Strings(dirs)
+	var out []string
+	for _, d := range dirs {
+		nested := false
+		for _, o := range out {
+			if within(d, o) {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// classify returns the category of the file at the cleaned path p.
+func (w *Walker) classify(p string) string {
+	l := w.Layout
+	name := filepath.Base(p)
+	switch {
+	case l.DBPath != "" && filepath.Dir(p) == filepath.Clean(filepath.Dir(l.DBPath)) &&
+		strings.HasPrefix(name, filepath.Base(l.DBPath)):
+		return CategoryDatabase // with -wal and -shm
+	case within(p, l.ModelsDir):
+		// .pending-* versions may be a publish in progress; leave them to the models
+		return CategoryModels
+	case strings.HasSuffix(name, ".tmp") || strings.HasPrefix(name, ".convert-"):
+		return CategoryTmp
+	case within(p, l.BackupDir):
+		return CategoryBackups
+	case within(p, filepath.Join(l.DataDir, "train", "jpeg")):
+		return CategoryCache
+	case within(p, filepath.Join(l.DataDir, "train")), within(p, filepath.Join(l.DataDir, "train-logs")):
+		return CategoryTraining
+	case within(p, l.ImagesDir):
+		if w.Tracked[p] {
+			return CategoryImages
+		}
+		if strings.HasPrefix(name, ".") {
+			return CategoryTmp // partial writes of other tools, e.g. .latest.jpg.Xa3f9
+		}
+		if w.IsImage == nil || w.IsImage(name) {
+			return CategoryUntracked
+		}
+	}
+	return CategoryOther
+}
+
+// modelVersion returns task/version of a file in the models directory.
+func (w *Walker) modelVersion(p string) string {
+	rel, err := filepath.Rel(filepath.Clean(w.Layout.ModelsDir), p)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[0] + "/" + parts[1]
+}
+
+// within reports whether the cleaned path p is dir or inside it.
+func within(p, dir string) bool {
+	if dir == "" {
+		return false
+	}
+	dir = filepath.Clean(dir)
+	return p == dir || strings.HasPrefix(p, dir+string(filepath.Separator))
+}
+
+// limiter spaces out a walk to a number of entries per second.
+type limiter struct {
+	rate  int
+	start time.Time
+	n     int
+}
+
+func newLimiter(rate int) *limiter {
+	return &limiter{rate: rate, start: time.Now()}
+}
+
+// limiterBatch is how many entries pass between sleeps.
+const limiterBatch = 256
+
+// wait accounts for one entry and sleeps when the walk is ahead of its rate.
+func (l *limiter) wait(ctx context.Context) error {
+	l.n++
+	if l.n%limiterBatch != 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if l.rate <= 0 {
+		return nil
+	}
+	ahead := time.Duration(l.n)*time.Second/time.Duration(l.rate) - time.Since(l.start)
+	if ahead <= 0 {
+		return nil
+	}
+	t := time.NewTimer(ahead)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
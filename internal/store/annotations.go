@@ -0,0 +1,118 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Annotation marks a region of an image, e.g. a meteor streak.
+// Coordinates are a bounding box in image pixels with the origin top-left.
+type Annotation struct {
+	ID        int64     `json:"id"`
+	ImageID   string    `json:"image_id"`
+	Kind      string    `json:"kind"`
+	X         int       `json:"x"`
+	Y         int       `json:"y"`
+	W         int       `json:"w"`
+	H         int       `json:"h"`
+	CreatedAt time.Time `json:"created_at"`
+	Labeler   string    `json:"labeler,omitempty"`
+}
+
+// ListAnnotations returns the annotations of one image, or of all images when
+// imageID is empty, ordered by image and creation.
+func (s *Store) ListAnnotations(ctx context.Context, imageID string) ([]Annotation, error) {
+	return listAnnotations(ctx, s.read, imageID)
+}
+
+func listAnnotations(ctx context.Context, db querier, imageID string) ([]Annotation, error) {
+	q := `SELECT id, image_id, kind, x, y, w, h, created_at, labeler FROM annotations`
+	var args []any
+	if imageID != "" {
+		q += ` WHERE image_id = ?`
+		args = append(args, imageID)
+	}
+	q += ` ORDER BY image_id, id`
+
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list annotations: %w", err)
+	}
+	defer rows.Close()
+
+	out := []Annotation{}
+	for rows.Next() {
+		var (
+			a            Annotation
+			createdAtStr string
+		)
+		if err := rows.Scan(&a.ID, &a.ImageID, &a.Kind, &a.X, &a.Y, &a.W, &a.H, &createdAtStr, &a.Labeler); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		a.CreatedAt, _ = time.Parse(time.RFC3339, createdAtStr)
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// CreateAnnotation stores a and returns it with ID and CreatedAt set.
+func (s *Store) CreateAnnotation(ctx context.Context, a Annotation) (Annotation, error) {
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now().UTC()
+	}
+	res, err := s.DB.ExecContext(ctx, `
+INSERT INTO annotations(image_id, kind, x, y, w, h, created_at, labeler)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.ImageID, a.Kind, a.X, a.Y, a.W, a.H, a.CreatedAt.UTC().Format(time.RFC3339), a.Labeler)
+	if err != nil {
+		return a, fmt.Errorf("create annotation: %w", err)
+	}
+	a.ID, _ = res.LastInsertId()
+	return a, nil
+}
+
+// UpdateAnnotation replaces kind, box and labeler of an existing annotation.
+// It returns false if no annotation with that ID exists on the image.
+func (s *Store) UpdateAnnotation(ctx context.Context, a Annotation) (bool, error) {
+	res, err := s.DB.ExecContext(ctx, `
+UPDATE annotations SET kind = ?, x = ?, y = ?, w = ?, h = ?, labeler = ?
+WHERE id = ? AND image_id = ?`,
+		a.Kind, a.X, a.Y, a.W, a.H, a.Labeler, a.ID, a.ImageID)
+	if err != nil {
+		return false, fmt.Errorf("update annotation: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// GetAnnotation returns one annotation of an image, or nil if it doesn't exist.
+func (s *Store) GetAnnotation(ctx context.Context, imageID string, id int64) (*Annotation, error) {
+	var (
+		a            Annotation
+		createdAtStr string
+	)
+	err := s.read.QueryRowContext(ctx, `
+SELECT id, image_id, kind, x, y, w, h, created_at, labeler
+FROM annotations WHERE id = ? AND image_id = ?`, id, imageID).
+		Scan(&a.ID, &a.ImageID, &a.Kind, &a.X, &a.Y, &a.W, &a.H, &createdAtStr, &a.Labeler)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get annotation: %w", err)
+	}
+	a.CreatedAt, _ = time.Parse(time.RFC3339, createdAtStr)
+	return &a, nil
+}
+
+// DeleteAnnotation removes one annotation of an image and reports whether it existed.
+func (s *Store) DeleteAnnotation(ctx context.Context, imageID string, id int64) (bool, error) {
+	res, err := s.DB.ExecContext(ctx, `DELETE FROM annotations WHERE id = ? AND image_id = ?`, id, imageID)
+	if err != nil {
+		return false, fmt.Errorf("delete annotation: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
@@ -0,0 +1,178 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Archived images are soft-deleted: archived_at is set and they drop out of
+// lists, latest, stats, splits, training and reports, but their rows, labels
+// and files stay until PurgeArchived removes them for good.
+
+// activeImage is the condition for images that aren't archived (alias i).
+const activeImage = "i.archived_at IS NULL"
+
+// ArchiveResult describes what an archive operation marked.
+type ArchiveResult struct {
+	ArchivedCount int   `json:"archived_count"`
+	ArchivedBytes int64 `json:"archived_bytes"`
+}
+
+// ArchiveImages archives the given images as of at. Images already archived
+// or unknown are skipped.
+func (s *Store) ArchiveImages(ctx context.Context, ids []string, at time.Time) (ArchiveResult, error) {
+	if len(ids) == 0 {
+		return ArchiveResult{}, nil
+	}
+	in := "(" + strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",") + ")"
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return s.archiveWhere(ctx, "i.id IN "+in, args, at)
+}
+
+// ArchiveImagesByDay archives every image fetched on day (YYYY-MM-DD, see
+// DayGrouping).
+func (s *Store) ArchiveImagesByDay(ctx context.Context, day string, at time.Time) (ArchiveResult, error) {
+	cond, args := s.days.dayCond("i.fetched_at", day)
+	return s.archiveWhere(ctx, cond, args, at)
+}
+
+func (s *Store) archiveWhere(ctx context.Context, cond string, args []any, at time.Time) (ArchiveResult, error) {
+	var result ArchiveResult
+	err := retryBusy(ctx, func() error {
+		tx, err := s.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("begin: %w", err)
+		}
+		defer tx.Rollback()
+
+		where := cond + " AND " + activeImage
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*), COALESCE(SUM(i.size_bytes), 0) FROM images i WHERE `+where, args...).
+			Scan(&result.ArchivedCount, &result.ArchivedBytes); err != nil {
+			return fmt.Errorf("count images to archive: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE images AS i SET archived_at = ? WHERE `+where,
+			append([]any{at.UTC().Format(time.RFC3339)}, args...)...); err != nil {
+			return fmt.Errorf("archive images: %w", err)
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return ArchiveResult{}, err
+	}
+	return result, nil
+}
+
+// UnarchiveImage restores an archived image and reports whether it was archived.
+func (s *Store) UnarchiveImage(ctx context.Context, id string) (bool, error) {
+	var n int64
+	err := retryBusy(ctx, func() error {
+		res, err := s.DB.ExecContext(ctx, `UPDATE images SET archived_at = NULL WHERE id = ? AND archived_at IS NOT NULL`, id)
+		if err != nil {
+			return fmt.Errorf("unarchive image: %w", err)
+		}
+		n, _ = res.RowsAffected()
+		return nil
+	})
+	return n > 0, err
+}
+
+// PurgeArchived permanently deletes images archived before cutoff, with their
+// labels and metadata, and returns their paths; files on disk are left to the
+// caller. With dryRun nothing is deleted and the result describes what would be.
+func (s *Store) PurgeArchived(ctx context.Context, cutoff time.Time, dryRun bool) (CleanupResult, error) {
+	cond := "archived_at IS NOT NULL AND archived_at < ?"
+	arg := cutoff.UTC().Format(time.RFC3339)
+
+	var result CleanupResult
+	err := retryBusy(ctx, func() error {
+		result = CleanupResult{}
+		tx, err := s.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("begin: %w", err)
+		}
+		defer tx.Rollback()
+
+		if result, err = listForDelete(ctx, tx, cond, arg); err != nil {
+			return err
+		}
+		if dryRun {
+			return nil
+		}
+		if _, err := deleteImagesWhere(ctx, tx, cond, arg); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return CleanupResult{}, err
+	}
+	return result, nil
+}
+
+// listForDelete collects the paths and sizes of the images matching cond.
+func listForDelete(ctx context.Context, tx *sql.Tx, cond string, args ...any) (CleanupResult, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT path, size_bytes FROM images WHERE `+cond, args...)
+	if err != nil {
+		return CleanupResult{}, fmt.Errorf("list images: %w", err)
+	}
+	defer rows.Close()
+	var result CleanupResult
+	for rows.Next() {
+		var (
+			path string
+			size int64
+		)
+		if err := rows.Scan(&path, &size); err != nil {
+			return CleanupResult{}, fmt.Errorf("scan: %w", err)
+		}
+		result.DeletedPaths = append(result.DeletedPaths, path)
+		result.FreedBytes += size
+		result.DeletedCount++
+	}
+	return result, rows.Err()
+}
+
+// deleteImagesWhere deletes the images matching cond (on the images table,
+// unaliased) and everything that refers to them. Dependents are removed
+// explicitly: foreign_keys is a per-connection pragma and not guaranteed on
+// every pooled connection.
+func deleteImagesWhere(ctx context.Context, tx *sql.Tx, cond string, args ...any) (int, error) {
+	sub := `(SELECT id FROM images WHERE ` + cond + `)`
+	for _, q := range []string{
+		`DELETE FROM labels WHERE image_id IN ` + sub,
+		`DELETE FROM image_meta WHERE image_id IN ` + sub,
+		`DELETE FROM predictions WHERE image_id IN ` + sub,
+		`DELETE FROM prediction_skips WHERE image_id IN ` + sub,
+		`DELETE FROM annotations WHERE image_id IN ` + sub,
+		`DELETE FROM claims WHERE image_id IN ` + sub,
+		`DELETE FROM suggested_labels WHERE image_id IN ` + sub,
+	} {
+		if _, err := tx.ExecContext(ctx, q, args...); err != nil {
+			return 0, fmt.Errorf("delete dependents: %w", err)
+		}
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM images WHERE `+cond, args...)
+	if err != nil {
+		return 0, fmt.Errorf("delete images: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// parseNullTime parses an optional RFC3339 column.
+func parseNullTime(ns sql.NullString) *time.Time {
+	if !ns.Valid {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, ns.String)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Audit outcomes.
+const (
+	AuditOK     = "ok"
+	AuditError  = "error"
+	AuditDenied = "denied"
+)
+
+// AuditEntry is one row of the audit log.
+type AuditEntry struct {
+	ID         int64             `json:"id"`
+	Action     string            `json:"action"`
+	Params     map[string]string `json:"params"`
+	RemoteAddr string            `json:"remote_addr"`
+	Identity   string            `json:"identity"`
+	Status     int               `json:"status"`
+	Outcome    string            `json:"outcome"`
+	Error      string            `json:"error,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// RecordAudit appends e to the audit log.
+func (s *Store) RecordAudit(ctx context.Context, e AuditEntry) error {
+	params := e.Params
+	if params == nil {
+		params = map[string]string{}
+	}
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("encode audit params: %w", err)
+	}
+	err = retryBusy(ctx, func() error {
+		_, err := s.DB.ExecContext(ctx, `
+INSERT INTO audit_log(action, params, remote_addr, identity, status, outcome, error, created_at)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?)`,
+			e.Action, string(raw), e.RemoteAddr, e.Identity, e.Status, e.Outcome, e.Error, e.CreatedAt.UTC().Format(time.RFC3339))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("record audit: %w", err)
+	}
+	return nil
+}
+
+// ListAudit returns up to limit audit entries, newest first, optionally only
+// those with the given action.
+func (s *Store) ListAudit(ctx context.Context, limit int, action string) ([]AuditEntry, error) {
+	q := `SELECT id, action, params, remote_addr, identity, status, outcome, error, created_at FROM audit_log`
+	var args []any
+	if action != "" {
+		q += ` WHERE action = ?`
+		args = append(args, action)
+	}
+	q += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.read.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list audit: %w", err)
+	}
+	defer rows.Close()
+
+	out := []AuditEntry{}
+	for rows.Next() {
+		var (
+			e                    AuditEntry
+			params, createdAtStr string
+		)
+		if err := rows.Scan(&e.ID, &e.Action, &params, &e.RemoteAddr, &e.Identity, &e.Status, &e.Outcome, &e.Error, &createdAtStr); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		_ = json.Unmarshal([]byte(params), &e.Params)
+		e.CreatedAt, _ = time.Parse(time.RFC3339, createdAtStr)
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
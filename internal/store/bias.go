@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ShownPrediction is the model suggestion a labeler saw while labeling.
+type ShownPrediction struct {
+	Skystate     string  `json:"skystate"`
+	Confidence   float64 `json:"confidence"`
+	ModelVersion string  `json:"model_version"`
+}
+
+// BiasGroup counts how often manual labels agree with the model.
+type BiasGroup struct {
+	Labels        int     `json:"labels"`
+	Compared      int     `json:"compared"` // labels with a model prediction to compare against
+	Agreed        int     `json:"agreed"`
+	AgreementRate float64 `json:"agreement_rate"` // Agreed / Compared; 0 if nothing was compared
+}
+
+// BiasReport compares agreement with the model between labels made with a
+// suggestion on screen and labels made without one.
+type BiasReport struct {
+	WithSuggestion    BiasGroup `json:"with_suggestion"`
+	WithoutSuggestion BiasGroup `json:"without_suggestion"`
+	// Difference is the with minus the without agreement rate; clearly
+	// positive means labelers follow what they are shown.
+	Difference float64 `json:"difference"`
+}
+
+// BiasFilter scopes BiasReport. Zero values mean "any".
+type BiasFilter struct {
+	Labeler string
+	Since   time.Time // labels recorded at or after
+}
+
+// BiasReport reads the manual labels in label_history. A label made with a
+// suggestion is compared against what was shown; one made without is
+// compared against the newest prediction stored for the image before it was
+// labeled, i.e. what the model would have suggested.
+func (s *Store) BiasReport(ctx context.Context, f BiasFilter) (BiasReport, error) {
+	where := []string{"h.source = ?"}
+	args := []any{LabelSourceManual}
+	if f.Labeler != "" {
+		where = append(where, "h.labeler = ?")
+		args = append(args, f.Labeler)
+	}
+	if !f.Since.IsZero() {
+		where = append(where, "h.recorded_at >= ?")
+		args = append(args, f.Since.UTC().Format(time.RFC3339))
+	}
+	rows, err := s.read.QueryContext(ctx, `
+SELECT shown, COUNT(*), COUNT(model), COALESCE(SUM(skystate = model), 0)
+FROM (
+  SELECT h.skystate, h.shown_skystate IS NOT NULL AS shown,
+         COALESCE(h.shown_skystate, (
+           SELECT p.skystate FROM predictions p
+           WHERE p.image_id = h.image_id AND p.predicted_at <= h.recorded_at
+           ORDER BY p.predicted_at DESC, p.id DESC LIMIT 1)) AS model
+  FROM label_history h
+  WHERE `+strings.Join(where, " AND ")+`
+)
+GROUP BY shown`, args...)
+	if err != nil {
+		return BiasReport{}, fmt.Errorf("bias report: %w", err)
+	}
+	defer rows.Close()
+
+	var r BiasReport
+	for rows.Next() {
+		var (
+			shown bool
+			g     BiasGroup
+		)
+		if err := rows.Scan(&shown, &g.Labels, &g.Compared, &g.Agreed); err != nil {
+			return BiasReport{}, fmt.Errorf("scan: %w", err)
+		}
+		if g.Compared > 0 {
+			g.AgreementRate = float64(g.Agreed) / float64(g.Compared)
+		}
+		if shown {
+			r.WithSuggestion = g
+		} else {
+			r.WithoutSuggestion = g
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return BiasReport{}, err
+	}
+	if r.WithSuggestion.Compared > 0 && r.WithoutSuggestion.Compared > 0 {
+		r.Difference = r.WithSuggestion.AgreementRate - r.WithoutSuggestion.AgreementRate
+	}
+	return r, nil
+}
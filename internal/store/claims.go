@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ClaimNextUnlabeled returns an unlabeled image not claimed by anyone else and
+// claims it for claimant until now+ttl. A claimant holds at most one claim: if it
+// already holds one on a still unlabeled image, that image is returned again with
+// a renewed expiry. skip (optional) releases the claimant's claim on that image
+// and excludes it from selection. Returns nil if no image is available.
+//
+// Selection and claim happen in one write transaction, so concurrent callers
+// never receive the same image.
+func (s *Store) ClaimNextUnlabeled(ctx context.Context, claimant string, ttl time.Duration, skip string) (*ImageWithLabel, time.Time, error) {
+	var (
+		out       *ImageWithLabel
+		expiresAt time.Time
+	)
+	err := retryBusy(ctx, func() error {
+		out = nil
+		now := time.Now().UTC()
+		expiresAt = now.Add(ttl)
+
+		tx, err := s.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("begin: %w", err)
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM claims WHERE expires_at <= ?`, now.Format(time.RFC3339)); err != nil {
+			return fmt.Errorf("expire claims: %w", err)
+		}
+		if skip != "" {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM claims WHERE image_id = ? AND claimant = ?`, skip, claimant); err != nil {
+				return fmt.Errorf("release claim: %w", err)
+			}
+		}
+
+		row := tx.QueryRowContext(ctx, `
+SELECT i.id, i.path, i.sha256, i.fetched_at, i.size_bytes, i.width, i.height
+FROM images i
+LEFT JOIN labels l ON l.image_id = i.id
+LEFT JOIN claims c ON c.image_id = i.id
+WHERE l.image_id IS NULL
+  AND i.archived_at IS NULL
+  AND i.id != ?
+  AND (c.image_id IS NULL OR c.claimant = ?)
+ORDER BY (c.claimant IS NOT NULL) DESC, i.fetched_at DESC
+LIMIT 1`, skip, claimant)
+
+		var (
+			img          ImageWithLabel
+			fetchedAtStr string
+		)
+		err = row.Scan(&img.ID, &img.Path, &img.SHA256, &fetchedAtStr, &img.SizeBytes, &img.Width, &img.Height)
+		if err == sql.ErrNoRows {
+			return tx.Commit()
+		}
+		if err != nil {
+			return fmt.Errorf("select unclaimed: %w", err)
+		}
+		img.FetchedAt, _ = time.Parse(time.RFC3339, fetchedAtStr)
+
+		// Drop any other claim this claimant still holds, then take this one
+		if _, err := tx.ExecContext(ctx, `DELETE FROM claims WHERE claimant = ? AND image_id != ?`, claimant, img.ID); err != nil {
+			return fmt.Errorf("release claims: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO claims(image_id, claimant, expires_at) VALUES(?, ?, ?)
+ON CONFLICT(image_id) DO UPDATE SET claimant=excluded.claimant, expires_at=excluded.expires_at`,
+			img.ID, claimant, expiresAt.Format(time.RFC3339)); err != nil {
+			return fmt.Errorf("claim image: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("commit: %w", err)
+		}
+		out = &img
+		return nil
+	})
+	return out, expiresAt, err
+}
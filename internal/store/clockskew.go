@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ClockSkewStats summarizes capture skews: capture time minus fetch time in
+// seconds, positive when the camera clock is ahead.
+type ClockSkewStats struct {
+	Images        int     `json:"images"`
+	MedianSeconds float64 `json:"median_seconds"` // nearest-rank
+	MinSeconds    float64 `json:"min_seconds"`
+	MaxSeconds    float64 `json:"max_seconds"`
+}
+
+// ClockSkewDay is the capture skew of the images fetched on one day.
+type ClockSkewDay struct {
+	Date string `json:"date"`
+	ClockSkewStats
+}
+
+// SetCaptureSkew records how far the camera clock was off when the image with
+// the given content hash was captured: the capture time it reported minus the
+// fetch time, before any correction.
+func (s *Store) SetCaptureSkew(ctx context.Context, sha256 string, skew time.Duration) error {
+	if _, err := s.DB.ExecContext(ctx, `UPDATE images SET capture_skew = ? WHERE sha256 = ?`, skew.Seconds(), sha256); err != nil {
+		return fmt.Errorf("set capture skew: %w", err)
+	}
+	return nil
+}
+
+// RecentClockSkew summarizes the capture skew of the newest n images that
+// have one. Images is 0 if none has.
+func (s *Store) RecentClockSkew(ctx context.Context, n int) (ClockSkewStats, error) {
+	var st ClockSkewStats
+	err := s.read.QueryRowContext(ctx, `
+WITH recent AS (
+  SELECT capture_skew AS skew
+  FROM images
+  WHERE capture_skew IS NOT NULL
+  ORDER BY fetched_at DESC
+  LIMIT ?
+), ranked AS (
+  SELECT skew, ROW_NUMBER() OVER (ORDER BY skew) AS r, COUNT(*) OVER () AS n
+  FROM recent
+)
+SELECT COUNT(*), COALESCE(MIN(CASE WHEN r >= 0.5 * n THEN skew END), 0),
+       COALESCE(MIN(skew), 0), COALESCE(MAX(skew), 0)
+FROM ranked`, n).Scan(&st.Images, &st.MedianSeconds, &st.MinSeconds, &st.MaxSeconds)
+	if err != nil {
+		return st, fmt.Errorf("recent clock skew: %w", err)
+	}
+	return st, nil
+}
+
+// ClockSkewByDay summarizes the capture skew per day for images fetched since
+// since, oldest day first. Days without a recorded skew are left out.
+func (s *Store) ClockSkewByDay(ctx context.Context, since time.Time) ([]ClockSkewDay, error) {
+	day, args := s.days.dayExpr("fetched_at", since, time.Now())
+	rows, err := s.read.QueryContext(ctx, `
+WITH skews AS (
+  SELECT `+day+` AS day, capture_skew AS skew
+  FROM images
+  WHERE capture_skew IS NOT NULL AND fetched_at >= ?
+), ranked AS (
+  SELECT day, skew,
+         ROW_NUMBER() OVER (PARTITION BY day ORDER BY skew) AS r,
+         COUNT(*) OVER (PARTITION BY day) AS n
+  FROM skews
+)
+SELECT day, MAX(n), MIN(CASE WHEN r >= 0.5 * n THEN skew END), MIN(skew), MAX(skew)
+FROM ranked
+GROUP BY day
+ORDER BY day`, append(args, since.UTC().Format(time.RFC3339))...)
+	if err != nil {
+		return nil, fmt.Errorf("clock skew by day: %w", err)
+	}
+	defer rows.Close()
+
+	out := []ClockSkewDay{}
+	for rows.Next() {
+		var d ClockSkewDay
+		if err := rows.Scan(&d.Date, &d.Images, &d.MedianSeconds, &d.MinSeconds, &d.MaxSeconds); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DayGrouping decides which day (YYYY-MM-DD) an image belongs to in the day
+// list, the date filters and the per-day operations. Stored times stay UTC;
+// only the day boundaries move.
+type DayGrouping struct {
+	Loc *time.Location // nil = UTC
+	// Night groups by observing night: the night labeled 2024-10-03 runs from
+	// local noon on Oct 3 to local noon on Oct 4.
+	Night bool
+}
+
+// SetDayGrouping changes how days are grouped; the zero value groups by UTC
+// calendar day. Call it before serving requests.
+func (s *Store) SetDayGrouping(g DayGrouping) {
+	s.days = g
+}
+
+// DayGrouping returns how days are grouped.
+func (s *Store) DayGrouping() DayGrouping {
+	return s.days
+}
+
+// Location returns the zone days are grouped in.
+func (g DayGrouping) Location() *time.Location {
+	if g.Loc == nil {
+		return time.UTC
+	}
+	return g.Loc
+}
+
+// plainUTC reports whether days are UTC calendar days, which SQLite's DATE()
+// computes directly (and idx_images_day serves).
+func (g DayGrouping) plainUTC() bool {
+	return !g.Night && g.Location() == time.UTC
+}
+
+func (g DayGrouping) startHour() int {
+	if g.Night {
+		return 12
+	}
+	return 0
+}
+
+// Bounds returns the span [start, end) of the given day. Across a DST change
+// the span is 23 or 25 hours long.
+func (g DayGrouping) Bounds(day time.Time) (start, end time.Time) {
+	loc := g.Location()
+	y, m, d := day.Date()
+	start = time.Date(y, m, d, g.startHour(), 0, 0, 0, loc)
+	end = time.Date(y, m, d+1, g.startHour(), 0, 0, 0, loc)
+	return start, end
+}
+
+// DayOf returns the day t belongs to.
+func (g DayGrouping) DayOf(t time.Time) string {
+	local := t.In(g.Location())
+	if local.Hour() < g.startHour() {
+		local = local.AddDate(0, 0, -1)
+	}
+	return local.Format("2006-01-02")
+}
+
+// dayCond returns a condition matching the times in col that fall on day
+// (YYYY-MM-DD), as a range on col so an index on it applies. An invalid day
+// matches nothing.
+func (g DayGrouping) dayCond(col, day string) (string, []any) {
+	t, err := time.Parse("2006-01-02", day)
+	if g.plainUTC() || err != nil {
+		return "DATE(" + col + ") = ?", []any{day}
+	}
+	start, end := g.Bounds(t)
+	return col + " >= ? AND " + col + " < ?", []any{
+		start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339),
+	}
+}
+
+// dayExpr returns an SQL expression for the day the time in col falls on,
+// exact for times between from and to. SQLite only knows UTC, so the zone
+// offsets in force over that span are passed in as a CASE on col, one branch
+// per DST period; times outside it use the nearest period's offset.
+func (g DayGrouping) dayExpr(col string, from, to time.Time) (string, []any) {
+	if g.plainUTC() {
+		return "DATE(" + col + ")", nil
+	}
+	shift := func(offset int) string {
+		return fmt.Sprintf("%+d seconds", offset-3600*g.startHour())
+	}
+	loc := g.Location()
+	var (
+		b    strings.Builder
+		args []any
+	)
+	t := from.In(loc)
+	for {
+		_, offset := t.Zone()
+		_, next := t.ZoneBounds()
+		if next.IsZero() || next.After(to) {
+			if b.Len() == 0 {
+				return "DATE(" + col + ", ?)", []any{shift(offset)}
+			}
+			b.WriteString(" ELSE ? END)")
+			args = append(args, shift(offset))
+			return "DATE(" + col + ", CASE" + b.String(), args
+		}
+		b.WriteString(" WHEN " + col + " < ? THEN ?")
+		args = append(args, next.UTC().Format(time.RFC3339), shift(offset))
+		t = next
+	}
+}
+
+// fetchedSpan returns the fetch times of the oldest and newest image, the
+// span dayExpr needs to be exact over the whole table.
+func fetchedSpan(ctx context.Context, q queryRower) (from, to time.Time, err error) {
+	var minNS, maxNS sql.NullString
+	if err := q.QueryRowContext(ctx, `SELECT MIN(fetched_at), MAX(fetched_at) FROM images`).Scan(&minNS, &maxNS); err != nil {
+		return from, to, fmt.Errorf("fetched span: %w", err)
+	}
+	from, _ = time.Parse(time.RFC3339, minNS.String)
+	to, _ = time.Parse(time.RFC3339, maxNS.String)
+	return from, to, nil
+}
@@ -0,0 +1,119 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+	_ "time/tzdata" // zones don't depend on the host
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestDayBoundsAcrossDST(t *testing.T) {
+	tests := []struct {
+		zone  string
+		night bool
+		day   string
+		want  time.Duration
+	}{
+		{"UTC", false, "2024-03-31", 24 * time.Hour},
+		{"Europe/Berlin", false, "2024-03-30", 24 * time.Hour},
+		{"Europe/Berlin", false, "2024-03-31", 23 * time.Hour}, // clocks go forward at 02:00
+		{"Europe/Berlin", false, "2024-10-27", 25 * time.Hour}, // and back at 03:00
+		{"Europe/Berlin", true, "2024-03-30", 23 * time.Hour},  // the night into the change
+		{"Europe/Berlin", true, "2024-03-31", 24 * time.Hour},
+		{"Europe/Berlin", true, "2024-10-26", 25 * time.Hour},
+		{"America/New_York", true, "2024-11-02", 25 * time.Hour},
+		{"America/New_York", false, "2024-03-10", 23 * time.Hour},
+		{"Australia/Sydney", true, "2024-04-06", 25 * time.Hour}, // southern autumn
+		{"Australia/Sydney", false, "2024-10-06", 23 * time.Hour},
+		{"Asia/Kolkata", true, "2024-03-30", 24 * time.Hour}, // no DST
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%s/night=%t", tt.zone, tt.day, tt.night), func(t *testing.T) {
+			g := DayGrouping{Loc: mustLoad(t, tt.zone), Night: tt.night}
+			day, _ := time.Parse("2006-01-02", tt.day)
+			start, end := g.Bounds(day)
+			if got := end.Sub(start); got != tt.want {
+				t.Fatalf("day lasts %v, want %v (%v to %v)", got, tt.want, start, end)
+			}
+			// The bounds belong to this day and the next
+			if got := g.DayOf(start); got != tt.day {
+				t.Fatalf("DayOf(start) = %s", got)
+			}
+			if got := g.DayOf(end.Add(-time.Second)); got != tt.day {
+				t.Fatalf("DayOf(end-1s) = %s", got)
+			}
+			if got := g.DayOf(end); got == tt.day {
+				t.Fatalf("DayOf(end) = %s, want the next day", got)
+			}
+		})
+	}
+}
+
+// TestListDaysAcrossDST checks the SQL grouping (dayExpr) and the day filter
+// (dayCond) agree with DayOf on both sides of a DST change.
+func TestListDaysAcrossDST(t *testing.T) {
+	tests := []struct {
+		name  string
+		g     DayGrouping
+		start time.Time // of the hourly frames, UTC
+	}{
+		{"berlin calendar autumn", DayGrouping{Loc: mustLoad(t, "Europe/Berlin")}, time.Date(2024, 10, 26, 0, 0, 0, 0, time.UTC)},
+		{"berlin night spring", DayGrouping{Loc: mustLoad(t, "Europe/Berlin"), Night: true}, time.Date(2024, 3, 29, 0, 0, 0, 0, time.UTC)},
+		{"new york night autumn", DayGrouping{Loc: mustLoad(t, "America/New_York"), Night: true}, time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC)},
+		{"utc calendar", DayGrouping{}, time.Date(2024, 10, 26, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := openTestStore(t)
+			s.SetDayGrouping(tt.g)
+
+			// Three days of hourly frames, half past so none sits on a bound
+			want := map[string]int{}
+			imgs := benchImages(72)
+			for i := range imgs {
+				imgs[i].FetchedAt = tt.start.Add(time.Duration(i)*time.Hour + 30*time.Minute)
+				want[tt.g.DayOf(imgs[i].FetchedAt)]++
+			}
+			if err := s.UpsertImages(ctx, imgs); err != nil {
+				t.Fatal(err)
+			}
+
+			days, err := s.ListDays(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]int{}
+			for _, d := range days {
+				got[d.Date] = d.Count
+			}
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Fatalf("ListDays %v, want %v", got, want)
+			}
+			for day, n := range want {
+				imgs, err := s.ListImagePathsByDay(ctx, day)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(imgs) != n {
+					t.Fatalf("%s: %d images by day filter, want %d", day, len(imgs), n)
+				}
+				for _, img := range imgs {
+					if got := tt.g.DayOf(img.FetchedAt); got != day {
+						t.Fatalf("%s filtered into %s, belongs to %s", img.FetchedAt, day, got)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ImageDetail is everything stored about one image. Parts the image doesn't
+// have (label, suggestion, weather, ...) are nil, lists empty.
+type ImageDetail struct {
+	ID         string     `json:"id"`
+	Path       string     `json:"path"`
+	SHA256     string     `json:"sha256"`
+	PHash      string     `json:"phash"` // empty if not computed
+	FetchedAt  time.Time  `json:"fetched_at"`
+	CapturedAt *time.Time `json:"captured_at"`
+	// Camera clock minus fetch time when captured, before any correction
+	CaptureSkewSeconds *float64   `json:"capture_skew_seconds"`
+	SizeBytes          int64      `json:"size_bytes"`
+	Width              int        `json:"width"`
+	Height             int        `json:"height"`
+	Format             string     `json:"format"`
+	Quality            string     `json:"quality"`
+	Split              string     `json:"split"`
+	Stale              bool       `json:"stale"`
+	PredictionPending  bool       `json:"prediction_pending"`
+	ArchivedAt         *time.Time `json:"archived_at"`
+	Notes              string     `json:"notes"`
+
+	Meta       map[string]string `json:"meta"`       // sidecar metadata
+	Provenance *Provenance       `json:"provenance"` // HTTP fetch details
+	Weather    *WeatherReading   `json:"weather"`    // nearest reading within WeatherMaxGap
+
+	Label        *ImageLabel       `json:"label"`
+	LabelHistory []LabelHistory    `json:"label_history"` // oldest first
+	Suggestion   *Suggestion       `json:"suggestion"`
+	Predictions  []ImagePrediction `json:"predictions"` // the last per model version, newest first
+	Annotations  []Annotation      `json:"annotations"`
+	Skips        []PredictionSkip  `json:"skips"` // oldest first
+}
+
+// ImageLabel is the current label of an image.
+type ImageLabel struct {
+	Skystate    string    `json:"skystate"`
+	Meteor      bool      `json:"meteor"`
+	LabeledAt   time.Time `json:"labeled_at"`
+	NeedsReview bool      `json:"needs_review"`
+}
+
+// LabelHistory is one label_history entry of an image.
+type LabelHistory struct {
+	Skystate    string    `json:"skystate"`
+	Meteor      bool      `json:"meteor"`
+	LabeledAt   time.Time `json:"labeled_at"`
+	Source      string    `json:"source"`
+	Labeler     string    `json:"labeler,omitempty"`
+	NeedsReview bool      `json:"needs_review"`
+	RecordedAt  time.Time `json:"recorded_at"`
+}
+
+// ImagePrediction is a stored prediction as part of an ImageDetail.
+type ImagePrediction struct {
+	ModelVersion string             `json:"model_version"`
+	Skystate     string             `json:"skystate"`
+	Confidence   float64            `json:"confidence"`
+	Probs        map[string]float32 `json:"probs"`
+	PreprocessMS float64            `json:"preprocess_ms"`
+	InferenceMS  float64            `json:"inference_ms"`
+	PredictedAt  time.Time          `json:"predicted_at"`
+}
+
+// GetImageDetail returns everything stored about the image with the given id,
+// read in one transaction so the parts agree, or nil if it doesn't exist.
+func (s *Store) GetImageDetail(ctx context.Context, id string) (*ImageDetail, error) {
+	tx, err := s.read.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	d, err := imageDetailRow(ctx, tx, id)
+	if d == nil || err != nil {
+		return nil, err
+	}
+	if d.LabelHistory, err = labelHistory(ctx, tx, id); err != nil {
+		return nil, err
+	}
+	if d.Predictions, err = imagePredictions(ctx, tx, id); err != nil {
+		return nil, err
+	}
+	if d.Annotations, err = listAnnotations(ctx, tx, id); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// imageDetailRow reads the image row with its label, suggestion and the
+// parts stored as JSON.
+func imageDetailRow(ctx context.Context, tx *sql.Tx, id string) (*ImageDetail, error) {
+	var (
+		d                               ImageDetail
+		fetchedAtStr                    string
+		capturedNS, archivedNS, notesNS sql.NullString
+		splitNS                         sql.NullString
+		skewNF                          sql.NullFloat64
+		metaNS, provenanceNS, weatherNS sql.NullString
+		skipsNS                         sql.NullString
+		skystateNS, labeledAtNS         sql.NullString
+		meteorNI, needsReviewNI         sql.NullInt64
+		sugStateNS, sugModelNS, sugAtNS sql.NullString
+		sugConfNF, sugThresholdNF       sql.NullFloat64
+	)
+	err := tx.QueryRowContext(ctx, `
+SELECT i.id, i.path, i.sha256, i.phash, i.fetched_at, i.captured_at, i.capture_skew, i.size_bytes, i.width, i.height,
+       i.format, i.quality, i.split, i.stale, i.prediction_pending, i.archived_at, i.notes,
+       (SELECT json_group_object(m.key, m.value) FROM image_meta m WHERE m.image_id = i.id),
+       i.provenance, `+weatherNearestSQL+`, `+skipsSQL+`,
+       l.skystate, l.meteor, l.labeled_at, l.needs_review,
+       sg.skystate, sg.confidence, sg.model_version, sg.suggested_at, sg.threshold
+FROM images i
+LEFT JOIN labels l ON l.image_id = i.id
+LEFT JOIN suggested_labels sg ON sg.image_id = i.id AND l.image_id IS NULL
+WHERE i.id = ?`, id).Scan(&d.ID, &d.Path, &d.SHA256, &d.PHash, &fetchedAtStr, &capturedNS, &skewNF, &d.SizeBytes, &d.Width, &d.Height,
+		&d.Format, &d.Quality, &splitNS, &d.Stale, &d.PredictionPending, &archivedNS, &notesNS,
+		&metaNS, &provenanceNS, &weatherNS, &skipsNS,
+		&skystateNS, &meteorNI, &labeledAtNS, &needsReviewNI,
+		&sugStateNS, &sugConfNF, &sugModelNS, &sugAtNS, &sugThresholdNF)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get image detail: %w", err)
+	}
+
+	d.FetchedAt, _ = time.Parse(time.RFC3339, fetchedAtStr)
+	d.CapturedAt = parseNullTime(capturedNS)
+	if skewNF.Valid {
+		d.CaptureSkewSeconds = &skewNF.Float64
+	}
+	d.Split = splitNS.String
+	d.ArchivedAt = parseNullTime(archivedNS)
+	d.Notes = notesNS.String
+
+	// json_group_object over no rows gives {}
+	if err := json.Unmarshal([]byte(metaNS.String), &d.Meta); err != nil || len(d.Meta) == 0 {
+		d.Meta = nil
+	}
+	if provenanceNS.Valid {
+		var p Provenance
+		if json.Unmarshal([]byte(provenanceNS.String), &p) == nil {
+			d.Provenance = &p
+		}
+	}
+	if weatherNS.Valid {
+		var w WeatherReading
+		if json.Unmarshal([]byte(weatherNS.String), &w) == nil {
+			d.Weather = &w
+		}
+	}
+	d.Skips = []PredictionSkip{}
+	if skipsNS.Valid {
+		_ = json.Unmarshal([]byte(skipsNS.String), &d.Skips)
+	}
+
+	if skystateNS.Valid {
+		d.Label = &ImageLabel{
+			Skystate:    skystateNS.String,
+			Meteor:      meteorNI.Int64 == 1,
+			NeedsReview: needsReviewNI.Int64 == 1,
+		}
+		d.Label.LabeledAt, _ = time.Parse(time.RFC3339, labeledAtNS.String)
+	}
+	if sugStateNS.Valid {
+		d.Suggestion = &Suggestion{
+			Skystate:     sugStateNS.String,
+			Confidence:   sugConfNF.Float64,
+			ModelVersion: sugModelNS.String,
+		}
+		d.Suggestion.SuggestedAt, _ = time.Parse(time.RFC3339, sugAtNS.String)
+		if sugThresholdNF.Valid {
+			d.Suggestion.Threshold = &sugThresholdNF.Float64
+		}
+	}
+	return &d, nil
+}
+
+// labelHistory returns the label history of an image, oldest first.
+func labelHistory(ctx context.Context, q querier, imageID string) ([]LabelHistory, error) {
+	rows, err := q.QueryContext(ctx, `
+SELECT skystate, meteor, labeled_at, source, labeler, needs_review, recorded_at
+FROM label_history
+WHERE image_id = ?
+ORDER BY id`, imageID)
+	if err != nil {
+		return nil, fmt.Errorf("label history: %w", err)
+	}
+	defer rows.Close()
+
+	out := []LabelHistory{}
+	for rows.Next() {
+		var (
+			h                     LabelHistory
+			labeledAt, recordedAt string
+		)
+		if err := rows.Scan(&h.Skystate, &h.Meteor, &labeledAt, &h.Source, &h.Labeler, &h.NeedsReview, &recordedAt); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		h.LabeledAt, _ = time.Parse(time.RFC3339, labeledAt)
+		h.RecordedAt, _ = time.Parse(time.RFC3339, recordedAt)
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+// imagePredictions returns the last stored prediction of an image per model
+// version, newest first.
+func imagePredictions(ctx context.Context, q querier, imageID string) ([]ImagePrediction, error) {
+	rows, err := q.QueryContext(ctx, `
+SELECT model_version, skystate, confidence, probs, preprocess_ms, inference_ms, predicted_at
+FROM predictions
+WHERE id IN (SELECT MAX(id) FROM predictions WHERE image_id = ? GROUP BY model_version)
+ORDER BY id DESC`, imageID)
+	if err != nil {
+		return nil, fmt.Errorf("image predictions: %w", err)
+	}
+	defer rows.Close()
+
+	out := []ImagePrediction{}
+	for rows.Next() {
+		var (
+			p                  ImagePrediction
+			probs, predictedAt string
+		)
+		if err := rows.Scan(&p.ModelVersion, &p.Skystate, &p.Confidence, &probs, &p.PreprocessMS, &p.InferenceMS, &predictedAt); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		_ = json.Unmarshal([]byte(probs), &p.Probs)
+		p.PredictedAt, _ = time.Parse(time.RFC3339, predictedAt)
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,134 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetImageDetail(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t)
+	at := time.Date(2024, 10, 3, 21, 30, 0, 0, time.UTC)
+	for id, fetched := range map[string]time.Time{"full": at, "bare": at.Add(-24 * time.Hour), "suggested": at} {
+		if err := s.UpsertImage(ctx, id, "/data/"+id+".jpg", "sha_"+id, fetched, 100); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Everything the full image can carry
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(s.SetImageCapture(ctx, "sha_full", at.Add(-30*time.Second), false))
+	must(s.SetCaptureSkew(ctx, "sha_full", -30*time.Second))
+	must(s.SetImageMeta(ctx, "sha_full", map[string]string{MetaKeyExposure: "15s"}))
+	must(s.SetImageProvenance(ctx, "sha_full", Provenance{HTTPStatus: 200, ContentLength: 100, BytesWritten: 100, FetchMS: 12}))
+	must(s.RecordWeather(ctx, WeatherReading{Time: at.Add(-10 * time.Minute), CloudCover: 20}))
+	must(s.RecordWeather(ctx, WeatherReading{Time: at.Add(5 * time.Minute), CloudCover: 80}))
+	must(s.WriteLabel(ctx, LabelWrite{ImageID: "full", Skystate: "heavy_clouds", LabeledAt: at.Add(time.Hour)}))
+	must(s.WriteLabel(ctx, LabelWrite{ImageID: "full", Skystate: "clear", Meteor: true, LabeledAt: at.Add(2 * time.Hour), Labeler: "ana", NeedsReview: true}))
+	for _, p := range []PredictionRecord{
+		{ImageID: "full", ModelVersion: "v1", Skystate: "heavy_clouds", Confidence: 0.6, PredictedAt: at},
+		{ImageID: "full", ModelVersion: "v2", Skystate: "clear", Confidence: 0.9, Probs: map[string]float32{"clear": 0.9}, PredictedAt: at},
+		{ImageID: "full", ModelVersion: "v1", Skystate: "clear", Confidence: 0.7, PredictedAt: at.Add(time.Minute)},
+	} {
+		must(s.RecordPrediction(ctx, p))
+	}
+	_, err := s.CreateAnnotation(ctx, Annotation{ImageID: "full", Kind: "meteor", X: 1, Y: 2, W: 30, H: 4})
+	must(err)
+	must(s.RecordPredictionSkip(ctx, "full", SkipThermal, "82°C"))
+	_, err = s.SetImageNotes(ctx, "full", "lens fogged")
+	must(err)
+	_, err = s.SuggestLabel(ctx, "suggested", Suggestion{Skystate: "precipitation", Confidence: 0.95, ModelVersion: "v2", SuggestedAt: at})
+	must(err)
+
+	t.Run("full", func(t *testing.T) {
+		d, err := s.GetImageDetail(ctx, "full")
+		if err != nil || d == nil {
+			t.Fatalf("GetImageDetail = %v, %v", d, err)
+		}
+		if d.CapturedAt == nil || !d.CapturedAt.Equal(at.Add(-30*time.Second)) || d.CaptureSkewSeconds == nil || *d.CaptureSkewSeconds != -30 {
+			t.Fatalf("capture %v, skew %v", d.CapturedAt, d.CaptureSkewSeconds)
+		}
+		if d.Meta[MetaKeyExposure] != "15s" || d.Notes != "lens fogged" {
+			t.Fatalf("meta %v, notes %q", d.Meta, d.Notes)
+		}
+		if d.Provenance == nil || d.Provenance.HTTPStatus != 200 || d.Quality != QualityOK {
+			t.Fatalf("provenance %+v, quality %s", d.Provenance, d.Quality)
+		}
+		if d.Weather == nil || d.Weather.CloudCover != 80 {
+			t.Fatalf("weather %+v, want the nearer reading", d.Weather)
+		}
+		if d.Label == nil || d.Label.Skystate != "clear" || !d.Label.Meteor || !d.Label.NeedsReview || !d.Label.LabeledAt.Equal(at.Add(2*time.Hour)) {
+			t.Fatalf("label %+v", d.Label)
+		}
+		if len(d.LabelHistory) != 2 || d.LabelHistory[0].Skystate != "heavy_clouds" || d.LabelHistory[1].Labeler != "ana" {
+			t.Fatalf("label history %+v", d.LabelHistory)
+		}
+		if d.Suggestion != nil {
+			t.Fatalf("suggestion %+v on a labeled image", d.Suggestion)
+		}
+		var preds []string
+		for _, p := range d.Predictions {
+			preds = append(preds, p.ModelVersion+"/"+p.Skystate)
+		}
+		if strings.Join(preds, ",") != "v1/clear,v2/clear" || d.Predictions[1].Probs["clear"] != 0.9 {
+			t.Fatalf("predictions %+v, want the last per version, newest first", d.Predictions)
+		}
+		if len(d.Annotations) != 1 || d.Annotations[0].W != 30 {
+			t.Fatalf("annotations %+v", d.Annotations)
+		}
+		if len(d.Skips) != 1 || d.Skips[0].Reason != SkipThermal || d.Skips[0].Detail != "82°C" {
+			t.Fatalf("skips %+v", d.Skips)
+		}
+	})
+
+	t.Run("bare", func(t *testing.T) {
+		d, err := s.GetImageDetail(ctx, "bare")
+		if err != nil || d == nil {
+			t.Fatalf("GetImageDetail = %v, %v", d, err)
+		}
+		b, err := json.Marshal(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got map[string]json.RawMessage
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatal(err)
+		}
+		// Missing parts are null, lists empty; no weather within a day
+		for _, k := range []string{"captured_at", "capture_skew_seconds", "archived_at", "meta", "provenance", "weather", "label", "suggestion"} {
+			if string(got[k]) != "null" {
+				t.Errorf("%s = %s, want null", k, got[k])
+			}
+		}
+		for _, k := range []string{"label_history", "predictions", "annotations", "skips"} {
+			if string(got[k]) != "[]" {
+				t.Errorf("%s = %s, want []", k, got[k])
+			}
+		}
+	})
+
+	t.Run("suggested", func(t *testing.T) {
+		d, err := s.GetImageDetail(ctx, "suggested")
+		if err != nil || d == nil {
+			t.Fatalf("GetImageDetail = %v, %v", d, err)
+		}
+		if d.Label != nil || d.Suggestion == nil || d.Suggestion.Skystate != "precipitation" || d.Suggestion.Threshold != nil {
+			t.Fatalf("label %+v, suggestion %+v", d.Label, d.Suggestion)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		d, err := s.GetImageDetail(ctx, "nope")
+		if err != nil || d != nil {
+			t.Fatalf("GetImageDetail = %+v, %v; want nil", d, err)
+		}
+	})
+}
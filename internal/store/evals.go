@@ -0,0 +1,165 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SplitHash fingerprints the labeled images of a split and their labels, so
+// cached evaluations become stale as soon as the split or a label changes.
+// It also returns the number of images.
+func (s *Store) SplitHash(ctx context.Context, split string) (string, int, error) {
+	rows, err := s.read.QueryContext(ctx, `
+SELECT i.id, l.skystate
+FROM images i
+JOIN labels l ON l.image_id = i.id
+WHERE i.split = ? AND i.archived_at IS NULL
+ORDER BY i.id`, split)
+	if err != nil {
+		return "", 0, fmt.Errorf("hash split: %w", err)
+	}
+	defer rows.Close()
+
+	h := sha256.New()
+	n := 0
+	for rows.Next() {
+		var id, skystate string
+		if err := rows.Scan(&id, &skystate); err != nil {
+			return "", 0, fmt.Errorf("scan: %w", err)
+		}
+		fmt.Fprintf(h, "%s:%s\n", id, skystate)
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16], n, nil
+}
+
+// EvalResult is one image's outcome in a model evaluation.
+type EvalResult struct {
+	ImageID    string
+	Truth      string // label at evaluation time
+	Predicted  string
+	Confidence float64
+}
+
+// EvalID identifies the evaluation of version on the split with splitHash.
+// Version names can't contain "@".
+func EvalID(version, splitHash string) string {
+	return version + "@" + splitHash
+}
+
+// ParseEvalID splits an EvalID into its version and split hash.
+func ParseEvalID(id string) (version, splitHash string, ok bool) {
+	version, splitHash, ok = strings.Cut(id, "@")
+	return version, splitHash, ok && version != "" && splitHash != ""
+}
+
+// SaveModelEval caches the JSON result of evaluating version on a split and
+// replaces its per-image results.
+func (s *Store) SaveModelEval(ctx context.Context, version, splitHash string, body []byte, evaluatedAt time.Time, results []EvalResult) error {
+	return retryBusy(ctx, func() error {
+		tx, err := s.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("begin: %w", err)
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO model_evals(version, split_hash, body, evaluated_at) VALUES(?, ?, ?, ?)
+ON CONFLICT(version, split_hash) DO UPDATE SET body = excluded.body, evaluated_at = excluded.evaluated_at`,
+			version, splitHash, string(body), evaluatedAt.UTC().Format(time.RFC3339)); err != nil {
+			return fmt.Errorf("save model eval: %w", err)
+		}
+
+		id := EvalID(version, splitHash)
+		if _, err := tx.ExecContext(ctx, `DELETE FROM eval_results WHERE eval_id = ?`, id); err != nil {
+			return fmt.Errorf("clear eval results: %w", err)
+		}
+		stmt, err := tx.PrepareContext(ctx, `
+INSERT INTO eval_results(eval_id, image_id, truth, predicted, confidence) VALUES(?, ?, ?, ?, ?)`)
+		if err != nil {
+			return fmt.Errorf("prepare: %w", err)
+		}
+		defer stmt.Close()
+		for _, r := range results {
+			if _, err := stmt.ExecContext(ctx, id, r.ImageID, r.Truth, r.Predicted, r.Confidence); err != nil {
+				return fmt.Errorf("save eval result: %w", err)
+			}
+		}
+		return tx.Commit()
+	})
+}
+
+// EvalPair is an image in a confusion cell of an evaluation.
+type EvalPair struct {
+	ImageID    string  `json:"image_id"`
+	Path       string  `json:"-"`
+	Truth      string  `json:"truth"`
+	Predicted  string  `json:"predicted"`
+	Confidence float64 `json:"confidence"`
+	// Label is the image's current label, which may have changed since the
+	// evaluation; empty if it was removed.
+	Label string `json:"label"`
+}
+
+// HasEvalResults reports whether per-image results are stored for evalID.
+func (s *Store) HasEvalResults(ctx context.Context, evalID string) (bool, error) {
+	var n int
+	if err := s.read.QueryRowContext(ctx, `SELECT COUNT(*) FROM (SELECT 1 FROM eval_results WHERE eval_id = ? LIMIT 1)`, evalID).Scan(&n); err != nil {
+		return false, fmt.Errorf("eval results: %w", err)
+	}
+	return n > 0, nil
+}
+
+// EvalPairs returns the images of evalID predicted as predicted and labeled
+// truth at evaluation time, most confident first, and how many there are in
+// total. Images deleted since are left out.
+func (s *Store) EvalPairs(ctx context.Context, evalID, predicted, truth string, limit, offset int) ([]EvalPair, int, error) {
+	const from = `
+FROM eval_results r
+JOIN images i ON i.id = r.image_id
+LEFT JOIN labels l ON l.image_id = r.image_id
+WHERE r.eval_id = ? AND r.predicted = ? AND r.truth = ?`
+
+	var total int
+	if err := s.read.QueryRowContext(ctx, `SELECT COUNT(*)`+from, evalID, predicted, truth).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count eval pairs: %w", err)
+	}
+	rows, err := s.read.QueryContext(ctx, `
+SELECT r.image_id, i.path, r.truth, r.predicted, r.confidence, COALESCE(l.skystate, '')`+from+`
+ORDER BY r.confidence DESC, r.image_id
+LIMIT ? OFFSET ?`, evalID, predicted, truth, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("eval pairs: %w", err)
+	}
+	defer rows.Close()
+	out := []EvalPair{}
+	for rows.Next() {
+		var p EvalPair
+		if err := rows.Scan(&p.ImageID, &p.Path, &p.Truth, &p.Predicted, &p.Confidence, &p.Label); err != nil {
+			return nil, 0, fmt.Errorf("scan: %w", err)
+		}
+		out = append(out, p)
+	}
+	return out, total, rows.Err()
+}
+
+// GetModelEval returns a cached evaluation body, or nil if there is none.
+func (s *Store) GetModelEval(ctx context.Context, version, splitHash string) ([]byte, error) {
+	var body string
+	err := s.read.QueryRowContext(ctx, `SELECT body FROM model_evals WHERE version = ? AND split_hash = ?`, version, splitHash).Scan(&body)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get model eval: %w", err)
+	}
+	return []byte(body), nil
+}
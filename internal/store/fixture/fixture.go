@@ -0,0 +1,93 @@
+// Package fixture fills a store with synthetic images and labels, for
+// measuring queries at the size of a long-running installation. No files are
+// written; the rows point at paths that don't exist.
+package fixture
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+// Options describes the generated dataset.
+type Options struct {
+	Images   int       // number of images
+	Start    time.Time // fetched_at of the first image (default 2024-01-01 UTC)
+	Interval time.Duration
+	Labeled  float64 // fraction of images with a label
+	Archived float64 // fraction of images archived
+	Seed     uint64
+}
+
+// Defaults returns a year of frames every 2m38s (200k images), 30% labeled.
+func Defaults() Options {
+	return Options{
+		Images:   200_000,
+		Start:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Interval: 158 * time.Second,
+		Labeled:  0.3,
+		Archived: 0.01,
+		Seed:     1,
+	}
+}
+
+var resolutions = [][2]int{{1920, 1080}, {1920, 1080}, {1920, 1080}, {1280, 960}}
+
+// Generate inserts the images (and labels) of o in one transaction. IDs are
+// the fetch times, like frames from the fetcher, so Interval must be >= 1s.
+func Generate(ctx context.Context, st *store.Store, o Options) error {
+	if o.Start.IsZero() {
+		o.Start = Defaults().Start
+	}
+	if o.Interval <= 0 {
+		o.Interval = Defaults().Interval
+	}
+	rng := rand.New(rand.NewPCG(o.Seed, o.Seed))
+
+	tx, err := st.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("fixture: %w", err)
+	}
+	defer tx.Rollback()
+	img, err := tx.PrepareContext(ctx, `INSERT INTO images(id, path, sha256, fetched_at, size_bytes, width, height, split, archived_at) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("fixture: %w", err)
+	}
+	defer img.Close()
+	label, err := tx.PrepareContext(ctx, `INSERT INTO labels(image_id, skystate, meteor, labeled_at, labeler, needs_review) VALUES(?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("fixture: %w", err)
+	}
+	defer label.Close()
+
+	for n := range o.Images {
+		at := o.Start.Add(time.Duration(n) * o.Interval).UTC()
+		id := at.Format("20060102_150405")
+		sum := fmt.Sprintf("%x", sha256.Sum256([]byte(id)))
+		res := resolutions[rng.IntN(len(resolutions))]
+		labeled := rng.Float64() < o.Labeled
+
+		var split, archivedAt any
+		if labeled {
+			split = store.SplitFor(sum, store.DefaultSplitRatios)
+		}
+		if rng.Float64() < o.Archived {
+			archivedAt = at.Add(24 * time.Hour).Format(time.RFC3339)
+		}
+		if _, err := img.ExecContext(ctx, id, "/fixture/"+id+".jpg", sum, at.Format(time.RFC3339),
+			150_000+rng.IntN(300_000), res[0], res[1], split, archivedAt); err != nil {
+			return fmt.Errorf("fixture: insert image: %w", err)
+		}
+		if labeled {
+			if _, err := label.ExecContext(ctx, id, store.SkyStates[rng.IntN(len(store.SkyStates))], rng.IntN(50) == 0,
+				at.Add(time.Hour).Format(time.RFC3339), "fixture", rng.IntN(20) == 0); err != nil {
+				return fmt.Errorf("fixture: insert label: %w", err)
+			}
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,41 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Image file formats stored in images.format.
+const (
+	FormatJPEG    = "jpeg"
+	FormatPNG     = "png"
+	FormatWebP    = "webp"
+	FormatFITS    = "fits"    // stored and listed, but never classified or exported by default
+	FormatHEIC    = "heic"    // likewise; there is no decoder for it
+	FormatUnknown = "unknown" // unrecognized frame, kept as-is
+)
+
+// Formats lists the supported image formats.
+var Formats = []string{FormatJPEG, FormatPNG, FormatWebP, FormatFITS, FormatHEIC, FormatUnknown}
+
+// predictableFormats are the formats the preprocessing can decode.
+var predictableFormats = []string{FormatJPEG, FormatPNG, FormatWebP}
+
+// Predictable reports whether the model can classify images of format.
+func Predictable(format string) bool {
+	return slices.Contains(predictableFormats, format)
+}
+
+// predictableSQL is a condition on images.format matching the predictable
+// formats; the values are constants, so they are inlined.
+var predictableSQL = "format IN ('" + strings.Join(predictableFormats, "', '") + "')"
+
+// SetImageFormat records the file format of the image with the given content hash.
+func (s *Store) SetImageFormat(ctx context.Context, sha256, format string) error {
+	if _, err := s.DB.ExecContext(ctx, `UPDATE images SET format = ? WHERE sha256 = ?`, format, sha256); err != nil {
+		return fmt.Errorf("set image format: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GetImage returns the image row with the given id, or nil if it doesn't exist.
+func (s *Store) GetImage(ctx context.Context, id string) (*Image, error) {
+	var (
+		img          Image
+		fetchedAtStr string
+		archivedAtNS sql.NullString
+	)
+	err := s.read.QueryRowContext(ctx, `SELECT id, path, sha256, fetched_at, size_bytes, width, height, format, archived_at FROM images WHERE id = ?`, id).
+		Scan(&img.ID, &img.Path, &img.SHA256, &fetchedAtStr, &img.SizeBytes, &img.Width, &img.Height, &img.Format, &archivedAtNS)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get image: %w", err)
+	}
+	img.FetchedAt, _ = time.Parse(time.RFC3339, fetchedAtStr)
+	img.ArchivedAt = parseNullTime(archivedAtNS)
+	return &img, nil
+}
+
+// ListImagePathsByDay returns all images fetched on day (YYYY-MM-DD, see
+// DayGrouping), archived ones included.
+func (s *Store) ListImagePathsByDay(ctx context.Context, day string) ([]Image, error) {
+	cond, args := s.days.dayCond("fetched_at", day)
+	rows, err := s.read.QueryContext(ctx, `
+SELECT id, path, sha256, fetched_at, size_bytes, archived_at
+FROM images
+WHERE `+cond+`
+ORDER BY fetched_at ASC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list images by day: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Image
+	for rows.Next() {
+		var (
+			img          Image
+			fetchedAtStr string
+			archivedAtNS sql.NullString
+		)
+		if err := rows.Scan(&img.ID, &img.Path, &img.SHA256, &fetchedAtStr, &img.SizeBytes, &archivedAtNS); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		img.FetchedAt, _ = time.Parse(time.RFC3339, fetchedAtStr)
+		img.ArchivedAt = parseNullTime(archivedAtNS)
+		out = append(out, img)
+	}
+	return out, rows.Err()
+}
+
+// DeleteImagesByDay removes every image fetched on day together with its labels
+// and metadata in a single transaction. Files on disk are left to the caller.
+func (s *Store) DeleteImagesByDay(ctx context.Context, day string) (CleanupResult, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return CleanupResult{}, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	cond, args := s.days.dayCond("fetched_at", day)
+	result, err := listForDelete(ctx, tx, cond, args...)
+	if err != nil {
+		return CleanupResult{}, err
+	}
+	if result.DeletedCount, err = deleteImagesWhere(ctx, tx, cond, args...); err != nil {
+		return CleanupResult{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return CleanupResult{}, fmt.Errorf("commit: %w", err)
+	}
+	return result, nil
+}
+
+// HasImageSHA reports whether an image with the given content hash is stored,
+// or was stored re-encoded (see OriginalFrame).
+func (s *Store) HasImageSHA(ctx context.Context, sha256 string) (bool, error) {
+	var n int
+	if err := s.read.QueryRowContext(ctx, `
+SELECT (SELECT COUNT(*) FROM images WHERE sha256 = ?) + (SELECT COUNT(*) FROM images WHERE original_sha256 = ?)`,
+		sha256, sha256).Scan(&n); err != nil {
+		return false, fmt.Errorf("lookup image hash: %w", err)
+	}
+	return n > 0, nil
+}
+
+// SetImageDimensions records the pixel size of the image with the given content hash.
+func (s *Store) SetImageDimensions(ctx context.Context, sha256 string, width, height int) error {
+	if _, err := s.DB.ExecContext(ctx, `UPDATE images SET width = ?, height = ? WHERE sha256 = ?`, width, height, sha256); err != nil {
+		return fmt.Errorf("set image dimensions: %w", err)
+	}
+	return nil
+}
+
+// SetImageCapture records when the camera captured an image (zero = unknown)
+// and whether it is stale: older than the latest image when it arrived. Stale
+// images stay in the dataset but never become the latest image.
+func (s *Store) SetImageCapture(ctx context.Context, sha256 string, capturedAt time.Time, stale bool) error {
+	var captured any
+	if !capturedAt.IsZero() {
+		captured = capturedAt.UTC().Format(time.RFC3339)
+	}
+	if _, err := s.DB.ExecContext(ctx, `UPDATE images SET captured_at = ?, stale = ? WHERE sha256 = ?`, captured, stale, sha256); err != nil {
+		return fmt.Errorf("set image capture: %w", err)
+	}
+	return nil
+}
+
+// NewestCapture returns the newest capture time of a non-stale image, or zero
+// if none was recorded.
+func (s *Store) NewestCapture(ctx context.Context) (time.Time, error) {
+	var raw sql.NullString
+	if err := s.read.QueryRowContext(ctx, `SELECT MAX(captured_at) FROM images WHERE stale = 0`).Scan(&raw); err != nil {
+		return time.Time{}, fmt.Errorf("newest capture: %w", err)
+	}
+	if !raw.Valid {
+		return time.Time{}, nil
+	}
+	t, _ := time.Parse(time.RFC3339, raw.String)
+	return t, nil
+}
+
+// FormatResolution renders a resolution as "WxH", or "unknown" when not recorded.
+func FormatResolution(width, height int) string {
+	if width <= 0 || height <= 0 {
+		return "unknown"
+	}
+	return fmt.Sprintf("%dx%d", width, height)
+}
+
+// ParseResolution parses "WxH" (e.g. "1920x1920").
+func ParseResolution(s string) (width, height int, err error) {
+	w, h, ok := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "x")
+	if ok {
+		width, err = strconv.Atoi(w)
+		if err == nil {
+			height, err = strconv.Atoi(h)
+		}
+	}
+	if !ok || err != nil || width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("invalid resolution %q; use WxH", s)
+	}
+	return width, height, nil
+}
+
+// SetImageNotes replaces the free-text notes of an image ("" clears them) and
+// reports whether the image exists.
+func (s *Store) SetImageNotes(ctx context.Context, id, notes string) (bool, error) {
+	var n int64
+	err := retryBusy(ctx, func() error {
+		res, err := s.DB.ExecContext(ctx, `UPDATE images SET notes = ? WHERE id = ?`, nullIfEmpty(notes), id)
+		if err != nil {
+			return fmt.Errorf("set image notes: %w", err)
+		}
+		n, _ = res.RowsAffected()
+		return nil
+	})
+	return n > 0, err
+}
+
+// globPrefix returns a GLOB pattern matching strings that start with p.
+func globPrefix(p string) string {
+	var b strings.Builder
+	for _, r := range p {
+		switch r {
+		case '*', '?', '[':
+			b.WriteString("[" + string(r) + "]")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String() + "*"
+}
+
+// escapeLike escapes the LIKE wildcards in v for use with ESCAPE '\'.
+func escapeLike(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(v)
+}
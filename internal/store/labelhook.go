@@ -0,0 +1,16 @@
+package store
+
+// SetLabelObserver registers fn to be told the images whose label was set,
+// changed or deleted, after the change is committed. fn is called on the
+// writing goroutine and must not block; nil removes the observer. Set it
+// before the store is used concurrently.
+func (s *Store) SetLabelObserver(fn func(imageIDs []string)) {
+	s.labelObserver = fn
+}
+
+// labelsChanged tells the observer about committed label changes.
+func (s *Store) labelsChanged(imageIDs ...string) {
+	if s.labelObserver != nil && len(imageIDs) > 0 {
+		s.labelObserver(imageIDs)
+	}
+}
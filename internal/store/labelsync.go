@@ -0,0 +1,202 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Label sources recorded in label_history.
+const (
+	LabelSourceManual     = "manual"
+	LabelSourceAuto       = "auto"   // accepted model suggestion
+	LabelSourceReview     = "review" // confirmation of a label marked for review
+	LabelSourceSyncPrefix = "sync:"
+)
+
+// LabelChange is a label keyed by image content hash, used to exchange labels
+// between instances (image IDs are local filenames and differ per instance).
+type LabelChange struct {
+	SHA256    string    `json:"sha256"`
+	ImageID   string    `json:"image_id,omitempty"`
+	Skystate  string    `json:"skystate"`
+	Meteor    bool      `json:"meteor"`
+	LabeledAt time.Time `json:"labeled_at"`
+}
+
+// Merge actions reported per incoming label.
+const (
+	MergeCreated      = "created"
+	MergeUpdated      = "updated"
+	MergeUnchanged    = "unchanged"
+	MergeOlder        = "older"
+	MergeUnknownImage = "unknown_image"
+)
+
+type MergeItem struct {
+	SHA256 string `json:"sha256"`
+	Action string `json:"action"`
+}
+
+type MergeResult struct {
+	DryRun       bool        `json:"dry_run"`
+	Created      int         `json:"created"`
+	Updated      int         `json:"updated"`
+	Unchanged    int         `json:"unchanged"`
+	Older        int         `json:"older"`
+	UnknownImage int         `json:"unknown_image"`
+	Items        []MergeItem `json:"items"`
+}
+
+// ListLabelChanges returns labels with labeled_at at or after since, oldest first.
+// labeled_at has second precision, so labels written in the second of since are
+// included again; callers skip those they have seen. Ordering is stable
+// (labeled_at, sha256) so limit/offset can be used for pagination.
+func (s *Store) ListLabelChanges(ctx context.Context, since time.Time, limit, offset int) ([]LabelChange, error) {
+	rows, err := s.read.QueryContext(ctx, `
+SELECT i.sha256, i.id, l.skystate, l.meteor, l.labeled_at
+FROM labels l
+JOIN images i ON i.id = l.image_id
+WHERE l.labeled_at >= ?
+ORDER BY l.labeled_at ASC, i.sha256 ASC
+LIMIT ? OFFSET ?`, since.UTC().Format(time.RFC3339), limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("list label changes: %w", err)
+	}
+	defer rows.Close()
+
+	out := []LabelChange{}
+	for rows.Next() {
+		var (
+			c            LabelChange
+			meteor       int
+			labeledAtStr string
+		)
+		if err := rows.Scan(&c.SHA256, &c.ImageID, &c.Skystate, &meteor, &labeledAtStr); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		c.Meteor = meteor == 1
+		c.LabeledAt, _ = time.Parse(time.RFC3339, labeledAtStr)
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// GetLabelBySHA256 looks up an image by content hash and returns its label, if any.
+// imageID is empty when no image with that hash exists.
+func (s *Store) GetLabelBySHA256(ctx context.Context, sha256 string) (imageID string, label *LabelChange, err error) {
+	return getLabelBySHA256(ctx, s.read, sha256)
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func getLabelBySHA256(ctx context.Context, q queryRower, sha256 string) (string, *LabelChange, error) {
+	var (
+		id          string
+		skyNS       sql.NullString
+		meteorNI    sql.NullInt64
+		labeledAtNS sql.NullString
+	)
+	err := q.QueryRowContext(ctx, `
+SELECT i.id, l.skystate, l.meteor, l.labeled_at
+FROM images i
+LEFT JOIN labels l ON l.image_id = i.id
+WHERE i.sha256 = ?`, sha256).Scan(&id, &skyNS, &meteorNI, &labeledAtNS)
+	if err == sql.ErrNoRows {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("get label by sha256: %w", err)
+	}
+	if !skyNS.Valid {
+		return id, nil, nil
+	}
+	labeledAt, _ := time.Parse(time.RFC3339, labeledAtNS.String)
+	return id, &LabelChange{
+		SHA256:    sha256,
+		ImageID:   id,
+		Skystate:  skyNS.String,
+		Meteor:    meteorNI.Int64 == 1,
+		LabeledAt: labeledAt,
+	}, nil
+}
+
+// MergeLabels applies labels from another instance using last-writer-wins on labeled_at.
+// Every applied label is recorded in label_history with the given source.
+// With dryRun set nothing is written, but the result reports what would change.
+func (s *Store) MergeLabels(ctx context.Context, changes []LabelChange, source string, dryRun bool) (MergeResult, error) {
+	var (
+		result  MergeResult
+		applied []string
+	)
+	err := retryBusy(ctx, func() error {
+		var err error
+		result, applied, err = s.mergeLabels(ctx, changes, source, dryRun)
+		return err
+	})
+	if err == nil {
+		s.labelsChanged(applied...)
+	}
+	return result, err
+}
+
+// mergeLabels is one attempt of MergeLabels; it returns the IDs of the images
+// whose label it changed.
+func (s *Store) mergeLabels(ctx context.Context, changes []LabelChange, source string, dryRun bool) (MergeResult, []string, error) {
+	result := MergeResult{DryRun: dryRun, Items: make([]MergeItem, 0, len(changes))}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return result, nil, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	var applied []string
+	for _, c := range changes {
+		imageID, existing, err := getLabelBySHA256(ctx, tx, c.SHA256)
+		if err != nil {
+			return result, nil, err
+		}
+
+		var action string
+		switch {
+		case imageID == "":
+			action = MergeUnknownImage
+			result.UnknownImage++
+		case existing == nil:
+			action = MergeCreated
+			result.Created++
+		case !c.LabeledAt.After(existing.LabeledAt):
+			action = MergeOlder
+			if existing.Skystate == c.Skystate && existing.Meteor == c.Meteor {
+				action = MergeUnchanged
+				result.Unchanged++
+			} else {
+				result.Older++
+			}
+		default:
+			action = MergeUpdated
+			result.Updated++
+		}
+		result.Items = append(result.Items, MergeItem{SHA256: c.SHA256, Action: action})
+
+		if dryRun || (action != MergeCreated && action != MergeUpdated) {
+			continue
+		}
+		if err := s.setLabelTx(ctx, tx, LabelWrite{ImageID: imageID, Skystate: c.Skystate, Meteor: c.Meteor, LabeledAt: c.LabeledAt, Source: source}); err != nil {
+			return result, nil, err
+		}
+		applied = append(applied, imageID)
+	}
+
+	if dryRun {
+		return result, nil, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return result, nil, fmt.Errorf("commit: %w", err)
+	}
+	return result, applied, nil
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
@@ -11,30 +12,44 @@ type LatestRow struct {
 	Path      string    `json:"path"`
 	SHA256    string    `json:"sha256"`
 	FetchedAt time.Time `json:"fetched_at"`
+	Format    string    `json:"format"`
 
 	SkyState  *string    `json:"skystate,omitempty"`
 	Meteor    *bool      `json:"meteor,omitempty"`
 	LabeledAt *time.Time `json:"labeled_at,omitempty"`
 }
 
-func (s *Store) GetLatest() (*LatestRow, error) {
-	row := s.DB.QueryRow(`
-SELECT i.id, i.path, i.sha256, i.fetched_at,
+// latestImageCond and latestImageOrder pick the image GetLatest returns;
+// QuotaEvictions spares the same one.
+const (
+	latestImageCond  = "i.archived_at IS NULL AND i.stale = 0 AND i.quality != '" + QualityTruncated + "'"
+	latestImageOrder = "i.fetched_at DESC, i.id DESC"
+)
+
+const getLatestSQL = `
+SELECT i.id, i.path, i.sha256, i.fetched_at, i.format,
        l.skystate, l.meteor, l.labeled_at
 FROM images i
 LEFT JOIN labels l ON l.image_id = i.id
-ORDER BY i.fetched_at DESC
+WHERE ` + latestImageCond + `
+ORDER BY ` + latestImageOrder + `
 LIMIT 1;
-`)
+`
+
+// GetLatest returns the newest image, skipping archived, stale (see
+// SetImageCapture) and truncated frames, or nil if there is none.
+func (s *Store) GetLatest(ctx context.Context) (*LatestRow, error) {
+	row := s.stmts.getLatest.QueryRowContext(ctx)
 
 	var (
 		id, path, sha256, fetchedAtStr string
+		format                         string
 		skyNS                          sql.NullString
 		meteorNI                       sql.NullInt64
 		labeledAtNS                    sql.NullString
 	)
 
-	if err := row.Scan(&id, &path, &sha256, &fetchedAtStr, &skyNS, &meteorNI, &labeledAtNS); err != nil {
+	if err := row.Scan(&id, &path, &sha256, &fetchedAtStr, &format, &skyNS, &meteorNI, &labeledAtNS); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -48,6 +63,7 @@ LIMIT 1;
 		Path:      path,
 		SHA256:    sha256,
 		FetchedAt: fetchedAt,
+		Format:    format,
 	}
 
 	if skyNS.Valid {
@@ -0,0 +1,180 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// restoreSuffix marks a backup staged by StageRestore; Open swaps it in.
+const restoreSuffix = ".restore"
+
+// Backup writes a consistent copy of the database to dest (which must not exist).
+func (s *Store) Backup(ctx context.Context, dest string) error {
+	if _, err := s.DB.ExecContext(ctx, `VACUUM INTO ?`, dest); err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+	return nil
+}
+
+// IntegrityCheck runs SQLite's integrity check and returns its findings;
+// a healthy database yields nil.
+func (s *Store) IntegrityCheck(ctx context.Context) ([]string, error) {
+	return integrityCheck(ctx, s.read)
+}
+
+func integrityCheck(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `PRAGMA integrity_check`)
+	if err != nil {
+		return nil, fmt.Errorf("integrity check: %w", err)
+	}
+	defer rows.Close()
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	return problems, rows.Err()
+}
+
+// Optimize checkpoints the WAL and refreshes query planner statistics; with
+// vacuum it also rebuilds the file to reclaim free pages (blocks writers).
+func (s *Store) Optimize(ctx context.Context, vacuum bool) error {
+	stmts := []string{`PRAGMA wal_checkpoint(TRUNCATE)`, `ANALYZE`, `PRAGMA optimize`}
+	if vacuum {
+		stmts = append(stmts, `VACUUM`)
+	}
+	for _, q := range stmts {
+		if _, err := s.DB.ExecContext(ctx, q); err != nil {
+			return fmt.Errorf("%s: %w", q, err)
+		}
+	}
+	return nil
+}
+
+// StageRestore checks that backup is a healthy database and stages it to
+// replace the live one on the next Open. The running store is not touched.
+func (s *Store) StageRestore(ctx context.Context, backup string) error {
+	db, err := sql.Open("sqlite", "file:"+backup+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("open backup: %w", err)
+	}
+	problems, err := integrityCheck(ctx, db)
+	db.Close()
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("backup failed integrity check: %s", problems[0])
+	}
+
+	src, err := os.Open(backup)
+	if err != nil {
+		return fmt.Errorf("open backup: %w", err)
+	}
+	defer src.Close()
+	tmp := s.path + restoreSuffix + ".tmp"
+	dst, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("stage restore: %w", err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("stage restore: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("stage restore: %w", err)
+	}
+	if err := os.Rename(tmp, s.path+restoreSuffix); err != nil {
+		return fmt.Errorf("stage restore: %w", err)
+	}
+	return nil
+}
+
+// applyStagedRestore replaces dbPath with a restore staged by StageRestore, if any.
+// The old WAL files belong to the replaced database and are removed with it.
+func applyStagedRestore(dbPath string) error {
+	staged := dbPath + restoreSuffix
+	if _, err := os.Stat(staged); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	for _, p := range []string{dbPath + "-wal", dbPath + "-shm"} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("apply restore: %w", err)
+		}
+	}
+	if err := os.Rename(staged, dbPath); err != nil {
+		return fmt.Errorf("apply restore: %w", err)
+	}
+	return nil
+}
+
+// ListImageRefs returns the id and path of every image, for reconciling the
+// database with the files on disk.
+func (s *Store) ListImageRefs(ctx context.Context) ([]Image, error) {
+	rows, err := s.read.QueryContext(ctx, `SELECT id, path, size_bytes FROM images ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("list image refs: %w", err)
+	}
+	defer rows.Close()
+	var out []Image
+	for rows.Next() {
+		var img Image
+		if err := rows.Scan(&img.ID, &img.Path, &img.SizeBytes); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		out = append(out, img)
+	}
+	return out, rows.Err()
+}
+
+// DeleteUnlabeledBefore deletes unlabeled images fetched before cutoff. With
+// dryRun nothing is deleted and the result describes what would be.
+func (s *Store) DeleteUnlabeledBefore(ctx context.Context, cutoff time.Time, dryRun bool) (CleanupResult, error) {
+	rows, err := s.read.QueryContext(ctx, `
+SELECT i.id, i.path, i.size_bytes
+FROM images i
+LEFT JOIN labels l ON l.image_id = i.id
+WHERE l.image_id IS NULL AND i.fetched_at < ?
+ORDER BY i.fetched_at ASC`, cutoff.UTC().Format(time.RFC3339))
+	if err != nil {
+		return CleanupResult{}, fmt.Errorf("list unlabeled before: %w", err)
+	}
+	var images []Image
+	for rows.Next() {
+		var img Image
+		if err := rows.Scan(&img.ID, &img.Path, &img.SizeBytes); err != nil {
+			rows.Close()
+			return CleanupResult{}, fmt.Errorf("scan: %w", err)
+		}
+		images = append(images, img)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return CleanupResult{}, err
+	}
+
+	result := CleanupResult{DeletedPaths: make([]string, 0, len(images))}
+	for _, img := range images {
+		if !dryRun {
+			if err := s.DeleteImage(ctx, img.ID); err != nil {
+				return result, fmt.Errorf("delete image %s: %w", img.ID, err)
+			}
+		}
+		result.DeletedCount++
+		result.DeletedPaths = append(result.DeletedPaths, img.Path)
+		result.FreedBytes += img.SizeBytes
+	}
+	return result, nil
+}
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// Well-known image_meta keys.
+const (
+	MetaKeyExposure = "exposure"
+)
+
+// SetImageMeta stores sidecar metadata for the image with the given content hash,
+// replacing any existing values for the same keys.
+func (s *Store) SetImageMeta(ctx context.Context, sha256 string, meta map[string]string) error {
+	if len(meta) == 0 {
+		return nil
+	}
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	for k, v := range meta {
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO image_meta(image_id, key, value)
+SELECT id, ?, ? FROM images WHERE sha256 = ?
+ON CONFLICT(image_id, key) DO UPDATE SET value=excluded.value`, k, v, sha256); err != nil {
+			return fmt.Errorf("set image meta %s: %w", k, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetImageMeta returns all metadata for an image (empty map if none).
+func (s *Store) GetImageMeta(ctx context.Context, imageID string) (map[string]string, error) {
+	rows, err := s.read.QueryContext(ctx, `SELECT key, value FROM image_meta WHERE image_id = ?`, imageID)
+	if err != nil {
+		return nil, fmt.Errorf("get image meta: %w", err)
+	}
+	defer rows.Close()
+
+	out := map[string]string{}
+	for rows.Next() {
+		var k, v string
+		if err := rows.Scan(&k, &v); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		out[k] = v
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,160 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Overview is what the dataset page shows: the counters, the day list and the
+// first page of images.
+type Overview struct {
+	Stats  DatasetStats     `json:"stats"`
+	Days   []DaySummary     `json:"days"`
+	Images []ImageWithLabel `json:"images"`
+}
+
+// The overview avoids sorting the images: UTC days are grouped along
+// idx_images_day, resolutions along idx_images_resolution, and only labels
+// are grouped by class, with the splits counted per class.
+const (
+	overviewDaysSQL = `
+SELECT %s AS day, SUM(archived_at IS NULL), SUM(CASE WHEN archived_at IS NULL THEN size_bytes ELSE 0 END),
+       SUM(archived_at IS NOT NULL)
+FROM images
+GROUP BY day
+ORDER BY day DESC`
+
+	overviewResolutionsSQL = `SELECT width, height, COUNT(*) FROM images WHERE archived_at IS NULL GROUP BY width, height`
+)
+
+var overviewLabelsSQL = `
+SELECT l.skystate, COUNT(*), SUM(l.needs_review)` + strings.Repeat(", SUM(i.split IS ?)", len(Splits)) + `
+FROM labels l
+JOIN images i ON i.id = l.image_id
+WHERE ` + activeImage + `
+GROUP BY l.skystate`
+
+// Overview returns what CountStats and ListDays do and the images matching f,
+// read in one transaction so they agree with each other. It is several times
+// faster than the three calls on a large dataset.
+func (s *Store) Overview(ctx context.Context, f ImageFilter) (Overview, error) {
+	tx, err := s.read.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return Overview{}, fmt.Errorf("overview: %w", err)
+	}
+	defer tx.Rollback()
+
+	out := Overview{Stats: newDatasetStats(), Days: []DaySummary{}}
+	if err := overviewDays(ctx, tx, s.days, &out); err != nil {
+		return Overview{}, err
+	}
+	if err := overviewResolutions(ctx, tx, &out.Stats); err != nil {
+		return Overview{}, err
+	}
+	if err := overviewLabels(ctx, tx, &out.Stats); err != nil {
+		return Overview{}, err
+	}
+	out.Stats.Unlabeled = out.Stats.Total - out.Stats.Labeled
+
+	if out.Images, err = listImages(ctx, tx, s.days, f); err != nil {
+		return Overview{}, err
+	}
+	if out.Images == nil {
+		out.Images = []ImageWithLabel{}
+	}
+	return out, nil
+}
+
+// overviewDays fills the day list, the image and archived counts and the total size.
+func overviewDays(ctx context.Context, tx *sql.Tx, g DayGrouping, out *Overview) error {
+	from, to, err := fetchedSpan(ctx, tx)
+	if err != nil {
+		return err
+	}
+	day, args := g.dayExpr("fetched_at", from, to)
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(overviewDaysSQL, day), args...)
+	if err != nil {
+		return fmt.Errorf("overview days: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			d        DaySummary
+			archived int
+		)
+		if err := rows.Scan(&d.Date, &d.Count, &d.SizeBytes, &archived); err != nil {
+			return fmt.Errorf("scan day: %w", err)
+		}
+		out.Stats.Total += d.Count
+		out.Stats.TotalSizeBytes += d.SizeBytes
+		out.Stats.Archived += archived
+		if d.Count > 0 {
+			out.Days = append(out.Days, d)
+		}
+	}
+	return rows.Err()
+}
+
+func overviewResolutions(ctx context.Context, tx *sql.Tx, stats *DatasetStats) error {
+	rows, err := tx.QueryContext(ctx, overviewResolutionsSQL)
+	if err != nil {
+		return fmt.Errorf("overview resolutions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var w, h, n int
+		if err := rows.Scan(&w, &h, &n); err != nil {
+			return fmt.Errorf("scan resolution count: %w", err)
+		}
+		stats.ByResolution[FormatResolution(w, h)] += n
+	}
+	return rows.Err()
+}
+
+// overviewLabels fills the label counters, by class and by split.
+func overviewLabels(ctx context.Context, tx *sql.Tx, stats *DatasetStats) error {
+	args := make([]any, len(Splits))
+	for i, sp := range Splits {
+		args[i] = sp
+	}
+	rows, err := tx.QueryContext(ctx, overviewLabelsSQL, args...)
+	if err != nil {
+		return fmt.Errorf("overview labels: %w", err)
+	}
+	defer rows.Close()
+
+	stats.BySplit = map[string]map[string]int{}
+	for _, sp := range append(Splits, SplitNone) {
+		stats.BySplit[sp] = map[string]int{}
+	}
+	for rows.Next() {
+		var (
+			class          string
+			n, needsReview int
+			perSplit       = make([]int, len(Splits))
+		)
+		dest := []any{&class, &n, &needsReview}
+		for i := range perSplit {
+			dest = append(dest, &perSplit[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return fmt.Errorf("scan class count: %w", err)
+		}
+		stats.Labeled += n
+		stats.ByClass[class] = n
+		stats.NeedsReview += needsReview
+		unassigned := n
+		for i, sp := range Splits {
+			if perSplit[i] > 0 {
+				stats.BySplit[sp][class] = perSplit[i]
+			}
+			unassigned -= perSplit[i]
+		}
+		if unassigned > 0 {
+			stats.BySplit[SplitNone][class] = unassigned
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SetImagePHash stores the perceptual hash for the image with the given content hash.
+func (s *Store) SetImagePHash(ctx context.Context, sha256, phash string) error {
+	if _, err := s.DB.ExecContext(ctx, `UPDATE images SET phash = ? WHERE sha256 = ?`, phash, sha256); err != nil {
+		return fmt.Errorf("set image phash: %w", err)
+	}
+	return nil
+}
+
+// ListImagesWithoutPHash returns up to limit images that have no perceptual hash yet,
+// ordered by id and starting after afterID so callers can page past failures.
+// Images in formats that can't be decoded (FITS, HEIC, ...) are skipped.
+func (s *Store) ListImagesWithoutPHash(ctx context.Context, afterID string, limit int) ([]Image, error) {
+	rows, err := s.read.QueryContext(ctx, `
+SELECT id, path, sha256, fetched_at, size_bytes
+FROM images
+WHERE phash = '' AND `+predictableSQL+` AND id > ?
+ORDER BY id ASC
+LIMIT ?`, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list images without phash: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Image
+	for rows.Next() {
+		var (
+			img          Image
+			fetchedAtStr string
+		)
+		if err := rows.Scan(&img.ID, &img.Path, &img.SHA256, &fetchedAtStr, &img.SizeBytes); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		img.FetchedAt, _ = time.Parse(time.RFC3339, fetchedAtStr)
+		out = append(out, img)
+	}
+	return out, rows.Err()
+}
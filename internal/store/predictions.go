@@ -0,0 +1,305 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// PredictionRecord is one model prediction for a stored image.
+type PredictionRecord struct {
+	ImageID      string
+	ModelVersion string
+	Skystate     string
+	Confidence   float64
+	Probs        map[string]float32
+	PreprocessMS float64
+	InferenceMS  float64
+	PredictedAt  time.Time
+}
+
+// RecordPrediction stores a prediction together with its timings, replacing
+// any earlier one of the same model version for the image: re-predicting an
+// image (cache expiry, detail views, replays) must not pile up rows that skew
+// the metrics built on them. The replacement gets a new id, so it counts as
+// the image's newest prediction.
+func (s *Store) RecordPrediction(ctx context.Context, p PredictionRecord) error {
+	probs, err := json.Marshal(p.Probs)
+	if err != nil {
+		return fmt.Errorf("marshal probs: %w", err)
+	}
+	return retryBusy(ctx, func() error {
+		_, err := s.DB.ExecContext(ctx, `
+INSERT OR REPLACE INTO predictions(image_id, model_version, skystate, confidence, probs, preprocess_ms, inference_ms, predicted_at)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?)`,
+			p.ImageID, p.ModelVersion, p.Skystate, p.Confidence, string(probs),
+			p.PreprocessMS, p.InferenceMS, p.PredictedAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return fmt.Errorf("record prediction: %w", err)
+		}
+		return nil
+	})
+}
+
+// dedupPredictions keeps only the newest prediction per image and model
+// version and then makes that unique, once: databases from before
+// RecordPrediction replaced rows have duplicates.
+func dedupPredictions(db *sql.DB) error {
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = 'idx_predictions_image_model'`).Scan(&n); err != nil {
+		return fmt.Errorf("check prediction index: %w", err)
+	}
+	if n > 0 {
+		return nil
+	}
+	res, err := db.Exec(`DELETE FROM predictions WHERE id NOT IN (SELECT MAX(id) FROM predictions GROUP BY image_id, model_version)`)
+	if err != nil {
+		return fmt.Errorf("remove duplicate predictions: %w", err)
+	}
+	if removed, _ := res.RowsAffected(); removed > 0 {
+		log.Printf("store: removed %d duplicate predictions", removed)
+	}
+	if _, err := db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_predictions_image_model ON predictions(image_id, model_version)`); err != nil {
+		return fmt.Errorf("create prediction index: %w", err)
+	}
+	return nil
+}
+
+// ListPredictableImages returns the unarchived images in a format the model
+// reads that were fetched on the days from through to (as grouped by
+// DayGrouping), oldest first.
+func (s *Store) ListPredictableImages(ctx context.Context, from, to time.Time) ([]Image, error) {
+	start, _ := s.days.Bounds(from)
+	_, end := s.days.Bounds(to)
+	rows, err := s.read.QueryContext(ctx, `
+SELECT i.id, i.path, i.fetched_at
+FROM images i
+WHERE i.fetched_at >= ? AND i.fetched_at < ? AND `+activeImage+` AND i.`+predictableSQL+`
+ORDER BY i.fetched_at ASC`, start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("list predictable images: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Image
+	for rows.Next() {
+		var (
+			img          Image
+			fetchedAtStr string
+		)
+		if err := rows.Scan(&img.ID, &img.Path, &fetchedAtStr); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		img.FetchedAt, _ = time.Parse(time.RFC3339, fetchedAtStr)
+		out = append(out, img)
+	}
+	return out, rows.Err()
+}
+
+// Percentiles holds nearest-rank latency percentiles in milliseconds.
+type Percentiles struct {
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+}
+
+// LatencyStats summarizes prediction timings for one model version
+// (or all versions when ModelVersion is empty).
+type LatencyStats struct {
+	ModelVersion string      `json:"model_version,omitempty"`
+	Count        int         `json:"count"`
+	Preprocess   Percentiles `json:"preprocess_ms"`
+	Inference    Percentiles `json:"inference_ms"`
+}
+
+// InferenceLatency returns latency percentiles over the last n predictions,
+// overall and per model version.
+func (s *Store) InferenceLatency(ctx context.Context, n int) (overall LatencyStats, byModel []LatencyStats, err error) {
+	all, err := s.latencyPercentiles(ctx, n, false)
+	if err != nil {
+		return overall, nil, err
+	}
+	if len(all) > 0 {
+		overall = all[0]
+	}
+	byModel, err = s.latencyPercentiles(ctx, n, true)
+	return overall, byModel, err
+}
+
+// latencyPercentiles ranks timings with window functions and picks, per group,
+// the smallest value whose rank reaches p*count (nearest-rank percentile).
+func (s *Store) latencyPercentiles(ctx context.Context, n int, byModel bool) ([]LatencyStats, error) {
+	rows, err := s.read.QueryContext(ctx, `
+WITH recent AS (
+  SELECT CASE WHEN ? THEN model_version ELSE '' END AS grp, preprocess_ms, inference_ms
+  FROM predictions
+  ORDER BY id DESC
+  LIMIT ?
+), ranked AS (
+  SELECT grp, preprocess_ms, inference_ms,
+         ROW_NUMBER() OVER (PARTITION BY grp ORDER BY preprocess_ms) AS rp,
+         ROW_NUMBER() OVER (PARTITION BY grp ORDER BY inference_ms) AS ri,
+         COUNT(*) OVER (PARTITION BY grp) AS n
+  FROM recent
+)
+SELECT grp, MAX(n),
+       MIN(CASE WHEN rp >= 0.50 * n THEN preprocess_ms END),
+       MIN(CASE WHEN rp >= 0.95 * n THEN preprocess_ms END),
+       MIN(CASE WHEN rp >= 0.99 * n THEN preprocess_ms END),
+       MIN(CASE WHEN ri >= 0.50 * n THEN inference_ms END),
+       MIN(CASE WHEN ri >= 0.95 * n THEN inference_ms END),
+       MIN(CASE WHEN ri >= 0.99 * n THEN inference_ms END)
+FROM ranked
+GROUP BY grp
+ORDER BY grp`, byModel, n)
+	if err != nil {
+		return nil, fmt.Errorf("inference latency: %w", err)
+	}
+	defer rows.Close()
+
+	out := []LatencyStats{}
+	for rows.Next() {
+		var st LatencyStats
+		if err := rows.Scan(&st.ModelVersion, &st.Count,
+			&st.Preprocess.P50, &st.Preprocess.P95, &st.Preprocess.P99,
+			&st.Inference.P50, &st.Inference.P95, &st.Inference.P99); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		out = append(out, st)
+	}
+	return out, rows.Err()
+}
+
+// DebounceWindow is how many recent predictions vote on the current sky state.
+const DebounceWindow = 5
+
+// Debounce returns the majority sky state of recent (newest first), which
+// smooths out single-frame flips; ties go to the newer prediction.
+func Debounce(recent []PredictionRecord) string {
+	votes := map[string]int{}
+	debounced := recent[0].Skystate
+	for _, p := range recent {
+		votes[p.Skystate]++
+		if votes[p.Skystate] > votes[debounced] {
+			debounced = p.Skystate
+		}
+	}
+	return debounced
+}
+
+// RecentPredictions returns the last n stored predictions, newest first.
+func (s *Store) RecentPredictions(ctx context.Context, n int) ([]PredictionRecord, error) {
+	rows, err := s.read.QueryContext(ctx, `
+SELECT image_id, model_version, skystate, confidence, probs, preprocess_ms, inference_ms, predicted_at
+FROM predictions
+ORDER BY id DESC
+LIMIT ?`, n)
+	if err != nil {
+		return nil, fmt.Errorf("recent predictions: %w", err)
+	}
+	defer rows.Close()
+
+	out := []PredictionRecord{}
+	for rows.Next() {
+		var (
+			p              PredictionRecord
+			probs          string
+			predictedAtStr string
+		)
+		if err := rows.Scan(&p.ImageID, &p.ModelVersion, &p.Skystate, &p.Confidence, &probs,
+			&p.PreprocessMS, &p.InferenceMS, &predictedAtStr); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		_ = json.Unmarshal([]byte(probs), &p.Probs)
+		p.PredictedAt, _ = time.Parse(time.RFC3339, predictedAtStr)
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+const latestPredictionSQL = `
+SELECT p.image_id, p.model_version, p.skystate, p.confidence, p.probs, p.preprocess_ms, p.inference_ms, p.predicted_at,
+       i.fetched_at
+FROM images i
+JOIN predictions p ON p.image_id = i.id
+WHERE ` + latestImageCond + `
+ORDER BY i.fetched_at DESC, p.id DESC
+LIMIT 1;
+`
+
+// LatestPrediction returns the last stored prediction of the newest image
+// that has one (archived, stale and truncated frames skipped, as in
+// GetLatest) and that image's fetch time, or nil if no image has a prediction.
+func (s *Store) LatestPrediction(ctx context.Context) (*PredictionRecord, time.Time, error) {
+	var (
+		p                          PredictionRecord
+		probs                      string
+		predictedAtStr, fetchedStr string
+	)
+	err := s.stmts.latestPrediction.QueryRowContext(ctx).Scan(&p.ImageID, &p.ModelVersion, &p.Skystate, &p.Confidence, &probs,
+		&p.PreprocessMS, &p.InferenceMS, &predictedAtStr, &fetchedStr)
+	if err == sql.ErrNoRows {
+		return nil, time.Time{}, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("latest prediction: %w", err)
+	}
+	_ = json.Unmarshal([]byte(probs), &p.Probs)
+	p.PredictedAt, _ = time.Parse(time.RFC3339, predictedAtStr)
+	fetchedAt, _ := time.Parse(time.RFC3339, fetchedStr)
+	return &p, fetchedAt, nil
+}
+
+// PendingPrediction is an image whose auto-prediction was dropped.
+type PendingPrediction struct {
+	ID   string
+	Path string
+}
+
+// SetPredictionPending marks an image as waiting for the catch-up sweep, or
+// clears the mark.
+func (s *Store) SetPredictionPending(ctx context.Context, imageID string, pending bool) error {
+	return retryBusy(ctx, func() error {
+		if _, err := s.DB.ExecContext(ctx, `UPDATE images SET prediction_pending = ? WHERE id = ?`, pending, imageID); err != nil {
+			return fmt.Errorf("set prediction pending: %w", err)
+		}
+		return nil
+	})
+}
+
+// PendingPredictions returns up to limit active images marked pending, oldest
+// first. Truncated images and those whose last prediction skip is not
+// retryable (see RetryableSkip) are left out.
+func (s *Store) PendingPredictions(ctx context.Context, limit int) ([]PendingPrediction, error) {
+	rows, err := s.read.QueryContext(ctx, `
+SELECT id, path FROM images
+WHERE prediction_pending = 1 AND archived_at IS NULL AND quality != '`+QualityTruncated+`' AND `+predictableSQL+`
+  AND COALESCE((SELECT k.reason FROM prediction_skips k WHERE k.image_id = images.id ORDER BY k.id DESC LIMIT 1), '') NOT IN (`+finalSkipsSQL+`)
+ORDER BY fetched_at ASC, id ASC
+LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("pending predictions: %w", err)
+	}
+	defer rows.Close()
+	var out []PendingPrediction
+	for rows.Next() {
+		var p PendingPrediction
+		if err := rows.Scan(&p.ID, &p.Path); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// CountPendingPredictions returns how many images are marked pending.
+func (s *Store) CountPendingPredictions(ctx context.Context) (int, error) {
+	var n int
+	if err := s.read.QueryRowContext(ctx, `SELECT COUNT(*) FROM images WHERE prediction_pending = 1`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count pending predictions: %w", err)
+	}
+	return n, nil
+}
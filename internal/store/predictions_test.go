@@ -0,0 +1,186 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func countPredictions(t *testing.T, s *Store, imageID, version string) int {
+	t.Helper()
+	var n int
+	if err := s.DB.QueryRow(`SELECT COUNT(*) FROM predictions WHERE image_id = ? AND model_version = ?`, imageID, version).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestRecordPredictionReplaces(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t)
+	if err := s.UpsertImage(ctx, "img1", "/data/img1.jpg", "sha1", time.Now(), 100); err != nil {
+		t.Fatal(err)
+	}
+	record := func(version, class string) {
+		t.Helper()
+		err := s.RecordPrediction(ctx, PredictionRecord{ImageID: "img1", ModelVersion: version, Skystate: class, Confidence: 0.9, PredictedAt: time.Now()})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	record("v1", "clear")
+	record("v2", "cloudy")
+	record("v1", "heavy_clouds") // e.g. the cache expired
+
+	if n := countPredictions(t, s, "img1", "v1"); n != 1 {
+		t.Fatalf("%d v1 predictions, want 1", n)
+	}
+	if n := countPredictions(t, s, "img1", "v2"); n != 1 {
+		t.Fatalf("%d v2 predictions, want 1", n)
+	}
+	// The replacement is the newest prediction
+	var version, class string
+	if err := s.DB.QueryRow(`SELECT model_version, skystate FROM predictions WHERE id = (SELECT MAX(id) FROM predictions)`).Scan(&version, &class); err != nil {
+		t.Fatal(err)
+	}
+	if version != "v1" || class != "heavy_clouds" {
+		t.Fatalf("newest prediction %s/%s, want v1/heavy_clouds", version, class)
+	}
+}
+
+func TestDedupPredictionsMigration(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t)
+	if err := s.UpsertImage(ctx, "img1", "/data/img1.jpg", "sha1", time.Now(), 100); err != nil {
+		t.Fatal(err)
+	}
+	// A database from before the unique index
+	if _, err := s.DB.Exec(`DROP INDEX idx_predictions_image_model`); err != nil {
+		t.Fatal(err)
+	}
+	for _, conf := range []float64{0.5, 0.6, 0.7} {
+		if _, err := s.DB.Exec(`INSERT INTO predictions(image_id, model_version, skystate, confidence, probs, preprocess_ms, inference_ms, predicted_at)
+VALUES('img1', 'v1', 'clear', ?, '{}', 0, 0, '2024-09-01T00:00:00Z')`, conf); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if n := countPredictions(t, s, "img1", "v1"); n != 1 {
+		t.Fatalf("%d predictions after the migration, want 1", n)
+	}
+	var conf float64
+	if err := s.DB.QueryRow(`SELECT confidence FROM predictions`).Scan(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf != 0.7 {
+		t.Fatalf("kept confidence %v, want the newest (0.7)", conf)
+	}
+}
+
+// TestTruncatedNeverLatestOrPending checks a frame marked truncated by an older
+// version is neither served as the latest image or prediction nor queued for
+// prediction.
+func TestTruncatedNeverLatestOrPending(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t)
+	now := time.Now()
+	if err := s.UpsertImage(ctx, "whole", "/data/whole.jpg", "sha1", now.Add(-time.Minute), 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpsertImage(ctx, "partial", "/data/partial.jpg", "sha2", now, 50); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetImageProvenance(ctx, "sha2", Provenance{HTTPStatus: 200, ContentLength: 100, BytesWritten: 50}); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"whole", "partial"} {
+		if err := s.SetPredictionPending(ctx, id, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	latest, err := s.GetLatest(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest == nil || latest.ID != "whole" {
+		t.Fatalf("latest %+v, want whole", latest)
+	}
+	for _, id := range []string{"whole", "partial"} {
+		if err := s.RecordPrediction(ctx, PredictionRecord{ImageID: id, ModelVersion: "v1", Skystate: "clear", PredictedAt: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if p, _, err := s.LatestPrediction(ctx); err != nil || p == nil || p.ImageID != "whole" {
+		t.Fatalf("latest prediction %+v, %v; want whole's", p, err)
+	}
+	pending, err := s.PendingPredictions(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].ID != "whole" {
+		t.Fatalf("pending %+v, want only whole", pending)
+	}
+}
+
+func TestLatestPredictionOrdering(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t)
+	if p, _, err := s.LatestPrediction(ctx); err != nil || p != nil {
+		t.Fatalf("empty store: %+v, %v", p, err)
+	}
+
+	base := time.Date(2024, 10, 3, 21, 0, 0, 0, time.UTC)
+	for i, id := range []string{"old", "mid", "new", "newest"} {
+		if err := s.UpsertImage(ctx, id, "/data/"+id+".jpg", "sha-"+id, base.Add(time.Duration(i)*time.Minute), 100); err != nil {
+			t.Fatal(err)
+		}
+	}
+	record := func(id, version, class string, at time.Time) {
+		t.Helper()
+		if err := s.RecordPrediction(ctx, PredictionRecord{ImageID: id, ModelVersion: version, Skystate: class, Confidence: 0.8, PredictedAt: at}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(wantID, wantVersion, wantClass string) {
+		t.Helper()
+		p, fetchedAt, err := s.LatestPrediction(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p == nil || p.ImageID != wantID || p.ModelVersion != wantVersion || p.Skystate != wantClass {
+			t.Fatalf("latest prediction %+v, want %s/%s/%s", p, wantID, wantVersion, wantClass)
+		}
+		var want string
+		if err := s.DB.QueryRowContext(ctx, `SELECT fetched_at FROM images WHERE id = ?`, wantID).Scan(&want); err != nil {
+			t.Fatal(err)
+		}
+		if fetchedAt.Format(time.RFC3339) != want {
+			t.Fatalf("fetched_at %v, want %s", fetchedAt, want)
+		}
+	}
+
+	// Predicted late, but the image is older than mid's
+	record("mid", "v1", "clear", base.Add(time.Hour))
+	record("old", "v1", "heavy_clouds", base.Add(2*time.Hour))
+	check("mid", "v1", "clear")
+
+	// The newest image's last recorded prediction wins, whatever its time
+	record("new", "v2", "light_clouds", base.Add(time.Hour))
+	record("new", "v1", "precipitation", base)
+	check("new", "v1", "precipitation")
+
+	// Archived and stale images are skipped
+	record("newest", "v1", "clear", base)
+	if _, err := s.DB.ExecContext(ctx, `UPDATE images SET archived_at = ? WHERE id = 'newest'`, base.Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+	check("new", "v1", "precipitation")
+	if _, err := s.DB.ExecContext(ctx, `UPDATE images SET stale = 1 WHERE id = 'new'`); err != nil {
+		t.Fatal(err)
+	}
+	check("mid", "v1", "clear")
+}
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Image quality values. The fetcher drops incomplete downloads, but rows
+// marked truncated by older versions stay for inspection; they are never the
+// latest image, predicted or exported for training.
+const (
+	QualityOK        = "ok"
+	QualityTruncated = "truncated"
+)
+
+// Provenance records how an image was fetched, for debugging camera issues.
+type Provenance struct {
+	HTTPStatus    int        `json:"http_status"`
+	ContentLength int64      `json:"content_length"` // -1 if the response didn't declare one
+	BytesWritten  int64      `json:"bytes_written"`
+	ServerDate    *time.Time `json:"server_date,omitempty"`   // the response Date header
+	LastModified  *time.Time `json:"last_modified,omitempty"` // the response Last-Modified header
+	FetchMS       float64    `json:"fetch_ms"`                // request start to last body byte
+
+	Original *OriginalFrame `json:"original,omitempty"` // set when the frame was re-encoded for storage
+}
+
+// OriginalFrame describes a frame as the camera delivered it, before it was
+// scaled down or re-encoded for storage.
+type OriginalFrame struct {
+	SHA256 string `json:"sha256"`
+	Bytes  int64  `json:"bytes"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// Truncated reports whether fewer (or more) bytes were written than the
+// server announced in Content-Length.
+func (p Provenance) Truncated() bool {
+	return p.ContentLength >= 0 && p.ContentLength != p.BytesWritten
+}
+
+// SetImageProvenance stores the fetch provenance for the image with the given
+// content hash and marks it truncated when the body was incomplete. The hash
+// of a re-encoded frame's original is kept for HasImageSHA.
+func (s *Store) SetImageProvenance(ctx context.Context, sha256 string, p Provenance) error {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("marshal provenance: %w", err)
+	}
+	quality := QualityOK
+	if p.Truncated() {
+		quality = QualityTruncated
+	}
+	var original any
+	if p.Original != nil {
+		original = p.Original.SHA256
+	}
+	if _, err := s.DB.ExecContext(ctx, `UPDATE images SET provenance = ?, quality = ?, original_sha256 = ? WHERE sha256 = ?`,
+		string(b), quality, original, sha256); err != nil {
+		return fmt.Errorf("set image provenance: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// Quota caps the stored images; zero fields mean no limit.
+type Quota struct {
+	MaxImages int
+	MaxBytes  int64
+	// EvictLabeled lets labeled images go too, oldest first, once there are
+	// no unlabeled ones left to evict.
+	EvictLabeled bool
+}
+
+// Enabled reports whether q sets any limit.
+func (q Quota) Enabled() bool {
+	return q.MaxImages > 0 || q.MaxBytes > 0
+}
+
+// QuotaUsage is what counts against a Quota: every image row, archived or
+// not, since archived frames keep their files.
+type QuotaUsage struct {
+	Images int   `json:"images"`
+	Bytes  int64 `json:"bytes"`
+}
+
+// Eviction is an image chosen by QuotaEvictions.
+type Eviction struct {
+	ID        string
+	Path      string
+	SizeBytes int64
+	Labeled   bool
+}
+
+// QuotaUsage returns the number and total size of the stored images.
+func (s *Store) QuotaUsage(ctx context.Context) (QuotaUsage, error) {
+	var u QuotaUsage
+	if err := s.read.QueryRowContext(ctx, `SELECT COUNT(*), COALESCE(SUM(size_bytes), 0) FROM images`).Scan(&u.Images, &u.Bytes); err != nil {
+		return QuotaUsage{}, fmt.Errorf("quota usage: %w", err)
+	}
+	return u, nil
+}
+
+// QuotaEvictions returns the images to delete to bring the store within q, in
+// eviction order: unlabeled images oldest first, then, if q.EvictLabeled,
+// labeled ones oldest first. The image /api/latest serves (see GetLatest)
+// and images whose path is in keep are never chosen. When that isn't enough to meet q, all candidates are
+// returned and over is true.
+func (s *Store) QuotaEvictions(ctx context.Context, q Quota, keep map[string]bool) (evs []Eviction, over bool, err error) {
+	if !q.Enabled() {
+		return nil, false, nil
+	}
+	u, err := s.QuotaUsage(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	within := func() bool {
+		return (q.MaxImages <= 0 || u.Images <= q.MaxImages) && (q.MaxBytes <= 0 || u.Bytes <= q.MaxBytes)
+	}
+	if within() {
+		return nil, false, nil
+	}
+
+	labeledCond := "l.image_id IS NULL"
+	if q.EvictLabeled {
+		labeledCond = "1"
+	}
+	rows, err := s.read.QueryContext(ctx, `
+SELECT i.id, i.path, i.size_bytes, l.image_id IS NOT NULL
+FROM images i
+LEFT JOIN labels l ON l.image_id = i.id
+WHERE `+labeledCond+`
+  AND i.id IS NOT (SELECT i.id FROM images i WHERE `+latestImageCond+` ORDER BY `+latestImageOrder+` LIMIT 1)
+ORDER BY l.image_id IS NOT NULL, i.fetched_at ASC, i.id ASC`)
+	if err != nil {
+		return nil, false, fmt.Errorf("quota evictions: %w", err)
+	}
+	defer rows.Close()
+	for !within() && rows.Next() {
+		var e Eviction
+		if err := rows.Scan(&e.ID, &e.Path, &e.SizeBytes, &e.Labeled); err != nil {
+			return nil, false, fmt.Errorf("scan: %w", err)
+		}
+		if keep[e.Path] {
+			continue
+		}
+		evs = append(evs, e)
+		u.Images--
+		u.Bytes -= e.SizeBytes
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	return evs, !within(), nil
+}
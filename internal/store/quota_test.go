@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openTestStore returns a migrated store in a temp directory.
+func openTestStore(t testing.TB) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "labels.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestQuotaEvictionsSparesServedLatest(t *testing.T) {
+	base := time.Date(2024, 9, 1, 22, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		hide   func(ctx context.Context, s *Store) error // makes img3, the newest, unservable
+		spared string
+	}{
+		{"newest served", func(context.Context, *Store) error { return nil }, "img3"},
+		{"newest stale", func(ctx context.Context, s *Store) error {
+			return s.SetImageCapture(ctx, "sha3", base, true)
+		}, "img2"},
+		{"newest archived", func(ctx context.Context, s *Store) error {
+			_, err := s.ArchiveImagesByDay(ctx, "2024-09-02", time.Now())
+			return err
+		}, "img2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := openTestStore(t)
+			for i, id := range []string{"img1", "img2", "img3"} {
+				fetched := base.Add(time.Duration(i) * time.Hour) // img3 falls on Sep 2
+				sha := "sha" + id[len(id)-1:]
+				if err := s.UpsertImage(ctx, id, "/data/"+id+".jpg", sha, fetched, 100); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := tt.hide(ctx, s); err != nil {
+				t.Fatal(err)
+			}
+			latest, err := s.GetLatest(ctx)
+			if err != nil || latest == nil || latest.ID != tt.spared {
+				t.Fatalf("GetLatest = %+v, %v; want %s", latest, err, tt.spared)
+			}
+
+			evs, over, err := s.QuotaEvictions(ctx, Quota{MaxImages: 1}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if over || len(evs) != 2 {
+				t.Fatalf("evictions %+v, over %v; want two", evs, over)
+			}
+			for _, e := range evs {
+				if e.ID == tt.spared {
+					t.Fatalf("evicted %s, the image /api/latest serves", e.ID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ReportInfo identifies a cached report.
+type ReportInfo struct {
+	Kind        string    `json:"kind"`
+	Date        string    `json:"date"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// SaveReport caches the JSON body of a generated report, replacing an older one.
+func (s *Store) SaveReport(ctx context.Context, kind, date string, body []byte, generatedAt time.Time) error {
+	_, err := s.DB.ExecContext(ctx, `
+INSERT INTO reports(kind, date, body, generated_at) VALUES(?, ?, ?, ?)
+ON CONFLICT(kind, date) DO UPDATE SET body = excluded.body, generated_at = excluded.generated_at`,
+		kind, date, string(body), generatedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("save report: %w", err)
+	}
+	return nil
+}
+
+// GetReport returns the cached report body, or nil if none was generated.
+func (s *Store) GetReport(ctx context.Context, kind, date string) ([]byte, error) {
+	var body string
+	err := s.read.QueryRowContext(ctx, `SELECT body FROM reports WHERE kind = ? AND date = ?`, kind, date).Scan(&body)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get report: %w", err)
+	}
+	return []byte(body), nil
+}
+
+// ListReports returns the cached reports of kind, newest date first.
+func (s *Store) ListReports(ctx context.Context, kind string) ([]ReportInfo, error) {
+	rows, err := s.read.QueryContext(ctx, `SELECT kind, date, generated_at FROM reports WHERE kind = ? ORDER BY date DESC`, kind)
+	if err != nil {
+		return nil, fmt.Errorf("list reports: %w", err)
+	}
+	defer rows.Close()
+	out := []ReportInfo{}
+	for rows.Next() {
+		var (
+			r              ReportInfo
+			generatedAtStr string
+		)
+		if err := rows.Scan(&r.Kind, &r.Date, &generatedAtStr); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		r.GeneratedAt, _ = time.Parse(time.RFC3339, generatedAtStr)
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// NightFrame is one image in a time window with its label, meteor regions
+// and latest stored prediction.
+type NightFrame struct {
+	ImageID       string
+	Path          string
+	FetchedAt     time.Time
+	Label         string // "" if unlabeled
+	Meteor        bool   // labeled as containing a meteor
+	MeteorRegions int    // "meteor" annotation regions
+	Predicted     string // latest prediction, "" if none
+	Confidence    float64
+}
+
+// ListFramesBetween returns the images fetched in [start, end), oldest first.
+func (s *Store) ListFramesBetween(ctx context.Context, start, end time.Time) ([]NightFrame, error) {
+	rows, err := s.read.QueryContext(ctx, `
+SELECT i.id, i.path, i.fetched_at,
+       COALESCE(l.skystate, ''), COALESCE(l.meteor, 0),
+       (SELECT COUNT(*) FROM annotations a WHERE a.image_id = i.id AND a.kind = 'meteor'),
+       COALESCE(p.skystate, ''), COALESCE(p.confidence, 0)
+FROM images i
+LEFT JOIN labels l ON l.image_id = i.id
+LEFT JOIN predictions p ON p.id = (SELECT MAX(id) FROM predictions WHERE image_id = i.id)
+WHERE i.fetched_at >= ? AND i.fetched_at < ? AND i.archived_at IS NULL
+ORDER BY i.fetched_at ASC`, start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("list frames: %w", err)
+	}
+	defer rows.Close()
+
+	var out []NightFrame
+	for rows.Next() {
+		var (
+			f            NightFrame
+			fetchedAtStr string
+			meteor       int
+		)
+		if err := rows.Scan(&f.ImageID, &f.Path, &fetchedAtStr, &f.Label, &meteor, &f.MeteorRegions, &f.Predicted, &f.Confidence); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		f.FetchedAt, _ = time.Parse(time.RFC3339, fetchedAtStr)
+		f.Meteor = meteor == 1
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
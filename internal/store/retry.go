@@ -0,0 +1,47 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	busyRetries   = 4
+	busyBaseDelay = 50 * time.Millisecond
+)
+
+// sqliteBusy and sqliteLocked are the primary SQLite result codes for lock
+// contention; extended codes (e.g. SQLITE_BUSY_SNAPSHOT) share the low byte.
+const (
+	sqliteBusy   = 5
+	sqliteLocked = 6
+)
+
+// isBusy reports whether err is a lock contention error from SQLite.
+func isBusy(err error) bool {
+	var coder interface{ Code() int }
+	if !errors.As(err, &coder) {
+		return false
+	}
+	code := coder.Code() & 0xff
+	return code == sqliteBusy || code == sqliteLocked
+}
+
+// retryBusy runs fn, retrying with exponential backoff and jitter while it fails
+// with SQLITE_BUSY. busy_timeout handles almost all contention; this covers the
+// rest (e.g. a checkpoint holding the lock longer than the timeout).
+func retryBusy(ctx context.Context, fn func() error) error {
+	err := fn()
+	for attempt := 0; attempt < busyRetries && isBusy(err); attempt++ {
+		delay := busyBaseDelay << attempt
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay/2 + rand.N(delay)):
+		}
+		err = fn()
+	}
+	return err
+}
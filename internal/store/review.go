@@ -0,0 +1,45 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ConfirmLabels clears the review flag of the given images' labels and records
+// each confirmation in label_history (source LabelSourceReview). Labels not
+// awaiting review are left alone; it returns how many were confirmed.
+func (s *Store) ConfirmLabels(ctx context.Context, imageIDs []string, labeler string) (int, error) {
+	var confirmed int
+	err := retryBusy(ctx, func() error {
+		confirmed = 0
+		tx, err := s.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("begin: %w", err)
+		}
+		defer tx.Rollback()
+
+		now := time.Now().UTC().Format(time.RFC3339)
+		for _, id := range imageIDs {
+			res, err := tx.ExecContext(ctx, `UPDATE labels SET needs_review = 0 WHERE image_id = ? AND needs_review = 1`, id)
+			if err != nil {
+				return fmt.Errorf("confirm label: %w", err)
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, `
+INSERT INTO label_history(image_id, skystate, meteor, labeled_at, source, labeler, recorded_at, needs_review)
+SELECT image_id, skystate, meteor, labeled_at, ?, ?, ?, 0 FROM labels WHERE image_id = ?`,
+				LabelSourceReview, labeler, now, id); err != nil {
+				return fmt.Errorf("record label history: %w", err)
+			}
+			confirmed++
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return 0, err
+	}
+	return confirmed, nil
+}
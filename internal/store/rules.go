@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Rule is a stored alerting rule. The store keeps its definition as given;
+// the rules package reads and validates it.
+type Rule struct {
+	ID          int64           `json:"id"`
+	Spec        json.RawMessage `json:"spec"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	LastFiredAt *time.Time      `json:"last_fired_at"`
+}
+
+// ListRules returns all rules, oldest first.
+func (s *Store) ListRules(ctx context.Context) ([]Rule, error) {
+	rows, err := s.read.QueryContext(ctx, `SELECT id, spec, created_at, updated_at, last_fired_at FROM rules ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("list rules: %w", err)
+	}
+	defer rows.Close()
+	out := []Rule{}
+	for rows.Next() {
+		r, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// GetRule returns the rule with the given ID, or nil if there is none.
+func (s *Store) GetRule(ctx context.Context, id int64) (*Rule, error) {
+	r, err := scanRule(s.read.QueryRowContext(ctx, `SELECT id, spec, created_at, updated_at, last_fired_at FROM rules WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func scanRule(row interface{ Scan(...any) error }) (Rule, error) {
+	var (
+		r                    Rule
+		spec                 string
+		createdAt, updatedAt string
+		firedAt              sql.NullString
+	)
+	if err := row.Scan(&r.ID, &spec, &createdAt, &updatedAt, &firedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return r, err
+		}
+		return r, fmt.Errorf("scan rule: %w", err)
+	}
+	r.Spec = json.RawMessage(spec)
+	r.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	r.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	r.LastFiredAt = parseNullTime(firedAt)
+	return r, nil
+}
+
+// CreateRule stores a new rule and returns its ID.
+func (s *Store) CreateRule(ctx context.Context, spec json.RawMessage) (int64, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	var id int64
+	err := retryBusy(ctx, func() error {
+		res, err := s.DB.ExecContext(ctx, `INSERT INTO rules(spec, created_at, updated_at) VALUES(?, ?, ?)`, string(spec), now, now)
+		if err != nil {
+			return err
+		}
+		id, err = res.LastInsertId()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("create rule: %w", err)
+	}
+	return id, nil
+}
+
+// UpdateRule replaces a rule's definition; it reports false for an unknown ID.
+func (s *Store) UpdateRule(ctx context.Context, id int64, spec json.RawMessage) (bool, error) {
+	var n int64
+	err := retryBusy(ctx, func() error {
+		res, err := s.DB.ExecContext(ctx, `UPDATE rules SET spec = ?, updated_at = ? WHERE id = ?`,
+			string(spec), time.Now().UTC().Format(time.RFC3339), id)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("update rule: %w", err)
+	}
+	return n > 0, nil
+}
+
+// DeleteRule deletes a rule; it reports false for an unknown ID.
+func (s *Store) DeleteRule(ctx context.Context, id int64) (bool, error) {
+	res, err := s.DB.ExecContext(ctx, `DELETE FROM rules WHERE id = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("delete rule: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// SetRuleFired records when a rule last fired, which its cooldown counts from.
+func (s *Store) SetRuleFired(ctx context.Context, id int64, at time.Time) error {
+	return retryBusy(ctx, func() error {
+		if _, err := s.DB.ExecContext(ctx, `UPDATE rules SET last_fired_at = ? WHERE id = ?`, at.UTC().Format(time.RFC3339), id); err != nil {
+			return fmt.Errorf("set rule fired: %w", err)
+		}
+		return nil
+	})
+}
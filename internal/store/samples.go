@@ -0,0 +1,77 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SampleImage is one labeled image of a class sample.
+type SampleImage struct {
+	ImageID     string    `json:"image_id"`
+	Path        string    `json:"-"`
+	FetchedAt   time.Time `json:"fetched_at"`
+	Skystate    string    `json:"skystate"`
+	Meteor      bool      `json:"meteor"`
+	NeedsReview bool      `json:"needs_review,omitempty"`
+}
+
+// sampleKey orders images for a seeded sample. It depends only on the image
+// and the seed, so a seed gives the same sample on every call, and labeling
+// more images only adds to it where the new ones sort first.
+func sampleKey(imageID string, seed int64) uint64 {
+	sum := sha256.Sum256([]byte(imageID + "\x00" + strconv.FormatInt(seed, 10)))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// SampleLabeled returns up to n labeled, active images of each class in
+// skystates, picked pseudo-randomly but reproducibly from seed. Every class
+// asked for has an entry, empty if it has no images.
+func (s *Store) SampleLabeled(ctx context.Context, skystates []string, n int, seed int64) (map[string][]SampleImage, error) {
+	out := make(map[string][]SampleImage, len(skystates))
+	if len(skystates) == 0 {
+		return out, nil
+	}
+	args := make([]any, len(skystates))
+	for i, c := range skystates {
+		out[c] = []SampleImage{}
+		args[i] = c
+	}
+	rows, err := s.read.QueryContext(ctx, `
+SELECT i.id, i.path, i.fetched_at, l.skystate, l.meteor, l.needs_review
+FROM labels l
+JOIN images i ON i.id = l.image_id
+WHERE `+activeImage+` AND l.skystate IN (?`+strings.Repeat(", ?", len(skystates)-1)+`)`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sample labels: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			im        SampleImage
+			fetchedAt string
+		)
+		if err := rows.Scan(&im.ImageID, &im.Path, &fetchedAt, &im.Skystate, &im.Meteor, &im.NeedsReview); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		im.FetchedAt, _ = time.Parse(time.RFC3339, fetchedAt)
+		out[im.Skystate] = append(out[im.Skystate], im)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for c, ims := range out {
+		slices.SortFunc(ims, func(a, b SampleImage) int {
+			return cmp.Or(cmp.Compare(sampleKey(a.ImageID, seed), sampleKey(b.ImageID, seed)), strings.Compare(a.ImageID, b.ImageID))
+		})
+		out[c] = ims[:min(n, len(ims))]
+	}
+	return out, nil
+}
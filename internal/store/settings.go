@@ -0,0 +1,53 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Setting keys.
+const (
+	SettingPreprocess             = "preprocess"                      // infer.PreprocessConfig
+	SettingAutoLabelMinConfidence = "auto_label_min_confidence"       // float64; 0 or unset = off
+	SettingAutoLabelClassMinConf  = "auto_label_class_min_confidence" // map class -> float64 overriding the above
+	SettingAuth                   = "auth"                            // api.Credentials; unset = no login
+	SettingClockCorrection        = "clock_correction"                // string: "auto" or a duration subtracted from capture times; unset = none
+	SettingFetcherState           = "fetcher_state"                   // map of fetcher source -> fetcher.State
+	SettingTrainStarts            = "train_starts"                    // trainer.StartHistory
+)
+
+// GetSetting decodes the JSON value stored under key into v.
+// It returns false if the key has never been set.
+func (s *Store) GetSetting(ctx context.Context, key string, v any) (bool, error) {
+	var raw string
+	err := s.read.QueryRowContext(ctx, `SELECT value FROM settings WHERE key = ?`, key).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("get setting %s: %w", key, err)
+	}
+	if err := json.Unmarshal([]byte(raw), v); err != nil {
+		return false, fmt.Errorf("decode setting %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// SetSetting stores v as JSON under key.
+func (s *Store) SetSetting(ctx context.Context, key string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode setting %s: %w", key, err)
+	}
+	_, err = s.DB.ExecContext(ctx, `
+INSERT INTO settings(key, value, updated_at) VALUES(?, ?, ?)
+ON CONFLICT(key) DO UPDATE SET value=excluded.value, updated_at=excluded.updated_at`,
+		key, string(raw), time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("set setting %s: %w", key, err)
+	}
+	return nil
+}
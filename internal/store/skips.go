@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Reasons an image got no prediction on ingest.
+const (
+	SkipQueueDropped = "queue_dropped"        // the prediction queue was full
+	SkipThermal      = "thermal"              // the board was too hot
+	SkipNoModel      = "no_model"             // no model was loaded
+	SkipTruncated    = "truncated"            // the download was incomplete
+	SkipFormat       = "unpredictable_format" // FITS, HEIC or unknown
+	SkipBadImage     = "bad_image"            // the model input couldn't be made from it
+	SkipPredictError = "predict_error"        // the model failed on it
+)
+
+// RetryableSkip reports whether the catch-up sweep retries images skipped
+// for reason: the load-related reasons pass, while the image's own faults
+// would only fail again.
+func RetryableSkip(reason string) bool {
+	return reason == SkipQueueDropped || reason == SkipThermal
+}
+
+// finalSkipsSQL lists the reasons RetryableSkip rejects, for an IN clause.
+var finalSkipsSQL = "'" + strings.Join([]string{SkipNoModel, SkipTruncated, SkipFormat, SkipBadImage, SkipPredictError}, "', '") + "'"
+
+// PredictionSkip is one decision not to predict an image.
+type PredictionSkip struct {
+	Reason string    `json:"reason"`
+	Detail string    `json:"detail,omitempty"` // e.g. the error
+	At     time.Time `json:"at"`
+}
+
+// prediction skips of image i as a JSON array, oldest first
+const skipsSQL = `(
+  SELECT json_group_array(json_object('reason', k.reason, 'detail', k.detail, 'at', k.at))
+  FROM (SELECT reason, detail, at FROM prediction_skips WHERE image_id = i.id ORDER BY id) k)`
+
+// RecordPredictionSkip records that the image was not predicted and why.
+func (s *Store) RecordPredictionSkip(ctx context.Context, imageID, reason, detail string) error {
+	return retryBusy(ctx, func() error {
+		_, err := s.DB.ExecContext(ctx, `INSERT INTO prediction_skips(image_id, reason, detail, at) VALUES(?, ?, ?, ?)`,
+			imageID, reason, detail, time.Now().UTC().Format(time.RFC3339))
+		if err != nil {
+			return fmt.Errorf("record prediction skip: %w", err)
+		}
+		return nil
+	})
+}
+
+// PredictionSkipsSince counts the prediction skips recorded since since by
+// reason.
+func (s *Store) PredictionSkipsSince(ctx context.Context, since time.Time) (map[string]int, error) {
+	rows, err := s.read.QueryContext(ctx, `
+SELECT reason, COUNT(*) FROM prediction_skips
+WHERE at >= ?
+GROUP BY reason`, since.UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("prediction skips: %w", err)
+	}
+	defer rows.Close()
+	out := map[string]int{}
+	for rows.Next() {
+		var (
+			reason string
+			n      int
+		)
+		if err := rows.Scan(&reason, &n); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		out[reason] = n
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,142 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// A dataset snapshot freezes the labels of the active images under a name,
+// keyed by image SHA-256, so that exact training set can be rebuilt after
+// relabeling. Snapshots are independent of the live labels: deleting one
+// leaves them alone, and relabeling leaves the snapshot alone.
+
+var snapshotNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// ValidSnapshotName reports whether name can name a snapshot, e.g.
+// "dataset-2024-10".
+func ValidSnapshotName(name string) bool {
+	return snapshotNameRe.MatchString(name)
+}
+
+// DatasetSnapshot describes a snapshot.
+type DatasetSnapshot struct {
+	Name      string         `json:"name"`
+	CreatedAt time.Time      `json:"created_at"`
+	Labels    int            `json:"labels"`
+	ByClass   map[string]int `json:"by_class"`
+	// Missing counts labeled images deleted since; exports and training
+	// from the snapshot leave them out.
+	Missing int `json:"missing"`
+}
+
+// CreateSnapshot records the current labels of the active images as
+// snapshot name. It returns nil if the name is taken.
+func (s *Store) CreateSnapshot(ctx context.Context, name string, at time.Time) (*DatasetSnapshot, error) {
+	created := false
+	err := retryBusy(ctx, func() error {
+		tx, err := s.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("begin: %w", err)
+		}
+		defer tx.Rollback()
+
+		res, err := tx.ExecContext(ctx, `INSERT INTO dataset_snapshots(name, created_at) VALUES(?, ?) ON CONFLICT(name) DO NOTHING`,
+			name, at.UTC().Format(time.RFC3339))
+		if err != nil {
+			return fmt.Errorf("create snapshot: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil
+		}
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO dataset_snapshot_labels(snapshot, sha256, skystate, meteor, needs_review, labeled_at)
+SELECT ?, i.sha256, l.skystate, l.meteor, l.needs_review, l.labeled_at
+FROM labels l
+JOIN images i ON i.id = l.image_id
+WHERE `+activeImage, name); err != nil {
+			return fmt.Errorf("snapshot labels: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("commit: %w", err)
+		}
+		created = true
+		return nil
+	})
+	if err != nil || !created {
+		return nil, err
+	}
+	return s.GetSnapshot(ctx, name)
+}
+
+// GetSnapshot returns the snapshot called name, or nil if there is none.
+func (s *Store) GetSnapshot(ctx context.Context, name string) (*DatasetSnapshot, error) {
+	snaps, err := s.listSnapshots(ctx, name)
+	if err != nil || len(snaps) == 0 {
+		return nil, err
+	}
+	return &snaps[0], nil
+}
+
+// ListSnapshots returns all snapshots, newest first.
+func (s *Store) ListSnapshots(ctx context.Context) ([]DatasetSnapshot, error) {
+	return s.listSnapshots(ctx, "")
+}
+
+func (s *Store) listSnapshots(ctx context.Context, name string) ([]DatasetSnapshot, error) {
+	rows, err := s.read.QueryContext(ctx, `
+SELECT s.name, s.created_at, sl.skystate, COUNT(sl.sha256),
+       COALESCE(SUM(sl.sha256 IS NOT NULL AND NOT EXISTS (SELECT 1 FROM images i WHERE i.sha256 = sl.sha256)), 0)
+FROM dataset_snapshots s
+LEFT JOIN dataset_snapshot_labels sl ON sl.snapshot = s.name
+WHERE ? = '' OR s.name = ?
+GROUP BY s.name, sl.skystate
+ORDER BY s.created_at DESC, s.name`, name, name)
+	if err != nil {
+		return nil, fmt.Errorf("list snapshots: %w", err)
+	}
+	defer rows.Close()
+	out := []DatasetSnapshot{}
+	for rows.Next() {
+		var (
+			snapName, createdAt string
+			skystate            sql.NullString
+			n, missing          int
+		)
+		if err := rows.Scan(&snapName, &createdAt, &skystate, &n, &missing); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		if len(out) == 0 || out[len(out)-1].Name != snapName {
+			t, _ := time.Parse(time.RFC3339, createdAt)
+			out = append(out, DatasetSnapshot{Name: snapName, CreatedAt: t, ByClass: map[string]int{}})
+		}
+		snap := &out[len(out)-1]
+		if skystate.Valid {
+			snap.ByClass[skystate.String] = n
+			snap.Labels += n
+			snap.Missing += missing
+		}
+	}
+	return out, rows.Err()
+}
+
+// DeleteSnapshot removes a snapshot and reports whether it existed. Live
+// labels are untouched.
+func (s *Store) DeleteSnapshot(ctx context.Context, name string) (bool, error) {
+	var n int64
+	err := retryBusy(ctx, func() error {
+		// Its labels go with it (ON DELETE CASCADE)
+		res, err := s.DB.ExecContext(ctx, `DELETE FROM dataset_snapshots WHERE name = ?`, name)
+		if err != nil {
+			return err
+		}
+		n, _ = res.RowsAffected()
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("delete snapshot: %w", err)
+	}
+	return n > 0, nil
+}
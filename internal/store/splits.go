@@ -0,0 +1,158 @@
+package store
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Dataset splits stored in images.split. Unassigned images have NULL.
+const (
+	SplitTrain = "train"
+	SplitVal   = "val"
+	SplitTest  = "test"
+
+	// SplitNone selects unassigned images in ImageFilter.Split and keys them
+	// in DatasetStats.BySplit.
+	SplitNone = "unassigned"
+)
+
+// Splits lists the valid split names.
+var Splits = []string{SplitTrain, SplitVal, SplitTest}
+
+// SplitRatios are the target fractions of newly assigned images.
+type SplitRatios struct {
+	Train float64 `json:"train"`
+	Val   float64 `json:"val"`
+	Test  float64 `json:"test"`
+}
+
+// DefaultSplitRatios is used when no ratios are given.
+var DefaultSplitRatios = SplitRatios{Train: 0.8, Val: 0.1, Test: 0.1}
+
+// Validate checks the ratios are non-negative and sum to 1.
+func (r SplitRatios) Validate() error {
+	if r.Train < 0 || r.Val < 0 || r.Test < 0 {
+		return errors.New("split ratios must not be negative")
+	}
+	if math.Abs(r.Train+r.Val+r.Test-1) > 1e-6 {
+		return errors.New("split ratios must sum to 1")
+	}
+	return nil
+}
+
+// SplitFor deterministically maps an image's content hash to a split: the
+// first 8 bytes of the sha256 as a fraction in [0, 1) against the cumulative
+// ratios. The same image lands in the same split on every instance.
+func SplitFor(sha256 string, r SplitRatios) string {
+	var u float64
+	if b, err := hex.DecodeString(sha256); err == nil && len(b) >= 8 {
+		u = float64(binary.BigEndian.Uint64(b[:8])) / math.Exp2(64)
+	}
+	switch {
+	case u < r.Train:
+		return SplitTrain
+	case u < r.Train+r.Val:
+		return SplitVal
+	default:
+		return SplitTest
+	}
+}
+
+// AssignSplits gives every labeled image without a split one via SplitFor.
+// Existing assignments are never changed, so the test set stays out of
+// training when ratios change or the dataset grows. It returns the number of
+// newly assigned images per split.
+func (s *Store) AssignSplits(ctx context.Context, r SplitRatios) (map[string]int, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	var assigned map[string]int
+	err := retryBusy(ctx, func() error {
+		assigned = map[string]int{SplitTrain: 0, SplitVal: 0, SplitTest: 0}
+		tx, err := s.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("begin: %w", err)
+		}
+		defer tx.Rollback()
+
+		rows, err := tx.QueryContext(ctx, `
+SELECT i.id, i.sha256
+FROM images i
+JOIN labels l ON l.image_id = i.id
+WHERE i.split IS NULL AND i.archived_at IS NULL`)
+		if err != nil {
+			return fmt.Errorf("list unassigned images: %w", err)
+		}
+		bySplit := map[string][]string{}
+		for rows.Next() {
+			var id, sha string
+			if err := rows.Scan(&id, &sha); err != nil {
+				rows.Close()
+				return fmt.Errorf("scan: %w", err)
+			}
+			sp := SplitFor(sha, r)
+			bySplit[sp] = append(bySplit[sp], id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		stmt, err := tx.PrepareContext(ctx, `UPDATE images SET split = ? WHERE id = ? AND split IS NULL`)
+		if err != nil {
+			return fmt.Errorf("prepare: %w", err)
+		}
+		defer stmt.Close()
+		for sp, ids := range bySplit {
+			for _, id := range ids {
+				if _, err := stmt.ExecContext(ctx, sp, id); err != nil {
+					return fmt.Errorf("assign split: %w", err)
+				}
+			}
+			assigned[sp] = len(ids)
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return assigned, nil
+}
+
+// countSplits returns labeled images matching cond (alias i) per split and
+// class; unassigned images are keyed SplitNone.
+func (s *Store) countSplits(ctx context.Context, cond string) (map[string]map[string]int, error) {
+	rows, err := s.read.QueryContext(ctx, `
+SELECT COALESCE(i.split, ?), l.skystate, COUNT(*)
+FROM images i
+JOIN labels l ON l.image_id = i.id
+WHERE `+cond+`
+GROUP BY 1, 2`, SplitNone)
+	if err != nil {
+		return nil, fmt.Errorf("count by split: %w", err)
+	}
+	defer rows.Close()
+
+	out := map[string]map[string]int{}
+	for _, sp := range append(Splits, SplitNone) {
+		out[sp] = map[string]int{}
+	}
+	for rows.Next() {
+		var (
+			sp, class string
+			n         int
+		)
+		if err := rows.Scan(&sp, &class, &n); err != nil {
+			return nil, fmt.Errorf("scan split count: %w", err)
+		}
+		if out[sp] == nil {
+			out[sp] = map[string]int{}
+		}
+		out[sp][class] = n
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// stmts caches prepared statements for the hot paths so their SQL is compiled
+// once per connection instead of on every call.
+type stmts struct {
+	// writer
+	upsertImage        *sql.Stmt
+	setLabel           *sql.Stmt
+	insertLabelHistory *sql.Stmt
+
+	// read pool
+	getLabel         *sql.Stmt
+	getLatest        *sql.Stmt
+	latestPrediction *sql.Stmt
+}
+
+func (s *Store) prepare() error {
+	for _, p := range []struct {
+		db    *sql.DB
+		query string
+		dst   **sql.Stmt
+	}{
+		{s.DB, upsertImageSQL, &s.stmts.upsertImage},
+		{s.DB, setLabelSQL, &s.stmts.setLabel},
+		{s.DB, insertLabelHistorySQL, &s.stmts.insertLabelHistory},
+		{s.read, getLabelSQL, &s.stmts.getLabel},
+		{s.read, getLatestSQL, &s.stmts.getLatest},
+		{s.read, latestPredictionSQL, &s.stmts.latestPrediction},
+	} {
+		stmt, err := p.db.Prepare(p.query)
+		if err != nil {
+			return fmt.Errorf("prepare: %w", err)
+		}
+		*p.dst = stmt
+	}
+	return nil
+}
+
+func (st *stmts) close() {
+	for _, stmt := range []*sql.Stmt{st.upsertImage, st.setLabel, st.insertLabelHistory, st.getLabel, st.getLatest, st.latestPrediction} {
+		if stmt != nil {
+			_ = stmt.Close()
+		}
+	}
+}
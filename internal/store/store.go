@@ -1,46 +1,87 @@
 package store
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	_ "modernc.org/sqlite"
 )
 
+// Store wraps the SQLite database. Writes go through a single connection so
+// writers queue in Go instead of failing with SQLITE_BUSY; queries use a
+// separate read-only pool that WAL lets run alongside the writer.
 type Store struct {
-	DB *sql.DB
+	DB   *sql.DB // writer: one connection, used for all writes and transactions
+	read *sql.DB // read-only pool
+	path string  // database file, for backups and staged restores
+
+	stmts stmts       // prepared statements for the hot paths
+	days  DayGrouping // how images are grouped into days
+
+	labelObserver func(imageIDs []string) // see SetLabelObserver
 }
 
+const (
+	busyTimeoutMS = 5000
+	maxReadConns  = 8
+)
+
 func Open(dbPath string) (*Store, error) {
 	// ensure folder exists
 	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
 		return nil, err
 	}
 
-	db, err := sql.Open("sqlite", dbPath)
+	if err := applyStagedRestore(dbPath); err != nil {
+		return nil, err
+	}
+
+	// Pragmas in the DSN apply to every pooled connection, not just the first.
+	pragmas := fmt.Sprintf("_pragma=busy_timeout(%d)&_pragma=foreign_keys(1)", busyTimeoutMS)
+
+	db, err := sql.Open("sqlite", "file:"+dbPath+"?"+pragmas+"&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_txlock=immediate")
 	if err != nil {
 		return nil, err
 	}
+	db.SetMaxOpenConns(1)
 
-	// basic pragmas
-	if _, err := db.Exec(`PRAGMA journal_mode=WAL; PRAGMA synchronous=NORMAL; PRAGMA foreign_keys=ON;`); err != nil {
+	s := &Store{DB: db, path: dbPath}
+	if err := s.Migrate(); err != nil {
 		_ = db.Close()
 		return nil, err
 	}
 
-	s := &Store{DB: db}
-	if err := s.Migrate(); err != nil {
+	// Opened after Migrate so the file and schema exist.
+	read, err := sql.Open("sqlite", "file:"+dbPath+"?mode=ro&"+pragmas)
+	if err != nil {
 		_ = db.Close()
 		return nil, err
 	}
+	read.SetMaxOpenConns(maxReadConns)
+	s.read = read
+
+	if err := s.prepare(); err != nil {
+		_ = s.Close()
+		return nil, err
+	}
 	return s, nil
 }
 
-func (s *Store) Close() error { return s.DB.Close() }
+func (s *Store) Close() error {
+	s.stmts.close()
+	rerr := s.read.Close()
+	if err := s.DB.Close(); err != nil {
+		return err
+	}
+	return rerr
+}
 
 func (s *Store) Migrate() error {
 	schema := `
@@ -59,7 +100,184 @@ CREATE TABLE IF NOT EXISTS labels (
   FOREIGN KEY(image_id) REFERENCES images(id) ON DELETE CASCADE
 );
 
+CREATE TABLE IF NOT EXISTS label_history (
+  id          INTEGER PRIMARY KEY AUTOINCREMENT,
+  image_id    TEXT NOT NULL,
+  skystate    TEXT NOT NULL,
+  meteor      INTEGER NOT NULL,
+  labeled_at  TEXT NOT NULL,
+  source      TEXT NOT NULL,      -- manual|sync:<peer>|...
+  recorded_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS image_meta (
+  image_id    TEXT NOT NULL,
+  key         TEXT NOT NULL,
+  value       TEXT NOT NULL,
+  PRIMARY KEY(image_id, key),
+  FOREIGN KEY(image_id) REFERENCES images(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS predictions (
+  id            INTEGER PRIMARY KEY AUTOINCREMENT,
+  image_id      TEXT NOT NULL,
+  model_version TEXT NOT NULL,
+  skystate      TEXT NOT NULL,
+  confidence    REAL NOT NULL,
+  probs         TEXT NOT NULL,    -- JSON object class -> probability
+  preprocess_ms REAL NOT NULL,
+  inference_ms  REAL NOT NULL,
+  predicted_at  TEXT NOT NULL,
+  FOREIGN KEY(image_id) REFERENCES images(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS annotations (
+  id          INTEGER PRIMARY KEY AUTOINCREMENT,
+  image_id    TEXT NOT NULL,
+  kind        TEXT NOT NULL,      -- meteor|satellite|plane|...
+  x           INTEGER NOT NULL,   -- bounding box in image pixels
+  y           INTEGER NOT NULL,
+  w           INTEGER NOT NULL,
+  h           INTEGER NOT NULL,
+  created_at  TEXT NOT NULL,
+  labeler     TEXT NOT NULL DEFAULT '',
+  FOREIGN KEY(image_id) REFERENCES images(id) ON DELETE CASCADE
+);
+
+-- Model suggestions for unlabeled images (never real labels)
+CREATE TABLE IF NOT EXISTS suggested_labels (
+  image_id       TEXT PRIMARY KEY,
+  skystate       TEXT NOT NULL,
+  confidence     REAL NOT NULL,
+  model_version  TEXT NOT NULL,
+  suggested_at   TEXT NOT NULL,
+  FOREIGN KEY(image_id) REFERENCES images(id) ON DELETE CASCADE
+);
+
+-- Runtime settings changed through the API (JSON values)
+CREATE TABLE IF NOT EXISTS settings (
+  key         TEXT PRIMARY KEY,
+  value       TEXT NOT NULL,
+  updated_at  TEXT NOT NULL
+);
+
+-- Short-lived claims so concurrent labelers are served different images
+CREATE TABLE IF NOT EXISTS claims (
+  image_id    TEXT PRIMARY KEY,
+  claimant    TEXT NOT NULL,
+  expires_at  TEXT NOT NULL,
+  FOREIGN KEY(image_id) REFERENCES images(id) ON DELETE CASCADE
+);
+
+-- Destructive/admin operations: who ran what, and how it ended
+CREATE TABLE IF NOT EXISTS audit_log (
+  id          INTEGER PRIMARY KEY AUTOINCREMENT,
+  action      TEXT NOT NULL,      -- labels.reset|backup|retention|...
+  params      TEXT NOT NULL,      -- JSON object of request parameters
+  remote_addr TEXT NOT NULL,
+  identity    TEXT NOT NULL,      -- admin|anonymous
+  status      INTEGER NOT NULL,   -- HTTP status of the response
+  outcome     TEXT NOT NULL,      -- ok|error|denied
+  error       TEXT NOT NULL DEFAULT '',
+  created_at  TEXT NOT NULL
+);
+
+-- Generated reports cached per kind and date (JSON)
+CREATE TABLE IF NOT EXISTS reports (
+  kind         TEXT NOT NULL,     -- night
+  date         TEXT NOT NULL,     -- YYYY-MM-DD
+  body         TEXT NOT NULL,
+  generated_at TEXT NOT NULL,
+  PRIMARY KEY(kind, date)
+);
+
+-- Model evaluation results cached per version and split contents (JSON)
+CREATE TABLE IF NOT EXISTS model_evals (
+  version      TEXT NOT NULL,
+  split_hash   TEXT NOT NULL,     -- SplitHash of the evaluated images and labels
+  body         TEXT NOT NULL,
+  evaluated_at TEXT NOT NULL,
+  PRIMARY KEY(version, split_hash)
+);
+
+-- Per-image outcomes of a model evaluation, for browsing confusion cells
+CREATE TABLE IF NOT EXISTS eval_results (
+  eval_id    TEXT NOT NULL,       -- EvalID(version, split hash)
+  image_id   TEXT NOT NULL,
+  truth      TEXT NOT NULL,       -- label at evaluation time
+  predicted  TEXT NOT NULL,
+  confidence REAL NOT NULL,
+  PRIMARY KEY(eval_id, image_id)
+);
+
+-- Named, frozen copies of the labels (see snapshots.go)
+CREATE TABLE IF NOT EXISTS dataset_snapshots (
+  name       TEXT PRIMARY KEY,
+  created_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS dataset_snapshot_labels (
+  snapshot     TEXT NOT NULL,
+  sha256       TEXT NOT NULL,     -- image content; survives re-ingestion under another ID
+  skystate     TEXT NOT NULL,
+  meteor       INTEGER NOT NULL,
+  needs_review INTEGER NOT NULL,
+  labeled_at   TEXT NOT NULL,
+  PRIMARY KEY(snapshot, sha256),
+  FOREIGN KEY(snapshot) REFERENCES dataset_snapshots(name) ON DELETE CASCADE
+);
+
+-- Completed training runs and the curves from their metrics.json (JSON)
+CREATE TABLE IF NOT EXISTS train_runs (
+  id           TEXT PRIMARY KEY,  -- trainer run ID (its start time)
+  versions     TEXT NOT NULL,     -- comma-separated model versions produced
+  run          TEXT NOT NULL,     -- settings and data of the run
+  summary      TEXT,              -- final validation metrics
+  metrics      TEXT,              -- per-epoch curves
+  warning      TEXT,              -- why there are no metrics
+  started_at   TEXT NOT NULL,
+  finished_at  TEXT NOT NULL
+);
+
+-- Current conditions at the site from the weather source
+CREATE TABLE IF NOT EXISTS weather (
+  time          TEXT PRIMARY KEY,  -- observation time (RFC3339, UTC)
+  cloud_cover   REAL NOT NULL,     -- percent
+  temperature   REAL NOT NULL,     -- °C
+  humidity      REAL NOT NULL,     -- percent
+  precipitation REAL NOT NULL      -- mm
+);
+
+-- Why ingestion did not predict an image (see skips.go)
+CREATE TABLE IF NOT EXISTS prediction_skips (
+  id        INTEGER PRIMARY KEY AUTOINCREMENT,
+  image_id  TEXT NOT NULL,
+  reason    TEXT NOT NULL,        -- queue_dropped|thermal|no_model|truncated|...
+  detail    TEXT NOT NULL,
+  at        TEXT NOT NULL,
+  FOREIGN KEY(image_id) REFERENCES images(id) ON DELETE CASCADE
+);
+
+-- Alerting rules (see package rules; the definition is JSON)
+CREATE TABLE IF NOT EXISTS rules (
+  id            INTEGER PRIMARY KEY AUTOINCREMENT,
+  spec          TEXT NOT NULL,
+  created_at    TEXT NOT NULL,
+  updated_at    TEXT NOT NULL,
+  last_fired_at TEXT
+);
+
 CREATE INDEX IF NOT EXISTS idx_images_fetched_at ON images(fetched_at);
+CREATE INDEX IF NOT EXISTS idx_labels_labeled_at ON labels(labeled_at);
+CREATE INDEX IF NOT EXISTS idx_label_history_image ON label_history(image_id);
+CREATE INDEX IF NOT EXISTS idx_predictions_image ON predictions(image_id);
+CREATE INDEX IF NOT EXISTS idx_predictions_model ON predictions(model_version);
+CREATE INDEX IF NOT EXISTS idx_annotations_image ON annotations(image_id);
+CREATE INDEX IF NOT EXISTS idx_claims_claimant ON claims(claimant);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
+CREATE INDEX IF NOT EXISTS idx_eval_results_cell ON eval_results(eval_id, predicted, truth, confidence);
+CREATE INDEX IF NOT EXISTS idx_prediction_skips_image ON prediction_skips(image_id);
+CREATE INDEX IF NOT EXISTS idx_prediction_skips_at ON prediction_skips(at);
 `
 	_, err := s.DB.Exec(schema)
 	if err != nil {
@@ -70,6 +288,105 @@ CREATE INDEX IF NOT EXISTS idx_images_fetched_at ON images(fetched_at);
 	if err := ensureColumn(s.DB, "images", "size_bytes", "INTEGER NOT NULL DEFAULT 0"); err != nil {
 		return err
 	}
+	if err := ensureColumn(s.DB, "labels", "labeler", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := ensureColumn(s.DB, "label_history", "labeler", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := ensureColumn(s.DB, "images", "width", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(s.DB, "images", "height", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(s.DB, "images", "phash", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if _, err := s.DB.Exec(`CREATE INDEX IF NOT EXISTS idx_images_phash ON images(phash)`); err != nil {
+		return fmt.Errorf("create phash index: %w", err)
+	}
+	if err := ensureColumn(s.DB, "images", "provenance", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(s.DB, "images", "quality", "TEXT NOT NULL DEFAULT 'ok'"); err != nil {
+		return err
+	}
+	if err := ensureColumn(s.DB, "images", "split", "TEXT"); err != nil {
+		return err
+	}
+	if _, err := s.DB.Exec(`CREATE INDEX IF NOT EXISTS idx_images_split ON images(split)`); err != nil {
+		return fmt.Errorf("create split index: %w", err)
+	}
+	if err := ensureColumn(s.DB, "images", "format", "TEXT NOT NULL DEFAULT 'jpeg'"); err != nil {
+		return err
+	}
+	if err := ensureColumn(s.DB, "labels", "needs_review", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(s.DB, "label_history", "needs_review", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(s.DB, "images", "archived_at", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(s.DB, "images", "notes", "TEXT"); err != nil {
+		return err
+	}
+	// Partial: searching notes only scans the images that have some
+	if _, err := s.DB.Exec(`CREATE INDEX IF NOT EXISTS idx_images_notes ON images(notes) WHERE notes IS NOT NULL`); err != nil {
+		return fmt.Errorf("create notes index: %w", err)
+	}
+	if err := ensureColumn(s.DB, "images", "captured_at", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(s.DB, "images", "stale", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	// What the model suggested while a label was made (NULL: nothing shown)
+	if err := ensureColumn(s.DB, "label_history", "shown_skystate", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(s.DB, "label_history", "shown_confidence", "REAL"); err != nil {
+		return err
+	}
+	if err := ensureColumn(s.DB, "label_history", "shown_model", "TEXT"); err != nil {
+		return err
+	}
+	// Set when an auto-prediction was dropped under load; the catch-up sweep
+	// reads them through the partial index
+	if err := ensureColumn(s.DB, "images", "prediction_pending", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if _, err := s.DB.Exec(`CREATE INDEX IF NOT EXISTS idx_images_prediction_pending ON images(fetched_at) WHERE prediction_pending = 1`); err != nil {
+		return fmt.Errorf("create prediction pending index: %w", err)
+	}
+	// Let the day list and the dataset overview group without sorting every
+	// image; the day index also serves DATE(fetched_at) = ? filters
+	if _, err := s.DB.Exec(`CREATE INDEX IF NOT EXISTS idx_images_day ON images(DATE(fetched_at), archived_at, size_bytes)`); err != nil {
+		return fmt.Errorf("create day index: %w", err)
+	}
+	if _, err := s.DB.Exec(`CREATE INDEX IF NOT EXISTS idx_images_resolution ON images(width, height, archived_at)`); err != nil {
+		return fmt.Errorf("create resolution index: %w", err)
+	}
+	// Camera clock minus fetch time in seconds, before any correction
+	if err := ensureColumn(s.DB, "images", "capture_skew", "REAL"); err != nil {
+		return err
+	}
+	// SHA-256 of the camera's original when the stored frame was re-encoded
+	if err := ensureColumn(s.DB, "images", "original_sha256", "TEXT"); err != nil {
+		return err
+	}
+	if _, err := s.DB.Exec(`CREATE INDEX IF NOT EXISTS idx_images_original_sha256 ON images(original_sha256) WHERE original_sha256 IS NOT NULL`); err != nil {
+		return fmt.Errorf("create original hash index: %w", err)
+	}
+	// Auto-label threshold a suggestion passed (NULL before it was recorded)
+	if err := ensureColumn(s.DB, "suggested_labels", "threshold", "REAL"); err != nil {
+		return err
+	}
+	if err := dedupPredictions(s.DB); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -97,53 +414,234 @@ type Image struct {
 	SHA256    string
 	FetchedAt time.Time
 	SizeBytes int64
+	Width     int    // 0 if unknown
+	Height    int    // 0 if unknown
+	Format    string // FormatJPEG etc.; only set by GetImage
+
+	ArchivedAt *time.Time // nil unless archived; only set by GetImage and ListImagePathsByDay
+}
+
+// SkyStates lists the valid skystate classes.
+var SkyStates = []string{"clear", "light_clouds", "heavy_clouds", "precipitation", "unknown"}
+
+// ValidSkystate reports whether s is one of SkyStates.
+func ValidSkystate(s string) bool {
+	return slices.Contains(SkyStates, s)
 }
 
 type DatasetStats struct {
-	Total          int            `json:"total"`
-	Labeled        int            `json:"labeled"`
-	Unlabeled      int            `json:"unlabeled"`
-	ByClass        map[string]int `json:"by_class"`
-	TotalSizeBytes int64          `json:"total_size_bytes"`
-}
-
-func (s *Store) UpsertImage(id, path, sha256 string, fetchedAt time.Time, sizeBytes int64) error {
-	_, err := s.DB.Exec(
-		`INSERT INTO images(id, path, sha256, fetched_at, size_bytes)
-		 VALUES(?, ?, ?, ?, ?)
-		 ON CONFLICT(sha256) DO UPDATE SET path=excluded.path, fetched_at=excluded.fetched_at, size_bytes=excluded.size_bytes`,
-		id, path, sha256, fetchedAt.UTC().Format(time.RFC3339), sizeBytes,
-	)
-	return err
+	Total          int                       `json:"total"`
+	Labeled        int                       `json:"labeled"`
+	Unlabeled      int                       `json:"unlabeled"`
+	ByClass        map[string]int            `json:"by_class"`
+	ByResolution   map[string]int            `json:"by_resolution"` // "WxH" -> count; "unknown" if not recorded
+	BySplit        map[string]map[string]int `json:"by_split"`      // split -> class -> labeled images
+	NeedsReview    int                       `json:"needs_review"`  // labels awaiting a second look
+	Archived       int                       `json:"archived"`      // soft-deleted images, counted in the rest only on request
+	TotalSizeBytes int64                     `json:"total_size_bytes"`
 }
 
+const upsertImageSQL = `INSERT INTO images(id, path, sha256, fetched_at, size_bytes)
+ VALUES(?, ?, ?, ?, ?)
+ ON CONFLICT(sha256) DO UPDATE SET path=excluded.path, fetched_at=excluded.fetched_at, size_bytes=excluded.size_bytes`
+
+func (s *Store) UpsertImage(ctx context.Context, id, path, sha256 string, fetchedAt time.Time, sizeBytes int64) error {
+	return retryBusy(ctx, func() error {
+		_, err := s.stmts.upsertImage.ExecContext(ctx, id, path, sha256, fetchedAt.UTC().Format(time.RFC3339), sizeBytes)
+		return err
+	})
+}
+
+// UpsertImages inserts or updates many images in one transaction, which is far
+// faster than individual upserts for bulk imports and reconciliation.
+func (s *Store) UpsertImages(ctx context.Context, batch []Image) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	return retryBusy(ctx, func() error {
+		tx, err := s.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("begin: %w", err)
+		}
+		defer tx.Rollback()
+
+		stmt := tx.StmtContext(ctx, s.stmts.upsertImage)
+		for _, img := range batch {
+			if _, err := stmt.ExecContext(ctx, img.ID, img.Path, img.SHA256, img.FetchedAt.UTC().Format(time.RFC3339), img.SizeBytes); err != nil {
+				return fmt.Errorf("upsert image %s: %w", img.ID, err)
+			}
+		}
+		return tx.Commit()
+	})
+}
+
+// LabelWrite describes one label assignment.
+type LabelWrite struct {
+	ImageID   string
+	Skystate  string
+	Meteor    bool
+	LabeledAt time.Time
+	Source    string // recorded in label_history; defaults to LabelSourceManual
+	Labeler   string // optional name of the person labeling
+
+	NeedsReview bool // labeler was unsure; listed in the review queue until confirmed
+
+	Shown *ShownPrediction // model suggestion visible while labeling, if any
+}
+
+// SetLabel stores a manual label and records it in label_history.
+//
+// Deprecated: use WriteLabel with a context.
 func (s *Store) SetLabel(imageID, skystate string, meteor bool, labeledAt time.Time) error {
+	return s.WriteLabel(context.Background(), LabelWrite{ImageID: imageID, Skystate: skystate, Meteor: meteor, LabeledAt: labeledAt})
+}
+
+// SetLabelWithSource stores a label and records where it came from in label_history.
+//
+// Deprecated: use WriteLabel with a context.
+func (s *Store) SetLabelWithSource(imageID, skystate string, meteor bool, labeledAt time.Time, source string) error {
+	return s.WriteLabel(context.Background(), LabelWrite{ImageID: imageID, Skystate: skystate, Meteor: meteor, LabeledAt: labeledAt, Source: source})
+}
+
+// WriteLabel stores a label and appends it to label_history in one transaction.
+func (s *Store) WriteLabel(ctx context.Context, l LabelWrite) error {
+	err := retryBusy(ctx, func() error {
+		tx, err := s.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("begin: %w", err)
+		}
+		defer tx.Rollback()
+
+		if err := s.setLabelTx(ctx, tx, l); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	if err == nil {
+		s.labelsChanged(l.ImageID)
+	}
+	return err
+}
+
+const (
+	setLabelSQL = `INSERT INTO labels(image_id, skystate, meteor, labeled_at, labeler, needs_review)
+ VALUES(?, ?, ?, ?, ?, ?)
+ ON CONFLICT(image_id) DO UPDATE SET skystate=excluded.skystate, meteor=excluded.meteor, labeled_at=excluded.labeled_at, labeler=excluded.labeler, needs_review=excluded.needs_review`
+	insertLabelHistorySQL = `INSERT INTO label_history(image_id, skystate, meteor, labeled_at, source, labeler, recorded_at, needs_review,
+ shown_skystate, shown_confidence, shown_model)
+ VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+)
+
+func (s *Store) setLabelTx(ctx context.Context, tx *sql.Tx, l LabelWrite) error {
 	m := 0
-	if meteor {
+	if l.Meteor {
 		m = 1
 	}
-	_, err := s.DB.Exec(
-		`INSERT INTO labels(image_id, skystate, meteor, labeled_at)
-		 VALUES(?, ?, ?, ?)
-		 ON CONFLICT(image_id) DO UPDATE SET skystate=excluded.skystate, meteor=excluded.meteor, labeled_at=excluded.labeled_at`,
-		imageID, skystate, m, labeledAt.UTC().Format(time.RFC3339),
-	)
-	return err
+	if l.Source == "" {
+		l.Source = LabelSourceManual
+	}
+	review := 0
+	if l.NeedsReview {
+		review = 1
+	}
+	var shownState, shownConf, shownModel any
+	if l.Shown != nil {
+		shownState, shownConf, shownModel = l.Shown.Skystate, l.Shown.Confidence, nullIfEmpty(l.Shown.ModelVersion)
+	}
+	ts := l.LabeledAt.UTC().Format(time.RFC3339)
+	if _, err := tx.StmtContext(ctx, s.stmts.setLabel).ExecContext(ctx, l.ImageID, l.Skystate, m, ts, l.Labeler, review); err != nil {
+		return fmt.Errorf("set label: %w", err)
+	}
+	if _, err := tx.StmtContext(ctx, s.stmts.insertLabelHistory).ExecContext(ctx,
+		l.ImageID, l.Skystate, m, ts, l.Source, l.Labeler, time.Now().UTC().Format(time.RFC3339), review,
+		shownState, shownConf, shownModel,
+	); err != nil {
+		return fmt.Errorf("record label history: %w", err)
+	}
+	// A labeled image no longer needs to be held for anyone
+	if _, err := tx.ExecContext(ctx, `DELETE FROM claims WHERE image_id = ?`, l.ImageID); err != nil {
+		return fmt.Errorf("release claim: %w", err)
+	}
+	// Real labels supersede model suggestions
+	if _, err := tx.ExecContext(ctx, `DELETE FROM suggested_labels WHERE image_id = ?`, l.ImageID); err != nil {
+		return fmt.Errorf("drop suggestion: %w", err)
+	}
+	return nil
 }
 
 // ClearLabels deletes all labels; images remain untouched.
+//
+// Deprecated: use ClearLabelsWhere with a context.
 func (s *Store) ClearLabels() error {
-	_, err := s.DB.Exec(`DELETE FROM labels`)
+	_, err := s.ClearLabelsWhere(context.Background(), LabelFilter{})
+	return err
+}
+
+// LabelFilter scopes ClearLabelsWhere. Zero values mean "any".
+type LabelFilter struct {
+	Date      string // YYYY-MM-DD the label was set (see DayGrouping)
+	ImageDate string // YYYY-MM-DD the image was fetched
+	Skystate  string
+	Labeler   string
+}
+
+// ClearLabelsWhere deletes the labels matching f and returns how many were removed.
+// An empty filter deletes all labels; images remain untouched.
+func (s *Store) ClearLabelsWhere(ctx context.Context, f LabelFilter) (int64, error) {
+	var (
+		where []string
+		args  []any
+	)
+	if f.Date != "" {
+		cond, dayArgs := s.days.dayCond("labeled_at", f.Date)
+		where = append(where, cond)
+		args = append(args, dayArgs...)
+	}
+	if f.ImageDate != "" {
+		cond, dayArgs := s.days.dayCond("fetched_at", f.ImageDate)
+		where = append(where, "image_id IN (SELECT id FROM images WHERE "+cond+")")
+		args = append(args, dayArgs...)
+	}
+	if f.Skystate != "" {
+		where = append(where, "skystate = ?")
+		args = append(args, f.Skystate)
+	}
+	if f.Labeler != "" {
+		where = append(where, "labeler = ?")
+		args = append(args, f.Labeler)
+	}
+
+	q := `DELETE FROM labels`
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	}
+	rows, err := s.DB.QueryContext(ctx, q+" RETURNING image_id", args...)
 	if err != nil {
-		return fmt.Errorf("clear labels: %w", err)
+		return 0, fmt.Errorf("clear labels: %w", err)
 	}
-	return nil
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("clear labels: %w", err)
+	}
+	s.labelsChanged(ids...)
+	return int64(len(ids)), nil
 }
 
-func (s *Store) GetLabel(imageID string) (skystate string, meteor bool, ok bool, err error) {
+const getLabelSQL = `SELECT skystate, meteor FROM labels WHERE image_id = ?`
+
+func (s *Store) GetLabel(ctx context.Context, imageID string) (skystate string, meteor bool, ok bool, err error) {
 	var m int
 	var w string
-	row := s.DB.QueryRow(`SELECT skystate, meteor FROM labels WHERE image_id = ?`, imageID)
+	row := s.stmts.getLabel.QueryRowContext(ctx, imageID)
 	switch e := row.Scan(&w, &m); {
 	case e == sql.ErrNoRows:
 		return "", false, false, nil
@@ -154,34 +652,53 @@ func (s *Store) GetLabel(imageID string) (skystate string, meteor bool, ok bool,
 	}
 }
 
-func (s *Store) CountLabeled() (int, error) {
+func (s *Store) CountLabeled(ctx context.Context) (int, error) {
 	var n int
-	if err := s.DB.QueryRow(`SELECT COUNT(*) FROM labels`).Scan(&n); err != nil {
+	if err := s.read.QueryRowContext(ctx, `SELECT COUNT(*) FROM labels l JOIN images i ON i.id = l.image_id WHERE `+activeImage).Scan(&n); err != nil {
 		return 0, fmt.Errorf("count labels: %w", err)
 	}
 	return n, nil
 }
 
-// CountStats returns basic dataset counters.
-func (s *Store) CountStats() (DatasetStats, error) {
-	var stats DatasetStats
+// CountStats returns basic dataset counters, leaving out archived images.
+func (s *Store) CountStats(ctx context.Context) (DatasetStats, error) {
+	return s.countStats(ctx, activeImage)
+}
+
+// CountStatsWithArchived is CountStats counting archived images as well.
+func (s *Store) CountStatsWithArchived(ctx context.Context) (DatasetStats, error) {
+	return s.countStats(ctx, "1 = 1")
+}
 
-	stats.ByClass = map[string]int{
-		"clear":         0,
-		"light_clouds":  0,
-		"heavy_clouds":  0,
-		"precipitation": 0,
-		"unknown":       0,
+// newDatasetStats returns zeroed counters with every class present.
+func newDatasetStats() DatasetStats {
+	return DatasetStats{
+		ByClass: map[string]int{
+			"clear":         0,
+			"light_clouds":  0,
+			"heavy_clouds":  0,
+			"precipitation": 0,
+			"unknown":       0,
+		},
+		ByResolution: map[string]int{},
 	}
+}
 
-	if err := s.DB.QueryRow(`SELECT COUNT(*) FROM images`).Scan(&stats.Total); err != nil {
+// countStats counts the images matching cond (alias i).
+func (s *Store) countStats(ctx context.Context, cond string) (DatasetStats, error) {
+	stats := newDatasetStats()
+
+	if err := s.read.QueryRowContext(ctx, `SELECT COUNT(*) FROM images i WHERE `+cond).Scan(&stats.Total); err != nil {
 		return stats, fmt.Errorf("count images: %w", err)
 	}
-	if err := s.DB.QueryRow(`SELECT COUNT(*) FROM labels`).Scan(&stats.Labeled); err != nil {
+	if err := s.read.QueryRowContext(ctx, `SELECT COUNT(*) FROM images i WHERE i.archived_at IS NOT NULL`).Scan(&stats.Archived); err != nil {
+		return stats, fmt.Errorf("count archived images: %w", err)
+	}
+	if err := s.read.QueryRowContext(ctx, `SELECT COUNT(*) FROM labels l JOIN images i ON i.id = l.image_id WHERE `+cond).Scan(&stats.Labeled); err != nil {
 		return stats, fmt.Errorf("count labels: %w", err)
 	}
 
-	rows, err := s.DB.Query(`SELECT skystate, COUNT(*) FROM labels GROUP BY skystate`)
+	rows, err := s.read.QueryContext(ctx, `SELECT l.skystate, COUNT(*) FROM labels l JOIN images i ON i.id = l.image_id WHERE `+cond+` GROUP BY l.skystate`)
 	if err != nil {
 		return stats, fmt.Errorf("count by class: %w", err)
 	}
@@ -198,18 +715,42 @@ func (s *Store) CountStats() (DatasetStats, error) {
 		return stats, fmt.Errorf("rows: %w", err)
 	}
 
-	if err := s.DB.QueryRow(`
+	if err := s.read.QueryRowContext(ctx, `
 SELECT COUNT(*)
 FROM images i
 LEFT JOIN labels l ON l.image_id = i.id
-WHERE l.image_id IS NULL`).Scan(&stats.Unlabeled); err != nil {
+WHERE l.image_id IS NULL AND `+cond).Scan(&stats.Unlabeled); err != nil {
 		return stats, fmt.Errorf("count unlabeled: %w", err)
 	}
 
-	if err := s.DB.QueryRow(`SELECT COALESCE(SUM(size_bytes), 0) FROM images`).Scan(&stats.TotalSizeBytes); err != nil {
+	if err := s.read.QueryRowContext(ctx, `SELECT COALESCE(SUM(i.size_bytes), 0) FROM images i WHERE `+cond).Scan(&stats.TotalSizeBytes); err != nil {
 		return stats, fmt.Errorf("sum sizes: %w", err)
 	}
 
+	resRows, err := s.read.QueryContext(ctx, `SELECT i.width, i.height, COUNT(*) FROM images i WHERE `+cond+` GROUP BY i.width, i.height`)
+	if err != nil {
+		return stats, fmt.Errorf("count by resolution: %w", err)
+	}
+	defer resRows.Close()
+	for resRows.Next() {
+		var w, h, n int
+		if err := resRows.Scan(&w, &h, &n); err != nil {
+			return stats, fmt.Errorf("scan resolution count: %w", err)
+		}
+		stats.ByResolution[FormatResolution(w, h)] += n
+	}
+	if err := resRows.Err(); err != nil {
+		return stats, fmt.Errorf("rows: %w", err)
+	}
+
+	if stats.BySplit, err = s.countSplits(ctx, cond); err != nil {
+		return stats, err
+	}
+
+	if err := s.read.QueryRowContext(ctx, `SELECT COUNT(*) FROM labels l JOIN images i ON i.id = l.image_id WHERE l.needs_review = 1 AND `+cond).Scan(&stats.NeedsReview); err != nil {
+		return stats, fmt.Errorf("count labels awaiting review: %w", err)
+	}
+
 	return stats, nil
 }
 
@@ -219,39 +760,209 @@ type ImageWithLabel struct {
 	SHA256    string    `json:"sha256"`
 	FetchedAt time.Time `json:"fetched_at"`
 	SizeBytes int64     `json:"size_bytes"`
+	Width     int       `json:"width,omitempty"`
+	Height    int       `json:"height,omitempty"`
+	PHash     string    `json:"phash,omitempty"` // perceptual hash (16 hex digits), empty if not computed
+	Quality   string    `json:"quality"`         // QualityOK or QualityTruncated
+	Split     string    `json:"split,omitempty"` // SplitTrain/SplitVal/SplitTest, empty if unassigned
+	Format    string    `json:"format"`          // FormatJPEG, FormatPNG, ...
+
+	Skystate    *string    `json:"skystate,omitempty"`
+	Meteor      *bool      `json:"meteor,omitempty"`
+	LabeledAt   *time.Time `json:"labeled_at,omitempty"`
+	NeedsReview bool       `json:"needs_review,omitempty"` // label awaits confirmation
+
+	Suggestion *Suggestion `json:"suggestion,omitempty"` // model suggestion, only for unlabeled images
+
+	Meta       map[string]string `json:"meta,omitempty"`
+	Provenance *Provenance       `json:"provenance,omitempty"`
+	Weather    *WeatherReading   `json:"weather,omitempty"` // nearest reading within WeatherMaxGap
+	Skips      []PredictionSkip  `json:"skips,omitempty"`   // why it wasn't predicted, oldest first
+
+	ArchivedAt *time.Time `json:"archived_at,omitempty"` // set for archived images (listed only on request)
+	Notes      string     `json:"notes,omitempty"`
+}
+
+// ImageFilter selects images for ListImagesFiltered. Zero values mean "no filter".
+type ImageFilter struct {
+	Limit         int
+	UnlabeledOnly bool
+	LabeledOnly   bool
+	Day           string // YYYY-MM-DD (see DayGrouping)
+	Skystate      string // only images currently labeled with this class
+
+	// Sidecar exposure range (image_meta key "exposure"); images without it are excluded.
+	ExposureMin *float64
+	ExposureMax *float64
+
+	// Exact image resolution; both must be set to filter.
+	Width  int
+	Height int
+
+	HasAnnotations bool // only images with at least one annotation region
 
-	Skystate  *string    `json:"skystate,omitempty"`
-	Meteor    *bool      `json:"meteor,omitempty"`
-	LabeledAt *time.Time `json:"labeled_at,omitempty"`
+	NeedsReview       bool // only images whose label awaits review
+	ExcludeUnreviewed bool // skip images whose label awaits review
+
+	ExcludeTruncated     bool // skip images whose download was incomplete
+	ExcludeUnpredictable bool // skip formats the model can't read (FITS, HEIC, unknown)
+
+	Split string // SplitTrain/SplitVal/SplitTest, or SplitNone for unassigned images
+
+	IncludeArchived bool // list archived images too
+	ArchivedOnly    bool // list only archived images
+
+	// Snapshot takes the labels from this dataset snapshot instead of the
+	// live labels; the other filters apply to them alike.
+	Snapshot string
+
+	// Search matches images whose ID starts with it or whose notes contain it
+	// (case-insensitive for notes).
+	Search string
+
+	IncludeMeta       bool // populate ImageWithLabel.Meta
+	IncludeProvenance bool // populate ImageWithLabel.Provenance
+	IncludeWeather    bool // populate ImageWithLabel.Weather
+	IncludeSkips      bool // populate ImageWithLabel.Skips
 }
 
+// ListImages is the non-context form of ListImagesFiltered.
+//
+// Deprecated: use ListImagesFiltered with a context.
 func (s *Store) ListImages(limit int, unlabeledOnly bool, day string) ([]ImageWithLabel, error) {
-	useLimit := limit > 0
+	return s.ListImagesFiltered(context.Background(), ImageFilter{Limit: limit, UnlabeledOnly: unlabeledOnly, Day: day})
+}
+
+func (s *Store) ListImagesFiltered(ctx context.Context, f ImageFilter) ([]ImageWithLabel, error) {
+	return listImages(ctx, s.read, s.days, f)
+}
 
-	var q string
+// querier is a *sql.DB or *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func listImages(ctx context.Context, db querier, g DayGrouping, f ImageFilter) ([]ImageWithLabel, error) {
 	var args []any
 	var where []string
 
-	if day != "" {
-		where = append(where, "DATE(i.fetched_at) = ?")
-		args = append(args, day)
+	cols := `i.id, i.path, i.sha256, i.fetched_at, i.size_bytes, i.width, i.height, i.phash, i.quality, COALESCE(i.split, ''), i.format, i.archived_at, COALESCE(i.notes, ''),
+       l.skystate, l.meteor, l.labeled_at, COALESCE(l.needs_review, 0),
+       sg.skystate, sg.confidence, sg.model_version, sg.suggested_at, sg.threshold`
+	if f.IncludeMeta {
+		cols += `,
+       (SELECT json_group_object(m.key, m.value) FROM image_meta m WHERE m.image_id = i.id) AS meta`
+	} else {
+		cols += `,
+       NULL AS meta`
+	}
+	if f.IncludeProvenance {
+		cols += `, i.provenance`
+	} else {
+		cols += `, NULL AS provenance`
+	}
+	if f.IncludeWeather {
+		cols += `,
+       ` + weatherNearestSQL + ` AS weather`
+	} else {
+		cols += `, NULL AS weather`
+	}
+	if f.IncludeSkips {
+		cols += `,
+       ` + skipsSQL + ` AS skips`
+	} else {
+		cols += `, NULL AS skips`
 	}
 
-	if unlabeledOnly {
-		q = `
-SELECT i.id, i.path, i.sha256, i.fetched_at, i.size_bytes,
-       NULL as skystate, NULL as meteor, NULL as labeled_at
+	labels := `labels l ON l.image_id = i.id`
+	if f.Snapshot != "" {
+		// Shaped like labels; image_id only needs to be non-NULL
+		labels = `(
+  SELECT sha256, sha256 AS image_id, skystate, meteor, needs_review, labeled_at
+  FROM dataset_snapshot_labels WHERE snapshot = ?
+) l ON l.sha256 = i.sha256`
+		args = append(args, f.Snapshot)
+	}
+	q := `
+SELECT ` + cols + `
 FROM images i
-LEFT JOIN labels l ON l.image_id = i.id
+LEFT JOIN ` + labels + `
+LEFT JOIN suggested_labels sg ON sg.image_id = i.id AND l.image_id IS NULL
 `
+
+	if f.Day != "" {
+		cond, dayArgs := g.dayCond("i.fetched_at", f.Day)
+		where = append(where, cond)
+		args = append(args, dayArgs...)
+	}
+	switch {
+	case f.ArchivedOnly:
+		where = append(where, "i.archived_at IS NOT NULL")
+	case !f.IncludeArchived:
+		where = append(where, activeImage)
+	}
+
+	if f.UnlabeledOnly {
 		where = append(where, "l.image_id IS NULL")
-	} else {
-		q = `
-SELECT i.id, i.path, i.sha256, i.fetched_at, i.size_bytes,
-       l.skystate, l.meteor, l.labeled_at
-FROM images i
-LEFT JOIN labels l ON l.image_id = i.id
-`
+	}
+	if f.LabeledOnly {
+		where = append(where, "l.image_id IS NOT NULL")
+	}
+	if f.Skystate != "" {
+		where = append(where, "l.skystate = ?")
+		args = append(args, f.Skystate)
+	}
+
+	if f.ExposureMin != nil || f.ExposureMax != nil {
+		cond := "EXISTS (SELECT 1 FROM image_meta m WHERE m.image_id = i.id AND m.key = ?"
+		args = append(args, MetaKeyExposure)
+		if f.ExposureMin != nil {
+			cond += " AND CAST(m.value AS REAL) >= ?"
+			args = append(args, *f.ExposureMin)
+		}
+		if f.ExposureMax != nil {
+			cond += " AND CAST(m.value AS REAL) <= ?"
+			args = append(args, *f.ExposureMax)
+		}
+		where = append(where, cond+")")
+	}
+
+	if f.Width > 0 && f.Height > 0 {
+		where = append(where, "i.width = ? AND i.height = ?")
+		args = append(args, f.Width, f.Height)
+	}
+	if f.HasAnnotations {
+		where = append(where, "EXISTS (SELECT 1 FROM annotations a WHERE a.image_id = i.id)")
+	}
+	if f.NeedsReview {
+		where = append(where, "l.needs_review = 1")
+	}
+	if f.ExcludeUnreviewed {
+		where = append(where, "COALESCE(l.needs_review, 0) = 0")
+	}
+	if f.ExcludeTruncated {
+		where = append(where, "i.quality != ?")
+		args = append(args, QualityTruncated)
+	}
+	if f.ExcludeUnpredictable {
+		where = append(where, "i."+predictableSQL)
+	}
+	switch f.Split {
+	case "":
+	case SplitNone:
+		where = append(where, "i.split IS NULL")
+	default:
+		where = append(where, "i.split = ?")
+		args = append(args, f.Split)
+	}
+	if f.Search != "" {
+		// The ID prefix uses the primary key index (GLOB is case-sensitive
+		// like the index, LIKE isn't), notes the partial notes index
+		where = append(where, `i.id IN (
+  SELECT id FROM images WHERE id GLOB ?
+  UNION
+  SELECT id FROM images WHERE notes IS NOT NULL AND notes LIKE ? ESCAPE '\')`)
+		args = append(args, globPrefix(f.Search), "%"+escapeLike(f.Search)+"%")
 	}
 
 	if len(where) > 0 {
@@ -260,12 +971,12 @@ LEFT JOIN labels l ON l.image_id = i.id
 
 	q += "ORDER BY i.fetched_at DESC"
 
-	if useLimit {
+	if f.Limit > 0 {
 		q += "\nLIMIT ?"
-		args = append(args, limit)
+		args = append(args, f.Limit)
 	}
 
-	rows, err := s.DB.Query(q, args...)
+	rows, err := db.QueryContext(ctx, q, args...)
 	if err != nil {
 		return nil, fmt.Errorf("list images: %w", err)
 	}
@@ -274,14 +985,24 @@ LEFT JOIN labels l ON l.image_id = i.id
 	var out []ImageWithLabel
 	for rows.Next() {
 		var (
-			id, path, sha256, fetchedAtStr string
-			sizeBytes                      int64
-			skystateNS                     sql.NullString
-			meteorNI                       sql.NullInt64
-			labeledAtNS                    sql.NullString
+			id, path, sha256, fetchedAtStr  string
+			sizeBytes                       int64
+			width, height                   int
+			phash, quality, split, format   string
+			archivedAtNS                    sql.NullString
+			notes                           string
+			skystateNS                      sql.NullString
+			meteorNI                        sql.NullInt64
+			labeledAtNS                     sql.NullString
+			needsReview                     int
+			sugStateNS, sugModelNS, sugAtNS sql.NullString
+			sugConfNF, sugThresholdNF       sql.NullFloat64
+			metaNS, provenanceNS, weatherNS sql.NullString
+			skipsNS                         sql.NullString
 		)
 
-		if err := rows.Scan(&id, &path, &sha256, &fetchedAtStr, &sizeBytes, &skystateNS, &meteorNI, &labeledAtNS); err != nil {
+		if err := rows.Scan(&id, &path, &sha256, &fetchedAtStr, &sizeBytes, &width, &height, &phash, &quality, &split, &format, &archivedAtNS, &notes, &skystateNS, &meteorNI, &labeledAtNS, &needsReview,
+			&sugStateNS, &sugConfNF, &sugModelNS, &sugAtNS, &sugThresholdNF, &metaNS, &provenanceNS, &weatherNS, &skipsNS); err != nil {
 			return nil, fmt.Errorf("scan: %w", err)
 		}
 
@@ -297,6 +1018,16 @@ LEFT JOIN labels l ON l.image_id = i.id
 			SHA256:    sha256,
 			FetchedAt: fetchedAt,
 			SizeBytes: sizeBytes,
+			Width:     width,
+			Height:    height,
+			PHash:     phash,
+			Quality:   quality,
+			Split:     split,
+			Format:    format,
+
+			NeedsReview: needsReview == 1,
+			ArchivedAt:  parseNullTime(archivedAtNS),
+			Notes:       notes,
 		}
 
 		if skystateNS.Valid {
@@ -313,6 +1044,35 @@ LEFT JOIN labels l ON l.image_id = i.id
 				item.LabeledAt = &tm
 			}
 		}
+		if sugStateNS.Valid {
+			item.Suggestion = &Suggestion{
+				Skystate:     sugStateNS.String,
+				Confidence:   sugConfNF.Float64,
+				ModelVersion: sugModelNS.String,
+			}
+			item.Suggestion.SuggestedAt, _ = time.Parse(time.RFC3339, sugAtNS.String)
+			if sugThresholdNF.Valid {
+				item.Suggestion.Threshold = &sugThresholdNF.Float64
+			}
+		}
+		if metaNS.Valid {
+			_ = json.Unmarshal([]byte(metaNS.String), &item.Meta)
+		}
+		if provenanceNS.Valid {
+			var p Provenance
+			if json.Unmarshal([]byte(provenanceNS.String), &p) == nil {
+				item.Provenance = &p
+			}
+		}
+		if weatherNS.Valid {
+			var w WeatherReading
+			if json.Unmarshal([]byte(weatherNS.String), &w) == nil {
+				item.Weather = &w
+			}
+		}
+		if skipsNS.Valid {
+			_ = json.Unmarshal([]byte(skipsNS.String), &item.Skips)
+		}
 
 		out = append(out, item)
 	}
@@ -330,13 +1090,20 @@ type DaySummary struct {
 	SizeBytes int64  `json:"size_bytes"`
 }
 
-// ListDays returns available days (UTC) with counts and total size, newest first.
-func (s *Store) ListDays() ([]DaySummary, error) {
-	rows, err := s.DB.Query(`
-SELECT DATE(fetched_at) as day, COUNT(*) as cnt, COALESCE(SUM(size_bytes), 0) as total_size
+// ListDays returns available days (as grouped by DayGrouping) with counts and
+// total size, newest first. Archived images are not counted.
+func (s *Store) ListDays(ctx context.Context) ([]DaySummary, error) {
+	from, to, err := fetchedSpan(ctx, s.read)
+	if err != nil {
+		return nil, err
+	}
+	day, args := s.days.dayExpr("fetched_at", from, to)
+	rows, err := s.read.QueryContext(ctx, `
+SELECT `+day+` as day, COUNT(*) as cnt, COALESCE(SUM(size_bytes), 0) as total_size
 FROM images
+WHERE archived_at IS NULL
 GROUP BY day
-ORDER BY day DESC`)
+ORDER BY day DESC`, args...)
 	if err != nil {
 		return nil, fmt.Errorf("list days: %w", err)
 	}
@@ -364,17 +1131,17 @@ type CleanupResult struct {
 }
 
 // GetOldestUnlabeledImages returns the oldest unlabeled images (by fetched_at)
-func (s *Store) GetOldestUnlabeledImages(limit int) ([]ImageWithLabel, error) {
+func (s *Store) GetOldestUnlabeledImages(ctx context.Context, limit int) ([]ImageWithLabel, error) {
 	q := `
 SELECT i.id, i.path, i.sha256, i.fetched_at, i.size_bytes,
        NULL as skystate, NULL as meteor, NULL as labeled_at
 FROM images i
 LEFT JOIN labels l ON l.image_id = i.id
-WHERE l.image_id IS NULL
+WHERE l.image_id IS NULL AND i.archived_at IS NULL
 ORDER BY i.fetched_at ASC
 LIMIT ?`
 
-	rows, err := s.DB.Query(q, limit)
+	rows, err := s.read.QueryContext(ctx, q, limit)
 	if err != nil {
 		return nil, fmt.Errorf("get oldest unlabeled: %w", err)
 	}
@@ -405,44 +1172,46 @@ LIMIT ?`
 }
 
 // DeleteImage removes an image from the database (labels are cascade deleted)
-func (s *Store) DeleteImage(id string) error {
-	_, err := s.DB.Exec(`DELETE FROM images WHERE id = ?`, id)
+func (s *Store) DeleteImage(ctx context.Context, id string) error {
+	_, err := s.DB.ExecContext(ctx, `DELETE FROM images WHERE id = ?`, id)
 	return err
 }
 
 // CountUnlabeled returns the number of unlabeled images
-func (s *Store) CountUnlabeled() (int, error) {
+func (s *Store) CountUnlabeled(ctx context.Context) (int, error) {
 	var n int
-	err := s.DB.QueryRow(`
+	err := s.read.QueryRowContext(ctx, `
 SELECT COUNT(*)
 FROM images i
 LEFT JOIN labels l ON l.image_id = i.id
-WHERE l.image_id IS NULL`).Scan(&n)
+WHERE l.image_id IS NULL AND i.archived_at IS NULL`).Scan(&n)
 	return n, err
 }
 
 // CountUnlabeledByDay returns unlabeled image count for a specific day
-func (s *Store) CountUnlabeledByDay(day string) (int, error) {
+func (s *Store) CountUnlabeledByDay(ctx context.Context, day string) (int, error) {
 	var n int
-	err := s.DB.QueryRow(`
+	cond, args := s.days.dayCond("i.fetched_at", day)
+	err := s.read.QueryRowContext(ctx, `
 SELECT COUNT(*)
 FROM images i
 LEFT JOIN labels l ON l.image_id = i.id
-WHERE l.image_id IS NULL AND DATE(i.fetched_at) = ?`, day).Scan(&n)
+WHERE l.image_id IS NULL AND i.archived_at IS NULL AND `+cond, args...).Scan(&n)
 	return n, err
 }
 
 // GetUnlabeledByDay returns all unlabeled images for a specific day
-func (s *Store) GetUnlabeledByDay(day string) ([]ImageWithLabel, error) {
+func (s *Store) GetUnlabeledByDay(ctx context.Context, day string) ([]ImageWithLabel, error) {
+	cond, args := s.days.dayCond("i.fetched_at", day)
 	q := `
 SELECT i.id, i.path, i.sha256, i.fetched_at, i.size_bytes,
        NULL as skystate, NULL as meteor, NULL as labeled_at
 FROM images i
 LEFT JOIN labels l ON l.image_id = i.id
-WHERE l.image_id IS NULL AND DATE(i.fetched_at) = ?
+WHERE l.image_id IS NULL AND i.archived_at IS NULL AND ` + cond + `
 ORDER BY i.fetched_at ASC`
 
-	rows, err := s.DB.Query(q, day)
+	rows, err := s.read.QueryContext(ctx, q, args...)
 	if err != nil {
 		return nil, fmt.Errorf("get unlabeled by day: %w", err)
 	}
@@ -473,8 +1242,8 @@ ORDER BY i.fetched_at ASC`
 }
 
 // DeleteUnlabeledByDay deletes all unlabeled images for a specific day and returns cleanup result
-func (s *Store) DeleteUnlabeledByDay(day string) (CleanupResult, error) {
-	images, err := s.GetUnlabeledByDay(day)
+func (s *Store) DeleteUnlabeledByDay(ctx context.Context, day string) (CleanupResult, error) {
+	images, err := s.GetUnlabeledByDay(ctx, day)
 	if err != nil {
 		return CleanupResult{}, err
 	}
@@ -484,7 +1253,7 @@ func (s *Store) DeleteUnlabeledByDay(day string) (CleanupResult, error) {
 	}
 
 	for _, img := range images {
-		if err := s.DeleteImage(img.ID); err != nil {
+		if err := s.DeleteImage(ctx, img.ID); err != nil {
 			return result, fmt.Errorf("delete image %s: %w", img.ID, err)
 		}
 		result.DeletedCount++
@@ -496,8 +1265,8 @@ func (s *Store) DeleteUnlabeledByDay(day string) (CleanupResult, error) {
 }
 
 // DeleteOldestUnlabeled deletes the oldest N unlabeled images to keep count under maxUnlabeled
-func (s *Store) DeleteOldestUnlabeled(maxUnlabeled int) (CleanupResult, error) {
-	count, err := s.CountUnlabeled()
+func (s *Store) DeleteOldestUnlabeled(ctx context.Context, maxUnlabeled int) (CleanupResult, error) {
+	count, err := s.CountUnlabeled(ctx)
 	if err != nil {
 		return CleanupResult{}, err
 	}
@@ -507,7 +1276,7 @@ func (s *Store) DeleteOldestUnlabeled(maxUnlabeled int) (CleanupResult, error) {
 	}
 
 	toDelete := count - maxUnlabeled
-	images, err := s.GetOldestUnlabeledImages(toDelete)
+	images, err := s.GetOldestUnlabeledImages(ctx, toDelete)
 	if err != nil {
 		return CleanupResult{}, err
 	}
@@ -517,7 +1286,7 @@ func (s *Store) DeleteOldestUnlabeled(maxUnlabeled int) (CleanupResult, error) {
 	}
 
 	for _, img := range images {
-		if err := s.DeleteImage(img.ID); err != nil {
+		if err := s.DeleteImage(ctx, img.ID); err != nil {
 			return result, fmt.Errorf("delete image %s: %w", img.ID, err)
 		}
 		result.DeletedCount++
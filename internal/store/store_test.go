@@ -0,0 +1,201 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// benchImages returns n distinct images fetched a second apart.
+func benchImages(n int) []Image {
+	base := time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+	imgs := make([]Image, n)
+	for i := range imgs {
+		id := fmt.Sprintf("img%07d", i)
+		imgs[i] = Image{
+			ID:        id,
+			Path:      "/data/images/" + id + ".jpg",
+			SHA256:    fmt.Sprintf("%064x", i),
+			FetchedAt: base.Add(time.Duration(i) * time.Second),
+			SizeBytes: 100_000,
+		}
+	}
+	return imgs
+}
+
+// BenchmarkUpsertImage compares the ingest paths per image: the cached
+// prepared statement, the same SQL compiled on every call, and one
+// UpsertImages transaction for 10k rows at a time.
+func BenchmarkUpsertImage(b *testing.B) {
+	ctx := context.Background()
+
+	b.Run("prepared", func(b *testing.B) {
+		s := openTestStore(b)
+		imgs := benchImages(b.N)
+		b.ResetTimer()
+		for _, img := range imgs {
+			if err := s.UpsertImage(ctx, img.ID, img.Path, img.SHA256, img.FetchedAt, img.SizeBytes); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("adhoc", func(b *testing.B) {
+		s := openTestStore(b)
+		imgs := benchImages(b.N)
+		b.ResetTimer()
+		for _, img := range imgs {
+			if _, err := s.DB.ExecContext(ctx, upsertImageSQL, img.ID, img.Path, img.SHA256, img.FetchedAt.UTC().Format(time.RFC3339), img.SizeBytes); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		s := openTestStore(b)
+		imgs := benchImages(b.N)
+		b.ResetTimer()
+		for batch := range slices.Chunk(imgs, 10_000) {
+			if err := s.UpsertImages(ctx, batch); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkGetLabel compares the cached prepared statement with the same
+// query compiled on every call, on 10k labeled images.
+func BenchmarkGetLabel(b *testing.B) {
+	ctx := context.Background()
+	s := openTestStore(b)
+	imgs := benchImages(10_000)
+	if err := s.UpsertImages(ctx, imgs); err != nil {
+		b.Fatal(err)
+	}
+	if _, err := s.DB.ExecContext(ctx, `INSERT INTO labels(image_id, skystate, meteor, labeled_at)
+		SELECT id, 'clear', 0, fetched_at FROM images`); err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("prepared", func(b *testing.B) {
+		for i := 0; b.Loop(); i++ {
+			if _, _, ok, err := s.GetLabel(ctx, imgs[i%len(imgs)].ID); err != nil || !ok {
+				b.Fatalf("label %v, %v", ok, err)
+			}
+		}
+	})
+	b.Run("adhoc", func(b *testing.B) {
+		for i := 0; b.Loop(); i++ {
+			var skystate string
+			var meteor int
+			if err := s.read.QueryRowContext(ctx, getLabelSQL, imgs[i%len(imgs)].ID).Scan(&skystate, &meteor); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// TestUpsertImagesMatchesUpsertImage checks the batch path stores what single
+// upserts do, including the update of an image already known by its hash.
+func TestUpsertImagesMatchesUpsertImage(t *testing.T) {
+	ctx := context.Background()
+	imgs := benchImages(3)
+	single, batch := openTestStore(t), openTestStore(t)
+	for _, img := range imgs {
+		if err := single.UpsertImage(ctx, img.ID, img.Path, img.SHA256, img.FetchedAt, img.SizeBytes); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := batch.UpsertImages(ctx, imgs); err != nil {
+		t.Fatal(err)
+	}
+
+	// Same content under a new path: updated in place
+	moved := imgs[1]
+	moved.Path = "/data/images/moved.jpg"
+	if err := single.UpsertImage(ctx, moved.ID, moved.Path, moved.SHA256, moved.FetchedAt, moved.SizeBytes); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.UpsertImages(ctx, []Image{moved}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []*Store{single, batch} {
+		var n int
+		var path string
+		if err := s.DB.QueryRowContext(ctx, `SELECT COUNT(*), (SELECT path FROM images WHERE id = ?) FROM images`, moved.ID).Scan(&n, &path); err != nil {
+			t.Fatal(err)
+		}
+		if n != len(imgs) || path != moved.Path {
+			t.Fatalf("%d images, %s at %s; want %d, %s", n, moved.ID, path, len(imgs), moved.Path)
+		}
+	}
+}
+
+// TestConcurrentLabelWritesDuringExport hammers the writer from 20 goroutines
+// while an export streams labeled images from the read pool: no caller may
+// see SQLITE_BUSY, and the export must finish with every label.
+func TestConcurrentLabelWritesDuringExport(t *testing.T) {
+	const writers, perWriter = 20, 50
+	ctx := context.Background()
+	s := openTestStore(t)
+	imgs := benchImages(writers * perWriter)
+	if err := s.UpsertImages(ctx, imgs); err != nil {
+		t.Fatal(err)
+	}
+	export := ImageFilter{LabeledOnly: true, ExcludeTruncated: true, ExcludeUnpredictable: true}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*perWriter)
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := w; i < len(imgs); i += writers {
+				var err error
+				if i%2 == 0 {
+					err = s.SetLabel(imgs[i].ID, "clear", false, time.Now())
+				} else {
+					err = s.WriteLabel(ctx, LabelWrite{ImageID: imgs[i].ID, Skystate: "heavy_clouds", LabeledAt: time.Now()})
+				}
+				if err != nil {
+					errs <- fmt.Errorf("label %s: %w", imgs[i].ID, err)
+				}
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() { wg.Wait(); close(done) }()
+
+	exports := 0
+	for running := true; running; exports++ {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		if _, err := s.ListImagesFiltered(ctx, export); err != nil {
+			t.Fatalf("export %d: %v", exports, err)
+		}
+		if _, err := s.CountStats(ctx); err != nil {
+			t.Fatalf("stats: %v", err)
+		}
+	}
+	close(errs)
+	for err := range errs {
+		if isBusy(err) {
+			t.Errorf("SQLITE_BUSY reached the caller: %v", err)
+		} else {
+			t.Error(err)
+		}
+	}
+
+	items, err := s.ListImagesFiltered(ctx, export)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != len(imgs) {
+		t.Fatalf("export after %d runs during the writes holds %d images, want %d", exports, len(items), len(imgs))
+	}
+}
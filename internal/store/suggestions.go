@@ -0,0 +1,181 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Suggestion is a label proposed by the model for an unlabeled image.
+type Suggestion struct {
+	Skystate     string    `json:"skystate"`
+	Confidence   float64   `json:"confidence"`
+	ModelVersion string    `json:"model_version"`
+	SuggestedAt  time.Time `json:"suggested_at"`
+	Threshold    *float64  `json:"threshold,omitempty"` // effective minimum confidence it passed
+}
+
+// AutoLabelThresholds are the minimum confidences for auto-label
+// suggestions: Global, unless ByClass overrides it for the predicted class.
+// 0 means no suggestions.
+type AutoLabelThresholds struct {
+	Global  float64
+	ByClass map[string]float64
+}
+
+// For returns the threshold that applies to class.
+func (t AutoLabelThresholds) For(class string) float64 {
+	if v, ok := t.ByClass[class]; ok {
+		return v
+	}
+	return t.Global
+}
+
+// Enabled reports whether any class gets suggestions.
+func (t AutoLabelThresholds) Enabled() bool {
+	if t.Global > 0 {
+		return true
+	}
+	for _, v := range t.ByClass {
+		if v > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// AutoLabelThresholds reads the auto-label thresholds from the settings.
+func (s *Store) AutoLabelThresholds(ctx context.Context) (AutoLabelThresholds, error) {
+	var t AutoLabelThresholds
+	if _, err := s.GetSetting(ctx, SettingAutoLabelMinConfidence, &t.Global); err != nil {
+		return t, err
+	}
+	if _, err := s.GetSetting(ctx, SettingAutoLabelClassMinConf, &t.ByClass); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// SuggestLabel stores (or replaces) the model suggestion for an image. Images
+// that already carry a real label are left alone; it reports whether a
+// suggestion was written.
+func (s *Store) SuggestLabel(ctx context.Context, imageID string, sg Suggestion) (bool, error) {
+	var n int64
+	err := retryBusy(ctx, func() error {
+		res, err := s.DB.ExecContext(ctx, `
+INSERT INTO suggested_labels(image_id, skystate, confidence, model_version, suggested_at, threshold)
+SELECT ?, ?, ?, ?, ?, ?
+WHERE NOT EXISTS (SELECT 1 FROM labels WHERE image_id = ?)
+ON CONFLICT(image_id) DO UPDATE SET skystate=excluded.skystate, confidence=excluded.confidence,
+  model_version=excluded.model_version, suggested_at=excluded.suggested_at, threshold=excluded.threshold`,
+			imageID, sg.Skystate, sg.Confidence, sg.ModelVersion, sg.SuggestedAt.UTC().Format(time.RFC3339), sg.Threshold, imageID)
+		if err != nil {
+			return fmt.Errorf("suggest label: %w", err)
+		}
+		n, _ = res.RowsAffected()
+		return nil
+	})
+	return n > 0, err
+}
+
+// SuggestionFilter scopes AcceptSuggestions. Zero values mean "any".
+type SuggestionFilter struct {
+	Day           string // YYYY-MM-DD the image was fetched (see DayGrouping)
+	Skystate      string
+	MinConfidence float64
+	// Thresholds, when set, also require each suggestion to reach the
+	// threshold of its class
+	Thresholds *AutoLabelThresholds
+}
+
+// AcceptSuggestions promotes matching suggestions to real labels, recorded in
+// label_history with source "auto", and returns how many were promoted.
+// Images labeled in the meantime are skipped.
+func (s *Store) AcceptSuggestions(ctx context.Context, f SuggestionFilter) (int, error) {
+	q := `
+SELECT sg.image_id, sg.skystate
+FROM suggested_labels sg
+JOIN images i ON i.id = sg.image_id
+LEFT JOIN labels l ON l.image_id = sg.image_id
+WHERE l.image_id IS NULL AND i.archived_at IS NULL`
+	var args []any
+	var where []string
+	if f.Day != "" {
+		cond, dayArgs := s.days.dayCond("i.fetched_at", f.Day)
+		where = append(where, cond)
+		args = append(args, dayArgs...)
+	}
+	if f.Skystate != "" {
+		where = append(where, "sg.skystate = ?")
+		args = append(args, f.Skystate)
+	}
+	if f.MinConfidence > 0 {
+		where = append(where, "sg.confidence >= ?")
+		args = append(args, f.MinConfidence)
+	}
+	if t := f.Thresholds; t != nil {
+		cond := "sg.confidence >= "
+		if len(t.ByClass) > 0 {
+			cond += "CASE sg.skystate"
+			for class, v := range t.ByClass {
+				cond += " WHEN ? THEN ?"
+				args = append(args, class, v)
+			}
+			cond += " ELSE ? END"
+		} else {
+			cond += "?"
+		}
+		where = append(where, cond)
+		args = append(args, t.Global)
+	}
+	if len(where) > 0 {
+		q += " AND " + strings.Join(where, " AND ")
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, q, args...)
+	if err != nil {
+		return 0, fmt.Errorf("select suggestions: %w", err)
+	}
+	type pick struct{ imageID, skystate string }
+	var picks []pick
+	for rows.Next() {
+		var p pick
+		if err := rows.Scan(&p.imageID, &p.skystate); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan: %w", err)
+		}
+		picks = append(picks, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	for _, p := range picks {
+		if err := s.setLabelTx(ctx, tx, LabelWrite{
+			ImageID:   p.imageID,
+			Skystate:  p.skystate,
+			LabeledAt: now,
+			Source:    LabelSourceAuto,
+		}); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	ids := make([]string, len(picks))
+	for i, p := range picks {
+		ids[i] = p.imageID
+	}
+	s.labelsChanged(ids...)
+	return len(picks), nil
+}
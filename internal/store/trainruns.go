@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TrainRun is a completed training run in the run history.
+type TrainRun struct {
+	ID         string          `json:"run_id"`
+	Versions   []string        `json:"versions"` // model versions the run produced
+	Run        json.RawMessage `json:"run"`
+	Summary    json.RawMessage `json:"summary,omitempty"` // final validation metrics
+	HasMetrics bool            `json:"has_metrics"`       // per-epoch curves are stored
+	Warning    string          `json:"warning,omitempty"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+}
+
+// SaveTrainRun records a completed run with its per-epoch curves (nil if there
+// are none), replacing an earlier record of the same run.
+func (s *Store) SaveTrainRun(ctx context.Context, r TrainRun, metrics []byte) error {
+	err := retryBusy(ctx, func() error {
+		_, err := s.DB.ExecContext(ctx, `
+INSERT INTO train_runs(id, versions, run, summary, metrics, warning, started_at, finished_at) VALUES(?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(id) DO UPDATE SET versions = excluded.versions, run = excluded.run, summary = excluded.summary,
+  metrics = excluded.metrics, warning = excluded.warning, started_at = excluded.started_at, finished_at = excluded.finished_at`,
+			r.ID, strings.Join(r.Versions, ","), string(r.Run), nullIfEmpty(string(r.Summary)), nullIfEmpty(string(metrics)), nullIfEmpty(r.Warning),
+			r.StartedAt.UTC().Format(time.RFC3339), r.FinishedAt.UTC().Format(time.RFC3339))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("save train run: %w", err)
+	}
+	return nil
+}
+
+// ListTrainRuns returns the run history, newest first.
+func (s *Store) ListTrainRuns(ctx context.Context, limit int) ([]TrainRun, error) {
+	rows, err := s.read.QueryContext(ctx, `
+SELECT id, versions, run, summary, metrics IS NOT NULL, warning, started_at, finished_at
+FROM train_runs ORDER BY started_at DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list train runs: %w", err)
+	}
+	defer rows.Close()
+	out := []TrainRun{}
+	for rows.Next() {
+		var (
+			r                     TrainRun
+			versions, run         string
+			summary, warning      sql.NullString
+			startedAt, finishedAt string
+		)
+		if err := rows.Scan(&r.ID, &versions, &run, &summary, &r.HasMetrics, &warning, &startedAt, &finishedAt); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		r.Versions = []string{}
+		if versions != "" {
+			r.Versions = strings.Split(versions, ",")
+		}
+		r.Run = json.RawMessage(run)
+		if summary.Valid {
+			r.Summary = json.RawMessage(summary.String)
+		}
+		r.Warning = warning.String
+		r.StartedAt, _ = time.Parse(time.RFC3339, startedAt)
+		r.FinishedAt, _ = time.Parse(time.RFC3339, finishedAt)
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// GetTrainRunMetrics returns the per-epoch curves of a run. found is false for
+// an unknown run; metrics is nil if the run has none.
+func (s *Store) GetTrainRunMetrics(ctx context.Context, id string) (metrics []byte, found bool, err error) {
+	var body sql.NullString
+	err = s.read.QueryRowContext(ctx, `SELECT metrics FROM train_runs WHERE id = ?`, id).Scan(&body)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("get train run metrics: %w", err)
+	}
+	if !body.Valid {
+		return nil, true, nil
+	}
+	return []byte(body.String), true, nil
+}
+
+// nullIfEmpty stores an empty string as NULL.
+func nullIfEmpty(v string) any {
+	if v == "" {
+		return nil
+	}
+	return v
+}
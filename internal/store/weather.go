@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WeatherMaxGap is how far a weather reading may be from an image's fetch
+// time to be joined to it.
+const WeatherMaxGap = time.Hour
+
+// WeatherReading is one observation of the current conditions at the site.
+type WeatherReading struct {
+	Time          time.Time `json:"time"`
+	CloudCover    float64   `json:"cloud_cover"`   // percent
+	Temperature   float64   `json:"temperature"`   // °C
+	Humidity      float64   `json:"humidity"`      // relative, percent
+	Precipitation float64   `json:"precipitation"` // mm
+}
+
+// The readings just before and just after an image's fetch time, both found
+// along the primary key.
+const (
+	weatherBeforeSQL = `(SELECT time FROM weather WHERE time <= i.fetched_at ORDER BY time DESC LIMIT 1)`
+	weatherAfterSQL  = `(SELECT time FROM weather WHERE time > i.fetched_at ORDER BY time ASC LIMIT 1)`
+)
+
+// weatherNearestSQL selects the reading nearest to the image i (within
+// WeatherMaxGap) as a JSON object, or NULL. SQLite can't order a correlated
+// subquery by the outer row, so the nearer of the two is picked with CASE;
+// a missing one compares as NULL and loses.
+var weatherNearestSQL = `(
+  SELECT json_object('time', w.time, 'cloud_cover', w.cloud_cover, 'temperature', w.temperature,
+                     'humidity', w.humidity, 'precipitation', w.precipitation)
+  FROM weather w
+  WHERE w.time = CASE
+      WHEN ` + weatherAfterSQL + ` IS NULL
+        OR julianday(i.fetched_at) - julianday(` + weatherBeforeSQL + `) <= julianday(` + weatherAfterSQL + `) - julianday(i.fetched_at)
+      THEN ` + weatherBeforeSQL + ` ELSE ` + weatherAfterSQL + ` END
+    AND ABS(julianday(w.time) - julianday(i.fetched_at)) * 86400 <= ` + fmt.Sprint(int(WeatherMaxGap.Seconds())) + `)`
+
+// RecordWeather stores a reading; a second reading for the same time
+// replaces the first.
+func (s *Store) RecordWeather(ctx context.Context, r WeatherReading) error {
+	return retryBusy(ctx, func() error {
+		_, err := s.DB.ExecContext(ctx, `
+INSERT INTO weather(time, cloud_cover, temperature, humidity, precipitation)
+VALUES(?, ?, ?, ?, ?)
+ON CONFLICT(time) DO UPDATE SET
+  cloud_cover = excluded.cloud_cover,
+  temperature = excluded.temperature,
+  humidity = excluded.humidity,
+  precipitation = excluded.precipitation`,
+			r.Time.UTC().Format(time.RFC3339), r.CloudCover, r.Temperature, r.Humidity, r.Precipitation)
+		if err != nil {
+			return fmt.Errorf("record weather: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,182 @@
+// Package thermal watches the SoC temperature so heavy work (auto-predict,
+// training) can wind down while a passively cooled board is throttling.
+// Ingestion and labeling are never paused for heat.
+package thermal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/webhook"
+)
+
+// ErrHot is returned by Check while the temperature is above the limit.
+var ErrHot = errors.New("thermal: too hot")
+
+// Sensor reads the current temperature in °C.
+type Sensor interface {
+	Celsius() (float64, error)
+}
+
+// FileSensor reads a sysfs thermal zone such as
+// /sys/class/thermal/thermal_zone0/temp, which holds millidegrees; values
+// below 1000 are taken as degrees.
+type FileSensor string
+
+// Celsius reads the file.
+func (f FileSensor) Celsius() (float64, error) {
+	b, err := os.ReadFile(string(f))
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(string(b)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", string(f), err)
+	}
+	if v >= 1000 {
+		v /= 1000
+	}
+	return v, nil
+}
+
+// Status is a snapshot of the monitor state.
+type Status struct {
+	Enabled  bool      `json:"enabled"`
+	Hot      bool      `json:"hot"` // auto-predict paused, training refused
+	Celsius  float64   `json:"celsius,omitempty"`
+	Max      float64   `json:"max_celsius,omitempty"`
+	Resume   float64   `json:"resume_celsius,omitempty"`
+	Since    time.Time `json:"since,omitempty"` // start of the current state
+	LastRead time.Time `json:"last_read,omitempty"`
+	Error    string    `json:"error,omitempty"` // last sensor error; the state is kept
+}
+
+// Monitor reads a sensor periodically. It turns hot at or above max and
+// cools down only below resume, so a temperature hovering at the limit
+// doesn't flap. A nil *Monitor is never hot.
+type Monitor struct {
+	sensor      Sensor
+	max, resume float64
+	interval    time.Duration
+	notifier    *webhook.Notifier
+
+	mu       sync.Mutex
+	hot      bool
+	celsius  float64
+	since    time.Time
+	lastRead time.Time
+	lastErr  error
+}
+
+// NewMonitor creates a monitor reading sensor every interval, hot from
+// maxC until below resumeC (°C).
+func NewMonitor(sensor Sensor, maxC, resumeC float64, interval time.Duration) *Monitor {
+	return &Monitor{sensor: sensor, max: maxC, resume: resumeC, interval: interval, since: time.Now().UTC()}
+}
+
+// SetNotifier sends a webhook event on entering and leaving the hot state.
+func (m *Monitor) SetNotifier(n *webhook.Notifier) {
+	m.notifier = n
+}
+
+// Start reads immediately and then every interval until ctx is canceled.
+func (m *Monitor) Start(ctx context.Context) {
+	t := time.NewTicker(m.interval)
+	defer t.Stop()
+	for {
+		m.Read(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Read takes one reading and updates the state; Start calls it on the
+// interval.
+func (m *Monitor) Read(ctx context.Context) {
+	c, err := m.sensor.Celsius()
+
+	m.mu.Lock()
+	now := time.Now().UTC()
+	m.lastRead = now
+	m.lastErr = err
+	if err != nil {
+		m.mu.Unlock()
+		log.Printf("thermal: %v", err)
+		return
+	}
+	m.celsius = c
+	was := m.hot
+	switch {
+	case !m.hot && c >= m.max:
+		m.hot = true
+	case m.hot && c < m.resume:
+		m.hot = false
+	}
+	if was == m.hot {
+		m.mu.Unlock()
+		return
+	}
+	m.since = now
+	st := m.statusLocked()
+	m.mu.Unlock()
+
+	event := webhook.EventThermalHot
+	if st.Hot {
+		log.Printf("thermal: %.1f°C, pausing auto-predict and training until below %.1f°C", c, m.resume)
+	} else {
+		event = webhook.EventThermalCooled
+		log.Printf("thermal: %.1f°C, resuming auto-predict and training", c)
+	}
+	if err := m.notifier.Send(ctx, event, st); err != nil {
+		log.Printf("thermal: %v", err)
+	}
+}
+
+// Check returns nil unless the monitor is hot, then an error wrapping ErrHot.
+func (m *Monitor) Check() error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.hot {
+		return nil
+	}
+	return fmt.Errorf("%w: %.1f°C, resuming below %.1f°C", ErrHot, m.celsius, m.resume)
+}
+
+// Status returns the current state.
+func (m *Monitor) Status() Status {
+	if m == nil {
+		return Status{}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.statusLocked()
+}
+
+func (m *Monitor) statusLocked() Status {
+	st := Status{
+		Enabled:  true,
+		Hot:      m.hot,
+		Celsius:  m.celsius,
+		Max:      m.max,
+		Resume:   m.resume,
+		Since:    m.since,
+		LastRead: m.lastRead,
+	}
+	if m.lastErr != nil {
+		st.Error = m.lastErr.Error()
+	}
+	return st
+}
@@ -0,0 +1,144 @@
+package trainer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Files written into the shared directory before a run.
+const (
+	ClassWeightsFile = "class_weights.json"
+	FilelistFile     = "filelist.csv"
+)
+
+// ClassWeights derives inverse-frequency weights from per-class label counts:
+// total / (classes * count), so a balanced dataset gets 1.0 everywhere and rare
+// classes weigh more. Classes without samples get 0 (nothing to weigh) and don't
+// count towards the number of classes.
+func ClassWeights(counts map[string]int) map[string]float64 {
+	total, present := 0, 0
+	for _, n := range counts {
+		if n > 0 {
+			total += n
+			present++
+		}
+	}
+
+	weights := make(map[string]float64, len(counts))
+	for class, n := range counts {
+		if n <= 0 {
+			weights[class] = 0
+			continue
+		}
+		weights[class] = float64(total) / float64(present*n)
+	}
+	return weights
+}
+
+// SetSharedDir sets the directory used to hand files (class weights, file list)
+// to the trainer container. It must be mounted at the same path in both
+// containers, e.g. DataDir/train. Empty disables both.
+func (t *Trainer) SetSharedDir(dir string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sharedDir = dir
+}
+
+// writeClassWeights writes weights as JSON into dir and returns the file path.
+func writeClassWeights(dir string, weights map[string]float64) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create shared dir: %w", err)
+	}
+	data, err := json.MarshalIndent(weights, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, ClassWeightsFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return "", fmt.Errorf("write class weights: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", fmt.Errorf("write class weights: %w", err)
+	}
+	return path, nil
+}
+
+// writeFilelist writes the training file list produced by fn into dir and
+// returns the file path and the SHA-256 of its content.
+func writeFilelist(ctx context.Context, dir string, fn func(context.Context, io.Writer) error) (string, string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", "", fmt.Errorf("create shared dir: %w", err)
+	}
+	path := filepath.Join(dir, FilelistFile)
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return "", "", fmt.Errorf("write filelist: %w", err)
+	}
+	h := sha256.New()
+	if err := fn(ctx, io.MultiWriter(f, h)); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return "", "", fmt.Errorf("write filelist: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", "", fmt.Errorf("write filelist: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", "", fmt.Errorf("write filelist: %w", err)
+	}
+	return path, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// FilelistPaths returns the image paths listed in the file list of the
+// running job, so they aren't deleted under it. It returns nil when no job
+// runs or the trainer doesn't hand out a file list.
+func (t *Trainer) FilelistPaths(ctx context.Context) ([]string, error) {
+	_, containerRunning := t.getJobContainerState(ctx)
+	t.mu.RLock()
+	running, dir := t.running || containerRunning, t.sharedDir
+	t.mu.RUnlock()
+	if !running || dir == "" || t.Filelist == nil {
+		return nil, nil
+	}
+
+	paths, err := readFilelistPaths(filepath.Join(dir, FilelistFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return paths, err
+}
+
+// readFilelistPaths returns the image paths listed in a file list.
+func readFilelistPaths(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read filelist: %w", err)
+	}
+	defer f.Close()
+	cr := csv.NewReader(f)
+	cr.FieldsPerRecord = -1
+	var paths []string
+	for first := true; ; first = false {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return paths, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read filelist: %w", err)
+		}
+		if !first && len(rec) > 0 { // the first row is the header
+			paths = append(paths, rec[0])
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package trainer
+
+import (
+	"math"
+	"testing"
+)
+
+func TestClassWeights(t *testing.T) {
+	tests := []struct {
+		name   string
+		counts map[string]int
+		want   map[string]float64
+	}{
+		{"balanced", map[string]int{"clear": 50, "light_clouds": 50, "heavy_clouds": 50},
+			map[string]float64{"clear": 1, "light_clouds": 1, "heavy_clouds": 1}},
+		{"skewed", map[string]int{"clear": 100, "heavy_clouds": 800, "precipitation": 100},
+			map[string]float64{"clear": 1000.0 / 300, "heavy_clouds": 1000.0 / 2400, "precipitation": 1000.0 / 300}},
+		{"zero-count class", map[string]int{"clear": 30, "heavy_clouds": 10, "precipitation": 0},
+			map[string]float64{"clear": 40.0 / 60, "heavy_clouds": 2, "precipitation": 0}},
+		{"negative count", map[string]int{"clear": 10, "unknown": -1},
+			map[string]float64{"clear": 1, "unknown": 0}},
+		{"all zero", map[string]int{"clear": 0, "heavy_clouds": 0},
+			map[string]float64{"clear": 0, "heavy_clouds": 0}},
+		{"single class", map[string]int{"clear": 7}, map[string]float64{"clear": 1}},
+		{"empty", map[string]int{}, map[string]float64{}},
+		{"nil", nil, map[string]float64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassWeights(tt.counts)
+			if got == nil || len(got) != len(tt.want) {
+				t.Fatalf("ClassWeights(%v) = %v, want %v", tt.counts, got, tt.want)
+			}
+			total, weighted := 0, 0.0
+			for class, w := range got {
+				if math.IsNaN(w) || math.IsInf(w, 0) || w < 0 {
+					t.Fatalf("weight of %s is %v", class, w)
+				}
+				if math.Abs(w-tt.want[class]) > 1e-9 {
+					t.Fatalf("weight of %s is %v, want %v", class, w, tt.want[class])
+				}
+				if n := tt.counts[class]; n > 0 {
+					total += n
+					weighted += w * float64(n)
+				}
+			}
+			// Reweighting keeps the effective number of samples
+			if math.Abs(weighted-float64(total)) > 1e-6 {
+				t.Fatalf("weighted samples %v, want the total %d", weighted, total)
+			}
+		})
+	}
+}
@@ -2,17 +2,56 @@ package trainer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
+
+	"github.com/SkyClf/SkyClf/internal/buildinfo"
+	"github.com/SkyClf/SkyClf/internal/export"
 )
 
+// ErrQueueFull is returned by StartOrQueue when a run is already queued.
+var ErrQueueFull = errors.New("a training run is already queued")
+
+// ErrBusy is wrapped by the errors of a start refused because a run is in
+// progress or waiting to start.
+var ErrBusy = errors.New("training already in progress")
+
+// ErrNoFilelist is returned for a run that needs a file list (a snapshot or
+// oversampling) when the trainer has no shared dir to write it to.
+var ErrNoFilelist = errors.New("needs a file list (shared dir)")
+
+// ErrDockerUnavailable is wrapped by the errors of a start that couldn't reach
+// the Docker daemon or the trainer container.
+var ErrDockerUnavailable = errors.New("docker unavailable")
+
+// GuardError is a start refused by Trainer.Guard; it reads and unwraps as
+// the guard's error.
+type GuardError struct {
+	Err error
+}
+
+func (e *GuardError) Error() string { return e.Err.Error() }
+
+func (e *GuardError) Unwrap() error { return e.Err }
+
+// unavailable marks err from a Docker call with ErrDockerUnavailable when the
+// daemon couldn't be reached or the container or image is missing.
+func unavailable(err error) error {
+	if client.IsErrConnectionFailed(err) || client.IsErrNotFound(err) {
+		return fmt.Errorf("%w (%w)", err, ErrDockerUnavailable)
+	}
+	return err
+}
+
 // TrainConsfig holds the training parameters from the UI
 type TrainConfig struct {
 	Epochs      int    `json:"epochs"`
@@ -22,6 +61,24 @@ type TrainConfig struct {
 	Seed        int    `json:"seed"`
 	ValSplit    string `json:"val_split"`    // e.g. "0.2"
 	FromScratch bool   `json:"from_scratch"` // Train from scratch instead of resuming
+
+	UseClassWeights bool `json:"use_class_weights"` // weight the loss by inverse class frequency
+
+	// Snapshot trains on the labels of this dataset snapshot instead of the
+	// live ones; needs a file list.
+	Snapshot string `json:"snapshot,omitempty"`
+
+	// Oversample repeats train-split images of rare classes in the file list;
+	// needs a file list.
+	Oversample *export.Oversample `json:"oversample,omitempty"`
+
+	// ExtraEnv is set in the job container's environment, for trainer knobs
+	// without a field of their own; keys must pass DisallowedEnv.
+	ExtraEnv map[string]string `json:"extra_env,omitempty"`
+
+	// IgnoreMinInterval starts the run even if the previous one started less
+	// than StartLimit.MinInterval ago, e.g. for manual starts from the UI.
+	IgnoreMinInterval bool `json:"ignore_min_interval,omitempty"`
 }
 
 // DefaultTrainConfig returns sensible defaults
@@ -33,18 +90,44 @@ func DefaultTrainConfig() TrainConfig {
 		ImageSize: 224,
 		Seed:      42,
 		ValSplit:  "0.2",
+
+		UseClassWeights: true,
 	}
 }
 
+// Run describes one training run; OnComplete receives it to record the
+// lineage of the model versions it produced.
+type Run struct {
+	ID             string             `json:"run_id"`                   // start time, as in the log file name
+	Parent         string             `json:"parent_version,omitempty"` // version the run resumed from
+	Config         TrainConfig        `json:"config"`
+	StartedAt      time.Time          `json:"started_at"`
+	FinishedAt     time.Time          `json:"finished_at,omitempty"`
+	ClassWeights   map[string]float64 `json:"class_weights,omitempty"`
+	FilelistSHA256 string             `json:"filelist_sha256,omitempty"` // the exact training data
+	ServerVersion  string             `json:"server_version,omitempty"`  // build that started the run
+
+	Oversample *export.OversampleResult `json:"oversample,omitempty"` // rows the file list repeats
+}
+
 // TrainStatus represents the current state of a training job
 type TrainStatus struct {
 	Running     bool         `json:"running"`
+	RunID       string       `json:"run_id,omitempty"` // of the current/last run
 	ContainerID string       `json:"container_id,omitempty"`
 	StartedAt   time.Time    `json:"started_at,omitempty"`
 	ExitCode    int          `json:"exit_code,omitempty"`
 	Error       string       `json:"error,omitempty"`
-	Logs        string       `json:"logs,omitempty"`
+	Logs        string       `json:"logs,omitempty"`      // last ~20 lines; full log via /api/train/logs
+	LogBytes    int64        `json:"log_bytes,omitempty"` // total log size of the current/last run
+	LogFile     string       `json:"log_file,omitempty"`  // persisted log of the current/last run
 	LastConfig  *TrainConfig `json:"last_config,omitempty"`
+	Parent      string       `json:"parent_version,omitempty"` // model the current/last run resumed from
+	Queued      *TrainConfig `json:"queued,omitempty"`         // starts when the current run ends
+	QueuedAt    *time.Time   `json:"queued_at,omitempty"`
+	QueuedUntil *time.Time   `json:"queued_until,omitempty"` // the queued run waits for the start limit until then
+
+	ClassWeights map[string]float64 `json:"class_weights,omitempty"` // weights used by the current/last run
 }
 
 // Trainer manages the SkyClf-Trainer Docker container
@@ -58,12 +141,49 @@ type Trainer struct {
 	lastLogs       string
 	lastConfig     *TrainConfig
 	jobContainerID string
+	run            Run // current/last run
+
+	// queued is launched by monitor when the current run ends. While it is
+	// set, running stays true across the handoff so no Start can slip in.
+	queued   *TrainConfig
+	queuedAt time.Time
+	deferred *time.Timer // launches queued once the start limit allows it
+	deferTo  time.Time   // when deferred fires
+
+	logDir   string       // where complete run logs are written ("" = disabled)
+	logPath  string       // log file of the current/last run
+	logBytes atomic.Int64 // bytes written to logPath so far
+
+	sharedDir        string             // handed to the container at the same path ("" = no class weights)
+	envPrefixes      []string           // allowed ExtraEnv key prefixes (nil = DefaultEnvPrefixes)
+	lastClassWeights map[string]float64 // weights used by the current/last run
+	volumes          VolumeCheck        // checked before each run
+	startLimit       StartLimit         // spacing between starts
+	starts           StartHistory       // recent start attempts
 
 	// Config - container name from compose stack
 	containerName string // e.g. "skyclf-trainer"
 
 	// Callback when training completes successfully
-	OnComplete func()
+	OnComplete func(run Run)
+
+	// LatestVersion returns the newest published model version, recorded as
+	// the parent of resumed runs ("" = none yet)
+	LatestVersion func() (string, error)
+
+	// ClassCounts returns labeled images per class, from the live labels or
+	// the named snapshot; needed for class weights
+	ClassCounts func(ctx context.Context, snapshot string) (map[string]int, error)
+
+	// Guard refuses new runs, queued ones included, while it returns an
+	// error, e.g. thermal.Monitor.Check
+	Guard func() error
+
+	// Filelist writes the training file list (export CSV with a split column)
+	// from the live labels or the named snapshot, oversampled if asked, and
+	// returns what oversampling did (nil without). When set, the trainer uses
+	// its train/val splits instead of --val.
+	Filelist func(ctx context.Context, w io.Writer, snapshot string, oversample *export.Oversample) (*export.OversampleResult, error)
 }
 
 // NewTrainer creates a new Trainer instance
@@ -86,6 +206,23 @@ func NewTrainer(containerName string) (*Trainer, error) {
 	return t, nil
 }
 
+// CheckDocker verifies the Docker daemon answers and the trainer container
+// exists, without touching any containers (unlike NewTrainer).
+func CheckDocker(ctx context.Context, containerName string) error {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return fmt.Errorf("docker client: %w", err)
+	}
+	defer cli.Close()
+	if _, err := cli.Ping(ctx); err != nil {
+		return fmt.Errorf("docker daemon: %w", err)
+	}
+	if _, err := cli.ContainerInspect(ctx, containerName); err != nil {
+		return fmt.Errorf("trainer container %q: %w", containerName, err)
+	}
+	return nil
+}
+
 // Close cleans up the Docker client
 func (t *Trainer) Close() error {
 	if t.cli != nil {
@@ -136,17 +273,32 @@ func (t *Trainer) Status(ctx context.Context) TrainStatus {
 
 	status := TrainStatus{
 		Running:     trainingRunning,
+		RunID:       t.run.ID,
 		ContainerID: containerID,
 		StartedAt:   t.startedAt,
 		ExitCode:    t.lastExitCode,
 		Error:       t.lastError,
-		Logs:        t.lastLogs,
+		Logs:        lastLines(t.lastLogs, statusLogLines),
+		LogBytes:    t.logBytes.Load(),
+		LogFile:     t.logPath,
 		LastConfig:  t.lastConfig,
+		Parent:      t.run.Parent,
+		Queued:      t.queued,
+
+		ClassWeights: t.lastClassWeights,
+	}
+	if t.queued != nil {
+		queuedAt := t.queuedAt
+		status.QueuedAt = &queuedAt
+	}
+	if t.deferred != nil {
+		deferTo := t.deferTo
+		status.QueuedUntil = &deferTo
 	}
 
 	// If running, get current logs
 	if trainingRunning && containerID != "" {
-		logs, err := t.getLogs(ctx, containerID, 100)
+		logs, err := t.getLogs(ctx, containerID, statusLogLines)
 		if err == nil {
 			status.Logs = logs
 		}
@@ -187,24 +339,166 @@ func (t *Trainer) getJobContainerState(ctx context.Context) (containerID string,
 	return id, ok
 }
 
+// State summarizes the run state without asking Docker, for callers that
+// must stay cheap: "running", "failed" (the last run did) or "idle".
+func (t *Trainer) State() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	switch {
+	case t.running && t.deferred == nil:
+		return "running"
+	case t.lastError != "" || t.lastExitCode != 0:
+		return "failed"
+	}
+	return "idle"
+}
+
 // Start starts a training job with the given config.
 // It keeps the wrapper container untouched and launches a separate job container with the training command.
 func (t *Trainer) Start(ctx context.Context, cfg TrainConfig) error {
 	// Check if already running
 	if _, isRunning := t.getJobContainerState(ctx); isRunning {
-		return fmt.Errorf("training already in progress")
+		return ErrBusy
 	}
 
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	if t.deferred != nil {
+		return fmt.Errorf("%w: a queued run waits to start at %s; cancel it first", ErrBusy, t.deferTo.UTC().Format(time.RFC3339))
+	}
 	if t.running {
-		return fmt.Errorf("training already in progress")
+		return ErrBusy
+	}
+	return t.launch(ctx, cfg)
+}
+
+// StartOrQueue starts a training job like Start or, if one is running, keeps
+// cfg to be started when it ends. Only one run can wait (ErrQueueFull). A
+// queued run that would start too soon after the current one waits for the
+// start limit instead of being refused.
+func (t *Trainer) StartOrQueue(ctx context.Context, cfg TrainConfig) (queued bool, err error) {
+	_, isRunning := t.getJobContainerState(ctx)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !isRunning && !t.running {
+		return false, t.launch(ctx, cfg)
+	}
+	if t.queued != nil {
+		return false, ErrQueueFull
+	}
+	if !t.running {
+		// Running without a monitor to launch the queue, e.g. a job
+		// container nobody waits for
+		return false, ErrBusy
+	}
+	// Refuse now what launch would refuse once the current run ends
+	if t.Guard != nil {
+		if err := t.Guard(); err != nil {
+			return false, &GuardError{Err: err}
+		}
+	}
+	if bad := t.disallowedEnvLocked(cfg.ExtraEnv); len(bad) > 0 {
+		return false, fmt.Errorf("extra_env keys not allowed: %s", strings.Join(bad, ", "))
+	}
+	t.queued = &cfg
+	t.queuedAt = time.Now()
+	log.Printf("trainer: queued run with epochs=%d batch=%d lr=%s", cfg.Epochs, cfg.BatchSize, cfg.LR)
+	return true, nil
+}
+
+// CancelQueued drops the queued run and returns its config, or nil if none was queued.
+func (t *Trainer) CancelQueued() *TrainConfig {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	cfg := t.queued
+	t.queued = nil
+	t.queuedAt = time.Time{}
+	if t.deferred != nil {
+		// No run is in progress, only the wait for the start limit
+		t.deferred.Stop()
+		t.deferred, t.deferTo = nil, time.Time{}
+		t.running = false
+	}
+	if cfg != nil {
+		log.Printf("trainer: queued run canceled")
+	}
+	return cfg
+}
+
+// launch creates and starts the job container; t.mu must be held.
+func (t *Trainer) launch(ctx context.Context, cfg TrainConfig) error {
+	if t.Guard != nil {
+		if err := t.Guard(); err != nil {
+			return &GuardError{Err: err}
+		}
+	}
+	if err := t.checkStartLimitLocked(ctx, cfg); err != nil {
+		return err
+	}
+	if bad := t.disallowedEnvLocked(cfg.ExtraEnv); len(bad) > 0 {
+		return fmt.Errorf("extra_env keys not allowed: %s", strings.Join(bad, ", "))
+	}
+
+	if cfg.Snapshot != "" && (t.Filelist == nil || t.sharedDir == "") {
+		return fmt.Errorf("training from a snapshot %w", ErrNoFilelist)
+	}
+	if cfg.Oversample != nil {
+		if t.Filelist == nil || t.sharedDir == "" {
+			return fmt.Errorf("oversampling %w", ErrNoFilelist)
+		}
+		if err := cfg.Oversample.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Class weights counter imbalance (e.g. 80% heavy_clouds)
+	var weights map[string]float64
+	var weightsPath string
+	if cfg.UseClassWeights && t.ClassCounts != nil && t.sharedDir != "" {
+		counts, err := t.ClassCounts(ctx, cfg.Snapshot)
+		if err != nil {
+			return fmt.Errorf("class counts: %w", err)
+		}
+		weights = ClassWeights(counts)
+		if weightsPath, err = writeClassWeights(t.sharedDir, weights); err != nil {
+			return err
+		}
+	}
+
+	var filelistPath, filelistSum string
+	var oversampled *export.OversampleResult
+	if t.Filelist != nil && t.sharedDir != "" {
+		path, sum, err := writeFilelist(ctx, t.sharedDir, func(ctx context.Context, w io.Writer) error {
+			var err error
+			oversampled, err = t.Filelist(ctx, w, cfg.Snapshot, cfg.Oversample)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		filelistPath, filelistSum = path, sum
+	}
+	if oversampled != nil {
+		for _, w := range oversampled.Warnings {
+			log.Printf("trainer: oversample: %s", w)
+		}
+	}
+
+	// Resumed runs fine-tune the newest model
+	var parent string
+	if !cfg.FromScratch && t.LatestVersion != nil {
+		v, err := t.LatestVersion()
+		if err != nil {
+			return fmt.Errorf("latest model version: %w", err)
+		}
+		parent = v
 	}
 
 	// Get the existing container config to preserve settings
 	existingInfo, err := t.cli.ContainerInspect(ctx, t.containerName)
 	if err != nil {
-		return fmt.Errorf("trainer container not found (is docker-compose up?): %w", err)
+		return fmt.Errorf("trainer container not found (is docker-compose up?): %w", unavailable(err))
 	}
 
 	// Build new command with training params
@@ -220,10 +514,22 @@ func (t *Trainer) Start(ctx context.Context, cfg TrainConfig) error {
 	if cfg.FromScratch {
 		cmd = append(cmd, "--from-scratch")
 	}
+	if weightsPath != "" {
+		cmd = append(cmd, "--class-weights", weightsPath)
+	}
+	if filelistPath != "" {
+		cmd = append(cmd, "--filelist", filelistPath)
+	}
 
 	cfgCopy := *existingInfo.Config
 	hostCopy := *existingInfo.HostConfig
 
+	// The job gets the wrapper's mounts: refuse to start on a full disk or
+	// with a file list the trainer can't read
+	if err := t.checkVolumesLocked(&hostCopy, filelistPath); err != nil {
+		return err
+	}
+
 	// Always keep the wrapper container running; create a separate job container
 	jobName := t.jobContainerName()
 	if err := t.cli.ContainerRemove(ctx, jobName, container.RemoveOptions{Force: true}); err != nil && !client.IsErrNotFound(err) {
@@ -233,15 +539,16 @@ func (t *Trainer) Start(ctx context.Context, cfg TrainConfig) error {
 	// Recreate with new command but same config (volumes, env, etc.)
 	newConfig := cfgCopy
 	newConfig.Cmd = cmd
+	newConfig.Env = mergeEnv(mergeEnv(cfgCopy.Env, publishEnv), cfg.ExtraEnv)
 
 	resp, err := t.cli.ContainerCreate(ctx, &newConfig, &hostCopy, nil, nil, jobName)
 	if err != nil {
-		return fmt.Errorf("recreate container: %w", err)
+		return fmt.Errorf("recreate container: %w", unavailable(err))
 	}
 
 	// Start the container
 	if err := t.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
-		return fmt.Errorf("start container: %w", err)
+		return fmt.Errorf("start container: %w", unavailable(err))
 	}
 
 	t.running = true
@@ -250,12 +557,43 @@ func (t *Trainer) Start(ctx context.Context, cfg TrainConfig) error {
 	t.lastError = ""
 	t.lastLogs = ""
 	t.lastConfig = &cfg
+	t.lastClassWeights = weights
 	t.jobContainerID = resp.ID
+	t.run = Run{
+		ID:             t.startedAt.UTC().Format(runIDLayout),
+		Parent:         parent,
+		Config:         cfg,
+		StartedAt:      t.startedAt,
+		ClassWeights:   weights,
+		FilelistSHA256: filelistSum,
+		ServerVersion:  buildinfo.String(),
+		Oversample:     oversampled,
+	}
+	t.logPath = ""
+	t.logBytes.Store(0)
+	t.recordStartLocked(ctx, StartAttempt{At: t.startedAt, RunID: t.run.ID, Override: cfg.IgnoreMinInterval && !t.starts.NextStart(t.startLimit.MinInterval).IsZero()})
+
+	// Persist the complete log; failures only cost the file, not the run
+	if f, err := t.openRunLog(t.startedAt); err != nil {
+		log.Printf("trainer: %v", err)
+	} else if f != nil {
+		t.logPath = f.Name()
+		go t.followLogs(resp.ID, f, &t.logBytes)
+	}
 
 	// Monitor in background
 	go t.monitor(resp.ID)
 
-	log.Printf("trainer: started %s with epochs=%d batch=%d lr=%s", jobName, cfg.Epochs, cfg.BatchSize, cfg.LR)
+	log.Printf("trainer: started %s with epochs=%d batch=%d lr=%s (server %s)", jobName, cfg.Epochs, cfg.BatchSize, cfg.LR, t.run.ServerVersion)
+	if cfg.Snapshot != "" {
+		log.Printf("trainer: training on snapshot %s", cfg.Snapshot)
+	}
+	if weights != nil {
+		log.Printf("trainer: class weights %v", weights)
+	}
+	if len(cfg.ExtraEnv) > 0 {
+		log.Printf("trainer: extra env %v", cfg.ExtraEnv)
+	}
 	return nil
 }
 
@@ -284,19 +622,24 @@ func (t *Trainer) monitor(containerID string) {
 
 	statusCh, errCh := t.cli.ContainerWait(ctx, containerID, container.WaitConditionNotRunning)
 
+	// With a run queued, running stays set until it has been launched below
+	var handoff bool
+
 	select {
 	case err := <-errCh:
 		t.mu.Lock()
-		t.running = false
+		handoff = t.queued != nil
+		t.running = handoff
 		t.lastError = err.Error()
 		t.mu.Unlock()
 		log.Printf("trainer: wait error: %v", err)
 
 	case result := <-statusCh:
-		logs, _ := t.getLogs(ctx, containerID, 500)
+		logs, _ := t.getLogs(ctx, containerID, keptLogLines)
 
 		t.mu.Lock()
-		t.running = false
+		handoff = t.queued != nil
+		t.running = handoff
 		t.lastExitCode = int(result.StatusCode)
 		t.lastLogs = logs
 		if result.Error != nil {
@@ -304,14 +647,17 @@ func (t *Trainer) monitor(containerID string) {
 		} else if result.StatusCode != 0 {
 			t.lastError = fmt.Sprintf("training failed with exit code %d", result.StatusCode)
 		}
-		onComplete := t.OnComplete
+		if t.run.ID != "" && result.StatusCode == 0 {
+			t.run.FinishedAt = time.Now()
+		}
+		onComplete, run := t.OnComplete, t.run
 		t.mu.Unlock()
 
 		if result.StatusCode == 0 {
 			log.Printf("trainer: completed successfully")
 			// Call completion callback (e.g., to reload models)
 			if onComplete != nil {
-				onComplete()
+				onComplete(run)
 			}
 		} else {
 			log.Printf("trainer: exited with code %d", result.StatusCode)
@@ -324,10 +670,47 @@ func (t *Trainer) monitor(containerID string) {
 	}
 
 	t.mu.Lock()
+	defer t.mu.Unlock()
 	if t.jobContainerID == containerID {
 		t.jobContainerID = ""
 	}
-	t.mu.Unlock()
+
+	if !handoff {
+		return
+	}
+	t.launchQueuedLocked(ctx)
+}
+
+// launchQueuedLocked launches the queued run, or defers it while the start
+// limit holds it back; running stays set meanwhile. t.mu must be held.
+func (t *Trainer) launchQueuedLocked(ctx context.Context) {
+	next := t.queued
+	if next == nil { // canceled in the meantime
+		t.running = false
+		return
+	}
+	if wait := t.starts.NextStart(t.startLimit.MinInterval); !wait.IsZero() && !next.IgnoreMinInterval {
+		log.Printf("trainer: queued run waits for the minimum interval between starts, until %s", wait.UTC().Format(time.RFC3339))
+		t.deferTo = wait
+		t.deferred = time.AfterFunc(time.Until(wait), func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.deferred == nil { // canceled
+				return
+			}
+			t.deferred, t.deferTo = nil, time.Time{}
+			t.launchQueuedLocked(context.Background())
+		})
+		return
+	}
+	t.queued = nil
+	t.queuedAt = time.Time{}
+	log.Printf("trainer: starting queued run")
+	if err := t.launch(ctx, *next); err != nil {
+		t.running = false
+		t.lastError = "queued run: " + err.Error()
+		log.Printf("trainer: start queued run: %v", err)
+	}
 }
 
 // getLogs retrieves the last N lines of container logs
@@ -0,0 +1,81 @@
+package trainer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/SkyClf/SkyClf/internal/infer"
+)
+
+// DefaultEnvPrefixes are the environment variable prefixes a run's ExtraEnv
+// may use unless SetEnvPrefixes says otherwise.
+var DefaultEnvPrefixes = []string{"TRAIN_"}
+
+// publishEnv tells the trainer the model publish protocol (see
+// infer.DoneMarker): write skystate/<prefix>vN and create the marker in it
+// once every file is complete.
+var publishEnv = map[string]string{
+	"SKYCLF_MODEL_PENDING_PREFIX": infer.PendingPrefix,
+	"SKYCLF_MODEL_DONE_MARKER":    infer.DoneMarker,
+}
+
+// SetEnvPrefixes sets which variables TrainConfig.ExtraEnv may set: only keys
+// starting with one of prefixes, so a run can't clobber PATH or the
+// container's credentials. Empty restores DefaultEnvPrefixes.
+func (t *Trainer) SetEnvPrefixes(prefixes []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.envPrefixes = prefixes
+}
+
+// DisallowedEnv returns the keys of env that a run may not set, sorted.
+func (t *Trainer) DisallowedEnv(env map[string]string) []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.disallowedEnvLocked(env)
+}
+
+// disallowedEnvLocked is DisallowedEnv with t.mu held.
+func (t *Trainer) disallowedEnvLocked(env map[string]string) []string {
+	prefixes := t.envPrefixes
+	if len(prefixes) == 0 {
+		prefixes = DefaultEnvPrefixes
+	}
+
+	var bad []string
+	for k := range env {
+		ok := !strings.ContainsAny(k, "= \t\n") && slices.ContainsFunc(prefixes, func(p string) bool {
+			return strings.HasPrefix(k, p)
+		})
+		if !ok {
+			bad = append(bad, k)
+		}
+	}
+	slices.Sort(bad)
+	return bad
+}
+
+// mergeEnv returns base (KEY=value entries) with the variables of extra
+// replaced or added, the added ones in key order.
+func mergeEnv(base []string, extra map[string]string) []string {
+	if len(extra) == 0 {
+		return base
+	}
+	out := make([]string, 0, len(base)+len(extra))
+	for _, kv := range base {
+		k, _, _ := strings.Cut(kv, "=")
+		if _, ok := extra[k]; !ok {
+			out = append(out, kv)
+		}
+	}
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		out = append(out, fmt.Sprintf("%s=%s", k, extra[k]))
+	}
+	return out
+}
@@ -0,0 +1,176 @@
+package trainer
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+const (
+	// statusLogLines is how many log lines are embedded in every status response.
+	statusLogLines = 20
+	// keptLogLines is how much of a finished run is kept in memory.
+	keptLogLines = 200
+	// MaxLogTail caps ?tail= on the logs endpoint.
+	MaxLogTail = 10000
+
+	// runIDLayout formats a run's start time (UTC) into its ID and log file name.
+	runIDLayout = "20060102_150405"
+)
+
+// SetLogDir enables persisting complete training logs as files in dir
+// (e.g. DataDir/train-logs). Empty disables it.
+func (t *Trainer) SetLogDir(dir string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.logDir = dir
+}
+
+// openRunLog creates the log file for a new run. Caller holds t.mu.
+func (t *Trainer) openRunLog(startedAt time.Time) (*os.File, error) {
+	if t.logDir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(t.logDir, 0o755); err != nil {
+		return nil, fmt.Errorf("create log dir: %w", err)
+	}
+	path := filepath.Join(t.logDir, startedAt.UTC().Format(runIDLayout)+".log")
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create log file: %w", err)
+	}
+	return f, nil
+}
+
+// countingWriter counts bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n *atomic.Int64
+}
+
+func (c countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// followLogs streams the job container's logs into f until the container exits,
+// counting bytes for the status. It closes f when done.
+func (t *Trainer) followLogs(containerID string, f *os.File, counter *atomic.Int64) {
+	defer f.Close()
+
+	reader, err := t.cli.ContainerLogs(context.Background(), containerID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+	})
+	if err != nil {
+		log.Printf("trainer: follow logs: %v", err)
+		return
+	}
+	defer reader.Close()
+
+	cw := countingWriter{w: f, n: counter}
+	if _, err := stdcopy.StdCopy(cw, cw, reader); err != nil {
+		log.Printf("trainer: follow logs: %v", err)
+	}
+}
+
+// Logs returns the last tail lines of the current (or last) run.
+func (t *Trainer) Logs(ctx context.Context, tail int) (string, error) {
+	if containerID, running := t.getJobContainerState(ctx); running && containerID != "" {
+		return t.getLogs(ctx, containerID, tail)
+	}
+
+	t.mu.RLock()
+	path, lastLogs := t.logPath, t.lastLogs
+	t.mu.RUnlock()
+	if path == "" {
+		return lastLogs, nil
+	}
+	return tailFile(path, tail)
+}
+
+// WriteFullLogs copies the complete log of the current (or last) run to w.
+func (t *Trainer) WriteFullLogs(ctx context.Context, w io.Writer) error {
+	t.mu.RLock()
+	path, lastLogs := t.logPath, t.lastLogs
+	t.mu.RUnlock()
+
+	if path != "" {
+		f, err := os.Open(path)
+		if err == nil {
+			defer f.Close()
+			_, err = io.Copy(w, f)
+			return err
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	// No log file (disabled or from before a restart): ask docker directly.
+	if containerID, running := t.getJobContainerState(ctx); running && containerID != "" {
+		reader, err := t.cli.ContainerLogs(ctx, containerID, container.LogsOptions{ShowStdout: true, ShowStderr: true})
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		_, err = stdcopy.StdCopy(w, w, reader)
+		return err
+	}
+	_, err := io.WriteString(w, lastLogs)
+	return err
+}
+
+// tailFile returns the last n lines of the file at path.
+func tailFile(path string, n int) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer f.Close()
+
+	ring := make([]string, 0, n)
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		if len(ring) == n {
+			ring = ring[1:]
+		}
+		ring = append(ring, sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		return "", err
+	}
+	if len(ring) == 0 {
+		return "", nil
+	}
+	return strings.Join(ring, "\n") + "\n", nil
+}
+
+// lastLines returns the last n lines of s.
+func lastLines(s string, n int) string {
+	trimmed := strings.TrimRight(s, "\n")
+	if trimmed == "" {
+		return ""
+	}
+	lines := strings.Split(trimmed, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
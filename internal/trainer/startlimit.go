@@ -0,0 +1,129 @@
+package trainer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ErrTooSoon is wrapped by the error of a run refused because the previous
+// one started less than the minimum interval ago.
+var ErrTooSoon = errors.New("training started too recently")
+
+// maxStartHistory bounds the attempts kept in a StartHistory.
+const maxStartHistory = 50
+
+// TooSoonError reports a start refused by StartLimit.MinInterval.
+type TooSoonError struct {
+	LastStart   time.Time
+	NextStart   time.Time // first time a start is allowed
+	MinInterval time.Duration
+}
+
+func (e *TooSoonError) Error() string {
+	return fmt.Sprintf("last training started at %s; the minimum interval between starts is %s, next start allowed at %s (or set ignore_min_interval)",
+		e.LastStart.UTC().Format(time.RFC3339), e.MinInterval, e.NextStart.UTC().Format(time.RFC3339))
+}
+
+func (e *TooSoonError) Unwrap() error { return ErrTooSoon }
+
+// StartAttempt is a run started, or refused for starting too soon.
+type StartAttempt struct {
+	At            time.Time `json:"at"`
+	RunID         string    `json:"run_id,omitempty"` // empty when refused
+	Refused       bool      `json:"refused,omitempty"`
+	Override      bool      `json:"override,omitempty"`       // started with ignore_min_interval
+	SincePrevious string    `json:"since_previous,omitempty"` // since the start before it
+}
+
+// StartHistory is the recent start attempts, oldest first.
+type StartHistory struct {
+	Attempts []StartAttempt `json:"attempts"`
+}
+
+// LastStart returns when the newest run started (zero if none did).
+func (h StartHistory) LastStart() time.Time {
+	for i := len(h.Attempts) - 1; i >= 0; i-- {
+		if !h.Attempts[i].Refused {
+			return h.Attempts[i].At
+		}
+	}
+	return time.Time{}
+}
+
+// NextStart returns when a run may start without an override (zero = now).
+func (h StartHistory) NextStart(minInterval time.Duration) time.Time {
+	last := h.LastStart()
+	if minInterval <= 0 || last.IsZero() {
+		return time.Time{}
+	}
+	if next := last.Add(minInterval); next.After(time.Now()) {
+		return next
+	}
+	return time.Time{}
+}
+
+// StartLimit spaces out training starts, against automation that starts runs
+// back-to-back.
+type StartLimit struct {
+	MinInterval time.Duration // between two starts (0 = no limit)
+	// Load and Save persist the start history, so a restart doesn't reset
+	// the limit, e.g. in the settings table (optional)
+	Load func(ctx context.Context) (StartHistory, error)
+	Save func(ctx context.Context, h StartHistory) error
+}
+
+// SetStartLimit sets the minimum interval between starts and loads the
+// start history.
+func (t *Trainer) SetStartLimit(l StartLimit) {
+	var h StartHistory
+	if l.Load != nil {
+		var err error
+		if h, err = l.Load(context.Background()); err != nil {
+			log.Printf("trainer: WARNING start history: %v", err)
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.startLimit, t.starts = l, h
+}
+
+// StartHistory returns the recent start attempts.
+func (t *Trainer) StartHistory() StartHistory {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return StartHistory{Attempts: append([]StartAttempt(nil), t.starts.Attempts...)}
+}
+
+// checkStartLimitLocked refuses cfg while the previous run started less than
+// the minimum interval ago, unless cfg overrides it; t.mu must be held.
+func (t *Trainer) checkStartLimitLocked(ctx context.Context, cfg TrainConfig) error {
+	next := t.starts.NextStart(t.startLimit.MinInterval)
+	if next.IsZero() || cfg.IgnoreMinInterval {
+		return nil
+	}
+	err := &TooSoonError{LastStart: t.starts.LastStart(), NextStart: next, MinInterval: t.startLimit.MinInterval}
+	log.Printf("trainer: refused start: %v", err)
+	t.recordStartLocked(ctx, StartAttempt{At: time.Now(), Refused: true})
+	return err
+}
+
+// recordStartLocked appends a to the start history and saves it; t.mu must
+// be held. Failures only log.
+func (t *Trainer) recordStartLocked(ctx context.Context, a StartAttempt) {
+	if last := t.starts.LastStart(); !last.IsZero() {
+		a.SincePrevious = a.At.Sub(last).Round(time.Second).String()
+	}
+	a.At = a.At.UTC()
+	t.starts.Attempts = append(t.starts.Attempts, a)
+	if n := len(t.starts.Attempts); n > maxStartHistory {
+		t.starts.Attempts = append([]StartAttempt(nil), t.starts.Attempts[n-maxStartHistory:]...)
+	}
+	if t.startLimit.Save != nil {
+		if err := t.startLimit.Save(context.WithoutCancel(ctx), t.starts); err != nil {
+			log.Printf("trainer: save start history: %v", err)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package trainer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStartHistoryNextStart(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		attempts []StartAttempt
+		min      time.Duration
+		wantZero bool
+	}{
+		{"no starts", nil, time.Hour, true},
+		{"no limit", []StartAttempt{{At: now}}, 0, true},
+		{"recent start", []StartAttempt{{At: now.Add(-time.Minute)}}, time.Hour, false},
+		{"old start", []StartAttempt{{At: now.Add(-2 * time.Hour)}}, time.Hour, true},
+		{"only refused since", []StartAttempt{{At: now.Add(-2 * time.Hour)}, {At: now, Refused: true}}, time.Hour, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := StartHistory{Attempts: tt.attempts}.NextStart(tt.min)
+			if next.IsZero() != tt.wantZero {
+				t.Fatalf("NextStart = %v, want zero %v", next, tt.wantZero)
+			}
+		})
+	}
+}
+
+func TestCheckStartLimit(t *testing.T) {
+	tr := &Trainer{
+		startLimit: StartLimit{MinInterval: time.Hour},
+		starts:     StartHistory{Attempts: []StartAttempt{{At: time.Now().Add(-time.Minute), RunID: "r1"}}},
+	}
+	err := tr.checkStartLimitLocked(context.Background(), TrainConfig{})
+	var tooSoon *TooSoonError
+	if !errors.As(err, &tooSoon) || !errors.Is(err, ErrTooSoon) {
+		t.Fatalf("err = %v, want a TooSoonError", err)
+	}
+	if last := tr.starts.Attempts[len(tr.starts.Attempts)-1]; !last.Refused {
+		t.Fatalf("refused start not recorded: %+v", last)
+	}
+	if err := tr.checkStartLimitLocked(context.Background(), TrainConfig{IgnoreMinInterval: true}); err != nil {
+		t.Fatalf("override refused: %v", err)
+	}
+}
+
+// queuedTrainer returns a trainer whose current run just ended, started a
+// minute ago, with a run queued behind it.
+func queuedTrainer(minInterval time.Duration) *Trainer {
+	return &Trainer{
+		running:    true,
+		queued:     &TrainConfig{Epochs: 3},
+		queuedAt:   time.Now(),
+		startLimit: StartLimit{MinInterval: minInterval},
+		starts:     StartHistory{Attempts: []StartAttempt{{At: time.Now().Add(-time.Minute), RunID: "r1"}}},
+	}
+}
+
+func TestQueuedRunWaitsForStartLimit(t *testing.T) {
+	tr := queuedTrainer(time.Hour)
+
+	tr.mu.Lock()
+	tr.launchQueuedLocked(context.Background())
+	tr.mu.Unlock()
+
+	if tr.queued == nil || tr.deferred == nil {
+		t.Fatalf("queued run not kept: queued %v, deferred %v", tr.queued, tr.deferred)
+	}
+	if tr.lastError != "" {
+		t.Fatalf("queued run refused: %s", tr.lastError)
+	}
+	if want := tr.starts.LastStart().Add(time.Hour); !tr.deferTo.Equal(want) {
+		t.Fatalf("deferred to %v, want %v", tr.deferTo, want)
+	}
+	if !tr.running {
+		t.Fatal("running cleared while a run waits; a Start could slip in")
+	}
+	if got := tr.State(); got != "idle" {
+		t.Fatalf("State = %q while only waiting, want idle", got)
+	}
+	for _, a := range tr.starts.Attempts {
+		if a.Refused {
+			t.Fatal("deferred run recorded as refused")
+		}
+	}
+
+	if cfg := tr.CancelQueued(); cfg == nil || cfg.Epochs != 3 {
+		t.Fatalf("CancelQueued = %+v", cfg)
+	}
+	if tr.running || tr.deferred != nil {
+		t.Fatalf("cancel left running %v, deferred %v", tr.running, tr.deferred)
+	}
+}
+
+func TestCanceledDeferredRunDoesNotLaunch(t *testing.T) {
+	// The limit lifts almost at once; the timer must find the run canceled
+	tr := queuedTrainer(time.Minute + 20*time.Millisecond)
+
+	tr.mu.Lock()
+	tr.launchQueuedLocked(context.Background())
+	timer := tr.deferred
+	tr.mu.Unlock()
+	if timer == nil {
+		t.Fatal("queued run not deferred")
+	}
+	tr.CancelQueued()
+
+	time.Sleep(60 * time.Millisecond)
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+	if tr.running || tr.queued != nil || tr.lastError != "" {
+		t.Fatalf("running %v, queued %v, lastError %q", tr.running, tr.queued, tr.lastError)
+	}
+}
@@ -0,0 +1,188 @@
+package trainer
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+)
+
+// ErrVolume is wrapped by the errors of a run refused because a volume it
+// needs is short of space or doesn't hold the dataset.
+var ErrVolume = errors.New("trainer volume check failed")
+
+// VolumeCheck is checked before every run, so a full disk fails the start
+// instead of the run hours in.
+type VolumeCheck struct {
+	// MinFreeBytes is the free space every checked volume needs (0 = don't check)
+	MinFreeBytes int64
+	// Dirs are checked besides the trainer's binds, e.g. the models and
+	// images directories as the server sees them
+	Dirs []string
+	// PathMap maps the host side of the trainer's bind mounts (a path
+	// prefix or a volume name) to where the server sees it. Binds it can't
+	// resolve are checked if the host path exists locally, else skipped.
+	PathMap map[string]string
+}
+
+// DiskSpaceError reports a volume below VolumeCheck.MinFreeBytes.
+type DiskSpaceError struct {
+	Path         string // as the server sees it
+	FreeBytes    int64
+	MinFreeBytes int64
+}
+
+func (e *DiskSpaceError) Error() string {
+	return fmt.Sprintf("not enough free space on %s: %.2f GB free, %.2f GB required",
+		e.Path, float64(e.FreeBytes)/1e9, float64(e.MinFreeBytes)/1e9)
+}
+
+func (e *DiskSpaceError) Unwrap() error { return ErrVolume }
+
+// SetVolumeCheck sets what is checked before a run starts.
+func (t *Trainer) SetVolumeCheck(vc VolumeCheck) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.volumes = vc
+}
+
+// volumeBind is a trainer mount with its source resolved on the server side.
+type volumeBind struct {
+	target string // in the trainer container
+	local  string // as the server sees it ("" = unresolved)
+}
+
+// checkVolumesLocked checks free space on the trainer's mounts and that the
+// file list's images exist where the trainer will look; t.mu must be held.
+func (t *Trainer) checkVolumesLocked(host *container.HostConfig, filelistPath string) error {
+	binds := t.resolveBinds(host)
+	if t.volumes.MinFreeBytes > 0 {
+		paths := append([]string{}, t.volumes.Dirs...)
+		for _, b := range binds {
+			if b.local != "" {
+				paths = append(paths, b.local)
+			}
+		}
+		seen := map[string]bool{}
+		for _, p := range paths {
+			if p == "" || seen[p] {
+				continue
+			}
+			seen[p] = true
+			free, err := freeBytes(p)
+			if err != nil {
+				log.Printf("trainer: volume check %s: %v", p, err)
+				continue
+			}
+			if free < t.volumes.MinFreeBytes {
+				return &DiskSpaceError{Path: p, FreeBytes: free, MinFreeBytes: t.volumes.MinFreeBytes}
+			}
+		}
+	}
+	if filelistPath != "" {
+		return checkFilelistPaths(filelistPath, binds)
+	}
+	return nil
+}
+
+// resolveBinds returns the trainer's bind and volume mounts with their
+// server-side paths where known.
+func (t *Trainer) resolveBinds(host *container.HostConfig) []volumeBind {
+	var out []volumeBind
+	add := func(source, target string) {
+		out = append(out, volumeBind{target: filepath.Clean(target), local: t.localPath(source)})
+	}
+	for _, b := range host.Binds {
+		// source:target[:options]
+		parts := strings.Split(b, ":")
+		if len(parts) >= 2 {
+			add(parts[0], parts[1])
+		}
+	}
+	for _, m := range host.Mounts {
+		if m.Type == mount.TypeBind || m.Type == mount.TypeVolume {
+			add(m.Source, m.Target)
+		}
+	}
+	return out
+}
+
+// localPath maps a mount source to the server's view of it, or "".
+func (t *Trainer) localPath(source string) string {
+	best, bestLocal := "", ""
+	for from, to := range t.volumes.PathMap {
+		if source == from || strings.HasPrefix(source, strings.TrimRight(from, "/")+"/") {
+			if len(from) > len(best) {
+				best, bestLocal = from, filepath.Join(to, strings.TrimPrefix(source, from))
+			}
+		}
+	}
+	if best != "" {
+		return bestLocal
+	}
+	if filepath.IsAbs(source) {
+		if _, err := os.Stat(source); err == nil {
+			return source
+		}
+	}
+	return ""
+}
+
+// maxMissingShown bounds the examples in a missing-images error.
+const maxMissingShown = 3
+
+// checkFilelistPaths verifies that the images listed in the file list are
+// inside the trainer's mounts and, where a mount resolves, exist there.
+func checkFilelistPaths(filelistPath string, binds []volumeBind) error {
+	paths, err := readFilelistPaths(filelistPath)
+	if err != nil {
+		return err
+	}
+	var unmounted, missing []string
+	for _, p := range paths {
+		if !filepath.IsAbs(p) {
+			continue // relative to the trainer's working directory; can't tell
+		}
+		var bind *volumeBind
+		for i, b := range binds {
+			if (p == b.target || strings.HasPrefix(p, strings.TrimRight(b.target, "/")+"/")) &&
+				(bind == nil || len(b.target) > len(bind.target)) {
+				bind = &binds[i]
+			}
+		}
+		switch {
+		case bind == nil:
+			unmounted = append(unmounted, p)
+		case bind.local != "":
+			local := filepath.Join(bind.local, strings.TrimPrefix(p, bind.target))
+			if _, err := os.Stat(local); err != nil {
+				missing = append(missing, p)
+			}
+		}
+	}
+	if len(binds) > 0 && len(unmounted) > 0 {
+		return fmt.Errorf("%w: %d file list images are outside the trainer's mounts, e.g. %s",
+			ErrVolume, len(unmounted), strings.Join(unmounted[:min(len(unmounted), maxMissingShown)], ", "))
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %d file list images are missing from the trainer's images volume, e.g. %s",
+			ErrVolume, len(missing), strings.Join(missing[:min(len(missing), maxMissingShown)], ", "))
+	}
+	return nil
+}
+
+// freeBytes returns the space available to unprivileged users on the
+// filesystem holding path.
+func freeBytes(path string) (int64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return int64(st.Bavail) * int64(st.Bsize), nil
+}
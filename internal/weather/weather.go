@@ -0,0 +1,199 @@
+// Package weather records the current conditions at the observing site from
+// an Open-Meteo style API, so classifications can be compared with them.
+// It runs on its own interval and never touches image ingestion.
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SkyClf/SkyClf/internal/buildinfo"
+	"github.com/SkyClf/SkyClf/internal/store"
+)
+
+const (
+	// currentVars are the Open-Meteo "current" variables requested.
+	currentVars = "cloud_cover,temperature_2m,relative_humidity_2m,precipitation"
+	// maxBackoff bounds the wait after a rate-limited or failed request.
+	maxBackoff = 6 * time.Hour
+)
+
+// Status is a snapshot of the poller state.
+type Status struct {
+	LastPoll    time.Time             `json:"last_poll,omitempty"`
+	LastError   string                `json:"last_error,omitempty"`
+	NextPoll    time.Time             `json:"next_poll"`
+	Latest      *store.WeatherReading `json:"latest,omitempty"`
+	Failures    int                   `json:"failures"` // consecutive
+	RateLimited bool                  `json:"rate_limited"`
+}
+
+// Poller fetches the current conditions every interval and stores them. After
+// a failure it backs off, doubling the wait up to maxBackoff; a 429 waits for
+// Retry-After if that is longer.
+type Poller struct {
+	st       *store.Store
+	url      string // with the site and the requested variables
+	interval time.Duration
+	client   *http.Client
+
+	mu     sync.Mutex
+	status Status
+}
+
+// NewPoller creates a poller for the API at baseURL (e.g.
+// https://api.open-meteo.com/v1/forecast) and the site at lat/lon. Query
+// parameters already in baseURL are kept.
+func NewPoller(st *store.Store, baseURL string, lat, lon float64, interval time.Duration) (*Poller, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("weather url: %w", err)
+	}
+	q := u.Query()
+	q.Set("latitude", strconv.FormatFloat(lat, 'f', -1, 64))
+	q.Set("longitude", strconv.FormatFloat(lon, 'f', -1, 64))
+	q.Set("current", currentVars)
+	q.Set("timezone", "GMT")
+	u.RawQuery = q.Encode()
+	return &Poller{
+		st:       st,
+		url:      u.String(),
+		interval: interval,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Start polls immediately and then on the interval (or the backoff) until ctx
+// is canceled.
+func (p *Poller) Start(ctx context.Context) {
+	for {
+		wait := p.poll(ctx)
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Status returns the poller state.
+func (p *Poller) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status
+}
+
+// poll fetches and stores one reading and returns how long to wait before
+// the next.
+func (p *Poller) poll(ctx context.Context) time.Duration {
+	r, retryAfter, err := p.fetch(ctx)
+	if err == nil {
+		err = p.st.RecordWeather(ctx, *r)
+	}
+	if ctx.Err() != nil {
+		return 0
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now().UTC()
+	p.status.LastPoll = now
+	p.status.RateLimited = retryAfter > 0
+	wait := p.interval
+	if err != nil {
+		p.status.LastError = err.Error()
+		p.status.Failures++
+		wait = min(p.interval<<min(p.status.Failures, 10), maxBackoff)
+		wait = max(wait, min(retryAfter, maxBackoff))
+		log.Printf("weather: %v (next try in %s)", err, wait)
+	} else {
+		p.status.LastError = ""
+		p.status.Failures = 0
+		p.status.Latest = r
+	}
+	p.status.NextPoll = now.Add(wait)
+	return wait
+}
+
+// errRateLimited is returned for a 429 response.
+var errRateLimited = errors.New("rate limited by the weather source")
+
+// response is the part of an Open-Meteo forecast response that is read.
+type response struct {
+	Current *struct {
+		Time          string   `json:"time"` // GMT, e.g. 2025-01-01T20:15
+		CloudCover    *float64 `json:"cloud_cover"`
+		Temperature   *float64 `json:"temperature_2m"`
+		Humidity      *float64 `json:"relative_humidity_2m"`
+		Precipitation *float64 `json:"precipitation"`
+	} `json:"current"`
+}
+
+// fetch requests the current conditions. retryAfter is set for a 429.
+func (p *Poller) fetch(ctx context.Context) (r *store.WeatherReading, retryAfter time.Duration, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("User-Agent", "SkyClf/"+buildinfo.Get().Version)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("fetch: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, 0, fmt.Errorf("read: %w", err)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter = time.Hour
+		if secs, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Retry-After"))); err == nil && secs > 0 {
+			retryAfter = time.Duration(secs) * time.Second
+		}
+		return nil, retryAfter, errRateLimited
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg := strings.TrimSpace(string(body))
+		if len(msg) > 200 {
+			msg = msg[:200] + "..."
+		}
+		return nil, 0, fmt.Errorf("fetch: HTTP %d: %s", resp.StatusCode, msg)
+	}
+	r, err = parse(body)
+	return r, 0, err
+}
+
+// parse reads a reading from an Open-Meteo response body.
+func parse(body []byte) (*store.WeatherReading, error) {
+	var resp response
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	c := resp.Current
+	if c == nil || c.CloudCover == nil || c.Temperature == nil || c.Humidity == nil || c.Precipitation == nil {
+		return nil, errors.New(`decode: response lacks "current" with ` + currentVars)
+	}
+	t, err := time.Parse("2006-01-02T15:04", c.Time)
+	if err != nil {
+		return nil, fmt.Errorf("decode time %q: %w", c.Time, err)
+	}
+	return &store.WeatherReading{
+		Time:          t,
+		CloudCover:    *c.CloudCover,
+		Temperature:   *c.Temperature,
+		Humidity:      *c.Humidity,
+		Precipitation: *c.Precipitation,
+	}, nil
+}
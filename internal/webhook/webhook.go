@@ -0,0 +1,62 @@
+// Package webhook posts JSON events to a user-configured URL.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Event names.
+const (
+	EventNightReportReady = "night_report_ready"
+	EventLabeledEvicted   = "labeled_evicted" // the image quota deleted labeled images
+	EventThermalHot       = "thermal_hot"     // auto-predict and training paused for heat
+	EventThermalCooled    = "thermal_cooled"  // and resumed
+)
+
+// Notifier sends events to one URL. A nil *Notifier drops every event.
+type Notifier struct {
+	url    string
+	client *http.Client
+}
+
+// New returns a notifier for url, or nil if url is empty.
+func New(url string) *Notifier {
+	if url == "" {
+		return nil
+	}
+	return &Notifier{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Send posts {"event": event, "time": ..., "data": data} and fails on a non-2xx answer.
+func (n *Notifier) Send(ctx context.Context, event string, data any) error {
+	if n == nil {
+		return nil
+	}
+	body, err := json.Marshal(map[string]any{
+		"event": event,
+		"time":  time.Now().UTC().Format(time.RFC3339),
+		"data":  data,
+	})
+	if err != nil {
+		return fmt.Errorf("webhook %s: %w", event, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook %s: %w", event, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook %s: %w", event, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s: %s", event, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+//go:build embedui
+
+package ui
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed all:dist
+var dist embed.FS
+
+// Dist returns the embedded UI build, rooted at dist/.
+func Dist() fs.FS {
+	sub, err := fs.Sub(dist, "dist")
+	if err != nil {
+		return nil
+	}
+	return sub
+}
@@ -0,0 +1,10 @@
+//go:build !embedui
+
+package ui
+
+import "io/fs"
+
+// Dist returns nil: this binary was built without -tags embedui.
+func Dist() fs.FS {
+	return nil
+}
@@ -152,6 +152,7 @@ async function startTraining(config: {
       img_size: 224,
       seed: 42,
       val_split: "0.2",
+      ignore_min_interval: true,
     }),
   });
   if (res.ok) {
@@ -0,0 +1,4 @@
+// Package ui exposes the built web UI (ui/dist). It is only embedded into the
+// binary when building with -tags embedui after "npm run build"; otherwise
+// Dist returns nil and the server needs ui/dist on disk.
+package ui